@@ -22,7 +22,7 @@ var (
 func main() {
 	flag.Parse()
 
-	addr := fmt.Sprintf("%s:%s", *host, *port)
+	addr := net.JoinHostPort(*host, *port)
 
 	conn, err := net.Dial("tcp", addr)
 	if err != nil {
@@ -39,10 +39,20 @@ func runREPL(conn net.Conn) {
 	reader := bufio.NewReader(os.Stdin)
 	decoder := json.NewDecoder(conn)
 	encoder := json.NewEncoder(conn)
+	defer func() { conn.Close() }()
 
-	fmt.Println("\nAvailable commands: INSERT, FIND, DELETE, CREATE_INDEX")
+	fmt.Println("\nAvailable commands: INSERT, FIND, UPDATE, COUNT, DISTINCT, DELETE, CREATE_INDEX, LIST_INDEXES, CREATE_COLLECTION, " +
+		"DROP_COLLECTION, RENAME_COLLECTION, USE, LIST_DATABASES, LIST_COLLECTIONS, DROP_DATABASE, STATS, REPL_STATUS, " +
+		"ADD_MEMBER, REMOVE_MEMBER, TAIL, WATCH, COLL_MOD, BEGIN, COMMIT, ABORT, " +
+		"SHARD_COLLECTION, SPLIT_CHUNK, MOVE_CHUNK, SHARDING_STATUS")
 	fmt.Print("> ")
 
+	// tailing закрывается, когда tailable-курсор прислал последний ответ
+	var tailing chan struct{}
+	// последний USE повторяется после переподключения к лидеру кластера
+	var use *api.Request
+	inTx := false
+
 	for {
 		input, err := reader.ReadString('\n')
 		if err != nil {
@@ -73,42 +83,204 @@ func runREPL(conn net.Conn) {
 		if err := encoder.Encode(req); err != nil {
 			log.Fatalf("Error encoding request: %v", err)
 		}
+		if tailing != nil {
+			// следующий запрос останавливает курсор: сначала дочитываем его ответы
+			<-tailing
+			tailing = nil
+		}
 
 		var resp api.Response
 		if err := decoder.Decode(&resp); err != nil {
 			log.Fatalf("Error decoding response: %v", err)
 		}
 
+		if resp.Status == api.StatusError && resp.Leader != "" && !inTx {
+			// узел кластера не лидер: запрос повторяется на лидере
+			fmt.Printf("Redirecting to the cluster leader %s\n", resp.Leader)
+			leaderConn, err := net.Dial("tcp", resp.Leader)
+			if err != nil {
+				log.Fatalf("Failed to connect to the leader %s: %v", resp.Leader, err)
+			}
+			conn.Close()
+			conn, decoder, encoder = leaderConn, json.NewDecoder(leaderConn), json.NewEncoder(leaderConn)
+			for _, r := range []*api.Request{use, req} {
+				if r == nil {
+					continue
+				}
+				if err := encoder.Encode(r); err != nil {
+					log.Fatalf("Error encoding request: %v", err)
+				}
+				if err := decoder.Decode(&resp); err != nil {
+					log.Fatalf("Error decoding response: %v", err)
+				}
+			}
+		}
+		if resp.Status == api.StatusSuccess {
+			switch req.Command {
+			case api.CmdUse:
+				use = req
+			case api.CmdBegin:
+				inTx = true
+			}
+		}
+		if req.Command == api.CmdCommit || req.Command == api.CmdAbort {
+			inTx = false
+		}
+
 		printResponse(resp)
+		if resp.Tailable && resp.Status == api.StatusSuccess {
+			fmt.Println("Streaming... enter the next command to stop")
+			tailing = make(chan struct{})
+			go printTail(decoder, tailing)
+		}
+		fmt.Print("> ")
+	}
+}
+
+// printTail печатает новые документы tailable-курсора и события watch до последнего ответа потока
+func printTail(decoder *json.Decoder, done chan struct{}) {
+	defer close(done)
+	for {
+		var resp api.Response
+		if err := decoder.Decode(&resp); err != nil {
+			log.Fatalf("Error decoding response: %v", err)
+		}
+		printResponse(resp)
+		if resp.Status == api.StatusError || resp.Message == "Tailable cursor closed" || resp.Message == "Change stream closed" {
+			return
+		}
 		fmt.Print("> ")
 	}
 }
 
 func parseLineToRequest(line string) (*api.Request, error) {
 	fields := strings.Fields(line)
+	if len(fields) == 1 {
+		// BEGIN / COMMIT / ABORT, списки текущей базы, STATS, REPL_STATUS и SHARDING_STATUS не привязаны к коллекции
+		switch cmd := strings.ToLower(fields[0]); cmd {
+		case api.CmdBegin, api.CmdCommit, api.CmdAbort, api.CmdListDatabases, api.CmdListCollections, api.CmdStats, api.CmdReplStatus,
+			api.CmdShardingStatus:
+			return &api.Request{Command: cmd}, nil
+		}
+	}
 	if len(fields) < 2 {
 		return nil, fmt.Errorf("invalid command format")
 	}
 
 	cmd := strings.ToUpper(fields[0])
+
+	switch cmd {
+	case "USE", "LIST_COLLECTIONS", "DROP_DATABASE":
+		// USE shop, LIST_COLLECTIONS shop, DROP_DATABASE shop
+		return &api.Request{Database: fields[1], Command: strings.ToLower(cmd)}, nil
+	case "ADD_MEMBER", "REMOVE_MEMBER":
+		// ADD_MEMBER 10.0.0.4:7000 — адрес узла в кластере
+		return &api.Request{Command: strings.ToLower(cmd), Options: map[string]any{"id": fields[1]}}, nil
+	}
+
+	// коллекция — в текущей базе сессии (USE)
 	collectionName := fields[1]
 
 	req := &api.Request{
-		Database: collectionName,
-		Command:  strings.ToLower(cmd),
+		Collection: collectionName,
+		Command:    strings.ToLower(cmd),
+	}
+
+	if cmd == "DROP_COLLECTION" || cmd == "LIST_INDEXES" {
+		return req, nil
+	}
+
+	if cmd == "RENAME_COLLECTION" {
+		// RENAME_COLLECTION orders orders_2024 [archive]
+		if len(fields) < 3 || len(fields) > 4 {
+			return nil, fmt.Errorf("usage: RENAME_COLLECTION <collection> <new_name> [database]")
+		}
+		req.Options = map[string]any{"to": fields[2]}
+		if len(fields) == 4 {
+			req.Options["to_database"] = fields[3]
+		}
+		return req, nil
 	}
 
 	if cmd == "CREATE_INDEX" {
 		if len(fields) < 3 {
-			return nil, fmt.Errorf("usage: CREATE_INDEX <collection> <field_name>")
+			return nil, fmt.Errorf("usage: CREATE_INDEX <collection> <field_name> [field_name...] [options]")
+		}
+		// CREATE_INDEX products name description {"type": "text"}
+		req.Query = map[string]any{}
+		for i, fieldName := range fields[2:] {
+			if strings.HasPrefix(fieldName, "{") {
+				options, err := query.ParseDocument(strings.Join(fields[2+i:], " "))
+				if err != nil {
+					return nil, fmt.Errorf("invalid JSON options: %v", err)
+				}
+				req.Options = options
+				break
+			}
+			req.Query[fieldName] = nil
+		}
+		return req, nil
+	}
+
+	if cmd == "CREATE_COLLECTION" || cmd == "COLL_MOD" || cmd == "SHARD_COLLECTION" || cmd == "SPLIT_CHUNK" || cmd == "MOVE_CHUNK" {
+		// CREATE_COLLECTION events {"engine": "lsm"}
+		// COLL_MOD users {"validationLevel": "warn"}
+		// SHARD_COLLECTION users {"key": "city", "strategy": "range", "splitPoints": ["M"]}
+		// SPLIT_CHUNK users {"chunk": 0}, MOVE_CHUNK users {"chunk": 1, "to": "10.0.0.2:8080"}
+		if len(fields) > 2 {
+			options, err := query.ParseDocument(strings.Join(fields[2:], " "))
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON options: %v", err)
+			}
+			req.Options = options
+		}
+		return req, nil
+	}
+
+	if cmd == "DISTINCT" {
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("usage: DISTINCT <collection> <field_name> [query]")
+		}
+		req.Field = fields[2]
+		if len(fields) > 3 {
+			q, err := query.Parse(strings.Join(fields[3:], " "))
+			if err != nil {
+				return nil, fmt.Errorf("invalid JSON query: %v", err)
+			}
+			req.Query = q.Conditions
+		}
+		return req, nil
+	}
+
+	if cmd == "WATCH" {
+		// WATCH orders [{"status": "new"}] [{"resumeAfter": "<token>", "fullDocument": true}]
+		if len(fields) == 2 {
+			return req, nil
+		}
+		objects, err := decodeObjects(strings.Join(fields[2:], " "))
+		if err != nil || len(objects) > 2 {
+			return nil, fmt.Errorf("usage: WATCH <collection> [query] [options]")
 		}
-		fieldName := fields[2]
-		req.Query = map[string]any{
-			fieldName: nil,
+		req.Query = objects[0]
+		if len(objects) == 2 {
+			req.Options = objects[1]
 		}
 		return req, nil
 	}
 
+	if cmd == "TAIL" {
+		// TAIL <collection> [query] [projection] — tailable find по capped-коллекции
+		req.Command = api.CmdFind
+		req.Options = map[string]any{"tailable": true}
+		if len(fields) == 2 {
+			return req, nil
+		}
+	}
+
+	if cmd == "COUNT" && len(fields) == 2 {
+		return req, nil
+	}
+
 	if len(fields) < 3 {
 		return nil, fmt.Errorf("missing JSON payload")
 	}
@@ -124,14 +296,68 @@ func parseLineToRequest(line string) (*api.Request, error) {
 		return req, nil
 	}
 
-	q, err := query.Parse(jsonPayload)
+	objects, err := decodeObjects(jsonPayload)
 	if err != nil {
 		return nil, fmt.Errorf("invalid JSON query: %v", err)
 	}
-	req.Query = q.Conditions
+	req.Query = objects[0]
+	if cmd == "UPDATE" {
+		// UPDATE <collection> <query> <update>
+		if len(objects) != 2 {
+			return nil, fmt.Errorf("usage: UPDATE <collection> <query> <update>")
+		}
+		req.Update = objects[1]
+		return req, nil
+	}
+	if len(objects) > 1 {
+		// FIND <collection> <query> <projection> [{"sort": [...], "limit": n}]
+		req.Projection = objects[1]
+	}
+	if len(objects) > 2 {
+		if err := applyFindOptions(req, objects[2]); err != nil {
+			return nil, err
+		}
+	}
 	return req, nil
 }
 
+// applyFindOptions переносит sort и limit из json-объекта опций в запрос
+func applyFindOptions(req *api.Request, options map[string]any) error {
+	if sortSpec, ok := options["sort"].([]any); ok {
+		for _, field := range sortSpec {
+			name, ok := field.(string)
+			if !ok {
+				return fmt.Errorf("sort fields must be strings")
+			}
+			req.Sort = append(req.Sort, name)
+		}
+	}
+	if limit, ok := options["limit"].(float64); ok {
+		req.Limit = int(limit)
+	}
+	return nil
+}
+
+// decodeObjects разбирает несколько json-объектов, записанных подряд
+func decodeObjects(payload string) ([]map[string]any, error) {
+	decoder := json.NewDecoder(strings.NewReader(payload))
+	var objects []map[string]any
+	for {
+		var obj map[string]any
+		if err := decoder.Decode(&obj); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("empty payload")
+	}
+	return objects, nil
+}
+
 func printResponse(resp api.Response) {
 	if resp.Status == api.StatusError {
 		fmt.Printf("ERROR: %s\n", resp.Message)
@@ -139,6 +365,28 @@ func printResponse(resp api.Response) {
 	}
 
 	fmt.Printf("SUCCESS: %s (Count: %d)\n", resp.Message, resp.Count)
+	for _, warning := range resp.Warnings {
+		fmt.Printf("WARNING: %s\n", warning)
+	}
+
+	if len(resp.Values) > 0 {
+		output, err := json.MarshalIndent(resp.Values, "", "  ")
+		if err != nil {
+			fmt.Printf("Warning: Failed to format values: %v\n", err)
+		}
+		fmt.Println(string(output))
+	}
+
+	if len(resp.Events) > 0 {
+		output, err := json.MarshalIndent(resp.Events, "", "  ")
+		if err != nil {
+			fmt.Printf("Warning: Failed to format events: %v\n", err)
+		}
+		fmt.Println(string(output))
+	}
+	if resp.ResumeToken != "" {
+		fmt.Printf("Resume token: %s\n", resp.ResumeToken)
+	}
 
 	if len(resp.Data) > 0 {
 		output, err := json.MarshalIndent(resp.Data, "", "  ")
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"nosql_db/internal/config"
+	"nosql_db/internal/router"
+	"strings"
+)
+
+func main() {
+	log.Println("Starting NoSQLdb router...")
+	cfg := config.Load()
+	flag.StringVar(&cfg.RouterShards, "shards", cfg.RouterShards, "shard servers (host:port), comma separated; the first one holds unsharded collections")
+	flag.StringVar(&cfg.RouterMeta, "meta", cfg.RouterMeta, "file with the chunk map of sharded collections")
+	flag.Parse()
+
+	var shards []string
+	for _, addr := range strings.Split(cfg.RouterShards, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			shards = append(shards, addr)
+		}
+	}
+	r, err := router.New(shards, cfg.RouterMeta)
+	if err != nil {
+		log.Fatalf("cannot start router: %v", err)
+	}
+	if err := r.Run(cfg.Host + ":" + cfg.Port); err != nil {
+		log.Fatal(err)
+	}
+}
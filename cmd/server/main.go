@@ -1,18 +1,127 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"net"
+	"nosql_db/internal/cluster"
 	"nosql_db/internal/config"
+	"nosql_db/internal/raft"
+	"nosql_db/internal/replication"
+	"nosql_db/internal/resp"
 	"nosql_db/internal/server"
+	"nosql_db/internal/storage"
+	"slices"
+	"strings"
 )
 
 func main() {
 	log.Println("Starting NoSQLdb server...")
 	cfg := config.Load()
+	flag.StringVar(&cfg.ReplicaOf, "replica-of", cfg.ReplicaOf, "address of the primary (host:port): run as a read-only replica")
+	flag.StringVar(&cfg.ClusterAddr, "cluster-addr", cfg.ClusterAddr, "cluster address of this node (host:port): run as a Raft cluster member")
+	flag.StringVar(&cfg.ClusterPeers, "cluster-peers", cfg.ClusterPeers, "initial cluster members, comma separated; empty — wait for add_member")
+	flag.StringVar(&cfg.HTTPPort, "http-port", cfg.HTTPPort, "port of the REST gateway; empty — disabled")
+	flag.StringVar(&cfg.GRPCPort, "grpc-port", cfg.GRPCPort, "port of the gRPC service; empty — disabled")
+	flag.StringVar(&cfg.RESPPort, "resp-port", cfg.RESPPort, "port of the Redis-compatible (RESP) listener; empty — disabled")
+	flag.Parse()
+
+	if err := storage.GlobalManager.OpenDataDir(cfg.DataDir); err != nil {
+		log.Fatalf("cannot open data directory %s: %v", cfg.DataDir, err)
+	}
+	storage.GlobalManager.SetMemoryLimit(cfg.MemoryLimitMB << 20)
+	if cfg.Warmup != "" {
+		// сервер принимает запросы во время прогрева: они ждут загрузки своих коллекций
+		go storage.GlobalManager.WarmUp(cfg.Warmup, cfg.WarmupParallel)
+	}
 
 	srv := server.New(cfg.Host + ":" + cfg.Port)
+	if cfg.ClusterAddr != "" {
+		if cfg.ReplicaOf != "" {
+			log.Fatal("--replica-of and --cluster-addr cannot be used together")
+		}
+		srv.Cluster = startCluster(cfg)
+	} else {
+		node := replication.NewNode(storage.GlobalManager, cfg.ReplicaOf)
+		if cfg.ReplicaOf != "" {
+			log.Printf("running as a replica of %s", cfg.ReplicaOf)
+		}
+		node.Start()
+		srv.Replication = node
+	}
+
+	if cfg.HTTPPort != "" {
+		httpSrv := server.NewHTTP(cfg.Host + ":" + cfg.HTTPPort)
+		httpSrv.Node = srv.Node()
+		go func() {
+			if err := httpSrv.Run(); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+	if cfg.GRPCPort != "" {
+		grpcSrv := server.NewGRPC(cfg.Host + ":" + cfg.GRPCPort)
+		grpcSrv.Node = srv.Node()
+		go func() {
+			if err := grpcSrv.Run(); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	if cfg.RESPPort != "" {
+		respSrv := resp.NewServer(cfg.Host + ":" + cfg.RESPPort)
+		respSrv.Node = srv.Node()
+		go func() {
+			if err := respSrv.Run(); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
 
 	if err := srv.Run(); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// startCluster запускает узел кластера Raft: журнал и снимки в cfg.ClusterDir,
+// сообщения узлов — на cfg.ClusterAddr
+func startCluster(cfg *config.Config) *cluster.Node {
+	host, _, err := net.SplitHostPort(cfg.ClusterAddr)
+	if err != nil {
+		log.Fatalf("invalid cluster address %s: %v", cfg.ClusterAddr, err)
+	}
+	var peers []string
+	for _, peer := range strings.Split(cfg.ClusterPeers, ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			peers = append(peers, peer)
+		}
+	}
+
+	if len(peers) > 0 && !slices.Contains(peers, cfg.ClusterAddr) {
+		log.Fatalf("cluster peers %v must include this node's address %s", peers, cfg.ClusterAddr)
+	}
+
+	store, err := raft.NewFileStorage(cfg.ClusterDir)
+	if err != nil {
+		log.Fatalf("cannot open cluster directory %s: %v", cfg.ClusterDir, err)
+	}
+	transport := raft.NewTCPTransport()
+	if err := transport.Listen(cfg.ClusterAddr); err != nil {
+		log.Fatalf("cannot listen on cluster address %s: %v", cfg.ClusterAddr, err)
+	}
+	node, err := cluster.NewNode(storage.GlobalManager, raft.Config{
+		ID:        cfg.ClusterAddr,
+		Addr:      net.JoinHostPort(host, cfg.Port),
+		Peers:     peers,
+		Storage:   store,
+		Transport: transport,
+	})
+	if err != nil {
+		log.Fatalf("cannot start cluster node: %v", err)
+	}
+	go transport.Serve(node.Step)
+	node.Start()
+	log.Printf("running as cluster node %s, initial members: %v", cfg.ClusterAddr, peers)
+	return node
+}
@@ -1,27 +1,120 @@
 package api
 
 type Request struct {
-	Database string           `json:"database"`        // имя бд
-	Command  string           `json:"operation"`       // операция
-	Data     []map[string]any `json:"data,omitempty"`  // данные
-	Query    map[string]any   `json:"query,omitempty"` // условия поиска
+	Database   string           `json:"database"`             // имя бд; без collection — имя коллекции в текущей бд
+	Collection string           `json:"collection,omitempty"` // имя коллекции
+	Command    string           `json:"operation"`            // операция
+	Data       []map[string]any `json:"data,omitempty"`       // данные
+	Query      map[string]any   `json:"query,omitempty"`      // условия поиска
+	Update     map[string]any   `json:"update,omitempty"`     // операторы обновления ($set, $unset, $inc)
+	Projection map[string]any   `json:"projection,omitempty"` // возвращаемые поля (find)
+	Field      string           `json:"field,omitempty"`      // поле для distinct
+	Sort       []string         `json:"sort,omitempty"`       // поля сортировки, "-" — по убыванию
+	Limit      int              `json:"limit,omitempty"`      // максимум документов в ответе
+	Options    map[string]any   `json:"options,omitempty"`    // параметры команды (тип индекса и т.п.)
+	Durability string           `json:"durability,omitempty"` // гарантия записи: none, flushed (по умолчанию), fsynced
+	IDs        []string         `json:"ids,omitempty"`        // _id документов insert, выданные лидером кластера
 }
 
 type Response struct {
 	Status  string           `json:"status"`            // success или error
 	Message string           `json:"message,omitempty"` // сообщение, если есть ошибка
+	Code    string           `json:"code,omitempty"`    // вид ошибки (Code*); пусто — ошибка в запросе
 	Data    []map[string]any `json:"data,omitempty"`    // результат запроса
+	Values  []any            `json:"values,omitempty"`  // значения (distinct)
 	Count   int              `json:"count,omitempty"`   // количество документов
+	Matched int              `json:"matched,omitempty"` // документов под условием update (count — изменённых)
+
+	Warnings []string `json:"warnings,omitempty"` // предупреждения: нарушения схемы коллекции с уровнем warn
+
+	Events      []ChangeEvent `json:"events,omitempty"`      // события изменений (watch)
+	ResumeToken string        `json:"resumeToken,omitempty"` // позиция потока изменений после этого ответа
+
+	Tailable bool `json:"tailable,omitempty"` // ответ потокового курсора: tailable find или watch
+
+	Leader string `json:"leader,omitempty"` // адрес лидера кластера, если узел не принимает записи
+}
+
+// ChangeEvent — событие потока изменений: вставка, обновление или удаление документа
+type ChangeEvent struct {
+	Token         string         `json:"token"` // позиция после события для options.resumeAfter
+	Operation     string         `json:"operation"`
+	Database      string         `json:"database"`
+	Collection    string         `json:"collection"`
+	ID            string         `json:"_id"`
+	Document      map[string]any `json:"document,omitempty"`      // документ целиком (insert, update с fullDocument)
+	UpdatedFields map[string]any `json:"updatedFields,omitempty"` // изменённые поля (update)
+	RemovedFields []string       `json:"removedFields,omitempty"` // удалённые поля (update)
 }
 
+// типы индексов в options.type команды create_index
+const (
+	IndexTypeBTree  = "btree"
+	IndexTypeText   = "text"
+	IndexTypeGeo    = "geo"
+	IndexTypeVector = "vector"
+	IndexTypeHash   = "hash"
+)
+
 const (
 	StatusSuccess = "success"
 	StatusError   = "error"
 )
 
+// коды ошибок в Response.Code: клиенты и шлюзы различают ошибки по ним, а не по тексту.
+// Ошибка без кода — неверный запрос клиента
+const (
+	CodeNotFound      = "not_found"         // коллекция, база, индекс или документ не существует
+	CodeAlreadyExists = "already_exists"    // коллекция или индекс уже есть
+	CodeValidation    = "validation_failed" // документ не прошёл схему коллекции
+	CodeNotWritable   = "not_writable"      // узел не принимает записи: реплика или последователь кластера
+	CodeUnavailable   = "unavailable"       // узел или шард недоступен, повторите позже
+	CodeTimeout       = "timeout"           // операция не завершилась вовремя
+	CodeInternal      = "internal"          // сбой на стороне сервера: хранилище, ввод-вывод
+	CodeNotDurable    = "not_durable"       // запись применена и видна, но не сохранена на диск
+)
+
 const (
 	CmdInsert      = "insert"
 	CmdFind        = "find"
 	CmdDelete      = "delete"
 	CmdCreateIndex = "create_index"
+	CmdListIndexes = "list_indexes"
+	CmdCount       = "count"
+	CmdDistinct    = "distinct"
+	CmdUpdate      = "update"
+	CmdWatch       = "watch"
+
+	CmdCreateCollection = "create_collection"
+	CmdDropCollection   = "drop_collection"
+	CmdRenameCollection = "rename_collection"
+	CmdCollMod          = "coll_mod"
+
+	// базы данных
+	CmdUse             = "use"
+	CmdListDatabases   = "list_databases"
+	CmdListCollections = "list_collections"
+	CmdDropDatabase    = "drop_database"
+
+	// администрирование
+	CmdStats = "stats"
+
+	// репликация: поток журнала операций для secondary и состояние узла
+	CmdReplicate  = "replicate"
+	CmdReplStatus = "repl_status"
+
+	// кластер Raft: изменение состава, options.id — адрес узла в кластере
+	CmdAddMember    = "add_member"
+	CmdRemoveMember = "remove_member"
+
+	// шардирование: команды роутера (cmd/router)
+	CmdShardCollection = "shard_collection"
+	CmdSplitChunk      = "split_chunk"
+	CmdMoveChunk       = "move_chunk"
+	CmdShardingStatus  = "sharding_status"
+
+	// транзакции в рамках сессии соединения
+	CmdBegin  = "begin"
+	CmdCommit = "commit"
+	CmdAbort  = "abort"
 )
@@ -0,0 +1,271 @@
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/handlers"
+	"nosql_db/internal/raft"
+	"nosql_db/internal/storage"
+)
+
+// Узел кластера: записи клиентов проходят через журнал Raft и применяются
+// обработчиками на каждом узле по порядку журнала. Данные узла — машина
+// состояний Raft: при старте и установке снимка они заменяются снимком кластера
+
+// Command — запись журнала: одна операция или буфер транзакции
+type Command struct {
+	Requests   []api.Request `json:"requests"`
+	Tx         bool          `json:"tx,omitempty"`
+	Durability string        `json:"durability,omitempty"`
+}
+
+// snapshotCollection — коллекция в снимке кластера
+type snapshotCollection struct {
+	NS   storage.Namespace      `json:"ns"`
+	Spec storage.CollectionSpec `json:"spec"`
+	Docs []map[string]any       `json:"docs,omitempty"`
+}
+
+// ttlReapInterval — как часто лидер ищет истёкшие документы
+const ttlReapInterval = 10 * time.Second
+
+// Node — узел кластера поверх коллекций mng
+type Node struct {
+	mng  *storage.CollectionMng
+	raft *raft.Node
+	stop chan struct{}
+}
+
+// NewNode создаёт узел; машиной состояний Raft становятся коллекции mng.
+// Собственный TTL-reaper менеджера выключается: истёкшие документы удаляет лидер
+// записями журнала, иначе каждый узел удалял бы их в своё время и данные расходились бы
+func NewNode(mng *storage.CollectionMng, cfg raft.Config) (*Node, error) {
+	n := &Node{mng: mng, stop: make(chan struct{})}
+	cfg.StateMachine = n
+	node, err := raft.NewNode(cfg)
+	if err != nil {
+		return nil, err
+	}
+	n.raft = node
+	mng.DisableTTLReaper()
+	return n, nil
+}
+
+// Start запускает узел Raft
+func (n *Node) Start() {
+	n.raft.Start()
+	go n.ttlReaper()
+}
+
+// Stop останавливает узел Raft
+func (n *Node) Stop() {
+	close(n.stop)
+	n.raft.Stop()
+}
+
+func (n *Node) ttlReaper() {
+	ticker := time.NewTicker(ttlReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if n.raft.IsLeader() {
+				n.ReapExpired(time.Now())
+			}
+		case <-n.stop:
+			return
+		}
+	}
+}
+
+// ReapExpired удаляет истёкшие к now документы обычными записями delete по _id
+// через журнал; возвращает число документов в принятых записях
+func (n *Node) ReapExpired(now time.Time) int {
+	removed := 0
+	for ns, ids := range n.mng.ExpiredIDs(now) {
+		values := make([]any, len(ids))
+		for i, id := range ids {
+			values[i] = id
+		}
+		resp := n.Propose([]api.Request{{
+			Command:    api.CmdDelete,
+			Database:   ns.DB,
+			Collection: ns.Coll,
+			Query:      map[string]any{"_id": map[string]any{"$in": values}},
+		}}, false, "")
+		if resp.Status != api.StatusSuccess {
+			log.Printf("ttl: %s: %s", ns, resp.Message)
+			continue
+		}
+		log.Printf("ttl: removed %d expired document(s) from %s", len(ids), ns)
+		removed += len(ids)
+	}
+	return removed
+}
+
+// Step передаёт узлу сообщение транспорта
+func (n *Node) Step(msg raft.Message) {
+	n.raft.Step(msg)
+}
+
+// WriteError — записи принимает только лидер
+func (n *Node) WriteError() error {
+	if n.raft.IsLeader() {
+		return nil
+	}
+	return n.notLeader()
+}
+
+func (n *Node) notLeader() error {
+	if _, addr := n.raft.Leader(); addr != "" {
+		return fmt.Errorf("not the leader: send writes to %s", addr)
+	}
+	return errors.New("not the leader: no leader elected yet, retry later")
+}
+
+// Status возвращает состояние узла Raft
+func (n *Node) Status() map[string]any {
+	return n.raft.Status()
+}
+
+// Leader возвращает адрес лидера для клиентов
+func (n *Node) Leader() string {
+	_, addr := n.raft.Leader()
+	return addr
+}
+
+// Propose реплицирует запись; _id вставляемых документов выдаются до репликации,
+// чтобы все узлы вставили одни и те же документы (если их не выдал клиент, например роутер)
+func (n *Node) Propose(reqs []api.Request, tx bool, durability string) api.Response {
+	if _, err := storage.ParseDurability(durability); err != nil {
+		return api.Response{Status: api.StatusError, Message: err.Error()}
+	}
+	cmd := Command{Requests: make([]api.Request, len(reqs)), Tx: tx, Durability: durability}
+	for i, req := range reqs {
+		if req.Command == api.CmdInsert && len(req.IDs) == 0 {
+			req.IDs = make([]string, len(req.Data))
+			for j := range req.IDs {
+				req.IDs[j] = storage.NewID()
+			}
+		}
+		cmd.Requests[i] = req
+	}
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("encode command: %v", err), Code: api.CodeInternal}
+	}
+
+	value, err := n.raft.Propose(data)
+	if errors.Is(err, raft.ErrNotLeader) {
+		return api.Response{Status: api.StatusError, Message: n.notLeader().Error(), Code: api.CodeNotWritable, Leader: n.Leader()}
+	}
+	if err != nil {
+		return api.Response{Status: api.StatusError, Message: err.Error(), Code: raftErrorCode(err), Leader: n.Leader()}
+	}
+	return value.(api.Response)
+}
+
+// raftErrorCode — код ответа для ошибки узла Raft
+func raftErrorCode(err error) string {
+	switch {
+	case errors.Is(err, raft.ErrNotLeader), errors.Is(err, raft.ErrLeadershipLost):
+		return api.CodeNotWritable
+	case errors.Is(err, raft.ErrTimeout):
+		return api.CodeTimeout
+	case errors.Is(err, raft.ErrStopped), errors.Is(err, raft.ErrConfigPending):
+		return api.CodeUnavailable
+	}
+	return ""
+}
+
+// ChangeMembers добавляет или удаляет узел кластера
+func (n *Node) ChangeMembers(id string, add bool) api.Response {
+	if err := n.raft.ChangeMembers(id, add); err != nil {
+		resp := api.Response{Status: api.StatusError, Message: err.Error(), Code: raftErrorCode(err)}
+		if errors.Is(err, raft.ErrNotLeader) {
+			resp.Message, resp.Leader = n.notLeader().Error(), n.Leader()
+		}
+		return resp
+	}
+	action := "added to"
+	if !add {
+		action = "removed from"
+	}
+	return api.Response{Status: api.StatusSuccess, Message: fmt.Sprintf("Node '%s' %s the cluster", id, action)}
+}
+
+// Apply применяет запись журнала обработчиками запросов
+func (n *Node) Apply(entry raft.Entry) any {
+	var cmd Command
+	if err := json.Unmarshal(entry.Data, &cmd); err != nil || len(cmd.Requests) == 0 {
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("malformed log entry %d", entry.Index), Code: api.CodeInternal}
+	}
+	if cmd.Tx {
+		durability, _ := storage.ParseDurability(cmd.Durability)
+		return handlers.CommitTransaction(n.mng, cmd.Requests, durability)
+	}
+	req := cmd.Requests[0]
+	req.Durability = cmd.Durability
+	return handlers.HandleRequest(n.mng, req)
+}
+
+// Snapshot сохраняет все коллекции: параметры, индексы и документы
+func (n *Node) Snapshot() ([]byte, error) {
+	dbs, err := n.mng.ListDatabases()
+	if err != nil {
+		return nil, err
+	}
+	var collections []snapshotCollection
+	for _, db := range dbs {
+		infos, err := n.mng.ListCollections(db)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range infos {
+			ns := storage.Namespace{DB: db, Coll: info.Name}
+			coll, err := n.mng.GetCollection(ns)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load collection '%s': %w", ns, err)
+			}
+			snap := coll.Snapshot()
+			collections = append(collections, snapshotCollection{NS: ns, Spec: coll.Spec(), Docs: snap.All()})
+			snap.Release()
+			coll.Release()
+		}
+	}
+	return json.Marshal(collections)
+}
+
+// Restore заменяет данные узла снимком кластера
+func (n *Node) Restore(data []byte) error {
+	if err := n.mng.DropAll(); err != nil {
+		return err
+	}
+	if data == nil {
+		return nil
+	}
+	var collections []snapshotCollection
+	if err := json.Unmarshal(data, &collections); err != nil {
+		return fmt.Errorf("decode cluster snapshot: %w", err)
+	}
+	documents := 0
+	for _, c := range collections {
+		if err := n.mng.CreateCollectionFromSpec(c.NS, c.Spec); err != nil {
+			return fmt.Errorf("restore collection '%s': %w", c.NS, err)
+		}
+		if err := n.mng.CreateIndexes(c.NS, c.Spec.Indexes); err != nil {
+			return fmt.Errorf("restore indexes of '%s': %w", c.NS, err)
+		}
+		if err := n.mng.ApplyChanges(storage.DocumentEvents(c.NS, c.Docs)); err != nil {
+			return fmt.Errorf("restore documents of '%s': %w", c.NS, err)
+		}
+		documents += len(c.Docs)
+	}
+	log.Printf("cluster: restored %d collection(s), %d document(s) from snapshot", len(collections), documents)
+	return nil
+}
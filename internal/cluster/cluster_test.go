@@ -0,0 +1,220 @@
+package cluster_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/cluster"
+	"nosql_db/internal/raft"
+	"nosql_db/internal/server"
+	"nosql_db/internal/storage"
+	"nosql_db/internal/testutil"
+)
+
+type testNode struct {
+	id   string
+	node *cluster.Node
+	srv  *server.TCPServer
+	mng  *storage.CollectionMng
+}
+
+// startNode запускает узел кластера с сервером на свободном порту loopback;
+// узлы обмениваются сообщениями Raft через сеть в памяти
+func startNode(t *testing.T, network *raft.Network, id string, peers []string) *testNode {
+	t.Helper()
+	mng := testutil.NewManager(t)
+	var node *cluster.Node
+	srv := testutil.StartServer(t, mng, func(srv *server.TCPServer) {
+		var err error
+		node, err = cluster.NewNode(mng, raft.Config{
+			ID: id, Addr: srv.Addr(), Peers: peers,
+			ElectionTimeout:   100 * time.Millisecond,
+			SnapshotThreshold: 5,
+			Storage:           raft.NewMemoryStorage(),
+			Transport:         network,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		network.Register(id, node.Step)
+		node.Start()
+		t.Cleanup(func() {
+			network.Unregister(id)
+			node.Stop()
+		})
+		srv.Cluster = node
+	})
+	return &testNode{id: id, node: node, srv: srv, mng: mng}
+}
+
+// ids возвращает отсортированные _id документов коллекции
+func ids(c *testutil.Client, coll string) []string {
+	resp := c.OK(api.Request{Command: api.CmdFind, Database: "shop", Collection: coll})
+	var found []string
+	for _, doc := range resp.Data {
+		found = append(found, doc["_id"].(string))
+	}
+	slices.Sort(found)
+	return found
+}
+
+func waitLeader(t *testing.T, nodes []*testNode) *testNode {
+	t.Helper()
+	var leader *testNode
+	testutil.WaitFor(t, "leader election", func() bool {
+		for _, n := range nodes {
+			if n.node.WriteError() == nil {
+				leader = n
+				return true
+			}
+		}
+		return false
+	})
+	return leader
+}
+
+func TestClusterWritesAndRedirect(t *testing.T) {
+	network := raft.NewNetwork()
+	peers := []string{"n1", "n2", "n3"}
+	var nodes []*testNode
+	for _, id := range peers {
+		nodes = append(nodes, startNode(t, network, id, peers))
+	}
+	leader := waitLeader(t, nodes)
+	var follower *testNode
+	for _, n := range nodes {
+		if n != leader {
+			follower = n
+			break
+		}
+	}
+
+	// последователь отклоняет запись и называет адрес лидера
+	f := testutil.Dial(t, follower.srv.Addr())
+	testutil.WaitFor(t, "leader address on follower", func() bool { return follower.node.Leader() == leader.srv.Addr() })
+	resp := f.Do(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "items", Data: []map[string]any{{"sku": "x"}}})
+	if resp.Status != api.StatusError || resp.Leader != leader.srv.Addr() || !strings.Contains(resp.Message, "not the leader") {
+		t.Fatalf("insert on follower: %+v", resp)
+	}
+
+	l := testutil.Dial(t, leader.srv.Addr())
+	l.OK(api.Request{Command: api.CmdCreateIndex, Database: "shop", Collection: "items", Query: map[string]any{"sku": 1}})
+	l.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "items", Data: []map[string]any{
+		{"sku": "a1", "qty": 1.0}, {"sku": "b2", "qty": 2.0},
+	}})
+	l.OK(api.Request{Command: api.CmdBegin})
+	l.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "items", Data: []map[string]any{{"sku": "c3", "qty": 3.0}}})
+	l.OK(api.Request{Command: api.CmdUpdate, Database: "shop", Collection: "items",
+		Query: map[string]any{"sku": "a1"}, Update: map[string]any{"$set": map[string]any{"qty": 10.0}}})
+	l.OK(api.Request{Command: api.CmdCommit})
+
+	// все узлы применяют те же записи с теми же _id
+	want := ids(l, "items")
+	if len(want) != 3 {
+		t.Fatalf("leader has %d documents, want 3", len(want))
+	}
+	for _, n := range nodes {
+		c := testutil.Dial(t, n.srv.Addr())
+		testutil.WaitFor(t, "replicated documents on "+n.id, func() bool { return slices.Equal(ids(c, "items"), want) })
+		found := c.OK(api.Request{Command: api.CmdFind, Database: "shop", Collection: "items", Query: map[string]any{"sku": "a1"}})
+		if len(found.Data) != 1 || found.Data[0]["qty"] != 10.0 {
+			t.Fatalf("%s: find a1: %+v", n.id, found.Data)
+		}
+	}
+
+	// лидер отрезан: новый лидер продолжает принимать записи, старый догоняет после восстановления
+	var rest []string
+	var restNodes []*testNode
+	for _, n := range nodes {
+		if n != leader {
+			rest = append(rest, n.id)
+			restNodes = append(restNodes, n)
+		}
+	}
+	network.Partition(rest, []string{leader.id})
+	next := waitLeader(t, restNodes)
+	testutil.Dial(t, next.srv.Addr()).OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "items",
+		Data: []map[string]any{{"sku": "d4"}}})
+	network.Heal()
+	testutil.WaitFor(t, "old leader catch up", func() bool { return len(ids(l, "items")) == 4 })
+}
+
+func TestClusterAddMember(t *testing.T) {
+	network := raft.NewNetwork()
+	peers := []string{"n1", "n2", "n3"}
+	var nodes []*testNode
+	for _, id := range peers {
+		nodes = append(nodes, startNode(t, network, id, peers))
+	}
+	l := testutil.Dial(t, waitLeader(t, nodes).srv.Addr())
+	l.OK(api.Request{Command: api.CmdCreateCollection, Database: "shop", Collection: "log", Options: map[string]any{
+		"capped": true, "max": 3.0,
+	}})
+	for i := 0; i < 10; i++ {
+		l.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "items", Data: []map[string]any{{"n": float64(i)}}})
+		l.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "log", Data: []map[string]any{{"n": float64(i)}}})
+	}
+
+	// новый узел получает данные снимком: журнал лидера уже сжат
+	joined := startNode(t, network, "n4", nil)
+	l.OK(api.Request{Command: api.CmdAddMember, Options: map[string]any{"id": "n4"}})
+	j := testutil.Dial(t, joined.srv.Addr())
+	testutil.WaitFor(t, "snapshot on the new node", func() bool {
+		return slices.Equal(ids(j, "items"), ids(l, "items")) && slices.Equal(ids(j, "log"), ids(l, "log"))
+	})
+
+	status := l.OK(api.Request{Command: api.CmdReplStatus}).Data[0]
+	if members := status["members"].([]any); len(members) != 4 {
+		t.Fatalf("members after add_member: %v", members)
+	}
+	l.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "log", Data: []map[string]any{{"n": 10.0}}})
+	testutil.WaitFor(t, "capped order on the new node", func() bool { return slices.Equal(ids(j, "log"), ids(l, "log")) })
+}
+
+// stored — число документов коллекции узла, включая истёкшие, но не удалённые
+func stored(t *testing.T, n *testNode, coll string) int {
+	t.Helper()
+	c, err := n.mng.GetCollection(storage.Namespace{DB: "shop", Coll: coll})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Release()
+	return c.Count()
+}
+
+func TestClusterTTL(t *testing.T) {
+	network := raft.NewNetwork()
+	peers := []string{"n1", "n2", "n3"}
+	var nodes []*testNode
+	for _, id := range peers {
+		nodes = append(nodes, startNode(t, network, id, peers))
+	}
+	leader := waitLeader(t, nodes)
+	l := testutil.Dial(t, leader.srv.Addr())
+	l.OK(api.Request{Command: api.CmdCreateIndex, Database: "shop", Collection: "sessions", Query: map[string]any{"at": 1},
+		Options: map[string]any{"expireAfterSeconds": 0.0}})
+	now := time.Now()
+	l.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "sessions", Data: []map[string]any{
+		{"at": float64(now.Add(-time.Hour).Unix())}, {"at": float64(now.Add(time.Hour).Unix())},
+	}})
+	for _, n := range nodes {
+		testutil.WaitFor(t, "replicated sessions on "+n.id, func() bool { return stored(t, n, "sessions") == 2 })
+	}
+
+	// последователь не удаляет истёкшие документы: удаление — запись журнала, её принимает только лидер
+	for _, n := range nodes {
+		if n != leader && n.node.ReapExpired(now) != 0 {
+			t.Fatalf("follower %s reaped expired documents", n.id)
+		}
+	}
+	// лидер удаляет их записью журнала, и её применяют все узлы
+	if removed := leader.node.ReapExpired(now); removed != 1 {
+		t.Fatalf("leader reaped %d document(s)", removed)
+	}
+	for _, n := range nodes {
+		testutil.WaitFor(t, "reaped sessions on "+n.id, func() bool { return stored(t, n, "sessions") == 1 })
+	}
+}
@@ -8,6 +8,33 @@ import (
 type Config struct {
 	Host string `env:"DB_HOST" env-default:""`
 	Port string `env:"DB_PORT" env-default:"8080"`
+	// порт REST-шлюза рядом с TCP-протоколом; пусто — шлюз выключен; флаг --http-port
+	HTTPPort string `env:"DB_HTTP_PORT" env-default:""`
+	// порт сервиса gRPC (proto/nosqldb.proto); пусто — выключен; флаг --grpc-port
+	GRPCPort string `env:"DB_GRPC_PORT" env-default:""`
+	// порт протокола Redis (RESP) для redis-cli; пусто — выключен; флаг --resp-port
+	RESPPort string `env:"DB_RESP_PORT" env-default:""`
+	// каталог данных: <DataDir>/<база>/<коллекция>/
+	DataDir string `env:"DB_DATA_DIR" env-default:"data"`
+	// лимит памяти открытых коллекций в мегабайтах, 0 — без лимита
+	MemoryLimitMB int64 `env:"DB_MEMORY_LIMIT_MB" env-default:"0"`
+	// коллекции, загружаемые при старте: "база.коллекция", "база.*" через запятую
+	Warmup         string `env:"DB_WARMUP" env-default:""`
+	WarmupParallel int    `env:"DB_WARMUP_PARALLEL" env-default:"4"`
+	// адрес primary ("host:port"): узел становится read-only репликой; флаг --replica-of
+	ReplicaOf string `env:"DB_REPLICA_OF" env-default:""`
+	// адрес узла в кластере Raft ("host:port"): включает режим кластера; флаг --cluster-addr.
+	// Клиентам узел называет адрес лидера как хост этого адреса и Port
+	ClusterAddr string `env:"DB_CLUSTER_ADDR" env-default:""`
+	// начальный состав кластера через запятую, включая ClusterAddr; флаг --cluster-peers.
+	// Пусто — узел ждёт, пока лидер добавит его командой add_member
+	ClusterPeers string `env:"DB_CLUSTER_PEERS" env-default:""`
+	// каталог журнала и снимков Raft
+	ClusterDir string `env:"DB_CLUSTER_DIR" env-default:"raft"`
+	// роутер (cmd/router): адреса шардов через запятую, первый — основной; флаг --shards
+	RouterShards string `env:"DB_ROUTER_SHARDS" env-default:""`
+	// файл карты распределённых коллекций роутера; флаг --meta
+	RouterMeta string `env:"DB_ROUTER_META" env-default:"router.json"`
 }
 
 func Load() *Config {
@@ -0,0 +1,74 @@
+package fulltext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStem(t *testing.T) {
+	cases := map[string]string{
+		// английский — алгоритм Портера
+		"caresses":        "caress",
+		"ponies":          "poni",
+		"running":         "run",
+		"connected":       "connect",
+		"relational":      "relat",
+		"generalizations": "gener",
+		// русский — Snowball
+		"книги":            "книг",
+		"красивая":         "красив",
+		"бегущий":          "бегущ",
+		"машинами":         "машин",
+		"программирование": "программирован",
+		"нежность":         "нежност",
+	}
+	for word, want := range cases {
+		if got := Stem(word); got != want {
+			t.Errorf("Stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("The QUICK brown foxes are running, и в лесу бегают волки!")
+	want := []string{"quick", "brown", "fox", "run", "лес", "бега", "волк"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Tokenize: got %v, want %v", got, want)
+	}
+	if terms := Tokenize("the and или"); len(terms) != 0 {
+		t.Fatalf("stop words must be dropped: %v", terms)
+	}
+}
+
+func TestSearchRanking(t *testing.T) {
+	idx := NewIndex([]string{"name", "tags"})
+	idx.Add("1", map[string]any{"name": "Running shoes", "tags": []any{"sport", "run"}})
+	idx.Add("2", map[string]any{"name": "Shoes for the office"})
+	idx.Add("3", map[string]any{"name": "A long guide to running a marathon and running daily"})
+	idx.Add("4", map[string]any{"name": "Красные кроссовки для бега"})
+
+	scores := idx.Search("run")
+	if len(scores) != 2 || scores["1"] <= scores["3"] {
+		// у документа 1 терм встречается дважды в коротком тексте, у 3 — дважды в длинном
+		t.Fatalf("BM25 scores: %v", scores)
+	}
+	if scores := idx.Search("shoes -office"); len(scores) != 1 || scores["1"] == 0 {
+		t.Fatalf("excluded term: %v", scores)
+	}
+	if scores := idx.Search("кроссовками"); len(scores) != 1 || scores["4"] == 0 {
+		t.Fatalf("russian stems: %v", scores)
+	}
+	if scores := idx.Search("the"); len(scores) != 0 {
+		t.Fatalf("stop word query: %v", scores)
+	}
+
+	// удаление и восстановление из сохранённых постингов дают тот же индекс
+	idx.Remove("1", map[string]any{"name": "Running shoes", "tags": []any{"sport", "run"}})
+	if scores := idx.Search("run"); len(scores) != 1 || scores["3"] == 0 {
+		t.Fatalf("after remove: %v", scores)
+	}
+	restored := Restore(idx.Fields(), idx.Postings(), idx.Lengths())
+	if got, want := restored.Search("running marathon"), idx.Search("running marathon"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored index: %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,162 @@
+package fulltext
+
+import (
+	"math"
+	"strings"
+)
+
+// параметры ранжирования BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Index — инвертированный индекс по текстовым полям документов
+type Index struct {
+	fields   []string
+	postings map[string]map[string]int // терм -> id документа -> частота терма
+	lengths  map[string]int            // id документа -> количество термов
+	total    int                       // сумма длин всех документов
+}
+
+// NewIndex создаёт пустой текстовый индекс по полям
+func NewIndex(fields []string) *Index {
+	return &Index{
+		fields:   fields,
+		postings: make(map[string]map[string]int),
+		lengths:  make(map[string]int),
+	}
+}
+
+// Restore восстанавливает индекс из сохранённых постингов и длин документов
+func Restore(fields []string, postings map[string]map[string]int, lengths map[string]int) *Index {
+	idx := NewIndex(fields)
+	if postings != nil {
+		idx.postings = postings
+	}
+	if lengths != nil {
+		idx.lengths = lengths
+	}
+	for _, l := range idx.lengths {
+		idx.total += l
+	}
+	return idx
+}
+
+// Fields возвращает индексируемые поля
+func (idx *Index) Fields() []string {
+	return idx.fields
+}
+
+// Postings возвращает постинги индекса (для сериализации)
+func (idx *Index) Postings() map[string]map[string]int {
+	return idx.postings
+}
+
+// Lengths возвращает длины документов (для сериализации)
+func (idx *Index) Lengths() map[string]int {
+	return idx.lengths
+}
+
+// Add индексирует текстовые поля документа
+func (idx *Index) Add(docID string, doc map[string]any) {
+	terms := Tokenize(idx.documentText(doc))
+	if len(terms) == 0 {
+		return
+	}
+	for _, term := range terms {
+		docs, ok := idx.postings[term]
+		if !ok {
+			docs = make(map[string]int)
+			idx.postings[term] = docs
+		}
+		docs[docID]++
+	}
+	idx.lengths[docID] = len(terms)
+	idx.total += len(terms)
+}
+
+// Remove удаляет документ из индекса
+func (idx *Index) Remove(docID string, doc map[string]any) {
+	if _, ok := idx.lengths[docID]; !ok {
+		return
+	}
+	for _, term := range Tokenize(idx.documentText(doc)) {
+		docs, ok := idx.postings[term]
+		if !ok {
+			continue
+		}
+		delete(docs, docID)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	idx.total -= idx.lengths[docID]
+	delete(idx.lengths, docID)
+}
+
+// Search ищет документы по строке запроса и возвращает их релевантность.
+// Документ подходит, если содержит хотя бы один терм запроса;
+// слова с префиксом "-" исключают документы, в которых встречаются
+func (idx *Index) Search(search string) map[string]float64 {
+	var include, exclude []string
+	for _, word := range strings.Fields(search) {
+		if strings.HasPrefix(word, "-") {
+			exclude = append(exclude, Tokenize(word[1:])...)
+			continue
+		}
+		include = append(include, Tokenize(word)...)
+	}
+
+	scores := make(map[string]float64)
+	n := float64(len(idx.lengths))
+	if n == 0 {
+		return scores
+	}
+	avgLen := float64(idx.total) / n
+
+	seen := make(map[string]struct{}, len(include))
+	for _, term := range include {
+		if _, dup := seen[term]; dup {
+			continue
+		}
+		seen[term] = struct{}{}
+
+		docs := idx.postings[term]
+		df := float64(len(docs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for docID, tf := range docs {
+			f := float64(tf)
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(idx.lengths[docID])/avgLen)
+			scores[docID] += idf * f * (bm25K1 + 1) / (f + norm)
+		}
+	}
+
+	for _, term := range exclude {
+		for docID := range idx.postings[term] {
+			delete(scores, docID)
+		}
+	}
+	return scores
+}
+
+// documentText собирает текст индексируемых полей документа; массивы строк тоже учитываются
+func (idx *Index) documentText(doc map[string]any) string {
+	var sb strings.Builder
+	for _, field := range idx.fields {
+		appendText(&sb, doc[field])
+	}
+	return sb.String()
+}
+
+func appendText(sb *strings.Builder, value any) {
+	switch v := value.(type) {
+	case string:
+		sb.WriteString(v)
+		sb.WriteByte(' ')
+	case []any:
+		for _, item := range v {
+			appendText(sb, item)
+		}
+	}
+}
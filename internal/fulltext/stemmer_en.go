@@ -0,0 +1,262 @@
+package fulltext
+
+// stemEnglish — стеммер Портера для английских слов
+func stemEnglish(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			// цифры и прочие символы оставляем как есть
+			return word
+		}
+	}
+
+	s := &porter{b: []byte(word)}
+	s.step1ab()
+	if len(s.b) > 1 {
+		s.step1c()
+		s.step2()
+		s.step3()
+		s.step4()
+		s.step5()
+	}
+	return string(s.b)
+}
+
+// porter хранит текущее слово b и границу основы j (b[0..j])
+type porter struct {
+	b []byte
+	j int
+}
+
+// cons — true, если b[i] согласная
+func (s *porter) cons(i int) bool {
+	switch s.b[i] {
+	case 'a', 'e', 'i', 'o', 'u':
+		return false
+	case 'y':
+		return i == 0 || !s.cons(i-1)
+	default:
+		return true
+	}
+}
+
+// m считает количество последовательностей "гласные-согласные" в b[0..j]
+func (s *porter) m() int {
+	n, i := 0, 0
+	for {
+		if i > s.j {
+			return n
+		}
+		if !s.cons(i) {
+			break
+		}
+		i++
+	}
+	i++
+	for {
+		for {
+			if i > s.j {
+				return n
+			}
+			if s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+		n++
+		for {
+			if i > s.j {
+				return n
+			}
+			if !s.cons(i) {
+				break
+			}
+			i++
+		}
+		i++
+	}
+}
+
+// vowelInStem — true, если в b[0..j] есть гласная
+func (s *porter) vowelInStem() bool {
+	for i := 0; i <= s.j; i++ {
+		if !s.cons(i) {
+			return true
+		}
+	}
+	return false
+}
+
+// doubleC — true, если b[i-1..i] двойная согласная
+func (s *porter) doubleC(i int) bool {
+	if i < 1 || s.b[i] != s.b[i-1] {
+		return false
+	}
+	return s.cons(i)
+}
+
+// cvc — true, если b[i-2..i] имеет вид согласная-гласная-согласная и последняя не w, x, y
+func (s *porter) cvc(i int) bool {
+	if i < 2 || !s.cons(i) || s.cons(i-1) || !s.cons(i-2) {
+		return false
+	}
+	ch := s.b[i]
+	return ch != 'w' && ch != 'x' && ch != 'y'
+}
+
+// ends проверяет окончание слова и выставляет j перед ним
+func (s *porter) ends(suffix string) bool {
+	if len(suffix) > len(s.b) || string(s.b[len(s.b)-len(suffix):]) != suffix {
+		return false
+	}
+	s.j = len(s.b) - len(suffix) - 1
+	return true
+}
+
+// setTo заменяет b[j+1..] на replacement
+func (s *porter) setTo(replacement string) {
+	s.b = append(s.b[:s.j+1], replacement...)
+}
+
+// replace заменяет окончание, если основа достаточно длинная
+func (s *porter) replace(replacement string) {
+	if s.m() > 0 {
+		s.setTo(replacement)
+	}
+}
+
+func (s *porter) last() int {
+	return len(s.b) - 1
+}
+
+// step1ab убирает множественное число и окончания -ed, -ing
+func (s *porter) step1ab() {
+	if s.b[s.last()] == 's' {
+		switch {
+		case s.ends("sses"):
+			s.b = s.b[:len(s.b)-2]
+		case s.ends("ies"):
+			s.setTo("i")
+		case s.b[s.last()-1] != 's':
+			s.b = s.b[:len(s.b)-1]
+		}
+	}
+
+	if s.ends("eed") {
+		if s.m() > 0 {
+			s.b = s.b[:len(s.b)-1]
+		}
+		return
+	}
+
+	if (s.ends("ed") || s.ends("ing")) && s.vowelInStem() {
+		s.b = s.b[:s.j+1]
+		switch {
+		case s.ends("at"):
+			s.setTo("ate")
+		case s.ends("bl"):
+			s.setTo("ble")
+		case s.ends("iz"):
+			s.setTo("ize")
+		case s.doubleC(s.last()):
+			if ch := s.b[s.last()]; ch != 'l' && ch != 's' && ch != 'z' {
+				s.b = s.b[:len(s.b)-1]
+			}
+		default:
+			s.j = s.last()
+			if s.m() == 1 && s.cvc(s.last()) {
+				s.b = append(s.b, 'e')
+			}
+		}
+	}
+}
+
+// step1c заменяет конечную y на i, если в основе есть гласная
+func (s *porter) step1c() {
+	if s.ends("y") && s.vowelInStem() {
+		s.b[s.last()] = 'i'
+	}
+}
+
+// porterRule — замена суффикса suffix на replacement
+type porterRule struct {
+	suffix      string
+	replacement string
+}
+
+var step2Rules = []porterRule{
+	{"ational", "ate"}, {"tional", "tion"}, {"enci", "ence"}, {"anci", "ance"},
+	{"izer", "ize"}, {"bli", "ble"}, {"alli", "al"}, {"entli", "ent"}, {"eli", "e"},
+	{"ousli", "ous"}, {"ization", "ize"}, {"ation", "ate"}, {"ator", "ate"},
+	{"alism", "al"}, {"iveness", "ive"}, {"fulness", "ful"}, {"ousness", "ous"},
+	{"aliti", "al"}, {"iviti", "ive"}, {"biliti", "ble"}, {"logi", "log"},
+}
+
+var step3Rules = []porterRule{
+	{"icate", "ic"}, {"ative", ""}, {"alize", "al"}, {"iciti", "ic"},
+	{"ical", "ic"}, {"ful", ""}, {"ness", ""},
+}
+
+var step4Suffixes = []string{
+	"al", "ance", "ence", "er", "ic", "able", "ible", "ant", "ement", "ment", "ent",
+	"ion", "ou", "ism", "ate", "iti", "ous", "ive", "ize",
+}
+
+// applyRules применяет первое подходящее правило
+func (s *porter) applyRules(rules []porterRule) {
+	for _, rule := range rules {
+		if s.ends(rule.suffix) {
+			s.replace(rule.replacement)
+			return
+		}
+	}
+}
+
+// step2 сводит двойные суффиксы к одинарным (-ization -> -ize)
+func (s *porter) step2() {
+	if len(s.b) < 2 {
+		return
+	}
+	s.applyRules(step2Rules)
+}
+
+// step3 обрабатывает -ic-, -full, -ness и подобные
+func (s *porter) step3() {
+	s.applyRules(step3Rules)
+}
+
+// step4 убирает суффиксы -ant, -ence и т.п. при m > 1
+func (s *porter) step4() {
+	if len(s.b) < 2 {
+		return
+	}
+	for _, suffix := range step4Suffixes {
+		if !s.ends(suffix) {
+			continue
+		}
+		if suffix == "ion" && (s.j < 0 || (s.b[s.j] != 's' && s.b[s.j] != 't')) {
+			continue
+		}
+		if s.m() > 1 {
+			s.b = s.b[:s.j+1]
+		}
+		return
+	}
+}
+
+// step5 убирает конечную -e и двойную -ll при m > 1
+func (s *porter) step5() {
+	s.j = s.last()
+	if s.b[s.last()] == 'e' {
+		a := s.m()
+		if a > 1 || (a == 1 && !s.cvc(s.last()-1)) {
+			s.b = s.b[:len(s.b)-1]
+		}
+	}
+	if s.b[s.last()] == 'l' && s.doubleC(s.last()) && s.m() > 1 {
+		s.b = s.b[:len(s.b)-1]
+	}
+}
@@ -0,0 +1,156 @@
+package fulltext
+
+import (
+	"sort"
+	"strings"
+)
+
+// группы окончаний русского стеммера Snowball.
+// Окончания "после а/я" удаляются только если перед ними стоит а или я
+var (
+	ruPerfectiveGerundAfterA = ruEndings("в", "вши", "вшись")
+	ruPerfectiveGerund       = ruEndings("ив", "ивши", "ившись", "ыв", "ывши", "ывшись")
+	ruAdjective              = ruEndings("ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею")
+	ruParticipleAfterA = ruEndings("ем", "нн", "вш", "ющ", "щ")
+	ruParticiple       = ruEndings("ивш", "ывш", "ующ")
+	ruReflexive        = ruEndings("ся", "сь")
+	ruVerbAfterA       = ruEndings("ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно")
+	ruVerb             = ruEndings("ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен",
+		"ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю")
+	ruNoun = ruEndings("а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й",
+		"иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я")
+	ruSuperlative  = ruEndings("ейш", "ейше")
+	ruDerivational = ruEndings("ост", "ость")
+	ruVowels       = "аеиоуыэюя"
+)
+
+// ruEndings переводит окончания в руны и сортирует по убыванию длины,
+// чтобы всегда находилось самое длинное совпадение
+func ruEndings(endings ...string) [][]rune {
+	result := make([][]rune, len(endings))
+	for i, e := range endings {
+		result[i] = []rune(e)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return len(result[i]) > len(result[j])
+	})
+	return result
+}
+
+// stemRussian — русский стеммер Snowball (Портера)
+func stemRussian(word string) string {
+	w := []rune(strings.ReplaceAll(word, "ё", "е"))
+	rv, r2 := ruRegions(w)
+	if rv >= len(w) {
+		return string(w)
+	}
+
+	// шаг 1: деепричастия, иначе возвратные частицы и
+	// окончания прилагательных/причастий, глаголов или существительных
+	if n := ruLongestEnding(w, rv, ruPerfectiveGerundAfterA, ruPerfectiveGerund); n > 0 {
+		w = w[:len(w)-n]
+	} else {
+		if n := ruMatch(w, rv, ruReflexive, false); n > 0 {
+			w = w[:len(w)-n]
+		}
+		if n := ruMatch(w, rv, ruAdjective, false); n > 0 {
+			w = w[:len(w)-n]
+			if n := ruLongestEnding(w, rv, ruParticipleAfterA, ruParticiple); n > 0 {
+				w = w[:len(w)-n]
+			}
+		} else if n := ruLongestEnding(w, rv, ruVerbAfterA, ruVerb); n > 0 {
+			w = w[:len(w)-n]
+		} else if n := ruMatch(w, rv, ruNoun, false); n > 0 {
+			w = w[:len(w)-n]
+		}
+	}
+
+	// шаг 2: конечная и
+	if len(w) > rv && w[len(w)-1] == 'и' {
+		w = w[:len(w)-1]
+	}
+
+	// шаг 3: словообразовательные суффиксы в R2
+	if n := ruMatch(w, r2, ruDerivational, false); n > 0 {
+		w = w[:len(w)-n]
+	}
+
+	// шаг 4: нн -> н, превосходная степень, мягкий знак
+	if n := ruMatch(w, rv, ruSuperlative, false); n > 0 {
+		w = w[:len(w)-n]
+	}
+	if ruHasSuffix(w, rv, []rune("нн")) {
+		w = w[:len(w)-1]
+	} else if len(w) > rv && w[len(w)-1] == 'ь' {
+		w = w[:len(w)-1]
+	}
+
+	return string(w)
+}
+
+// ruRegions вычисляет начала областей RV и R2
+func ruRegions(w []rune) (rv, r2 int) {
+	rv, r1 := len(w), len(w)
+	for i, r := range w {
+		if ruIsVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+	r1 = ruNextRegion(w, 0)
+	r2 = ruNextRegion(w, r1)
+	return rv, r2
+}
+
+// ruNextRegion находит позицию после первой согласной, следующей за гласной, начиная с from
+func ruNextRegion(w []rune, from int) int {
+	for i := from + 1; i < len(w); i++ {
+		if !ruIsVowel(w[i]) && ruIsVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+func ruIsVowel(r rune) bool {
+	return strings.ContainsRune(ruVowels, r)
+}
+
+// ruLongestEnding выбирает самое длинное окончание из двух групп:
+// "после а/я" и обычной
+func ruLongestEnding(w []rune, region int, afterA, plain [][]rune) int {
+	return max(ruMatch(w, region, afterA, true), ruMatch(w, region, plain, false))
+}
+
+// ruMatch возвращает длину самого длинного окончания из endings внутри области region;
+// при afterA перед окончанием должна стоять а или я (тоже внутри области)
+func ruMatch(w []rune, region int, endings [][]rune, afterA bool) int {
+	for _, e := range endings {
+		if !ruHasSuffix(w, region, e) {
+			continue
+		}
+		if afterA {
+			pos := len(w) - len(e) - 1
+			if pos < region || (w[pos] != 'а' && w[pos] != 'я') {
+				continue
+			}
+		}
+		return len(e)
+	}
+	return 0
+}
+
+// ruHasSuffix проверяет, что слово оканчивается на suffix и окончание лежит в области region
+func ruHasSuffix(w []rune, region int, suffix []rune) bool {
+	start := len(w) - len(suffix)
+	if start < region || start < 0 {
+		return false
+	}
+	for i, r := range suffix {
+		if w[start+i] != r {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,38 @@
+package fulltext
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Tokenize разбивает текст на термы: слова в нижнем регистре
+// без стоп-слов, приведённые к основе стеммером своего языка
+func Tokenize(text string) []string {
+	var terms []string
+	for _, word := range splitWords(text) {
+		if IsStopWord(word) {
+			continue
+		}
+		if term := Stem(word); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// splitWords выделяет последовательности букв и цифр и приводит их к нижнему регистру
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Stem выбирает стеммер по алфавиту слова: кириллица — русский, иначе английский
+func Stem(word string) string {
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return stemRussian(word)
+		}
+	}
+	return stemEnglish(word)
+}
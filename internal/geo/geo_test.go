@@ -0,0 +1,171 @@
+package geo
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+var (
+	moscow     = Point{Lon: 37.6173, Lat: 55.7558}
+	petersburg = Point{Lon: 30.3351, Lat: 59.9343}
+)
+
+func TestDistance(t *testing.T) {
+	if d := Distance(moscow, petersburg); math.Abs(d-634e3) > 5e3 {
+		t.Fatalf("Moscow - Saint Petersburg: %.0f m", d)
+	}
+	if d := Distance(moscow, moscow); d != 0 {
+		t.Fatalf("distance to itself: %v", d)
+	}
+	// четверть экватора
+	if d := Distance(Point{Lon: 0}, Point{Lon: 90}); math.Abs(d-math.Pi/2*earthRadius) > 1 {
+		t.Fatalf("quarter of the equator: %.0f m", d)
+	}
+	// через 180-й меридиан расстояние короткое
+	if d := Distance(Point{Lon: 179.5}, Point{Lon: -179.5}); d > 112e3 {
+		t.Fatalf("across the antimeridian: %.0f m", d)
+	}
+}
+
+func TestParsePoint(t *testing.T) {
+	for _, value := range []any{
+		[]any{37.6173, 55.7558},
+		map[string]any{"type": "Point", "coordinates": []any{37.6173, 55.7558}},
+	} {
+		if p, ok := ParsePoint(value); !ok || p != moscow {
+			t.Errorf("%v: %v %v", value, p, ok)
+		}
+	}
+	for _, value := range []any{
+		[]any{181.0, 0.0},
+		[]any{0.0, -91.0},
+		[]any{1.0},
+		[]any{"1", 2.0},
+		map[string]any{"type": "LineString", "coordinates": []any{0.0, 0.0}},
+		"0,0",
+	} {
+		if p, ok := ParsePoint(value); ok {
+			t.Errorf("%v must not parse, got %v", value, p)
+		}
+	}
+}
+
+func TestCoverBox(t *testing.T) {
+	covered := func(ranges []KeyRange, p Point) bool {
+		key := Key(p)
+		for _, r := range ranges {
+			if bytes.Compare(key, r.Start) >= 0 && bytes.Compare(key, r.End) <= 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	box := Box{Min: Point{Lon: 30, Lat: 55}, Max: Point{Lon: 38, Lat: 60}}
+	ranges := CoverBox(box)
+	if len(ranges) == 0 || len(ranges) > maxCells {
+		t.Fatalf("%d range(s)", len(ranges))
+	}
+	for _, p := range []Point{moscow, petersburg, box.Min, box.Max} {
+		if !covered(ranges, p) {
+			t.Errorf("%v is inside the box but not covered", p)
+		}
+	}
+	if covered(ranges, Point{Lon: -74, Lat: 40.7}) {
+		t.Error("New York is covered by a box around Moscow")
+	}
+
+	// прямоугольник через 180-й меридиан покрывает обе стороны, но не Гринвич
+	crossing := Box{Min: Point{Lon: 170, Lat: -25}, Max: Point{Lon: -165, Lat: -10}}
+	ranges = CoverBox(crossing)
+	for _, p := range []Point{{Lon: 178.4, Lat: -18.1}, {Lon: -171.8, Lat: -13.8}} {
+		if !covered(ranges, p) {
+			t.Errorf("%v is not covered", p)
+		}
+	}
+	if covered(ranges, Point{Lon: 0, Lat: -15}) {
+		t.Error("Greenwich is covered by a box across the antimeridian")
+	}
+}
+
+func TestShapes(t *testing.T) {
+	parse := func(spec map[string]any) Shape {
+		t.Helper()
+		shape, err := ParseShape(spec)
+		if err != nil {
+			t.Fatalf("%v: %v", spec, err)
+		}
+		return shape
+	}
+
+	// многоугольник с дырой: точка в дыре не входит
+	square := []any{[]any{0.0, 0.0}, []any{10.0, 0.0}, []any{10.0, 10.0}, []any{0.0, 10.0}, []any{0.0, 0.0}}
+	hole := []any{[]any{4.0, 4.0}, []any{6.0, 4.0}, []any{6.0, 6.0}, []any{4.0, 6.0}, []any{4.0, 4.0}}
+	withHole := parse(map[string]any{"$geometry": map[string]any{"type": "Polygon", "coordinates": []any{square, hole}}})
+	for p, want := range map[Point]bool{{Lon: 2, Lat: 2}: true, {Lon: 5, Lat: 5}: false, {Lon: 11, Lat: 5}: false} {
+		if got := withHole.Contains(p); got != want {
+			t.Errorf("polygon with a hole contains %v: %v", p, got)
+		}
+	}
+	if bounds := withHole.Bounds(); bounds != (Box{Min: Point{}, Max: Point{Lon: 10, Lat: 10}}) {
+		t.Errorf("polygon bounds: %v", bounds)
+	}
+
+	// описанный прямоугольник круга содержит все точки круга
+	circle := parse(map[string]any{"$center": []any{[]any{moscow.Lon, moscow.Lat}, 700000.0}})
+	if !circle.Contains(petersburg) || !circle.Bounds().Contains(petersburg) {
+		t.Error("Saint Petersburg is within 700 km of Moscow")
+	}
+	if circle.Contains(Point{Lon: 13.4, Lat: 52.5}) {
+		t.Error("Berlin is not within 700 km of Moscow")
+	}
+	// у полюса — полный диапазон долгот
+	if bounds := (Circle{Center: Point{Lon: 10, Lat: 89}, Radius: 300000}).Bounds(); bounds.Min.Lon != -180 || bounds.Max.Lon != 180 || bounds.Max.Lat != 90 {
+		t.Errorf("circle bounds near the pole: %v", bounds)
+	}
+	// через 180-й меридиан — прямоугольник с Min.Lon > Max.Lon
+	if bounds := (Circle{Center: Point{Lon: 179, Lat: 0}, Radius: 300000}).Bounds(); bounds.Min.Lon < bounds.Max.Lon || !bounds.Contains(Point{Lon: -179.5}) {
+		t.Errorf("circle bounds across the antimeridian: %v", bounds)
+	}
+
+	box := parse(map[string]any{"$box": []any{[]any{30.0, 60.0}, []any{38.0, 55.0}}})
+	if !box.Contains(moscow) || box.Contains(Point{Lon: 40, Lat: 57}) {
+		t.Errorf("box %v", box)
+	}
+
+	for _, spec := range []map[string]any{
+		{"$box": []any{[]any{0.0, 0.0}}},
+		{"$center": []any{[]any{0.0, 0.0}, -1.0}},
+		{"$polygon": []any{[]any{0.0, 0.0}, []any{1.0, 1.0}}},
+		{"$geometry": map[string]any{"type": "Point", "coordinates": []any{0.0, 0.0}}},
+		{"$box": []any{[]any{0.0, 0.0}, []any{1.0, 1.0}}, "$center": []any{[]any{0.0, 0.0}, 1.0}},
+		{"$sphere": 1.0},
+	} {
+		if _, err := ParseShape(spec); err == nil {
+			t.Errorf("%v must be rejected", spec)
+		}
+	}
+}
+
+func TestParseNear(t *testing.T) {
+	near, err := ParseNear(map[string]any{"$geometry": map[string]any{"type": "Point", "coordinates": []any{moscow.Lon, moscow.Lat}}, "$maxDistance": 700000.0})
+	if err != nil || near.Center != moscow || near.MaxDistance != 700000 {
+		t.Fatalf("$near with $geometry: %v %v", near, err)
+	}
+	if !near.Matches(petersburg) || near.Matches(Point{Lon: 13.4, Lat: 52.5}) {
+		t.Error("$maxDistance is not applied")
+	}
+	if near, err := ParseNear([]any{moscow.Lon, moscow.Lat}); err != nil || near.MaxDistance != 0 || !near.Matches(Point{Lon: -74, Lat: 40.7}) {
+		t.Fatalf("$near with a pair: %v %v", near, err)
+	}
+	for _, spec := range []any{
+		map[string]any{"$geometry": []any{0.0, 0.0}, "$maxDistance": -1.0},
+		map[string]any{"$geometry": []any{200.0, 0.0}},
+		"here",
+	} {
+		if _, err := ParseNear(spec); err == nil {
+			t.Errorf("%v must be rejected", spec)
+		}
+	}
+}
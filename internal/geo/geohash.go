@@ -0,0 +1,91 @@
+package geo
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// геохеш: по 26 бит на долготу и широту, биты чередуются (первый — долгота).
+// 52 бита хранятся в старших разрядах uint64, поэтому у ячейки любой точности
+// все точки внутри образуют непрерывный диапазон ключей b-tree
+const (
+	bitsPerAxis = 26
+	hashBits    = bitsPerAxis * 2
+	hashShift   = 64 - hashBits
+	maxCells    = 32
+)
+
+// Key возвращает ключ b-tree для точки
+func Key(p Point) []byte {
+	return hashKey(Encode(p))
+}
+
+// Encode вычисляет 52-битный геохеш точки
+func Encode(p Point) uint64 {
+	return interleave(axisIndex(p.Lon, -180, 360, bitsPerAxis), axisIndex(p.Lat, -90, 180, bitsPerAxis))
+}
+
+// axisIndex переводит координату в номер ячейки сетки из 2^bits ячеек
+func axisIndex(value, min, span float64, bits int) uint32 {
+	cells := float64(uint64(1) << bits)
+	idx := math.Floor((value - min) / span * cells)
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= cells {
+		idx = cells - 1
+	}
+	return uint32(idx)
+}
+
+// interleave чередует биты долготы и широты, начиная со старших
+func interleave(lon, lat uint32) uint64 {
+	var h uint64
+	for i := bitsPerAxis - 1; i >= 0; i-- {
+		h = h<<1 | uint64(lon>>uint(i)&1)
+		h = h<<1 | uint64(lat>>uint(i)&1)
+	}
+	return h
+}
+
+func hashKey(h uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, h<<hashShift)
+	return buf
+}
+
+// KeyRange — диапазон ключей b-tree [Start, End] включительно
+type KeyRange struct {
+	Start []byte
+	End   []byte
+}
+
+// CoverBox возвращает диапазоны ключей ячеек геохеша, покрывающих прямоугольник.
+// Точность подбирается так, чтобы ячеек было не больше maxCells; прямоугольник
+// через 180-й меридиан покрывается по частям
+func CoverBox(box Box) []KeyRange {
+	if parts := box.Split(); len(parts) > 1 {
+		return append(CoverBox(parts[0]), CoverBox(parts[1])...)
+	}
+	for bits := bitsPerAxis; bits > 0; bits-- {
+		minLon := axisIndex(box.Min.Lon, -180, 360, bits)
+		maxLon := axisIndex(box.Max.Lon, -180, 360, bits)
+		minLat := axisIndex(box.Min.Lat, -90, 180, bits)
+		maxLat := axisIndex(box.Max.Lat, -90, 180, bits)
+		if int(maxLon-minLon+1)*int(maxLat-minLat+1) > maxCells {
+			continue
+		}
+
+		shift := uint(bitsPerAxis - bits)
+		cellSize := uint64(1) << (2 * shift)
+		ranges := make([]KeyRange, 0, (maxLon-minLon+1)*(maxLat-minLat+1))
+		for lon := minLon; lon <= maxLon; lon++ {
+			for lat := minLat; lat <= maxLat; lat++ {
+				start := interleave(lon<<shift, lat<<shift)
+				ranges = append(ranges, KeyRange{Start: hashKey(start), End: hashKey(start + cellSize - 1)})
+			}
+		}
+		return ranges
+	}
+	return []KeyRange{{Start: hashKey(0), End: hashKey(1<<hashBits - 1)}}
+}
@@ -0,0 +1,89 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// earthRadius — средний радиус Земли в метрах
+const earthRadius = 6371008.8
+
+// Point — точка на сфере: долгота и широта в градусах
+type Point struct {
+	Lon float64
+	Lat float64
+}
+
+// ParsePoint разбирает точку из значения поля документа:
+// пара [lon, lat] или GeoJSON {"type": "Point", "coordinates": [lon, lat]}
+func ParsePoint(value any) (Point, bool) {
+	switch v := value.(type) {
+	case []any:
+		return pointFromPair(v)
+	case map[string]any:
+		if t, _ := v["type"].(string); t != "Point" {
+			return Point{}, false
+		}
+		coords, ok := v["coordinates"].([]any)
+		if !ok {
+			return Point{}, false
+		}
+		return pointFromPair(coords)
+	default:
+		return Point{}, false
+	}
+}
+
+// pointFromPair разбирает [lon, lat] и проверяет допустимые диапазоны
+func pointFromPair(pair []any) (Point, bool) {
+	if len(pair) != 2 {
+		return Point{}, false
+	}
+	lon, ok1 := toFloat(pair[0])
+	lat, ok2 := toFloat(pair[1])
+	if !ok1 || !ok2 || lon < -180 || lon > 180 || lat < -90 || lat > 90 {
+		return Point{}, false
+	}
+	return Point{Lon: lon, Lat: lat}, true
+}
+
+// mustPoint разбирает точку из аргумента запроса с понятной ошибкой
+func mustPoint(value any) (Point, error) {
+	p, ok := ParsePoint(value)
+	if !ok {
+		return Point{}, fmt.Errorf("invalid point %v: expected [lon, lat] or GeoJSON Point", value)
+	}
+	return p, nil
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// Distance возвращает расстояние между точками в метрах (формула гаверсинусов)
+func Distance(a, b Point) float64 {
+	lat1, lat2 := toRadians(a.Lat), toRadians(b.Lat)
+	dLat := lat2 - lat1
+	dLon := toRadians(b.Lon - a.Lon)
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
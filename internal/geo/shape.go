@@ -0,0 +1,308 @@
+package geo
+
+import (
+	"fmt"
+	"math"
+)
+
+// Box — прямоугольник в координатах: Min — юго-западный угол, Max — северо-восточный.
+// Min.Lon > Max.Lon — прямоугольник пересекает 180-й меридиан
+type Box struct {
+	Min Point
+	Max Point
+}
+
+// Shape — область для $geoWithin
+type Shape interface {
+	Contains(p Point) bool
+	Bounds() Box
+}
+
+// Contains проверяет попадание точки в прямоугольник
+func (b Box) Contains(p Point) bool {
+	if p.Lat < b.Min.Lat || p.Lat > b.Max.Lat {
+		return false
+	}
+	if b.Min.Lon > b.Max.Lon {
+		return p.Lon >= b.Min.Lon || p.Lon <= b.Max.Lon
+	}
+	return p.Lon >= b.Min.Lon && p.Lon <= b.Max.Lon
+}
+
+// Split делит прямоугольник, пересекающий 180-й меридиан, на части по обе стороны от него
+func (b Box) Split() []Box {
+	if b.Min.Lon <= b.Max.Lon {
+		return []Box{b}
+	}
+	return []Box{
+		{Min: b.Min, Max: Point{Lon: 180, Lat: b.Max.Lat}},
+		{Min: Point{Lon: -180, Lat: b.Min.Lat}, Max: b.Max},
+	}
+}
+
+// Bounds возвращает сам прямоугольник
+func (b Box) Bounds() Box {
+	return b
+}
+
+// Circle — круг на сфере, радиус в метрах
+type Circle struct {
+	Center Point
+	Radius float64
+}
+
+// Contains проверяет, что точка не дальше радиуса от центра
+func (c Circle) Contains(p Point) bool {
+	return Distance(c.Center, p) <= c.Radius
+}
+
+// Bounds возвращает описанный прямоугольник; у полюсов берётся полный диапазон долгот,
+// через 180-й меридиан прямоугольник переходит на другую сторону
+func (c Circle) Bounds() Box {
+	dLat := toDegrees(c.Radius / earthRadius)
+	minLat, maxLat := c.Center.Lat-dLat, c.Center.Lat+dLat
+	if minLat <= -90 || maxLat >= 90 {
+		return Box{
+			Min: Point{Lon: -180, Lat: math.Max(minLat, -90)},
+			Max: Point{Lon: 180, Lat: math.Min(maxLat, 90)},
+		}
+	}
+
+	dLon := toDegrees(c.Radius / (earthRadius * math.Cos(toRadians(c.Center.Lat))))
+	minLon, maxLon := c.Center.Lon-dLon, c.Center.Lon+dLon
+	switch {
+	case dLon >= 180:
+		minLon, maxLon = -180, 180
+	case minLon < -180:
+		minLon += 360
+	case maxLon > 180:
+		maxLon -= 360
+	}
+	return Box{Min: Point{Lon: minLon, Lat: minLat}, Max: Point{Lon: maxLon, Lat: maxLat}}
+}
+
+// Polygon — многоугольник: внешний контур и необязательные дыры.
+// Многоугольник, пересекающий 180-й меридиан (ребро длиннее 180° по долготе),
+// хранится с западными долготами, сдвинутыми на +360
+type Polygon struct {
+	Outer    []Point
+	Holes    [][]Point
+	Crossing bool // контуры пересекают 180-й меридиан
+}
+
+// newPolygon создаёт многоугольник и сдвигает долготы, если он пересекает 180-й меридиан
+func newPolygon(outer []Point, holes [][]Point) Polygon {
+	pg := Polygon{Outer: outer, Holes: holes}
+	for _, ring := range append([][]Point{outer}, holes...) {
+		for i := range ring {
+			if math.Abs(ring[i].Lon-ring[(i+1)%len(ring)].Lon) > 180 {
+				pg.Crossing = true
+			}
+		}
+	}
+	if pg.Crossing {
+		pg.Outer = shiftWest(outer)
+		for i, hole := range holes {
+			pg.Holes[i] = shiftWest(hole)
+		}
+	}
+	return pg
+}
+
+// shiftWest переносит западные долготы контура за 180-й меридиан (+360)
+func shiftWest(ring []Point) []Point {
+	shifted := make([]Point, len(ring))
+	for i, p := range ring {
+		if p.Lon < 0 {
+			p.Lon += 360
+		}
+		shifted[i] = p
+	}
+	return shifted
+}
+
+// Contains проверяет попадание точки внутрь внешнего контура и вне дыр
+func (pg Polygon) Contains(p Point) bool {
+	if pg.Crossing && p.Lon < 0 {
+		p.Lon += 360
+	}
+	if !ringContains(pg.Outer, p) {
+		return false
+	}
+	for _, hole := range pg.Holes {
+		if ringContains(hole, p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Bounds возвращает прямоугольник, описанный вокруг внешнего контура
+func (pg Polygon) Bounds() Box {
+	box := Box{Min: pg.Outer[0], Max: pg.Outer[0]}
+	for _, p := range pg.Outer[1:] {
+		box.Min.Lon = math.Min(box.Min.Lon, p.Lon)
+		box.Min.Lat = math.Min(box.Min.Lat, p.Lat)
+		box.Max.Lon = math.Max(box.Max.Lon, p.Lon)
+		box.Max.Lat = math.Max(box.Max.Lat, p.Lat)
+	}
+	if box.Max.Lon > 180 {
+		// восточный край — за 180-м меридианом
+		box.Max.Lon -= 360
+	}
+	return box
+}
+
+// ringContains — проверка луча (even-odd) в плоских координатах lon/lat
+func ringContains(ring []Point, p Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a.Lat > p.Lat) != (b.Lat > p.Lat) &&
+			p.Lon < (b.Lon-a.Lon)*(p.Lat-a.Lat)/(b.Lat-a.Lat)+a.Lon {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// ParseShape разбирает аргумент $geoWithin:
+// {"$box": [[lon, lat], [lon, lat]]} (юго-западный и северо-восточный углы: долгота первого
+// больше — прямоугольник через 180-й меридиан), {"$center": [[lon, lat], meters]},
+// {"$polygon": [[lon, lat], ...]} или {"$geometry": GeoJSON Polygon}
+func ParseShape(spec any) (Shape, error) {
+	specMap, ok := spec.(map[string]any)
+	if !ok || len(specMap) != 1 {
+		return nil, fmt.Errorf("$geoWithin requires exactly one of $box, $center, $polygon, $geometry")
+	}
+
+	for kind, value := range specMap {
+		switch kind {
+		case "$box":
+			corners, ok := value.([]any)
+			if !ok || len(corners) != 2 {
+				return nil, fmt.Errorf("$box requires two corners")
+			}
+			a, err := mustPoint(corners[0])
+			if err != nil {
+				return nil, err
+			}
+			b, err := mustPoint(corners[1])
+			if err != nil {
+				return nil, err
+			}
+			return Box{
+				Min: Point{Lon: a.Lon, Lat: math.Min(a.Lat, b.Lat)},
+				Max: Point{Lon: b.Lon, Lat: math.Max(a.Lat, b.Lat)},
+			}, nil
+		case "$center":
+			args, ok := value.([]any)
+			if !ok || len(args) != 2 {
+				return nil, fmt.Errorf("$center requires [[lon, lat], radius]")
+			}
+			center, err := mustPoint(args[0])
+			if err != nil {
+				return nil, err
+			}
+			radius, ok := toFloat(args[1])
+			if !ok || radius < 0 {
+				return nil, fmt.Errorf("$center radius must be a non-negative number of meters")
+			}
+			return Circle{Center: center, Radius: radius}, nil
+		case "$polygon":
+			ring, err := parseRing(value)
+			if err != nil {
+				return nil, err
+			}
+			return newPolygon(ring, nil), nil
+		case "$geometry":
+			return parseGeoJSONPolygon(value)
+		}
+	}
+	return nil, fmt.Errorf("unknown $geoWithin shape")
+}
+
+// parseGeoJSONPolygon разбирает {"type": "Polygon", "coordinates": [outer, holes...]}
+func parseGeoJSONPolygon(value any) (Shape, error) {
+	geometry, ok := value.(map[string]any)
+	if !ok || geometry["type"] != "Polygon" {
+		return nil, fmt.Errorf("$geometry must be a GeoJSON Polygon")
+	}
+	rings, ok := geometry["coordinates"].([]any)
+	if !ok || len(rings) == 0 {
+		return nil, fmt.Errorf("GeoJSON Polygon requires coordinates")
+	}
+	var outer []Point
+	var holes [][]Point
+	for i, r := range rings {
+		ring, err := parseRing(r)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			outer = ring
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+	return newPolygon(outer, holes), nil
+}
+
+// parseRing разбирает контур [[lon, lat], ...] минимум из трёх точек
+func parseRing(value any) ([]Point, error) {
+	coords, ok := value.([]any)
+	if !ok {
+		return nil, fmt.Errorf("polygon ring must be an array of points")
+	}
+	ring := make([]Point, 0, len(coords))
+	for _, c := range coords {
+		p, err := mustPoint(c)
+		if err != nil {
+			return nil, err
+		}
+		ring = append(ring, p)
+	}
+	if len(ring) < 3 {
+		return nil, fmt.Errorf("polygon requires at least 3 points")
+	}
+	return ring, nil
+}
+
+// Near — аргумент $near: точка и ограничение расстояния (0 — без ограничения)
+type Near struct {
+	Center      Point
+	MaxDistance float64
+}
+
+// ParseNear разбирает аргумент $near: [lon, lat], GeoJSON Point
+// или {"$geometry": точка, "$maxDistance": метры}
+func ParseNear(spec any) (Near, error) {
+	if specMap, ok := spec.(map[string]any); ok {
+		if geometry, ok := specMap["$geometry"]; ok {
+			center, err := mustPoint(geometry)
+			if err != nil {
+				return Near{}, err
+			}
+			near := Near{Center: center}
+			if maxDistance, exists := specMap["$maxDistance"]; exists {
+				d, ok := toFloat(maxDistance)
+				if !ok || d < 0 {
+					return Near{}, fmt.Errorf("$maxDistance must be a non-negative number of meters")
+				}
+				near.MaxDistance = d
+			}
+			return near, nil
+		}
+	}
+
+	center, err := mustPoint(spec)
+	if err != nil {
+		return Near{}, err
+	}
+	return Near{Center: center}, nil
+}
+
+// Matches проверяет ограничение $maxDistance для точки
+func (n Near) Matches(p Point) bool {
+	return n.MaxDistance == 0 || Distance(n.Center, p) <= n.MaxDistance
+}
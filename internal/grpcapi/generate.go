@@ -0,0 +1,4 @@
+// Package grpcapi — сообщения и сервис gRPC, сгенерированные из proto/nosqldb.proto
+package grpcapi
+
+//go:generate protoc -I ../../proto --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative nosqldb.proto
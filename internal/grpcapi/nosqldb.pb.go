@@ -0,0 +1,569 @@
+// Сервис NoSQLdb для gRPC: те же команды, что и в протоколе TCP (api.Request/api.Response).
+// Клиенты на других языках генерируются из этого файла, код Go — в internal/grpcapi
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: nosqldb.proto
+
+package grpcapi
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Request — запрос, как api.Request
+type Request struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Database      string                 `protobuf:"bytes,1,opt,name=database,proto3" json:"database,omitempty"` // база; без collection — имя коллекции в текущей базе
+	Collection    string                 `protobuf:"bytes,2,opt,name=collection,proto3" json:"collection,omitempty"`
+	Operation     string                 `protobuf:"bytes,3,opt,name=operation,proto3" json:"operation,omitempty"`    // команда (только в Session)
+	Data          []*structpb.Struct     `protobuf:"bytes,4,rep,name=data,proto3" json:"data,omitempty"`              // документы insert
+	Query         *structpb.Struct       `protobuf:"bytes,5,opt,name=query,proto3" json:"query,omitempty"`            // условия поиска
+	Update        *structpb.Struct       `protobuf:"bytes,6,opt,name=update,proto3" json:"update,omitempty"`          // операторы обновления ($set, $unset, $inc)
+	Projection    *structpb.Struct       `protobuf:"bytes,7,opt,name=projection,proto3" json:"projection,omitempty"`  // возвращаемые поля (find)
+	Field         string                 `protobuf:"bytes,8,opt,name=field,proto3" json:"field,omitempty"`            // поле distinct
+	Sort          []string               `protobuf:"bytes,9,rep,name=sort,proto3" json:"sort,omitempty"`              // поля сортировки, "-" — по убыванию
+	Limit         int32                  `protobuf:"varint,10,opt,name=limit,proto3" json:"limit,omitempty"`          // максимум документов в ответе
+	Options       *structpb.Struct       `protobuf:"bytes,11,opt,name=options,proto3" json:"options,omitempty"`       // параметры команды (тип индекса и т.п.)
+	Durability    string                 `protobuf:"bytes,12,opt,name=durability,proto3" json:"durability,omitempty"` // none, flushed (по умолчанию), fsynced
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Request) Reset() {
+	*x = Request{}
+	mi := &file_nosqldb_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Request) ProtoMessage() {}
+
+func (x *Request) ProtoReflect() protoreflect.Message {
+	mi := &file_nosqldb_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Request.ProtoReflect.Descriptor instead.
+func (*Request) Descriptor() ([]byte, []int) {
+	return file_nosqldb_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Request) GetDatabase() string {
+	if x != nil {
+		return x.Database
+	}
+	return ""
+}
+
+func (x *Request) GetCollection() string {
+	if x != nil {
+		return x.Collection
+	}
+	return ""
+}
+
+func (x *Request) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *Request) GetData() []*structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Request) GetQuery() *structpb.Struct {
+	if x != nil {
+		return x.Query
+	}
+	return nil
+}
+
+func (x *Request) GetUpdate() *structpb.Struct {
+	if x != nil {
+		return x.Update
+	}
+	return nil
+}
+
+func (x *Request) GetProjection() *structpb.Struct {
+	if x != nil {
+		return x.Projection
+	}
+	return nil
+}
+
+func (x *Request) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *Request) GetSort() []string {
+	if x != nil {
+		return x.Sort
+	}
+	return nil
+}
+
+func (x *Request) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *Request) GetOptions() *structpb.Struct {
+	if x != nil {
+		return x.Options
+	}
+	return nil
+}
+
+func (x *Request) GetDurability() string {
+	if x != nil {
+		return x.Durability
+	}
+	return ""
+}
+
+// Response — ответ, как api.Response
+type Response struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        string                 `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"` // success или error
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Data          []*structpb.Struct     `protobuf:"bytes,3,rep,name=data,proto3" json:"data,omitempty"`     // документы результата
+	Values        []*structpb.Value      `protobuf:"bytes,4,rep,name=values,proto3" json:"values,omitempty"` // значения distinct
+	Count         int64                  `protobuf:"varint,5,opt,name=count,proto3" json:"count,omitempty"`
+	Warnings      []string               `protobuf:"bytes,6,rep,name=warnings,proto3" json:"warnings,omitempty"`                          // нарушения схемы с уровнем warn
+	Events        []*ChangeEvent         `protobuf:"bytes,7,rep,name=events,proto3" json:"events,omitempty"`                              // события watch
+	ResumeToken   string                 `protobuf:"bytes,8,opt,name=resume_token,json=resumeToken,proto3" json:"resume_token,omitempty"` // позиция потока изменений после ответа
+	Tailable      bool                   `protobuf:"varint,9,opt,name=tailable,proto3" json:"tailable,omitempty"`                         // ответ потока Tail или Watch
+	Leader        string                 `protobuf:"bytes,10,opt,name=leader,proto3" json:"leader,omitempty"`                             // адрес лидера кластера, если узел не принимает записи
+	Code          string                 `protobuf:"bytes,11,opt,name=code,proto3" json:"code,omitempty"`                                 // вид ошибки, как api.Response.Code
+	Matched       int64                  `protobuf:"varint,12,opt,name=matched,proto3" json:"matched,omitempty"`                          // документов под условием update
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Response) Reset() {
+	*x = Response{}
+	mi := &file_nosqldb_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Response) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response) ProtoMessage() {}
+
+func (x *Response) ProtoReflect() protoreflect.Message {
+	mi := &file_nosqldb_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response.ProtoReflect.Descriptor instead.
+func (*Response) Descriptor() ([]byte, []int) {
+	return file_nosqldb_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Response) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Response) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *Response) GetData() []*structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Response) GetValues() []*structpb.Value {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+func (x *Response) GetCount() int64 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *Response) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+func (x *Response) GetEvents() []*ChangeEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+func (x *Response) GetResumeToken() string {
+	if x != nil {
+		return x.ResumeToken
+	}
+	return ""
+}
+
+func (x *Response) GetTailable() bool {
+	if x != nil {
+		return x.Tailable
+	}
+	return false
+}
+
+func (x *Response) GetLeader() string {
+	if x != nil {
+		return x.Leader
+	}
+	return ""
+}
+
+func (x *Response) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Response) GetMatched() int64 {
+	if x != nil {
+		return x.Matched
+	}
+	return 0
+}
+
+// ChangeEvent — событие изменения, как api.ChangeEvent
+type ChangeEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"` // позиция после события для options.resumeAfter
+	Operation     string                 `protobuf:"bytes,2,opt,name=operation,proto3" json:"operation,omitempty"`
+	Database      string                 `protobuf:"bytes,3,opt,name=database,proto3" json:"database,omitempty"`
+	Collection    string                 `protobuf:"bytes,4,opt,name=collection,proto3" json:"collection,omitempty"`
+	Id            string                 `protobuf:"bytes,5,opt,name=id,proto3" json:"id,omitempty"`
+	Document      *structpb.Struct       `protobuf:"bytes,6,opt,name=document,proto3" json:"document,omitempty"`                                // документ целиком (insert, update с fullDocument)
+	UpdatedFields *structpb.Struct       `protobuf:"bytes,7,opt,name=updated_fields,json=updatedFields,proto3" json:"updated_fields,omitempty"` // изменённые поля (update)
+	RemovedFields []string               `protobuf:"bytes,8,rep,name=removed_fields,json=removedFields,proto3" json:"removed_fields,omitempty"` // удалённые поля (update)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChangeEvent) Reset() {
+	*x = ChangeEvent{}
+	mi := &file_nosqldb_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeEvent) ProtoMessage() {}
+
+func (x *ChangeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_nosqldb_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeEvent.ProtoReflect.Descriptor instead.
+func (*ChangeEvent) Descriptor() ([]byte, []int) {
+	return file_nosqldb_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ChangeEvent) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetDatabase() string {
+	if x != nil {
+		return x.Database
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetCollection() string {
+	if x != nil {
+		return x.Collection
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ChangeEvent) GetDocument() *structpb.Struct {
+	if x != nil {
+		return x.Document
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetUpdatedFields() *structpb.Struct {
+	if x != nil {
+		return x.UpdatedFields
+	}
+	return nil
+}
+
+func (x *ChangeEvent) GetRemovedFields() []string {
+	if x != nil {
+		return x.RemovedFields
+	}
+	return nil
+}
+
+var File_nosqldb_proto protoreflect.FileDescriptor
+
+const file_nosqldb_proto_rawDesc = "" +
+	"\n" +
+	"\rnosqldb.proto\x12\n" +
+	"nosqldb.v1\x1a\x1cgoogle/protobuf/struct.proto\"\xbc\x03\n" +
+	"\aRequest\x12\x1a\n" +
+	"\bdatabase\x18\x01 \x01(\tR\bdatabase\x12\x1e\n" +
+	"\n" +
+	"collection\x18\x02 \x01(\tR\n" +
+	"collection\x12\x1c\n" +
+	"\toperation\x18\x03 \x01(\tR\toperation\x12+\n" +
+	"\x04data\x18\x04 \x03(\v2\x17.google.protobuf.StructR\x04data\x12-\n" +
+	"\x05query\x18\x05 \x01(\v2\x17.google.protobuf.StructR\x05query\x12/\n" +
+	"\x06update\x18\x06 \x01(\v2\x17.google.protobuf.StructR\x06update\x127\n" +
+	"\n" +
+	"projection\x18\a \x01(\v2\x17.google.protobuf.StructR\n" +
+	"projection\x12\x14\n" +
+	"\x05field\x18\b \x01(\tR\x05field\x12\x12\n" +
+	"\x04sort\x18\t \x03(\tR\x04sort\x12\x14\n" +
+	"\x05limit\x18\n" +
+	" \x01(\x05R\x05limit\x121\n" +
+	"\aoptions\x18\v \x01(\v2\x17.google.protobuf.StructR\aoptions\x12\x1e\n" +
+	"\n" +
+	"durability\x18\f \x01(\tR\n" +
+	"durability\"\x81\x03\n" +
+	"\bResponse\x12\x16\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12+\n" +
+	"\x04data\x18\x03 \x03(\v2\x17.google.protobuf.StructR\x04data\x12.\n" +
+	"\x06values\x18\x04 \x03(\v2\x16.google.protobuf.ValueR\x06values\x12\x14\n" +
+	"\x05count\x18\x05 \x01(\x03R\x05count\x12\x1a\n" +
+	"\bwarnings\x18\x06 \x03(\tR\bwarnings\x12/\n" +
+	"\x06events\x18\a \x03(\v2\x17.nosqldb.v1.ChangeEventR\x06events\x12!\n" +
+	"\fresume_token\x18\b \x01(\tR\vresumeToken\x12\x1a\n" +
+	"\btailable\x18\t \x01(\bR\btailable\x12\x16\n" +
+	"\x06leader\x18\n" +
+	" \x01(\tR\x06leader\x12\x12\n" +
+	"\x04code\x18\v \x01(\tR\x04code\x12\x18\n" +
+	"\amatched\x18\f \x01(\x03R\amatched\"\xa9\x02\n" +
+	"\vChangeEvent\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12\x1c\n" +
+	"\toperation\x18\x02 \x01(\tR\toperation\x12\x1a\n" +
+	"\bdatabase\x18\x03 \x01(\tR\bdatabase\x12\x1e\n" +
+	"\n" +
+	"collection\x18\x04 \x01(\tR\n" +
+	"collection\x12\x0e\n" +
+	"\x02id\x18\x05 \x01(\tR\x02id\x123\n" +
+	"\bdocument\x18\x06 \x01(\v2\x17.google.protobuf.StructR\bdocument\x12>\n" +
+	"\x0eupdated_fields\x18\a \x01(\v2\x17.google.protobuf.StructR\rupdatedFields\x12%\n" +
+	"\x0eremoved_fields\x18\b \x03(\tR\rremovedFields2\xf4\b\n" +
+	"\aNoSQLdb\x123\n" +
+	"\x06Insert\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x123\n" +
+	"\x04Find\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response0\x01\x123\n" +
+	"\x06Update\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x123\n" +
+	"\x06Delete\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x122\n" +
+	"\x05Count\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x125\n" +
+	"\bDistinct\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x128\n" +
+	"\vCreateIndex\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x128\n" +
+	"\vListIndexes\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x12=\n" +
+	"\x10CreateCollection\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x12;\n" +
+	"\x0eDropCollection\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x12=\n" +
+	"\x10RenameCollection\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x124\n" +
+	"\aCollMod\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x12:\n" +
+	"\rListDatabases\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x12<\n" +
+	"\x0fListCollections\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x129\n" +
+	"\fDropDatabase\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x122\n" +
+	"\x05Stats\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x127\n" +
+	"\n" +
+	"ReplStatus\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response\x123\n" +
+	"\x04Tail\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response0\x01\x124\n" +
+	"\x05Watch\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response0\x01\x128\n" +
+	"\aSession\x12\x13.nosqldb.v1.Request\x1a\x14.nosqldb.v1.Response(\x010\x01B#Z!nosql_db/internal/grpcapi;grpcapib\x06proto3"
+
+var (
+	file_nosqldb_proto_rawDescOnce sync.Once
+	file_nosqldb_proto_rawDescData []byte
+)
+
+func file_nosqldb_proto_rawDescGZIP() []byte {
+	file_nosqldb_proto_rawDescOnce.Do(func() {
+		file_nosqldb_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_nosqldb_proto_rawDesc), len(file_nosqldb_proto_rawDesc)))
+	})
+	return file_nosqldb_proto_rawDescData
+}
+
+var file_nosqldb_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_nosqldb_proto_goTypes = []any{
+	(*Request)(nil),         // 0: nosqldb.v1.Request
+	(*Response)(nil),        // 1: nosqldb.v1.Response
+	(*ChangeEvent)(nil),     // 2: nosqldb.v1.ChangeEvent
+	(*structpb.Struct)(nil), // 3: google.protobuf.Struct
+	(*structpb.Value)(nil),  // 4: google.protobuf.Value
+}
+var file_nosqldb_proto_depIdxs = []int32{
+	3,  // 0: nosqldb.v1.Request.data:type_name -> google.protobuf.Struct
+	3,  // 1: nosqldb.v1.Request.query:type_name -> google.protobuf.Struct
+	3,  // 2: nosqldb.v1.Request.update:type_name -> google.protobuf.Struct
+	3,  // 3: nosqldb.v1.Request.projection:type_name -> google.protobuf.Struct
+	3,  // 4: nosqldb.v1.Request.options:type_name -> google.protobuf.Struct
+	3,  // 5: nosqldb.v1.Response.data:type_name -> google.protobuf.Struct
+	4,  // 6: nosqldb.v1.Response.values:type_name -> google.protobuf.Value
+	2,  // 7: nosqldb.v1.Response.events:type_name -> nosqldb.v1.ChangeEvent
+	3,  // 8: nosqldb.v1.ChangeEvent.document:type_name -> google.protobuf.Struct
+	3,  // 9: nosqldb.v1.ChangeEvent.updated_fields:type_name -> google.protobuf.Struct
+	0,  // 10: nosqldb.v1.NoSQLdb.Insert:input_type -> nosqldb.v1.Request
+	0,  // 11: nosqldb.v1.NoSQLdb.Find:input_type -> nosqldb.v1.Request
+	0,  // 12: nosqldb.v1.NoSQLdb.Update:input_type -> nosqldb.v1.Request
+	0,  // 13: nosqldb.v1.NoSQLdb.Delete:input_type -> nosqldb.v1.Request
+	0,  // 14: nosqldb.v1.NoSQLdb.Count:input_type -> nosqldb.v1.Request
+	0,  // 15: nosqldb.v1.NoSQLdb.Distinct:input_type -> nosqldb.v1.Request
+	0,  // 16: nosqldb.v1.NoSQLdb.CreateIndex:input_type -> nosqldb.v1.Request
+	0,  // 17: nosqldb.v1.NoSQLdb.ListIndexes:input_type -> nosqldb.v1.Request
+	0,  // 18: nosqldb.v1.NoSQLdb.CreateCollection:input_type -> nosqldb.v1.Request
+	0,  // 19: nosqldb.v1.NoSQLdb.DropCollection:input_type -> nosqldb.v1.Request
+	0,  // 20: nosqldb.v1.NoSQLdb.RenameCollection:input_type -> nosqldb.v1.Request
+	0,  // 21: nosqldb.v1.NoSQLdb.CollMod:input_type -> nosqldb.v1.Request
+	0,  // 22: nosqldb.v1.NoSQLdb.ListDatabases:input_type -> nosqldb.v1.Request
+	0,  // 23: nosqldb.v1.NoSQLdb.ListCollections:input_type -> nosqldb.v1.Request
+	0,  // 24: nosqldb.v1.NoSQLdb.DropDatabase:input_type -> nosqldb.v1.Request
+	0,  // 25: nosqldb.v1.NoSQLdb.Stats:input_type -> nosqldb.v1.Request
+	0,  // 26: nosqldb.v1.NoSQLdb.ReplStatus:input_type -> nosqldb.v1.Request
+	0,  // 27: nosqldb.v1.NoSQLdb.Tail:input_type -> nosqldb.v1.Request
+	0,  // 28: nosqldb.v1.NoSQLdb.Watch:input_type -> nosqldb.v1.Request
+	0,  // 29: nosqldb.v1.NoSQLdb.Session:input_type -> nosqldb.v1.Request
+	1,  // 30: nosqldb.v1.NoSQLdb.Insert:output_type -> nosqldb.v1.Response
+	1,  // 31: nosqldb.v1.NoSQLdb.Find:output_type -> nosqldb.v1.Response
+	1,  // 32: nosqldb.v1.NoSQLdb.Update:output_type -> nosqldb.v1.Response
+	1,  // 33: nosqldb.v1.NoSQLdb.Delete:output_type -> nosqldb.v1.Response
+	1,  // 34: nosqldb.v1.NoSQLdb.Count:output_type -> nosqldb.v1.Response
+	1,  // 35: nosqldb.v1.NoSQLdb.Distinct:output_type -> nosqldb.v1.Response
+	1,  // 36: nosqldb.v1.NoSQLdb.CreateIndex:output_type -> nosqldb.v1.Response
+	1,  // 37: nosqldb.v1.NoSQLdb.ListIndexes:output_type -> nosqldb.v1.Response
+	1,  // 38: nosqldb.v1.NoSQLdb.CreateCollection:output_type -> nosqldb.v1.Response
+	1,  // 39: nosqldb.v1.NoSQLdb.DropCollection:output_type -> nosqldb.v1.Response
+	1,  // 40: nosqldb.v1.NoSQLdb.RenameCollection:output_type -> nosqldb.v1.Response
+	1,  // 41: nosqldb.v1.NoSQLdb.CollMod:output_type -> nosqldb.v1.Response
+	1,  // 42: nosqldb.v1.NoSQLdb.ListDatabases:output_type -> nosqldb.v1.Response
+	1,  // 43: nosqldb.v1.NoSQLdb.ListCollections:output_type -> nosqldb.v1.Response
+	1,  // 44: nosqldb.v1.NoSQLdb.DropDatabase:output_type -> nosqldb.v1.Response
+	1,  // 45: nosqldb.v1.NoSQLdb.Stats:output_type -> nosqldb.v1.Response
+	1,  // 46: nosqldb.v1.NoSQLdb.ReplStatus:output_type -> nosqldb.v1.Response
+	1,  // 47: nosqldb.v1.NoSQLdb.Tail:output_type -> nosqldb.v1.Response
+	1,  // 48: nosqldb.v1.NoSQLdb.Watch:output_type -> nosqldb.v1.Response
+	1,  // 49: nosqldb.v1.NoSQLdb.Session:output_type -> nosqldb.v1.Response
+	30, // [30:50] is the sub-list for method output_type
+	10, // [10:30] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_nosqldb_proto_init() }
+func file_nosqldb_proto_init() {
+	if File_nosqldb_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_nosqldb_proto_rawDesc), len(file_nosqldb_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   3,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_nosqldb_proto_goTypes,
+		DependencyIndexes: file_nosqldb_proto_depIdxs,
+		MessageInfos:      file_nosqldb_proto_msgTypes,
+	}.Build()
+	File_nosqldb_proto = out.File
+	file_nosqldb_proto_goTypes = nil
+	file_nosqldb_proto_depIdxs = nil
+}
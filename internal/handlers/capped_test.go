@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"nosql_db/internal/api"
+)
+
+func TestCappedUpdateGrowth(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateCollection, Options: map[string]any{"capped": true, "size": 250.0}})
+	insertDocs(t, mng,
+		map[string]any{"name": "a", "pad": "x"},
+		map[string]any{"name": "b", "pad": "x"},
+		map[string]any{"name": "c", "pad": "x"},
+	)
+
+	// самый старый документ вырос: место освобождают следующие за ним по возрасту
+	mustHandle(t, mng, api.Request{Command: api.CmdUpdate, Query: map[string]any{"name": "a"},
+		Update: map[string]any{"$set": map[string]any{"pad": strings.Repeat("x", 120)}}})
+	found := mustHandle(t, mng, api.Request{Command: api.CmdFind})
+	if got := foundNames(found); !slices.Equal(got, []string{"a", "c"}) {
+		t.Fatalf("after growth: %v", got)
+	}
+
+	// документ больше всей коллекции отклоняется, и обновление откатывается
+	mustFail(t, mng, api.Request{Command: api.CmdUpdate, Query: map[string]any{"name": "c"},
+		Update: map[string]any{"$set": map[string]any{"pad": strings.Repeat("x", 300)}}})
+	found = mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{"name": "c"}})
+	if found.Count != 1 || found.Data[0]["pad"] != "x" {
+		t.Fatalf("rejected update changed the document: %v", found.Data)
+	}
+}
+
+func TestCappedInsertionOrder(t *testing.T) {
+	mng := testManager(t)
+	for _, options := range []map[string]any{
+		{"capped": true},
+		{"capped": true, "max": -1.0},
+		{"capped": true, "size": 10.5},
+		{"capped": true, "max": "3"},
+	} {
+		mustFail(t, mng, api.Request{Command: api.CmdCreateCollection, Options: options})
+	}
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateCollection, Options: map[string]any{"capped": true, "max": 3.0}})
+	mustFail(t, mng, api.Request{Command: api.CmdCreateCollection, Options: map[string]any{"capped": true, "max": 3.0}})
+
+	// имена не упорядочены: find возвращает порядок вставки, а не порядок имён или хеш-таблицы
+	for _, name := range []string{"zeta", "alpha", "mu", "beta", "omega"} {
+		insertDocs(t, mng, map[string]any{"name": name})
+	}
+	found := mustHandle(t, mng, api.Request{Command: api.CmdFind})
+	if got := foundNames(found); !slices.Equal(got, []string{"mu", "beta", "omega"}) {
+		t.Fatalf("capped find: %v", got)
+	}
+}
+
+func TestTailableFind(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateCollection, Options: map[string]any{"capped": true, "max": 10.0}})
+	insertDocs(t, mng, map[string]any{"name": "a", "level": "info"}, map[string]any{"name": "b", "level": "error"})
+
+	s := NewSession(mng, nil)
+	responses := make(chan api.Response, 10)
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	req := api.Request{Command: api.CmdFind, Collection: "test", Query: map[string]any{"level": "error"},
+		Options: map[string]any{"tailable": true}}
+	if !IsStreaming(req) {
+		t.Fatal("tailable find must be a streaming request")
+	}
+	go func() {
+		done <- s.Stream(req, func(resp api.Response) error {
+			responses <- resp
+			return nil
+		}, stop)
+	}()
+
+	// первая порция — уже вставленные документы, затем новые по мере вставки
+	if resp := <-responses; !resp.Tailable || !slices.Equal(foundNames(resp), []string{"b"}) {
+		t.Fatalf("first batch: %+v", resp)
+	}
+	insertDocs(t, mng, map[string]any{"name": "c", "level": "info"})
+	insertDocs(t, mng, map[string]any{"name": "d", "level": "error"}, map[string]any{"name": "e", "level": "error"})
+	if resp := <-responses; !slices.Equal(foundNames(resp), []string{"d", "e"}) {
+		t.Fatalf("next batch: %+v", resp)
+	}
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if resp := <-responses; resp.Message != "Tailable cursor closed" {
+		t.Fatalf("last response: %+v", resp)
+	}
+
+	// курсор по обычной коллекции или с сортировкой отклоняется
+	for _, req := range []api.Request{
+		{Command: api.CmdFind, Collection: "plain", Options: map[string]any{"tailable": true}},
+		{Command: api.CmdFind, Collection: "test", Sort: []string{"name"}, Options: map[string]any{"tailable": true}},
+	} {
+		s.Tail(req, func(resp api.Response) error {
+			responses <- resp
+			return nil
+		}, stop)
+		if resp := <-responses; resp.Status != api.StatusError || !resp.Tailable {
+			t.Fatalf("%v: %+v", req, resp)
+		}
+	}
+}
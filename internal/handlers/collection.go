@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"fmt"
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+)
+
+// handleCreateCollection создаёт коллекцию с движком хранения из options.engine
+// (hashmap — по умолчанию, memory, lsm). options.capped с max и/или size создаёт
+// capped-коллекцию: последние max документов или size байт. options.validator задаёт
+// JSON Schema документов, options.validationLevel — strict (по умолчанию) или warn
+func handleCreateCollection(mng *storage.CollectionMng, req api.Request) api.Response {
+	engine, _ := req.Options["engine"].(string)
+	engine, err := storage.ValidEngine(engine)
+	if err != nil {
+		return errorResponse(err)
+	}
+	opts := storage.CollectionOptions{Engine: engine}
+	if capped, _ := req.Options["capped"].(bool); capped {
+		if opts.Capped, err = parseCappedOptions(req.Options); err != nil {
+			return errorResponse(err)
+		}
+	}
+	schema, hasSchema, err := parseValidator(req.Options)
+	if err != nil {
+		return errorResponse(err)
+	}
+	level, err := parseValidationLevel(req.Options)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if level != "" && !hasSchema {
+		return api.Response{Status: api.StatusError, Message: "validationLevel requires a validator"}
+	}
+	if len(schema) > 0 {
+		level, _ = storage.ValidLevel(level)
+		opts.Validator = &storage.ValidatorOptions{Schema: schema, Level: level}
+	}
+
+	ns := namespaceOf(req)
+	if err := mng.CreateCollection(ns, opts); err != nil {
+		return errorResponse(err)
+	}
+	message := fmt.Sprintf("Collection '%s' created (engine: %s)", ns, engine)
+	if opts.Capped != nil {
+		message += fmt.Sprintf(", capped: max %d document(s), size %d byte(s)", opts.Capped.Max, opts.Capped.Size)
+	}
+	if opts.Validator != nil {
+		message += fmt.Sprintf(", validator: %s", opts.Validator.Level)
+	}
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: message,
+	}
+}
+
+// handleDropCollection удаляет коллекцию вместе с данными и индексами
+func handleDropCollection(mng *storage.CollectionMng, req api.Request) api.Response {
+	ns := namespaceOf(req)
+	if err := mng.DropCollection(ns); err != nil {
+		return errorResponse(err)
+	}
+	return api.Response{Status: api.StatusSuccess, Message: fmt.Sprintf("Collection '%s' dropped", ns)}
+}
+
+// handleRenameCollection переименовывает коллекцию в options.to; options.to_database
+// переносит её в другую базу
+func handleRenameCollection(mng *storage.CollectionMng, req api.Request) api.Response {
+	to, _ := req.Options["to"].(string)
+	toDB, _ := req.Options["to_database"].(string)
+	if toDB == "" {
+		toDB = req.Database
+	}
+	if err := storage.ValidateName("target collection", to); err != nil {
+		return errorResponse(err)
+	}
+	if err := storage.ValidateDatabaseName("target database", toDB); err != nil {
+		return errorResponse(err)
+	}
+
+	from, target := namespaceOf(req), storage.Namespace{DB: toDB, Coll: to}
+	if err := mng.RenameCollection(from, target); err != nil {
+		return errorResponse(err)
+	}
+	return api.Response{Status: api.StatusSuccess, Message: fmt.Sprintf("Collection '%s' renamed to '%s'", from, target)}
+}
+
+// handleCollMod меняет валидатор существующей коллекции: options.validator — новая
+// схема ({} снимает валидатор), options.validationLevel — уровень проверки.
+// Уже сохранённые документы не перепроверяются
+func handleCollMod(mng *storage.CollectionMng, req api.Request) api.Response {
+	schema, hasSchema, err := parseValidator(req.Options)
+	if err != nil {
+		return errorResponse(err)
+	}
+	level, err := parseValidationLevel(req.Options)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if !hasSchema && level == "" {
+		return api.Response{Status: api.StatusError, Message: "coll_mod requires options.validator or options.validationLevel"}
+	}
+
+	ns := namespaceOf(req)
+	validator, err := mng.CollMod(ns, storage.CollModOptions{Schema: schema, SetSchema: hasSchema, Level: level})
+	if err != nil {
+		return errorResponse(err)
+	}
+	message := fmt.Sprintf("Collection '%s' modified: validator removed", ns)
+	if validator != nil {
+		message = fmt.Sprintf("Collection '%s' modified: validator %s", ns, validator.Level)
+	}
+	return api.Response{Status: api.StatusSuccess, Message: message}
+}
+
+// parseCappedOptions читает ограничения capped-коллекции: max документов и size байт (0 — нет)
+func parseCappedOptions(options map[string]any) (*storage.CappedOptions, error) {
+	capped := &storage.CappedOptions{}
+	for _, name := range []string{"max", "size"} {
+		raw, ok := options[name]
+		if !ok {
+			continue
+		}
+		value, isNumber := raw.(float64)
+		if !isNumber || value < 0 || value != float64(int64(value)) {
+			return nil, fmt.Errorf("%s must be a non-negative integer", name)
+		}
+		if name == "max" {
+			capped.Max = int(value)
+		} else {
+			capped.Size = int64(value)
+		}
+	}
+	if capped.Max == 0 && capped.Size == 0 {
+		return nil, fmt.Errorf("capped collection requires max or size")
+	}
+	return capped, nil
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"fmt"
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+)
+
+func handleCount(snap *storage.Snapshot, req api.Request) api.Response {
+	plan, err := planRead(snap, req.Query)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	var count int
+	indexOnly := false
+
+	switch {
+	case len(req.Query) == 0:
+		// без условий отвечает счётчик движка; если снимок с ним расходится, Count обходит документы
+		count, indexOnly = snap.StoredCount()
+		if !indexOnly {
+			count = snap.Count()
+		}
+	case plan.covered:
+		// все условия отвечены индексами — документы не читаем
+		count = len(plan.ids)
+		indexOnly = true
+	case plan.useIndex:
+		count = len(fetchCandidates(snap, plan))
+	default:
+		count = len(findFullScan(snap, req.Query))
+	}
+
+	message := fmt.Sprintf("Counted %d document(s)", count)
+	if indexOnly {
+		message += " (index-only)"
+	}
+
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: message,
+		Count:   count,
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+)
+
+// coveredCollection — коллекция test с b-tree индексами на age и city
+func coveredCollection(t *testing.T) *storage.CollectionMng {
+	t.Helper()
+	mng := testManager(t)
+	for _, field := range []string{"age", "city"} {
+		mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{field: 1}})
+	}
+	insertDocs(t, mng,
+		map[string]any{"name": "ann", "age": 25.0, "city": "Kazan"},
+		map[string]any{"name": "bob", "age": 31.0, "city": "Moscow"},
+		map[string]any{"name": "eve", "age": 40.0, "city": "Kazan"},
+		map[string]any{"name": "max", "age": 35.0, "city": "Omsk"},
+		map[string]any{"name": "kid", "city": "Omsk"},
+	)
+	return mng
+}
+
+func TestCountIndexOnly(t *testing.T) {
+	mng := coveredCollection(t)
+
+	cases := []struct {
+		query     map[string]any
+		want      int
+		indexOnly bool
+	}{
+		{nil, 5, true},
+		{map[string]any{"age": map[string]any{"$gt": 30.0}}, 3, true},
+		{map[string]any{"age": map[string]any{"$gt": 30.0}, "city": "Kazan"}, 1, true},
+		{map[string]any{"city": map[string]any{"$in": []any{"Omsk", "Moscow"}}}, 3, true},
+		// неиндексированное поле: кандидаты по индексу перепроверяются по документам
+		{map[string]any{"age": map[string]any{"$gt": 30.0}, "name": "bob"}, 1, false},
+		{map[string]any{"name": map[string]any{"$like": "%a%"}}, 2, false},
+		{map[string]any{"$or": []any{map[string]any{"age": 25.0}, map[string]any{"city": "Omsk"}}}, 3, false},
+	}
+	for _, tc := range cases {
+		resp := mustHandle(t, mng, api.Request{Command: api.CmdCount, Query: tc.query})
+		if resp.Count != tc.want {
+			t.Errorf("count %v: expected %d, got %d", tc.query, tc.want, resp.Count)
+		}
+		if got := strings.Contains(resp.Message, "index-only"); got != tc.indexOnly {
+			t.Errorf("count %v: index-only %v, message %q", tc.query, tc.indexOnly, resp.Message)
+		}
+	}
+}
+
+func TestDistinctIndexOnly(t *testing.T) {
+	mng := coveredCollection(t)
+
+	cases := []struct {
+		field     string
+		query     map[string]any
+		want      []any
+		indexOnly bool
+	}{
+		{"city", nil, []any{"Kazan", "Moscow", "Omsk"}, true},
+		{"city", map[string]any{"age": map[string]any{"$gt": 30.0}}, []any{"Kazan", "Moscow", "Omsk"}, true},
+		{"city", map[string]any{"age": map[string]any{"$lt": 30.0}}, []any{"Kazan"}, true},
+		{"age", map[string]any{"city": "Kazan"}, []any{25.0, 40.0}, true},
+		{"name", map[string]any{"city": "Omsk"}, []any{"kid", "max"}, false},
+	}
+	for _, tc := range cases {
+		resp := mustHandle(t, mng, api.Request{Command: api.CmdDistinct, Field: tc.field, Query: tc.query})
+		if !reflect.DeepEqual(resp.Values, tc.want) {
+			t.Errorf("distinct %s %v: expected %v, got %v", tc.field, tc.query, tc.want, resp.Values)
+		}
+		if got := strings.Contains(resp.Message, "index-only"); got != tc.indexOnly {
+			t.Errorf("distinct %s %v: index-only %v, message %q", tc.field, tc.query, tc.indexOnly, resp.Message)
+		}
+	}
+	mustFail(t, mng, api.Request{Command: api.CmdDistinct})
+}
+
+func TestCoveredFind(t *testing.T) {
+	mng := coveredCollection(t)
+	query := map[string]any{"age": map[string]any{"$gt": 30.0}}
+
+	if plan := testPlan(t, mng, query); !plan.covered {
+		t.Fatalf("query on an indexed field must be covered: %+v", plan)
+	}
+
+	// проекция только индексированных полей собирается из ключей индексов
+	resp := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: query, Projection: map[string]any{"age": 1, "city": 1, "_id": 0}})
+	want := []map[string]any{
+		{"age": 31.0, "city": "Moscow"},
+		{"age": 35.0, "city": "Omsk"},
+		{"age": 40.0, "city": "Kazan"},
+	}
+	if !reflect.DeepEqual(resp.Data, want) {
+		t.Fatalf("covered find: %v", resp.Data)
+	}
+	// поле без индекса читается из документов
+	resp = mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: query, Projection: map[string]any{"name": 1, "_id": 0}})
+	if len(resp.Data) != 3 || resp.Data[0]["name"] != "bob" {
+		t.Fatalf("find with an unindexed projection: %v", resp.Data)
+	}
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"fmt"
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+	"time"
+)
+
+// handleListDatabases возвращает базы и число коллекций в каждой
+func handleListDatabases(mng *storage.CollectionMng) api.Response {
+	names, err := mng.ListDatabases()
+	if err != nil {
+		return errorResponse(fmt.Errorf("failed to list databases: %w", err))
+	}
+	data := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		colls, err := mng.ListCollections(name)
+		if err != nil {
+			return errorResponse(fmt.Errorf("failed to list databases: %w", err))
+		}
+		data = append(data, map[string]any{"name": name, "collections": len(colls)})
+	}
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("Found %d database(s)", len(data)),
+		Data:    data,
+		Count:   len(data),
+	}
+}
+
+// handleListCollections возвращает коллекции базы с их движками хранения
+func handleListCollections(mng *storage.CollectionMng, req api.Request) api.Response {
+	if err := storage.ValidateDatabaseName("database", req.Database); err != nil {
+		return errorResponse(err)
+	}
+	infos, err := mng.ListCollections(req.Database)
+	if err != nil {
+		return errorResponse(fmt.Errorf("failed to list collections: %w", err))
+	}
+	data := make([]map[string]any, 0, len(infos))
+	for _, info := range infos {
+		data = append(data, map[string]any{"name": info.Name, "engine": info.Engine})
+	}
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("Found %d collection(s) in '%s'", len(data), req.Database),
+		Data:    data,
+		Count:   len(data),
+	}
+}
+
+// handleDropDatabase удаляет базу со всеми коллекциями
+func handleDropDatabase(mng *storage.CollectionMng, req api.Request) api.Response {
+	if err := storage.ValidateDatabaseName("database", req.Database); err != nil {
+		return errorResponse(err)
+	}
+	dropped, err := mng.DropDatabase(req.Database)
+	if err != nil {
+		return errorResponse(err)
+	}
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("Database '%s' dropped with %d collection(s)", req.Database, dropped),
+		Count:   dropped,
+	}
+}
+
+// handleStats возвращает память открытых коллекций: документы и индексы по отдельности
+func handleStats(mng *storage.CollectionMng) api.Response {
+	stats := mng.MemoryStats()
+	data := make([]map[string]any, 0, len(stats.Collections))
+	for _, s := range stats.Collections {
+		data = append(data, map[string]any{
+			"database":    s.Namespace.DB,
+			"collection":  s.Namespace.Coll,
+			"engine":      s.Engine,
+			"documents":   s.Documents,
+			"data_bytes":  s.DataBytes,
+			"index_bytes": s.IndexBytes,
+			"last_used":   s.LastUsed.Format(time.RFC3339),
+		})
+	}
+	limit := "unlimited"
+	if stats.Limit > 0 {
+		limit = fmt.Sprintf("%d bytes", stats.Limit)
+	}
+	return api.Response{
+		Status: api.StatusSuccess,
+		Message: fmt.Sprintf("%d collection(s) in memory: %d bytes, limit %s, %d eviction(s)",
+			len(data), stats.Total, limit, stats.Evictions),
+		Data:  data,
+		Count: len(data),
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"nosql_db/internal/api"
+)
+
+func TestDatabaseCommands(t *testing.T) {
+	mng := testManager(t)
+	s := NewSession(mng, nil)
+
+	// без базы запрос идёт в текущую базу соединения
+	sessionDo(t, s, api.Request{Command: api.CmdInsert, Data: []map[string]any{{"n": 1.0}}}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdUse, Database: "shop"}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdInsert, Collection: "orders", Data: []map[string]any{{"n": 2.0}, {"n": 3.0}}}, true)
+	// database без collection — имя коллекции в текущей базе
+	if resp := s.Handle(api.Request{Command: api.CmdCount, Database: "orders"}); resp.Status != api.StatusSuccess || resp.Count != 2 {
+		t.Fatalf("orders in shop: %+v", resp)
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount}); resp.Count != 1 {
+		t.Fatalf("test in default: %d", resp.Count)
+	}
+	sessionDo(t, s, api.Request{Command: api.CmdUse, Database: "../etc"}, false)
+	// точка отделяет базу от коллекции в "база.коллекция": в имени базы она запрещена,
+	// в имени коллекции — нет
+	sessionDo(t, s, api.Request{Command: api.CmdUse, Database: "shop.eu"}, false)
+	mustFail(t, mng, api.Request{Command: api.CmdInsert, Database: "shop.eu", Collection: "orders", Data: []map[string]any{{"n": 1.0}}})
+	sessionDo(t, s, api.Request{Command: api.CmdRenameCollection, Collection: "orders",
+		Options: map[string]any{"to": "orders", "to_database": "shop.eu"}}, false)
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, Database: "logs", Collection: "app.v1", Data: []map[string]any{{"n": 1.0}}})
+	mustHandle(t, mng, api.Request{Command: api.CmdDropDatabase, Database: "logs"})
+	mustFail(t, mng, api.Request{Command: api.CmdUse, Database: "shop"})
+
+	resp := mustHandle(t, mng, api.Request{Command: api.CmdListDatabases})
+	if !slices.Equal(foundNames(resp), []string{"default", "shop"}) || resp.Data[1]["collections"] != 1 {
+		t.Fatalf("list_databases: %v", resp.Data)
+	}
+	resp = sessionDo(t, s, api.Request{Command: api.CmdListCollections}, true)
+	if !slices.Equal(foundNames(resp), []string{"orders"}) || resp.Data[0]["engine"] != "hashmap" {
+		t.Fatalf("list_collections in shop: %v", resp.Data)
+	}
+
+	// rename_collection с to_database переносит коллекцию в другую базу
+	sessionDo(t, s, api.Request{Command: api.CmdRenameCollection, Collection: "orders",
+		Options: map[string]any{"to": "orders_old", "to_database": "archive"}}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdRenameCollection, Collection: "orders",
+		Options: map[string]any{"to": "x"}}, false)
+	sessionDo(t, s, api.Request{Command: api.CmdRenameCollection, Collection: "test", Database: "default",
+		Options: map[string]any{"to": "orders_old", "to_database": "archive"}}, false)
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount, Database: "archive", Collection: "orders_old"}); resp.Count != 2 {
+		t.Fatalf("renamed collection: %d", resp.Count)
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdListDatabases}); !slices.Equal(foundNames(resp), []string{"archive", "default"}) {
+		t.Fatalf("databases after rename: %v", resp.Data)
+	}
+
+	mustHandle(t, mng, api.Request{Command: api.CmdDropCollection})
+	mustFail(t, mng, api.Request{Command: api.CmdDropCollection})
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdDropDatabase, Database: "archive"}); resp.Count != 1 {
+		t.Fatalf("drop_database: %+v", resp)
+	}
+	mustFail(t, mng, api.Request{Command: api.CmdDropDatabase, Database: "archive"})
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdListDatabases}); resp.Count != 0 {
+		t.Fatalf("databases after drop: %v", resp.Data)
+	}
+}
+
+func TestStats(t *testing.T) {
+	mng := testManager(t)
+	insertDocs(t, mng, map[string]any{"name": "a"}, map[string]any{"name": "b"})
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"name": 1}})
+	mng.SetMemoryLimit(1 << 30)
+
+	resp := mustHandle(t, mng, api.Request{Command: api.CmdStats})
+	if resp.Count != 1 || !strings.Contains(resp.Message, "limit 1073741824 bytes") {
+		t.Fatalf("stats: %+v", resp)
+	}
+	s := resp.Data[0]
+	if s["database"] != "default" || s["collection"] != "test" || s["engine"] != "hashmap" || s["documents"] != 2 {
+		t.Fatalf("collection stats: %v", s)
+	}
+	if s["data_bytes"].(int64) <= 0 || s["index_bytes"].(int64) <= 0 {
+		t.Fatalf("memory of the collection: %v", s)
+	}
+}
@@ -5,42 +5,26 @@ import (
 	"nosql_db/internal/api"
 	"nosql_db/internal/operators"
 	"nosql_db/internal/storage"
+	"time"
 )
 
-func handleDelete(req api.Request) api.Response {
-	// Используем очередь для write-операции
-	result := storage.GlobalManager.Enqueue(req.Database, func(coll *storage.Collection) (storage.WriteResult, error) {
-		// Находим документы для удаления через FullScan
-		allDocs := coll.All()
-		deletedCount := 0
-
-		for _, doc := range allDocs {
-			if operators.MatchDocument(doc, req.Query) {
-				if id, ok := doc["_id"].(string); ok {
-					if coll.Delete(id) {
-						deletedCount++
-					}
-				}
-			}
-		}
+func handleDelete(mng *storage.CollectionMng, req api.Request) api.Response {
+	if err := checkScanQuery(req.Command, req.Query); err != nil {
+		return errorResponse(err)
+	}
 
-		if deletedCount > 0 {
-			if err := coll.Save(); err != nil {
-				return storage.WriteResult{}, fmt.Errorf("failed to save changes: %w", err)
-			}
-			if err := coll.RebuildAllIndexes(); err != nil {
-				return storage.WriteResult{}, fmt.Errorf("failed to rebuild indexes: %w", err)
-			}
-		}
+	// Используем очередь для write-операции
+	durability, err := storage.ParseDurability(req.Durability)
+	if err != nil {
+		return errorResponse(err)
+	}
 
-		return storage.WriteResult{
-			DeletedCount: deletedCount,
-			Message:      fmt.Sprintf("Deleted %d document(s)", deletedCount),
-		}, nil
+	result := mng.EnqueueDurable(namespaceOf(req), durability, func(coll *storage.Collection) (storage.WriteResult, error) {
+		return applyDelete(coll, req)
 	})
 
 	if result.Error != nil {
-		return api.Response{Status: api.StatusError, Message: result.Error.Error()}
+		return errorResponse(result.Error)
 	}
 
 	return api.Response{
@@ -49,3 +33,33 @@ func handleDelete(req api.Request) api.Response {
 		Count:   result.DeletedCount,
 	}
 }
+
+// applyDelete удаляет документы, подходящие под условие; выполняется в worker'е.
+// Индексы обновляются при каждом удалении, сохранение выполняет менеджер после фиксации
+func applyDelete(coll *storage.Collection, req api.Request) (storage.WriteResult, error) {
+	// из capped-коллекции документы уходят только вытеснением, в порядке вставки
+	if coll.Capped() != nil {
+		return storage.WriteResult{}, fmt.Errorf("cannot delete from capped collection '%s'", coll.Namespace())
+	}
+	// Находим документы для удаления: по _id напрямую, иначе через FullScan
+	allDocs := matchingDocuments(coll, req.Query)
+	expired := coll.Expired(time.Now())
+	deletedCount := 0
+
+	for _, doc := range allDocs {
+		if operators.MatchDocument(doc, req.Query) {
+			if id, ok := doc["_id"].(string); ok {
+				// истёкший документ удаляется, как его удалил бы reaper, но не считается:
+				// для клиента его уже нет
+				if coll.Delete(id) && !expired(doc) {
+					deletedCount++
+				}
+			}
+		}
+	}
+
+	return storage.WriteResult{
+		DeletedCount: deletedCount,
+		Message:      fmt.Sprintf("Deleted %d document(s)", deletedCount),
+	}, nil
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"nosql_db/internal/api"
+	"nosql_db/internal/index"
+	"nosql_db/internal/storage"
+	"sort"
+)
+
+func handleDistinct(snap *storage.Snapshot, req api.Request) api.Response {
+	if req.Field == "" {
+		return api.Response{Status: api.StatusError, Message: "field name required for distinct"}
+	}
+
+	plan, err := planRead(snap, req.Query)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	values, indexOnly := distinctFromIndex(snap, req, plan)
+	if !indexOnly {
+		values = distinctFromDocuments(snap, req, plan)
+	}
+
+	message := fmt.Sprintf("Found %d distinct value(s)", len(values))
+	if indexOnly {
+		message += " (index-only)"
+	}
+
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: message,
+		Values:  values,
+		Count:   len(values),
+	}
+}
+
+// distinctFromIndex берёт различные значения прямо из ключей индекса поля.
+// Работает, если запрос пустой или полностью покрыт индексами и коллекция не менялась после снимка
+func distinctFromIndex(snap *storage.Snapshot, req api.Request, plan queryPlan) ([]any, bool) {
+	coll := snap.Collection()
+	var allowed map[string]struct{}
+	if len(req.Query) > 0 {
+		if !plan.covered {
+			return nil, false
+		}
+		allowed = make(map[string]struct{}, len(plan.ids))
+		for _, id := range plan.ids {
+			allowed[id] = struct{}{}
+		}
+	}
+
+	values := []any{}
+	decoded := true
+	found := coll.ReadIndex(req.Field, func(btree *index.BTree) {
+		btree.Ascend(func(key index.Key, ids []index.Value) bool {
+			if allowed != nil && !containsAllowed(ids, allowed) {
+				return true
+			}
+			value, ok := index.KeyToValue(key)
+			if !ok {
+				decoded = false
+				return false
+			}
+			values = append(values, value)
+			return true
+		})
+	})
+	if !found || !decoded || snap.Stale() {
+		return nil, false
+	}
+	return values, true
+}
+
+func containsAllowed(ids []index.Value, allowed map[string]struct{}) bool {
+	for _, id := range ids {
+		if _, ok := allowed[string(id)]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// distinctFromDocuments собирает значения поля из подходящих документов,
+// порядок совпадает с порядком ключей индекса
+func distinctFromDocuments(snap *storage.Snapshot, req api.Request, plan queryPlan) []any {
+	var docs []map[string]any
+	if plan.useIndex {
+		docs = fetchCandidates(snap, plan)
+	} else {
+		docs = findFullScan(snap, req.Query)
+	}
+
+	seen := make(map[string]any)
+	for _, doc := range docs {
+		if value, ok := doc[req.Field]; ok {
+			seen[string(index.ValueToKey(value))] = value
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	values := make([]any, 0, len(keys))
+	for _, k := range keys {
+		values = append(values, seen[k])
+	}
+	return values
+}
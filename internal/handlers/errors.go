@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+	"os"
+	"syscall"
+)
+
+// errorResponse — ответ с ошибкой err и кодом её вида
+func errorResponse(err error) api.Response {
+	return api.Response{Status: api.StatusError, Message: err.Error(), Code: ErrorCode(err)}
+}
+
+// ErrorCode возвращает код ответа для ошибки: виды ошибок хранилища (storage.Err*),
+// истёкший срок и сбои файловой системы; пусто — ошибка в запросе клиента
+func ErrorCode(err error) string {
+	var pathErr *fs.PathError
+	var linkErr *os.LinkError
+	var sysErr *os.SyscallError
+	var errno syscall.Errno
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return api.CodeNotFound
+	case errors.Is(err, storage.ErrExists):
+		return api.CodeAlreadyExists
+	case errors.Is(err, storage.ErrValidation):
+		return api.CodeValidation
+	case errors.Is(err, storage.ErrNotDurable):
+		return api.CodeNotDurable
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.Is(err, context.DeadlineExceeded):
+		return api.CodeTimeout
+	case errors.As(err, &pathErr), errors.As(err, &linkErr), errors.As(err, &sysErr), errors.As(err, &errno):
+		return api.CodeInternal
+	}
+	return ""
+}
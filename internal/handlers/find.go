@@ -4,27 +4,80 @@ import (
 	"nosql_db/internal/api"
 	"nosql_db/internal/index"
 	"nosql_db/internal/operators"
+	"nosql_db/internal/query"
 	"nosql_db/internal/storage"
 )
 
-func handleFind(coll *storage.Collection, req api.Request) api.Response {
-	var results []map[string]any
-	usedIndex := false
-
-	if len(req.Query) == 1 && !hasLogicalOperators(req.Query) {
-		for field, condition := range req.Query {
-			if coll.HasIndex(field) {
-				results = findWithIndex(coll, field, condition)
-				usedIndex = true
-				break
+func handleFind(snap *storage.Snapshot, req api.Request) api.Response {
+	if after, ok := req.Options["after"]; ok {
+		return findAfter(snap, req, after)
+	}
+	plan, err := planRead(snap, req.Query)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	// покрывающий запрос: и условия, и проекция отвечаются листьями индексов
+	if plan.covered && plan.meta == nil && len(req.Sort) == 0 {
+		if results, ok := projectFromIndexes(snap, plan.ids, req.Projection); ok {
+			results = limitDocuments(results, req.Limit)
+			return api.Response{
+				Status: api.StatusSuccess,
+				Data:   results,
+				Count:  len(results),
 			}
 		}
 	}
 
-	if !usedIndex {
-		results = findFullScan(coll, req.Query)
+	var results []map[string]any
+	if plan.useIndex {
+		results = fetchCandidates(snap, plan)
+	} else {
+		results = findFullScan(snap, req.Query)
+	}
+
+	meta := query.MetaFields(req.Projection)
+	if len(req.Sort) > 0 {
+		sortDocuments(results, req.Sort, meta, plan.meta)
+	}
+	results = limitDocuments(results, req.Limit)
+
+	if len(req.Projection) > 0 {
+		for i, doc := range results {
+			projected := query.ApplyProjection(doc, req.Projection)
+			addMetaFields(projected, doc, meta, plan.meta)
+			results[i] = projected
+		}
+	}
+
+	return api.Response{
+		Status: api.StatusSuccess,
+		Data:   results,
+		Count:  len(results),
 	}
+}
 
+// findAfter возвращает порцию документов по возрастанию _id после options.after
+// ("" — с начала): постраничный обход коллекции, продолжаемый с последнего _id порции
+func findAfter(snap *storage.Snapshot, req api.Request, after any) api.Response {
+	id, ok := after.(string)
+	if !ok {
+		return api.Response{Status: api.StatusError, Message: "options.after must be a string _id"}
+	}
+	if len(req.Sort) > 0 {
+		return api.Response{Status: api.StatusError, Message: "options.after returns documents in _id order and cannot be combined with sort"}
+	}
+	if err := checkScanQuery("find with options.after", req.Query); err != nil {
+		return errorResponse(err)
+	}
+	results := snap.Page(id, req.Limit, func(doc map[string]any) bool {
+		return operators.MatchDocument(doc, req.Query)
+	})
+	if len(req.Projection) > 0 {
+		for i, doc := range results {
+			results[i] = query.ApplyProjection(doc, req.Projection)
+		}
+	}
 	return api.Response{
 		Status: api.StatusSuccess,
 		Data:   results,
@@ -32,15 +85,38 @@ func handleFind(coll *storage.Collection, req api.Request) api.Response {
 	}
 }
 
+// limitDocuments обрезает результат до limit документов (0 — без ограничения)
+func limitDocuments(docs []map[string]any, limit int) []map[string]any {
+	if limit > 0 && len(docs) > limit {
+		return docs[:limit]
+	}
+	return docs
+}
+
+// addMetaFields добавляет в документ поля {"$meta": ...} из проекции
+func addMetaFields(projected, doc map[string]any, meta map[string]string, values map[string]map[string]float64) {
+	for field, kind := range meta {
+		if byID, ok := values[kind]; ok {
+			projected[field] = metaValue(doc, byID)
+		}
+	}
+}
+
+// metaValue возвращает значение $meta (релевантность, расстояние) для документа
+func metaValue(doc map[string]any, byID map[string]float64) float64 {
+	id, _ := doc["_id"].(string)
+	return byID[id]
+}
+
 func hasLogicalOperators(conditions map[string]any) bool {
 	_, hasOr := conditions["$or"]
 	_, hasAnd := conditions["$and"]
 	return hasOr || hasAnd
 }
 
-func findFullScan(coll *storage.Collection, queryMap map[string]any) []map[string]any {
+func findFullScan(snap *storage.Snapshot, queryMap map[string]any) []map[string]any {
 	var results []map[string]any
-	allDocs := coll.All()
+	allDocs := snap.All()
 
 	for _, doc := range allDocs {
 		if operators.MatchDocument(doc, queryMap) {
@@ -50,48 +126,73 @@ func findFullScan(coll *storage.Collection, queryMap map[string]any) []map[strin
 	return results
 }
 
-func findWithIndex(coll *storage.Collection, field string, condition any) []map[string]any {
-	btree, ok := coll.GetIndex(field)
-	if !ok {
-		return nil
-	}
-
-	var docIDs []string
-	switch v := condition.(type) {
-	case float64, int, int64, string, bool:
-		key := index.ValueToKey(v)
-		values := btree.Search(key)
-		docIDs = index.ValuesToStrings(values)
-	case map[string]any:
-		if gtValue, exists := v["$gt"]; exists {
-			key := index.ValueToKey(gtValue)
-			values := btree.SearchGreaterThan(key)
-			docIDs = index.ValuesToStrings(values)
-		} else if ltValue, exists := v["$lt"]; exists {
-			key := index.ValueToKey(ltValue)
-			values := btree.SearchLessThan(key)
-			docIDs = index.ValuesToStrings(values)
-		} else if eqValue, exists := v["$eq"]; exists {
-			key := index.ValueToKey(eqValue)
-			values := btree.Search(key)
-			docIDs = index.ValuesToStrings(values)
-		} else if inValues, exists := v["$in"]; exists {
-			if inArray, ok := inValues.([]any); ok {
-				var keys []index.Key
-				for _, val := range inArray {
-					keys = append(keys, index.ValueToKey(val))
-				}
-				values := btree.SearchIn(keys)
-				docIDs = index.ValuesToStrings(values)
-			}
+// fetchCandidates читает документы-кандидаты плана; если план не покрывающий,
+// оставшиеся условия проверяются по самому документу
+func fetchCandidates(snap *storage.Snapshot, plan queryPlan) []map[string]any {
+	var results []map[string]any
+	for _, id := range plan.ids {
+		doc, ok := snap.Get(id)
+		if !ok {
+			continue
+		}
+		if !plan.covered && !operators.MatchDocument(doc, plan.residual) {
+			continue
+		}
+		results = append(results, doc)
+	}
+	return results
+}
+
+// projectFromIndexes собирает результат покрывающего запроса из ключей индексов:
+// каждое поле проекции должно иметь индекс, а его ключи — восстанавливаться в значения.
+// false, если проекция не покрывается индексами или коллекция изменилась после снимка
+func projectFromIndexes(snap *storage.Snapshot, ids []string, projection map[string]any) ([]map[string]any, bool) {
+	coll := snap.Collection()
+	if !query.IsInclusionProjection(projection) {
+		return nil, false
+	}
+
+	withID := query.IncludesID(projection)
+	docs := make(map[string]map[string]any, len(ids))
+	for _, id := range ids {
+		doc := make(map[string]any)
+		if withID {
+			doc["_id"] = id
 		}
+		docs[id] = doc
 	}
 
-	var results []map[string]any
-	for _, id := range docIDs {
-		if doc, ok := coll.GetByID(id); ok {
-			results = append(results, doc)
+	for _, field := range query.ProjectedFields(projection) {
+		decoded := true
+		found := coll.ReadIndex(field, func(btree *index.BTree) {
+			btree.Ascend(func(key index.Key, values []index.Value) bool {
+				for _, v := range values {
+					doc, ok := docs[string(v)]
+					if !ok {
+						continue
+					}
+					value, ok := index.KeyToValue(key)
+					if !ok {
+						decoded = false
+						return false
+					}
+					doc[field] = value
+				}
+				return true
+			})
+		})
+		if !found || !decoded {
+			return nil, false
 		}
 	}
-	return results
+
+	if snap.Stale() {
+		return nil, false
+	}
+
+	results := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		results = append(results, docs[id])
+	}
+	return results, true
 }
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+)
+
+func TestTextSearch(t *testing.T) {
+	dir := t.TempDir()
+	mng := openManager(t, dir)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"name": 1, "description": 1}, Options: map[string]any{"type": api.IndexTypeText}})
+	insertDocs(t, mng,
+		map[string]any{"name": "Trail running shoes", "description": "Shoes for running on rocks", "price": 120.0},
+		map[string]any{"name": "Office shoes", "description": "Leather", "price": 90.0},
+		map[string]any{"name": "Running socks", "description": "Thin", "price": 10.0},
+		map[string]any{"name": "Кроссовки для бега", "description": "Лёгкие беговые кроссовки", "price": 70.0},
+	)
+
+	// релевантность проецируется через $meta и по ней сортируется
+	search := func(mng *storage.CollectionMng, text string, filter map[string]any) api.Response {
+		t.Helper()
+		query := map[string]any{"$text": map[string]any{"$search": text}}
+		for field, condition := range filter {
+			query[field] = condition
+		}
+		return mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: query,
+			Projection: map[string]any{"name": 1, "score": map[string]any{"$meta": "textScore"}}, Sort: []string{"-score"}})
+	}
+	resp := search(mng, "running", nil)
+	if got := foundNames(resp); !slices.Equal(got, []string{"Trail running shoes", "Running socks"}) {
+		t.Fatalf("$text ranking: %v", got)
+	}
+	if first, second := resp.Data[0]["score"].(float64), resp.Data[1]["score"].(float64); first <= second || second <= 0 {
+		t.Fatalf("scores: %v", resp.Data)
+	}
+	if got := foundNames(search(mng, "running", map[string]any{"price": map[string]any{"$lt": 50.0}})); !slices.Equal(got, []string{"Running socks"}) {
+		t.Fatalf("$text with a filter: %v", got)
+	}
+	if got := foundNames(search(mng, "кроссовками", nil)); !slices.Equal(got, []string{"Кроссовки для бега"}) {
+		t.Fatalf("russian $text: %v", got)
+	}
+
+	// индекс лежит рядом с b-tree индексами и переживает перезапуск
+	if got := foundNames(search(openManager(t, dir), "shoes -office", nil)); !slices.Equal(got, []string{"Trail running shoes"}) {
+		t.Fatalf("$text after reopen: %v", got)
+	}
+
+	mustFail(t, mng, api.Request{Command: api.CmdFind, Collection: "plain", Query: map[string]any{"$text": map[string]any{"$search": "x"}}})
+}
+
+func TestTextOperatorPlacement(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"body": 1}, Options: map[string]any{"type": api.IndexTypeText}})
+	insertDocs(t, mng,
+		map[string]any{"body": "running shoes", "tag": "a"},
+		map[string]any{"body": "red apples", "tag": "b"},
+	)
+
+	// $text на верхнем уровне вместе с $or — поддерживается
+	found := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{
+		"$text": map[string]any{"$search": "run"},
+		"$or":   []any{map[string]any{"tag": "a"}, map[string]any{"tag": "b"}},
+	}})
+	if found.Count != 1 || found.Data[0]["tag"] != "a" {
+		t.Fatalf("top-level $text: %v", found.Data)
+	}
+
+	for _, q := range []map[string]any{
+		{"$or": []any{map[string]any{"$text": map[string]any{"$search": "run"}}, map[string]any{"tag": "b"}}},
+		{"$and": []any{map[string]any{"$or": []any{map[string]any{"$text": map[string]any{"$search": "run"}}}}}},
+		{"body": map[string]any{"$text": map[string]any{"$search": "run"}}},
+	} {
+		for _, command := range []string{api.CmdFind, api.CmdCount} {
+			resp := mustFail(t, mng, api.Request{Command: command, Query: q})
+			if !strings.Contains(resp.Message, "$text") {
+				t.Fatalf("%s %v: unexpected error %q", command, q, resp.Message)
+			}
+		}
+	}
+}
+
+// TestTextRejectedWithoutPlan: update, delete и find с options.after сверяют запрос с документами
+// без индекса, поэтому $text в них — ошибка, а не условие на поле "$text"
+func TestTextRejectedWithoutPlan(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"body": 1}, Options: map[string]any{"type": api.IndexTypeText}})
+	insertDocs(t, mng, map[string]any{"body": "running shoes"})
+	text := map[string]any{"$text": map[string]any{"$search": "run"}}
+
+	for _, req := range []api.Request{
+		{Command: api.CmdUpdate, Query: text, Update: map[string]any{"$set": map[string]any{"seen": true}}},
+		{Command: api.CmdDelete, Query: text},
+		{Command: api.CmdFind, Query: text, Options: map[string]any{"after": ""}},
+	} {
+		resp := mustFail(t, mng, req)
+		if !strings.Contains(resp.Message, "$text") {
+			t.Fatalf("%s: unexpected error %q", req.Command, resp.Message)
+		}
+	}
+
+	s := NewSession(mng, nil)
+	sessionDo(t, s, api.Request{Command: api.CmdBegin}, true)
+	if resp := s.Handle(api.Request{Command: api.CmdDelete, Collection: "test", Query: text}); resp.Status != api.StatusError {
+		t.Fatalf("delete with $text in a transaction: %+v", resp)
+	}
+
+	if got := mustHandle(t, mng, api.Request{Command: api.CmdCount}); got.Count != 1 {
+		t.Fatalf("documents changed by a rejected request: %d", got.Count)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"nosql_db/internal/geo"
+	"nosql_db/internal/storage"
+	"sort"
+)
+
+// isGeoCondition проверяет, что условие поля — $near или $geoWithin
+func isGeoCondition(condition any) bool {
+	condMap, ok := condition.(map[string]any)
+	if !ok {
+		return false
+	}
+	_, near := condMap["$near"]
+	_, within := condMap["$geoWithin"]
+	return near || within
+}
+
+// geoCandidates ищет кандидатов по гео-индексу поля.
+// Для $near кандидаты упорядочены по расстоянию и возвращаются расстояния до центра;
+// nil без ошибки — индекса нет, и $geoWithin проверяется полным просмотром
+func geoCandidates(coll *storage.Collection, field string, condition map[string]any) ([]string, map[string]float64, error) {
+	if nearSpec, ok := condition["$near"]; ok {
+		near, err := geo.ParseNear(nearSpec)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !coll.HasGeoIndex(field) {
+			return nil, nil, fmt.Errorf("geo index on field '%s' required for $near query", field)
+		}
+		return nearCandidates(coll, field, near)
+	}
+
+	shape, err := geo.ParseShape(condition["$geoWithin"])
+	if err != nil {
+		return nil, nil, err
+	}
+	ids, ok := coll.SearchGeo(field, shape.Bounds())
+	if !ok {
+		return nil, nil, nil
+	}
+	return ids, nil, nil
+}
+
+// nearCandidates выбирает документы не дальше $maxDistance (или все точки индекса)
+// и сортирует их по расстоянию по формуле гаверсинусов
+func nearCandidates(coll *storage.Collection, field string, near geo.Near) ([]string, map[string]float64, error) {
+	box := geo.Box{Min: geo.Point{Lon: -180, Lat: -90}, Max: geo.Point{Lon: 180, Lat: 90}}
+	if near.MaxDistance > 0 {
+		box = geo.Circle{Center: near.Center, Radius: near.MaxDistance}.Bounds()
+	}
+	candidates, _ := coll.SearchGeo(field, box)
+
+	distances := make(map[string]float64, len(candidates))
+	ids := make([]string, 0, len(candidates))
+	for _, id := range uniqueIDs(candidates) {
+		doc, ok := coll.GetByID(id)
+		if !ok {
+			continue
+		}
+		point, ok := geo.ParsePoint(doc[field])
+		if !ok || !near.Matches(point) {
+			continue
+		}
+		distances[id] = geo.Distance(near.Center, point)
+		ids = append(ids, id)
+	}
+
+	sort.SliceStable(ids, func(i, j int) bool {
+		return distances[ids[i]] < distances[ids[j]]
+	})
+	return ids, distances, nil
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+
+	"nosql_db/internal/api"
+)
+
+func TestGeoWithinAcrossAntimeridian(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"loc": 1}, Options: map[string]any{"type": api.IndexTypeGeo}})
+	insertDocs(t, mng,
+		map[string]any{"name": "fiji", "loc": []any{178.4, -18.1}},
+		map[string]any{"name": "samoa", "loc": []any{-171.8, -13.8}},
+		map[string]any{"name": "tonga", "loc": []any{-175.2, -21.1}},
+		map[string]any{"name": "greenwich", "loc": []any{0.0, -15.0}},
+	)
+	want := []string{"fiji", "samoa", "tonga"}
+
+	for _, shape := range []map[string]any{
+		// юго-западный угол восточнее северо-восточного — прямоугольник через 180-й меридиан
+		{"$box": []any{[]any{170.0, -25.0}, []any{-165.0, -10.0}}},
+		{"$polygon": []any{[]any{170.0, -25.0}, []any{-165.0, -25.0}, []any{-165.0, -10.0}, []any{170.0, -10.0}}},
+		{"$geometry": map[string]any{"type": "Polygon", "coordinates": []any{
+			[]any{[]any{170.0, -25.0}, []any{-165.0, -25.0}, []any{-165.0, -10.0}, []any{170.0, -10.0}, []any{170.0, -25.0}},
+		}}},
+		{"$center": []any{[]any{180.0, -17.0}, 1200000.0}},
+	} {
+		resp := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{"loc": map[string]any{"$geoWithin": shape}}, Sort: []string{"name"}})
+		if got := foundNames(resp); !slices.Equal(got, want) {
+			t.Errorf("%v: expected %v, got %v", shape, want, got)
+		}
+	}
+
+	near := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{"loc": map[string]any{"$near": map[string]any{
+		"$geometry": []any{179.9, -18.0}, "$maxDistance": 1500000.0,
+	}}}})
+	if got := foundNames(near); !slices.Equal(got, []string{"fiji", "tonga", "samoa"}) {
+		t.Errorf("$near across the antimeridian: %v", got)
+	}
+}
+
+func TestCreateIndexArguments(t *testing.T) {
+	mng := testManager(t)
+	for _, indexType := range []string{api.IndexTypeBTree, api.IndexTypeGeo, api.IndexTypeHash, api.IndexTypeVector} {
+		mustFail(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"a": 1, "b": 1}, Options: map[string]any{"type": indexType}})
+	}
+	mustFail(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"a": 1}, Options: map[string]any{"type": 1.0}})
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"a": 1, "b": 1}, Options: map[string]any{"type": api.IndexTypeText}})
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdListIndexes}); resp.Count != 1 {
+		t.Fatalf("only the text index must be created: %v", resp.Data)
+	}
+}
+
+func TestGeoNearOrder(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"loc": 1}, Options: map[string]any{"type": api.IndexTypeGeo}})
+	point := func(lon, lat float64) map[string]any {
+		return map[string]any{"type": "Point", "coordinates": []any{lon, lat}}
+	}
+	insertDocs(t, mng,
+		map[string]any{"name": "berlin", "loc": point(13.405, 52.52)},
+		map[string]any{"name": "petersburg", "loc": []any{30.3351, 59.9343}},
+		map[string]any{"name": "kazan", "loc": point(49.1221, 55.7887)},
+		map[string]any{"name": "tver", "loc": point(35.9006, 56.8587)},
+		map[string]any{"name": "nowhere"},
+	)
+
+	near := func(query map[string]any, limit int) []string {
+		t.Helper()
+		return foundNames(mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{"loc": map[string]any{"$near": query}}, Limit: limit}))
+	}
+	moscow := point(37.6173, 55.7558)
+	if got := near(map[string]any{"$geometry": moscow}, 0); !slices.Equal(got, []string{"tver", "petersburg", "kazan", "berlin"}) {
+		t.Errorf("$near order: %v", got)
+	}
+	if got := near(map[string]any{"$geometry": moscow, "$maxDistance": 800000.0}, 0); !slices.Equal(got, []string{"tver", "petersburg", "kazan"}) {
+		t.Errorf("$near with $maxDistance: %v", got)
+	}
+	if got := near(map[string]any{"$geometry": moscow}, 2); !slices.Equal(got, []string{"tver", "petersburg"}) {
+		t.Errorf("$near with limit: %v", got)
+	}
+
+	// GeoJSON точки и пары [lon, lat] в одном индексе, документ без точки не попадает в выборку
+	within := mustHandle(t, mng, api.Request{Command: api.CmdFind, Sort: []string{"name"}, Query: map[string]any{"loc": map[string]any{
+		"$geoWithin": map[string]any{"$box": []any{[]any{29.0, 55.0}, []any{38.0, 60.0}}},
+	}}})
+	if got := foundNames(within); !slices.Equal(got, []string{"petersburg", "tver"}) {
+		t.Errorf("$geoWithin over mixed points: %v", got)
+	}
+	mustFail(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{"loc": map[string]any{"$near": []any{200.0, 0.0}}}})
+}
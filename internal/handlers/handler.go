@@ -6,30 +6,121 @@ import (
 	"nosql_db/internal/storage"
 )
 
-// HandleRequest — точка входа для обработки запросов
-func HandleRequest(req api.Request) api.Response {
-	if req.Database == "" {
-		return api.Response{Status: api.StatusError, Message: "database name is required"}
+// HandleRequest — точка входа для обработки запросов без сессии
+func HandleRequest(mng *storage.CollectionMng, req api.Request) api.Response {
+	switch req.Command {
+	case api.CmdBegin, api.CmdCommit, api.CmdAbort:
+		return api.Response{Status: api.StatusError, Message: "transactions require a connection session"}
+	case api.CmdUse, api.CmdReplStatus, api.CmdAddMember, api.CmdRemoveMember:
+		return api.Response{Status: api.StatusError, Message: req.Command + " requires a connection session"}
+	case api.CmdShardCollection, api.CmdSplitChunk, api.CmdMoveChunk, api.CmdShardingStatus:
+		return api.Response{Status: api.StatusError, Message: req.Command + " is handled by the router"}
+	}
+	req = ResolveRequest(req, storage.DefaultDatabase)
+
+	switch req.Command {
+	case api.CmdListDatabases:
+		return handleListDatabases(mng)
+	case api.CmdListCollections:
+		return handleListCollections(mng, req)
+	case api.CmdDropDatabase:
+		return handleDropDatabase(mng, req)
+	case api.CmdStats:
+		return handleStats(mng)
+	}
+	if err := validateNamespace(req); err != nil {
+		return errorResponse(err)
 	}
 
 	switch req.Command {
 	case api.CmdInsert:
 		// Write-операция через очередь
-		return handleInsert(req)
-	case api.CmdFind:
-		// Read-операция напрямую (не требует очереди)
-		coll, err := storage.GlobalManager.GetCollection(req.Database)
+		return handleInsert(mng, req)
+	case api.CmdFind, api.CmdCount, api.CmdDistinct:
+		// Read-операции напрямую (не требуют очереди)
+		coll, err := mng.GetCollection(namespaceOf(req))
 		if err != nil {
-			return api.Response{Status: api.StatusError, Message: fmt.Sprintf("failed to load database: %v", err)}
+			return errorResponse(fmt.Errorf("failed to load database: %w", err))
 		}
-		return handleFind(coll, req)
+		defer coll.Release()
+		return handleRead(coll, req)
 	case api.CmdDelete:
 		// Write-операция через очередь
-		return handleDelete(req)
+		return handleDelete(mng, req)
+	case api.CmdUpdate:
+		// Write-операция через очередь
+		return handleUpdate(mng, req)
+	case api.CmdWatch:
+		return api.Response{Status: api.StatusError, Message: "watch requires a connection session"}
 	case api.CmdCreateIndex:
 		// Write-операция через очередь
-		return handleCreateIndex(req)
+		return handleCreateIndex(mng, req)
+	case api.CmdListIndexes:
+		return handleListIndexes(mng, req)
+	case api.CmdCreateCollection:
+		// Выполняется за барьером в очереди коллекции
+		return handleCreateCollection(mng, req)
+	case api.CmdDropCollection:
+		return handleDropCollection(mng, req)
+	case api.CmdRenameCollection:
+		return handleRenameCollection(mng, req)
+	case api.CmdCollMod:
+		return handleCollMod(mng, req)
 	default:
 		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("unknown command: %s", req.Command)}
 	}
 }
+
+// ResolveRequest приводит запрос к виду «база + коллекция». Запрос без collection
+// по-старому называет полем database коллекцию; база по умолчанию — текущая база сессии
+func ResolveRequest(req api.Request, current string) api.Request {
+	if isDatabaseCommand(req.Command) {
+		if req.Database == "" {
+			req.Database = current
+		}
+		return req
+	}
+	if req.Collection == "" {
+		req.Collection, req.Database = req.Database, ""
+	}
+	if req.Database == "" {
+		req.Database = current
+	}
+	return req
+}
+
+// isDatabaseCommand — команды, у которых поле database — имя базы, а не коллекции
+func isDatabaseCommand(command string) bool {
+	switch command {
+	case api.CmdUse, api.CmdListDatabases, api.CmdListCollections, api.CmdDropDatabase, api.CmdStats:
+		return true
+	}
+	return false
+}
+
+func namespaceOf(req api.Request) storage.Namespace {
+	return storage.Namespace{DB: req.Database, Coll: req.Collection}
+}
+
+func validateNamespace(req api.Request) error {
+	if err := storage.ValidateDatabaseName("database", req.Database); err != nil {
+		return err
+	}
+	return storage.ValidateName("collection", req.Collection)
+}
+
+// handleRead выбирает обработчик read-операции; чтение идёт из снимка коллекции,
+// поэтому незафиксированные и частично применённые записи не видны
+func handleRead(coll *storage.Collection, req api.Request) api.Response {
+	snap := coll.Snapshot()
+	defer snap.Release()
+
+	switch req.Command {
+	case api.CmdCount:
+		return handleCount(snap, req)
+	case api.CmdDistinct:
+		return handleDistinct(snap, req)
+	default:
+		return handleFind(snap, req)
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"testing"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+)
+
+// testManager открывает менеджер коллекций во временном каталоге
+func testManager(t *testing.T) *storage.CollectionMng {
+	t.Helper()
+	return openManager(t, t.TempDir())
+}
+
+// openManager открывает менеджер коллекций в каталоге dir: второй менеджер
+// в том же каталоге читает то, что записал первый, как сервер после перезапуска
+func openManager(t *testing.T, dir string) *storage.CollectionMng {
+	t.Helper()
+	mng := storage.NewManager()
+	if err := mng.OpenDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mng.Stop)
+	return mng
+}
+
+// mustHandle выполняет запрос к коллекции test и проверяет, что он успешен
+func mustHandle(t *testing.T, mng *storage.CollectionMng, req api.Request) api.Response {
+	t.Helper()
+	if req.Collection == "" {
+		req.Collection = "test"
+	}
+	resp := HandleRequest(mng, req)
+	if resp.Status != api.StatusSuccess {
+		t.Fatalf("%s %v: %s", req.Command, req.Query, resp.Message)
+	}
+	return resp
+}
+
+// mustFail выполняет запрос к коллекции test и проверяет, что он отклонён
+func mustFail(t *testing.T, mng *storage.CollectionMng, req api.Request) api.Response {
+	t.Helper()
+	if req.Collection == "" {
+		req.Collection = "test"
+	}
+	resp := HandleRequest(mng, req)
+	if resp.Status != api.StatusError {
+		t.Fatalf("%s %v: expected an error, got %+v", req.Command, req.Query, resp)
+	}
+	return resp
+}
+
+// insertDocs вставляет документы в коллекцию test
+func insertDocs(t *testing.T, mng *storage.CollectionMng, docs ...map[string]any) {
+	t.Helper()
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, Data: docs})
+}
+
+// foundNames возвращает поле name найденных документов в порядке ответа
+func foundNames(resp api.Response) []string {
+	names := make([]string, 0, len(resp.Data))
+	for _, doc := range resp.Data {
+		name, _ := doc["name"].(string)
+		names = append(names, name)
+	}
+	return names
+}
+
+// testPlan строит план запроса к коллекции test
+func testPlan(t *testing.T, mng *storage.CollectionMng, conditions map[string]any) queryPlan {
+	t.Helper()
+	coll, err := mng.GetCollection(storage.Namespace{DB: storage.DefaultDatabase, Coll: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer coll.Release()
+	plan, err := planQuery(coll, conditions)
+	if err != nil {
+		t.Fatalf("plan %v: %v", conditions, err)
+	}
+	return plan
+}
@@ -4,32 +4,173 @@ import (
 	"fmt"
 	"nosql_db/internal/api"
 	"nosql_db/internal/storage"
+	"nosql_db/internal/vector"
+	"sort"
 )
 
-func handleCreateIndex(req api.Request) api.Response {
-	fieldName := ""
+func handleCreateIndex(mng *storage.CollectionMng, req api.Request) api.Response {
+	fields := make([]string, 0, len(req.Query))
 	for k := range req.Query {
-		fieldName = k
-		break
+		fields = append(fields, k)
 	}
+	sort.Strings(fields)
 
-	if fieldName == "" {
+	if len(fields) == 0 {
 		return api.Response{Status: api.StatusError, Message: "field name required in query"}
 	}
 
-	// Используем очередь для write-операции
-	result := storage.GlobalManager.Enqueue(req.Database, func(coll *storage.Collection) (storage.WriteResult, error) {
-		if err := coll.CreateIndex(fieldName, 64); err != nil {
-			return storage.WriteResult{}, fmt.Errorf("failed to create index: %w", err)
+	indexType := api.IndexTypeBTree
+	if t, exists := req.Options["type"]; exists {
+		name, ok := t.(string)
+		if !ok {
+			return api.Response{Status: api.StatusError, Message: "index type must be a string"}
 		}
+		if name != "" {
+			indexType = name
+		}
+	}
+	// составной бывает только текстовый индекс: остальные строятся по одному полю
+	if indexType != api.IndexTypeText && len(fields) > 1 {
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("%s index supports a single field, got %v", indexType, fields)}
+	}
+
+	if ttl, ok := req.Options["expireAfterSeconds"]; ok {
+		// TTL-индекс — b-tree по полю с датой, по которой истекают документы
+		seconds, isNumber := ttl.(float64)
+		if !isNumber || seconds < 0 || seconds != float64(int64(seconds)) {
+			return api.Response{Status: api.StatusError, Message: "expireAfterSeconds must be a non-negative integer"}
+		}
+		if indexType != api.IndexTypeBTree || len(fields) != 1 {
+			return api.Response{Status: api.StatusError, Message: "expireAfterSeconds requires a single-field btree index"}
+		}
+		fieldName := fields[0]
+		return enqueueIndexCreation(mng, req, func(coll *storage.Collection) (storage.WriteResult, error) {
+			if err := coll.CreateTTLIndex(fieldName, int64(seconds)); err != nil {
+				return storage.WriteResult{}, fmt.Errorf("failed to create TTL index: %w", err)
+			}
+			return storage.WriteResult{
+				Message: fmt.Sprintf("TTL index created on field '%s': documents expire %d second(s) after it", fieldName, int64(seconds)),
+			}, nil
+		})
+	}
+
+	var operation func(coll *storage.Collection) (storage.WriteResult, error)
+	switch indexType {
+	case api.IndexTypeBTree:
+		fieldName := fields[0]
+		operation = func(coll *storage.Collection) (storage.WriteResult, error) {
+			if err := coll.CreateIndex(fieldName, 64); err != nil {
+				return storage.WriteResult{}, fmt.Errorf("failed to create index: %w", err)
+			}
+			return storage.WriteResult{
+				Message: fmt.Sprintf("Index created on field '%s'", fieldName),
+			}, nil
+		}
+	case api.IndexTypeText:
+		operation = func(coll *storage.Collection) (storage.WriteResult, error) {
+			name, err := coll.CreateTextIndex(fields)
+			if err != nil {
+				return storage.WriteResult{}, fmt.Errorf("failed to create text index: %w", err)
+			}
+			return storage.WriteResult{
+				Message: fmt.Sprintf("Text index '%s' created on fields %v", name, fields),
+			}, nil
+		}
+	case api.IndexTypeGeo:
+		fieldName := fields[0]
+		operation = func(coll *storage.Collection) (storage.WriteResult, error) {
+			if err := coll.CreateGeoIndex(fieldName); err != nil {
+				return storage.WriteResult{}, fmt.Errorf("failed to create geo index: %w", err)
+			}
+			return storage.WriteResult{
+				Message: fmt.Sprintf("Geo index created on field '%s'", fieldName),
+			}, nil
+		}
+	case api.IndexTypeHash:
+		fieldName := fields[0]
+		operation = func(coll *storage.Collection) (storage.WriteResult, error) {
+			if err := coll.CreateHashIndex(fieldName); err != nil {
+				return storage.WriteResult{}, fmt.Errorf("failed to create hash index: %w", err)
+			}
+			return storage.WriteResult{
+				Message: fmt.Sprintf("Hash index created on field '%s'", fieldName),
+			}, nil
+		}
+	case api.IndexTypeVector:
+		fieldName := fields[0]
+		metricName, _ := req.Options["metric"].(string)
+		metric, err := vector.ParseMetric(metricName)
+		if err != nil {
+			return errorResponse(err)
+		}
+		dims := 0
+		if d, ok := req.Options["dimensions"].(float64); ok {
+			if d < 1 {
+				return api.Response{Status: api.StatusError, Message: "dimensions must be positive"}
+			}
+			dims = int(d)
+		}
+		operation = func(coll *storage.Collection) (storage.WriteResult, error) {
+			if err := coll.CreateVectorIndex(fieldName, metric, dims); err != nil {
+				return storage.WriteResult{}, fmt.Errorf("failed to create vector index: %w", err)
+			}
+			return storage.WriteResult{
+				Message: fmt.Sprintf("Vector index (%s) created on field '%s'", metric, fieldName),
+			}, nil
+		}
+	default:
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("unknown index type: %s", indexType)}
+	}
+	return enqueueIndexCreation(mng, req, operation)
+}
+
+// handleListIndexes возвращает индексы коллекции: тип, поля и параметры
+func handleListIndexes(mng *storage.CollectionMng, req api.Request) api.Response {
+	coll, err := mng.GetCollection(namespaceOf(req))
+	if err != nil {
+		return errorResponse(fmt.Errorf("failed to load database: %w", err))
+	}
+	indexes := coll.Spec().Indexes
+	coll.Release()
 
-		return storage.WriteResult{
-			Message: fmt.Sprintf("Index created on field '%s'", fieldName),
-		}, nil
+	sort.Slice(indexes, func(i, j int) bool {
+		if indexes[i].Type != indexes[j].Type {
+			return indexes[i].Type < indexes[j].Type
+		}
+		return fmt.Sprint(indexes[i].Fields) < fmt.Sprint(indexes[j].Fields)
+	})
+	data := make([]map[string]any, 0, len(indexes))
+	for _, index := range indexes {
+		doc := map[string]any{"type": index.Type, "fields": index.Fields}
+		if index.Type == storage.IndexVector {
+			doc["metric"], doc["dimensions"] = index.Metric, index.Dims
+		}
+		if index.ExpireAfterSeconds != nil {
+			doc["expireAfterSeconds"] = *index.ExpireAfterSeconds
+		}
+		data = append(data, doc)
+	}
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("Found %d index(es) on '%s'", len(data), namespaceOf(req)),
+		Data:    data,
+		Count:   len(data),
+	}
+}
+
+// enqueueIndexCreation выполняет создание индекса в очереди коллекции
+func enqueueIndexCreation(mng *storage.CollectionMng, req api.Request, operation func(coll *storage.Collection) (storage.WriteResult, error)) api.Response {
+	// Используем очередь для write-операции; до создания индекса его файлы
+	// помечаются неактуальными, чтобы после сбоя индексы перестроились из данных
+	result := mng.Enqueue(namespaceOf(req), func(coll *storage.Collection) (storage.WriteResult, error) {
+		if err := coll.InvalidateIndexCheckpoint(); err != nil {
+			return storage.WriteResult{}, fmt.Errorf("failed to update manifest: %w", err)
+		}
+		return operation(coll)
 	})
 
 	if result.Error != nil {
-		return api.Response{Status: api.StatusError, Message: result.Error.Error()}
+		return errorResponse(result.Error)
 	}
 
 	return api.Response{
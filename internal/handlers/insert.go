@@ -2,48 +2,117 @@ package handlers
 
 import (
 	"fmt"
+	"maps"
 	"nosql_db/internal/api"
 	"nosql_db/internal/storage"
+	"reflect"
+	"time"
 )
 
-func handleInsert(req api.Request) api.Response {
+func handleInsert(mng *storage.CollectionMng, req api.Request) api.Response {
 	if len(req.Data) == 0 {
 		return api.Response{Status: api.StatusError, Message: "no data provided for insert"}
 	}
 
-	// Используем очередь для write-операции
-	result := storage.GlobalManager.Enqueue(req.Database, func(coll *storage.Collection) (storage.WriteResult, error) {
-		var insertedIDs []string
+	// Используем очередь для write-операции; при ошибке вставка откатывается целиком
+	durability, err := storage.ParseDurability(req.Durability)
+	if err != nil {
+		return errorResponse(err)
+	}
 
-		for _, doc := range req.Data {
-			id, err := coll.Insert(doc)
-			if err != nil {
-				return storage.WriteResult{}, fmt.Errorf("insert error: %w", err)
-			}
-			insertedIDs = append(insertedIDs, id)
-		}
+	result := mng.EnqueueDurable(namespaceOf(req), durability, func(coll *storage.Collection) (storage.WriteResult, error) {
+		return applyInsert(coll, req)
+	})
 
-		if err := coll.Save(); err != nil {
-			return storage.WriteResult{}, fmt.Errorf("failed to save data: %w", err)
-		}
+	if result.Error != nil {
+		return errorResponse(result.Error)
+	}
+
+	return api.Response{
+		Status:   api.StatusSuccess,
+		Message:  result.Message,
+		Count:    len(result.InsertedIDs),
+		Warnings: result.Warnings,
+	}
+}
 
-		if err := coll.SaveAllIndexes(); err != nil {
-			return storage.WriteResult{}, fmt.Errorf("failed to save indexes: %w", err)
+// applyInsert вставляет документы запроса; выполняется в worker'е
+func applyInsert(coll *storage.Collection, req api.Request) (storage.WriteResult, error) {
+	if expect, ok := req.Options["expect"]; ok {
+		return applyPut(coll, req, expect)
+	}
+	var insertedIDs, warnings []string
+
+	for i, doc := range req.Data {
+		warning, err := validateDocument(coll, doc, fmt.Sprintf("document %d", i+1))
+		if err != nil {
+			return storage.WriteResult{}, fmt.Errorf("insert error: %w", err)
+		}
+		id := ""
+		if i < len(req.IDs) {
+			id = req.IDs[i]
+		}
+		id, err = coll.InsertWithID(id, doc)
+		if err != nil {
+			return storage.WriteResult{}, fmt.Errorf("insert error: %w", err)
 		}
+		insertedIDs = append(insertedIDs, id)
+		warnings = append(warnings, warning...)
+	}
 
-		return storage.WriteResult{
-			InsertedIDs: insertedIDs,
-			Message:     fmt.Sprintf("Inserted %d document(s)", len(insertedIDs)),
-		}, nil
-	})
+	return storage.WriteResult{
+		InsertedIDs: insertedIDs,
+		Warnings:    warnings,
+		Message:     fmt.Sprintf("Inserted %d document(s)", len(insertedIDs)),
+	}, nil
+}
 
-	if result.Error != nil {
-		return api.Response{Status: api.StatusError, Message: result.Error.Error()}
+// applyPut записывает документ запроса с _id из ids, только если текущий документ с этим
+// _id совпадает с options.expect (null — документа нет или он истёк): проверка и запись
+// выполняются одной задачей worker'а. Если документ изменился, ничего не записывается
+// и ответ — 0 документов: клиент перечитывает документ и повторяет запись
+func applyPut(coll *storage.Collection, req api.Request, expect any) (storage.WriteResult, error) {
+	if len(req.Data) != 1 || len(req.IDs) != 1 || req.IDs[0] == "" {
+		return storage.WriteResult{}, fmt.Errorf("options.expect requires exactly one document and its _id in ids")
+	}
+	if coll.Capped() != nil {
+		return storage.WriteResult{}, fmt.Errorf("options.expect is not supported by capped collection '%s'", coll.Namespace())
+	}
+	expected, ok := expect.(map[string]any)
+	if expect != nil && !ok {
+		return storage.WriteResult{}, fmt.Errorf("options.expect must be a document or null")
 	}
 
-	return api.Response{
-		Status:  api.StatusSuccess,
-		Message: result.Message,
-		Count:   len(result.InsertedIDs),
+	id, doc := req.IDs[0], req.Data[0]
+	current, stored := coll.GetByID(id)
+	live := stored && !coll.Expired(time.Now())(current)
+	if (expect == nil && live) || (expect != nil && (!live || !sameDocument(current, expected))) {
+		return storage.WriteResult{Message: "Document changed, nothing written"}, nil
 	}
+
+	warnings, err := validateDocument(coll, doc, "document 1")
+	if err != nil {
+		return storage.WriteResult{}, fmt.Errorf("insert error: %w", err)
+	}
+	// истёкший документ, ещё не удалённый reaper'ом, заменяется, как отсутствующий
+	if stored {
+		_, err = coll.Replace(id, doc)
+	} else {
+		_, err = coll.InsertWithID(id, doc)
+	}
+	if err != nil {
+		return storage.WriteResult{}, fmt.Errorf("insert error: %w", err)
+	}
+	return storage.WriteResult{
+		InsertedIDs: []string{id},
+		Warnings:    warnings,
+		Message:     "Inserted 1 document(s)",
+	}, nil
+}
+
+// sameDocument сравнивает документ с ожидаемым без учёта _id
+func sameDocument(doc, expected map[string]any) bool {
+	expected = maps.Clone(expected)
+	expected["_id"] = doc["_id"]
+	return reflect.DeepEqual(doc, expected)
 }
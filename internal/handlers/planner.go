@@ -0,0 +1,467 @@
+package handlers
+
+import (
+	"fmt"
+	"nosql_db/internal/index"
+	"nosql_db/internal/query"
+	"nosql_db/internal/storage"
+	"sort"
+)
+
+// queryPlan — результат выбора индексов для запроса
+type queryPlan struct {
+	ids      []string                      // id кандидатов, найденные по индексам (в порядке ключей)
+	useIndex bool                          // ids получены из индексов
+	covered  bool                          // все условия отвечены индексами: документы перепроверять не нужно
+	fields   []string                      // поля, индексы которых использованы
+	residual map[string]any                // условия, которые проверяются по документу
+	meta     map[string]map[string]float64 // вид $meta -> id документа -> значение
+}
+
+// planQuery строит план запроса: $vectorSearch отвечается векторным индексом вместе
+// со всеми остальными условиями, $text — текстовым индексом,
+// $near/$geoWithin — гео-индексом, остальные условия верхнего уровня — b-tree индексами.
+// Порядок кандидатов задаёт первый из них: релевантность, расстояние или ключи b-tree
+func planQuery(coll *storage.Collection, conditions map[string]any) (queryPlan, error) {
+	plan := queryPlan{residual: conditions}
+	if len(conditions) == 0 {
+		return plan, nil
+	}
+	if err := checkTopLevelOperators(conditions, false); err != nil {
+		return queryPlan{}, err
+	}
+
+	if spec, hasVector := conditions[string(query.OpVectorSearch)]; hasVector {
+		return planVectorSearch(coll, spec, withoutField(conditions, string(query.OpVectorSearch)))
+	}
+
+	rest := conditions
+	exact := true
+
+	if textCondition, hasText := conditions[string(query.OpText)]; hasText {
+		search, err := textSearchString(textCondition)
+		if err != nil {
+			return queryPlan{}, err
+		}
+		scores, err := coll.SearchText(search)
+		if err != nil {
+			return queryPlan{}, err
+		}
+		rest = withoutField(conditions, string(query.OpText))
+		plan.residual = rest
+		plan.addCandidates(string(query.OpText), idsByScore(scores))
+		plan.setMeta(query.MetaTextScore, scores)
+	}
+
+	plain := make(map[string]any, len(rest))
+	for _, field := range sortedFields(rest) {
+		condition := rest[field]
+		if !isGeoCondition(condition) {
+			plain[field] = condition
+			continue
+		}
+		// гео-индекс даёт кандидатов по ячейкам геохеша: условие перепроверяется по документу
+		exact = false
+		ids, distances, err := geoCandidates(coll, field, condition.(map[string]any))
+		if err != nil {
+			return queryPlan{}, err
+		}
+		if ids == nil {
+			continue
+		}
+		plan.addCandidates(field, ids)
+		if distances != nil {
+			plan.setMeta(query.MetaGeoDistance, distances)
+		}
+	}
+
+	if len(plain) > 0 {
+		sub := planIndexes(coll, plain)
+		if sub.useIndex {
+			plan.addCandidates("", sub.ids)
+			plan.fields = append(plan.fields, sub.fields...)
+		}
+		exact = exact && sub.covered
+	}
+
+	plan.covered = plan.useIndex && exact
+	return plan, nil
+}
+
+// maxPlanAttempts — сколько раз план перестраивается, если коллекция менялась во время планирования
+const maxPlanAttempts = 3
+
+// planRead строит план для чтения из снимка. Индексы отражают текущее состояние коллекции:
+// если оно отличается от снимка, план перестаёт быть покрывающим, к кандидатам добавляются
+// документы, изменённые после снимка, и все условия перепроверяются по версиям из снимка.
+// Если коллекция менялась во время планирования, план строится заново
+func planRead(snap *storage.Snapshot, conditions map[string]any) (queryPlan, error) {
+	var plan queryPlan
+	for attempt := 0; attempt < maxPlanAttempts; attempt++ {
+		mods := snap.Modifications()
+		var err error
+		plan, err = planQuery(snap.Collection(), conditions)
+		if err != nil {
+			return queryPlan{}, err
+		}
+		if !snap.Stale() {
+			return plan, nil
+		}
+
+		plan.covered = false
+		// кандидаты $text/$near/$vectorSearch упорядочены по оценке индекса: их не дополняем
+		if plan.useIndex && plan.meta == nil {
+			plan.ids = uniqueIDs(append(plan.ids, snap.Changed()...))
+		}
+		if snap.Modifications() == mods {
+			break
+		}
+	}
+	return plan, nil
+}
+
+// addCandidates сужает множество кандидатов плана, сохраняя порядок первого источника
+func (p *queryPlan) addCandidates(field string, ids []string) {
+	if p.useIndex {
+		p.ids = intersectIDs(p.ids, ids)
+	} else {
+		p.ids = uniqueIDs(ids)
+		p.useIndex = true
+	}
+	if field != "" {
+		p.fields = append(p.fields, field)
+	}
+}
+
+// setMeta запоминает значения $meta для документов
+func (p *queryPlan) setMeta(kind string, values map[string]float64) {
+	if p.meta == nil {
+		p.meta = make(map[string]map[string]float64)
+	}
+	p.meta[kind] = values
+}
+
+// checkTopLevelOperators отклоняет $text и $vectorSearch не на верхнем уровне запроса:
+// внутри $or/$and или как оператор поля их не ответить индексом, и запрос молча ничего бы не нашёл
+func checkTopLevelOperators(conditions map[string]any, nested bool) error {
+	for field, condition := range conditions {
+		switch field {
+		case "$or", "$and":
+			list, _ := condition.([]any)
+			for _, sub := range list {
+				if subMap, ok := sub.(map[string]any); ok {
+					if err := checkTopLevelOperators(subMap, true); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		case string(query.OpText), string(query.OpVectorSearch):
+			if nested {
+				return fmt.Errorf("%s is only supported at the top level of a query, not inside $or/$and", field)
+			}
+			continue
+		}
+		condMap, ok := condition.(map[string]any)
+		if !ok {
+			continue
+		}
+		for _, op := range []query.Operator{query.OpText, query.OpVectorSearch} {
+			if _, misplaced := condMap[string(op)]; misplaced {
+				return fmt.Errorf("%s is only supported at the top level of a query, not as a condition of field '%s'", op, field)
+			}
+		}
+	}
+	return nil
+}
+
+// indexOnlyOperators — условия, которые отвечает только индекс: по документу их не проверить
+var indexOnlyOperators = []query.Operator{query.OpText, query.OpVectorSearch}
+
+// checkScanQuery проверяет запрос, который сверяется с каждым документом без плана
+// (update, delete, find с options.after): условие только для индекса в нём молча ничего
+// не нашло бы, поэтому отклоняется
+func checkScanQuery(command string, conditions map[string]any) error {
+	if err := checkTopLevelOperators(conditions, false); err != nil {
+		return err
+	}
+	for _, op := range indexOnlyOperators {
+		if _, ok := conditions[string(op)]; ok {
+			return fmt.Errorf("%s is not supported in %s: it is answered only by its index", op, command)
+		}
+	}
+	return nil
+}
+
+// withoutField возвращает копию условий без поля
+func withoutField(conditions map[string]any, field string) map[string]any {
+	rest := make(map[string]any, len(conditions))
+	for f, condition := range conditions {
+		if f != field {
+			rest[f] = condition
+		}
+	}
+	return rest
+}
+
+func sortedFields(conditions map[string]any) []string {
+	fields := make([]string, 0, len(conditions))
+	for field := range conditions {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// textSearchString достаёт строку поиска из {"$search": "..."}
+func textSearchString(condition any) (string, error) {
+	condMap, ok := condition.(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("$text requires an object with $search")
+	}
+	search, ok := condMap["$search"].(string)
+	if !ok {
+		return "", fmt.Errorf("$text requires a string $search")
+	}
+	return search, nil
+}
+
+// idsByScore упорядочивает документы по убыванию релевантности
+func idsByScore(scores map[string]float64) []string {
+	ids := make([]string, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// planIndexes подбирает индексы для условий верхнего уровня (неявный AND).
+// Равенство ($eq, $in) сначала ищется в хеш-индексе, остальные условия — в b-tree.
+// Каждое поле с индексом сужает множество кандидатов; если индексами отвечены
+// все условия, план считается покрывающим
+func planIndexes(coll *storage.Collection, conditions map[string]any) queryPlan {
+	plan := queryPlan{residual: conditions}
+	if len(conditions) == 0 || hasLogicalOperators(conditions) {
+		return plan
+	}
+
+	covered := true
+	for _, field := range sortedFields(conditions) {
+		if field == "_id" {
+			if ids, ok := equalityIDs(conditions[field]); ok {
+				// документ по _id берётся прямо из данных коллекции; условие перепроверяется
+				// по документу, потому что id может и не быть
+				plan.addCandidates(field, ids)
+				covered = false
+				continue
+			}
+		}
+		var ids []string
+		answered := false
+		if keys, ok := equalityKeys(conditions[field]); ok {
+			ids, answered = coll.LookupHash(field, keys)
+		}
+		if !answered {
+			coll.ReadIndex(field, func(btree *index.BTree) {
+				ids, answered = idsFromIndex(btree, conditions[field])
+			})
+		}
+		if !answered {
+			covered = false
+			continue
+		}
+		plan.addCandidates(field, ids)
+	}
+
+	plan.covered = plan.useIndex && covered
+	return plan
+}
+
+// equalityKeys возвращает ключи условия на равенство: значение, {"$eq": v} или {"$in": [...]}.
+// Только такие условия может отвечать хеш-индекс; диапазоны ему недоступны
+func equalityKeys(condition any) ([]index.Key, bool) {
+	condMap, ok := condition.(map[string]any)
+	if !ok {
+		if !isScalar(condition) {
+			return nil, false
+		}
+		return []index.Key{index.ValueToKey(condition)}, true
+	}
+	if len(condMap) != 1 {
+		return nil, false
+	}
+	if operand, ok := condMap["$eq"]; ok && isScalar(operand) {
+		return []index.Key{index.ValueToKey(operand)}, true
+	}
+	values, ok := condMap["$in"].([]any)
+	if !ok {
+		return nil, false
+	}
+	keys := make([]index.Key, 0, len(values))
+	for _, v := range values {
+		if !isScalar(v) {
+			return nil, false
+		}
+		keys = append(keys, index.ValueToKey(v))
+	}
+	return keys, true
+}
+
+// equalityIDs возвращает _id из условия на равенство _id: значение, {"$eq": v} или {"$in": [...]}.
+// _id всегда строка, поэтому другие значения ничего не находят
+func equalityIDs(condition any) ([]string, bool) {
+	keys, ok := equalityKeys(condition)
+	if !ok {
+		return nil, false
+	}
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if value, ok := index.KeyToValue(key); ok {
+			if id, ok := value.(string); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, true
+}
+
+// matchingDocuments возвращает текущие версии документов, подходящих под условие на _id,
+// или все документы коллекции, если такого условия нет; выполняется в worker'е, остальные
+// условия проверяет вызывающий
+func matchingDocuments(coll *storage.Collection, conditions map[string]any) []map[string]any {
+	condition, hasID := conditions["_id"]
+	if !hasID {
+		return coll.All()
+	}
+	ids, ok := equalityIDs(condition)
+	if !ok {
+		return coll.All()
+	}
+	docs := make([]map[string]any, 0, len(ids))
+	for _, id := range uniqueIDs(ids) {
+		if doc, ok := coll.GetByID(id); ok {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// idsFromIndex ищет id документов по условию на одно поле;
+// false, если условие нельзя полностью ответить индексом
+func idsFromIndex(btree *index.BTree, condition any) ([]string, bool) {
+	condMap, ok := condition.(map[string]any)
+	if !ok {
+		if !isScalar(condition) {
+			return nil, false
+		}
+		return index.ValuesToStrings(btree.Search(index.ValueToKey(condition))), true
+	}
+	if len(condMap) == 0 {
+		return nil, false
+	}
+
+	operators := make([]string, 0, len(condMap))
+	for op := range condMap {
+		operators = append(operators, op)
+	}
+	sort.Strings(operators)
+
+	var result []string
+	for i, op := range operators {
+		ids, ok := idsForOperator(btree, op, condMap[op])
+		if !ok {
+			return nil, false
+		}
+		if i == 0 {
+			result = uniqueIDs(ids)
+		} else {
+			result = intersectIDs(result, ids)
+		}
+	}
+	return result, true
+}
+
+// idsForOperator выполняет один оператор сравнения по индексу
+func idsForOperator(btree *index.BTree, op string, operand any) ([]string, bool) {
+	switch op {
+	case "$eq":
+		if !isScalar(operand) {
+			return nil, false
+		}
+		return index.ValuesToStrings(btree.Search(index.ValueToKey(operand))), true
+	case "$gt", "$lt":
+		if !isScalar(operand) {
+			return nil, false
+		}
+		key := index.ValueToKey(operand)
+		// $gt/$lt сравнивают только числа, как и operators.CompareGt
+		if !index.IsNumberKey(key) {
+			return nil, true
+		}
+		lower, upper := index.TypeBounds(key)
+		if op == "$gt" {
+			return index.ValuesToStrings(btree.RangeSearch(key, upper, false, false)), true
+		}
+		return index.ValuesToStrings(btree.RangeSearch(lower, key, true, false)), true
+	case "$in":
+		values, ok := operand.([]any)
+		if !ok {
+			return nil, false
+		}
+		keys := make([]index.Key, 0, len(values))
+		for _, v := range values {
+			if !isScalar(v) {
+				return nil, false
+			}
+			keys = append(keys, index.ValueToKey(v))
+		}
+		return index.ValuesToStrings(btree.SearchIn(keys)), true
+	default:
+		return nil, false
+	}
+}
+
+// isScalar проверяет, что значение можно сравнить по ключу индекса
+func isScalar(value any) bool {
+	switch value.(type) {
+	case nil, bool, string, float64, float32, int, int32, int64:
+		return true
+	default:
+		return false
+	}
+}
+
+// uniqueIDs убирает повторы, сохраняя порядок
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		result = append(result, id)
+	}
+	return result
+}
+
+// intersectIDs оставляет из a только id, которые есть в b, сохраняя порядок a
+func intersectIDs(a, b []string) []string {
+	set := make(map[string]struct{}, len(b))
+	for _, id := range b {
+		set[id] = struct{}{}
+	}
+	result := make([]string, 0, len(a))
+	for _, id := range a {
+		if _, ok := set[id]; ok {
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+
+	"nosql_db/internal/api"
+)
+
+func TestHashIndexPlan(t *testing.T) {
+	mng := testManager(t)
+	for _, field := range []string{"sku", "size"} {
+		mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{field: 1}, Options: map[string]any{"type": api.IndexTypeHash}})
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdListIndexes}); resp.Count != 2 || resp.Data[0]["type"] != api.IndexTypeHash {
+		t.Fatalf("list_indexes: %v", resp.Data)
+	}
+	insertDocs(t, mng,
+		map[string]any{"name": "pen", "sku": "a1", "size": 1.0},
+		map[string]any{"name": "cup", "sku": "b2", "size": 2.0},
+		map[string]any{"name": "mug", "sku": "b2", "size": 3.0},
+		map[string]any{"name": "box", "sku": "c3", "size": 4.0},
+	)
+
+	// равенство и $in отвечаются хеш-индексом полностью
+	for _, tt := range []struct {
+		query map[string]any
+		want  []string
+	}{
+		{map[string]any{"sku": "b2"}, []string{"cup", "mug"}},
+		{map[string]any{"sku": map[string]any{"$eq": "a1"}}, []string{"pen"}},
+		{map[string]any{"sku": map[string]any{"$in": []any{"a1", "c3", "zz"}}}, []string{"box", "pen"}},
+		{map[string]any{"sku": "zz"}, []string{}},
+	} {
+		if plan := testPlan(t, mng, tt.query); !plan.useIndex || !plan.covered || !slices.Equal(plan.fields, []string{"sku"}) {
+			t.Errorf("%v: expected a covered hash plan, got %+v", tt.query, plan)
+		}
+		resp := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: tt.query, Sort: []string{"name"}})
+		if got := foundNames(resp); !slices.Equal(got, tt.want) {
+			t.Errorf("%v: expected %v, got %v", tt.query, tt.want, got)
+		}
+	}
+
+	// диапазоны и $like хеш-индекс не отвечает: полный перебор с тем же результатом
+	for _, tt := range []struct {
+		query map[string]any
+		want  []string
+	}{
+		{map[string]any{"size": map[string]any{"$gt": 1.0}}, []string{"box", "cup", "mug"}},
+		{map[string]any{"size": map[string]any{"$lt": 2.0}}, []string{"pen"}},
+		{map[string]any{"sku": map[string]any{"$like": "b%"}}, []string{"cup", "mug"}},
+	} {
+		if plan := testPlan(t, mng, tt.query); plan.useIndex {
+			t.Errorf("%v: hash index must not answer ranges: %+v", tt.query, plan)
+		}
+		resp := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: tt.query, Sort: []string{"name"}})
+		if got := foundNames(resp); !slices.Equal(got, tt.want) {
+			t.Errorf("%v: expected %v, got %v", tt.query, tt.want, got)
+		}
+	}
+
+	// b-tree на том же поле берёт на себя диапазоны
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"size": 1}})
+	if plan := testPlan(t, mng, map[string]any{"size": map[string]any{"$gt": 1.0}}); !plan.useIndex || len(plan.ids) != 3 {
+		t.Errorf("range over a b-tree: %+v", plan)
+	}
+}
+
+func TestIDPointLookup(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, IDs: []string{"p1", "p2", "p3"}, Data: []map[string]any{
+		{"name": "pen", "size": 1.0}, {"name": "cup", "size": 2.0}, {"name": "mug", "size": 3.0},
+	}})
+
+	// равенство и $in по _id берут документы из данных без полного перебора
+	for _, tt := range []struct {
+		query map[string]any
+		want  []string
+	}{
+		{map[string]any{"_id": "p2"}, []string{"cup"}},
+		{map[string]any{"_id": map[string]any{"$in": []any{"p3", "p1", "zz", 1.0}}}, []string{"mug", "pen"}},
+		{map[string]any{"_id": "p2", "size": 3.0}, []string{}},
+		{map[string]any{"_id": "zz"}, []string{}},
+	} {
+		if plan := testPlan(t, mng, tt.query); !plan.useIndex || plan.covered || plan.fields[0] != "_id" {
+			t.Errorf("%v: expected an _id lookup, got %+v", tt.query, plan)
+		}
+		resp := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: tt.query})
+		if got := foundNames(resp); !slices.Equal(got, tt.want) {
+			t.Errorf("%v: expected %v, got %v", tt.query, tt.want, got)
+		}
+		if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount, Query: tt.query}); resp.Count != len(tt.want) {
+			t.Errorf("%v: count %d, expected %d", tt.query, resp.Count, len(tt.want))
+		}
+	}
+
+	// update и delete по _id затрагивают только свой документ
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdUpdate, Query: map[string]any{"_id": "p1"},
+		Update: map[string]any{"$inc": map[string]any{"size": 10.0}}}); resp.Count != 1 {
+		t.Fatalf("update by _id modified %d document(s)", resp.Count)
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdDelete, Query: map[string]any{"_id": map[string]any{"$in": []any{"p2", "p2", "zz"}}}}); resp.Count != 1 {
+		t.Fatalf("delete by _id removed %d document(s)", resp.Count)
+	}
+	resp := mustHandle(t, mng, api.Request{Command: api.CmdFind, Sort: []string{"name"}})
+	if got := foundNames(resp); !slices.Equal(got, []string{"mug", "pen"}) || resp.Data[1]["size"] != 11.0 {
+		t.Fatalf("after update and delete by _id: %v", resp.Data)
+	}
+}
+
+func TestFindAfter(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, IDs: []string{"d", "b", "e", "a", "c"}, Data: []map[string]any{
+		{"name": "dd", "n": 4.0}, {"name": "bb", "n": 2.0}, {"name": "ee", "n": 5.0}, {"name": "aa", "n": 1.0}, {"name": "cc", "n": 3.0},
+	}})
+
+	// обход порциями по 2 с последнего _id порции
+	var names []string
+	after := ""
+	for {
+		resp := mustHandle(t, mng, api.Request{Command: api.CmdFind, Limit: 2, Options: map[string]any{"after": after}})
+		names = append(names, foundNames(resp)...)
+		if resp.Count < 2 {
+			break
+		}
+		after = resp.Data[len(resp.Data)-1]["_id"].(string)
+	}
+	if !slices.Equal(names, []string{"aa", "bb", "cc", "dd", "ee"}) {
+		t.Fatalf("paged find returned %v", names)
+	}
+
+	// изменение коллекции сбрасывает кеш порядка _id
+	mustHandle(t, mng, api.Request{Command: api.CmdDelete, Query: map[string]any{"name": "cc"}})
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, IDs: []string{"bz"}, Data: []map[string]any{{"name": "bz", "n": 6.0}}})
+	resp := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{"n": map[string]any{"$gt": 1.0}},
+		Options: map[string]any{"after": "a"}})
+	if got := foundNames(resp); !slices.Equal(got, []string{"bb", "bz", "dd", "ee"}) {
+		t.Fatalf("find after a: %v", got)
+	}
+	mustFail(t, mng, api.Request{Command: api.CmdFind, Sort: []string{"n"}, Options: map[string]any{"after": ""}})
+	mustFail(t, mng, api.Request{Command: api.CmdFind, Options: map[string]any{"after": 1.0}})
+}
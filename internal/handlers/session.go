@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"nosql_db/internal/api"
+	"nosql_db/internal/operators"
+	"nosql_db/internal/query"
+	"nosql_db/internal/storage"
+)
+
+// Node — роль узла в репликации: реплика отклоняет записи клиентов
+type Node interface {
+	// WriteError возвращает причину, по которой узел не принимает записи; nil — принимает
+	WriteError() error
+	// Status возвращает состояние репликации для repl_status
+	Status() map[string]any
+}
+
+// Cluster — узел кластера Raft: записи проходят через журнал кластера и применяются
+// на всех узлах. WriteError последователя — «not the leader»
+type Cluster interface {
+	Node
+	// Leader возвращает адрес лидера для клиентов; пусто — лидер неизвестен
+	Leader() string
+	// Propose реплицирует запись или транзакцию (tx) и возвращает результат её применения
+	Propose(reqs []api.Request, tx bool, durability string) api.Response
+	// ChangeMembers добавляет (add) или удаляет узел кластера по его адресу в кластере
+	ChangeMembers(id string, add bool) api.Response
+}
+
+// Session — состояние одного соединения клиента: менеджер коллекций узла, текущая база,
+// открытая транзакция и буфер её write-операций
+type Session struct {
+	mng     *storage.CollectionMng
+	node    Node // nil — узел без репликации
+	db      string
+	inTx    bool
+	pending []api.Request
+}
+
+// NewSession создаёт сессию соединения с коллекциями mng и базой по умолчанию;
+// node — роль узла в репликации, nil — одиночный сервер
+func NewSession(mng *storage.CollectionMng, node Node) *Session {
+	return &Session{mng: mng, node: node, db: storage.DefaultDatabase}
+}
+
+// Handle обрабатывает запрос в контексте сессии. Внутри транзакции insert/update/delete
+// буферизуются и применяются атомарно при commit; чтение видит только зафиксированные данные
+func (s *Session) Handle(req api.Request) api.Response {
+	switch req.Command {
+	case api.CmdBegin:
+		if s.inTx {
+			return api.Response{Status: api.StatusError, Message: "transaction already started"}
+		}
+		s.inTx = true
+		s.pending = nil
+		return api.Response{Status: api.StatusSuccess, Message: "Transaction started"}
+	case api.CmdCommit:
+		if !s.inTx {
+			return api.Response{Status: api.StatusError, Message: "no transaction in progress"}
+		}
+		durability, err := storage.ParseDurability(req.Durability)
+		if err != nil {
+			return errorResponse(err)
+		}
+		pending := s.pending
+		s.Close()
+		if cluster, ok := s.node.(Cluster); ok && len(pending) > 0 {
+			return cluster.Propose(pending, true, req.Durability)
+		}
+		return CommitTransaction(s.mng, pending, durability)
+	case api.CmdUse:
+		if err := storage.ValidateDatabaseName("database", req.Database); err != nil {
+			return errorResponse(err)
+		}
+		s.db = req.Database
+		return api.Response{Status: api.StatusSuccess, Message: fmt.Sprintf("Switched to database '%s'", s.db)}
+	case api.CmdAbort:
+		if !s.inTx {
+			return api.Response{Status: api.StatusError, Message: "no transaction in progress"}
+		}
+		discarded := len(s.pending)
+		s.Close()
+		return api.Response{
+			Status:  api.StatusSuccess,
+			Message: fmt.Sprintf("Transaction aborted, %d operation(s) discarded", discarded),
+		}
+	case api.CmdReplStatus:
+		return s.replStatus()
+	case api.CmdAddMember, api.CmdRemoveMember:
+		cluster, ok := s.node.(Cluster)
+		if !ok {
+			return api.Response{Status: api.StatusError, Message: req.Command + " requires cluster mode"}
+		}
+		id, _ := req.Options["id"].(string)
+		if id == "" {
+			return api.Response{Status: api.StatusError, Message: "options.id (cluster address of the node) is required"}
+		}
+		return cluster.ChangeMembers(id, req.Command == api.CmdAddMember)
+	}
+
+	// операции буфера транзакции запоминают базу, текущую на момент постановки
+	req = ResolveRequest(req, s.db)
+	if s.node != nil && (isTxWrite(req.Command) || isSchemaCommand(req.Command)) {
+		if err := s.node.WriteError(); err != nil {
+			resp := api.Response{Status: api.StatusError, Message: err.Error(), Code: api.CodeNotWritable}
+			if cluster, ok := s.node.(Cluster); ok {
+				resp.Leader = cluster.Leader()
+			}
+			return resp
+		}
+	}
+	if s.inTx && isTxWrite(req.Command) {
+		if err := validateTxWrite(req); err != nil {
+			return errorResponse(err)
+		}
+		s.pending = append(s.pending, req)
+		return api.Response{
+			Status:  api.StatusSuccess,
+			Message: fmt.Sprintf("Queued in transaction (%d operation(s))", len(s.pending)),
+		}
+	}
+	if s.inTx && isSchemaCommand(req.Command) {
+		return api.Response{Status: api.StatusError, Message: req.Command + " is not allowed in a transaction"}
+	}
+	if cluster, ok := s.node.(Cluster); ok && (isTxWrite(req.Command) || isSchemaCommand(req.Command)) {
+		return cluster.Propose([]api.Request{req}, false, req.Durability)
+	}
+
+	return HandleRequest(s.mng, req)
+}
+
+// replStatus возвращает состояние репликации узла
+func (s *Session) replStatus() api.Response {
+	if s.node == nil {
+		return api.Response{Status: api.StatusSuccess, Message: "Replication is not configured"}
+	}
+	status := s.node.Status()
+	message := fmt.Sprint(status["role"])
+	if state, ok := status["state"]; ok && state != status["role"] {
+		message += fmt.Sprintf(": %v", state)
+	}
+	if term, ok := status["term"]; ok {
+		message += fmt.Sprintf(", term %v, leader %v", term, status["leaderAddr"])
+	}
+	if lag, ok := status["lagEvents"]; ok {
+		message += fmt.Sprintf(", lag %v event(s), %.3fs", lag, status["lagSeconds"])
+	}
+	return api.Response{Status: api.StatusSuccess, Message: message, Data: []map[string]any{status}, Count: 1}
+}
+
+// Close завершает сессию, отбрасывая незафиксированную транзакцию
+func (s *Session) Close() {
+	s.inTx = false
+	s.pending = nil
+}
+
+func isTxWrite(command string) bool {
+	return command == api.CmdInsert || command == api.CmdUpdate || command == api.CmdDelete
+}
+
+// isSchemaCommand — команды, меняющие индексы, коллекции и базы: они выполняются
+// за барьером и не входят в транзакции
+func isSchemaCommand(command string) bool {
+	switch command {
+	case api.CmdCreateIndex, api.CmdCreateCollection, api.CmdDropCollection,
+		api.CmdRenameCollection, api.CmdCollMod, api.CmdDropDatabase:
+		return true
+	}
+	return false
+}
+
+// validateTxWrite проверяет операцию при постановке в буфер, чтобы ошибка пришла сразу
+func validateTxWrite(req api.Request) error {
+	if err := validateNamespace(req); err != nil {
+		return err
+	}
+	if req.Durability != "" {
+		return fmt.Errorf("durability is set on commit, not on operations inside a transaction")
+	}
+	switch req.Command {
+	case api.CmdInsert:
+		if len(req.Data) == 0 {
+			return fmt.Errorf("no data provided for insert")
+		}
+	case api.CmdUpdate:
+		if len(req.Update) == 0 {
+			return fmt.Errorf("no update provided")
+		}
+	}
+	if req.Command != api.CmdInsert {
+		return checkScanQuery(req.Command, req.Query)
+	}
+	return nil
+}
+
+// CommitTransaction применяет буфер транзакции одной задачей worker'а:
+// ошибка любой операции откатывает данные и индексы во всех коллекциях
+func CommitTransaction(mng *storage.CollectionMng, pending []api.Request, durability storage.Durability) api.Response {
+	if len(pending) == 0 {
+		return api.Response{Status: api.StatusSuccess, Message: "Transaction committed, no operations"}
+	}
+
+	names := make([]storage.Namespace, 0, len(pending))
+	for _, req := range pending {
+		names = append(names, namespaceOf(req))
+	}
+
+	result := mng.EnqueueTx(names, durability, func(tx *storage.Tx) (storage.WriteResult, error) {
+		var total storage.WriteResult
+		for i, req := range pending {
+			coll, err := tx.Collection(namespaceOf(req))
+			if err != nil {
+				return storage.WriteResult{}, err
+			}
+
+			var result storage.WriteResult
+			switch req.Command {
+			case api.CmdInsert:
+				result, err = applyInsert(coll, req)
+			case api.CmdUpdate:
+				result, err = applyUpdate(coll, req)
+			case api.CmdDelete:
+				result, err = applyDelete(coll, req)
+			}
+			if err != nil {
+				return storage.WriteResult{}, fmt.Errorf("operation %d (%s %s) failed, transaction rolled back: %w",
+					i+1, req.Command, namespaceOf(req), err)
+			}
+
+			total.InsertedIDs = append(total.InsertedIDs, result.InsertedIDs...)
+			total.ModifiedCount += result.ModifiedCount
+			total.DeletedCount += result.DeletedCount
+			total.Warnings = append(total.Warnings, result.Warnings...)
+		}
+		total.Message = fmt.Sprintf("Transaction committed: inserted %d, updated %d, deleted %d document(s)",
+			len(total.InsertedIDs), total.ModifiedCount, total.DeletedCount)
+		return total, nil
+	})
+
+	if result.Error != nil {
+		return errorResponse(result.Error)
+	}
+
+	return api.Response{
+		Status:   api.StatusSuccess,
+		Message:  result.Message,
+		Count:    len(result.InsertedIDs) + result.ModifiedCount + result.DeletedCount,
+		Warnings: result.Warnings,
+	}
+}
+
+// IsStreaming — запросы, отвечающие потоком до следующего запроса: find с options.tailable
+// (курсор ждёт новых документов capped-коллекции) и watch
+func IsStreaming(req api.Request) bool {
+	tailable, _ := req.Options["tailable"].(bool)
+	return (req.Command == api.CmdFind && tailable) || req.Command == api.CmdWatch
+}
+
+// Stream выполняет потоковый запрос, пока не закроется stop
+func (s *Session) Stream(req api.Request, send func(api.Response) error, stop <-chan struct{}) error {
+	if req.Command == api.CmdWatch {
+		return s.Watch(req, send, stop)
+	}
+	return s.Tail(req, send, stop)
+}
+
+// Tail выполняет tailable find: отправляет подходящие документы capped-коллекции
+// в порядке вставки, затем ждёт новых вставок и отправляет их по мере фиксации,
+// пока не закроется stop. Все ответы курсора помечены tailable; последний —
+// «Tailable cursor closed» или ошибка. Возвращает ошибку отправки
+func (s *Session) Tail(req api.Request, send func(api.Response) error, stop <-chan struct{}) error {
+	fail := func(err error) error {
+		resp := errorResponse(err)
+		resp.Tailable = true
+		return send(resp)
+	}
+	if s.inTx {
+		return fail(errors.New("tailable find is not allowed in a transaction"))
+	}
+	req = ResolveRequest(req, s.db)
+	if err := validateNamespace(req); err != nil {
+		return fail(err)
+	}
+	if len(req.Sort) > 0 {
+		return fail(errors.New("tailable find returns documents in insertion order and cannot be sorted"))
+	}
+	coll, err := s.mng.GetCollection(namespaceOf(req))
+	if err != nil {
+		return fail(fmt.Errorf("failed to load database: %w", err))
+	}
+	defer coll.Release()
+
+	position := ""
+	for first := true; ; first = false {
+		docs, next, changed, err := coll.Tail(position)
+		if err != nil {
+			return fail(err)
+		}
+		position = next
+
+		var results []map[string]any
+		for _, doc := range docs {
+			if !operators.MatchDocument(doc, req.Query) {
+				continue
+			}
+			if len(req.Projection) > 0 {
+				doc = query.ApplyProjection(doc, req.Projection)
+			}
+			results = append(results, doc)
+		}
+		if first || len(results) > 0 {
+			resp := api.Response{Status: api.StatusSuccess, Data: results, Count: len(results), Tailable: true}
+			if err := send(resp); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-changed:
+		case <-stop:
+			return send(api.Response{Status: api.StatusSuccess, Message: "Tailable cursor closed", Tailable: true})
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"nosql_db/internal/api"
+)
+
+// sessionDo выполняет запрос в сессии и проверяет его статус; коллекция по умолчанию — test
+func sessionDo(t *testing.T, s *Session, req api.Request, success bool) api.Response {
+	t.Helper()
+	if req.Collection == "" && req.Command != api.CmdBegin && req.Command != api.CmdCommit && req.Command != api.CmdAbort {
+		req.Collection = "test"
+	}
+	resp := s.Handle(req)
+	if (resp.Status == api.StatusSuccess) != success {
+		t.Fatalf("%s %v: unexpected %s: %s", req.Command, req.Query, resp.Status, resp.Message)
+	}
+	return resp
+}
+
+func TestTransactionCommit(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"balance": 1}})
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, IDs: []string{"ann", "bob"},
+		Data: []map[string]any{{"balance": 100.0}, {"balance": 50.0}}})
+	s := NewSession(mng, nil)
+
+	sessionDo(t, s, api.Request{Command: api.CmdBegin}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdBegin}, false)
+	sessionDo(t, s, api.Request{Command: api.CmdUpdate, Query: map[string]any{"_id": "ann"}, Update: map[string]any{"$inc": map[string]any{"balance": -30.0}}}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdUpdate, Query: map[string]any{"_id": "bob"}, Update: map[string]any{"$inc": map[string]any{"balance": 30.0}}}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdInsert, Collection: "log", Data: []map[string]any{{"from": "ann", "amount": -30.0}, {"to": "bob", "amount": 30.0}}}, true)
+	// схема и durability внутри транзакции не допускаются
+	sessionDo(t, s, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"x": 1}}, false)
+	sessionDo(t, s, api.Request{Command: api.CmdDelete, Query: map[string]any{}, Durability: "fsynced"}, false)
+
+	// до commit чтение видит только зафиксированные данные
+	if resp := sessionDo(t, s, api.Request{Command: api.CmdFind, Query: map[string]any{"balance": 100.0}}, true); resp.Count != 1 {
+		t.Fatalf("uncommitted update is visible: %v", resp.Data)
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount, Collection: "log"}); resp.Count != 0 {
+		t.Fatalf("uncommitted insert is visible: %d", resp.Count)
+	}
+
+	resp := sessionDo(t, s, api.Request{Command: api.CmdCommit}, true)
+	// count — затронутые документы, а не число операций
+	if resp.Count != 4 || !strings.Contains(resp.Message, "inserted 2, updated 2") {
+		t.Fatalf("commit: %+v", resp)
+	}
+	sessionDo(t, s, api.Request{Command: api.CmdCommit}, false)
+
+	// индекс следует за зафиксированными данными
+	found := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{"balance": 80.0}})
+	if len(found.Data) != 1 || found.Data[0]["_id"] != "bob" {
+		t.Fatalf("balance 80 after commit: %v", found.Data)
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount, Collection: "log"}); resp.Count != 2 {
+		t.Fatalf("log after commit: %d", resp.Count)
+	}
+}
+
+func TestTransactionRollback(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"n": 1}})
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, IDs: []string{"a", "b", "c"},
+		Data: []map[string]any{{"n": 1.0}, {"n": 2.0}, {"n": "three"}}})
+	s := NewSession(mng, nil)
+	state := func() []any {
+		t.Helper()
+		var values []any
+		for _, doc := range mustHandle(t, mng, api.Request{Command: api.CmdFind, Sort: []string{"_id"}}).Data {
+			values = append(values, doc["_id"], doc["n"])
+		}
+		return values
+	}
+	before := state()
+
+	// abort отбрасывает буфер
+	sessionDo(t, s, api.Request{Command: api.CmdBegin}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdDelete, Query: map[string]any{}}, true)
+	if resp := sessionDo(t, s, api.Request{Command: api.CmdAbort}, true); !strings.Contains(resp.Message, "1 operation(s) discarded") {
+		t.Fatalf("abort: %s", resp.Message)
+	}
+	sessionDo(t, s, api.Request{Command: api.CmdAbort}, false)
+
+	// ошибка третьей операции откатывает первые две вместе с индексом и в другой коллекции
+	sessionDo(t, s, api.Request{Command: api.CmdBegin}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdInsert, Collection: "other", Data: []map[string]any{{"n": 10.0}}}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdDelete, Query: map[string]any{"_id": "a"}}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdUpdate, Query: map[string]any{}, Update: map[string]any{"$inc": map[string]any{"n": 1.0}}}, true)
+	resp := sessionDo(t, s, api.Request{Command: api.CmdCommit}, false)
+	if !strings.Contains(resp.Message, "operation 3 (update") || !strings.Contains(resp.Message, "rolled back") {
+		t.Fatalf("commit error: %s", resp.Message)
+	}
+
+	if after := state(); !slices.Equal(after, before) {
+		t.Fatalf("documents after rollback: %v, before %v", after, before)
+	}
+	for n, want := range map[float64]int{1: 1, 2: 1, 3: 0} {
+		if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount, Query: map[string]any{"n": n}}); resp.Count != want {
+			t.Errorf("index count n=%v after rollback: %d", n, resp.Count)
+		}
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount, Collection: "other"}); resp.Count != 0 {
+		t.Fatalf("insert into another collection survived the rollback: %d", resp.Count)
+	}
+
+	// после неудачного commit сессия вне транзакции, записи идут сразу
+	sessionDo(t, s, api.Request{Command: api.CmdDelete, Query: map[string]any{"_id": "c"}}, true)
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount}); resp.Count != 2 {
+		t.Fatalf("count after a plain delete: %d", resp.Count)
+	}
+}
+
+func TestWriteDurability(t *testing.T) {
+	mng := testManager(t)
+	for _, durability := range []string{"none", "flushed", "fsynced"} {
+		mustHandle(t, mng, api.Request{Command: api.CmdInsert, Durability: durability, Data: []map[string]any{{"d": durability}}})
+	}
+	mustFail(t, mng, api.Request{Command: api.CmdInsert, Durability: "majority", Data: []map[string]any{{"d": "x"}}})
+	mustFail(t, mng, api.Request{Command: api.CmdUpdate, Durability: "majority", Query: map[string]any{}, Update: map[string]any{"$set": map[string]any{"x": 1.0}}})
+	mustFail(t, mng, api.Request{Command: api.CmdDelete, Durability: "majority", Query: map[string]any{}})
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount}); resp.Count != 3 {
+		t.Fatalf("count: %d", resp.Count)
+	}
+
+	s := NewSession(mng, nil)
+	sessionDo(t, s, api.Request{Command: api.CmdBegin}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdDelete, Query: map[string]any{}}, true)
+	sessionDo(t, s, api.Request{Command: api.CmdCommit, Durability: "majority"}, false)
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount}); resp.Count != 3 {
+		t.Fatalf("commit with an unknown durability applied the transaction: %d", resp.Count)
+	}
+}
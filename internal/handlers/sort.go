@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"bytes"
+	"nosql_db/internal/index"
+	"sort"
+	"strings"
+)
+
+// sortDocuments сортирует документы по списку полей ("-" перед именем — по убыванию).
+// Значения сравниваются по ключам индекса, поэтому порядок совпадает с порядком b-tree.
+// Поле, объявленное в проекции как {"$meta": ...}, сортируется по значению метаданных
+func sortDocuments(docs []map[string]any, sortFields []string, meta map[string]string, values map[string]map[string]float64) {
+	sort.SliceStable(docs, func(i, j int) bool {
+		for _, spec := range sortFields {
+			field, desc := strings.TrimPrefix(spec, "-"), strings.HasPrefix(spec, "-")
+
+			var cmp int
+			if byID, ok := values[meta[field]]; ok {
+				a, b := metaValue(docs[i], byID), metaValue(docs[j], byID)
+				switch {
+				case a < b:
+					cmp = -1
+				case a > b:
+					cmp = 1
+				}
+			} else {
+				cmp = compareFieldValues(docs[i], docs[j], field)
+			}
+
+			if cmp == 0 {
+				continue
+			}
+			if desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+}
+
+// compareFieldValues сравнивает значения поля двух документов; отсутствующее поле меньше любого значения
+func compareFieldValues(a, b map[string]any, field string) int {
+	va, okA := a[field]
+	vb, okB := b[field]
+	switch {
+	case !okA && !okB:
+		return 0
+	case !okA:
+		return -1
+	case !okB:
+		return 1
+	}
+	return bytes.Compare(index.ValueToKey(va), index.ValueToKey(vb))
+}
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"nosql_db/internal/api"
+)
+
+func TestTTLIndex(t *testing.T) {
+	mng := testManager(t)
+	for _, options := range []map[string]any{
+		{"expireAfterSeconds": -1.0},
+		{"expireAfterSeconds": 1.5},
+		{"expireAfterSeconds": "60"},
+		{"expireAfterSeconds": 60.0, "type": "hash"},
+	} {
+		mustFail(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"at": 1}, Options: options})
+	}
+	mustFail(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"at": 1, "b": 1},
+		Options: map[string]any{"expireAfterSeconds": 60.0}})
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"at": 1},
+		Options: map[string]any{"expireAfterSeconds": 60.0}})
+
+	indexes := mustHandle(t, mng, api.Request{Command: api.CmdListIndexes})
+	if indexes.Count != 1 || indexes.Data[0]["expireAfterSeconds"] != int64(60) {
+		t.Fatalf("list_indexes: %v", indexes.Data)
+	}
+
+	now := time.Now()
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, IDs: []string{"expired", "epoch", "live"}, Data: []map[string]any{
+		{"at": now.Add(-time.Hour).Format(time.RFC3339), "kind": "code"},
+		{"at": float64(now.Add(-time.Hour).Unix()), "kind": "code"},
+		{"at": now.Format(time.RFC3339), "kind": "code"},
+	}})
+	// запросы не возвращают истёкшие документы, даже если reaper ещё не запускался
+	found := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{"kind": "code"}})
+	if len(found.Data) != 1 || found.Data[0]["_id"] != "live" {
+		t.Fatalf("find: %v", found.Data)
+	}
+	// счётчик движка ещё включает истёкшие документы: count их обходит и не пишет «index-only»
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount}); resp.Count != 1 || strings.Contains(resp.Message, "index-only") {
+		t.Fatalf("count: %d, %q", resp.Count, resp.Message)
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{"_id": "expired"}}); len(resp.Data) != 0 {
+		t.Fatalf("find by _id: %v", resp.Data)
+	}
+
+	// записи тоже не видят истёкших документов
+	updated := mustHandle(t, mng, api.Request{Command: api.CmdUpdate, Query: map[string]any{"kind": "code"},
+		Update: map[string]any{"$set": map[string]any{"kind": "text"}}})
+	if updated.Count != 1 {
+		t.Fatalf("update matched expired documents: %d", updated.Count)
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdDelete, Query: map[string]any{}}); resp.Count != 1 {
+		t.Fatalf("delete counted expired documents: %d", resp.Count)
+	}
+}
+
+func TestInsertExpect(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"at": 1},
+		Options: map[string]any{"expireAfterSeconds": 0.0}})
+	put := func(id string, doc map[string]any, expect any) int {
+		t.Helper()
+		return mustHandle(t, mng, api.Request{Command: api.CmdInsert, IDs: []string{id}, Data: []map[string]any{doc},
+			Options: map[string]any{"expect": expect}}).Count
+	}
+
+	// null — только если документа нет; документ — только если он не изменился
+	if n := put("k", map[string]any{"v": "a"}, nil); n != 1 {
+		t.Fatalf("put into an empty key wrote %d document(s)", n)
+	}
+	if n := put("k", map[string]any{"v": "b"}, nil); n != 0 {
+		t.Fatal("put with expect null replaced an existing document")
+	}
+	if n := put("k", map[string]any{"v": "b"}, map[string]any{"v": "old"}); n != 0 {
+		t.Fatal("put replaced a document that differs from expect")
+	}
+	if n := put("k", map[string]any{"v": "b"}, map[string]any{"_id": "k", "v": "a"}); n != 1 {
+		t.Fatal("put did not replace the expected document")
+	}
+
+	// истёкший документ считается отсутствующим
+	put("old", map[string]any{"v": "x", "at": float64(time.Now().Add(-time.Hour).Unix())}, nil)
+	if n := put("old", map[string]any{"v": "y"}, nil); n != 1 {
+		t.Fatal("put with expect null did not replace an expired document")
+	}
+	found := mustHandle(t, mng, api.Request{Command: api.CmdFind, Sort: []string{"_id"}})
+	if len(found.Data) != 2 || found.Data[0]["v"] != "b" || found.Data[1]["v"] != "y" {
+		t.Fatalf("documents after put: %v", found.Data)
+	}
+	mustFail(t, mng, api.Request{Command: api.CmdInsert, Data: []map[string]any{{"v": 1.0}}, Options: map[string]any{"expect": nil}})
+}
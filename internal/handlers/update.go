@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"fmt"
+	"nosql_db/internal/api"
+	"nosql_db/internal/operators"
+	"nosql_db/internal/storage"
+	"reflect"
+	"time"
+)
+
+func handleUpdate(mng *storage.CollectionMng, req api.Request) api.Response {
+	if len(req.Update) == 0 {
+		return api.Response{Status: api.StatusError, Message: "no update provided"}
+	}
+
+	if err := checkScanQuery(req.Command, req.Query); err != nil {
+		return errorResponse(err)
+	}
+
+	// Используем очередь для write-операции; при ошибке изменения откатываются целиком
+	durability, err := storage.ParseDurability(req.Durability)
+	if err != nil {
+		return errorResponse(err)
+	}
+
+	result := mng.EnqueueDurable(namespaceOf(req), durability, func(coll *storage.Collection) (storage.WriteResult, error) {
+		return applyUpdate(coll, req)
+	})
+
+	if result.Error != nil {
+		return errorResponse(result.Error)
+	}
+
+	return api.Response{
+		Status:   api.StatusSuccess,
+		Message:  result.Message,
+		Count:    result.ModifiedCount,
+		Matched:  result.MatchedCount,
+		Warnings: result.Warnings,
+	}
+}
+
+// applyUpdate применяет $set/$unset/$inc к документам, подходящим под условие;
+// выполняется в worker'е
+func applyUpdate(coll *storage.Collection, req api.Request) (storage.WriteResult, error) {
+	modifiedCount, matchedCount := 0, 0
+	var warnings []string
+	expired := coll.Expired(time.Now())
+
+	for _, doc := range matchingDocuments(coll, req.Query) {
+		if expired(doc) || !operators.MatchDocument(doc, req.Query) {
+			continue
+		}
+		id, ok := doc["_id"].(string)
+		if !ok {
+			continue
+		}
+		matchedCount++
+		updated, err := operators.ApplyUpdate(doc, req.Update)
+		if err != nil {
+			return storage.WriteResult{}, fmt.Errorf("update error in document %s: %w", id, err)
+		}
+		if reflect.DeepEqual(updated, doc) {
+			// документ не меняется: не пишем его и не считаем изменённым
+			continue
+		}
+		warning, err := validateDocument(coll, updated, "document "+id)
+		if err != nil {
+			return storage.WriteResult{}, fmt.Errorf("update error: %w", err)
+		}
+		warnings = append(warnings, warning...)
+		replaced, err := coll.Replace(id, updated)
+		if err != nil {
+			return storage.WriteResult{}, fmt.Errorf("update error in document %s: %w", id, err)
+		}
+		if replaced {
+			modifiedCount++
+		}
+	}
+
+	return storage.WriteResult{
+		ModifiedCount: modifiedCount,
+		MatchedCount:  matchedCount,
+		Warnings:      warnings,
+		Message:       fmt.Sprintf("Updated %d document(s)", modifiedCount),
+	}, nil
+}
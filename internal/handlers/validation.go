@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"nosql_db/internal/storage"
+	"strings"
+)
+
+// validateDocument проверяет документ схемой коллекции. При уровне strict нарушения
+// возвращаются ошибкой с путями полей, при warn — пишутся в лог и возвращаются предупреждениями
+func validateDocument(coll *storage.Collection, doc map[string]any, label string) ([]string, error) {
+	validator := coll.Validator()
+	if validator == nil {
+		return nil, nil
+	}
+	violations := validator.Schema.Validate(doc)
+	if len(violations) == 0 {
+		return nil, nil
+	}
+
+	details := make([]string, len(violations))
+	for i, v := range violations {
+		details[i] = v.String()
+	}
+	if validator.Level == storage.ValidationStrict {
+		return nil, storage.Errorf(storage.ErrValidation, "%s failed validation: %s", label, strings.Join(details, "; "))
+	}
+
+	warnings := make([]string, len(details))
+	for i, detail := range details {
+		warnings[i] = label + ": " + detail
+	}
+	log.Printf("validation: %s: %s does not match schema: %s", coll.Namespace(), label, strings.Join(details, "; "))
+	return warnings, nil
+}
+
+// parseValidator читает options.validator (JSON Schema, можно в обёртке {"$jsonSchema": ...})
+// и options.validationLevel; ok=false — валидатор в опциях не задан
+func parseValidator(options map[string]any) (schema map[string]any, ok bool, err error) {
+	raw, ok := options["validator"]
+	if !ok {
+		return nil, false, nil
+	}
+	schema, isObject := raw.(map[string]any)
+	if !isObject {
+		return nil, false, fmt.Errorf("validator must be a JSON Schema object")
+	}
+	if inner, wrapped := schema["$jsonSchema"]; wrapped && len(schema) == 1 {
+		if schema, isObject = inner.(map[string]any); !isObject {
+			return nil, false, fmt.Errorf("$jsonSchema must be an object")
+		}
+	}
+	return schema, true, nil
+}
+
+// parseValidationLevel читает options.validationLevel: strict или warn; "" — не задан
+func parseValidationLevel(options map[string]any) (string, error) {
+	raw, ok := options["validationLevel"]
+	if !ok {
+		return "", nil
+	}
+	level, isString := raw.(string)
+	if !isString {
+		return "", fmt.Errorf("validationLevel must be a string")
+	}
+	if _, err := storage.ValidLevel(level); err != nil {
+		return "", err
+	}
+	return level, nil
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"nosql_db/internal/api"
+)
+
+// usersValidator — схема коллекции users из примеров README
+var usersValidator = map[string]any{"$jsonSchema": map[string]any{
+	"type":     "object",
+	"required": []any{"name", "age"},
+	"properties": map[string]any{
+		"name": map[string]any{"type": "string"},
+		"age":  map[string]any{"type": "integer", "minimum": 0.0},
+		"tags": map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+	},
+}}
+
+func TestValidatorStrict(t *testing.T) {
+	dir := t.TempDir()
+	mng := openManager(t, dir)
+	for _, options := range []map[string]any{
+		{"validator": "object"},
+		{"validator": map[string]any{"type": "date"}},
+		{"validator": usersValidator, "validationLevel": "loose"},
+		{"validationLevel": "warn"},
+	} {
+		mustFail(t, mng, api.Request{Command: api.CmdCreateCollection, Options: options})
+	}
+	resp := mustHandle(t, mng, api.Request{Command: api.CmdCreateCollection, Options: map[string]any{"validator": usersValidator}})
+	if !strings.HasSuffix(resp.Message, "validator: strict") {
+		t.Fatalf("create_collection: %s", resp.Message)
+	}
+
+	// при strict вставка отклоняется целиком, ошибка называет документ и путь поля
+	resp = mustFail(t, mng, api.Request{Command: api.CmdInsert, Data: []map[string]any{
+		{"name": "Ann", "age": 30.0},
+		{"name": "Bob", "age": "twenty", "tags": []any{"a", 1.0}},
+	}})
+	if !strings.Contains(resp.Message, "document 2 failed validation: age: expected type integer, got string; tags[1]: expected type string, got integer") {
+		t.Fatalf("insert error: %s", resp.Message)
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount}); resp.Count != 0 {
+		t.Fatalf("%d document(s) after a rejected insert", resp.Count)
+	}
+
+	insertDocs(t, mng, map[string]any{"name": "Ann", "age": 30.0})
+	resp = mustFail(t, mng, api.Request{Command: api.CmdUpdate, Query: map[string]any{"name": "Ann"},
+		Update: map[string]any{"$unset": map[string]any{"age": ""}}})
+	if !strings.Contains(resp.Message, "age: required field is missing") {
+		t.Fatalf("update error: %s", resp.Message)
+	}
+	// после перезапуска валидатор читается из метаданных коллекции
+	reopened := openManager(t, dir)
+	mustFail(t, reopened, api.Request{Command: api.CmdUpdate, Query: map[string]any{"name": "Ann"},
+		Update: map[string]any{"$inc": map[string]any{"age": -31.0}}})
+	found := mustHandle(t, reopened, api.Request{Command: api.CmdFind})
+	if len(found.Data) != 1 || found.Data[0]["age"] != 30.0 {
+		t.Fatalf("rejected updates changed the document: %v", found.Data)
+	}
+}
+
+func TestValidatorWarnAndCollMod(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	mng := testManager(t)
+	insertDocs(t, mng, map[string]any{"name": "legacy"})
+	mustFail(t, mng, api.Request{Command: api.CmdCollMod})
+	mustFail(t, mng, api.Request{Command: api.CmdCollMod, Options: map[string]any{"validationLevel": "warn"}})
+	mustFail(t, mng, api.Request{Command: api.CmdCollMod, Collection: "missing", Options: map[string]any{"validator": usersValidator}})
+
+	// coll_mod не перепроверяет сохранённые документы
+	resp := mustHandle(t, mng, api.Request{Command: api.CmdCollMod, Options: map[string]any{"validator": usersValidator, "validationLevel": "warn"}})
+	if !strings.HasSuffix(resp.Message, "validator warn") {
+		t.Fatalf("coll_mod: %s", resp.Message)
+	}
+
+	// при warn документ записывается, нарушения — в предупреждениях ответа и в логе
+	resp = mustHandle(t, mng, api.Request{Command: api.CmdInsert, Data: []map[string]any{{"name": "Bob"}, {"name": "Ann", "age": 1.0}}})
+	if !slices.Equal(resp.Warnings, []string{"document 1: age: required field is missing"}) {
+		t.Fatalf("insert warnings: %v", resp.Warnings)
+	}
+	resp = mustHandle(t, mng, api.Request{Command: api.CmdUpdate, Query: map[string]any{"name": "Ann"},
+		Update: map[string]any{"$set": map[string]any{"age": -1.0}}})
+	if len(resp.Warnings) != 1 || !strings.HasSuffix(resp.Warnings[0], "age: value -1 is less than minimum 0") {
+		t.Fatalf("update warnings: %v", resp.Warnings)
+	}
+	if !strings.Contains(out.String(), "does not match schema") {
+		t.Fatalf("warnings are not logged: %s", out.String())
+	}
+	if resp := mustHandle(t, mng, api.Request{Command: api.CmdCount}); resp.Count != 3 {
+		t.Fatalf("%d document(s) with warn", resp.Count)
+	}
+
+	// смена уровня сохраняет схему, пустая схема снимает валидатор
+	mustHandle(t, mng, api.Request{Command: api.CmdCollMod, Options: map[string]any{"validationLevel": "strict"}})
+	mustFail(t, mng, api.Request{Command: api.CmdInsert, Data: []map[string]any{{"name": "Eve"}}})
+	resp = mustHandle(t, mng, api.Request{Command: api.CmdCollMod, Options: map[string]any{"validator": map[string]any{}}})
+	if !strings.HasSuffix(resp.Message, "validator removed") {
+		t.Fatalf("coll_mod: %s", resp.Message)
+	}
+	insertDocs(t, mng, map[string]any{"name": "Eve"})
+	mustFail(t, mng, api.Request{Command: api.CmdCollMod, Options: map[string]any{"validationLevel": "warn"}})
+}
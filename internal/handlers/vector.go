@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"nosql_db/internal/query"
+	"nosql_db/internal/storage"
+	"nosql_db/internal/vector"
+)
+
+// параметры $vectorSearch по умолчанию
+const (
+	DefaultVectorK     = 10 // число результатов без k (его учитывает и роутер)
+	minVectorEfSearch  = 64
+	vectorEfMultiplier = 10
+)
+
+// planVectorSearch разбирает {"$vectorSearch": {"path", "vector", "k", "exact",
+// "numCandidates", "metric", "filter"}}. Фильтр и остальные условия запроса
+// проверяются внутри поиска через operators.MatchDocument, поэтому план покрывающий
+func planVectorSearch(coll *storage.Collection, spec any, rest map[string]any) (queryPlan, error) {
+	if _, hasText := rest[string(query.OpText)]; hasText {
+		return queryPlan{}, fmt.Errorf("$vectorSearch cannot be combined with $text")
+	}
+	q, err := parseVectorSearch(coll, spec, rest)
+	if err != nil {
+		return queryPlan{}, err
+	}
+	results, err := coll.SearchVector(q)
+	if err != nil {
+		return queryPlan{}, err
+	}
+
+	ids := make([]string, len(results))
+	scores := make(map[string]float64, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+		scores[r.ID] = r.Score
+	}
+
+	// фильтр уже проверен при поиске; по документу из снимка он перепроверяется
+	plan := queryPlan{residual: q.Filter}
+	plan.addCandidates(q.Field, ids)
+	plan.setMeta(query.MetaVectorScore, scores)
+	// результаты — документы, а не ключи индекса: проекцию из индексов строить нельзя
+	plan.covered = false
+	return plan, nil
+}
+
+// parseVectorSearch собирает параметры поиска из спецификации $vectorSearch
+func parseVectorSearch(coll *storage.Collection, spec any, rest map[string]any) (storage.VectorQuery, error) {
+	specMap, ok := spec.(map[string]any)
+	if !ok {
+		return storage.VectorQuery{}, fmt.Errorf("$vectorSearch requires an object")
+	}
+	field, ok := specMap["path"].(string)
+	if !ok || field == "" {
+		return storage.VectorQuery{}, fmt.Errorf("$vectorSearch requires a string path")
+	}
+	vec, ok := vector.Parse(specMap["vector"])
+	if !ok {
+		return storage.VectorQuery{}, fmt.Errorf("$vectorSearch requires a numeric vector")
+	}
+
+	q := storage.VectorQuery{Field: field, Vector: vec, K: DefaultVectorK}
+	if k, ok := specMap["k"].(float64); ok {
+		if k < 1 {
+			return storage.VectorQuery{}, fmt.Errorf("$vectorSearch k must be positive")
+		}
+		q.K = int(k)
+	}
+	q.Exact, _ = specMap["exact"].(bool)
+	q.Ef = max(q.K*vectorEfMultiplier, minVectorEfSearch)
+	if ef, ok := specMap["numCandidates"].(float64); ok {
+		if int(ef) < q.K {
+			return storage.VectorQuery{}, fmt.Errorf("$vectorSearch numCandidates must be at least k")
+		}
+		q.Ef = int(ef)
+	}
+
+	metricName, _ := specMap["metric"].(string)
+	if indexMetric, indexed := coll.VectorIndexMetric(field); indexed {
+		if metricName != "" && vector.Metric(metricName) != indexMetric {
+			return storage.VectorQuery{}, fmt.Errorf("vector index on field '%s' uses metric %s", field, indexMetric)
+		}
+		q.Metric = indexMetric
+	} else {
+		metric, err := vector.ParseMetric(metricName)
+		if err != nil {
+			return storage.VectorQuery{}, err
+		}
+		q.Metric = metric
+	}
+
+	filter, _ := specMap["filter"].(map[string]any)
+	if _, hasFilter := specMap["filter"]; hasFilter && filter == nil {
+		return storage.VectorQuery{}, fmt.Errorf("$vectorSearch filter must be an object")
+	}
+	switch {
+	case len(filter) == 0:
+		q.Filter = rest
+	case len(rest) == 0:
+		q.Filter = filter
+	default:
+		q.Filter = map[string]any{string(query.OpAnd): []any{filter, rest}}
+	}
+	return q, nil
+}
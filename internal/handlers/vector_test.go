@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"nosql_db/internal/api"
+)
+
+func TestVectorSearch(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"embedding": 1}, Options: map[string]any{"type": api.IndexTypeVector, "metric": "l2", "dimensions": 2.0}})
+	insertDocs(t, mng,
+		map[string]any{"name": "a", "kind": "x", "embedding": []any{0.0, 0.0}},
+		map[string]any{"name": "b", "kind": "y", "embedding": []any{1.0, 0.0}},
+		map[string]any{"name": "c", "kind": "x", "embedding": []any{2.0, 0.0}},
+		map[string]any{"name": "d", "kind": "y", "embedding": []any{3.0, 0.0}},
+		map[string]any{"name": "e", "kind": "x"},
+	)
+
+	search := func(spec map[string]any, rest map[string]any) api.Response {
+		t.Helper()
+		query := map[string]any{"$vectorSearch": spec}
+		for field, condition := range rest {
+			query[field] = condition
+		}
+		return mustHandle(t, mng, api.Request{Command: api.CmdFind, Query: query,
+			Projection: map[string]any{"name": 1, "score": map[string]any{"$meta": "vectorSearchScore"}}})
+	}
+	spec := func(k float64, extra map[string]any) map[string]any {
+		s := map[string]any{"path": "embedding", "vector": []any{0.9, 0.0}, "k": k}
+		for key, value := range extra {
+			s[key] = value
+		}
+		return s
+	}
+
+	resp := search(spec(3, nil), nil)
+	if got := foundNames(resp); !slices.Equal(got, []string{"b", "a", "c"}) {
+		t.Fatalf("nearest: %v", got)
+	}
+	if score := resp.Data[0]["score"].(float64); score <= resp.Data[1]["score"].(float64) || score > 1 {
+		t.Fatalf("scores: %v", resp.Data)
+	}
+	if got := foundNames(search(spec(3, map[string]any{"exact": true}), nil)); !slices.Equal(got, []string{"b", "a", "c"}) {
+		t.Fatalf("exact: %v", got)
+	}
+	// фильтр в спецификации и условия рядом с $vectorSearch применяются до обрезки до k
+	if got := foundNames(search(spec(2, map[string]any{"filter": map[string]any{"kind": "y"}}), nil)); !slices.Equal(got, []string{"b", "d"}) {
+		t.Fatalf("filter: %v", got)
+	}
+	if got := foundNames(search(spec(2, nil), map[string]any{"kind": "x"})); !slices.Equal(got, []string{"a", "c"}) {
+		t.Fatalf("query conditions: %v", got)
+	}
+	if got := foundNames(search(spec(5, map[string]any{"filter": map[string]any{"kind": "x"}}), map[string]any{"name": map[string]any{"$in": []any{"c", "e"}}})); !slices.Equal(got, []string{"c"}) {
+		t.Fatalf("filter and query conditions: %v", got)
+	}
+
+	for _, bad := range []map[string]any{
+		spec(0, nil),
+		spec(3, map[string]any{"numCandidates": 2.0}),
+		spec(3, map[string]any{"metric": "cosine"}),
+		spec(3, map[string]any{"filter": "kind"}),
+		{"path": "embedding", "vector": "0.9"},
+		{"vector": []any{0.9, 0.0}},
+	} {
+		mustFail(t, mng, api.Request{Command: api.CmdFind, Query: map[string]any{"$vectorSearch": bad}})
+	}
+}
+
+// TestVectorSearchRejectedWithoutPlan: $vectorSearch отвечает только индекс, поэтому в update,
+// delete и find с options.after он — ошибка
+func TestVectorSearchRejectedWithoutPlan(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdCreateIndex, Query: map[string]any{"embedding": 1}, Options: map[string]any{"type": api.IndexTypeVector, "metric": "l2", "dimensions": 2.0}})
+	insertDocs(t, mng, map[string]any{"name": "a", "embedding": []any{0.0, 0.0}})
+	search := map[string]any{"$vectorSearch": map[string]any{"path": "embedding", "vector": []any{0.0, 0.0}, "k": 1.0}}
+
+	for _, req := range []api.Request{
+		{Command: api.CmdUpdate, Query: search, Update: map[string]any{"$set": map[string]any{"seen": true}}},
+		{Command: api.CmdDelete, Query: search},
+		{Command: api.CmdFind, Query: search, Options: map[string]any{"after": ""}},
+	} {
+		resp := mustFail(t, mng, req)
+		if !strings.Contains(resp.Message, "$vectorSearch") {
+			t.Fatalf("%s: unexpected error %q", req.Command, resp.Message)
+		}
+	}
+	if got := mustHandle(t, mng, api.Request{Command: api.CmdCount}); got.Count != 1 {
+		t.Fatalf("documents changed by a rejected request: %d", got.Count)
+	}
+}
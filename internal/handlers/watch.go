@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"nosql_db/internal/api"
+	"nosql_db/internal/operators"
+	"nosql_db/internal/storage"
+	"slices"
+)
+
+// watchBatchSize — сколько событий уходит клиенту одним ответом
+const watchBatchSize = 1000
+
+// watchOptions — параметры watch из options запроса
+type watchOptions struct {
+	resumeAfter  string   // токен, после которого продолжить поток
+	fullDocument bool     // отправлять документ целиком и для update
+	operations   []string // только эти операции; пусто — все
+	match        map[string]any
+}
+
+// parseWatchOptions читает options.resumeAfter, options.fullDocument, options.operations
+// и фильтр $match из query (можно без обёртки)
+func parseWatchOptions(req api.Request) (watchOptions, error) {
+	var opts watchOptions
+	if raw, ok := req.Options["resumeAfter"]; ok {
+		token, isString := raw.(string)
+		if !isString {
+			return opts, fmt.Errorf("resumeAfter must be a resume token string")
+		}
+		opts.resumeAfter = token
+	}
+	if raw, ok := req.Options["fullDocument"]; ok {
+		full, isBool := raw.(bool)
+		if !isBool {
+			return opts, fmt.Errorf("fullDocument must be a boolean")
+		}
+		opts.fullDocument = full
+	}
+	if raw, ok := req.Options["operations"]; ok {
+		list, isList := raw.([]any)
+		if !isList {
+			return opts, fmt.Errorf("operations must be an array")
+		}
+		for _, item := range list {
+			op, _ := item.(string)
+			if op != storage.ChangeInsert && op != storage.ChangeUpdate && op != storage.ChangeDelete {
+				return opts, fmt.Errorf("unknown change operation %v (expected insert, update or delete)", item)
+			}
+			opts.operations = append(opts.operations, op)
+		}
+	}
+
+	opts.match = req.Query
+	if inner, wrapped := req.Query["$match"]; wrapped && len(req.Query) == 1 {
+		match, isObject := inner.(map[string]any)
+		if !isObject {
+			return opts, fmt.Errorf("$match must be an object")
+		}
+		opts.match = match
+	}
+	return opts, nil
+}
+
+// matches проверяет событие фильтрами watch: $match — по документу после изменения
+// (у delete — по удалённому) вместе с полями события operation, collection и _id.
+// Команды журнала (создание коллекций, индексы) не отправляются
+func (o watchOptions) matches(event storage.ChangeEvent) bool {
+	if !storage.IsDocumentChange(event.Op) || (len(o.operations) > 0 && !slices.Contains(o.operations, event.Op)) {
+		return false
+	}
+	return len(o.match) == 0 || operators.MatchDocument(matchTarget(event), o.match)
+}
+
+// matchTarget — то, к чему применяется $match: документ события и поля самого события
+func matchTarget(event storage.ChangeEvent) map[string]any {
+	target := make(map[string]any, len(event.Doc)+3)
+	for field, value := range event.Doc {
+		target[field] = value
+	}
+	target["_id"] = event.ID
+	target["operation"] = event.Op
+	target["collection"] = event.NS.Coll
+	return target
+}
+
+// changeEvent переводит событие журнала в ответ клиенту
+func (o watchOptions) changeEvent(event storage.ChangeEvent) api.ChangeEvent {
+	out := api.ChangeEvent{
+		Token:      event.Token,
+		Operation:  event.Op,
+		Database:   event.NS.DB,
+		Collection: event.NS.Coll,
+		ID:         event.ID,
+	}
+	switch event.Op {
+	case storage.ChangeInsert:
+		out.Document = event.Doc
+	case storage.ChangeUpdate:
+		out.UpdatedFields, out.RemovedFields = event.Updated, event.Removed
+		if o.fullDocument {
+			out.Document = event.Doc
+		}
+	}
+	return out
+}
+
+// Watch отправляет события изменений коллекции, зафиксированные после options.resumeAfter
+// (без него — после открытия потока), пока не закроется stop. Первый ответ —
+// «Change stream opened» с токеном текущей позиции; каждый ответ несёт resumeToken,
+// с которого поток можно продолжить после переподключения. Возвращает ошибку отправки
+func (s *Session) Watch(req api.Request, send func(api.Response) error, stop <-chan struct{}) error {
+	fail := func(err error) error {
+		resp := errorResponse(err)
+		resp.Tailable = true
+		return send(resp)
+	}
+	if s.inTx {
+		return fail(errors.New("watch is not allowed in a transaction"))
+	}
+	req = ResolveRequest(req, s.db)
+	if err := validateNamespace(req); err != nil {
+		return fail(err)
+	}
+	opts, err := parseWatchOptions(req)
+	if err != nil {
+		return fail(err)
+	}
+
+	ns := namespaceOf(req)
+	token := opts.resumeAfter
+	for first := true; ; first = false {
+		events, next, changed, err := s.mng.Changes(token, watchBatchSize)
+		if err != nil {
+			return fail(err)
+		}
+		token = next
+
+		var results []api.ChangeEvent
+		for _, event := range events {
+			if event.NS == ns && opts.matches(event) {
+				results = append(results, opts.changeEvent(event))
+			}
+		}
+		if first || len(results) > 0 {
+			resp := api.Response{Status: api.StatusSuccess, Events: results, Count: len(results), ResumeToken: token, Tailable: true}
+			if first {
+				resp.Message = fmt.Sprintf("Change stream opened on '%s'", ns)
+			}
+			if err := send(resp); err != nil {
+				return err
+			}
+		}
+		if len(events) == watchBatchSize {
+			// журнал отдал не всё: следующую порцию читаем сразу
+			continue
+		}
+
+		select {
+		case <-changed:
+		case <-stop:
+			return send(api.Response{Status: api.StatusSuccess, Message: "Change stream closed", ResumeToken: token, Tailable: true})
+		}
+	}
+}
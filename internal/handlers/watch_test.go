@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"slices"
+	"testing"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+)
+
+// watchEvents открывает поток с позиции token и возвращает операции и _id событий первой
+// порции по алфавиту: порядок удалений delete по условию не задан
+func watchEvents(t *testing.T, mng *storage.CollectionMng, token string, match map[string]any) []string {
+	t.Helper()
+	stop := make(chan struct{})
+	close(stop)
+	var events []string
+	send := func(resp api.Response) error {
+		if resp.Status != api.StatusSuccess {
+			t.Fatalf("watch %v: %s", match, resp.Message)
+		}
+		for _, event := range resp.Events {
+			events = append(events, event.Operation+" "+event.ID)
+		}
+		return nil
+	}
+	req := api.Request{Command: api.CmdWatch, Collection: "test", Query: match, Options: map[string]any{"resumeAfter": token}}
+	if err := NewSession(mng, nil).Watch(req, send, stop); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(events)
+	return events
+}
+
+func TestWatchMatchDeletes(t *testing.T) {
+	mng := testManager(t)
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, IDs: []string{"a", "b"},
+		Data: []map[string]any{{"name": "ann"}, {"name": "bob"}}})
+	token := mng.ChangeToken()
+	mustHandle(t, mng, api.Request{Command: api.CmdUpdate, Query: map[string]any{"_id": "a"}, Update: map[string]any{"$set": map[string]any{"age": 30.0}}})
+	mustHandle(t, mng, api.Request{Command: api.CmdDelete, Query: map[string]any{}})
+
+	cases := []struct {
+		match map[string]any
+		want  []string
+	}{
+		{nil, []string{"delete a", "delete b", "update a"}},
+		{map[string]any{"$match": map[string]any{"operation": "delete"}}, []string{"delete a", "delete b"}},
+		{map[string]any{"operation": "delete", "_id": "b"}, []string{"delete b"}},
+		{map[string]any{"name": "ann"}, []string{"delete a", "update a"}},
+		{map[string]any{"collection": "other"}, nil},
+	}
+	for _, tc := range cases {
+		if got := watchEvents(t, mng, token, tc.match); !slices.Equal(got, tc.want) {
+			t.Errorf("$match %v: expected %v, got %v", tc.match, tc.want, got)
+		}
+	}
+}
+
+func TestWatchStream(t *testing.T) {
+	mng := testManager(t)
+	s := NewSession(mng, nil)
+	for _, options := range []map[string]any{
+		{"resumeAfter": 1.0},
+		{"fullDocument": "yes"},
+		{"operations": []any{"insert", "replace"}},
+		{"resumeAfter": "garbage"},
+	} {
+		var resp api.Response
+		s.Watch(api.Request{Command: api.CmdWatch, Collection: "test", Options: options}, func(r api.Response) error {
+			resp = r
+			return nil
+		}, nil)
+		if resp.Status != api.StatusError || !resp.Tailable {
+			t.Errorf("%v: %+v", options, resp)
+		}
+	}
+
+	// поток живёт до stop: первое сообщение — открытие, дальше события по мере фиксации
+	responses := make(chan api.Response, 10)
+	stop := make(chan struct{})
+	done := make(chan error, 1)
+	req := api.Request{Command: api.CmdWatch, Collection: "test",
+		Options: map[string]any{"operations": []any{"insert", "update"}}}
+	go func() {
+		done <- s.Watch(req, func(resp api.Response) error {
+			responses <- resp
+			return nil
+		}, stop)
+	}()
+	opened := <-responses
+	if opened.Message != "Change stream opened on 'default.test'" || opened.ResumeToken == "" || opened.Count != 0 {
+		t.Fatalf("open: %+v", opened)
+	}
+
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, Collection: "other", Data: []map[string]any{{"n": 0.0}}})
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, IDs: []string{"a"}, Data: []map[string]any{{"n": 1.0, "note": "x"}}})
+	insert := <-responses
+	if insert.Count != 1 || insert.Events[0].Operation != "insert" || insert.Events[0].Document["n"] != 1.0 || insert.ResumeToken != insert.Events[0].Token {
+		t.Fatalf("insert: %+v", insert)
+	}
+	// update без fullDocument присылает только изменения
+	mustHandle(t, mng, api.Request{Command: api.CmdUpdate, Query: map[string]any{"_id": "a"},
+		Update: map[string]any{"$inc": map[string]any{"n": 1.0}, "$unset": map[string]any{"note": ""}}})
+	update := (<-responses).Events[0]
+	if update.Document != nil || update.UpdatedFields["n"] != 2.0 || !slices.Equal(update.RemovedFields, []string{"note"}) || update.Database != "default" {
+		t.Fatalf("update: %+v", update)
+	}
+	// delete отфильтрован списком операций
+	mustHandle(t, mng, api.Request{Command: api.CmdDelete, Query: map[string]any{"_id": "a"}})
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	closed := <-responses
+	if closed.Message != "Change stream closed" {
+		t.Fatalf("close: %+v", closed)
+	}
+
+	// после переподключения поток продолжается с токена: изменения за время разрыва не теряются
+	mustHandle(t, mng, api.Request{Command: api.CmdInsert, IDs: []string{"b"}, Data: []map[string]any{{"n": 5.0}}})
+	mustHandle(t, mng, api.Request{Command: api.CmdUpdate, Query: map[string]any{"_id": "b"},
+		Update: map[string]any{"$set": map[string]any{"n": 6.0}}})
+	resumed := make(chan struct{})
+	close(resumed)
+	var events []api.ChangeEvent
+	err := s.Watch(api.Request{Command: api.CmdWatch, Collection: "test",
+		Options: map[string]any{"resumeAfter": closed.ResumeToken, "fullDocument": true}}, func(resp api.Response) error {
+		events = append(events, resp.Events...)
+		return nil
+	}, resumed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// отфильтрованный delete остался до токена закрытия и не повторяется
+	if len(events) != 2 || events[0].ID != "b" || events[1].Operation != "update" || events[1].Document["n"] != 6.0 {
+		t.Fatalf("resumed: %+v", events)
+	}
+}
@@ -138,3 +138,28 @@ func (tree *BTree) GetAllValues() []Value {
 
 	return result
 }
+
+// Ascend обходит листья по возрастанию ключа; обход прекращается, если fn вернула false
+func (tree *BTree) Ascend(fn func(key Key, values []Value) bool) {
+	if tree.root == nil {
+		return
+	}
+
+	for leaf := tree.findLeftmostLeaf(tree.root); leaf != nil; leaf = leaf.next {
+		for i, k := range leaf.keys {
+			if !fn(k, leaf.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Len возвращает количество значений в дереве
+func (tree *BTree) Len() int {
+	count := 0
+	tree.Ascend(func(_ Key, values []Value) bool {
+		count += len(values)
+		return true
+	})
+	return count
+}
@@ -6,42 +6,102 @@ import (
 	"math"
 )
 
+// теги типов в первом байте ключа: задают порядок между типами
+// и позволяют восстановить значение из ключа без чтения документа
+const (
+	tagNull   byte = 0x01
+	tagBool   byte = 0x02
+	tagNumber byte = 0x03
+	tagString byte = 0x04
+	tagOther  byte = 0x05
+)
+
 // ValueToKey конвертирует значение в ключ для b-tree (массив байт)
 func ValueToKey(value any) Key {
 	switch v := value.(type) {
+	case nil:
+		return Key{tagNull}
+	case bool:
+		if v {
+			return Key{tagBool, 1}
+		}
+		return Key{tagBool, 0}
 	case int:
-		buf := make([]byte, 8)
-		binary.BigEndian.PutUint64(buf, uint64(v))
-		return buf
+		return numberKey(float64(v))
 	case int32:
-		buf := make([]byte, 8)
-		binary.BigEndian.PutUint64(buf, uint64(v))
-		return buf
+		return numberKey(float64(v))
 	case int64:
-		buf := make([]byte, 8)
-		binary.BigEndian.PutUint64(buf, uint64(v))
-		return buf
+		return numberKey(float64(v))
 	case float32:
-		// json числа чаще float64
-		buf := make([]byte, 8)
-		binary.BigEndian.PutUint64(buf, math.Float64bits(float64(v)))
-		return buf
+		return numberKey(float64(v))
 	case float64:
-		// для сортировок float используем побитовое представление
-		buf := make([]byte, 8)
-		binary.BigEndian.PutUint64(buf, math.Float64bits(v))
-		return buf
+		// json числа приходят как float64
+		return numberKey(v)
 	case string:
-		// строки просто конвертируем в []byte
-		return []byte(v)
-	case bool:
-		if v {
-			return []byte{1}
+		key := make(Key, 0, len(v)+1)
+		key = append(key, tagString)
+		return append(key, v...)
+	default:
+		return append(Key{tagOther}, fmt.Sprintf("%v", v)...)
+	}
+}
+
+// numberKey кодирует число так, чтобы побайтовое сравнение совпадало с числовым:
+// у положительных инвертируем знаковый бит, у отрицательных — все биты
+func numberKey(f float64) Key {
+	bits := math.Float64bits(f)
+	if bits&(1<<63) != 0 {
+		bits = ^bits
+	} else {
+		bits |= 1 << 63
+	}
+	key := make(Key, 9)
+	key[0] = tagNumber
+	binary.BigEndian.PutUint64(key[1:], bits)
+	return key
+}
+
+// KeyToValue восстанавливает значение из ключа;
+// false, если ключ построен из составного значения (массив, объект)
+func KeyToValue(key Key) (any, bool) {
+	if len(key) == 0 {
+		return nil, false
+	}
+	switch key[0] {
+	case tagNull:
+		return nil, true
+	case tagBool:
+		return len(key) > 1 && key[1] == 1, true
+	case tagNumber:
+		if len(key) != 9 {
+			return nil, false
+		}
+		bits := binary.BigEndian.Uint64(key[1:])
+		if bits&(1<<63) != 0 {
+			bits &^= 1 << 63
+		} else {
+			bits = ^bits
 		}
-		return []byte{0}
+		return math.Float64frombits(bits), true
+	case tagString:
+		return string(key[1:]), true
 	default:
-		return []byte(fmt.Sprintf("%v", v))
+		return nil, false
+	}
+}
+
+// IsNumberKey проверяет, что ключ построен из числа
+func IsNumberKey(key Key) bool {
+	return len(key) > 0 && key[0] == tagNumber
+}
+
+// TypeBounds возвращает границы диапазона ключей того же типа, что и key:
+// lower <= k < upper для любого ключа k этого типа
+func TypeBounds(key Key) (lower, upper Key) {
+	if len(key) == 0 {
+		return nil, nil
 	}
+	return Key{key[0]}, Key{key[0] + 1}
 }
 
 // ValuesToStrings конвертирует массив value ([]byte) в массив строк (ids)
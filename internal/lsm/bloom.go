@@ -0,0 +1,66 @@
+package lsm
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+)
+
+// bloom — фильтр Блума по ключам таблицы: false означает, что ключа в таблице точно нет
+type bloom struct {
+	bits []byte
+	k    int // число хеш-функций
+}
+
+func keyHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// newBloom строит фильтр по хешам ключей, bitsPerKey бит на ключ
+func newBloom(hashes []uint64, bitsPerKey int) *bloom {
+	// оптимальное число хеш-функций — bitsPerKey * ln 2
+	k := max(1, min(30, int(math.Round(float64(bitsPerKey)*math.Ln2))))
+	nbits := max(64, len(hashes)*bitsPerKey)
+	b := &bloom{bits: make([]byte, (nbits+7)/8), k: k}
+	for _, h := range hashes {
+		b.add(h)
+	}
+	return b
+}
+
+// позиции бит получаются двойным хешированием: h1 + i*h2
+func (b *bloom) add(h uint64) {
+	nbits := uint32(len(b.bits) * 8)
+	h1, h2 := uint32(h), uint32(h>>32)
+	for i := 0; i < b.k; i++ {
+		pos := (h1 + uint32(i)*h2) % nbits
+		b.bits[pos/8] |= 1 << (pos % 8)
+	}
+}
+
+func (b *bloom) mayContain(h uint64) bool {
+	nbits := uint32(len(b.bits) * 8)
+	h1, h2 := uint32(h), uint32(h>>32)
+	for i := 0; i < b.k; i++ {
+		pos := (h1 + uint32(i)*h2) % nbits
+		if b.bits[pos/8]&(1<<(pos%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloom) encode() []byte {
+	buf := binary.AppendUvarint(nil, uint64(b.k))
+	return append(buf, b.bits...)
+}
+
+func decodeBloom(buf []byte) (*bloom, error) {
+	k, n := binary.Uvarint(buf)
+	if n <= 0 || k == 0 || len(buf) == n {
+		return nil, errCorrupted
+	}
+	return &bloom{k: int(k), bits: buf[n:]}, nil
+}
@@ -0,0 +1,236 @@
+package lsm
+
+import "fmt"
+
+// maybeCompactLocked запускает фоновую компактизацию, если какой-то уровень переполнен
+// и она ещё не идёт
+func (db *DB) maybeCompactLocked() {
+	if db.compacting || db.closed {
+		return
+	}
+	if _, ok := db.pickCompaction(db.current); !ok {
+		return
+	}
+	db.compacting = true
+	db.bg.Add(1)
+	go db.compactLoop()
+}
+
+// compactLoop сливает переполненные уровни, пока все не уложатся в свои пределы:
+// уровень 0 — по числу таблиц, остальные — по объёму (каждый в LevelMultiplier раз больше).
+// Слияние идёт без блокировки; под мьютексом выбираются входы и ставится новая версия
+func (db *DB) compactLoop() {
+	defer db.bg.Done()
+	for {
+		db.mu.Lock()
+		level, ok := db.pickCompaction(db.current)
+		if !ok || db.closed {
+			db.compacting = false
+			db.mu.Unlock()
+			return
+		}
+		c := db.prepareCompaction(level)
+		db.mu.Unlock()
+
+		outputs, err := db.writeMerged(c.merge())
+		c.version.release()
+
+		db.mu.Lock()
+		if err == nil {
+			err = db.installCompactionLocked(c, outputs)
+		}
+		if err != nil {
+			db.bgErr = fmt.Errorf("compaction of level %d: %w", level, err)
+			db.compacting = false
+			db.mu.Unlock()
+			return
+		}
+		db.mu.Unlock()
+	}
+}
+
+func (db *DB) pickCompaction(v *version) (int, bool) {
+	if len(v.levels[0]) >= db.opts.L0Tables {
+		return 0, true
+	}
+	limit := db.opts.LevelBase
+	for level := 1; level < numLevels-1; level++ {
+		if levelSize(v.levels[level]) > limit {
+			return level, true
+		}
+		limit *= db.opts.LevelMultiplier
+	}
+	return 0, false
+}
+
+func levelSize(tables []*table) int64 {
+	var size int64
+	for _, t := range tables {
+		size += t.size
+	}
+	return size
+}
+
+// compaction — таблицы уровня и пересекающиеся с ними таблицы следующего уровня
+type compaction struct {
+	level    int
+	inputs   []*table
+	overlaps []*table
+	hi       string
+	bottom   bool     // ниже нет данных: tombstone можно выбросить
+	version  *version // держит входы до конца слияния
+}
+
+// prepareCompaction выбирает входы: с уровня 0 уходят все таблицы (они пересекаются
+// между собой), с остальных — одна, по кругу по диапазону ключей
+func (db *DB) prepareCompaction(level int) *compaction {
+	v := db.current
+	v.ref()
+	c := &compaction{level: level, version: v, bottom: true}
+	if level == 0 {
+		c.inputs = v.levels[0]
+	} else {
+		c.inputs = []*table{db.pickTable(v, level)}
+	}
+
+	lo, hi := c.inputs[0].minKey(), c.inputs[0].maxKey()
+	for _, t := range c.inputs[1:] {
+		lo, hi = min(lo, t.minKey()), max(hi, t.maxKey())
+	}
+	c.hi = hi
+	for _, t := range v.levels[level+1] {
+		if t.overlaps(lo, hi) {
+			c.overlaps = append(c.overlaps, t)
+		}
+	}
+	for _, tables := range v.levels[level+2:] {
+		if len(tables) > 0 {
+			c.bottom = false
+		}
+	}
+	return c
+}
+
+func (c *compaction) merge() iterator {
+	sources := make([]iterator, 0, len(c.inputs)+1)
+	for _, t := range c.inputs {
+		sources = append(sources, t.iter())
+	}
+	if len(c.overlaps) > 0 {
+		sources = append(sources, &levelIter{tables: c.overlaps})
+	}
+	return newMergeIter(sources, c.bottom)
+}
+
+// installCompactionLocked заменяет входы результатом слияния. Таблицы уровня 0,
+// сброшенные во время слияния, остаются на месте: они новее входов
+func (db *DB) installCompactionLocked(c *compaction, outputs []*table) error {
+	levels := db.current.levels
+	levels[c.level] = without(levels[c.level], c.inputs)
+	levels[c.level+1] = insertSorted(without(levels[c.level+1], c.overlaps), outputs)
+	replaced := append(c.inputs, c.overlaps...)
+	for _, t := range replaced {
+		t.obsolete.Store(true)
+	}
+	if err := db.installLocked(levels, outputs); err != nil {
+		for _, t := range replaced {
+			t.obsolete.Store(false)
+		}
+		return err
+	}
+	db.pointers[c.level] = c.hi
+	return nil
+}
+
+// pickTable выбирает таблицу уровня, следующую по ключам за прошлой компактизацией
+func (db *DB) pickTable(v *version, level int) *table {
+	for _, t := range v.levels[level] {
+		if t.minKey() > db.pointers[level] {
+			return t
+		}
+	}
+	return v.levels[level][0]
+}
+
+// writeMerged пишет поток записей в новые таблицы размером около TableSize
+func (db *DB) writeMerged(it iterator) ([]*table, error) {
+	var outputs []*table
+	var tw *tableWriter
+	fail := func(err error) ([]*table, error) {
+		if tw != nil {
+			tw.abort()
+		}
+		for _, t := range outputs {
+			t.obsolete.Store(true)
+			t.unref()
+		}
+		return nil, err
+	}
+
+	for it.next() {
+		if tw == nil {
+			var err error
+			db.mu.Lock()
+			tw, err = db.newTable()
+			db.mu.Unlock()
+			if err != nil {
+				return fail(err)
+			}
+		}
+		key, value, deleted := it.entry()
+		if err := tw.add(key, value, deleted); err != nil {
+			return fail(err)
+		}
+		if tw.estimatedSize() >= db.opts.TableSize {
+			t, err := tw.finish()
+			tw = nil
+			if err != nil {
+				return fail(err)
+			}
+			outputs = append(outputs, t)
+		}
+	}
+	if err := it.error(); err != nil {
+		return fail(err)
+	}
+	if tw != nil {
+		t, err := tw.finish()
+		tw = nil
+		if err != nil {
+			return fail(err)
+		}
+		outputs = append(outputs, t)
+	}
+	return outputs, nil
+}
+
+func without(tables, remove []*table) []*table {
+	kept := make([]*table, 0, len(tables))
+	for _, t := range tables {
+		removed := false
+		for _, r := range remove {
+			if t == r {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// insertSorted добавляет таблицы в уровень, сохраняя порядок по ключам
+func insertSorted(tables, added []*table) []*table {
+	result := make([]*table, 0, len(tables)+len(added))
+	i := 0
+	for _, t := range tables {
+		for i < len(added) && added[i].minKey() < t.minKey() {
+			result = append(result, added[i])
+			i++
+		}
+		result = append(result, t)
+	}
+	return append(result, added[i:]...)
+}
@@ -0,0 +1,604 @@
+// Package lsm — хранилище ключ-значение на LSM-дереве: записи попадают в журнал (WAL)
+// и skiplist-memtable, заполненная memtable в фоне сбрасывается в неизменяемую
+// отсортированную таблицу (SSTable) уровня 0, а уровни сливаются вниз (leveled compaction)
+package lsm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	manifestName    = "MANIFEST"
+	tableExt        = ".sst"
+	manifestVersion = 1
+
+	// numLevels — число уровней дерева
+	numLevels = 7
+)
+
+// Options — параметры дерева; нулевые значения заменяются значениями по умолчанию
+type Options struct {
+	MemtableSize    int64 // объём memtable, после которого она сбрасывается в таблицу
+	BlockSize       int   // размер блока данных таблицы
+	BloomBitsPerKey int   // бит фильтра Блума на ключ
+	L0Tables        int   // число таблиц уровня 0, после которого они сливаются в уровень 1
+	TableSize       int64 // размер таблицы, на котором компактизация начинает следующую
+	LevelBase       int64 // предельный объём уровня 1
+	LevelMultiplier int64 // во сколько раз каждый следующий уровень больше предыдущего
+}
+
+func (o Options) withDefaults() Options {
+	if o.MemtableSize <= 0 {
+		o.MemtableSize = 4 << 20
+	}
+	if o.BlockSize <= 0 {
+		o.BlockSize = 4 << 10
+	}
+	if o.BloomBitsPerKey <= 0 {
+		o.BloomBitsPerKey = 10
+	}
+	if o.L0Tables <= 0 {
+		o.L0Tables = 4
+	}
+	if o.TableSize <= 0 {
+		o.TableSize = 2 << 20
+	}
+	if o.LevelBase <= 0 {
+		o.LevelBase = 10 << 20
+	}
+	if o.LevelMultiplier <= 1 {
+		o.LevelMultiplier = 10
+	}
+	return o
+}
+
+// manifest — список таблиц по уровням; уровень 0 — от новых к старым,
+// остальные уровни — по возрастанию ключей
+type manifest struct {
+	Version    int        `json:"version"`
+	NextTable  int        `json:"next_table"`  // следующий номер файла (таблицы и WAL)
+	Seq        uint64     `json:"seq"`         // номер последней записи, попавшей в таблицы
+	FlushedLog int        `json:"flushed_log"` // WAL с номерами не больше этого уже в таблицах
+	Levels     [][]string `json:"levels"`
+}
+
+// version — неизменяемый набор таблиц по уровням. Чтение держит ссылку на версию,
+// поэтому её таблицы не удаляются, пока чтение не закончится
+type version struct {
+	levels [numLevels][]*table
+	refs   atomic.Int32
+}
+
+func newVersion(levels [numLevels][]*table) *version {
+	v := &version{levels: levels}
+	for _, tables := range levels {
+		for _, t := range tables {
+			t.ref()
+		}
+	}
+	v.refs.Store(1)
+	return v
+}
+
+func (v *version) ref() { v.refs.Add(1) }
+
+func (v *version) release() {
+	if v.refs.Add(-1) > 0 {
+		return
+	}
+	for _, tables := range v.levels {
+		for _, t := range tables {
+			t.unref()
+		}
+	}
+}
+
+// get ищет ключ в таблицах версии сверху вниз
+func (v *version) get(key string) ([]byte, bool, error) {
+	hash := keyHash(key)
+	// таблицы уровня 0 пересекаются: проверяются все, от новых к старым
+	for _, t := range v.levels[0] {
+		value, deleted, found, err := t.get(key, hash)
+		if err != nil || found {
+			return value, found && !deleted, err
+		}
+	}
+	for _, tables := range v.levels[1:] {
+		i := sort.Search(len(tables), func(i int) bool { return tables[i].maxKey() >= key })
+		if i == len(tables) {
+			continue
+		}
+		value, deleted, found, err := tables[i].get(key, hash)
+		if err != nil || found {
+			return value, found && !deleted, err
+		}
+	}
+	return nil, false, nil
+}
+
+// DB — LSM-дерево в каталоге dir
+type DB struct {
+	mu      sync.RWMutex
+	flushMu sync.Mutex // упорядочивает вызовы Flush
+	cond    *sync.Cond // ожидание сброса неизменяемой memtable (на mu)
+	dir     string
+	opts    Options
+
+	mem    *memtable
+	imm    *memtable // заполненная memtable, которая пишется в таблицу
+	immLog int       // WAL с номерами не больше этого покрываются imm
+	wal    *os.File  // WAL текущей memtable, создаётся при первой записи
+	batch  []byte    // записи, ещё не попавшие в WAL
+
+	current    *version
+	pointers   [numLevels]string // последний ключ, слитый с уровня (выбор следующей таблицы)
+	next       int
+	seq        uint64 // номер последней записи
+	committed  uint64 // номер последней записи в WAL
+	flushedSeq uint64 // номер последней записи в таблицах
+	flushedLog int
+
+	compacting bool
+	bgErr      error // ошибка фонового сброса или компактизации, возвращается из Flush
+	bg         sync.WaitGroup
+	closed     bool
+}
+
+// Open открывает дерево в каталоге dir: читает манифест и применяет журналы
+// несброшенных memtable (каталог создаётся при первой записи)
+func Open(dir string, opts Options) (*DB, error) {
+	db := &DB{dir: dir, opts: opts.withDefaults(), mem: newMemtable(), next: 1}
+	db.cond = sync.NewCond(&db.mu)
+
+	var levels [numLevels][]*table
+	raw, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err == nil {
+		var m manifest
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("failed to parse manifest: %w", err)
+		}
+		if m.Version != manifestVersion {
+			return nil, fmt.Errorf("unsupported manifest version %d", m.Version)
+		}
+		db.next, db.flushedSeq, db.flushedLog = m.NextTable, m.Seq, m.FlushedLog
+		for level, names := range m.Levels {
+			for _, name := range names {
+				t, err := openTable(filepath.Join(dir, name), name)
+				if err != nil {
+					for _, tables := range levels {
+						for _, t := range tables {
+							t.unref()
+						}
+					}
+					return nil, err
+				}
+				levels[level] = append(levels[level], t)
+			}
+		}
+	}
+	db.current = newVersion(levels)
+	// таблицы принадлежат версии
+	for _, tables := range levels {
+		for _, t := range tables {
+			t.unref()
+		}
+	}
+
+	if err := db.recover(); err != nil {
+		db.current.release()
+		return nil, err
+	}
+	db.seq = max(db.flushedSeq, db.mem.lastSeq)
+	db.committed = db.seq
+	return db, nil
+}
+
+// recover применяет несброшенные журналы и удаляет файлы, не попавшие в манифест
+// (сбой во время сброса или компактизации)
+func (db *DB) recover() error {
+	entries, err := os.ReadDir(db.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	live := make(map[string]bool)
+	for _, tables := range db.current.levels {
+		for _, t := range tables {
+			live[t.name] = true
+		}
+	}
+
+	var logs []int
+	for _, entry := range entries {
+		name := entry.Name()
+		num, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(name, tableExt), walExt))
+		if err != nil {
+			continue
+		}
+		db.next = max(db.next, num+1)
+		switch {
+		case strings.HasSuffix(name, tableExt) && !live[name]:
+			os.Remove(filepath.Join(db.dir, name))
+		case strings.HasSuffix(name, walExt) && num <= db.flushedLog:
+			os.Remove(filepath.Join(db.dir, name))
+		case strings.HasSuffix(name, walExt):
+			logs = append(logs, num)
+		}
+	}
+	sort.Ints(logs)
+	for _, num := range logs {
+		if err := replayWAL(filepath.Join(db.dir, walName(num)), db.mem); err != nil {
+			return fmt.Errorf("failed to replay wal: %w", err)
+		}
+	}
+	return nil
+}
+
+func walName(num int) string {
+	return fmt.Sprintf("%06d%s", num, walExt)
+}
+
+// Get возвращает значение ключа: memtable, затем таблицы сверху вниз
+func (db *DB) Get(key string) ([]byte, bool, error) {
+	db.mu.RLock()
+	if e, ok := db.mem.get(key); ok {
+		db.mu.RUnlock()
+		return e.value, !e.deleted, nil
+	}
+	if db.imm != nil {
+		if e, ok := db.imm.get(key); ok {
+			db.mu.RUnlock()
+			return e.value, !e.deleted, nil
+		}
+	}
+	v := db.current
+	v.ref()
+	db.mu.RUnlock()
+
+	defer v.release()
+	return v.get(key)
+}
+
+// Put записывает значение ключа; запись становится долговечной после Flush
+func (db *DB) Put(key string, value []byte) {
+	db.write(memEntry{key: key, value: value})
+}
+
+// Delete записывает tombstone ключа; запись становится долговечной после Flush
+func (db *DB) Delete(key string) {
+	db.write(memEntry{key: key, deleted: true})
+}
+
+func (db *DB) write(e memEntry) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.seq++
+	db.mem.put(e)
+	db.mem.lastSeq = db.seq
+	db.batch = append(db.batch, encodeRecord(e.key, e.value, e.deleted)...)
+}
+
+// Seq возвращает номер последней записи, попавшей в журнал
+func (db *DB) Seq() uint64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.committed
+}
+
+// MemoryUsage — примерный объём памяти дерева: memtable, несброшенные записи,
+// индексы блоков и фильтры Блума открытых таблиц
+func (db *DB) MemoryUsage() int64 {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	total := db.mem.size + int64(len(db.batch))
+	if db.imm != nil {
+		total += db.imm.size
+	}
+	for _, level := range db.current.levels {
+		for _, t := range level {
+			total += t.memoryUsage()
+		}
+	}
+	return total
+}
+
+// Scan обходит живые ключи по возрастанию; fn возвращает false, чтобы остановиться
+func (db *DB) Scan(fn func(key string, value []byte) bool) error {
+	snap := db.Snapshot()
+	defer snap.Release()
+	return snap.Scan(fn)
+}
+
+// Flush дописывает записи с прошлого вызова в WAL одним пакетом (sync — с fsync).
+// Заполненная memtable становится неизменяемой и сбрасывается в таблицу в фоне;
+// если предыдущая ещё не сброшена, Flush её дожидается
+func (db *DB) Flush(sync bool) error {
+	db.flushMu.Lock()
+	defer db.flushMu.Unlock()
+
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return fmt.Errorf("lsm: database is closed")
+	}
+	batch, last := db.batch, db.seq
+	db.batch = nil
+	created := false
+	if len(batch) > 0 && db.wal == nil {
+		f, err := db.createWAL()
+		if err != nil {
+			db.batch = append(batch, db.batch...)
+			db.mu.Unlock()
+			return err
+		}
+		db.wal, created = f, true
+	}
+	wal := db.wal
+	db.mu.Unlock()
+
+	if len(batch) > 0 {
+		if err := appendBatch(wal, batch, last); err != nil {
+			db.mu.Lock()
+			db.batch = append(batch, db.batch...)
+			db.mu.Unlock()
+			return err
+		}
+	}
+	if sync && wal != nil {
+		if err := wal.Sync(); err != nil {
+			return fmt.Errorf("fsync wal: %w", err)
+		}
+		if created {
+			if err := syncDir(db.dir); err != nil {
+				return err
+			}
+		}
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.committed = max(db.committed, last)
+	if err := db.takeBackgroundError(); err != nil {
+		return err
+	}
+	if db.mem.size < db.opts.MemtableSize {
+		return nil
+	}
+	for db.imm != nil && db.bgErr == nil && !db.closed {
+		db.cond.Wait()
+	}
+	if err := db.takeBackgroundError(); err != nil {
+		return err
+	}
+	db.rotateLocked()
+	return nil
+}
+
+// takeBackgroundError возвращает ошибку фоновой работы и повторяет несостоявшийся сброс
+func (db *DB) takeBackgroundError() error {
+	err := db.bgErr
+	if err == nil {
+		return nil
+	}
+	db.bgErr = nil
+	if db.imm != nil {
+		db.bg.Add(1)
+		go db.flushImmutable()
+	}
+	return err
+}
+
+func (db *DB) createWAL() (*os.File, error) {
+	if err := os.MkdirAll(db.dir, 0755); err != nil {
+		return nil, fmt.Errorf("mkdir error: %w", err)
+	}
+	num := db.next
+	db.next++
+	f, err := os.OpenFile(filepath.Join(db.dir, walName(num)), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wal: %w", err)
+	}
+	return f, nil
+}
+
+// rotateLocked делает memtable неизменяемой и запускает её сброс в таблицу.
+// Журналы, созданные до ротации, покрываются этой memtable
+func (db *DB) rotateLocked() {
+	if db.wal != nil {
+		db.wal.Close()
+		db.wal = nil
+	}
+	db.imm = db.mem
+	db.immLog = db.next - 1
+	db.mem = newMemtable()
+	db.bg.Add(1)
+	go db.flushImmutable()
+}
+
+// flushImmutable пишет неизменяемую memtable в таблицу уровня 0 и удаляет её журналы
+func (db *DB) flushImmutable() {
+	defer db.bg.Done()
+
+	db.mu.Lock()
+	imm, immLog := db.imm, db.immLog
+	tw, err := db.newTable()
+	db.mu.Unlock()
+
+	var t *table
+	if err == nil {
+		t, err = writeEntries(tw, imm.entries())
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	defer db.cond.Broadcast()
+	if err != nil {
+		db.bgErr = fmt.Errorf("flush memtable: %w", err)
+		return
+	}
+
+	levels := db.current.levels
+	levels[0] = append([]*table{t}, levels[0]...)
+	prevSeq, prevLog := db.flushedSeq, db.flushedLog
+	db.flushedSeq, db.flushedLog = max(db.flushedSeq, imm.lastSeq), immLog
+	if err := db.installLocked(levels, []*table{t}); err != nil {
+		db.flushedSeq, db.flushedLog = prevSeq, prevLog
+		db.bgErr = err
+		return
+	}
+	db.imm = nil
+
+	entries, _ := os.ReadDir(db.dir)
+	for _, entry := range entries {
+		num, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), walExt))
+		if err == nil && strings.HasSuffix(entry.Name(), walExt) && num <= immLog {
+			os.Remove(filepath.Join(db.dir, entry.Name()))
+		}
+	}
+	db.maybeCompactLocked()
+}
+
+func writeEntries(tw *tableWriter, entries []memEntry) (*table, error) {
+	for _, e := range entries {
+		if err := tw.add(e.key, e.value, e.deleted); err != nil {
+			tw.abort()
+			return nil, err
+		}
+	}
+	return tw.finish()
+}
+
+// installLocked делает levels текущей версией и записывает манифест. Новые таблицы
+// created при ошибке удаляются; ссылки создателя на них отпускаются в любом случае
+func (db *DB) installLocked(levels [numLevels][]*table, created []*table) error {
+	v := newVersion(levels)
+	if err := db.writeManifest(v); err != nil {
+		v.release()
+		for _, t := range created {
+			t.obsolete.Store(true)
+			t.unref()
+		}
+		return err
+	}
+	for _, t := range created {
+		t.unref()
+	}
+	old := db.current
+	db.current = v
+	old.release()
+	return nil
+}
+
+// Close дописывает несохранённые записи в WAL, дожидается фоновой работы и закрывает файлы
+func (db *DB) Close() error {
+	db.flushMu.Lock()
+	defer db.flushMu.Unlock()
+
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return nil
+	}
+	db.closed = true
+	db.cond.Broadcast()
+	db.mu.Unlock()
+	db.bg.Wait()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	var err error
+	if len(db.batch) > 0 {
+		if db.wal == nil {
+			db.wal, err = db.createWAL()
+		}
+		if err == nil {
+			err = appendBatch(db.wal, db.batch, db.seq)
+		}
+		db.batch = nil
+	}
+	if db.wal != nil {
+		if closeErr := db.wal.Close(); err == nil {
+			err = closeErr
+		}
+		db.wal = nil
+	}
+	db.current.release()
+	return err
+}
+
+func (db *DB) newTable() (*tableWriter, error) {
+	if err := os.MkdirAll(db.dir, 0755); err != nil {
+		return nil, fmt.Errorf("mkdir error: %w", err)
+	}
+	name := fmt.Sprintf("%06d%s", db.next, tableExt)
+	db.next++
+	return createTable(filepath.Join(db.dir, name), name, db.opts)
+}
+
+// writeManifest атомарно заменяет манифест (временный файл, fsync, rename)
+func (db *DB) writeManifest(v *version) error {
+	m := manifest{
+		Version:    manifestVersion,
+		NextTable:  db.next,
+		Seq:        db.flushedSeq,
+		FlushedLog: db.flushedLog,
+	}
+	for _, tables := range v.levels {
+		names := make([]string, 0, len(tables))
+		for _, t := range tables {
+			names = append(names, t.name)
+		}
+		m.Levels = append(m.Levels, names)
+	}
+	return writeManifestFile(db.dir, m)
+}
+
+func writeManifestFile(dir string, m manifest) error {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	tmp := filepath.Join(dir, manifestName+".tmp")
+	if err := writeFileSync(tmp, raw); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, manifestName)); err != nil {
+		return fmt.Errorf("rename manifest: %w", err)
+	}
+	return syncDir(dir)
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func syncDir(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
@@ -0,0 +1,186 @@
+package lsm
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+)
+
+// iterator — последовательный обход записей по возрастанию ключа
+type iterator interface {
+	next() bool
+	entry() (key string, value []byte, deleted bool)
+	error() error
+}
+
+// memIter обходит отсортированные записи memtable
+type memIter struct {
+	entries []memEntry
+	i       int
+}
+
+func (it *memIter) next() bool {
+	it.i++
+	return it.i <= len(it.entries)
+}
+
+func (it *memIter) entry() (string, []byte, bool) {
+	e := it.entries[it.i-1]
+	return e.key, e.value, e.deleted
+}
+
+func (it *memIter) error() error { return nil }
+
+// tableIter читает записи блоков данных таблицы подряд
+type tableIter struct {
+	r       *bufio.Reader
+	key     string
+	value   []byte
+	deleted bool
+	err     error
+}
+
+func (it *tableIter) next() bool {
+	if it.err != nil {
+		return false
+	}
+	keyLen, err := binary.ReadUvarint(it.r)
+	if err == io.EOF {
+		return false
+	}
+	if err != nil {
+		it.err = err
+		return false
+	}
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(it.r, key); err != nil {
+		it.err = errCorrupted
+		return false
+	}
+	flag, err := it.r.ReadByte()
+	if err != nil {
+		it.err = errCorrupted
+		return false
+	}
+	valueLen, err := binary.ReadUvarint(it.r)
+	if err != nil {
+		it.err = errCorrupted
+		return false
+	}
+	value := make([]byte, valueLen)
+	if _, err := io.ReadFull(it.r, value); err != nil {
+		it.err = errCorrupted
+		return false
+	}
+	it.key, it.value, it.deleted = string(key), value, flag == flagTombstone
+	return true
+}
+
+func (it *tableIter) entry() (string, []byte, bool) { return it.key, it.value, it.deleted }
+func (it *tableIter) error() error                  { return it.err }
+
+// levelIter обходит непересекающиеся таблицы уровня по очереди
+type levelIter struct {
+	tables []*table
+	cur    *tableIter
+	err    error
+}
+
+func (it *levelIter) next() bool {
+	for {
+		if it.cur != nil {
+			if it.cur.next() {
+				return true
+			}
+			if it.err = it.cur.error(); it.err != nil {
+				return false
+			}
+		}
+		if len(it.tables) == 0 {
+			return false
+		}
+		it.cur = it.tables[0].iter()
+		it.tables = it.tables[1:]
+	}
+}
+
+func (it *levelIter) entry() (string, []byte, bool) { return it.cur.entry() }
+func (it *levelIter) error() error                  { return it.err }
+
+// mergeIter сливает источники в один поток по возрастанию ключа. Источники передаются
+// от новых к старым: из одинаковых ключей остаётся запись самого нового источника
+type mergeIter struct {
+	h              mergeHeap
+	dropTombstones bool
+	key            string
+	value          []byte
+	deleted        bool
+	err            error
+}
+
+type mergeSource struct {
+	it       iterator
+	priority int // меньше — новее
+}
+
+type mergeHeap []mergeSource
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	ki, _, _ := h[i].it.entry()
+	kj, _, _ := h[j].it.entry()
+	if ki != kj {
+		return ki < kj
+	}
+	return h[i].priority < h[j].priority
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(mergeSource)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+func newMergeIter(sources []iterator, dropTombstones bool) *mergeIter {
+	m := &mergeIter{dropTombstones: dropTombstones}
+	for i, it := range sources {
+		m.push(mergeSource{it: it, priority: i})
+	}
+	return m
+}
+
+func (m *mergeIter) push(src mergeSource) {
+	if src.it.next() {
+		heap.Push(&m.h, src)
+	} else if err := src.it.error(); err != nil && m.err == nil {
+		m.err = err
+	}
+}
+
+func (m *mergeIter) next() bool {
+	for m.err == nil && m.h.Len() > 0 {
+		top := heap.Pop(&m.h).(mergeSource)
+		key, value, deleted := top.it.entry()
+		m.push(top)
+		// более старые версии того же ключа пропускаются
+		for m.h.Len() > 0 {
+			k, _, _ := m.h[0].it.entry()
+			if k != key {
+				break
+			}
+			m.push(heap.Pop(&m.h).(mergeSource))
+		}
+		if deleted && m.dropTombstones {
+			continue
+		}
+		m.key, m.value, m.deleted = key, value, deleted
+		return true
+	}
+	return false
+}
+
+func (m *mergeIter) entry() (string, []byte, bool) { return m.key, m.value, m.deleted }
+func (m *mergeIter) error() error                  { return m.err }
@@ -0,0 +1,261 @@
+package lsm
+
+import (
+	"fmt"
+	"maps"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// smallOptions — маленькие memtable, таблицы и уровни, чтобы сброс и компактизация
+// случались на нескольких тысячах записей
+var smallOptions = Options{MemtableSize: 4 << 10, BlockSize: 512, TableSize: 8 << 10, LevelBase: 16 << 10, L0Tables: 2}
+
+// openTestDB открывает дерево в каталоге dir и закрывает его в конце теста
+func openTestDB(t *testing.T, dir string, opts Options) *DB {
+	t.Helper()
+	db, err := Open(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// contents возвращает все живые ключи и значения дерева
+func contents(t *testing.T, db *DB) map[string]string {
+	t.Helper()
+	found := make(map[string]string)
+	var last string
+	if err := db.Scan(func(key string, value []byte) bool {
+		if key <= last && len(found) > 0 {
+			t.Fatalf("scan is not ordered: %q after %q", key, last)
+		}
+		last = key
+		found[key] = string(value)
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
+
+func TestMemtable(t *testing.T) {
+	mem := newMemtable()
+	model := make(map[string]memEntry)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("k%04d", rng.Intn(500))
+		e := memEntry{key: key, value: []byte(fmt.Sprint(i)), deleted: rng.Intn(5) == 0}
+		mem.put(e)
+		model[key] = e
+	}
+
+	if mem.count != len(model) {
+		t.Fatalf("count %d, want %d", mem.count, len(model))
+	}
+	entries := mem.entries()
+	keys := slices.Sorted(maps.Keys(model))
+	for i, e := range entries {
+		if e.key != keys[i] || string(e.value) != string(model[e.key].value) || e.deleted != model[e.key].deleted {
+			t.Fatalf("entry %d: %+v, want %+v", i, e, model[keys[i]])
+		}
+	}
+	if e, ok := mem.get(keys[0]); !ok || e.key != keys[0] {
+		t.Fatalf("get %s: %+v %v", keys[0], e, ok)
+	}
+	if _, ok := mem.get("k9999"); ok {
+		t.Fatal("missing key found")
+	}
+}
+
+func TestBloom(t *testing.T) {
+	var hashes []uint64
+	for i := 0; i < 10000; i++ {
+		hashes = append(hashes, keyHash(fmt.Sprintf("key%d", i)))
+	}
+	filter, err := decodeBloom(newBloom(hashes, 10).encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range hashes {
+		if !filter.mayContain(h) {
+			t.Fatal("bloom filter lost a key")
+		}
+	}
+	// при 10 битах на ключ ложных срабатываний около 1%
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if filter.mayContain(keyHash(fmt.Sprintf("other%d", i))) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 300 {
+		t.Fatalf("%d false positive(s) of 10000", falsePositives)
+	}
+}
+
+func TestTable(t *testing.T) {
+	dir := t.TempDir()
+	tw, err := createTable(filepath.Join(dir, "000001.sst"), "000001.sst", Options{}.withDefaults())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw.blockSize = 256
+	for i := 0; i < 1000; i++ {
+		if err := tw.add(fmt.Sprintf("key%04d", i*2), []byte(fmt.Sprint(i)), i%10 == 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := tw.finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	tbl, err := openTable(filepath.Join(dir, "000001.sst"), "000001.sst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tbl.unref()
+	if len(tbl.blocks) < 10 || tbl.count != 1000 || tbl.minKey() != "key0000" || tbl.maxKey() != "key1998" {
+		t.Fatalf("%d block(s), %d record(s), keys %s..%s", len(tbl.blocks), tbl.count, tbl.minKey(), tbl.maxKey())
+	}
+	for _, tt := range []struct {
+		key            string
+		value          string
+		deleted, found bool
+	}{
+		{"key0002", "1", false, true},
+		{"key0020", "", true, true},
+		{"key1998", "999", false, true},
+		{"key0003", "", false, false},
+		{"key9999", "", false, false},
+		{"a", "", false, false},
+	} {
+		value, deleted, found, err := tbl.get(tt.key, keyHash(tt.key))
+		if err != nil || found != tt.found || deleted != tt.deleted || (found && string(value) != tt.value) {
+			t.Errorf("get %s: %q deleted=%v found=%v %v", tt.key, value, deleted, found, err)
+		}
+	}
+
+	it := tbl.iter()
+	n := 0
+	for ; it.next(); n++ {
+		if key, _, _ := it.entry(); key != fmt.Sprintf("key%04d", n*2) {
+			t.Fatalf("record %d: %s", n, key)
+		}
+	}
+	if it.error() != nil || n != 1000 {
+		t.Fatalf("iterated %d record(s): %v", n, it.error())
+	}
+}
+
+func TestWALRecovery(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir, Options{})
+	db.Put("a", []byte("1"))
+	db.Put("b", []byte("2"))
+	db.Delete("a")
+	if err := db.Flush(true); err != nil {
+		t.Fatal(err)
+	}
+	// не сброшенная в WAL запись при сбое теряется
+	db.Put("c", []byte("3"))
+
+	// сбой: дерево не закрыто, в журнале — недописанный пакет
+	wals, _ := filepath.Glob(filepath.Join(dir, "*"+walExt))
+	if len(wals) != 1 {
+		t.Fatalf("wal files: %v", wals)
+	}
+	before, _ := os.Stat(wals[0])
+	f, err := os.OpenFile(wals[0], os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte{200, 0, 0, 0, 1, 2, 3, 4, 5})
+	f.Close()
+
+	recovered := openTestDB(t, dir, Options{})
+	if got := contents(t, recovered); !maps.Equal(got, map[string]string{"b": "2"}) {
+		t.Fatalf("recovered: %v", got)
+	}
+	if recovered.Seq() != 3 {
+		t.Fatalf("seq %d after recovery", recovered.Seq())
+	}
+	if after, _ := os.Stat(wals[0]); after.Size() != before.Size() {
+		t.Fatalf("wal is %d bytes after recovery, want %d", after.Size(), before.Size())
+	}
+}
+
+func TestFlushAndCompaction(t *testing.T) {
+	dir := t.TempDir()
+	db := openTestDB(t, dir, smallOptions)
+	model := make(map[string]string)
+	rng := rand.New(rand.NewSource(2))
+	for i := 0; i < 6000; i++ {
+		key := fmt.Sprintf("key%05d", rng.Intn(2000))
+		if rng.Intn(4) == 0 {
+			db.Delete(key)
+			delete(model, key)
+		} else {
+			value := fmt.Sprintf("value %d %s", i, key)
+			db.Put(key, []byte(value))
+			model[key] = value
+		}
+		if i%50 == 49 {
+			if err := db.Flush(false); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// снимок не видит последующих записей и держит таблицы до Release
+	snap := db.Snapshot()
+	db.Put("key00000", []byte("after snapshot"))
+	if err := db.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok, _ := snap.Get("key00000"); ok == false && model["key00000"] != "" || ok && string(value) != model["key00000"] {
+		t.Fatalf("snapshot: %q %v", value, ok)
+	}
+	snap.Release()
+	model["key00000"] = "after snapshot"
+
+	if got := contents(t, db); !maps.Equal(got, model) {
+		t.Fatalf("scan: %d key(s), want %d", len(got), len(model))
+	}
+	for key, want := range model {
+		if value, ok, err := db.Get(key); err != nil || !ok || string(value) != want {
+			t.Fatalf("get %s: %q %v %v", key, value, ok, err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := openTestDB(t, dir, smallOptions)
+	reopened.mu.RLock()
+	levels := reopened.current.levels
+	reopened.mu.RUnlock()
+	deeper := 0
+	for _, tables := range levels[1:] {
+		deeper += len(tables)
+	}
+	if deeper == 0 {
+		t.Fatalf("%d table(s) on level 0, %d below", len(levels[0]), deeper)
+	}
+	// на диске только таблицы из манифеста
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+tableExt))
+	live := len(levels[0]) + deeper
+	if len(files) != live {
+		t.Fatalf("%d table file(s), %d in the manifest", len(files), live)
+	}
+	if got := contents(t, reopened); !maps.Equal(got, model) {
+		t.Fatalf("after reopen: %d key(s), want %d", len(got), len(model))
+	}
+	if _, ok, _ := reopened.Get("missing"); ok {
+		t.Fatal("missing key found")
+	}
+}
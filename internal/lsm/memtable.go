@@ -0,0 +1,107 @@
+package lsm
+
+import "math/rand"
+
+const (
+	skipMaxHeight = 12
+	skipBranching = 4 // вероятность подняться на уровень выше — 1/4
+
+	// entryOverhead — примерные накладные расходы на запись memtable сверх ключа и значения
+	entryOverhead = 64
+)
+
+type memEntry struct {
+	key     string
+	value   []byte
+	deleted bool
+}
+
+type skipNode struct {
+	entry memEntry
+	next  []*skipNode
+}
+
+// memtable — skiplist записей, упорядоченных по ключу. Изменяется под мьютексом DB;
+// после ротации становится неизменяемой и читается без блокировок
+type memtable struct {
+	head    *skipNode
+	height  int
+	rnd     *rand.Rand
+	size    int64  // примерный объём в байтах
+	count   int    // число ключей
+	lastSeq uint64 // номер последней записи
+}
+
+func newMemtable() *memtable {
+	return &memtable{
+		head:   &skipNode{next: make([]*skipNode, skipMaxHeight)},
+		height: 1,
+		rnd:    rand.New(rand.NewSource(rand.Int63())),
+	}
+}
+
+func (m *memtable) randomHeight() int {
+	h := 1
+	for h < skipMaxHeight && m.rnd.Intn(skipBranching) == 0 {
+		h++
+	}
+	return h
+}
+
+// findGreaterOrEqual возвращает первый узел с ключом >= key; prev заполняется
+// последними узлами с меньшим ключом на каждом уровне
+func (m *memtable) findGreaterOrEqual(key string, prev []*skipNode) *skipNode {
+	x := m.head
+	for level := m.height - 1; level >= 0; level-- {
+		for next := x.next[level]; next != nil && next.entry.key < key; next = x.next[level] {
+			x = next
+		}
+		if prev != nil {
+			prev[level] = x
+		}
+	}
+	return x.next[0]
+}
+
+func (m *memtable) get(key string) (memEntry, bool) {
+	node := m.findGreaterOrEqual(key, nil)
+	if node != nil && node.entry.key == key {
+		return node.entry, true
+	}
+	return memEntry{}, false
+}
+
+// put добавляет запись или заменяет запись с тем же ключом
+func (m *memtable) put(e memEntry) {
+	var prev [skipMaxHeight]*skipNode
+	node := m.findGreaterOrEqual(e.key, prev[:])
+	if node != nil && node.entry.key == e.key {
+		m.size += int64(len(e.value) - len(node.entry.value))
+		node.entry = e
+		return
+	}
+
+	height := m.randomHeight()
+	if height > m.height {
+		for level := m.height; level < height; level++ {
+			prev[level] = m.head
+		}
+		m.height = height
+	}
+	node = &skipNode{entry: e, next: make([]*skipNode, height)}
+	for level := 0; level < height; level++ {
+		node.next[level] = prev[level].next[level]
+		prev[level].next[level] = node
+	}
+	m.size += int64(len(e.key)+len(e.value)) + entryOverhead
+	m.count++
+}
+
+// entries возвращает записи по возрастанию ключа
+func (m *memtable) entries() []memEntry {
+	entries := make([]memEntry, 0, m.count)
+	for x := m.head.next[0]; x != nil; x = x.next[0] {
+		entries = append(entries, x.entry)
+	}
+	return entries
+}
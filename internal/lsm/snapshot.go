@@ -0,0 +1,70 @@
+package lsm
+
+// Snapshot — неизменяемый срез дерева: копия memtable, неизменяемая memtable и версия
+// таблиц на момент создания. Таблицы, заменённые компактизацией, удаляются только
+// после Release всех снимков
+type Snapshot struct {
+	mem      *memtable
+	imm      *memtable
+	version  *version
+	released bool
+}
+
+// Snapshot создаёт снимок дерева
+func (db *DB) Snapshot() *Snapshot {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	s := &Snapshot{mem: newMemtable(), imm: db.imm, version: db.current}
+	for _, e := range db.mem.entries() {
+		s.mem.put(e)
+	}
+	s.version.ref()
+	return s
+}
+
+// Get возвращает значение ключа в снимке
+func (s *Snapshot) Get(key string) ([]byte, bool, error) {
+	if e, ok := s.mem.get(key); ok {
+		return e.value, !e.deleted, nil
+	}
+	if s.imm != nil {
+		if e, ok := s.imm.get(key); ok {
+			return e.value, !e.deleted, nil
+		}
+	}
+	return s.version.get(key)
+}
+
+// Scan обходит живые ключи снимка по возрастанию
+func (s *Snapshot) Scan(fn func(key string, value []byte) bool) error {
+	sources := []iterator{&memIter{entries: s.mem.entries()}}
+	if s.imm != nil {
+		sources = append(sources, &memIter{entries: s.imm.entries()})
+	}
+	for _, t := range s.version.levels[0] {
+		sources = append(sources, t.iter())
+	}
+	for _, tables := range s.version.levels[1:] {
+		if len(tables) > 0 {
+			sources = append(sources, &levelIter{tables: tables})
+		}
+	}
+	it := newMergeIter(sources, true)
+	for it.next() {
+		key, value, _ := it.entry()
+		if !fn(key, value) {
+			return nil
+		}
+	}
+	return it.error()
+}
+
+// Release отпускает таблицы снимка
+func (s *Snapshot) Release() {
+	if s.released {
+		return
+	}
+	s.released = true
+	s.version.release()
+}
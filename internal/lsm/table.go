@@ -0,0 +1,358 @@
+package lsm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync/atomic"
+)
+
+// Формат SSTable:
+//
+//	блоки данных (~BlockSize): записи подряд по возрастанию ключа — uvarint длина ключа,
+//	    ключ, флаг (1 — tombstone), uvarint длина значения, значение
+//	индекс: первый ключ таблицы, затем для каждого блока последний ключ, смещение и размер
+//	фильтр Блума по ключам
+//	footer: смещение и длина индекса, смещение и длина фильтра, число записей, magic
+//
+// В памяти держатся только индекс блоков и фильтр; точечное чтение проверяет фильтр
+// и читает с диска один блок
+
+const (
+	flagValue     byte = 0
+	flagTombstone byte = 1
+
+	tableMagic uint64 = 0x4e4f53514c4c534d // "NOSQLLSM"
+	footerSize        = 6 * 8
+)
+
+// blockHandle — положение блока данных и его последний ключ
+type blockHandle struct {
+	lastKey string
+	offset  int64
+	size    int64
+}
+
+// table — неизменяемый отсортированный файл
+type table struct {
+	name     string
+	path     string
+	file     *os.File
+	firstKey string
+	blocks   []blockHandle
+	filter   *bloom
+	dataSize int64 // блоки данных занимают [0, dataSize)
+	size     int64
+	count    int
+
+	refs     atomic.Int32 // ссылки: список уровней и открытые снимки
+	obsolete atomic.Bool  // таблица заменена компактизацией, файл удаляется с последней ссылкой
+}
+
+func (t *table) minKey() string { return t.firstKey }
+func (t *table) maxKey() string { return t.blocks[len(t.blocks)-1].lastKey }
+
+// memoryUsage — память таблицы: индекс блоков и фильтр Блума
+func (t *table) memoryUsage() int64 {
+	total := int64(len(t.filter.bits))
+	for _, h := range t.blocks {
+		total += int64(len(h.lastKey)) + 32
+	}
+	return total
+}
+
+func (t *table) ref() { t.refs.Add(1) }
+
+// unref отпускает ссылку; последняя ссылка закрывает файл и удаляет устаревшую таблицу
+func (t *table) unref() {
+	if t.refs.Add(-1) > 0 {
+		return
+	}
+	t.file.Close()
+	if t.obsolete.Load() {
+		os.Remove(t.path)
+	}
+}
+
+// overlaps — пересекается ли диапазон ключей таблицы с [lo, hi]
+func (t *table) overlaps(lo, hi string) bool {
+	return t.maxKey() >= lo && t.minKey() <= hi
+}
+
+// get ищет ключ в таблице; found == false, если ключа нет. Ключи, отсеянные
+// фильтром Блума, не требуют чтения с диска
+func (t *table) get(key string, hash uint64) (value []byte, deleted, found bool, err error) {
+	if key < t.minKey() || key > t.maxKey() || !t.filter.mayContain(hash) {
+		return nil, false, false, nil
+	}
+	i := sort.Search(len(t.blocks), func(i int) bool { return t.blocks[i].lastKey >= key })
+	block := make([]byte, t.blocks[i].size)
+	if _, err := t.file.ReadAt(block, t.blocks[i].offset); err != nil {
+		return nil, false, false, fmt.Errorf("read table %s: %w", t.name, err)
+	}
+	for len(block) > 0 {
+		k, v, del, n, err := decodeRecord(block)
+		if err != nil {
+			return nil, false, false, fmt.Errorf("table %s: %w", t.name, err)
+		}
+		if k == key {
+			return v, del, true, nil
+		}
+		if k > key {
+			break
+		}
+		block = block[n:]
+	}
+	return nil, false, false, nil
+}
+
+// iter возвращает последовательный итератор по записям таблицы
+func (t *table) iter() *tableIter {
+	return &tableIter{r: bufio.NewReaderSize(io.NewSectionReader(t.file, 0, t.dataSize), 64<<10)}
+}
+
+// openTable открывает файл таблицы: читает footer, индекс блоков и фильтр
+func openTable(path, name string) (*table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := readTableMeta(f, name)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("table %s: %w", name, err)
+	}
+	t.path = path
+	t.ref()
+	return t, nil
+}
+
+func readTableMeta(f *os.File, name string) (*table, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size < footerSize {
+		return nil, errCorrupted
+	}
+	footer := make([]byte, footerSize)
+	if _, err := f.ReadAt(footer, size-footerSize); err != nil {
+		return nil, err
+	}
+	field := func(i int) uint64 { return binary.LittleEndian.Uint64(footer[i*8:]) }
+	indexOff, indexLen, filterOff, filterLen, count := field(0), field(1), field(2), field(3), field(4)
+	if field(5) != tableMagic || indexOff+indexLen > uint64(size) || filterOff+filterLen > uint64(size) {
+		return nil, errCorrupted
+	}
+
+	meta := make([]byte, indexLen+filterLen)
+	if _, err := f.ReadAt(meta, int64(indexOff)); err != nil {
+		return nil, err
+	}
+	t := &table{name: name, file: f, dataSize: int64(indexOff), size: size, count: int(count)}
+	if err := t.decodeIndex(meta[:indexLen]); err != nil {
+		return nil, err
+	}
+	if t.filter, err = decodeBloom(meta[indexLen:]); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *table) decodeIndex(buf []byte) error {
+	readString := func() (string, bool) {
+		n, k := binary.Uvarint(buf)
+		if k <= 0 || uint64(len(buf)-k) < n {
+			return "", false
+		}
+		s := string(buf[k : k+int(n)])
+		buf = buf[k+int(n):]
+		return s, true
+	}
+	readInt := func() (int64, bool) {
+		v, k := binary.Uvarint(buf)
+		if k <= 0 {
+			return 0, false
+		}
+		buf = buf[k:]
+		return int64(v), true
+	}
+
+	var ok bool
+	if t.firstKey, ok = readString(); !ok {
+		return errCorrupted
+	}
+	for len(buf) > 0 {
+		var h blockHandle
+		lastKey, ok1 := readString()
+		offset, ok2 := readInt()
+		size, ok3 := readInt()
+		if !ok1 || !ok2 || !ok3 {
+			return errCorrupted
+		}
+		h.lastKey, h.offset, h.size = lastKey, offset, size
+		t.blocks = append(t.blocks, h)
+	}
+	if len(t.blocks) == 0 {
+		return errors.New("empty table")
+	}
+	return nil
+}
+
+// tableWriter пишет новую таблицу; ключи должны идти по возрастанию
+type tableWriter struct {
+	name       string
+	path       string
+	f          *os.File
+	w          *bufio.Writer
+	blockSize  int
+	bitsPerKey int
+
+	block    bytes.Buffer // текущий блок данных
+	lastKey  string
+	firstKey string
+	blocks   []blockHandle
+	hashes   []uint64
+	size     int64 // записано в файл
+}
+
+func createTable(path, name string, opts Options) (*tableWriter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &tableWriter{
+		name:       name,
+		path:       path,
+		f:          f,
+		w:          bufio.NewWriterSize(f, 64<<10),
+		blockSize:  opts.BlockSize,
+		bitsPerKey: opts.BloomBitsPerKey,
+	}, nil
+}
+
+func (tw *tableWriter) add(key string, value []byte, deleted bool) error {
+	if len(tw.hashes) == 0 {
+		tw.firstKey = key
+	}
+	tw.block.Write(encodeRecord(key, value, deleted))
+	tw.lastKey = key
+	tw.hashes = append(tw.hashes, keyHash(key))
+	if tw.block.Len() >= tw.blockSize {
+		return tw.finishBlock()
+	}
+	return nil
+}
+
+// estimatedSize — размер таблицы с учётом недописанного блока
+func (tw *tableWriter) estimatedSize() int64 {
+	return tw.size + int64(tw.block.Len())
+}
+
+func (tw *tableWriter) finishBlock() error {
+	if tw.block.Len() == 0 {
+		return nil
+	}
+	n, err := tw.w.Write(tw.block.Bytes())
+	if err != nil {
+		return err
+	}
+	tw.blocks = append(tw.blocks, blockHandle{lastKey: tw.lastKey, offset: tw.size, size: int64(n)})
+	tw.size += int64(n)
+	tw.block.Reset()
+	return nil
+}
+
+// finish дописывает индекс, фильтр и footer, делает fsync и открывает таблицу для чтения
+func (tw *tableWriter) finish() (*table, error) {
+	if err := tw.finishBlock(); err != nil {
+		tw.abort()
+		return nil, err
+	}
+
+	index := binary.AppendUvarint(nil, uint64(len(tw.firstKey)))
+	index = append(index, tw.firstKey...)
+	for _, h := range tw.blocks {
+		index = binary.AppendUvarint(index, uint64(len(h.lastKey)))
+		index = append(index, h.lastKey...)
+		index = binary.AppendUvarint(index, uint64(h.offset))
+		index = binary.AppendUvarint(index, uint64(h.size))
+	}
+	filter := newBloom(tw.hashes, tw.bitsPerKey).encode()
+
+	footer := make([]byte, 0, footerSize)
+	for _, v := range []uint64{
+		uint64(tw.size), uint64(len(index)),
+		uint64(tw.size) + uint64(len(index)), uint64(len(filter)),
+		uint64(len(tw.hashes)), tableMagic,
+	} {
+		footer = binary.LittleEndian.AppendUint64(footer, v)
+	}
+	for _, part := range [][]byte{index, filter, footer} {
+		if _, err := tw.w.Write(part); err != nil {
+			tw.abort()
+			return nil, err
+		}
+	}
+
+	if err := tw.w.Flush(); err != nil {
+		tw.abort()
+		return nil, err
+	}
+	if err := tw.f.Sync(); err != nil {
+		tw.abort()
+		return nil, err
+	}
+	if err := tw.f.Close(); err != nil {
+		os.Remove(tw.path)
+		return nil, err
+	}
+	return openTable(tw.path, tw.name)
+}
+
+// abort закрывает и удаляет недописанную таблицу
+func (tw *tableWriter) abort() {
+	tw.f.Close()
+	os.Remove(tw.path)
+}
+
+func encodeRecord(key string, value []byte, deleted bool) []byte {
+	buf := make([]byte, 0, len(key)+len(value)+2*binary.MaxVarintLen64+1)
+	buf = binary.AppendUvarint(buf, uint64(len(key)))
+	buf = append(buf, key...)
+	if deleted {
+		buf = append(buf, flagTombstone)
+		value = nil
+	} else {
+		buf = append(buf, flagValue)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(value)))
+	return append(buf, value...)
+}
+
+var errCorrupted = errors.New("corrupted record")
+
+// decodeRecord разбирает запись в начале buf и возвращает её длину
+func decodeRecord(buf []byte) (key string, value []byte, deleted bool, n int, err error) {
+	keyLen, k := binary.Uvarint(buf)
+	if k <= 0 || uint64(len(buf)-k) < keyLen+1 {
+		return "", nil, false, 0, errCorrupted
+	}
+	n = k + int(keyLen)
+	key = string(buf[k:n])
+	deleted = buf[n] == flagTombstone
+	n++
+	valueLen, k := binary.Uvarint(buf[n:])
+	if k <= 0 || uint64(len(buf)-n-k) < valueLen {
+		return "", nil, false, 0, errCorrupted
+	}
+	n += k
+	value = buf[n : n+int(valueLen)]
+	return key, value, deleted, n + int(valueLen), nil
+}
@@ -0,0 +1,87 @@
+package lsm
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// Журнал упреждающей записи (WAL) memtable: пакеты записей, по одному на Flush.
+// Пакет: uint32 длина, uint32 crc32 содержимого, содержимое — uvarint номер
+// последней записи пакета и записи в формате таблицы. Пакет применяется целиком:
+// недописанный или повреждённый хвост (сбой во время записи) отбрасывается
+
+const walExt = ".wal"
+
+// appendBatch дописывает пакет в конец журнала
+func appendBatch(f *os.File, records []byte, lastSeq uint64) error {
+	payload := binary.AppendUvarint(nil, lastSeq)
+	payload = append(payload, records...)
+	header := make([]byte, 8)
+	binary.LittleEndian.PutUint32(header, uint32(len(payload)))
+	binary.LittleEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload))
+	if _, err := f.Write(append(header, payload...)); err != nil {
+		return fmt.Errorf("write wal: %w", err)
+	}
+	return nil
+}
+
+// replayWAL применяет пакеты журнала к memtable и обрезает файл после последнего целого пакета
+func replayWAL(path string, mem *memtable) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var offset int64
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			break
+		}
+		payload := make([]byte, binary.LittleEndian.Uint32(header))
+		if _, err := io.ReadFull(r, payload); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:]) {
+			break
+		}
+		lastSeq, n := binary.Uvarint(payload)
+		if n <= 0 {
+			break
+		}
+		var entries []memEntry
+		ok := true
+		for rest := payload[n:]; len(rest) > 0; {
+			key, value, deleted, size, err := decodeRecord(rest)
+			if err != nil {
+				ok = false
+				break
+			}
+			entries = append(entries, memEntry{key: key, value: value, deleted: deleted})
+			rest = rest[size:]
+		}
+		if !ok {
+			break
+		}
+		for _, e := range entries {
+			mem.put(e)
+		}
+		mem.lastSeq = max(mem.lastSeq, lastSeq)
+		offset += int64(len(header) + len(payload))
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > offset {
+		return os.Truncate(path, offset)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package operators
+
+import "nosql_db/internal/geo"
+
+// GeoWithin возвращает true, если точка из fieldValue лежит внутри фигуры $geoWithin
+func GeoWithin(fieldValue, shapeSpec any) bool {
+	point, ok := geo.ParsePoint(fieldValue)
+	if !ok {
+		return false
+	}
+	shape, err := geo.ParseShape(shapeSpec)
+	if err != nil {
+		return false
+	}
+	return shape.Contains(point)
+}
+
+// GeoNear проверяет ограничение $maxDistance условия $near;
+// сортировку по расстоянию выполняет планировщик запроса
+func GeoNear(fieldValue, nearSpec any) bool {
+	point, ok := geo.ParsePoint(fieldValue)
+	if !ok {
+		return false
+	}
+	near, err := geo.ParseNear(nearSpec)
+	if err != nil {
+		return false
+	}
+	return near.Matches(point)
+}
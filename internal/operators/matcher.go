@@ -62,6 +62,10 @@ func applyOperator(fieldValue any, operator string, queryValue any) bool {
 		return CompareLike(fieldValue, queryValue)
 	case "$in":
 		return CompareIn(fieldValue, queryValue)
+	case "$geoWithin":
+		return GeoWithin(fieldValue, queryValue)
+	case "$near":
+		return GeoNear(fieldValue, queryValue)
 	default:
 		fmt.Printf("Warning: unknown operator %s\n", operator)
 		return false
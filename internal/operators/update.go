@@ -0,0 +1,57 @@
+package operators
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ApplyUpdate возвращает копию документа с применёнными операторами обновления:
+// {"$set": {...}, "$unset": {...}, "$inc": {...}}. Поле _id изменять нельзя
+func ApplyUpdate(doc map[string]any, update map[string]any) (map[string]any, error) {
+	result := make(map[string]any, len(doc))
+	for field, value := range doc {
+		result[field] = value
+	}
+
+	ops := make([]string, 0, len(update))
+	for op := range update {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	for _, op := range ops {
+		fields, ok := update[op].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s requires an object", op)
+		}
+		for field, value := range fields {
+			if field == "_id" {
+				return nil, fmt.Errorf("field _id cannot be modified")
+			}
+			switch op {
+			case "$set":
+				result[field] = value
+			case "$unset":
+				delete(result, field)
+			case "$inc":
+				delta, ok := value.(float64)
+				if !ok {
+					return nil, fmt.Errorf("$inc value for '%s' must be a number", field)
+				}
+				current, exists := result[field]
+				if !exists {
+					result[field] = delta
+					continue
+				}
+				number, ok := current.(float64)
+				if !ok {
+					return nil, fmt.Errorf("cannot $inc non-numeric field '%s'", field)
+				}
+				result[field] = number + delta
+			default:
+				return nil, fmt.Errorf("unknown update operator: %s", op)
+			}
+		}
+	}
+	return result, nil
+}
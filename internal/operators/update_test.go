@@ -0,0 +1,38 @@
+package operators
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyUpdate(t *testing.T) {
+	doc := map[string]any{"_id": "a", "name": "ann", "age": 30.0, "tmp": true}
+	updated, err := ApplyUpdate(doc, map[string]any{
+		"$set":   map[string]any{"name": "Ann", "city": "Kazan"},
+		"$unset": map[string]any{"tmp": ""},
+		"$inc":   map[string]any{"age": 1.0, "visits": 2.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"_id": "a", "name": "Ann", "age": 31.0, "city": "Kazan", "visits": 2.0}
+	if !reflect.DeepEqual(updated, want) {
+		t.Fatalf("updated: %v", updated)
+	}
+	// исходный документ не меняется
+	if doc["name"] != "ann" || doc["tmp"] != true {
+		t.Fatalf("original document was modified: %v", doc)
+	}
+
+	for _, update := range []map[string]any{
+		{"$set": map[string]any{"_id": "b"}},
+		{"$inc": map[string]any{"name": 1.0}},
+		{"$inc": map[string]any{"age": "1"}},
+		{"$set": "name"},
+		{"$push": map[string]any{"tags": "x"}},
+	} {
+		if _, err := ApplyUpdate(doc, update); err == nil {
+			t.Errorf("%v must be rejected", update)
+		}
+	}
+}
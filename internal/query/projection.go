@@ -0,0 +1,98 @@
+package query
+
+// ApplyProjection возвращает новый документ только с полями из projection.
+// Режим включения: {"name": 1}; режим исключения: {"age": 0}.
+// Поле _id возвращается всегда, если явно не указано {"_id": 0}
+func ApplyProjection(doc map[string]any, projection map[string]any) map[string]any {
+	if len(projection) == 0 {
+		return doc
+	}
+
+	result := make(map[string]any)
+	if IsInclusionProjection(projection) {
+		for field, flag := range projection {
+			if !projectionFlag(flag) {
+				continue
+			}
+			if value, ok := doc[field]; ok {
+				result[field] = value
+			}
+		}
+		if id, ok := doc["_id"]; ok && IncludesID(projection) {
+			result["_id"] = id
+		}
+		return result
+	}
+
+	for field, value := range doc {
+		if flag, set := projection[field]; set && !projectionFlag(flag) {
+			if _, meta := flag.(map[string]any); !meta {
+				continue
+			}
+		}
+		result[field] = value
+	}
+	return result
+}
+
+// IsInclusionProjection — true, если проекция перечисляет включаемые поля
+func IsInclusionProjection(projection map[string]any) bool {
+	for field, flag := range projection {
+		if field != "_id" && projectionFlag(flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// IncludesID — true, если проекция оставляет поле _id
+func IncludesID(projection map[string]any) bool {
+	flag, set := projection["_id"]
+	return !set || projectionFlag(flag)
+}
+
+// ProjectedFields возвращает поля, которые попадут в результат при проекции-включении
+func ProjectedFields(projection map[string]any) []string {
+	var fields []string
+	for field, flag := range projection {
+		if field != "_id" && projectionFlag(flag) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// MetaFields возвращает поля проекции вида {"score": {"$meta": "textScore"}}: имя поля -> вид метаданных
+func MetaFields(projection map[string]any) map[string]string {
+	var meta map[string]string
+	for field, flag := range projection {
+		spec, ok := flag.(map[string]any)
+		if !ok {
+			continue
+		}
+		if kind, ok := spec["$meta"].(string); ok {
+			if meta == nil {
+				meta = make(map[string]string)
+			}
+			meta[field] = kind
+		}
+	}
+	return meta
+}
+
+// projectionFlag трактует значение проекции: 1/true — включить, 0/false — исключить;
+// поля $meta не включают и не исключают поля документа
+func projectionFlag(flag any) bool {
+	switch v := flag.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case int:
+		return v != 0
+	case map[string]any:
+		return false
+	default:
+		return flag != nil
+	}
+}
@@ -14,4 +14,17 @@ const (
 	OpIn   Operator = "$in"
 	OpAnd  Operator = "$and"
 	OpOr   Operator = "$or"
+	OpText Operator = "$text"
+
+	OpNear      Operator = "$near"
+	OpGeoWithin Operator = "$geoWithin"
+
+	OpVectorSearch Operator = "$vectorSearch"
+)
+
+// виды {"$meta": ...} в проекции
+const (
+	MetaTextScore   = "textScore"         // релевантность запроса $text
+	MetaGeoDistance = "geoNearDistance"   // расстояние в метрах до точки $near
+	MetaVectorScore = "vectorSearchScore" // близость вектора в $vectorSearch
 )
@@ -0,0 +1,219 @@
+package raft
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// файлы FileStorage
+const (
+	stateFile    = "state.json"
+	logFile      = "log.jsonl"
+	snapshotFile = "snapshot.json"
+)
+
+// FileStorage хранит состояние узла в каталоге: срок и голос в state.json,
+// журнал после снимка в log.jsonl (одна запись в строке), снимок в snapshot.json.
+// Запись в журнал дописывается с fsync; усечение и снимок заменяют файлы атомарно
+type FileStorage struct {
+	dir string
+
+	mu      sync.Mutex
+	log     *os.File
+	entries []Entry // копия журнала для перезаписи при усечении
+}
+
+// NewFileStorage открывает (или создаёт) каталог хранилища
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("mkdir error: %w", err)
+	}
+	return &FileStorage{dir: dir}, nil
+}
+
+func (s *FileStorage) Load() (HardState, *Snapshot, []Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var state HardState
+	if err := readJSON(filepath.Join(s.dir, stateFile), &state); err != nil {
+		return state, nil, nil, err
+	}
+	var snap *Snapshot
+	var stored Snapshot
+	switch err := readJSON(filepath.Join(s.dir, snapshotFile), &stored); {
+	case err != nil:
+		return state, nil, nil, err
+	case stored.Index > 0:
+		snap = &stored
+	}
+
+	raw, err := os.ReadFile(filepath.Join(s.dir, logFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return state, nil, nil, fmt.Errorf("read raft log: %w", err)
+	}
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			// недописанная последняя строка после сбоя: запись не была подтверждена
+			break
+		}
+		if snap != nil && entry.Index <= snap.Index {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	// журнал перезаписывается без недописанного хвоста
+	if err := s.rewriteLocked(entries); err != nil {
+		return state, nil, nil, err
+	}
+	return state, snap, slices.Clone(entries), nil
+}
+
+func (s *FileStorage) SaveState(state HardState) error {
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal raft state: %w", err)
+	}
+	return s.replace(stateFile, raw)
+}
+
+func (s *FileStorage) Append(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		if err := s.rewriteLocked(s.entries); err != nil {
+			return err
+		}
+	}
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("marshal raft entry: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if _, err := s.log.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("write raft log: %w", err)
+	}
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("sync raft log: %w", err)
+	}
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *FileStorage) Truncate(index uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rewriteLocked(truncateEntries(s.entries, index))
+}
+
+func (s *FileStorage) SaveSnapshot(snap Snapshot, entries []Entry) error {
+	raw, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("marshal raft snapshot: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// снимок пишется раньше журнала: после сбоя между ними Load отбросит записи, вошедшие в снимок
+	if err := s.replace(snapshotFile, raw); err != nil {
+		return err
+	}
+	return s.rewriteLocked(slices.Clone(entries))
+}
+
+// Close закрывает файл журнала
+func (s *FileStorage) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.log == nil {
+		return nil
+	}
+	err := s.log.Close()
+	s.log = nil
+	return err
+}
+
+// rewriteLocked атомарно заменяет журнал записями entries и открывает его для дозаписи
+func (s *FileStorage) rewriteLocked(entries []Entry) error {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("marshal raft entry: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if s.log != nil {
+		s.log.Close()
+		s.log = nil
+	}
+	if err := s.replace(logFile, buf.Bytes()); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, logFile), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("open raft log: %w", err)
+	}
+	s.log, s.entries = f, entries
+	return nil
+}
+
+// replace атомарно заменяет файл каталога: запись во временный файл, fsync, rename
+func (s *FileStorage) replace(name string, data []byte) error {
+	tmp := filepath.Join(s.dir, name+".tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("sync %s: %w", name, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		return fmt.Errorf("rename %s: %w", name, err)
+	}
+	dir, err := os.Open(s.dir)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+	return dir.Sync()
+}
+
+// readJSON читает файл в v; отсутствующий файл оставляет v нулевым
+func readJSON(path string, v any) error {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", filepath.Base(path), err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("decode %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package raft
+
+import (
+	"slices"
+	"sync"
+)
+
+// типы записей журнала
+const (
+	EntryCommand = "command" // команда машины состояний
+	EntryConfig  = "config"  // новый состав кластера: Members
+	EntryNoop    = "noop"    // пустая запись нового лидера: фиксирует записи прошлых сроков
+)
+
+// Entry — запись журнала Raft
+type Entry struct {
+	Index   uint64   `json:"index"`
+	Term    uint64   `json:"term"`
+	Type    string   `json:"type"`
+	Data    []byte   `json:"data,omitempty"`
+	Members []string `json:"members,omitempty"`
+}
+
+// Snapshot — состояние машины после записи Index; записи до неё из журнала удалены
+type Snapshot struct {
+	Index   uint64   `json:"index"`
+	Term    uint64   `json:"term"`
+	Members []string `json:"members"`
+	Data    []byte   `json:"data,omitempty"`
+}
+
+// HardState — срок и голос узла: сохраняются до ответа на любое сообщение
+type HardState struct {
+	Term uint64 `json:"term"`
+	Vote string `json:"vote,omitempty"`
+}
+
+// Storage хранит состояние узла между перезапусками. Все методы вызываются
+// под мьютексом узла и должны вернуться только после надёжной записи
+type Storage interface {
+	// Load возвращает сохранённое состояние; у нового узла — нулевые значения
+	Load() (HardState, *Snapshot, []Entry, error)
+	SaveState(state HardState) error
+	// Append дописывает записи в конец журнала
+	Append(entries []Entry) error
+	// Truncate удаляет записи начиная с index
+	Truncate(index uint64) error
+	// SaveSnapshot сохраняет снимок и заменяет журнал записями после него
+	SaveSnapshot(snap Snapshot, entries []Entry) error
+}
+
+// MemoryStorage — хранилище в памяти для тестов: переживает перезапуск Node, но не процесса
+type MemoryStorage struct {
+	mu      sync.Mutex
+	state   HardState
+	snap    *Snapshot
+	entries []Entry
+}
+
+// NewMemoryStorage создаёт пустое хранилище в памяти
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{}
+}
+
+func (s *MemoryStorage) Load() (HardState, *Snapshot, []Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state, s.snap, slices.Clone(s.entries), nil
+}
+
+func (s *MemoryStorage) SaveState(state HardState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+	return nil
+}
+
+func (s *MemoryStorage) Append(entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, entries...)
+	return nil
+}
+
+func (s *MemoryStorage) Truncate(index uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = truncateEntries(s.entries, index)
+	return nil
+}
+
+func (s *MemoryStorage) SaveSnapshot(snap Snapshot, entries []Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snap = &snap
+	s.entries = slices.Clone(entries)
+	return nil
+}
+
+// truncateEntries оставляет записи с индексом меньше index
+func truncateEntries(entries []Entry, index uint64) []Entry {
+	for i, entry := range entries {
+		if entry.Index >= index {
+			return entries[:i]
+		}
+	}
+	return entries
+}
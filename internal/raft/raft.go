@@ -0,0 +1,822 @@
+package raft
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Реализация Raft: выбор лидера, репликация журнала, сжатие журнала снимками
+// и изменение состава кластера по одному узлу. Узел — конечный автомат под одним
+// мьютексом: входящие сообщения (Step), таймер и предложения клиентов меняют его
+// состояние; зафиксированные записи применяет к машине состояний отдельная горутина
+
+// роли узла
+const (
+	RoleFollower  = "follower"
+	RoleCandidate = "candidate"
+	RoleLeader    = "leader"
+)
+
+var (
+	ErrNotLeader      = errors.New("not the leader")
+	ErrLeadershipLost = errors.New("leadership lost before the entry was committed")
+	ErrTimeout        = errors.New("entry was not committed in time")
+	ErrStopped        = errors.New("raft node stopped")
+	ErrConfigPending  = errors.New("another membership change is in progress")
+)
+
+// StateMachine — состояние, которое кластер реплицирует журналом.
+// Apply вызывается для записей EntryCommand по порядку на каждом узле и должен
+// быть детерминированным; результат получает предложивший запись лидер
+type StateMachine interface {
+	Apply(entry Entry) any
+	// Snapshot возвращает состояние после последней применённой записи
+	Snapshot() ([]byte, error)
+	// Restore заменяет состояние снимком; nil — пустое состояние
+	Restore(data []byte) error
+}
+
+// Config — параметры узла; нулевые интервалы заменяются значениями по умолчанию
+type Config struct {
+	ID   string // адрес узла в транспорте
+	Addr string // адрес узла для клиентов: последователи называют его как адрес лидера
+	// начальный состав кластера, включая ID; одинаковый у всех начальных узлов.
+	// Пусто — узел ждёт, пока лидер добавит его (ChangeMembers)
+	Peers []string
+
+	ElectionTimeout   time.Duration // без сообщений лидера дольше [t, 2t) начинаются выборы
+	HeartbeatInterval time.Duration
+	SnapshotThreshold uint64        // применённых записей после снимка, после которых журнал сжимается
+	ProposeTimeout    time.Duration // сколько Propose ждёт фиксации
+	MaxBatch          int           // записей в одном MsgAppend
+
+	Storage      Storage
+	Transport    Transport
+	StateMachine StateMachine
+}
+
+func (c *Config) setDefaults() {
+	if c.ElectionTimeout == 0 {
+		c.ElectionTimeout = time.Second
+	}
+	if c.HeartbeatInterval == 0 {
+		c.HeartbeatInterval = c.ElectionTimeout / 10
+	}
+	if c.SnapshotThreshold == 0 {
+		c.SnapshotThreshold = 10000
+	}
+	if c.ProposeTimeout == 0 {
+		c.ProposeTimeout = 5 * time.Second
+	}
+	if c.MaxBatch == 0 {
+		c.MaxBatch = 256
+	}
+}
+
+// result — исход записи для ожидающего Propose
+type result struct {
+	value any
+	err   error
+}
+
+type waiter struct {
+	term uint64
+	ch   chan result
+}
+
+// Node — узел Raft
+type Node struct {
+	cfg Config
+
+	mu         sync.Mutex
+	role       string
+	term       uint64
+	vote       string
+	leader     string // id известного лидера срока
+	leaderAddr string // его адрес для клиентов
+	// последнее сообщение лидера
+	leaderContact time.Time
+	entries       []Entry
+	snap          Snapshot // последний снимок: записи до snap.Index включительно удалены из entries
+	members       []string // текущий состав: последняя запись EntryConfig журнала или снимок
+	configIdx     uint64   // индекс записи, задавшей members (0 — снимок или начальный состав)
+	commit        uint64
+	applied       uint64
+
+	// состояние лидера
+	next    map[string]uint64
+	match   map[string]uint64
+	lastAck map[string]time.Time
+	since   time.Time // начало срока лидера
+	votes   map[string]bool
+
+	electionDeadline time.Time
+	heartbeatDue     time.Time
+	waiters          map[uint64]waiter
+	applyCond        *sync.Cond
+	stopped          bool
+	stop             chan struct{}
+	wg               sync.WaitGroup
+	rand             *rand.Rand
+}
+
+// NewNode создаёт узел и восстанавливает машину состояний из сохранённого снимка;
+// записи журнала после снимка применяются заново, когда узел узнает индекс фиксации
+func NewNode(cfg Config) (*Node, error) {
+	cfg.setDefaults()
+	state, snap, entries, err := cfg.Storage.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load raft state: %w", err)
+	}
+	n := &Node{
+		cfg:     cfg,
+		role:    RoleFollower,
+		term:    state.Term,
+		vote:    state.Vote,
+		entries: entries,
+		waiters: make(map[uint64]waiter),
+		stop:    make(chan struct{}),
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	n.applyCond = sync.NewCond(&n.mu)
+	var data []byte
+	if snap != nil {
+		n.snap = *snap
+		data = snap.Data
+	}
+	if err := cfg.StateMachine.Restore(data); err != nil {
+		return nil, fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	n.commit, n.applied = n.snap.Index, n.snap.Index
+	n.updateMembersLocked()
+	n.resetElectionTimerLocked()
+	return n, nil
+}
+
+// Start запускает таймер выборов и heartbeat и применение записей
+func (n *Node) Start() {
+	n.wg.Add(2)
+	go n.ticker()
+	go n.applier()
+}
+
+// Stop останавливает узел; ожидающие Propose получают ErrStopped
+func (n *Node) Stop() {
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return
+	}
+	n.stopped = true
+	n.failWaitersLocked(0, ErrStopped)
+	close(n.stop)
+	n.applyCond.Broadcast()
+	n.mu.Unlock()
+	n.wg.Wait()
+}
+
+// ID возвращает адрес узла в транспорте
+func (n *Node) ID() string {
+	return n.cfg.ID
+}
+
+// Leader возвращает id и адрес для клиентов известного лидера; пусто — лидер неизвестен
+func (n *Node) Leader() (id, addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.leader, n.leaderAddr
+}
+
+// IsLeader — узел лидер своего срока
+func (n *Node) IsLeader() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.role == RoleLeader
+}
+
+// Members возвращает текущий состав кластера
+func (n *Node) Members() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return slices.Clone(n.members)
+}
+
+// Status возвращает состояние узла для repl_status
+func (n *Node) Status() map[string]any {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	status := map[string]any{
+		"id":            n.cfg.ID,
+		"role":          n.role,
+		"term":          n.term,
+		"leader":        n.leader,
+		"leaderAddr":    n.leaderAddr,
+		"members":       slices.Clone(n.members),
+		"commitIndex":   n.commit,
+		"appliedIndex":  n.applied,
+		"lastIndex":     n.lastIndexLocked(),
+		"snapshotIndex": n.snap.Index,
+	}
+	if n.role == RoleLeader {
+		match := make(map[string]uint64, len(n.members))
+		for _, id := range n.members {
+			match[id] = n.match[id]
+		}
+		status["match"] = match
+	}
+	return status
+}
+
+// Propose добавляет команду в журнал лидера и ждёт, пока она будет зафиксирована
+// и применена; возвращает результат StateMachine.Apply
+func (n *Node) Propose(data []byte) (any, error) {
+	return n.propose(Entry{Type: EntryCommand, Data: data})
+}
+
+// ChangeMembers добавляет (add) или удаляет узел id. Состав меняется по одному узлу:
+// новая конфигурация действует с момента записи в журнал, следующая — после её фиксации
+func (n *Node) ChangeMembers(id string, add bool) error {
+	n.mu.Lock()
+	if n.role != RoleLeader {
+		n.mu.Unlock()
+		return ErrNotLeader
+	}
+	if n.configIdx > n.commit {
+		n.mu.Unlock()
+		return ErrConfigPending
+	}
+	members := slices.Clone(n.members)
+	switch has := slices.Contains(members, id); {
+	case add && has:
+		n.mu.Unlock()
+		return fmt.Errorf("node '%s' is already a member", id)
+	case !add && !has:
+		n.mu.Unlock()
+		return fmt.Errorf("node '%s' is not a member", id)
+	case add:
+		members = append(members, id)
+		n.next[id], n.match[id] = n.lastIndexLocked()+1, 0
+		n.lastAck[id] = time.Now()
+	default:
+		members = slices.DeleteFunc(members, func(m string) bool { return m == id })
+	}
+	slices.Sort(members)
+	n.mu.Unlock()
+
+	_, err := n.propose(Entry{Type: EntryConfig, Members: members})
+	return err
+}
+
+func (n *Node) propose(entry Entry) (any, error) {
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return nil, ErrStopped
+	}
+	if n.role != RoleLeader {
+		n.mu.Unlock()
+		return nil, ErrNotLeader
+	}
+	entry.Index, entry.Term = n.lastIndexLocked()+1, n.term
+	n.appendLocked([]Entry{entry})
+	ch := make(chan result, 1)
+	n.waiters[entry.Index] = waiter{term: n.term, ch: ch}
+	n.broadcastAppendLocked()
+	n.maybeCommitLocked()
+	n.mu.Unlock()
+
+	timer := time.NewTimer(n.cfg.ProposeTimeout)
+	defer timer.Stop()
+	select {
+	case res := <-ch:
+		return res.value, res.err
+	case <-timer.C:
+		n.mu.Lock()
+		delete(n.waiters, entry.Index)
+		n.mu.Unlock()
+		return nil, ErrTimeout
+	}
+}
+
+// Step обрабатывает входящее сообщение; вызывается транспортом
+func (n *Node) Step(msg Message) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.stopped {
+		return
+	}
+
+	if msg.Type == MsgVote && msg.Term > n.term && n.leaderAliveLocked() {
+		// узел, отрезанный от кластера или уже удалённый из него, не сбивает работающего лидера
+		return
+	}
+	if msg.Term > n.term {
+		leader := ""
+		if msg.Type == MsgAppend || msg.Type == MsgSnapshot {
+			leader = msg.From
+		}
+		n.becomeFollowerLocked(msg.Term, leader)
+	}
+	if msg.Term < n.term {
+		// запрос устаревшего срока получает отказ с текущим сроком: отправитель отстанет
+		switch msg.Type {
+		case MsgVote:
+			n.send(Message{Type: MsgVoteResp, To: msg.From})
+		case MsgAppend, MsgSnapshot:
+			n.send(Message{Type: MsgAppendResp, To: msg.From, Match: n.lastIndexLocked()})
+		}
+		return
+	}
+
+	switch msg.Type {
+	case MsgVote:
+		n.handleVoteLocked(msg)
+	case MsgVoteResp:
+		n.handleVoteRespLocked(msg)
+	case MsgAppend:
+		n.handleAppendLocked(msg)
+	case MsgAppendResp, MsgSnapshotResp:
+		n.handleAppendRespLocked(msg)
+	case MsgSnapshot:
+		n.handleSnapshotLocked(msg)
+	}
+}
+
+// leaderAliveLocked — узел сам лидер или слышал лидера меньше ElectionTimeout назад
+func (n *Node) leaderAliveLocked() bool {
+	return n.role == RoleLeader || (n.leader != "" && time.Since(n.leaderContact) < n.cfg.ElectionTimeout)
+}
+
+func (n *Node) send(msg Message) {
+	msg.From, msg.Term = n.cfg.ID, n.term
+	n.cfg.Transport.Send(msg)
+}
+
+func (n *Node) ticker() {
+	defer n.wg.Done()
+	ticker := time.NewTicker(n.cfg.HeartbeatInterval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-n.stop:
+			return
+		case <-ticker.C:
+			n.tick()
+		}
+	}
+}
+
+func (n *Node) tick() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	now := time.Now()
+	if n.role == RoleLeader {
+		if !n.hasQuorumLocked(now) {
+			// лидер, отрезанный от большинства, уступает: клиенты уйдут к новому лидеру
+			log.Printf("raft %s: lost contact with the majority, stepping down", n.cfg.ID)
+			n.becomeFollowerLocked(n.term, "")
+			return
+		}
+		if now.After(n.heartbeatDue) {
+			n.broadcastAppendLocked()
+		}
+		return
+	}
+	if now.After(n.electionDeadline) && slices.Contains(n.members, n.cfg.ID) {
+		n.campaignLocked()
+	}
+}
+
+// hasQuorumLocked — большинство узлов отвечало лидеру за последний ElectionTimeout
+func (n *Node) hasQuorumLocked(now time.Time) bool {
+	if now.Sub(n.since) < n.cfg.ElectionTimeout {
+		return true
+	}
+	alive := 0
+	for _, id := range n.members {
+		if id == n.cfg.ID || now.Sub(n.lastAck[id]) < n.cfg.ElectionTimeout {
+			alive++
+		}
+	}
+	return alive >= n.quorumLocked()
+}
+
+func (n *Node) quorumLocked() int {
+	return len(n.members)/2 + 1
+}
+
+func (n *Node) resetElectionTimerLocked() {
+	timeout := n.cfg.ElectionTimeout + time.Duration(n.rand.Int63n(int64(n.cfg.ElectionTimeout)))
+	n.electionDeadline = time.Now().Add(timeout)
+}
+
+func (n *Node) persistLocked() {
+	if err := n.cfg.Storage.SaveState(HardState{Term: n.term, Vote: n.vote}); err != nil {
+		log.Panicf("raft %s: failed to save state: %v", n.cfg.ID, err)
+	}
+}
+
+func (n *Node) campaignLocked() {
+	n.role = RoleCandidate
+	n.term++
+	n.vote = n.cfg.ID
+	n.leader, n.leaderAddr = "", ""
+	n.persistLocked()
+	n.votes = map[string]bool{n.cfg.ID: true}
+	n.resetElectionTimerLocked()
+	if len(n.votes) >= n.quorumLocked() {
+		n.becomeLeaderLocked()
+		return
+	}
+	for _, id := range n.members {
+		if id != n.cfg.ID {
+			n.send(Message{Type: MsgVote, To: id, LastIndex: n.lastIndexLocked(), LastTerm: n.lastTermLocked()})
+		}
+	}
+}
+
+func (n *Node) becomeFollowerLocked(term uint64, leader string) {
+	if term > n.term {
+		n.term, n.vote = term, ""
+		n.persistLocked()
+	}
+	if n.role == RoleLeader {
+		// зафиксированные записи ещё применятся: их ожидающие получат результат
+		n.failWaitersLocked(n.commit+1, ErrLeadershipLost)
+	}
+	n.role = RoleFollower
+	n.leader = leader
+	if leader == "" {
+		n.leaderAddr = ""
+	}
+	n.resetElectionTimerLocked()
+}
+
+func (n *Node) becomeLeaderLocked() {
+	log.Printf("raft %s: became leader for term %d", n.cfg.ID, n.term)
+	n.role = RoleLeader
+	n.leader, n.leaderAddr = n.cfg.ID, n.cfg.Addr
+	n.since = time.Now()
+	n.next = make(map[string]uint64)
+	n.match = make(map[string]uint64)
+	n.lastAck = make(map[string]time.Time)
+	for _, id := range n.members {
+		n.next[id] = n.lastIndexLocked() + 1
+		n.lastAck[id] = n.since
+	}
+	// пустая запись своего срока фиксирует записи предыдущих лидеров
+	n.appendLocked([]Entry{{Index: n.lastIndexLocked() + 1, Term: n.term, Type: EntryNoop}})
+	n.broadcastAppendLocked()
+	n.maybeCommitLocked()
+}
+
+func (n *Node) handleVoteLocked(msg Message) {
+	upToDate := msg.LastTerm > n.lastTermLocked() ||
+		(msg.LastTerm == n.lastTermLocked() && msg.LastIndex >= n.lastIndexLocked())
+	granted := (n.vote == "" || n.vote == msg.From) && upToDate
+	if granted {
+		n.vote = msg.From
+		n.persistLocked()
+		n.resetElectionTimerLocked()
+	}
+	n.send(Message{Type: MsgVoteResp, To: msg.From, Granted: granted})
+}
+
+func (n *Node) handleVoteRespLocked(msg Message) {
+	if n.role != RoleCandidate || !msg.Granted {
+		return
+	}
+	n.votes[msg.From] = true
+	granted := 0
+	for _, id := range n.members {
+		if n.votes[id] {
+			granted++
+		}
+	}
+	if granted >= n.quorumLocked() {
+		n.becomeLeaderLocked()
+	}
+}
+
+func (n *Node) handleAppendLocked(msg Message) {
+	n.role = RoleFollower
+	n.leader, n.leaderAddr, n.leaderContact = msg.From, msg.LeaderAddr, time.Now()
+	n.resetElectionTimerLocked()
+
+	prevIndex, prevTerm, entries := msg.PrevIndex, msg.PrevTerm, msg.Entries
+	lastNew := prevIndex + uint64(len(entries))
+	if prevIndex < n.snap.Index {
+		// начало уже вошло в снимок: зафиксированные записи совпадают с лидером
+		skip := min(n.snap.Index-prevIndex, uint64(len(entries)))
+		entries = entries[skip:]
+		prevIndex, prevTerm = n.snap.Index, n.snap.Term
+		if len(entries) == 0 {
+			n.send(Message{Type: MsgAppendResp, To: msg.From, Success: true, Match: max(lastNew, n.snap.Index)})
+			return
+		}
+	}
+	if prevIndex > n.lastIndexLocked() {
+		n.send(Message{Type: MsgAppendResp, To: msg.From, Match: n.lastIndexLocked()})
+		return
+	}
+	if term := n.termAtLocked(prevIndex); term != prevTerm {
+		// записи конфликтующего срока пропускаются целиком
+		hint := prevIndex - 1
+		for hint > n.snap.Index && n.termAtLocked(hint) == term {
+			hint--
+		}
+		n.send(Message{Type: MsgAppendResp, To: msg.From, Match: hint})
+		return
+	}
+
+	for i, entry := range entries {
+		if entry.Index <= n.lastIndexLocked() {
+			if n.termAtLocked(entry.Index) == entry.Term {
+				continue
+			}
+			n.truncateLocked(entry.Index)
+		}
+		n.appendLocked(entries[i:])
+		break
+	}
+	if commit := min(msg.Commit, lastNew); commit > n.commit {
+		n.commit = commit
+		n.applyCond.Broadcast()
+	}
+	n.send(Message{Type: MsgAppendResp, To: msg.From, Success: true, Match: lastNew})
+}
+
+func (n *Node) handleAppendRespLocked(msg Message) {
+	if n.role != RoleLeader {
+		return
+	}
+	n.lastAck[msg.From] = time.Now()
+	if msg.Success || msg.Type == MsgSnapshotResp {
+		if msg.Match > n.match[msg.From] {
+			n.match[msg.From] = msg.Match
+		}
+		n.next[msg.From] = max(n.next[msg.From], n.match[msg.From]+1)
+		n.maybeCommitLocked()
+		if n.role == RoleLeader && n.next[msg.From] <= n.lastIndexLocked() {
+			n.sendAppendLocked(msg.From)
+		}
+		return
+	}
+	n.next[msg.From] = max(n.match[msg.From]+1, min(msg.Match+1, n.lastIndexLocked()+1))
+	n.sendAppendLocked(msg.From)
+}
+
+func (n *Node) handleSnapshotLocked(msg Message) {
+	n.role = RoleFollower
+	n.leader, n.leaderAddr, n.leaderContact = msg.From, msg.LeaderAddr, time.Now()
+	n.resetElectionTimerLocked()
+
+	snap := msg.Snapshot
+	if snap == nil {
+		return
+	}
+	if snap.Index > n.commit {
+		var rest []Entry
+		if n.termAtLocked(snap.Index) == snap.Term {
+			for _, entry := range n.entries {
+				if entry.Index > snap.Index {
+					rest = append(rest, entry)
+				}
+			}
+		}
+		n.snap, n.entries = *snap, rest
+		if err := n.cfg.Storage.SaveSnapshot(n.snap, n.entries); err != nil {
+			log.Panicf("raft %s: failed to save snapshot: %v", n.cfg.ID, err)
+		}
+		n.updateMembersLocked()
+		n.commit = snap.Index
+		n.applyCond.Broadcast()
+		log.Printf("raft %s: installed snapshot at index %d from %s", n.cfg.ID, snap.Index, msg.From)
+	}
+	n.send(Message{Type: MsgSnapshotResp, To: msg.From, Success: true, Match: snap.Index})
+}
+
+func (n *Node) broadcastAppendLocked() {
+	for _, id := range n.members {
+		if id != n.cfg.ID {
+			n.sendAppendLocked(id)
+		}
+	}
+	n.heartbeatDue = time.Now().Add(n.cfg.HeartbeatInterval)
+}
+
+// sendAppendLocked отправляет узлу записи начиная с next[to] или снимок,
+// если нужные записи уже удалены из журнала
+func (n *Node) sendAppendLocked(to string) {
+	next, ok := n.next[to]
+	if !ok {
+		next = n.lastIndexLocked() + 1
+		n.next[to] = next
+	}
+	if next <= n.snap.Index {
+		snap := n.snap
+		n.send(Message{Type: MsgSnapshot, To: to, Snapshot: &snap, LeaderAddr: n.cfg.Addr})
+		return
+	}
+	prev := next - 1
+	var entries []Entry
+	if last := n.lastIndexLocked(); next <= last {
+		start := int(next - n.snap.Index - 1)
+		end := min(start+n.cfg.MaxBatch, len(n.entries))
+		entries = slices.Clone(n.entries[start:end])
+	}
+	n.send(Message{
+		Type: MsgAppend, To: to, PrevIndex: prev, PrevTerm: n.termAtLocked(prev),
+		Entries: entries, Commit: n.commit, LeaderAddr: n.cfg.Addr,
+	})
+}
+
+// maybeCommitLocked фиксирует последнюю запись своего срока, которая есть у большинства
+func (n *Node) maybeCommitLocked() {
+	n.match[n.cfg.ID] = n.lastIndexLocked()
+	for index := n.lastIndexLocked(); index > n.commit; index-- {
+		if n.termAtLocked(index) != n.term {
+			break
+		}
+		replicated := 0
+		for _, id := range n.members {
+			if n.match[id] >= index {
+				replicated++
+			}
+		}
+		if replicated < n.quorumLocked() {
+			continue
+		}
+		n.commit = index
+		n.applyCond.Broadcast()
+		n.broadcastAppendLocked()
+		if !slices.Contains(n.members, n.cfg.ID) && n.configIdx <= n.commit {
+			// лидер удалил себя из кластера: после фиксации он уступает
+			log.Printf("raft %s: removed from the cluster, stepping down", n.cfg.ID)
+			n.becomeFollowerLocked(n.term, "")
+		}
+		return
+	}
+}
+
+// appendLocked дописывает записи в журнал и хранилище
+func (n *Node) appendLocked(entries []Entry) {
+	if err := n.cfg.Storage.Append(entries); err != nil {
+		log.Panicf("raft %s: failed to append entries: %v", n.cfg.ID, err)
+	}
+	n.entries = append(n.entries, entries...)
+	for _, entry := range entries {
+		if entry.Type == EntryConfig {
+			n.updateMembersLocked()
+			break
+		}
+	}
+}
+
+// truncateLocked удаляет незафиксированные записи начиная с index
+func (n *Node) truncateLocked(index uint64) {
+	if err := n.cfg.Storage.Truncate(index); err != nil {
+		log.Panicf("raft %s: failed to truncate log: %v", n.cfg.ID, err)
+	}
+	n.entries = truncateEntries(n.entries, index)
+	n.failWaitersLocked(index, ErrLeadershipLost)
+	n.updateMembersLocked()
+}
+
+// failWaitersLocked завершает ожидания записей с индексом не меньше from
+func (n *Node) failWaitersLocked(from uint64, err error) {
+	for index, w := range n.waiters {
+		if index >= from {
+			w.ch <- result{err: err}
+			delete(n.waiters, index)
+		}
+	}
+}
+
+// updateMembersLocked вычисляет состав по последней записи EntryConfig
+func (n *Node) updateMembersLocked() {
+	n.members, n.configIdx = n.membersAtLocked(n.lastIndexLocked())
+}
+
+// membersAtLocked возвращает состав кластера после записи index и индекс задавшей его записи
+func (n *Node) membersAtLocked(index uint64) ([]string, uint64) {
+	members, configIdx := n.snap.Members, uint64(0)
+	if n.snap.Index == 0 && members == nil {
+		members = n.cfg.Peers
+	}
+	for _, entry := range n.entries {
+		if entry.Index > index {
+			break
+		}
+		if entry.Type == EntryConfig {
+			members, configIdx = entry.Members, entry.Index
+		}
+	}
+	return slices.Clone(members), configIdx
+}
+
+func (n *Node) lastIndexLocked() uint64 {
+	if len(n.entries) > 0 {
+		return n.entries[len(n.entries)-1].Index
+	}
+	return n.snap.Index
+}
+
+func (n *Node) lastTermLocked() uint64 {
+	return n.termAtLocked(n.lastIndexLocked())
+}
+
+// termAtLocked возвращает срок записи; 0 — записи нет в журнале
+func (n *Node) termAtLocked(index uint64) uint64 {
+	if index == n.snap.Index {
+		return n.snap.Term
+	}
+	if index < n.snap.Index || index > n.lastIndexLocked() {
+		return 0
+	}
+	return n.entries[index-n.snap.Index-1].Term
+}
+
+// applier применяет зафиксированные записи по порядку и сжимает журнал снимками
+func (n *Node) applier() {
+	defer n.wg.Done()
+	for {
+		n.mu.Lock()
+		for !n.stopped && n.applied >= n.commit && n.applied >= n.snap.Index {
+			n.applyCond.Wait()
+		}
+		if n.stopped {
+			n.mu.Unlock()
+			return
+		}
+		if n.applied < n.snap.Index {
+			// снимок от лидера заменяет состояние целиком
+			snap := n.snap
+			n.mu.Unlock()
+			if err := n.cfg.StateMachine.Restore(snap.Data); err != nil {
+				log.Panicf("raft %s: failed to restore snapshot: %v", n.cfg.ID, err)
+			}
+			n.mu.Lock()
+			n.applied = max(n.applied, snap.Index)
+			n.mu.Unlock()
+			continue
+		}
+		start := int(n.applied - n.snap.Index)
+		end := int(n.commit - n.snap.Index)
+		batch := slices.Clone(n.entries[start:end])
+		n.mu.Unlock()
+
+		for _, entry := range batch {
+			var value any
+			if entry.Type == EntryCommand {
+				value = n.cfg.StateMachine.Apply(entry)
+			}
+			n.mu.Lock()
+			n.applied = max(n.applied, entry.Index)
+			if w, ok := n.waiters[entry.Index]; ok {
+				delete(n.waiters, entry.Index)
+				if w.term == entry.Term {
+					w.ch <- result{value: value}
+				} else {
+					w.ch <- result{err: ErrLeadershipLost}
+				}
+			}
+			n.mu.Unlock()
+		}
+		n.maybeSnapshot()
+	}
+}
+
+// maybeSnapshot сжимает журнал, когда после снимка применено SnapshotThreshold записей
+func (n *Node) maybeSnapshot() {
+	n.mu.Lock()
+	index := n.applied
+	due := index >= n.snap.Index+n.cfg.SnapshotThreshold
+	n.mu.Unlock()
+	if !due {
+		return
+	}
+	data, err := n.cfg.StateMachine.Snapshot()
+	if err != nil {
+		log.Printf("raft %s: snapshot failed: %v", n.cfg.ID, err)
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if index <= n.snap.Index {
+		return
+	}
+	members, _ := n.membersAtLocked(index)
+	snap := Snapshot{Index: index, Term: n.termAtLocked(index), Members: members, Data: data}
+	rest := slices.Clone(n.entries[index-n.snap.Index:])
+	if err := n.cfg.Storage.SaveSnapshot(snap, rest); err != nil {
+		log.Panicf("raft %s: failed to save snapshot: %v", n.cfg.ID, err)
+	}
+	n.snap, n.entries = snap, rest
+}
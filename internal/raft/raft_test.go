@@ -0,0 +1,349 @@
+package raft
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testMachine — машина состояний теста: список применённых команд
+type testMachine struct {
+	mu       sync.Mutex
+	values   []string
+	restores int
+}
+
+func (m *testMachine) Apply(entry Entry) any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values = append(m.values, string(entry.Data))
+	return len(m.values)
+}
+
+func (m *testMachine) Snapshot() ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return json.Marshal(m.values)
+}
+
+func (m *testMachine) Restore(data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values = nil
+	if data != nil {
+		m.restores++
+		return json.Unmarshal(data, &m.values)
+	}
+	return nil
+}
+
+func (m *testMachine) snapshot() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Clone(m.values)
+}
+
+// testCluster — узлы на сети в памяти
+type testCluster struct {
+	t         *testing.T
+	net       *Network
+	threshold uint64
+	nodes     map[string]*Node
+	machines  map[string]*testMachine
+	storages  map[string]*MemoryStorage
+}
+
+func newCluster(t *testing.T, size int, threshold uint64) *testCluster {
+	c := &testCluster{
+		t:         t,
+		net:       NewNetwork(),
+		threshold: threshold,
+		nodes:     make(map[string]*Node),
+		machines:  make(map[string]*testMachine),
+		storages:  make(map[string]*MemoryStorage),
+	}
+	peers := make([]string, size)
+	for i := range peers {
+		peers[i] = fmt.Sprintf("n%d", i+1)
+	}
+	for _, id := range peers {
+		c.start(id, peers)
+	}
+	t.Cleanup(func() {
+		for _, node := range c.nodes {
+			node.Stop()
+		}
+	})
+	return c
+}
+
+// start запускает узел id; хранилище сохраняется между перезапусками
+func (c *testCluster) start(id string, peers []string) {
+	c.t.Helper()
+	if c.storages[id] == nil {
+		c.storages[id] = NewMemoryStorage()
+	}
+	machine := &testMachine{}
+	node, err := NewNode(Config{
+		ID: id, Addr: "client-" + id, Peers: peers,
+		ElectionTimeout:   100 * time.Millisecond,
+		HeartbeatInterval: 10 * time.Millisecond,
+		SnapshotThreshold: c.threshold,
+		ProposeTimeout:    2 * time.Second,
+		Storage:           c.storages[id],
+		Transport:         c.net,
+		StateMachine:      machine,
+	})
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.nodes[id], c.machines[id] = node, machine
+	c.net.Register(id, node.Step)
+	node.Start()
+}
+
+func (c *testCluster) stop(id string) {
+	c.net.Unregister(id)
+	c.nodes[id].Stop()
+	delete(c.nodes, id)
+}
+
+// waitLeader ждёт единственного лидера среди узлов ids
+func (c *testCluster) waitLeader(ids ...string) *Node {
+	c.t.Helper()
+	var leader *Node
+	waitFor(c.t, "leader election", func() bool {
+		leader = nil
+		for _, id := range ids {
+			if node := c.nodes[id]; node != nil && node.IsLeader() {
+				if leader != nil {
+					return false
+				}
+				leader = node
+			}
+		}
+		return leader != nil
+	})
+	return leader
+}
+
+// propose предлагает команду лидеру среди ids, повторяя после смены лидера
+func (c *testCluster) propose(value string, ids ...string) {
+	c.t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		_, err := c.waitLeader(ids...).Propose([]byte(value))
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			c.t.Fatalf("propose %s: %v", value, err)
+		}
+	}
+}
+
+// waitValues ждёт, пока машины узлов ids применят ровно want
+func (c *testCluster) waitValues(want []string, ids ...string) {
+	c.t.Helper()
+	waitFor(c.t, "replicated values", func() bool {
+		for _, id := range ids {
+			if !slices.Equal(c.machines[id].snapshot(), want) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func values(from, to int) []string {
+	var out []string
+	for i := from; i < to; i++ {
+		out = append(out, fmt.Sprintf("v%d", i))
+	}
+	return out
+}
+
+func TestElectionAndFailover(t *testing.T) {
+	c := newCluster(t, 3, 0)
+	all := []string{"n1", "n2", "n3"}
+	leader := c.waitLeader(all...)
+	for _, id := range all {
+		if lid, addr := c.nodes[id].Leader(); id != leader.ID() && lid == leader.ID() && addr != "client-"+lid {
+			t.Fatalf("%s knows leader %s at %q", id, lid, addr)
+		}
+	}
+
+	// лидер отрезан: остальные выбирают нового, старый уступает без большинства
+	var rest []string
+	for _, id := range all {
+		if id != leader.ID() {
+			rest = append(rest, id)
+		}
+	}
+	c.net.Partition(rest, []string{leader.ID()})
+	next := c.waitLeader(rest...)
+	if next.ID() == leader.ID() {
+		t.Fatalf("isolated leader kept leadership")
+	}
+	waitFor(t, "old leader step down", func() bool { return !leader.IsLeader() })
+	c.propose("after-failover", rest...)
+
+	// после восстановления сети старый лидер догоняет журнал и не сбивает нового
+	c.net.Heal()
+	c.waitValues([]string{"after-failover"}, all...)
+	if !next.IsLeader() {
+		t.Fatalf("healed node disrupted the new leader")
+	}
+}
+
+func TestReplicationWithMessageDrops(t *testing.T) {
+	c := newCluster(t, 5, 0)
+	all := []string{"n1", "n2", "n3", "n4", "n5"}
+	c.waitLeader(all...)
+	c.net.SetDropRate(0.2)
+	for _, value := range values(0, 30) {
+		c.propose(value, all...)
+	}
+	c.net.SetDropRate(0)
+	// повтор после таймаута может применить команду дважды, но порядок у всех узлов общий
+	want := c.machines[c.waitLeader(all...).ID()].snapshot()
+	for _, value := range values(0, 30) {
+		if !slices.Contains(want, value) {
+			t.Fatalf("value %s is missing: %v", value, want)
+		}
+	}
+	c.waitValues(want, all...)
+}
+
+func TestMinorityPartition(t *testing.T) {
+	c := newCluster(t, 5, 0)
+	all := []string{"n1", "n2", "n3", "n4", "n5"}
+	leader := c.waitLeader(all...)
+	c.propose("before", all...)
+	c.waitValues([]string{"before"}, all...)
+
+	var minority, majority []string
+	minority = append(minority, leader.ID())
+	for _, id := range all {
+		switch {
+		case id == leader.ID():
+		case len(minority) < 2:
+			minority = append(minority, id)
+		default:
+			majority = append(majority, id)
+		}
+	}
+	c.net.Partition(minority, majority)
+
+	// лидер меньшинства принимает запись, но не может её зафиксировать
+	_, err := leader.Propose([]byte("lost"))
+	if !errors.Is(err, ErrLeadershipLost) && !errors.Is(err, ErrTimeout) {
+		t.Fatalf("minority leader committed an entry: %v", err)
+	}
+	c.propose("during", majority...)
+	c.waitValues([]string{"before", "during"}, majority...)
+
+	// после восстановления незафиксированная запись меньшинства заменяется журналом большинства
+	c.net.Heal()
+	c.propose("after", all...)
+	c.waitValues([]string{"before", "during", "after"}, all...)
+}
+
+func TestSnapshotInstall(t *testing.T) {
+	c := newCluster(t, 3, 10)
+	all := []string{"n1", "n2", "n3"}
+	leader := c.waitLeader(all...)
+	var lagging string
+	var rest []string
+	for _, id := range all {
+		if id != leader.ID() && lagging == "" {
+			lagging = id
+		} else {
+			rest = append(rest, id)
+		}
+	}
+	c.net.Partition(rest, []string{lagging})
+	for _, value := range values(0, 35) {
+		c.propose(value, rest...)
+	}
+	waitFor(t, "log compaction", func() bool { return c.nodes[leader.ID()].Status()["snapshotIndex"].(uint64) > 0 })
+
+	c.net.Heal()
+	c.waitValues(values(0, 35), all...)
+	if c.machines[lagging].restores == 0 {
+		t.Fatalf("lagging node caught up without a snapshot")
+	}
+}
+
+func TestMembershipChanges(t *testing.T) {
+	c := newCluster(t, 3, 0)
+	c.propose("v0", "n1", "n2", "n3")
+
+	// новый узел запускается без состава и получает журнал после добавления
+	c.start("n4", nil)
+	leader := c.waitLeader("n1", "n2", "n3")
+	if err := leader.ChangeMembers("n4", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := leader.ChangeMembers("n4", true); err == nil {
+		t.Fatalf("adding a member twice succeeded")
+	}
+	c.propose("v1", "n1", "n2", "n3", "n4")
+	c.waitValues([]string{"v0", "v1"}, "n1", "n2", "n3", "n4")
+	if members := c.nodes["n4"].Members(); len(members) != 4 {
+		t.Fatalf("new node members: %v", members)
+	}
+
+	// лидер удаляет себя: оставшиеся выбирают нового лидера
+	leader = c.waitLeader("n1", "n2", "n3", "n4")
+	if err := leader.ChangeMembers(leader.ID(), false); err != nil {
+		t.Fatal(err)
+	}
+	var rest []string
+	for _, id := range []string{"n1", "n2", "n3", "n4"} {
+		if id != leader.ID() {
+			rest = append(rest, id)
+		}
+	}
+	next := c.waitLeader(rest...)
+	if members := next.Members(); len(members) != 3 || slices.Contains(members, leader.ID()) {
+		t.Fatalf("members after removal: %v", members)
+	}
+	c.stop(leader.ID())
+	c.propose("v2", rest...)
+	c.waitValues([]string{"v0", "v1", "v2"}, rest...)
+}
+
+func TestRestart(t *testing.T) {
+	c := newCluster(t, 3, 5)
+	all := []string{"n1", "n2", "n3"}
+	for _, value := range values(0, 8) {
+		c.propose(value, all...)
+	}
+	c.waitValues(values(0, 8), all...)
+
+	// узлы восстанавливают машину из снимка и повторяют журнал после него
+	for _, id := range all {
+		c.stop(id)
+	}
+	for _, id := range all {
+		c.start(id, all)
+	}
+	c.propose("v8", all...)
+	c.waitValues(values(0, 9), all...)
+}
@@ -0,0 +1,162 @@
+package raft
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	peerQueue   = 1024 // сообщений в очереди к одному узлу; лишние теряются
+	dialTimeout = time.Second
+	// пауза перед повторным подключением к недоступному узлу
+	redialDelay = 200 * time.Millisecond
+)
+
+// TCPTransport передаёт сообщения по TCP: к каждому узлу одно исходящее соединение
+// со своей очередью и горутиной, сообщения кодируются JSON по одному в строке.
+// Входящие соединения принимает Serve и передаёт сообщения step
+type TCPTransport struct {
+	mu     sync.Mutex
+	peers  map[string]chan Message
+	ln     net.Listener
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// NewTCPTransport создаёт транспорт без входящего адреса
+func NewTCPTransport() *TCPTransport {
+	return &TCPTransport{
+		peers: make(map[string]chan Message),
+		conns: make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen открывает входящий адрес узла
+func (t *TCPTransport) Listen(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.ln = ln
+	t.mu.Unlock()
+	return nil
+}
+
+// Serve принимает входящие соединения и передаёт сообщения step до Close
+func (t *TCPTransport) Serve(step func(Message)) {
+	for {
+		conn, err := t.ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("raft transport: accept failed: %v", err)
+			}
+			return
+		}
+		t.mu.Lock()
+		if t.closed {
+			t.mu.Unlock()
+			conn.Close()
+			return
+		}
+		t.conns[conn] = struct{}{}
+		t.wg.Add(1)
+		t.mu.Unlock()
+		go t.receive(conn, step)
+	}
+}
+
+func (t *TCPTransport) receive(conn net.Conn, step func(Message)) {
+	defer t.wg.Done()
+	defer func() {
+		t.mu.Lock()
+		delete(t.conns, conn)
+		t.mu.Unlock()
+		conn.Close()
+	}()
+	decoder := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var msg Message
+		if err := decoder.Decode(&msg); err != nil {
+			return
+		}
+		step(msg)
+	}
+}
+
+// Send ставит сообщение в очередь узла msg.To; при переполненной очереди сообщение теряется
+func (t *TCPTransport) Send(msg Message) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return
+	}
+	queue, ok := t.peers[msg.To]
+	if !ok {
+		queue = make(chan Message, peerQueue)
+		t.peers[msg.To] = queue
+		t.wg.Add(1)
+		go t.sendLoop(msg.To, queue)
+	}
+	select {
+	case queue <- msg:
+	default:
+	}
+}
+
+// sendLoop передаёт очередь узлу addr, переподключаясь после ошибок
+func (t *TCPTransport) sendLoop(addr string, queue chan Message) {
+	defer t.wg.Done()
+	var conn net.Conn
+	var encoder *json.Encoder
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	for msg := range queue {
+		if conn == nil {
+			c, err := net.DialTimeout("tcp", addr, dialTimeout)
+			if err != nil {
+				// узел недоступен: сообщение теряется, Raft повторит его сам
+				time.Sleep(redialDelay)
+				continue
+			}
+			conn, encoder = c, json.NewEncoder(c)
+		}
+		conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+		if err := encoder.Encode(msg); err != nil {
+			conn.Close()
+			conn = nil
+		}
+	}
+}
+
+// Close закрывает входящий адрес и все соединения
+func (t *TCPTransport) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	var err error
+	if t.ln != nil {
+		err = t.ln.Close()
+	}
+	for conn := range t.conns {
+		conn.Close()
+	}
+	for _, queue := range t.peers {
+		close(queue)
+	}
+	t.mu.Unlock()
+	t.wg.Wait()
+	return err
+}
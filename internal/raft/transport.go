@@ -0,0 +1,125 @@
+package raft
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// типы сообщений
+const (
+	MsgVote         = "vote"          // кандидат просит голос
+	MsgVoteResp     = "vote_resp"     // ответ на MsgVote: Granted
+	MsgAppend       = "append"        // лидер передаёт записи после PrevIndex (и heartbeat)
+	MsgAppendResp   = "append_resp"   // ответ на MsgAppend: Success и Match
+	MsgSnapshot     = "snapshot"      // лидер передаёт снимок отставшему узлу
+	MsgSnapshotResp = "snapshot_resp" // ответ на MsgSnapshot: Match — индекс снимка
+)
+
+// Message — сообщение между узлами Raft
+type Message struct {
+	Type string `json:"type"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	Term uint64 `json:"term"`
+
+	// MsgVote: последняя запись журнала кандидата
+	LastIndex uint64 `json:"lastIndex,omitempty"`
+	LastTerm  uint64 `json:"lastTerm,omitempty"`
+	Granted   bool   `json:"granted,omitempty"`
+
+	// MsgAppend: записи после PrevIndex и индекс фиксации лидера
+	PrevIndex  uint64  `json:"prevIndex,omitempty"`
+	PrevTerm   uint64  `json:"prevTerm,omitempty"`
+	Entries    []Entry `json:"entries,omitempty"`
+	Commit     uint64  `json:"commit,omitempty"`
+	LeaderAddr string  `json:"leaderAddr,omitempty"` // адрес лидера для клиентов
+
+	// ответы: Success и последний совпавший индекс; при отказе Match — подсказка,
+	// с какого индекса лидеру повторить
+	Success bool   `json:"success,omitempty"`
+	Match   uint64 `json:"match,omitempty"`
+
+	Snapshot *Snapshot `json:"snapshot,omitempty"`
+}
+
+// Transport доставляет сообщения узлам. Send не ждёт доставки: сообщение может
+// потеряться, задержаться или прийти не по порядку — Raft это допускает
+type Transport interface {
+	Send(msg Message)
+}
+
+// Network — транспорт в памяти процесса для тестов: узлы регистрируются по id,
+// сообщения доставляются асинхронно. Позволяет разделять сеть и терять сообщения
+type Network struct {
+	mu       sync.Mutex
+	nodes    map[string]func(Message)
+	group    map[string]int // номер части сети при разделении; без разделения пусто
+	dropRate float64
+	rand     *rand.Rand
+}
+
+// NewNetwork создаёт сеть в памяти
+func NewNetwork() *Network {
+	return &Network{
+		nodes: make(map[string]func(Message)),
+		group: make(map[string]int),
+		rand:  rand.New(rand.NewSource(1)),
+	}
+}
+
+// Register подключает к сети узел id: входящие сообщения передаются step
+func (n *Network) Register(id string, step func(Message)) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.nodes[id] = step
+}
+
+// Unregister отключает узел: сообщения ему теряются
+func (n *Network) Unregister(id string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.nodes, id)
+}
+
+// Partition делит сеть: узлы из разных групп не слышат друг друга,
+// узлы вне групп — никого
+func (n *Network) Partition(groups ...[]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.group = map[string]int{}
+	for i, ids := range groups {
+		for _, id := range ids {
+			n.group[id] = i + 1
+		}
+	}
+}
+
+// Heal снимает разделение сети
+func (n *Network) Heal() {
+	n.Partition()
+}
+
+// SetDropRate задаёт долю теряемых сообщений, от 0 до 1
+func (n *Network) SetDropRate(rate float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.dropRate = rate
+}
+
+// Send доставляет сообщение, если получатель подключён, достижим и сообщение не потеряно
+func (n *Network) Send(msg Message) {
+	n.mu.Lock()
+	step, ok := n.nodes[msg.To]
+	if len(n.group) > 0 {
+		if from := n.group[msg.From]; from == 0 || from != n.group[msg.To] {
+			ok = false
+		}
+	}
+	if n.dropRate > 0 && n.rand.Float64() < n.dropRate {
+		ok = false
+	}
+	n.mu.Unlock()
+	if ok {
+		go step(msg)
+	}
+}
@@ -0,0 +1,27 @@
+package replication
+
+import "nosql_db/internal/storage"
+
+// типы сообщений потока репликации
+const (
+	MsgSync       = "sync"       // начало начальной синхронизации; Token — позиция журнала, с которой продолжится поток
+	MsgCollection = "collection" // коллекция начальной синхронизации: NS и Spec
+	MsgDocuments  = "documents"  // порция документов коллекции NS
+	MsgSynced     = "synced"     // начальная синхронизация завершена
+	MsgOps        = "ops"        // события журнала; Token — позиция после них
+	MsgHeartbeat  = "heartbeat"  // новых событий нет; Token — позиция потока
+	MsgError      = "error"      // primary прервал поток
+)
+
+// Message — сообщение потока репликации от primary к secondary (JSON построчно,
+// как ответы клиентам). Каждое сообщение несёт Head — последнюю позицию журнала primary
+type Message struct {
+	Type  string                  `json:"type"`
+	Token string                  `json:"token,omitempty"`
+	Head  string                  `json:"head,omitempty"`
+	NS    *storage.Namespace      `json:"ns,omitempty"`
+	Spec  *storage.CollectionSpec `json:"spec,omitempty"`
+	Docs  []map[string]any        `json:"docs,omitempty"`
+	Ops   []storage.ChangeEvent   `json:"ops,omitempty"`
+	Error string                  `json:"error,omitempty"`
+}
@@ -0,0 +1,172 @@
+package replication
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"nosql_db/internal/storage"
+)
+
+// Асинхронная репликация leader–follower. Primary отдаёт журнал операций
+// (журнал изменений менеджера вместе с командами, storage/oplog.go) по запросу replicate.
+// Secondary подключается к primary, при первом подключении или после отставания
+// дальше журнала получает начальную синхронизацию — параметры, индексы и документы всех
+// коллекций, — затем применяет поток операций и отклоняет записи клиентов
+
+// состояния узла
+const (
+	StatePrimary    = "primary"
+	StateConnecting = "connecting" // secondary подключается к primary
+	StateSyncing    = "syncing"    // идёт начальная синхронизация
+	StateStreaming  = "streaming"  // secondary применяет поток операций
+)
+
+const (
+	// heartbeatInterval — как часто primary сообщает позицию журнала простаивающему потоку
+	heartbeatInterval = time.Second
+	// readTimeout — secondary переподключается, если от primary так долго нет сообщений
+	readTimeout = 10 * heartbeatInterval
+	// batchSize — событий журнала или документов в одном сообщении
+	batchSize = 1000
+
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 5 * time.Second
+)
+
+// Node — узел репликации: primary или secondary (replicaOf не пуст)
+type Node struct {
+	mng       *storage.CollectionMng
+	replicaOf string // адрес primary; "" — узел сам primary
+
+	mu          sync.Mutex
+	state       string
+	token       string    // позиция журнала primary, до которой применены операции
+	head        string    // последняя известная позиция журнала primary
+	applied     int       // применено событий с запуска
+	lag         float64   // сколько секунд прошло от фиксации на primary до применения
+	lastContact time.Time // последнее сообщение от primary
+	lastError   string
+	conn        net.Conn            // соединение secondary с primary
+	replicas    map[string]*replica // secondary, подключённые к primary, по адресу
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// replica — поток журнала, который primary отдаёт secondary
+type replica struct {
+	token string // позиция после последних отправленных событий
+	since time.Time
+}
+
+// NewNode создаёт узел для коллекций mng; replicaOf — адрес primary ("host:port")
+// для secondary, пустая строка — primary
+func NewNode(mng *storage.CollectionMng, replicaOf string) *Node {
+	state := StatePrimary
+	if replicaOf != "" {
+		state = StateConnecting
+	}
+	return &Node{
+		mng:       mng,
+		replicaOf: replicaOf,
+		state:     state,
+		replicas:  make(map[string]*replica),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start запускает у secondary репликацию с primary в фоне
+func (n *Node) Start() {
+	if n.replicaOf == "" {
+		close(n.done)
+		return
+	}
+	// истёкшие документы удаляет reaper primary, удаления приходят в журнале
+	n.mng.DisableTTLReaper()
+	go n.run()
+}
+
+// Stop останавливает репликацию и дожидается её завершения
+func (n *Node) Stop() {
+	close(n.stop)
+	n.mu.Lock()
+	if n.conn != nil {
+		n.conn.Close()
+	}
+	n.mu.Unlock()
+	<-n.done
+}
+
+// Secondary — узел реплицирует данные другого узла
+func (n *Node) Secondary() bool {
+	return n.replicaOf != ""
+}
+
+// WriteError возвращает ошибку для записей клиентов: secondary принимает только чтение
+func (n *Node) WriteError() error {
+	if n.Secondary() {
+		return fmt.Errorf("node is a read-only replica of %s: send writes to the primary", n.replicaOf)
+	}
+	return nil
+}
+
+// Status возвращает роль, состояние и отставание узла для repl_status
+func (n *Node) Status() map[string]any {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.Secondary() {
+		head := n.mng.ChangeToken()
+		replicas := make([]map[string]any, 0, len(n.replicas))
+		for addr, r := range n.replicas {
+			replicas = append(replicas, map[string]any{
+				"address":   addr,
+				"token":     r.token,
+				"lagEvents": tokenDistance(r.token, head),
+				"since":     r.since.Format(time.RFC3339),
+			})
+		}
+		sort.Slice(replicas, func(i, j int) bool { return replicas[i]["address"].(string) < replicas[j]["address"].(string) })
+		return map[string]any{"role": "primary", "state": n.state, "token": head, "replicas": replicas}
+	}
+
+	status := map[string]any{
+		"role":       "secondary",
+		"primary":    n.replicaOf,
+		"state":      n.state,
+		"token":      n.token,
+		"applied":    n.applied,
+		"lagEvents":  tokenDistance(n.token, n.head),
+		"lagSeconds": n.lag,
+	}
+	if !n.lastContact.IsZero() {
+		status["lastContact"] = n.lastContact.Format(time.RFC3339)
+	}
+	if n.lastError != "" {
+		status["lastError"] = n.lastError
+	}
+	return status
+}
+
+// tokenDistance — сколько событий журнала между токенами одной эпохи;
+// -1, если расстояние неизвестно (нет позиции или журнал перезапущен)
+func tokenDistance(from, to string) int64 {
+	fromEpoch, fromSeq, ok1 := parseToken(from)
+	toEpoch, toSeq, ok2 := parseToken(to)
+	if !ok1 || !ok2 || fromEpoch != toEpoch {
+		return -1
+	}
+	return max(int64(toSeq)-int64(fromSeq), 0)
+}
+
+func parseToken(token string) (string, uint64, bool) {
+	epoch, rest, found := strings.Cut(token, ".")
+	seq, err := strconv.ParseUint(rest, 10, 64)
+	return epoch, seq, found && err == nil
+}
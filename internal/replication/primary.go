@@ -0,0 +1,147 @@
+package replication
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+)
+
+// Serve отдаёт secondary с адресом addr журнал операций после options.after, пока
+// не закроется stop. Без токена или если журнал его уже не содержит, сначала выполняется
+// начальная синхронизация. Возвращает ошибку отправки
+func (n *Node) Serve(req api.Request, addr string, send func(any) error, stop <-chan struct{}) error {
+	after, _ := req.Options["after"].(string)
+	log.Printf("replication: replica %s connected (after '%s')", addr, after)
+	n.addReplica(addr, after)
+	defer n.removeReplica(addr)
+
+	token := after
+	if _, _, _, err := n.mng.Changes(after, 1); after == "" || err != nil {
+		synced, err := n.initialSync(send)
+		if err != nil {
+			return err
+		}
+		token = synced
+		n.setReplicaToken(addr, token)
+	}
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		events, next, changed, err := n.mng.Changes(token, batchSize)
+		if err != nil {
+			// secondary отстал дальше журнала: при переподключении он синхронизируется заново
+			return send(Message{Type: MsgError, Error: err.Error(), Head: n.mng.ChangeToken()})
+		}
+		if len(events) > 0 {
+			if err := send(Message{Type: MsgOps, Ops: events, Token: next, Head: n.mng.ChangeToken()}); err != nil {
+				return err
+			}
+			token = next
+			n.setReplicaToken(addr, token)
+			if len(events) == batchSize {
+				continue
+			}
+		}
+
+		select {
+		case <-changed:
+		case <-heartbeat.C:
+			if err := send(Message{Type: MsgHeartbeat, Token: token, Head: n.mng.ChangeToken()}); err != nil {
+				return err
+			}
+		case <-stop:
+			return nil
+		}
+	}
+}
+
+// initialSync отправляет все коллекции: параметры с индексами и документы снимков.
+// Возвращает позицию журнала, взятую до снимков: изменения после неё придут потоком,
+// а их повторное применение к уже скопированным документам ничего не меняет
+func (n *Node) initialSync(send func(any) error) (string, error) {
+	token := n.mng.ChangeToken()
+	if err := send(Message{Type: MsgSync, Token: token, Head: token}); err != nil {
+		return "", err
+	}
+
+	dbs, err := n.mng.ListDatabases()
+	if err != nil {
+		return "", n.abortSync(send, err)
+	}
+	collections, documents := 0, 0
+	for _, db := range dbs {
+		infos, err := n.mng.ListCollections(db)
+		if err != nil {
+			return "", n.abortSync(send, err)
+		}
+		for _, info := range infos {
+			ns := storage.Namespace{DB: db, Coll: info.Name}
+			sent, err := n.syncCollection(ns, send)
+			if err != nil {
+				return "", err
+			}
+			collections++
+			documents += sent
+		}
+	}
+	log.Printf("replication: initial sync sent %d collection(s), %d document(s)", collections, documents)
+	return token, send(Message{Type: MsgSynced, Token: token, Head: n.mng.ChangeToken()})
+}
+
+// syncCollection отправляет параметры коллекции и её документы порциями по batchSize
+func (n *Node) syncCollection(ns storage.Namespace, send func(any) error) (int, error) {
+	coll, err := n.mng.GetCollection(ns)
+	if err != nil {
+		return 0, n.abortSync(send, fmt.Errorf("failed to load collection '%s': %w", ns, err))
+	}
+	defer coll.Release()
+
+	spec := coll.Spec()
+	snap := coll.Snapshot()
+	docs := snap.All()
+	snap.Release()
+
+	if err := send(Message{Type: MsgCollection, NS: &ns, Spec: &spec}); err != nil {
+		return 0, err
+	}
+	for start := 0; start < len(docs); start += batchSize {
+		batch := docs[start:min(start+batchSize, len(docs))]
+		if err := send(Message{Type: MsgDocuments, NS: &ns, Docs: batch}); err != nil {
+			return 0, err
+		}
+	}
+	return len(docs), nil
+}
+
+// abortSync сообщает secondary об ошибке синхронизации и возвращает её
+func (n *Node) abortSync(send func(any) error, err error) error {
+	if sendErr := send(Message{Type: MsgError, Error: err.Error()}); sendErr != nil {
+		return sendErr
+	}
+	return err
+}
+
+func (n *Node) addReplica(addr, token string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.replicas[addr] = &replica{token: token, since: time.Now()}
+}
+
+func (n *Node) setReplicaToken(addr, token string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if r, ok := n.replicas[addr]; ok {
+		r.token = token
+	}
+}
+
+func (n *Node) removeReplica(addr string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.replicas, addr)
+	log.Printf("replication: replica %s disconnected", addr)
+}
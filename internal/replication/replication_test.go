@@ -0,0 +1,121 @@
+package replication_test
+
+import (
+	"strings"
+	"testing"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/replication"
+	"nosql_db/internal/server"
+	"nosql_db/internal/testutil"
+)
+
+// startNode запускает сервер с собственным каталогом данных на свободном порту loopback
+func startNode(t *testing.T, replicaOf string) *server.TCPServer {
+	t.Helper()
+	mng := testutil.NewManager(t)
+	node := replication.NewNode(mng, replicaOf)
+	node.Start()
+	t.Cleanup(node.Stop)
+	return testutil.StartServer(t, mng, func(srv *server.TCPServer) { srv.Replication = node })
+}
+
+func count(c *testutil.Client, coll string, query map[string]any) int {
+	return c.OK(api.Request{Command: api.CmdCount, Database: "shop", Collection: coll, Query: query}).Count
+}
+
+func replStatus(c *testutil.Client) map[string]any {
+	return c.OK(api.Request{Command: api.CmdReplStatus}).Data[0]
+}
+
+func TestReplication(t *testing.T) {
+	primary := startNode(t, "")
+	p := testutil.Dial(t, primary.Addr())
+
+	// данные до подключения реплики приходят начальной синхронизацией
+	p.OK(api.Request{Command: api.CmdCreateCollection, Database: "shop", Collection: "items", Options: map[string]any{
+		"validator": map[string]any{"required": []any{"sku"}},
+	}})
+	p.OK(api.Request{Command: api.CmdCreateIndex, Database: "shop", Collection: "items", Query: map[string]any{"sku": 1}})
+	p.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "items", Data: []map[string]any{
+		{"sku": "a1", "qty": 1.0}, {"sku": "b2", "qty": 2.0}, {"sku": "c3", "qty": 3.0},
+	}})
+	p.OK(api.Request{Command: api.CmdCreateCollection, Database: "shop", Collection: "log", Options: map[string]any{
+		"capped": true, "max": 2.0,
+	}})
+
+	secondary := startNode(t, primary.Addr())
+	s := testutil.Dial(t, secondary.Addr())
+	testutil.WaitFor(t, "initial sync", func() bool { return replStatus(s)["state"] == replication.StateStreaming })
+
+	if n := count(s, "items", nil); n != 3 {
+		t.Fatalf("replica has %d documents after initial sync, want 3", n)
+	}
+	found := s.OK(api.Request{Command: api.CmdFind, Database: "shop", Collection: "items", Query: map[string]any{"sku": "b2"}})
+	if len(found.Data) != 1 || found.Data[0]["qty"] != 2.0 {
+		t.Fatalf("replica find by sku: %+v", found.Data)
+	}
+
+	// записи после синхронизации приходят потоком журнала
+	p.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "items", Data: []map[string]any{{"sku": "d4", "qty": 4.0}}})
+	p.OK(api.Request{Command: api.CmdUpdate, Database: "shop", Collection: "items",
+		Query: map[string]any{"sku": "a1"}, Update: map[string]any{"$set": map[string]any{"qty": 10.0}}})
+	p.OK(api.Request{Command: api.CmdDelete, Database: "shop", Collection: "items", Query: map[string]any{"sku": "c3"}})
+	for i := 0; i < 3; i++ {
+		p.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "log", Data: []map[string]any{{"n": float64(i)}}})
+	}
+	p.OK(api.Request{Command: api.CmdCreateCollection, Database: "shop", Collection: "tmp"})
+	p.OK(api.Request{Command: api.CmdRenameCollection, Database: "shop", Collection: "tmp",
+		Options: map[string]any{"to": "archive"}})
+	p.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "archive", Data: []map[string]any{{"sku": "z9"}}})
+
+	testutil.WaitFor(t, "replicated writes", func() bool {
+		return count(s, "items", nil) == 3 && count(s, "items", map[string]any{"qty": 10.0}) == 1 &&
+			count(s, "log", nil) == 2 && count(s, "archive", nil) == 1
+	})
+	if n := count(s, "items", map[string]any{"sku": "c3"}); n != 0 {
+		t.Fatalf("deleted document is still on the replica")
+	}
+	collections := s.OK(api.Request{Command: api.CmdListCollections, Database: "shop"})
+	for _, doc := range collections.Data {
+		if doc["name"] == "tmp" {
+			t.Fatalf("renamed collection 'tmp' is still on the replica")
+		}
+	}
+
+	// реплика только читает
+	resp := s.Do(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "items", Data: []map[string]any{{"sku": "x"}}})
+	if resp.Status != api.StatusError || !strings.Contains(resp.Message, "read-only replica") {
+		t.Fatalf("insert on replica: %+v", resp)
+	}
+	resp = s.Do(api.Request{Command: api.CmdDropCollection, Database: "shop", Collection: "items"})
+	if resp.Status != api.StatusError {
+		t.Fatalf("drop_collection on replica succeeded")
+	}
+
+	testutil.WaitFor(t, "zero lag", func() bool {
+		status := replStatus(s)
+		return status["lagEvents"] == 0.0 && status["lagSeconds"] == 0.0
+	})
+	status := replStatus(p)
+	if status["role"] != "primary" || len(status["replicas"].([]any)) != 1 {
+		t.Fatalf("primary status: %+v", status)
+	}
+}
+
+func TestReplicationDropDatabase(t *testing.T) {
+	primary := startNode(t, "")
+	p := testutil.Dial(t, primary.Addr())
+	p.OK(api.Request{Command: api.CmdInsert, Database: "shop", Collection: "items", Data: []map[string]any{{"sku": "a1"}}})
+
+	secondary := startNode(t, primary.Addr())
+	s := testutil.Dial(t, secondary.Addr())
+	testutil.WaitFor(t, "initial sync", func() bool { return replStatus(s)["state"] == replication.StateStreaming })
+	testutil.WaitFor(t, "document", func() bool { return count(s, "items", nil) == 1 })
+
+	p.OK(api.Request{Command: api.CmdDropDatabase, Database: "shop"})
+	testutil.WaitFor(t, "dropped database", func() bool {
+		dbs := s.OK(api.Request{Command: api.CmdListDatabases})
+		return dbs.Count == 0
+	})
+}
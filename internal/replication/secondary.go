@@ -0,0 +1,167 @@
+package replication
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"time"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+)
+
+// run подключается к primary и применяет его журнал; после обрыва переподключается
+// с растущей паузой. Позиция журнала хранится в памяти: после перезапуска secondary
+// синхронизируется заново
+func (n *Node) run() {
+	defer close(n.done)
+	backoff := minBackoff
+	for {
+		progress, err := n.follow()
+		select {
+		case <-n.stop:
+			return
+		default:
+		}
+		n.setState(StateConnecting, err)
+		log.Printf("replication: connection to primary %s lost: %v", n.replicaOf, err)
+		if progress {
+			backoff = minBackoff
+		}
+		select {
+		case <-time.After(backoff):
+		case <-n.stop:
+			return
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// follow читает поток primary до ошибки. progress — от primary пришло хотя бы одно сообщение
+func (n *Node) follow() (progress bool, err error) {
+	conn, err := net.DialTimeout("tcp", n.replicaOf, readTimeout)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	n.mu.Lock()
+	select {
+	case <-n.stop:
+		n.mu.Unlock()
+		return false, errors.New("replication stopped")
+	default:
+	}
+	n.conn = conn
+	after := n.token
+	n.mu.Unlock()
+
+	req := api.Request{Command: api.CmdReplicate, Options: map[string]any{"after": after}}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return false, err
+	}
+
+	decoder := json.NewDecoder(conn)
+	syncToken := ""
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(readTimeout))
+		var msg Message
+		if err := decoder.Decode(&msg); err != nil {
+			return progress, err
+		}
+		progress = true
+		n.contact(msg.Head)
+
+		switch msg.Type {
+		case MsgSync:
+			log.Printf("replication: initial sync from %s", n.replicaOf)
+			n.setState(StateSyncing, nil)
+			n.setToken("")
+			if err := n.mng.DropAll(); err != nil {
+				return progress, err
+			}
+			syncToken = msg.Token
+		case MsgCollection:
+			if msg.NS == nil || msg.Spec == nil {
+				return progress, fmt.Errorf("malformed %s message", msg.Type)
+			}
+			if err := n.mng.CreateCollectionFromSpec(*msg.NS, *msg.Spec); err != nil {
+				return progress, fmt.Errorf("sync collection '%s': %w", msg.NS, err)
+			}
+			if err := n.mng.CreateIndexes(*msg.NS, msg.Spec.Indexes); err != nil {
+				return progress, fmt.Errorf("sync indexes of '%s': %w", msg.NS, err)
+			}
+		case MsgDocuments:
+			if msg.NS == nil {
+				return progress, fmt.Errorf("malformed %s message", msg.Type)
+			}
+			if err := n.mng.ApplyChanges(storage.DocumentEvents(*msg.NS, msg.Docs)); err != nil {
+				return progress, err
+			}
+		case MsgSynced:
+			log.Printf("replication: initial sync from %s complete", n.replicaOf)
+			n.setToken(syncToken)
+			n.setState(StateStreaming, nil)
+		case MsgOps:
+			if err := n.mng.ApplyChanges(msg.Ops); err != nil {
+				return progress, err
+			}
+			n.applyDone(msg.Token, msg.Ops)
+			n.setState(StateStreaming, nil)
+		case MsgHeartbeat:
+			n.caughtUp(msg.Token)
+		case MsgError:
+			return progress, fmt.Errorf("primary: %s", msg.Error)
+		}
+	}
+}
+
+func (n *Node) setState(state string, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.state = state
+	if err != nil {
+		n.lastError = err.Error()
+	}
+}
+
+func (n *Node) setToken(token string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.token = token
+}
+
+// contact отмечает сообщение primary и его позицию журнала
+func (n *Node) contact(head string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.lastContact = time.Now()
+	if head != "" {
+		n.head = head
+	}
+}
+
+// applyDone сдвигает позицию после применения событий; отставание по времени —
+// возраст последнего применённого события
+func (n *Node) applyDone(token string, events []storage.ChangeEvent) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.token = token
+	n.applied += len(events)
+	if len(events) > 0 {
+		n.lag = max(time.Since(time.Unix(0, events[len(events)-1].Time)).Seconds(), 0)
+	}
+	if n.token == n.head {
+		n.lag = 0
+	}
+}
+
+// caughtUp обнуляет отставание, если heartbeat primary не опережает применённую позицию
+func (n *Node) caughtUp(token string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if token == n.token && n.token == n.head {
+		n.lag = 0
+	}
+}
@@ -0,0 +1,857 @@
+package resp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/handlers"
+	"nosql_db/internal/storage"
+)
+
+// Ключи Redis отображаются на документы: "коллекция:_id" — документ коллекции текущей
+// базы (SELECT), ключ без двоеточия — документ коллекции kv. Строка SET хранится в поле
+// value, срок EXPIRE — в поле _expireAt (секунды Unix) под TTL-индексом коллекции
+
+const (
+	defaultCollection = "kv"        // коллекция ключей без двоеточия
+	valueField        = "value"     // значение строкового ключа
+	expireField       = "_expireAt" // момент истечения ключа
+	// compatVersion — версия Redis, которую видят клиенты в HELLO и INFO
+	compatVersion = "7.0.0"
+)
+
+// key — документ ключа Redis
+type key struct {
+	coll, id string
+}
+
+func parseKey(s string) key {
+	if coll, id, ok := strings.Cut(s, ":"); ok && coll != "" && id != "" {
+		return key{coll: coll, id: id}
+	}
+	return key{coll: defaultCollection, id: s}
+}
+
+// name возвращает ключ документа; _id с двоеточием в коллекции kv сохраняет префикс
+func (k key) name() string {
+	if k.coll == defaultCollection && !strings.Contains(k.id, ":") {
+		return k.id
+	}
+	return k.coll + ":" + k.id
+}
+
+// client — соединение клиента: текущая база, сессия обработчиков и курсоры SCAN
+type client struct {
+	session *handlers.Session
+	node    handlers.Node
+	db      string
+	w       *writer
+	quit    bool
+
+	cursors    map[int]scanCursor
+	lastCursor int
+}
+
+// command — команда Redis; arity — число аргументов вместе с именем,
+// отрицательное — не меньше -arity
+type command struct {
+	arity int
+	run   func(c *client, args []string) any
+}
+
+var commands = map[string]command{
+	"PING":     {-1, ping},
+	"ECHO":     {2, func(c *client, args []string) any { return args[1] }},
+	"HELLO":    {-1, hello},
+	"SELECT":   {2, selectDB},
+	"QUIT":     {1, func(c *client, args []string) any { c.quit = true; return simpleString("OK") }},
+	"COMMAND":  {-1, func(c *client, args []string) any { return []any{} }},
+	"CLIENT":   {-2, clientCmd},
+	"INFO":     {-1, info},
+	"DBSIZE":   {1, dbsize},
+	"GET":      {2, get},
+	"SET":      {-3, set},
+	"DEL":      {-2, del},
+	"UNLINK":   {-2, del},
+	"EXISTS":   {-2, exists},
+	"TYPE":     {2, typeCmd},
+	"EXPIRE":   {3, expire},
+	"PEXPIRE":  {3, expire},
+	"TTL":      {2, ttl},
+	"PTTL":     {2, ttl},
+	"PERSIST":  {2, persist},
+	"KEYS":     {2, keys},
+	"SCAN":     {-2, scan},
+	"JSON.GET": {-2, jsonGet},
+	"JSON.SET": {-4, jsonSet},
+	"JSON.DEL": {-2, jsonDel},
+}
+
+// execute выполняет команду и возвращает ответ
+func (c *client) execute(args []string) any {
+	name := strings.ToUpper(args[0])
+	cmd, ok := commands[name]
+	if !ok {
+		return errorReply(fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+	if (cmd.arity > 0 && len(args) != cmd.arity) || (cmd.arity < 0 && len(args) < -cmd.arity) {
+		return errorReply(fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(name)))
+	}
+	return cmd.run(c, args)
+}
+
+// do выполняет запрос к коллекции ключа в сессии клиента
+func (c *client) do(k key, req api.Request) api.Response {
+	req.Database, req.Collection = c.db, k.coll
+	return c.session.Handle(req)
+}
+
+// responseError — ответ обработчика с ошибкой
+type responseError api.Response
+
+func (e *responseError) Error() string { return e.Message }
+
+// replyError переводит ошибку обработчика в ответ Redis
+func replyError(err error) errorReply {
+	var resp *responseError
+	if errors.As(err, &resp) && resp.Code == api.CodeNotWritable {
+		return errorReply("READONLY " + err.Error())
+	}
+	return errorReply("ERR " + err.Error())
+}
+
+func failed(resp api.Response) error {
+	if resp.Status != api.StatusSuccess {
+		return (*responseError)(&resp)
+	}
+	return nil
+}
+
+// lookup читает документ ключа; nil — ключа нет (или он истёк). Документ — копия
+func (c *client) lookup(k key) (map[string]any, error) {
+	resp := c.do(k, api.Request{Command: api.CmdFind, Query: map[string]any{"_id": k.id}, Limit: 1})
+	if err := failed(resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) == 0 {
+		return nil, nil
+	}
+	return maps.Clone(resp.Data[0]), nil
+}
+
+// maxPutAttempts — сколько раз команда перечитывает ключ, изменённый другим клиентом
+const maxPutAttempts = 16
+
+// put записывает документ ключа, если ключ не изменился с чтения old (nil — ключа не было):
+// insert с options.expect проверяет это и пишет одной задачей записи.
+// false — ключ изменился, команду нужно повторить с новым документом
+func (c *client) put(k key, old, doc map[string]any) (bool, error) {
+	doc = maps.Clone(doc)
+	delete(doc, "_id")
+	var expect any
+	if old != nil {
+		expect = old
+	}
+	resp := c.do(k, api.Request{Command: api.CmdInsert, Data: []map[string]any{doc}, IDs: []string{k.id},
+		Options: map[string]any{"expect": expect}})
+	return resp.Count == 1, failed(resp)
+}
+
+// retryPut выполняет attempt, пока он не запишет ключ (attempt сам читает ключ и вызывает put)
+func retryPut(attempt func() (reply any, written bool, err error)) any {
+	for range maxPutAttempts {
+		reply, written, err := attempt()
+		if err != nil {
+			return replyError(err)
+		}
+		if written {
+			return reply
+		}
+	}
+	return errorReply("ERR the key is modified concurrently, try again")
+}
+
+// modify применяет к документу ключа операторы обновления ($set, $unset)
+// modify обновляет ключ и возвращает число найденных документов: 0 — ключ удалили
+// (или он истёк) после того, как команда его прочитала
+func (c *client) modify(k key, update map[string]any) (int, error) {
+	resp := c.do(k, api.Request{Command: api.CmdUpdate, Query: map[string]any{"_id": k.id}, Update: update})
+	return resp.Matched, failed(resp)
+}
+
+func (c *client) remove(k key) (int, error) {
+	resp := c.do(k, api.Request{Command: api.CmdDelete, Query: map[string]any{"_id": k.id}})
+	return resp.Count, failed(resp)
+}
+
+// ensureTTL создаёт TTL-индекс по полю срока, если у коллекции ключа его ещё нет
+func (c *client) ensureTTL(k key) error {
+	resp := c.do(k, api.Request{Command: api.CmdListIndexes})
+	if err := failed(resp); err != nil {
+		return err
+	}
+	for _, index := range resp.Data {
+		fields, _ := index["fields"].([]string)
+		if _, ttl := index["expireAfterSeconds"]; ttl && slices.Equal(fields, []string{expireField}) {
+			return nil
+		}
+	}
+	return failed(c.do(k, api.Request{
+		Command: api.CmdCreateIndex,
+		Query:   map[string]any{expireField: nil},
+		Options: map[string]any{"expireAfterSeconds": 0.0},
+	}))
+}
+
+// ttlDuration переводит срок из n единиц unit в Duration; false — момент истечения
+// не помещается в int64 наносекунд Unix, такой срок Redis тоже отвергает
+func ttlDuration(n int64, unit time.Duration) (time.Duration, bool) {
+	limit := (math.MaxInt64 - time.Now().UnixNano()) / int64(unit)
+	if n > limit || n < -limit {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// expireAt — значение поля срока через d
+func expireAt(d time.Duration) float64 {
+	return float64(time.Now().Add(d).UnixNano()) / 1e9
+}
+
+// remaining возвращает время до истечения ключа; false — у ключа нет срока
+func remaining(doc map[string]any) (time.Duration, bool) {
+	at, ok := doc[expireField].(float64)
+	if !ok {
+		return 0, false
+	}
+	sec, frac := math.Modf(at)
+	return max(time.Until(time.Unix(int64(sec), int64(frac*1e9))), 0), true
+}
+
+// publicFields возвращает поля документа без _id и срока
+func publicFields(doc map[string]any) map[string]any {
+	out := maps.Clone(doc)
+	delete(out, "_id")
+	delete(out, expireField)
+	return out
+}
+
+// stringValue возвращает значение строкового ключа (SET) или документ в JSON
+func stringValue(doc map[string]any) string {
+	fields := publicFields(doc)
+	if value, ok := fields[valueField].(string); ok && len(fields) == 1 {
+		return value
+	}
+	data, _ := json.Marshal(fields)
+	return string(data)
+}
+
+func isString(doc map[string]any) bool {
+	fields := publicFields(doc)
+	_, ok := fields[valueField].(string)
+	return ok && len(fields) == 1
+}
+
+func ping(c *client, args []string) any {
+	switch len(args) {
+	case 1:
+		return simpleString("PONG")
+	case 2:
+		return args[1]
+	}
+	return errorReply("ERR wrong number of arguments for 'ping' command")
+}
+
+// hello переключает версию протокола: HELLO [2|3] [AUTH ...] [SETNAME ...]
+func hello(c *client, args []string) any {
+	if len(args) > 1 {
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 2 || version > 3 {
+			return errorReply("NOPROTO unsupported protocol version")
+		}
+		c.w.version = version
+	}
+	role := "master"
+	if c.node != nil && c.node.WriteError() != nil {
+		role = "replica"
+	}
+	return mapReply{
+		"server", "nosqldb",
+		"version", compatVersion,
+		"proto", c.w.version,
+		"mode", "standalone",
+		"role", role,
+		"modules", []any{},
+	}
+}
+
+// selectDB выбирает базу: SELECT 0 — база по умолчанию, иначе — имя базы
+func selectDB(c *client, args []string) any {
+	db := args[1]
+	if db == "0" {
+		db = storage.DefaultDatabase
+	}
+	if err := storage.ValidateDatabaseName("database", db); err != nil {
+		return replyError(err)
+	}
+	c.db = db
+	return simpleString("OK")
+}
+
+func clientCmd(c *client, args []string) any {
+	switch strings.ToUpper(args[1]) {
+	case "SETNAME", "SETINFO", "NO-EVICT", "NO-TOUCH", "REPLY":
+		return simpleString("OK")
+	}
+	return errorReply(fmt.Sprintf("ERR unsupported CLIENT subcommand '%s'", args[1]))
+}
+
+func info(c *client, args []string) any {
+	return fmt.Sprintf("# Server\r\nredis_version:%s\r\nredis_mode:standalone\r\nserver_name:nosqldb\r\n", compatVersion)
+}
+
+func dbsize(c *client, args []string) any {
+	names, err := c.collections()
+	if err != nil {
+		return replyError(err)
+	}
+	total := 0
+	for _, name := range names {
+		resp := c.do(key{coll: name}, api.Request{Command: api.CmdCount})
+		if err := failed(resp); err != nil {
+			return replyError(err)
+		}
+		total += resp.Count
+	}
+	return total
+}
+
+func get(c *client, args []string) any {
+	doc, err := c.lookup(parseKey(args[1]))
+	if err != nil {
+		return replyError(err)
+	}
+	if doc == nil {
+		return nil
+	}
+	return stringValue(doc)
+}
+
+// set — SET key value [NX|XX] [GET] [EX seconds|PX milliseconds|KEEPTTL]
+func set(c *client, args []string) any {
+	var nx, xx, keepTTL, returnOld bool
+	var ttl time.Duration
+	for i := 3; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); option {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "GET":
+			returnOld = true
+		case "KEEPTTL":
+			keepTTL = true
+		case "EX", "PX":
+			if i+1 == len(args) || ttl != 0 {
+				return errorReply("ERR syntax error")
+			}
+			i++
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			unit := time.Second
+			if option == "PX" {
+				unit = time.Millisecond
+			}
+			var ok bool
+			if ttl, ok = ttlDuration(n, unit); err != nil || n <= 0 || !ok {
+				return errorReply("ERR invalid expire time in 'set' command")
+			}
+		default:
+			return errorReply("ERR syntax error")
+		}
+	}
+	if (nx && xx) || (keepTTL && ttl != 0) {
+		return errorReply("ERR syntax error")
+	}
+
+	k := parseKey(args[1])
+	if ttl != 0 {
+		if err := c.ensureTTL(k); err != nil {
+			return replyError(err)
+		}
+	}
+	// NX/XX, GET и KEEPTTL смотрят на тот документ, который заменяет запись
+	return retryPut(func() (any, bool, error) {
+		old, err := c.lookup(k)
+		if err != nil {
+			return nil, false, err
+		}
+		var reply any = simpleString("OK")
+		if returnOld {
+			reply = nil
+			if old != nil {
+				reply = stringValue(old)
+			}
+		}
+		if (nx && old != nil) || (xx && old == nil) {
+			if returnOld {
+				return reply, true, nil
+			}
+			return nil, true, nil
+		}
+
+		doc := map[string]any{valueField: args[2]}
+		switch {
+		case ttl != 0:
+			doc[expireField] = expireAt(ttl)
+		case keepTTL && old != nil && old[expireField] != nil:
+			doc[expireField] = old[expireField]
+		}
+		written, err := c.put(k, old, doc)
+		return reply, written, err
+	})
+}
+
+func del(c *client, args []string) any {
+	deleted := 0
+	for _, name := range args[1:] {
+		n, err := c.remove(parseKey(name))
+		if err != nil {
+			return replyError(err)
+		}
+		deleted += n
+	}
+	return deleted
+}
+
+func exists(c *client, args []string) any {
+	found := 0
+	for _, name := range args[1:] {
+		doc, err := c.lookup(parseKey(name))
+		if err != nil {
+			return replyError(err)
+		}
+		if doc != nil {
+			found++
+		}
+	}
+	return found
+}
+
+func typeCmd(c *client, args []string) any {
+	doc, err := c.lookup(parseKey(args[1]))
+	switch {
+	case err != nil:
+		return replyError(err)
+	case doc == nil:
+		return simpleString("none")
+	case isString(doc):
+		return simpleString("string")
+	}
+	return simpleString("ReJSON-RL")
+}
+
+// expire задаёт срок ключа: EXPIRE key seconds, PEXPIRE key milliseconds
+func expire(c *client, args []string) any {
+	n, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		return errorReply("ERR value is not an integer or out of range")
+	}
+	command, unit := strings.ToUpper(args[0]), time.Second
+	if command == "PEXPIRE" {
+		unit = time.Millisecond
+	}
+	d, ok := ttlDuration(n, unit)
+	if !ok {
+		return errorReply(fmt.Sprintf("ERR invalid expire time in '%s' command", strings.ToLower(command)))
+	}
+	k := parseKey(args[1])
+	doc, err := c.lookup(k)
+	if err != nil {
+		return replyError(err)
+	}
+	if doc == nil {
+		return 0
+	}
+	if d <= 0 {
+		// срок в прошлом удаляет ключ, как в Redis
+		removed, err := c.remove(k)
+		if err != nil {
+			return replyError(err)
+		}
+		return removed
+	}
+	if err := c.ensureTTL(k); err != nil {
+		return replyError(err)
+	}
+	updated, err := c.modify(k, map[string]any{"$set": map[string]any{expireField: expireAt(d)}})
+	if err != nil {
+		return replyError(err)
+	}
+	return updated
+}
+
+// ttl — оставшийся срок: -2 — ключа нет, -1 — ключ без срока
+func ttl(c *client, args []string) any {
+	doc, err := c.lookup(parseKey(args[1]))
+	if err != nil {
+		return replyError(err)
+	}
+	if doc == nil {
+		return -2
+	}
+	left, ok := remaining(doc)
+	if !ok {
+		return -1
+	}
+	if strings.ToUpper(args[0]) == "PTTL" {
+		return int(left.Milliseconds())
+	}
+	return int((left + time.Second/2) / time.Second)
+}
+
+func persist(c *client, args []string) any {
+	k := parseKey(args[1])
+	doc, err := c.lookup(k)
+	if err != nil {
+		return replyError(err)
+	}
+	if doc == nil || doc[expireField] == nil {
+		return 0
+	}
+	updated, err := c.modify(k, map[string]any{"$unset": map[string]any{expireField: ""}})
+	if err != nil {
+		return replyError(err)
+	}
+	return updated
+}
+
+// collections возвращает коллекции текущей базы по имени
+func (c *client) collections() ([]string, error) {
+	resp := c.session.Handle(api.Request{Command: api.CmdListCollections, Database: c.db})
+	if err := failed(resp); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(resp.Data))
+	for _, info := range resp.Data {
+		if name, ok := info["name"].(string); ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// allKeys возвращает упорядоченные ключи всех документов текущей базы
+func (c *client) allKeys() ([]string, error) {
+	names, err := c.collections()
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, name := range names {
+		resp := c.do(key{coll: name}, api.Request{Command: api.CmdFind, Projection: map[string]any{"_id": 1.0}})
+		if err := failed(resp); err != nil {
+			return nil, err
+		}
+		for _, doc := range resp.Data {
+			if id, ok := doc["_id"].(string); ok {
+				keys = append(keys, key{coll: name, id: id}.name())
+			}
+		}
+	}
+	slices.Sort(keys)
+	return keys, nil
+}
+
+// matchKey сравнивает ключ с glob-шаблоном Redis (*, ?, [...])
+func matchKey(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+func keys(c *client, args []string) any {
+	all, err := c.allKeys()
+	if err != nil {
+		return replyError(err)
+	}
+	matched := []string{}
+	for _, name := range all {
+		if matchKey(args[1], name) {
+			matched = append(matched, name)
+		}
+	}
+	return matched
+}
+
+// scanCursor — позиция SCAN: коллекция и последний выданный в ней _id
+type scanCursor struct {
+	coll, after string
+}
+
+// maxCursors — сколько незавершённых обходов SCAN помнит соединение
+const maxCursors = 64
+
+// saveCursor запоминает позицию и возвращает номер курсора для клиента;
+// самый старый курсор забывается, когда их становится больше maxCursors
+func (c *client) saveCursor(pos scanCursor) int {
+	if c.cursors == nil {
+		c.cursors = make(map[int]scanCursor)
+	}
+	if len(c.cursors) >= maxCursors {
+		delete(c.cursors, slices.Min(slices.Collect(maps.Keys(c.cursors))))
+	}
+	c.lastCursor++
+	c.cursors[c.lastCursor] = pos
+	return c.lastCursor
+}
+
+// scan — SCAN cursor [MATCH pattern] [COUNT count]. Ключи обходятся по коллекциям и
+// по возрастанию _id в них (find с options.after), курсор — номер позиции обхода в соединении,
+// так что страница читает только свои count ключей. Ключи, добавленные во время обхода
+// перед позицией курсора, пропускаются, как и в Redis
+func scan(c *client, args []string) any {
+	cursor, err := strconv.Atoi(args[1])
+	if err != nil || cursor < 0 {
+		return errorReply("ERR invalid cursor")
+	}
+	pattern, count := "*", 10
+	for i := 2; i < len(args); i += 2 {
+		if i+1 == len(args) {
+			return errorReply("ERR syntax error")
+		}
+		switch strings.ToUpper(args[i]) {
+		case "MATCH":
+			pattern = args[i+1]
+		case "COUNT":
+			if count, err = strconv.Atoi(args[i+1]); err != nil || count < 1 {
+				return errorReply("ERR value is not an integer or out of range")
+			}
+		default:
+			return errorReply("ERR syntax error")
+		}
+	}
+
+	var pos scanCursor
+	if cursor != 0 {
+		var ok bool
+		if pos, ok = c.cursors[cursor]; !ok {
+			return errorReply("ERR invalid cursor")
+		}
+		delete(c.cursors, cursor)
+	}
+	names, err := c.collections()
+	if err != nil {
+		return replyError(err)
+	}
+	matched := []string{}
+	for _, name := range names {
+		if name < pos.coll {
+			continue
+		}
+		after := ""
+		if name == pos.coll {
+			after = pos.after
+		}
+		resp := c.do(key{coll: name}, api.Request{Command: api.CmdFind, Projection: map[string]any{"_id": 1.0},
+			Limit: count, Options: map[string]any{"after": after}})
+		if err := failed(resp); err != nil {
+			return replyError(err)
+		}
+		for _, doc := range resp.Data {
+			after, _ = doc["_id"].(string)
+			if keyName := (key{coll: name, id: after}).name(); matchKey(pattern, keyName) {
+				matched = append(matched, keyName)
+			}
+		}
+		if count -= len(resp.Data); count == 0 {
+			next := c.saveCursor(scanCursor{coll: name, after: after})
+			return []any{strconv.Itoa(next), matched}
+		}
+	}
+	return []any{"0", matched}
+}
+
+// jsonPath разбирает путь JSON.*: "" , "." и "$" — весь документ, "$.поле" и ".поле" —
+// поле верхнего уровня; dollar — путь JSONPath, ответ JSON.GET по нему — массив
+func jsonPath(p string) (field string, dollar bool, err error) {
+	switch {
+	case p == "" || p == ".":
+		return "", false, nil
+	case p == "$":
+		return "", true, nil
+	case strings.HasPrefix(p, "$."):
+		field, dollar = p[2:], true
+	case strings.HasPrefix(p, "."):
+		field = p[1:]
+	default:
+		field = p
+	}
+	if field == "" || strings.ContainsAny(field, ".[]*") {
+		return "", false, fmt.Errorf("only top-level paths ($.field) are supported")
+	}
+	if field == "_id" || field == expireField {
+		return "", false, fmt.Errorf("field '%s' is managed by the server", field)
+	}
+	return field, dollar, nil
+}
+
+func marshal(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return replyError(err)
+	}
+	return string(data)
+}
+
+// jsonGet — JSON.GET key [path]
+func jsonGet(c *client, args []string) any {
+	if len(args) > 3 {
+		return errorReply("ERR only one path is supported")
+	}
+	p := ""
+	if len(args) == 3 {
+		p = args[2]
+	}
+	field, dollar, err := jsonPath(p)
+	if err != nil {
+		return replyError(err)
+	}
+	doc, err := c.lookup(parseKey(args[1]))
+	if err != nil {
+		return replyError(err)
+	}
+	if doc == nil {
+		return nil
+	}
+	body := publicFields(doc)
+	if field == "" {
+		if dollar {
+			return marshal([]any{body})
+		}
+		return marshal(body)
+	}
+	value, ok := body[field]
+	switch {
+	case dollar && !ok:
+		return "[]"
+	case dollar:
+		return marshal([]any{value})
+	case !ok:
+		return nil
+	}
+	return marshal(value)
+}
+
+// jsonSet — JSON.SET key path value [NX|XX]: корень заменяет документ (срок ключа
+// сохраняется), поле верхнего уровня меняется в существующем документе
+func jsonSet(c *client, args []string) any {
+	if len(args) > 5 {
+		return errorReply("ERR syntax error")
+	}
+	var nx, xx bool
+	if len(args) == 5 {
+		switch strings.ToUpper(args[4]) {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		default:
+			return errorReply("ERR syntax error")
+		}
+	}
+	field, _, err := jsonPath(args[2])
+	if err != nil {
+		return replyError(err)
+	}
+	var value any
+	if err := json.Unmarshal([]byte(args[3]), &value); err != nil {
+		return errorReply(fmt.Sprintf("ERR invalid JSON value: %v", err))
+	}
+	obj, isObject := value.(map[string]any)
+	if field == "" {
+		if !isObject {
+			return errorReply("ERR the root value must be a JSON object")
+		}
+		if _, ok := obj["_id"]; ok {
+			return errorReply("ERR _id is taken from the key and cannot be set")
+		}
+	}
+
+	// условие NX/XX и срок ключа проверяются по тому документу, который заменяет запись
+	k := parseKey(args[1])
+	return retryPut(func() (any, bool, error) {
+		doc, err := c.lookup(k)
+		if err != nil {
+			return nil, false, err
+		}
+		var updated map[string]any
+		if field == "" {
+			if (nx && doc != nil) || (xx && doc == nil) {
+				return nil, true, nil
+			}
+			updated = maps.Clone(obj)
+			delete(updated, expireField)
+			if doc != nil && doc[expireField] != nil {
+				updated[expireField] = doc[expireField]
+			}
+		} else {
+			if doc == nil {
+				return errorReply("ERR new objects must be created at the root"), true, nil
+			}
+			if _, has := doc[field]; (nx && has) || (xx && !has) {
+				return nil, true, nil
+			}
+			updated = maps.Clone(doc)
+			updated[field] = value
+		}
+		written, err := c.put(k, doc, updated)
+		return simpleString("OK"), written, err
+	})
+}
+
+// jsonDel — JSON.DEL key [path]: корень удаляет ключ, поле — только поле
+func jsonDel(c *client, args []string) any {
+	if len(args) > 3 {
+		return errorReply("ERR syntax error")
+	}
+	p := ""
+	if len(args) == 3 {
+		p = args[2]
+	}
+	field, _, err := jsonPath(p)
+	if err != nil {
+		return replyError(err)
+	}
+	k := parseKey(args[1])
+	if field == "" {
+		n, err := c.remove(k)
+		if err != nil {
+			return replyError(err)
+		}
+		return n
+	}
+	doc, err := c.lookup(k)
+	if err != nil {
+		return replyError(err)
+	}
+	if doc == nil {
+		return 0
+	}
+	if _, has := doc[field]; !has {
+		return 0
+	}
+	updated, err := c.modify(k, map[string]any{"$unset": map[string]any{field: ""}})
+	if err != nil {
+		return replyError(err)
+	}
+	return updated
+}
@@ -0,0 +1,194 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Протокол Redis: команда — массив bulk-строк (или inline-строка, как из telnet),
+// ответ — значение RESP2 или RESP3 (версию выбирает клиент командой HELLO)
+
+const (
+	maxBulkLen  = 64 << 20 // наибольшая bulk-строка команды
+	maxArgs     = 1 << 20  // наибольшее число аргументов команды
+	maxInlineLn = 64 << 10 // наибольшая inline-команда
+)
+
+// errProtocol — клиент нарушил протокол: соединение закрывается после ответа с ошибкой
+var errProtocol = errors.New("Protocol error")
+
+// simpleString — ответ вида +OK
+type simpleString string
+
+// errorReply — ответ с ошибкой; текст начинается с кода (ERR, WRONGTYPE, ...)
+type errorReply string
+
+// mapReply — пары ключ-значение: map в RESP3, плоский массив в RESP2
+type mapReply []any
+
+// reader читает команды клиента
+type reader struct {
+	r *bufio.Reader
+}
+
+func newReader(r io.Reader) *reader {
+	return &reader{r: bufio.NewReader(r)}
+}
+
+// readCommand читает команду: массив bulk-строк или inline-строку через пробелы
+func (r *reader) readCommand() ([]string, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			continue
+		}
+		if line[0] != '*' {
+			return strings.Fields(line), nil
+		}
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n > maxArgs {
+			return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+		}
+		if n <= 0 {
+			continue
+		}
+		args := make([]string, n)
+		for i := range args {
+			if args[i], err = r.readBulk(); err != nil {
+				return nil, err
+			}
+		}
+		return args, nil
+	}
+}
+
+func (r *reader) readBulk() (string, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return "", err
+	}
+	if line == "" || line[0] != '$' {
+		return "", fmt.Errorf("%w: expected '$', got '%.1s'", errProtocol, line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 0 || n > maxBulkLen {
+		return "", fmt.Errorf("%w: invalid bulk length", errProtocol)
+	}
+	buf := make([]byte, n+2)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return "", err
+	}
+	if buf[n] != '\r' || buf[n+1] != '\n' {
+		return "", fmt.Errorf("%w: bulk string is not terminated by CRLF", errProtocol)
+	}
+	return string(buf[:n]), nil
+}
+
+// readLine читает строку до \n без \r\n
+func (r *reader) readLine() (string, error) {
+	var line []byte
+	for {
+		chunk, isPrefix, err := r.r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		line = append(line, chunk...)
+		if len(line) > maxInlineLn {
+			return "", fmt.Errorf("%w: too big inline request", errProtocol)
+		}
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+// writer пишет ответы в версии протокола version (2 или 3)
+type writer struct {
+	w       *bufio.Writer
+	version int
+}
+
+func newWriter(w io.Writer) *writer {
+	return &writer{w: bufio.NewWriter(w), version: 2}
+}
+
+func (w *writer) flush() error {
+	return w.w.Flush()
+}
+
+// write кодирует значение: nil — null, string — bulk-строка, int — integer,
+// []any и []string — массив, bool и float64 — типы RESP3 (в RESP2 — integer и bulk-строка)
+func (w *writer) write(v any) {
+	switch v := v.(type) {
+	case nil:
+		if w.version >= 3 {
+			w.w.WriteString("_\r\n")
+		} else {
+			w.w.WriteString("$-1\r\n")
+		}
+	case simpleString:
+		w.line('+', string(v))
+	case errorReply:
+		w.line('-', string(v))
+	case int:
+		w.line(':', strconv.Itoa(v))
+	case int64:
+		w.line(':', strconv.FormatInt(v, 10))
+	case string:
+		w.line('$', strconv.Itoa(len(v)))
+		w.w.WriteString(v)
+		w.w.WriteString("\r\n")
+	case bool:
+		switch {
+		case w.version >= 3 && v:
+			w.w.WriteString("#t\r\n")
+		case w.version >= 3:
+			w.w.WriteString("#f\r\n")
+		case v:
+			w.write(1)
+		default:
+			w.write(0)
+		}
+	case float64:
+		s := strconv.FormatFloat(v, 'f', -1, 64)
+		if w.version >= 3 {
+			w.line(',', s)
+		} else {
+			w.write(s)
+		}
+	case []string:
+		w.line('*', strconv.Itoa(len(v)))
+		for _, item := range v {
+			w.write(item)
+		}
+	case []any:
+		w.line('*', strconv.Itoa(len(v)))
+		for _, item := range v {
+			w.write(item)
+		}
+	case mapReply:
+		if w.version >= 3 {
+			w.line('%', strconv.Itoa(len(v)/2))
+		} else {
+			w.line('*', strconv.Itoa(len(v)))
+		}
+		for _, item := range v {
+			w.write(item)
+		}
+	default:
+		w.line('-', fmt.Sprintf("ERR unsupported reply type %T", v))
+	}
+}
+
+func (w *writer) line(prefix byte, s string) {
+	w.w.WriteByte(prefix)
+	w.w.WriteString(s)
+	w.w.WriteString("\r\n")
+}
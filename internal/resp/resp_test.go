@@ -0,0 +1,273 @@
+package resp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"nosql_db/internal/storage"
+)
+
+// testConn — клиент RESP для тестов: команда — массив bulk-строк, ответ разбирается в Go-значение
+type testConn struct {
+	t    *testing.T
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *testConn) do(args ...string) any {
+	c.t.Helper()
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.conn.Write([]byte(b.String())); err != nil {
+		c.t.Fatal(err)
+	}
+	return c.read()
+}
+
+// read разбирает ответ: ошибка — error, null — nil, map — map[string]any
+func (c *testConn) read() any {
+	c.t.Helper()
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	body := line[1:]
+	switch line[0] {
+	case '+':
+		return body
+	case '-':
+		return fmt.Errorf("%s", body)
+	case ':':
+		n, _ := strconv.Atoi(body)
+		return n
+	case '_':
+		return nil
+	case '$':
+		n, _ := strconv.Atoi(body)
+		if n < 0 {
+			return nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			c.t.Fatal(err)
+		}
+		return string(buf[:n])
+	case '*':
+		n, _ := strconv.Atoi(body)
+		items := make([]any, n)
+		for i := range items {
+			items[i] = c.read()
+		}
+		return items
+	case '%':
+		n, _ := strconv.Atoi(body)
+		m := map[string]any{}
+		for range n {
+			k := c.read().(string)
+			m[k] = c.read()
+		}
+		return m
+	}
+	c.t.Fatalf("unexpected reply %q", line)
+	return nil
+}
+
+func (c *testConn) expect(want any, args ...string) {
+	c.t.Helper()
+	if got := c.do(args...); !reflect.DeepEqual(got, want) {
+		c.t.Fatalf("%v: got %#v, want %#v", args, got, want)
+	}
+}
+
+// startServer запускает слушатель RESP над менеджером во временном каталоге
+func startServer(t *testing.T) string {
+	t.Helper()
+	mng := storage.NewManager()
+	if err := mng.OpenDataDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mng.Stop)
+	srv := NewServer("127.0.0.1:0")
+	srv.Manager = mng
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+	return srv.Addr()
+}
+
+// dial открывает соединение клиента с сервером addr
+func dial(t *testing.T, addr string) *testConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+	return &testConn{t: t, conn: conn, r: bufio.NewReader(conn)}
+}
+
+func TestRESPCommands(t *testing.T) {
+	c := dial(t, startServer(t))
+
+	c.expect("PONG", "PING")
+
+	// строковые ключи: документ коллекции kv с полем value
+	c.expect(nil, "GET", "greeting")
+	c.expect("OK", "SET", "greeting", "hello")
+	c.expect("hello", "GET", "greeting")
+	c.expect(nil, "SET", "greeting", "again", "NX")
+	c.expect("OK", "SET", "users:1", "alice")
+	c.expect(2, "EXISTS", "greeting", "users:1", "missing")
+	c.expect("string", "TYPE", "users:1")
+
+	// срок ключа — поле _expireAt под TTL-индексом
+	c.expect(-1, "TTL", "greeting")
+	c.expect("OK", "SET", "session", "token", "EX", "100")
+	if ttl := c.do("TTL", "session"); ttl != 100 {
+		t.Fatalf("TTL after SET EX: %v", ttl)
+	}
+	// срок, момент истечения которого не помещается в int64, отвергается, как в Redis
+	for _, args := range [][]string{
+		{"EXPIRE", "greeting", "9223372036854775807"},
+		{"PEXPIRE", "greeting", "-9223372036854775808"},
+		{"SET", "greeting", "hello", "EX", "9223372036854775807"},
+	} {
+		reply := c.do(args...)
+		if err, ok := reply.(error); !ok || !strings.Contains(err.Error(), "invalid expire time") {
+			t.Fatalf("%v: expected an invalid expire time error, got %v", args, reply)
+		}
+	}
+	c.expect(-1, "TTL", "greeting")
+	c.expect(1, "PEXPIRE", "greeting", "100")
+	time.Sleep(300 * time.Millisecond)
+	c.expect(nil, "GET", "greeting")
+	c.expect(-2, "TTL", "greeting")
+	// истёкший ключ, ещё не удалённый reaper'ом, DEL не считает, а SET NX заменяет
+	c.expect(0, "DEL", "greeting")
+	c.expect("OK", "SET", "session", "renewed", "KEEPTTL")
+	if ttl := c.do("TTL", "session"); ttl != 100 {
+		t.Fatalf("TTL after SET KEEPTTL: %v", ttl)
+	}
+	c.expect(1, "PEXPIRE", "session", "100")
+	time.Sleep(300 * time.Millisecond)
+	c.expect(nil, "SET", "session", "late", "XX")
+	c.expect("OK", "SET", "session", "fresh", "NX")
+	c.expect(-1, "TTL", "session")
+
+	// документы JSON
+	c.expect("OK", "JSON.SET", "users:2", "$", `{"name":"bob","age":30}`)
+	c.expect("OK", "JSON.SET", "users:2", "$.age", "31")
+	c.expect("[31]", "JSON.GET", "users:2", "$.age")
+	c.expect(`{"age":31,"name":"bob"}`, "JSON.GET", "users:2")
+	c.expect("ReJSON-RL", "TYPE", "users:2")
+	if _, ok := c.do("JSON.GET", "users:2", "$.address.city").(error); !ok {
+		t.Fatal("nested JSON paths must be rejected")
+	}
+
+	// обход ключей
+	c.expect([]any{"users:1", "users:2"}, "KEYS", "users:*")
+	c.expect([]any{"0", []any{"users:1", "users:2"}}, "SCAN", "0", "MATCH", "users:*", "COUNT", "100")
+	first := c.do("SCAN", "0", "COUNT", "1").([]any)
+	if first[0] != "1" || len(first[1].([]any)) != 1 {
+		t.Fatalf("first SCAN page: %v", first)
+	}
+	c.expect(2, "DEL", "users:1", "users:2", "missing")
+	c.expect(0, "EXISTS", "users:1")
+
+	// RESP3: HELLO 3 отвечает map, null — "_"
+	reply, ok := c.do("HELLO", "3").(map[string]any)
+	if !ok || reply["proto"] != 3 || reply["version"] != compatVersion {
+		t.Fatalf("HELLO 3: %#v", reply)
+	}
+	c.expect(nil, "GET", "missing")
+	if _, ok := c.do("NOSUCH").(error); !ok {
+		t.Fatal("unknown command must fail")
+	}
+}
+
+func TestRESPConcurrentSet(t *testing.T) {
+	addr := startServer(t)
+	const clients = 8
+
+	// из одновременных SET NX записывает ровно один, JSON.SET полей не теряет записи друг друга
+	dial(t, addr).expect("OK", "JSON.SET", "counters:1", "$", "{}")
+	results := make(chan any, clients)
+	conns := make([]*testConn, clients)
+	for i := range conns {
+		conns[i] = dial(t, addr)
+	}
+	for i, c := range conns {
+		go func() {
+			results <- c.do("SET", "lock", strconv.Itoa(i), "NX")
+			results <- c.do("JSON.SET", "counters:1", "$.c"+strconv.Itoa(i), "1")
+		}()
+	}
+	set := 0
+	for range 2 * clients {
+		switch reply := <-results; reply {
+		case "OK":
+			set++
+		case nil:
+		default:
+			t.Fatalf("unexpected reply %#v", reply)
+		}
+	}
+	if set != clients+1 {
+		t.Fatalf("%d SET NX and JSON.SET succeeded, expected 1 SET NX and %d JSON.SET", set-clients, clients)
+	}
+	c := dial(t, addr)
+	fields := c.do("JSON.GET", "counters:1").(string)
+	for i := range clients {
+		if !strings.Contains(fields, fmt.Sprintf(`"c%d":1`, i)) {
+			t.Fatalf("JSON.SET of field c%d is lost: %s", i, fields)
+		}
+	}
+}
+
+func TestRESPScanPages(t *testing.T) {
+	c := dial(t, startServer(t))
+	want := map[string]bool{}
+	for i := range 25 {
+		for _, name := range []string{fmt.Sprintf("k%02d", i), fmt.Sprintf("users:%02d", i)} {
+			c.expect("OK", "SET", name, "v")
+			want[name] = true
+		}
+	}
+
+	// обход страницами по 7 ключей выдаёт каждый ключ ровно один раз
+	seen := map[string]bool{}
+	cursor, pages := "0", 0
+	for {
+		page := c.do("SCAN", cursor, "COUNT", "7").([]any)
+		pages++
+		for _, name := range page[1].([]any) {
+			if seen[name.(string)] {
+				t.Fatalf("SCAN returned %s twice", name)
+			}
+			seen[name.(string)] = true
+		}
+		if cursor = page[0].(string); cursor == "0" {
+			break
+		}
+	}
+	if !reflect.DeepEqual(seen, want) || pages != 8 {
+		t.Fatalf("SCAN returned %d key(s) in %d page(s), expected %d in 8", len(seen), pages, len(want))
+	}
+	if _, ok := c.do("SCAN", "12345").(error); !ok {
+		t.Fatal("unknown SCAN cursor must be rejected")
+	}
+}
@@ -0,0 +1,115 @@
+// Package resp — слушатель протокола Redis (RESP2/RESP3): ключи Redis отображаются
+// на документы коллекций, так что с базой можно работать из redis-cli
+package resp
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"nosql_db/internal/handlers"
+	"nosql_db/internal/storage"
+)
+
+// Server — слушатель RESP; команды выполняются обработчиками в сессии соединения
+type Server struct {
+	Manager *storage.CollectionMng // коллекции, с которыми работают команды
+	Node    handlers.Node          // роль узла (реплика, кластер); nil — одиночный сервер
+	Address string
+	Timeout int // простой соединения в секундах; 0 — без ограничения, как в Redis
+
+	listener net.Listener
+}
+
+func NewServer(address string) *Server {
+	return &Server{
+		Manager: storage.GlobalManager,
+		Address: address,
+	}
+}
+
+func (s *Server) Run() error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Listen открывает порт; с адресом ":0" порт выбирает система (см. Addr)
+func (s *Server) Listen() error {
+	listener, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	return nil
+}
+
+// Addr возвращает адрес открытого порта
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close закрывает порт: Serve возвращается, открытые соединения дорабатывают сами
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+// Serve принимает соединения на порту, открытом Listen, до вызова Close
+func (s *Server) Serve() error {
+	log.Printf("resp server running on %s", s.listener.Addr())
+	for {
+		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			log.Printf("conn error: %v", err)
+			continue
+		}
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *Server) handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	// незафиксированная транзакция отбрасывается при закрытии соединения
+	session := handlers.NewSession(s.Manager, s.Node)
+	defer session.Close()
+
+	r := newReader(conn)
+	c := &client{session: session, node: s.Node, db: storage.DefaultDatabase, w: newWriter(conn)}
+	for {
+		if s.Timeout > 0 {
+			_ = conn.SetDeadline(time.Now().Add(time.Duration(s.Timeout) * time.Second))
+		}
+		args, err := r.readCommand()
+		if errors.Is(err, errProtocol) {
+			c.w.write(errorReply("ERR " + err.Error()))
+			_ = c.w.flush()
+			return
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.Printf("resp: read from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+		c.w.write(c.execute(args))
+		// ответы конвейера (pipelining) отправляются вместе, когда прочитаны все команды
+		if r.r.Buffered() == 0 || c.quit {
+			if err := c.w.flush(); err != nil {
+				return
+			}
+		}
+		if c.quit {
+			return
+		}
+	}
+}
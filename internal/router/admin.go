@@ -0,0 +1,331 @@
+package router
+
+import (
+	"fmt"
+	"log"
+	"slices"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/storage"
+)
+
+// migrateBatch — документов в одной вставке и удалении при переносе чанка
+const migrateBatch = 1000
+
+// shardCollection распределяет коллекцию: options.key — ключ, options.strategy — hash
+// (по умолчанию) или range, options.chunks — число начальных hash-чанков (по умолчанию
+// по числу шардов), options.splitPoints — границы начальных range-чанков.
+// Если на основном шарде уже есть документы, все чанки остаются на нём до move_chunk
+func (r *Router) shardCollection(ns storage.Namespace, req api.Request) api.Response {
+	l := r.lock(ns)
+	l.Lock()
+	defer l.Unlock()
+
+	key, _ := req.Options["key"].(string)
+	if key == "" {
+		return api.Response{Status: api.StatusError, Message: "options.key (shard key field) is required"}
+	}
+	if key == "_id" {
+		return api.Response{Status: api.StatusError, Message: "_id is assigned by the shards and cannot be the shard key"}
+	}
+	strategy, _ := req.Options["strategy"].(string)
+	if strategy == "" {
+		strategy = StrategyHash
+	}
+	count := len(r.shards)
+	if n, ok := req.Options["chunks"].(float64); ok {
+		if n < 1 || n != float64(int(n)) {
+			return api.Response{Status: api.StatusError, Message: "options.chunks must be a positive integer"}
+		}
+		count = int(n)
+	}
+	splitPoints, _ := req.Options["splitPoints"].([]any)
+	if r.sharded(ns) != nil {
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("collection '%s' is already sharded", ns)}
+	}
+
+	existing := r.shards[0].call(api.Request{Command: api.CmdCount, Database: ns.DB, Collection: ns.Coll})
+	if existing.Status != api.StatusSuccess {
+		return existing
+	}
+	owners := r.shardAddrs()
+	if existing.Count > 0 {
+		owners = owners[:1]
+	}
+	sharded, err := newSharded(key, strategy, count, splitPoints, owners)
+	if err != nil {
+		return api.Response{Status: api.StatusError, Message: err.Error()}
+	}
+	if err := r.update(ns, sharded); err != nil {
+		return api.Response{Status: api.StatusError, Message: err.Error()}
+	}
+	message := fmt.Sprintf("Collection '%s' sharded by %s '%s' into %d chunk(s)", ns, strategy, key, len(sharded.Chunks))
+	if existing.Count > 0 {
+		message += fmt.Sprintf("; %d existing document(s) stay on %s until move_chunk", existing.Count, owners[0])
+	}
+	return api.Response{Status: api.StatusSuccess, Message: message, Count: len(sharded.Chunks)}
+}
+
+// splitChunk делит чанк по точке options.at (у hash — значение хеша) или, без неё,
+// по медиане точек документов чанка
+func (r *Router) splitChunk(ns storage.Namespace, req api.Request) api.Response {
+	l := r.lock(ns)
+	l.Lock()
+	defer l.Unlock()
+
+	sharded := r.sharded(ns)
+	if sharded == nil {
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("collection '%s' is not sharded", ns)}
+	}
+	at, ok := req.Options["at"]
+	if !ok {
+		i, err := chunkOption(sharded, req.Options)
+		if err != nil {
+			return api.Response{Status: api.StatusError, Message: err.Error()}
+		}
+		points, resp := r.chunkPoints(ns, sharded, i)
+		if resp != nil {
+			return *resp
+		}
+		// медиана, большая наименьшей точки: обе части чанка не пусты
+		at = nil
+		for j := len(points) / 2; j < len(points); j++ {
+			if compareKeys(points[j], points[0]) > 0 {
+				at = points[j]
+				break
+			}
+		}
+		if at == nil {
+			return api.Response{Status: api.StatusError, Message: fmt.Sprintf("chunk %d cannot be split: its documents share one shard key point", i)}
+		}
+	}
+	if err := sharded.split(at); err != nil {
+		return api.Response{Status: api.StatusError, Message: err.Error()}
+	}
+	if err := r.update(ns, sharded); err != nil {
+		return api.Response{Status: api.StatusError, Message: err.Error()}
+	}
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("Chunk split at %v: '%s' has %d chunk(s)", at, ns, len(sharded.Chunks)),
+		Count:   len(sharded.Chunks),
+	}
+}
+
+// chunkOption выбирает чанк по options.chunk (номер) или options.at (точка внутри него)
+func chunkOption(sharded *Sharded, options map[string]any) (int, error) {
+	if n, ok := options["chunk"].(float64); ok {
+		if n < 0 || int(n) >= len(sharded.Chunks) || n != float64(int(n)) {
+			return 0, fmt.Errorf("chunk %v does not exist (0..%d)", n, len(sharded.Chunks)-1)
+		}
+		return int(n), nil
+	}
+	if at, ok := options["at"]; ok {
+		return sharded.chunkAt(at), nil
+	}
+	return 0, fmt.Errorf("options.chunk (number) or options.at (point inside the chunk) is required")
+}
+
+// chunkPoints возвращает упорядоченные точки документов чанка i
+func (r *Router) chunkPoints(ns storage.Namespace, sharded *Sharded, i int) ([]any, *api.Response) {
+	var points []any
+	resp := r.chunkDocuments(ns, sharded, i, map[string]any{sharded.Key: 1.0}, func(docs []map[string]any) *api.Response {
+		for _, doc := range docs {
+			points = append(points, sharded.point(doc[sharded.Key]))
+		}
+		return nil
+	})
+	if resp != nil {
+		return nil, resp
+	}
+	slices.SortFunc(points, compareKeys)
+	return points, nil
+}
+
+// chunkDocuments читает документы чанка i с его шарда порциями по migrateBatch
+// (find с options.after по возрастанию _id) и передаёт каждую порцию fn
+func (r *Router) chunkDocuments(ns storage.Namespace, sharded *Sharded, i int, projection map[string]any,
+	fn func(docs []map[string]any) *api.Response) *api.Response {
+	chunk := sharded.Chunks[i]
+	after := ""
+	for {
+		resp := r.shard(chunk.Shard).call(api.Request{Command: api.CmdFind, Database: ns.DB, Collection: ns.Coll,
+			Projection: projection, Limit: migrateBatch, Options: map[string]any{"after": after}})
+		if resp.Status != api.StatusSuccess {
+			return &resp
+		}
+		var docs []map[string]any
+		for _, doc := range resp.Data {
+			after, _ = doc["_id"].(string)
+			if chunk.contains(sharded.point(doc[sharded.Key])) {
+				docs = append(docs, doc)
+			}
+		}
+		if len(docs) > 0 {
+			if failed := fn(docs); failed != nil {
+				return failed
+			}
+		}
+		if len(resp.Data) < migrateBatch {
+			return nil
+		}
+	}
+}
+
+// moveChunk переносит чанк (options.chunk или options.at) на шард options.to. Запросы
+// к коллекции ждут конца переноса: документы копируются на новый шард с теми же _id,
+// карта переключается вместе с записью об очистке старого шарда, затем документы
+// удаляются с него. Неудавшаяся очистка повторяется перед следующими запросами
+func (r *Router) moveChunk(ns storage.Namespace, req api.Request) api.Response {
+	l := r.lock(ns)
+	l.Lock()
+	defer l.Unlock()
+
+	// вставка на шард, где остались копии прошлого переноса, столкнулась бы с их _id
+	if pending := r.cleanupLocked(ns); pending > 0 {
+		return api.Response{Status: api.StatusError, Code: api.CodeUnavailable,
+			Message: fmt.Sprintf("move chunk: %d cleanup(s) of earlier moves of '%s' are still pending, retry later", pending, ns)}
+	}
+	sharded := r.sharded(ns)
+	if sharded == nil {
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("collection '%s' is not sharded", ns)}
+	}
+	i, err := chunkOption(sharded, req.Options)
+	if err != nil {
+		return api.Response{Status: api.StatusError, Message: err.Error()}
+	}
+	to, _ := req.Options["to"].(string)
+	target := r.shard(to)
+	if target == nil {
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("options.to must be one of the shards %v", r.shardAddrs())}
+	}
+	from := r.shard(sharded.Chunks[i].Shard)
+	if from == target {
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("chunk %d is already on %s", i, to)}
+	}
+
+	var ids []string
+	failed := r.chunkDocuments(ns, sharded, i, nil, func(docs []map[string]any) *api.Response {
+		batch := make([]string, len(docs))
+		for j, doc := range docs {
+			batch[j], _ = doc["_id"].(string)
+		}
+		resp := target.call(api.Request{Command: api.CmdInsert, Database: ns.DB, Collection: ns.Coll, Data: docs, IDs: batch})
+		if resp.Status != api.StatusSuccess {
+			return &resp
+		}
+		ids = append(ids, batch...)
+		return nil
+	})
+	if failed != nil {
+		// копия на новом шарде удаляется: документы остаются на старом
+		r.deleteIDs(target, ns, ids)
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("move chunk %d: copy to %s failed: %s", i, to, failed.Message)}
+	}
+
+	sharded.Chunks[i].Shard = to
+	if len(ids) > 0 {
+		sharded.Cleanup = append(sharded.Cleanup, Cleanup{Shard: from.addr, IDs: ids})
+	}
+	if err := r.update(ns, sharded); err != nil {
+		r.deleteIDs(target, ns, ids)
+		return api.Response{Status: api.StatusError, Message: err.Error()}
+	}
+	r.cleanupLocked(ns)
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("Moved chunk %d of '%s' (%d document(s)) from %s to %s", i, ns, len(ids), from.addr, to),
+		Count:   len(ids),
+	}
+}
+
+// retryCleanup повторяет отложенную очистку старых шардов коллекции
+func (r *Router) retryCleanup(ns storage.Namespace) {
+	l := r.lock(ns)
+	l.Lock()
+	defer l.Unlock()
+	r.cleanupLocked(ns)
+}
+
+// cleanupLocked удаляет со старых шардов копии перенесённых чанков и убирает выполненные
+// записи из карты; возвращает число оставшихся. Вызывается под блокировкой коллекции на запись
+func (r *Router) cleanupLocked(ns storage.Namespace) int {
+	sharded := r.sharded(ns)
+	if sharded == nil || len(sharded.Cleanup) == 0 {
+		return 0
+	}
+	var pending []Cleanup
+	for _, cleanup := range sharded.Cleanup {
+		if err := r.deleteIDs(r.shard(cleanup.Shard), ns, cleanup.IDs); err != nil {
+			log.Printf("router: cleanup of %d moved document(s) of %s on %s failed: %v", len(cleanup.IDs), ns, cleanup.Shard, err)
+			pending = append(pending, cleanup)
+		}
+	}
+	if len(pending) < len(sharded.Cleanup) {
+		sharded.Cleanup = pending
+		if err := r.update(ns, sharded); err != nil {
+			log.Printf("router: failed to save cleanup state of %s: %v", ns, err)
+		}
+	}
+	return len(pending)
+}
+
+// deleteIDs удаляет документы по _id порциями
+func (r *Router) deleteIDs(s *shard, ns storage.Namespace, ids []string) error {
+	for start := 0; start < len(ids); start += migrateBatch {
+		batch := make([]any, 0, migrateBatch)
+		for _, id := range ids[start:min(start+migrateBatch, len(ids))] {
+			batch = append(batch, id)
+		}
+		resp := s.call(api.Request{Command: api.CmdDelete, Database: ns.DB, Collection: ns.Coll,
+			Query: map[string]any{"_id": map[string]any{"$in": batch}}})
+		if resp.Status != api.StatusSuccess {
+			return fmt.Errorf("%s", resp.Message)
+		}
+	}
+	return nil
+}
+
+// update сохраняет распределение коллекции в карте и файле
+func (r *Router) update(ns storage.Namespace, sharded *Sharded) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	prev, had := r.meta.Collections[ns]
+	r.meta.Collections[ns] = sharded
+	if err := r.meta.save(r.metaPath); err != nil {
+		if had {
+			r.meta.Collections[ns] = prev
+		} else {
+			delete(r.meta.Collections, ns)
+		}
+		return err
+	}
+	return nil
+}
+
+// status возвращает шарды и чанки распределённых коллекций
+func (r *Router) status() api.Response {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := r.meta.namespaces()
+	data := make([]map[string]any, 0, len(names))
+	for _, ns := range names {
+		s := r.meta.Collections[ns]
+		chunks := make([]map[string]any, len(s.Chunks))
+		for i, chunk := range s.Chunks {
+			chunks[i] = map[string]any{"chunk": i, "min": chunk.Min, "max": chunk.Max, "shard": chunk.Shard}
+		}
+		pending := 0
+		for _, cleanup := range s.Cleanup {
+			pending += len(cleanup.IDs)
+		}
+		data = append(data, map[string]any{"database": ns.DB, "collection": ns.Coll, "key": s.Key, "strategy": s.Strategy, "chunks": chunks,
+			"pendingCleanup": pending})
+	}
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("%d sharded collection(s) on shards %v", len(data), r.shardAddrs()),
+		Data:    data,
+		Count:   len(data),
+	}
+}
@@ -0,0 +1,236 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"nosql_db/internal/index"
+	"nosql_db/internal/storage"
+)
+
+// стратегии распределения
+const (
+	StrategyHash  = "hash"  // точка документа — FNV-1a хеш значения ключа, [0, 2^32)
+	StrategyRange = "range" // точка документа — само значение ключа в порядке индекса
+)
+
+// hashSpace — размер пространства хешей
+const hashSpace = float64(math.MaxUint32) + 1
+
+// Chunk — диапазон точек [Min, Max) на одном шарде; nil — граница без ограничения
+type Chunk struct {
+	Min   any    `json:"min"`
+	Max   any    `json:"max"`
+	Shard string `json:"shard"`
+}
+
+// Sharded — распределённая коллекция: ключ, стратегия и чанки по возрастанию
+type Sharded struct {
+	Key      string    `json:"key"`
+	Strategy string    `json:"strategy"`
+	Chunks   []Chunk   `json:"chunks"`
+	Cleanup  []Cleanup `json:"cleanup,omitempty"` // копии перенесённых чанков, ещё не удалённые со старых шардов
+}
+
+// Cleanup — документы перенесённого чанка, которые нужно удалить со старого шарда
+type Cleanup struct {
+	Shard string   `json:"shard"`
+	IDs   []string `json:"ids"`
+}
+
+// Metadata — карта распределённых коллекций роутера; хранится в JSON-файле
+type Metadata struct {
+	Collections map[storage.Namespace]*Sharded
+}
+
+// metadataFile — карта в файле: списком, потому что ключ объекта JSON — строка, а строка
+// "база.коллекция" неоднозначна, когда в именах есть точки
+type metadataFile struct {
+	Collections []metadataEntry `json:"collections"`
+}
+
+type metadataEntry struct {
+	Namespace storage.Namespace `json:"ns"`
+	Sharded   *Sharded          `json:"sharded"`
+}
+
+// point возвращает точку документа со значением ключа value
+func (s *Sharded) point(value any) any {
+	if s.Strategy == StrategyHash {
+		return hashPoint(value)
+	}
+	return value
+}
+
+func hashPoint(value any) float64 {
+	h := fnv.New32a()
+	h.Write(index.ValueToKey(value))
+	return float64(h.Sum32())
+}
+
+// chunkAt возвращает индекс чанка, содержащего точку
+func (s *Sharded) chunkAt(point any) int {
+	for i, chunk := range s.Chunks {
+		if chunk.contains(point) {
+			return i
+		}
+	}
+	// чанки покрывают всё пространство: сюда не доходим
+	return len(s.Chunks) - 1
+}
+
+// shardOf возвращает шард документа со значением ключа value
+func (s *Sharded) shardOf(value any) string {
+	return s.Chunks[s.chunkAt(s.point(value))].Shard
+}
+
+func (c Chunk) contains(point any) bool {
+	return (c.Min == nil || compareKeys(c.Min, point) <= 0) && (c.Max == nil || compareKeys(point, c.Max) < 0)
+}
+
+// overlaps — чанк пересекает отрезок точек [lo, hi]; nil — без ограничения
+func (c Chunk) overlaps(lo, hi any) bool {
+	return (hi == nil || c.Min == nil || compareKeys(c.Min, hi) <= 0) &&
+		(lo == nil || c.Max == nil || compareKeys(lo, c.Max) < 0)
+}
+
+// compareKeys сравнивает значения в порядке ключей индекса — том же, в котором сортирует find
+func compareKeys(a, b any) int {
+	return bytes.Compare(index.ValueToKey(a), index.ValueToKey(b))
+}
+
+// shards возвращает шарды, на которых есть чанки коллекции
+func (s *Sharded) shards() []string {
+	var shards []string
+	for _, chunk := range s.Chunks {
+		if !slices.Contains(shards, chunk.Shard) {
+			shards = append(shards, chunk.Shard)
+		}
+	}
+	return shards
+}
+
+// newSharded строит начальные чанки: hash — count равных диапазонов хешей,
+// range — диапазоны между splitPoints; чанки раздаются шардам по кругу
+func newSharded(key, strategy string, count int, splitPoints []any, shards []string) (*Sharded, error) {
+	s := &Sharded{Key: key, Strategy: strategy}
+	var bounds []any
+	switch strategy {
+	case StrategyHash:
+		if len(splitPoints) > 0 {
+			return nil, errors.New("splitPoints apply to range sharding; hash sharding takes options.chunks")
+		}
+		for i := 1; i < count; i++ {
+			bounds = append(bounds, math.Floor(hashSpace*float64(i)/float64(count)))
+		}
+	case StrategyRange:
+		bounds = slices.Clone(splitPoints)
+		slices.SortFunc(bounds, compareKeys)
+		for i, bound := range bounds {
+			if bound == nil {
+				return nil, errors.New("null cannot be a split point")
+			}
+			if i > 0 && compareKeys(bounds[i-1], bound) == 0 {
+				return nil, fmt.Errorf("duplicate split point %v", bound)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unknown sharding strategy '%s' (hash or range)", strategy)
+	}
+
+	var min any
+	for i := 0; i <= len(bounds); i++ {
+		var max any
+		if i < len(bounds) {
+			max = bounds[i]
+		}
+		s.Chunks = append(s.Chunks, Chunk{Min: min, Max: max, Shard: shards[i%len(shards)]})
+		min = max
+	}
+	return s, nil
+}
+
+// split делит чанк, содержащий at, на [Min, at) и [at, Max) на том же шарде
+func (s *Sharded) split(at any) error {
+	if at == nil {
+		return errors.New("null cannot be a split point")
+	}
+	i := s.chunkAt(at)
+	chunk := s.Chunks[i]
+	if chunk.Min != nil && compareKeys(chunk.Min, at) == 0 {
+		return fmt.Errorf("%v is already a chunk boundary", at)
+	}
+	left, right := chunk, chunk
+	left.Max, right.Min = at, at
+	s.Chunks = slices.Replace(s.Chunks, i, i+1, left, right)
+	return nil
+}
+
+// loadMetadata читает карту коллекций; отсутствующий файл — пустая карта
+func loadMetadata(path string) (*Metadata, error) {
+	meta := &Metadata{Collections: map[storage.Namespace]*Sharded{}}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return meta, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read router metadata: %w", err)
+	}
+	var file metadataFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("decode router metadata: %w", err)
+	}
+	for _, entry := range file.Collections {
+		if entry.Sharded == nil {
+			return nil, fmt.Errorf("decode router metadata: collection '%s' has no distribution", entry.Namespace)
+		}
+		meta.Collections[entry.Namespace] = entry.Sharded
+	}
+	return meta, nil
+}
+
+// save атомарно записывает карту коллекций
+func (m *Metadata) save(path string) error {
+	file := metadataFile{Collections: make([]metadataEntry, 0, len(m.Collections))}
+	for _, ns := range m.namespaces() {
+		file.Collections = append(file.Collections, metadataEntry{Namespace: ns, Sharded: m.Collections[ns]})
+	}
+	raw, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal router metadata: %w", err)
+	}
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("mkdir error: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0644); err != nil {
+		return fmt.Errorf("write router metadata: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// namespaces возвращает распределённые коллекции по базе и имени
+func (m *Metadata) namespaces() []storage.Namespace {
+	names := make([]storage.Namespace, 0, len(m.Collections))
+	for ns := range m.Collections {
+		names = append(names, ns)
+	}
+	slices.SortFunc(names, func(a, b storage.Namespace) int {
+		if c := strings.Compare(a.DB, b.DB); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Coll, b.Coll)
+	})
+	return names
+}
@@ -0,0 +1,780 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/handlers"
+	"nosql_db/internal/index"
+	"nosql_db/internal/query"
+	"nosql_db/internal/storage"
+)
+
+// Роутер распределяет коллекции по серверам-шардам по ключу шардирования.
+// Клиенты подключаются к роутеру тем же протоколом, что и к серверу: запрос
+// с ключом уходит одному шарду, остальные рассылаются всем шардам коллекции,
+// и ответы объединяются. Нераспределённые коллекции живут на первом шарде
+
+// Router — роутер поверх шардов
+type Router struct {
+	shards   []*shard // первый — основной шард нераспределённых коллекций
+	metaPath string
+
+	mu   sync.RWMutex
+	meta *Metadata
+
+	// блокировка коллекции: запросы берут её на чтение, перенос чанка — на запись
+	locksMu sync.Mutex
+	locks   map[storage.Namespace]*sync.RWMutex
+
+	listener net.Listener
+}
+
+// New создаёт роутер над шардами addrs; карта распределённых коллекций хранится в metaPath
+func New(addrs []string, metaPath string) (*Router, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("router needs at least one shard")
+	}
+	meta, err := loadMetadata(metaPath)
+	if err != nil {
+		return nil, err
+	}
+	r := &Router{metaPath: metaPath, meta: meta, locks: make(map[storage.Namespace]*sync.RWMutex)}
+	for _, addr := range addrs {
+		r.shards = append(r.shards, newShard(addr))
+	}
+	for ns, sharded := range meta.Collections {
+		for _, chunk := range sharded.Chunks {
+			if r.shard(chunk.Shard) == nil {
+				return nil, fmt.Errorf("collection '%s' has a chunk on unknown shard %s", ns, chunk.Shard)
+			}
+		}
+		for _, cleanup := range sharded.Cleanup {
+			if r.shard(cleanup.Shard) == nil {
+				return nil, fmt.Errorf("collection '%s' has a pending cleanup on unknown shard %s", ns, cleanup.Shard)
+			}
+		}
+	}
+	return r, nil
+}
+
+// Close закрывает порт роутера (Serve возвращается) и свободные соединения с шардами
+func (r *Router) Close() error {
+	for _, s := range r.shards {
+		s.close()
+	}
+	if r.listener == nil {
+		return nil
+	}
+	return r.listener.Close()
+}
+
+func (r *Router) shard(addr string) *shard {
+	for _, s := range r.shards {
+		if s.addr == addr {
+			return s
+		}
+	}
+	return nil
+}
+
+func (r *Router) shardAddrs() []string {
+	addrs := make([]string, len(r.shards))
+	for i, s := range r.shards {
+		addrs[i] = s.addr
+	}
+	return addrs
+}
+
+// sharded возвращает копию распределения коллекции; nil — коллекция не распределена
+func (r *Router) sharded(ns storage.Namespace) *Sharded {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	s, ok := r.meta.Collections[ns]
+	if !ok {
+		return nil
+	}
+	c := *s
+	c.Chunks = slices.Clone(s.Chunks)
+	c.Cleanup = slices.Clone(s.Cleanup)
+	return &c
+}
+
+func (r *Router) lock(ns storage.Namespace) *sync.RWMutex {
+	r.locksMu.Lock()
+	defer r.locksMu.Unlock()
+	l, ok := r.locks[ns]
+	if !ok {
+		l = &sync.RWMutex{}
+		r.locks[ns] = l
+	}
+	return l
+}
+
+// Session — соединение клиента с роутером: текущая база
+type Session struct {
+	r  *Router
+	db string
+}
+
+// NewSession создаёт сессию с базой по умолчанию
+func (r *Router) NewSession() *Session {
+	return &Session{r: r, db: storage.DefaultDatabase}
+}
+
+// Handle выполняет запрос клиента на шардах
+func (s *Session) Handle(req api.Request) api.Response {
+	switch req.Command {
+	case api.CmdUse:
+		if err := storage.ValidateDatabaseName("database", req.Database); err != nil {
+			return api.Response{Status: api.StatusError, Message: err.Error()}
+		}
+		s.db = req.Database
+		return api.Response{Status: api.StatusSuccess, Message: fmt.Sprintf("Switched to database '%s'", s.db)}
+	case api.CmdBegin, api.CmdCommit, api.CmdAbort, api.CmdWatch, api.CmdReplicate,
+		api.CmdAddMember, api.CmdRemoveMember:
+		return api.Response{Status: api.StatusError, Message: req.Command + " is not supported by the router"}
+	case api.CmdListDatabases:
+		return s.r.listDatabases()
+	case api.CmdStats, api.CmdReplStatus:
+		return s.r.perShard(req)
+	case api.CmdShardingStatus:
+		return s.r.status()
+	}
+	if handlers.IsStreaming(req) {
+		return api.Response{Status: api.StatusError, Message: "tailable find is not supported by the router"}
+	}
+
+	req = handlers.ResolveRequest(req, s.db)
+	switch req.Command {
+	case api.CmdListCollections:
+		return s.r.listCollections(req)
+	case api.CmdDropDatabase:
+		return s.r.dropDatabase(req)
+	}
+	if err := storage.ValidateDatabaseName("database", req.Database); err != nil {
+		return api.Response{Status: api.StatusError, Message: err.Error()}
+	}
+	if err := storage.ValidateName("collection", req.Collection); err != nil {
+		return api.Response{Status: api.StatusError, Message: err.Error()}
+	}
+	ns := storage.Namespace{DB: req.Database, Coll: req.Collection}
+
+	switch req.Command {
+	case api.CmdShardCollection:
+		return s.r.shardCollection(ns, req)
+	case api.CmdSplitChunk:
+		return s.r.splitChunk(ns, req)
+	case api.CmdMoveChunk:
+		return s.r.moveChunk(ns, req)
+	}
+
+	// копии перенесённого чанка на старом шарде дали бы в ответах дубликаты
+	if sharded := s.r.sharded(ns); sharded != nil && len(sharded.Cleanup) > 0 {
+		s.r.retryCleanup(ns)
+	}
+
+	l := s.r.lock(ns)
+	l.RLock()
+	defer l.RUnlock()
+	return s.r.route(ns, req)
+}
+
+// route выполняет запрос к коллекции: команды схемы — на всех шардах,
+// нераспределённая коллекция — на основном шарде, распределённая — на шардах её чанков
+func (r *Router) route(ns storage.Namespace, req api.Request) api.Response {
+	sharded := r.sharded(ns)
+	switch req.Command {
+	case api.CmdCreateCollection, api.CmdCreateIndex, api.CmdCollMod:
+		return r.broadcast(r.shards, req)
+	case api.CmdDropCollection:
+		resp := r.broadcast(r.shards, req)
+		if resp.Status == api.StatusSuccess && sharded != nil {
+			r.forget(func(name storage.Namespace) bool { return name == ns })
+		}
+		return resp
+	case api.CmdRenameCollection:
+		if sharded != nil {
+			return api.Response{Status: api.StatusError, Message: fmt.Sprintf("cannot rename sharded collection '%s'", ns)}
+		}
+		return r.broadcast(r.shards, req)
+	case api.CmdListIndexes:
+		// индексы одинаковы на всех шардах: команды схемы рассылаются всем
+		return r.shards[0].call(req)
+	}
+	if sharded == nil {
+		return r.shards[0].call(req)
+	}
+
+	switch req.Command {
+	case api.CmdInsert:
+		return r.insert(sharded, req)
+	case api.CmdUpdate:
+		for _, fields := range req.Update {
+			if m, ok := fields.(map[string]any); ok {
+				if _, ok := m[sharded.Key]; ok {
+					return api.Response{Status: api.StatusError, Message: fmt.Sprintf("shard key '%s' cannot be updated", sharded.Key)}
+				}
+			}
+		}
+	}
+
+	targets := r.targets(sharded, req.Query)
+	if len(targets) == 1 {
+		return targets[0].call(req)
+	}
+	switch req.Command {
+	case api.CmdFind:
+		return r.find(targets, req)
+	case api.CmdCount, api.CmdUpdate, api.CmdDelete:
+		return r.sum(targets, req)
+	case api.CmdDistinct:
+		return r.distinct(targets, req)
+	}
+	return api.Response{Status: api.StatusError, Message: fmt.Sprintf("unknown command: %s", req.Command)}
+}
+
+// targets возвращает шарды, на которых могут быть документы запроса: равенство ключу
+// и $in выбирают чанки точек, у range-распределения — и $gt/$lt; иначе — все шарды коллекции
+func (r *Router) targets(sharded *Sharded, q map[string]any) []*shard {
+	addrs := sharded.shards()
+	switch cond := q[sharded.Key].(type) {
+	case nil:
+	case map[string]any:
+		if value, ok := cond["$eq"]; ok {
+			addrs = []string{sharded.shardOf(value)}
+		} else if values, ok := cond["$in"].([]any); ok {
+			addrs = nil
+			for _, value := range values {
+				if addr := sharded.shardOf(value); !slices.Contains(addrs, addr) {
+					addrs = append(addrs, addr)
+				}
+			}
+		} else if sharded.Strategy == StrategyRange {
+			addrs = sharded.rangeShards(cond)
+		}
+	default:
+		addrs = []string{sharded.shardOf(cond)}
+	}
+
+	targets := make([]*shard, 0, len(addrs))
+	for _, s := range r.shards {
+		if slices.Contains(addrs, s.addr) {
+			targets = append(targets, s)
+		}
+	}
+	return targets
+}
+
+// rangeShards возвращает шарды чанков, пересекающих числовой диапазон $gt/$lt
+func (s *Sharded) rangeShards(cond map[string]any) []string {
+	var lo, hi any
+	if v, ok := cond["$gt"].(float64); ok {
+		lo = v
+	}
+	if v, ok := cond["$lt"].(float64); ok {
+		hi = v
+	}
+	if lo == nil && hi == nil {
+		return s.shards()
+	}
+	var shards []string
+	for _, chunk := range s.Chunks {
+		if chunk.overlaps(lo, hi) && !slices.Contains(shards, chunk.Shard) {
+			shards = append(shards, chunk.Shard)
+		}
+	}
+	return shards
+}
+
+// call отправляет запрос шардам параллельно; ответы в порядке targets
+func (r *Router) call(targets []*shard, req api.Request) []api.Response {
+	responses := make([]api.Response, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = target.call(req)
+		}()
+	}
+	wg.Wait()
+	return responses
+}
+
+// firstError возвращает ошибку первого неуспешного ответа с адресом шарда
+func firstError(targets []*shard, responses []api.Response) *api.Response {
+	for i, resp := range responses {
+		if resp.Status != api.StatusSuccess {
+			return &api.Response{Status: api.StatusError, Message: fmt.Sprintf("shard %s: %s", targets[i].addr, resp.Message), Code: resp.Code}
+		}
+	}
+	return nil
+}
+
+// broadcast выполняет команду схемы на всех шардах. Успех хотя бы одного шарда — успех;
+// ошибки остальных (кроме «не существует» у шардов без коллекции) — предупреждения
+func (r *Router) broadcast(targets []*shard, req api.Request) api.Response {
+	responses := r.call(targets, req)
+	var ok *api.Response
+	var warnings []string
+	for i, resp := range responses {
+		if resp.Status == api.StatusSuccess {
+			if ok == nil {
+				ok = &responses[i]
+			}
+			continue
+		}
+		if resp.Code != api.CodeNotFound {
+			warnings = append(warnings, fmt.Sprintf("shard %s: %s", targets[i].addr, resp.Message))
+		}
+	}
+	if ok == nil {
+		return *firstError(targets, responses)
+	}
+	resp := *ok
+	resp.Warnings = append(resp.Warnings, warnings...)
+	return resp
+}
+
+// insert раскладывает документы по шардам ключа
+func (r *Router) insert(sharded *Sharded, req api.Request) api.Response {
+	if len(req.Data) == 0 {
+		return api.Response{Status: api.StatusError, Message: "no data provided for insert"}
+	}
+	docs := make(map[string][]map[string]any)
+	for i, doc := range req.Data {
+		value, ok := doc[sharded.Key]
+		if !ok {
+			return api.Response{Status: api.StatusError, Message: fmt.Sprintf("document %d is missing shard key '%s'", i+1, sharded.Key)}
+		}
+		addr := sharded.shardOf(value)
+		docs[addr] = append(docs[addr], doc)
+	}
+
+	var targets []*shard
+	var responses []api.Response
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, s := range r.shards {
+		if len(docs[s.addr]) == 0 {
+			continue
+		}
+		part := req
+		part.Data = docs[s.addr]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := s.call(part)
+			mu.Lock()
+			targets, responses = append(targets, s), append(responses, resp)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	inserted := 0
+	var warnings []string
+	for _, resp := range responses {
+		if resp.Status == api.StatusSuccess {
+			inserted += resp.Count
+			warnings = append(warnings, resp.Warnings...)
+		}
+	}
+	if failed := firstError(targets, responses); failed != nil {
+		failed.Message = fmt.Sprintf("inserted %d of %d document(s): %s", inserted, len(req.Data), failed.Message)
+		failed.Count = inserted
+		return *failed
+	}
+	return api.Response{
+		Status:   api.StatusSuccess,
+		Message:  fmt.Sprintf("Inserted %d document(s) on %d shard(s)", inserted, len(targets)),
+		Count:    inserted,
+		Warnings: warnings,
+	}
+}
+
+// sum складывает Count (и Matched у update) ответов count, update и delete
+func (r *Router) sum(targets []*shard, req api.Request) api.Response {
+	responses := r.call(targets, req)
+	total, matched := 0, 0
+	var warnings []string
+	for _, resp := range responses {
+		total += resp.Count
+		matched += resp.Matched
+		warnings = append(warnings, resp.Warnings...)
+	}
+	if failed := firstError(targets, responses); failed != nil {
+		if req.Command != api.CmdCount {
+			failed.Message = fmt.Sprintf("%s applied to %d document(s) before the error: %s", req.Command, total, failed.Message)
+		}
+		return *failed
+	}
+	verb := map[string]string{api.CmdCount: "Counted", api.CmdUpdate: "Updated", api.CmdDelete: "Deleted"}[req.Command]
+	return api.Response{
+		Status:   api.StatusSuccess,
+		Message:  fmt.Sprintf("%s %d document(s) on %d shard(s)", verb, total, len(targets)),
+		Count:    total,
+		Matched:  matched,
+		Warnings: warnings,
+	}
+}
+
+// distinct объединяет различные значения шардов
+func (r *Router) distinct(targets []*shard, req api.Request) api.Response {
+	responses := r.call(targets, req)
+	if failed := firstError(targets, responses); failed != nil {
+		return *failed
+	}
+	seen := make(map[string]bool)
+	var values []any
+	for _, resp := range responses {
+		for _, value := range resp.Values {
+			key := string(index.ValueToKey(value))
+			if !seen[key] {
+				seen[key] = true
+				values = append(values, value)
+			}
+		}
+	}
+	slices.SortFunc(values, compareKeys)
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("Found %d distinct value(s) on %d shard(s)", len(values), len(targets)),
+		Values:  values,
+		Count:   len(values),
+	}
+}
+
+// find собирает документы шардов. С сортировкой каждый шард возвращает свои первые
+// limit документов по порядку, и роутер сливает упорядоченные списки. Без сортировки
+// результаты $vectorSearch, $text и $near упорядочиваются по оценке или расстоянию,
+// как на одном сервере, и обрезаются до k и limit
+func (r *Router) find(targets []*shard, req api.Request) api.Response {
+	shardReq := req
+	var added []string
+	if len(req.Sort) > 0 && len(req.Projection) > 0 {
+		// поля сортировки нужны для слияния, даже если проекция их не возвращает
+		shardReq.Projection, added = projectionWithFields(req.Projection, sortFields(req.Sort))
+	}
+	sort, limit := req.Sort, req.Limit
+	if len(sort) == 0 {
+		if rank, ok := rankOf(req.Query); ok {
+			var field string
+			shardReq.Projection, field, added = projectionWithMeta(req.Projection, rank.meta)
+			sort = []string{field}
+			if rank.desc {
+				sort[0] = "-" + field
+			}
+			if rank.k > 0 && (limit <= 0 || rank.k < limit) {
+				limit = rank.k
+			}
+		}
+	}
+	responses := r.call(targets, shardReq)
+	if failed := firstError(targets, responses); failed != nil {
+		return *failed
+	}
+
+	lists := make([][]map[string]any, len(responses))
+	for i, resp := range responses {
+		lists[i] = resp.Data
+	}
+	var results []map[string]any
+	if len(sort) > 0 {
+		results = mergeSorted(lists, sort, limit)
+	} else {
+		for _, list := range lists {
+			results = append(results, list...)
+		}
+		if limit > 0 && len(results) > limit {
+			results = results[:limit]
+		}
+	}
+	for _, doc := range results {
+		for _, field := range added {
+			delete(doc, field)
+		}
+	}
+	return api.Response{Status: api.StatusSuccess, Data: results, Count: len(results)}
+}
+
+// rank — порядок результата запроса без сортировки: по значению $meta
+type rank struct {
+	meta string // вид $meta
+	desc bool   // по убыванию (оценка), иначе по возрастанию (расстояние)
+	k    int    // число результатов $vectorSearch; 0 — без ограничения
+}
+
+// rankOf возвращает порядок, в котором шард отдаёт результат запроса: $vectorSearch,
+// затем $text, затем $near поля верхнего уровня — как в плане запроса на сервере
+func rankOf(conditions map[string]any) (rank, bool) {
+	if spec, ok := conditions[string(query.OpVectorSearch)]; ok {
+		k := handlers.DefaultVectorK
+		if specMap, ok := spec.(map[string]any); ok {
+			if v, ok := specMap["k"].(float64); ok && v >= 1 {
+				k = int(v)
+			}
+		}
+		return rank{meta: query.MetaVectorScore, desc: true, k: k}, true
+	}
+	if _, ok := conditions[string(query.OpText)]; ok {
+		return rank{meta: query.MetaTextScore, desc: true}, true
+	}
+	for _, condition := range conditions {
+		if m, ok := condition.(map[string]any); ok {
+			if _, near := m[string(query.OpNear)]; near {
+				return rank{meta: query.MetaGeoDistance}, true
+			}
+		}
+	}
+	return rank{}, false
+}
+
+// rankField — поле со значением $meta, которое роутер добавляет в проекцию шардов
+const rankField = "_routerRank"
+
+// projectionWithMeta возвращает проекцию с полем значения $meta вида kind, имя этого
+// поля и поля, которые нужно удалить из результата. Поле клиента с тем же $meta
+// используется как есть
+func projectionWithMeta(projection map[string]any, kind string) (map[string]any, string, []string) {
+	for field, flag := range query.MetaFields(projection) {
+		if flag == kind {
+			return projection, field, nil
+		}
+	}
+	out := make(map[string]any, len(projection)+1)
+	for field, flag := range projection {
+		out[field] = flag
+	}
+	out[rankField] = map[string]any{"$meta": kind}
+	return out, rankField, []string{rankField}
+}
+
+func sortFields(sort []string) []string {
+	fields := make([]string, len(sort))
+	for i, spec := range sort {
+		fields[i] = strings.TrimPrefix(spec, "-")
+	}
+	return fields
+}
+
+// projectionWithFields возвращает проекцию, оставляющую fields, и поля, которые
+// клиент не запрашивал и которые нужно удалить из результата
+func projectionWithFields(projection map[string]any, fields []string) (map[string]any, []string) {
+	out := make(map[string]any, len(projection)+len(fields))
+	for field, flag := range projection {
+		out[field] = flag
+	}
+	var added []string
+	inclusion := query.IsInclusionProjection(projection)
+	for _, field := range fields {
+		flag, set := projection[field]
+		if _, meta := flag.(map[string]any); meta {
+			continue
+		}
+		switch {
+		case field == "_id" && !query.IncludesID(projection):
+			delete(out, field)
+			added = append(added, field)
+		case field == "_id":
+		case inclusion && !set:
+			out[field] = 1.0
+			added = append(added, field)
+		case !inclusion && set:
+			delete(out, field)
+			added = append(added, field)
+		}
+	}
+	return out, added
+}
+
+// mergeSorted сливает упорядоченные списки шардов в один, не больше limit документов
+func mergeSorted(lists [][]map[string]any, sort []string, limit int) []map[string]any {
+	var out []map[string]any
+	pos := make([]int, len(lists))
+	for limit <= 0 || len(out) < limit {
+		best := -1
+		for i, list := range lists {
+			if pos[i] == len(list) {
+				continue
+			}
+			if best < 0 || compareDocuments(list[pos[i]], lists[best][pos[best]], sort) < 0 {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		out = append(out, lists[best][pos[best]])
+		pos[best]++
+	}
+	return out
+}
+
+// compareDocuments сравнивает документы по полям сортировки так же, как find:
+// отсутствующее поле меньше любого значения, "-" — по убыванию
+func compareDocuments(a, b map[string]any, sort []string) int {
+	for _, spec := range sort {
+		field, desc := strings.TrimPrefix(spec, "-"), strings.HasPrefix(spec, "-")
+		va, okA := a[field]
+		vb, okB := b[field]
+		var cmp int
+		switch {
+		case !okA && !okB:
+		case !okA:
+			cmp = -1
+		case !okB:
+			cmp = 1
+		default:
+			cmp = compareKeys(va, vb)
+		}
+		if desc {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// listDatabases объединяет базы всех шардов
+func (r *Router) listDatabases() api.Response {
+	responses := r.call(r.shards, api.Request{Command: api.CmdListDatabases})
+	if failed := firstError(r.shards, responses); failed != nil {
+		return *failed
+	}
+	var data []map[string]any
+	for _, resp := range responses {
+		for _, doc := range resp.Data {
+			// запись без имени не с чем объединить: пропускаем её
+			if _, ok := doc["name"].(string); !ok {
+				continue
+			}
+			i := slices.IndexFunc(data, func(d map[string]any) bool { return d["name"] == doc["name"] })
+			if i < 0 {
+				data = append(data, doc)
+				continue
+			}
+			have, _ := data[i]["collections"].(float64)
+			if count, _ := doc["collections"].(float64); count > have {
+				data[i] = doc
+			}
+		}
+	}
+	slices.SortFunc(data, byName)
+	return api.Response{Status: api.StatusSuccess, Message: fmt.Sprintf("Found %d database(s)", len(data)), Data: data, Count: len(data)}
+}
+
+// byName упорядочивает базы и коллекции ответа по имени
+func byName(a, b map[string]any) int {
+	nameA, _ := a["name"].(string)
+	nameB, _ := b["name"].(string)
+	return strings.Compare(nameA, nameB)
+}
+
+// listCollections объединяет коллекции базы на всех шардах и отмечает распределённые
+func (r *Router) listCollections(req api.Request) api.Response {
+	responses := r.call(r.shards, req)
+	if failed := firstError(r.shards, responses); failed != nil {
+		return *failed
+	}
+	var data []map[string]any
+	for _, resp := range responses {
+		for _, doc := range resp.Data {
+			name, ok := doc["name"].(string)
+			if !ok {
+				continue
+			}
+			if !slices.ContainsFunc(data, func(d map[string]any) bool { return d["name"] == name }) {
+				if sharded := r.sharded(storage.Namespace{DB: req.Database, Coll: name}); sharded != nil {
+					doc["shardKey"], doc["strategy"] = sharded.Key, sharded.Strategy
+				}
+				data = append(data, doc)
+			}
+		}
+	}
+	slices.SortFunc(data, byName)
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("Found %d collection(s) in '%s'", len(data), req.Database),
+		Data:    data,
+		Count:   len(data),
+	}
+}
+
+// dropDatabase удаляет базу на всех шардах и забывает её распределённые коллекции
+func (r *Router) dropDatabase(req api.Request) api.Response {
+	if err := storage.ValidateDatabaseName("database", req.Database); err != nil {
+		return api.Response{Status: api.StatusError, Message: err.Error()}
+	}
+	responses := r.call(r.shards, req)
+	dropped, found := 0, false
+	for i, resp := range responses {
+		if resp.Status == api.StatusSuccess {
+			dropped, found = max(dropped, resp.Count), true
+		} else if resp.Code != api.CodeNotFound {
+			return *firstError(r.shards[i:i+1], responses[i:i+1])
+		}
+	}
+	if !found {
+		return responses[0]
+	}
+	r.forget(func(ns storage.Namespace) bool { return ns.DB == req.Database })
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("Database '%s' dropped with %d collection(s)", req.Database, dropped),
+		Count:   dropped,
+	}
+}
+
+// perShard выполняет служебную команду на каждом шарде; документы ответов помечены адресом шарда
+func (r *Router) perShard(req api.Request) api.Response {
+	responses := r.call(r.shards, req)
+	var data []map[string]any
+	for i, resp := range responses {
+		if resp.Status != api.StatusSuccess {
+			data = append(data, map[string]any{"shard": r.shards[i].addr, "error": resp.Message})
+			continue
+		}
+		if len(resp.Data) == 0 {
+			data = append(data, map[string]any{"shard": r.shards[i].addr, "message": resp.Message})
+		}
+		for _, doc := range resp.Data {
+			doc["shard"] = r.shards[i].addr
+			data = append(data, doc)
+		}
+	}
+	return api.Response{
+		Status:  api.StatusSuccess,
+		Message: fmt.Sprintf("%s of %d shard(s)", req.Command, len(r.shards)),
+		Data:    data,
+		Count:   len(data),
+	}
+}
+
+// forget удаляет коллекции из карты и сохраняет её
+func (r *Router) forget(match func(ns storage.Namespace) bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := false
+	for ns := range r.meta.Collections {
+		if match(ns) {
+			delete(r.meta.Collections, ns)
+			changed = true
+		}
+	}
+	if changed {
+		if err := r.meta.save(r.metaPath); err != nil {
+			// карта в памяти уже верна; файл исправит следующее сохранение
+			log.Printf("router: %v", err)
+		}
+	}
+}
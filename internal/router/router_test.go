@@ -0,0 +1,390 @@
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/server"
+	"nosql_db/internal/storage"
+	"nosql_db/internal/testutil"
+)
+
+type testShard struct {
+	srv *server.TCPServer
+	mng *storage.CollectionMng
+}
+
+// startShards запускает n серверов-шардов на свободных портах loopback
+func startShards(t *testing.T, n int) []*testShard {
+	t.Helper()
+	shards := make([]*testShard, n)
+	for i := range shards {
+		mng := testutil.NewManager(t)
+		shards[i] = &testShard{srv: testutil.StartServer(t, mng, nil), mng: mng}
+	}
+	return shards
+}
+
+// startRouter запускает роутер над шардами на свободном порту
+func startRouter(t *testing.T, shards []*testShard) *Router {
+	t.Helper()
+	addrs := make([]string, len(shards))
+	for i, s := range shards {
+		addrs[i] = s.srv.Addr()
+	}
+	r, err := New(addrs, filepath.Join(t.TempDir(), "router.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	go r.Serve()
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// count возвращает число документов коллекции на шарде в обход роутера
+func (s *testShard) count(t *testing.T, coll string) int {
+	t.Helper()
+	c := testutil.Dial(t, s.srv.Addr())
+	return c.OK(api.Request{Command: api.CmdCount, Collection: coll}).Count
+}
+
+func TestHashSharding(t *testing.T) {
+	shards := startShards(t, 3)
+	r := startRouter(t, shards)
+	c := testutil.Dial(t, r.Addr())
+
+	resp := c.OK(api.Request{Command: api.CmdShardCollection, Collection: "users", Options: map[string]any{"key": "user"}})
+	if resp.Count != 3 {
+		t.Fatalf("expected 3 initial chunks, got %d", resp.Count)
+	}
+	var docs []map[string]any
+	for i := 0; i < 300; i++ {
+		docs = append(docs, map[string]any{"user": fmt.Sprintf("user%03d", i), "age": float64(i % 50)})
+	}
+	if resp := c.OK(api.Request{Command: api.CmdInsert, Collection: "users", Data: docs}); resp.Count != 300 {
+		t.Fatalf("expected 300 inserted, got %d", resp.Count)
+	}
+	total := 0
+	for i, s := range shards {
+		n := s.count(t, "users")
+		if n == 0 {
+			t.Fatalf("shard %d received no documents", i)
+		}
+		total += n
+	}
+	if total != 300 {
+		t.Fatalf("shards hold %d documents, expected 300", total)
+	}
+
+	// запрос с ключом уходит одному шарду
+	sharded := r.sharded(storage.Namespace{DB: storage.DefaultDatabase, Coll: "users"})
+	if targets := r.targets(sharded, map[string]any{"user": "user042"}); len(targets) != 1 {
+		t.Fatalf("equality on the shard key should target 1 shard, got %d", len(targets))
+	}
+	found := c.OK(api.Request{Command: api.CmdFind, Collection: "users", Query: map[string]any{"user": "user042"}})
+	if found.Count != 1 || found.Data[0]["age"] != 42.0 {
+		t.Fatalf("targeted find returned %v", found.Data)
+	}
+
+	if resp := c.OK(api.Request{Command: api.CmdCount, Collection: "users", Query: map[string]any{"age": map[string]any{"$lt": 10.0}}}); resp.Count != 60 {
+		t.Fatalf("scatter-gather count: expected 60, got %d", resp.Count)
+	}
+	distinct := c.OK(api.Request{Command: api.CmdDistinct, Collection: "users", Field: "age"})
+	if distinct.Count != 50 || distinct.Values[0] != 0.0 || distinct.Values[49] != 49.0 {
+		t.Fatalf("distinct returned %d value(s): %v", distinct.Count, distinct.Values)
+	}
+
+	// слияние упорядоченных ответов шардов
+	sorted := c.OK(api.Request{Command: api.CmdFind, Collection: "users", Sort: []string{"-age", "user"}, Limit: 7,
+		Projection: map[string]any{"user": 1.0, "_id": 0.0}})
+	want := []string{"user049", "user099", "user149", "user199", "user249", "user299", "user048"}
+	if sorted.Count != len(want) {
+		t.Fatalf("expected %d documents, got %d", len(want), sorted.Count)
+	}
+	for i, doc := range sorted.Data {
+		if doc["user"] != want[i] || len(doc) != 1 {
+			t.Fatalf("document %d: expected only user %s, got %v", i, want[i], doc)
+		}
+	}
+
+	if resp := c.Do(api.Request{Command: api.CmdUpdate, Collection: "users", Query: map[string]any{"user": "user001"},
+		Update: map[string]any{"$set": map[string]any{"user": "other"}}}); resp.Status != api.StatusError {
+		t.Fatal("updating the shard key should fail")
+	}
+	if resp := c.OK(api.Request{Command: api.CmdDelete, Collection: "users", Query: map[string]any{"age": 0.0}}); resp.Count != 6 {
+		t.Fatalf("expected 6 deleted, got %d", resp.Count)
+	}
+}
+
+func TestRangeSplitAndMove(t *testing.T) {
+	shards := startShards(t, 3)
+	r := startRouter(t, shards)
+	c := testutil.Dial(t, r.Addr())
+
+	c.OK(api.Request{Command: api.CmdShardCollection, Collection: "events",
+		Options: map[string]any{"key": "ts", "strategy": "range", "splitPoints": []any{100.0, 200.0}}})
+	var docs []map[string]any
+	for i := 0; i < 300; i++ {
+		docs = append(docs, map[string]any{"ts": float64(i)})
+	}
+	c.OK(api.Request{Command: api.CmdInsert, Collection: "events", Data: docs})
+	for i, s := range shards {
+		if n := s.count(t, "events"); n != 100 {
+			t.Fatalf("shard %d holds %d documents, expected 100", i, n)
+		}
+	}
+	sharded := r.sharded(storage.Namespace{DB: storage.DefaultDatabase, Coll: "events"})
+	if targets := r.targets(sharded, map[string]any{"ts": map[string]any{"$gt": 120.0, "$lt": 180.0}}); len(targets) != 1 {
+		t.Fatalf("range inside one chunk should target 1 shard, got %d", len(targets))
+	}
+
+	// делим первый чанк по медиане и переносим верхнюю половину на третий шард
+	if resp := c.OK(api.Request{Command: api.CmdSplitChunk, Collection: "events", Options: map[string]any{"chunk": 0.0}}); resp.Count != 4 {
+		t.Fatalf("expected 4 chunks after split, got %d", resp.Count)
+	}
+	moved := c.OK(api.Request{Command: api.CmdMoveChunk, Collection: "events",
+		Options: map[string]any{"at": 75.0, "to": shards[2].srv.Addr()}})
+	if moved.Count != 50 {
+		t.Fatalf("expected 50 documents moved, got %d", moved.Count)
+	}
+	for i, want := range []int{50, 100, 150} {
+		if n := shards[i].count(t, "events"); n != want {
+			t.Fatalf("shard %d holds %d documents after move, expected %d", i, n, want)
+		}
+	}
+
+	if resp := c.OK(api.Request{Command: api.CmdCount, Collection: "events"}); resp.Count != 300 {
+		t.Fatalf("expected 300 documents, got %d", resp.Count)
+	}
+	found := c.OK(api.Request{Command: api.CmdFind, Collection: "events", Query: map[string]any{"ts": 60.0}})
+	if found.Count != 1 {
+		t.Fatalf("find of a moved document returned %d document(s)", found.Count)
+	}
+	window := c.OK(api.Request{Command: api.CmdFind, Collection: "events",
+		Query: map[string]any{"ts": map[string]any{"$gt": 45.0, "$lt": 105.0}}, Sort: []string{"ts"}})
+	if window.Count != 59 || window.Data[0]["ts"] != 46.0 || window.Data[58]["ts"] != 104.0 {
+		t.Fatalf("range find across chunks returned %d document(s)", window.Count)
+	}
+
+	// карта переживает перезапуск роутера
+	restarted, err := New(r.shardAddrs(), r.metaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.Close()
+	if got := restarted.sharded(storage.Namespace{DB: storage.DefaultDatabase, Coll: "events"}); len(got.Chunks) != 4 || got.Chunks[1].Shard != shards[2].srv.Addr() {
+		t.Fatalf("reloaded chunk map: %+v", got)
+	}
+}
+
+func TestMoveChunkPendingCleanup(t *testing.T) {
+	shards := startShards(t, 2)
+	r := startRouter(t, shards)
+	c := testutil.Dial(t, r.Addr())
+	ns := storage.Namespace{DB: storage.DefaultDatabase, Coll: "events"}
+
+	c.OK(api.Request{Command: api.CmdShardCollection, Collection: "events",
+		Options: map[string]any{"key": "ts", "strategy": "range", "splitPoints": []any{100.0}}})
+	var docs []map[string]any
+	for i := 0; i < 200; i++ {
+		docs = append(docs, map[string]any{"ts": float64(i)})
+	}
+	c.OK(api.Request{Command: api.CmdInsert, Collection: "events", Data: docs})
+
+	// переносим второй чанк и возвращаем его копии на старый шард, как после неудавшейся очистки
+	from := testutil.Dial(t, shards[1].srv.Addr())
+	copies := from.OK(api.Request{Command: api.CmdFind, Collection: "events"}).Data
+	c.OK(api.Request{Command: api.CmdMoveChunk, Collection: "events", Options: map[string]any{"chunk": 1.0, "to": shards[0].srv.Addr()}})
+	ids := make([]string, len(copies))
+	for i, doc := range copies {
+		ids[i] = doc["_id"].(string)
+	}
+	from.OK(api.Request{Command: api.CmdInsert, Collection: "events", Data: copies, IDs: ids})
+	sharded := r.sharded(ns)
+	sharded.Cleanup = []Cleanup{{Shard: shards[1].srv.Addr(), IDs: ids}}
+	if err := r.update(ns, sharded); err != nil {
+		t.Fatal(err)
+	}
+
+	// запись об очистке хранится в карте и выполняется перед следующим запросом
+	if got := r.sharded(ns); len(got.Cleanup) != 1 {
+		t.Fatalf("pending cleanup: %+v", got.Cleanup)
+	}
+	if resp := c.OK(api.Request{Command: api.CmdCount, Collection: "events"}); resp.Count != 200 {
+		t.Fatalf("count with a pending cleanup returned %d, expected 200", resp.Count)
+	}
+	if n := shards[1].count(t, "events"); n != 0 {
+		t.Fatalf("old shard still holds %d moved document(s)", n)
+	}
+	if got := r.sharded(ns); len(got.Cleanup) != 0 {
+		t.Fatalf("cleanup was not removed from the chunk map: %+v", got.Cleanup)
+	}
+}
+
+func TestDottedNamespaces(t *testing.T) {
+	shards := startShards(t, 2)
+	r := startRouter(t, shards)
+	c := testutil.Dial(t, r.Addr())
+
+	// "a" + "x.y" и "a.x" + "y" — разные коллекции, хотя строкой обе "a.x.y"
+	outer := storage.Namespace{DB: "a", Coll: "x.y"}
+	inner := storage.Namespace{DB: "a.x", Coll: "y"}
+	for ns, key := range map[storage.Namespace]string{outer: "k1", inner: "k2"} {
+		if err := r.update(ns, &Sharded{Key: key, Strategy: StrategyHash,
+			Chunks: []Chunk{{Shard: shards[0].srv.Addr()}}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	restarted, err := New(r.shardAddrs(), r.metaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restarted.Close()
+	for _, router := range []*Router{r, restarted} {
+		if router.sharded(outer).Key != "k1" || router.sharded(inner).Key != "k2" {
+			t.Fatalf("dotted namespaces share metadata: %+v %+v", router.sharded(outer), router.sharded(inner))
+		}
+	}
+
+	// удаление базы "a" не трогает коллекции базы "a.x"
+	c.OK(api.Request{Command: api.CmdInsert, Database: "a", Collection: "z", Data: []map[string]any{{"n": 1.0}}})
+	c.OK(api.Request{Command: api.CmdDropDatabase, Database: "a"})
+	if r.sharded(outer) != nil || r.sharded(inner) == nil {
+		t.Fatalf("drop_database a: outer %+v, inner %+v", r.sharded(outer), r.sharded(inner))
+	}
+}
+
+// fakeShard отвечает на каждый запрос одним и тем же ответом resp
+func fakeShard(t *testing.T, resp api.Response) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				decoder, encoder := json.NewDecoder(conn), json.NewEncoder(conn)
+				for {
+					var req api.Request
+					if decoder.Decode(&req) != nil || encoder.Encode(resp) != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestMalformedShardListing(t *testing.T) {
+	shards := startShards(t, 1)
+	c := testutil.Dial(t, shards[0].srv.Addr())
+	c.OK(api.Request{Command: api.CmdInsert, Collection: "events", Data: []map[string]any{{"n": 1.0}}})
+	bad := fakeShard(t, api.Response{Status: api.StatusSuccess, Data: []map[string]any{
+		{"name": storage.DefaultDatabase, "collections": "many"}, {"collections": 3.0}, {"name": 7.0},
+	}})
+	r, err := New([]string{shards[0].srv.Addr(), bad}, filepath.Join(t.TempDir(), "router.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	// записи без имени или с нечисловым счётчиком не роняют роутер
+	dbs := r.listDatabases()
+	if dbs.Status != api.StatusSuccess || dbs.Count != 1 || dbs.Data[0]["name"] != storage.DefaultDatabase {
+		t.Fatalf("list_databases: %+v", dbs)
+	}
+	colls := r.listCollections(api.Request{Command: api.CmdListCollections, Database: storage.DefaultDatabase})
+	if colls.Status != api.StatusSuccess || colls.Count != 2 {
+		t.Fatalf("list_collections: %+v", colls)
+	}
+}
+
+func TestRankedScatterGather(t *testing.T) {
+	shards := startShards(t, 2)
+	r := startRouter(t, shards)
+	c := testutil.Dial(t, r.Addr())
+
+	// чётные документы — на первом шарде, нечётные — на втором: ближайшие чередуются
+	c.OK(api.Request{Command: api.CmdShardCollection, Collection: "places",
+		Options: map[string]any{"key": "part", "strategy": "range", "splitPoints": []any{1.0}}})
+	c.OK(api.Request{Command: api.CmdCreateIndex, Collection: "places", Query: map[string]any{"vec": 1.0},
+		Options: map[string]any{"type": api.IndexTypeVector, "metric": "l2"}})
+	c.OK(api.Request{Command: api.CmdCreateIndex, Collection: "places", Query: map[string]any{"loc": 1.0},
+		Options: map[string]any{"type": api.IndexTypeGeo}})
+	c.OK(api.Request{Command: api.CmdCreateIndex, Collection: "places", Query: map[string]any{"body": 1.0},
+		Options: map[string]any{"type": api.IndexTypeText}})
+	var docs []map[string]any
+	for i := 0; i < 20; i++ {
+		body := "pear"
+		if i < 6 {
+			body = strings.Repeat("apple ", i+1) + strings.Repeat("pear ", 6-i)
+		}
+		docs = append(docs, map[string]any{
+			"name": fmt.Sprintf("n%02d", i),
+			"part": float64(i % 2),
+			"vec":  []any{float64(i), 1.0},
+			"loc":  []any{float64(i) * 0.01, 0.0},
+			"body": body,
+		})
+	}
+	c.OK(api.Request{Command: api.CmdInsert, Collection: "places", Data: docs})
+
+	names := func(resp api.Response) []string {
+		var out []string
+		for _, doc := range resp.Data {
+			if _, ok := doc[rankField]; ok {
+				t.Fatalf("router rank field leaked into %v", doc)
+			}
+			out = append(out, doc["name"].(string))
+		}
+		return out
+	}
+
+	// k ближайших векторов по всем шардам, а не k с каждого шарда
+	vec := c.OK(api.Request{Command: api.CmdFind, Collection: "places", Query: map[string]any{
+		"$vectorSearch": map[string]any{"path": "vec", "vector": []any{0.0, 1.0}, "k": 3.0}}})
+	if got := names(vec); !slices.Equal(got, []string{"n00", "n01", "n02"}) {
+		t.Fatalf("$vectorSearch returned %v", got)
+	}
+
+	near := c.OK(api.Request{Command: api.CmdFind, Collection: "places", Limit: 4, Query: map[string]any{
+		"loc": map[string]any{"$near": []any{0.0, 0.0}}}})
+	if got := names(near); !slices.Equal(got, []string{"n00", "n01", "n02", "n03"}) {
+		t.Fatalf("$near returned %v", got)
+	}
+
+	// релевантность $text: результат упорядочен по оценке, limit берёт лучшие документы
+	text := c.OK(api.Request{Command: api.CmdFind, Collection: "places",
+		Query:      map[string]any{"$text": map[string]any{"$search": "apple"}},
+		Projection: map[string]any{"name": 1.0, "score": map[string]any{"$meta": "textScore"}}})
+	if text.Count != 6 {
+		t.Fatalf("$text matched %d document(s), expected 6", text.Count)
+	}
+	for i := 1; i < len(text.Data); i++ {
+		if text.Data[i-1]["score"].(float64) < text.Data[i]["score"].(float64) {
+			t.Fatalf("$text results are not ordered by score: %v", text.Data)
+		}
+	}
+	top := c.OK(api.Request{Command: api.CmdFind, Collection: "places", Limit: 2,
+		Query: map[string]any{"$text": map[string]any{"$search": "apple"}}})
+	if got := names(top); !slices.Equal(got, []string{text.Data[0]["name"].(string), text.Data[1]["name"].(string)}) {
+		t.Fatalf("$text with limit returned %v", got)
+	}
+}
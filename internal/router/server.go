@@ -0,0 +1,74 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"nosql_db/internal/api"
+)
+
+// clientTimeout — простой соединения клиента, после которого роутер его закрывает
+const clientTimeout = 60 * time.Second
+
+func (r *Router) Run(addr string) error {
+	if err := r.Listen(addr); err != nil {
+		return err
+	}
+	return r.Serve()
+}
+
+// Listen открывает порт роутера; с адресом ":0" порт выбирает система (см. Addr)
+func (r *Router) Listen(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	r.listener = listener
+	return nil
+}
+
+// Addr возвращает адрес открытого порта
+func (r *Router) Addr() string {
+	return r.listener.Addr().String()
+}
+
+// Serve принимает клиентов на порту, открытом Listen, до вызова Close
+func (r *Router) Serve() error {
+	log.Printf("router running on %s, shards %v", r.listener.Addr(), r.shardAddrs())
+	for {
+		conn, err := r.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
+		if err != nil {
+			log.Printf("conn error: %v", err)
+			continue
+		}
+		go r.handleConnection(conn)
+	}
+}
+
+func (r *Router) handleConnection(conn net.Conn) {
+	defer conn.Close()
+	session := r.NewSession()
+	decoder := json.NewDecoder(conn)
+	encoder := json.NewEncoder(conn)
+	for {
+		_ = conn.SetDeadline(time.Now().Add(clientTimeout))
+		var req api.Request
+		if err := decoder.Decode(&req); err != nil {
+			if err != io.EOF {
+				log.Printf("decode error from %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		if err := encoder.Encode(session.Handle(req)); err != nil {
+			log.Printf("encode error to %s: %v", conn.RemoteAddr(), err)
+			return
+		}
+	}
+}
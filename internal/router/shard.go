@@ -0,0 +1,102 @@
+package router
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"nosql_db/internal/api"
+)
+
+const (
+	shardDialTimeout = 3 * time.Second
+	shardTimeout     = 60 * time.Second
+	maxIdleConns     = 16 // свободных соединений к одному шарду
+	// свободное соединение старше этого не используется: сервер закрывает простаивающие через минуту
+	maxIdleTime = 30 * time.Second
+)
+
+// shardConn — соединение с шардом: запрос и ответ по одному JSON в строке
+type shardConn struct {
+	conn    net.Conn
+	encoder *json.Encoder
+	decoder *json.Decoder
+	used    time.Time
+}
+
+// shard — сервер-шард и пул соединений к нему. Каждый запрос берёт свободное
+// соединение: ответ шарда приходит в том же соединении, что и запрос
+type shard struct {
+	addr string
+
+	mu   sync.Mutex
+	idle []*shardConn
+}
+
+func newShard(addr string) *shard {
+	return &shard{addr: addr}
+}
+
+// call отправляет запрос шарду; ошибка соединения — ответ с ошибкой unavailable
+func (s *shard) call(req api.Request) api.Response {
+	c, err := s.get()
+	if err != nil {
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("shard %s: %v", s.addr, err), Code: api.CodeUnavailable}
+	}
+	_ = c.conn.SetDeadline(time.Now().Add(shardTimeout))
+	var resp api.Response
+	if err := c.encoder.Encode(req); err != nil {
+		c.conn.Close()
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("shard %s: %v", s.addr, err), Code: api.CodeUnavailable}
+	}
+	if err := c.decoder.Decode(&resp); err != nil {
+		c.conn.Close()
+		return api.Response{Status: api.StatusError, Message: fmt.Sprintf("shard %s: %v", s.addr, err), Code: api.CodeUnavailable}
+	}
+	s.put(c)
+	return resp
+}
+
+func (s *shard) get() (*shardConn, error) {
+	s.mu.Lock()
+	for n := len(s.idle); n > 0; n-- {
+		c := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		if time.Since(c.used) < maxIdleTime {
+			s.mu.Unlock()
+			return c, nil
+		}
+		c.conn.Close()
+	}
+	s.mu.Unlock()
+
+	conn, err := net.DialTimeout("tcp", s.addr, shardDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &shardConn{conn: conn, encoder: json.NewEncoder(conn), decoder: json.NewDecoder(bufio.NewReader(conn))}, nil
+}
+
+func (s *shard) put(c *shardConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.idle) >= maxIdleConns {
+		c.conn.Close()
+		return
+	}
+	c.used = time.Now()
+	s.idle = append(s.idle, c)
+}
+
+// close закрывает свободные соединения
+func (s *shard) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.idle {
+		c.conn.Close()
+	}
+	s.idle = nil
+}
@@ -0,0 +1,340 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Подмножество JSON Schema для валидаторов коллекций: type, required, properties,
+// additionalProperties, enum, minimum/maximum, minLength/maxLength, pattern,
+// items, minItems/maxItems. title, description и $schema допускаются и не проверяются
+
+// Schema — разобранная схема
+type Schema struct {
+	types                []string
+	required             []string
+	properties           map[string]*Schema
+	additionalProperties *bool
+	enum                 []any
+	minimum, maximum     *float64
+	minLength, maxLength *int
+	minItems, maxItems   *int
+	pattern              *regexp.Regexp
+	items                *Schema
+}
+
+// Violation — нарушение схемы: путь к полю (address.zip, tags[1]) и описание
+type Violation struct {
+	Path    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+var knownTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// Compile разбирает схему из json-объекта
+func Compile(raw map[string]any) (*Schema, error) {
+	return compile(raw, "")
+}
+
+func compile(raw map[string]any, path string) (*Schema, error) {
+	s := &Schema{}
+	fail := func(format string, args ...any) (*Schema, error) {
+		return nil, fmt.Errorf("invalid schema at %s: %s", rootPath(path), fmt.Sprintf(format, args...))
+	}
+
+	// ключи по порядку, чтобы ошибка разбора не зависела от порядка обхода map
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := raw[key]
+		switch key {
+		case "title", "description", "$schema":
+		case "type":
+			switch t := value.(type) {
+			case string:
+				s.types = []string{t}
+			case []any:
+				for _, item := range t {
+					name, ok := item.(string)
+					if !ok {
+						return fail("type must be a string or an array of strings")
+					}
+					s.types = append(s.types, name)
+				}
+			default:
+				return fail("type must be a string or an array of strings")
+			}
+			for _, name := range s.types {
+				if !knownTypes[name] {
+					return fail("unknown type '%s'", name)
+				}
+			}
+		case "required":
+			list, ok := value.([]any)
+			if !ok {
+				return fail("required must be an array of field names")
+			}
+			for _, item := range list {
+				name, ok := item.(string)
+				if !ok {
+					return fail("required must be an array of field names")
+				}
+				s.required = append(s.required, name)
+			}
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				return fail("properties must be an object")
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for name, sub := range props {
+				subRaw, ok := sub.(map[string]any)
+				if !ok {
+					return fail("property '%s' must be a schema object", name)
+				}
+				compiled, err := compile(subRaw, joinPath(path, name))
+				if err != nil {
+					return nil, err
+				}
+				s.properties[name] = compiled
+			}
+		case "additionalProperties":
+			allowed, ok := value.(bool)
+			if !ok {
+				return fail("additionalProperties must be a boolean")
+			}
+			s.additionalProperties = &allowed
+		case "enum":
+			list, ok := value.([]any)
+			if !ok || len(list) == 0 {
+				return fail("enum must be a non-empty array")
+			}
+			s.enum = list
+		case "minimum", "maximum":
+			n, ok := value.(float64)
+			if !ok {
+				return fail("%s must be a number", key)
+			}
+			if key == "minimum" {
+				s.minimum = &n
+			} else {
+				s.maximum = &n
+			}
+		case "minLength", "maxLength", "minItems", "maxItems":
+			n, ok := value.(float64)
+			if !ok || n < 0 || n != math.Trunc(n) {
+				return fail("%s must be a non-negative integer", key)
+			}
+			limit := int(n)
+			switch key {
+			case "minLength":
+				s.minLength = &limit
+			case "maxLength":
+				s.maxLength = &limit
+			case "minItems":
+				s.minItems = &limit
+			default:
+				s.maxItems = &limit
+			}
+		case "pattern":
+			expr, ok := value.(string)
+			if !ok {
+				return fail("pattern must be a string")
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return fail("invalid pattern: %v", err)
+			}
+			s.pattern = re
+		case "items":
+			itemRaw, ok := value.(map[string]any)
+			if !ok {
+				return fail("items must be a schema object")
+			}
+			compiled, err := compile(itemRaw, path+"[]")
+			if err != nil {
+				return nil, err
+			}
+			s.items = compiled
+		default:
+			return fail("unsupported keyword '%s'", key)
+		}
+	}
+	return s, nil
+}
+
+// Validate проверяет документ и возвращает все нарушения; пустой результат — документ подходит
+func (s *Schema) Validate(doc map[string]any) []Violation {
+	var violations []Violation
+	s.validate(doc, "", &violations)
+	return violations
+}
+
+func (s *Schema) validate(value any, path string, violations *[]Violation) {
+	report := func(format string, args ...any) {
+		*violations = append(*violations, Violation{Path: rootPath(path), Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) > 0 && !s.matchesType(value) {
+		report("expected type %s, got %s", strings.Join(s.types, " or "), typeOf(value))
+		// остальные ключевые слова относятся к ожидаемому типу
+		return
+	}
+	if s.enum != nil && !inEnum(value, s.enum) {
+		report("value %s is not one of the allowed values", formatValue(value))
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range s.required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, Violation{Path: joinPath(path, name), Message: "required field is missing"})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if sub, ok := s.properties[name]; ok {
+				sub.validate(v[name], joinPath(path, name), violations)
+			} else if s.additionalProperties != nil && !*s.additionalProperties && !(path == "" && name == "_id") {
+				*violations = append(*violations, Violation{Path: joinPath(path, name), Message: "additional field is not allowed"})
+			}
+		}
+	case []any:
+		if s.minItems != nil && len(v) < *s.minItems {
+			report("expected at least %d item(s), got %d", *s.minItems, len(v))
+		}
+		if s.maxItems != nil && len(v) > *s.maxItems {
+			report("expected at most %d item(s), got %d", *s.maxItems, len(v))
+		}
+		if s.items != nil {
+			for i, item := range v {
+				s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), violations)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.minLength != nil && length < *s.minLength {
+			report("expected at least %d character(s), got %d", *s.minLength, length)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			report("expected at most %d character(s), got %d", *s.maxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			report("value %q does not match pattern %s", v, s.pattern)
+		}
+	default:
+		if n, ok := toNumber(value); ok {
+			if s.minimum != nil && n < *s.minimum {
+				report("value %v is less than minimum %v", n, *s.minimum)
+			}
+			if s.maximum != nil && n > *s.maximum {
+				report("value %v is greater than maximum %v", n, *s.maximum)
+			}
+		}
+	}
+}
+
+func (s *Schema) matchesType(value any) bool {
+	actual := typeOf(value)
+	for _, t := range s.types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf возвращает тип значения в терминах JSON Schema; целые числа — integer
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		if n, ok := toNumber(v); ok {
+			if n == math.Trunc(n) && !math.IsInf(n, 0) {
+				return "integer"
+			}
+			return "number"
+		}
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func inEnum(value any, enum []any) bool {
+	for _, allowed := range enum {
+		if a, ok := toNumber(allowed); ok {
+			if n, ok := toNumber(value); ok && a == n {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(value, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatValue(value any) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// rootPath обозначает сам документ как (root)
+func rootPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
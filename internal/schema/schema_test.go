@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// usersSchema — схема со всеми ключевыми словами подмножества
+var usersSchema = map[string]any{
+	"type":                 "object",
+	"required":             []any{"name", "age"},
+	"additionalProperties": false,
+	"properties": map[string]any{
+		"name":   map[string]any{"type": "string", "minLength": 2.0, "maxLength": 10.0},
+		"age":    map[string]any{"type": "integer", "minimum": 0.0, "maximum": 150.0},
+		"email":  map[string]any{"type": "string", "pattern": "^[^@]+@[^@]+$"},
+		"role":   map[string]any{"enum": []any{"admin", "user", 1.0}},
+		"score":  map[string]any{"type": []any{"number", "null"}},
+		"active": map[string]any{"type": "boolean"},
+		"tags":   map[string]any{"type": "array", "minItems": 1.0, "maxItems": 3.0, "items": map[string]any{"type": "string"}},
+		"address": map[string]any{
+			"type":       "object",
+			"required":   []any{"city"},
+			"properties": map[string]any{"zip": map[string]any{"type": "string", "pattern": "^[0-9]{6}$"}},
+		},
+	},
+}
+
+// violations возвращает нарушения документа в виде "путь: описание"
+func violations(t *testing.T, s *Schema, doc map[string]any) []string {
+	t.Helper()
+	var found []string
+	for _, v := range s.Validate(doc) {
+		found = append(found, v.String())
+	}
+	return found
+}
+
+func TestValidate(t *testing.T) {
+	s, err := Compile(usersSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := map[string]any{
+		"_id": "u1", "name": "Анна", "age": 30.0, "email": "anna@example.com", "role": 1.0,
+		"score": nil, "active": true, "tags": []any{"a", "b"},
+		"address": map[string]any{"city": "Moscow", "zip": "101000", "street": "Tverskaya"},
+	}
+	if got := violations(t, s, valid); len(got) != 0 {
+		t.Fatalf("valid document: %v", got)
+	}
+
+	for _, tt := range []struct {
+		doc  map[string]any
+		want []string
+	}{
+		{map[string]any{"name": "Ann"}, []string{"age: required field is missing"}},
+		{map[string]any{"name": "Ann", "age": "twenty"}, []string{"age: expected type integer, got string"}},
+		{map[string]any{"name": "Ann", "age": 30.5}, []string{"age: expected type integer, got number"}},
+		{map[string]any{"name": "Ann", "age": -1.0}, []string{"age: value -1 is less than minimum 0"}},
+		{map[string]any{"name": "Ann", "age": 200.0}, []string{"age: value 200 is greater than maximum 150"}},
+		{map[string]any{"name": "A", "age": 1.0}, []string{"name: expected at least 2 character(s), got 1"}},
+		{map[string]any{"name": "Анастасия Петровна", "age": 1.0}, []string{"name: expected at most 10 character(s), got 18"}},
+		{map[string]any{"name": "Ann", "age": 1.0, "email": "no-at"}, []string{`email: value "no-at" does not match pattern ^[^@]+@[^@]+$`}},
+		{map[string]any{"name": "Ann", "age": 1.0, "role": "root"}, []string{`role: value "root" is not one of the allowed values`}},
+		{map[string]any{"name": "Ann", "age": 1.0, "score": "high"}, []string{"score: expected type number or null, got string"}},
+		{map[string]any{"name": "Ann", "age": 1.0, "tags": []any{}}, []string{"tags: expected at least 1 item(s), got 0"}},
+		{map[string]any{"name": "Ann", "age": 1.0, "tags": []any{"a", "b", "c", "d"}}, []string{"tags: expected at most 3 item(s), got 4"}},
+		{map[string]any{"name": "Ann", "age": 1.0, "tags": []any{"a", 2.0}}, []string{"tags[1]: expected type string, got integer"}},
+		{map[string]any{"name": "Ann", "age": 1.0, "address": map[string]any{"zip": "1"}}, []string{
+			"address.city: required field is missing",
+			`address.zip: value "1" does not match pattern ^[0-9]{6}$`,
+		}},
+		{map[string]any{"name": "Ann", "age": 1.0, "nickname": "a"}, []string{"nickname: additional field is not allowed"}},
+		// все нарушения документа, по порядку полей
+		{map[string]any{"age": "x", "active": "yes"}, []string{
+			"name: required field is missing",
+			"active: expected type boolean, got string",
+			"age: expected type integer, got string",
+		}},
+	} {
+		if got := violations(t, s, tt.doc); !slices.Equal(got, tt.want) {
+			t.Errorf("%v: %q, want %q", tt.doc, got, tt.want)
+		}
+	}
+
+	// схема не объекта проверяет сам документ
+	root, err := Compile(map[string]any{"type": "array"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := violations(t, root, map[string]any{}); !slices.Equal(got, []string{"(root): expected type array, got object"}) {
+		t.Fatalf("root violation: %v", got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, tt := range []struct {
+		schema map[string]any
+		want   string
+	}{
+		{map[string]any{"type": "date"}, "invalid schema at (root): unknown type 'date'"},
+		{map[string]any{"type": 1.0}, "type must be a string or an array of strings"},
+		{map[string]any{"required": "name"}, "required must be an array of field names"},
+		{map[string]any{"properties": map[string]any{"age": "integer"}}, "property 'age' must be a schema object"},
+		{map[string]any{"properties": map[string]any{"address": map[string]any{"properties": map[string]any{"zip": map[string]any{"minLength": -1.0}}}}},
+			"invalid schema at address.zip: minLength must be a non-negative integer"},
+		{map[string]any{"items": map[string]any{"pattern": "("}}, "invalid schema at []: invalid pattern"},
+		{map[string]any{"enum": []any{}}, "enum must be a non-empty array"},
+		{map[string]any{"maximum": "10"}, "maximum must be a number"},
+		{map[string]any{"additionalProperties": map[string]any{}}, "additionalProperties must be a boolean"},
+		{map[string]any{"format": "email"}, "unsupported keyword 'format'"},
+	} {
+		_, err := Compile(tt.schema)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: %v, want %q", tt.schema, err, tt.want)
+		}
+	}
+	if _, err := Compile(map[string]any{"title": "users", "description": "d", "$schema": "x"}); err != nil {
+		t.Fatalf("annotations must be accepted: %v", err)
+	}
+}
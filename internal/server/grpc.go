@@ -0,0 +1,409 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"nosql_db/internal/api"
+	"nosql_db/internal/grpcapi"
+	"nosql_db/internal/handlers"
+	"nosql_db/internal/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// GRPCServer — сервис gRPC (proto/nosqldb.proto) поверх тех же обработчиков, что и TCP:
+// унарный метод выполняет свою команду в отдельной сессии, Tail и Watch — потоковые
+// запросы сессии, Session — сессия с базой и транзакциями, как соединение TCP
+type GRPCServer struct {
+	Manager *storage.CollectionMng // коллекции, с которыми работают запросы
+	Node    handlers.Node          // роль узла (реплика, кластер); nil — одиночный сервер
+	Address string
+
+	server   *grpc.Server
+	listener net.Listener
+}
+
+func NewGRPC(address string) *GRPCServer {
+	return &GRPCServer{
+		Manager: storage.GlobalManager,
+		Address: address,
+	}
+}
+
+func (s *GRPCServer) Run() error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Listen открывает порт; с адресом ":0" порт выбирает система (см. Addr)
+func (s *GRPCServer) Listen() error {
+	listener, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.server = grpc.NewServer()
+	grpcapi.RegisterNoSQLdbServer(s.server, &grpcService{s: s})
+	return nil
+}
+
+// Addr возвращает адрес открытого порта
+func (s *GRPCServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close закрывает порт и прерывает вызовы: Serve возвращается
+func (s *GRPCServer) Close() error {
+	s.server.Stop()
+	return nil
+}
+
+// Serve принимает вызовы на порту, открытом Listen, до вызова Close
+func (s *GRPCServer) Serve() error {
+	log.Printf("grpc server running on %s", s.listener.Addr())
+	if err := s.server.Serve(s.listener); !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// порция ответа Find: не больше findBatchSize документов и, кроме первого документа
+// порции, не больше findBatchBytes в protobuf — с запасом до 4 МиБ, которые клиент
+// gRPC принимает по умолчанию
+const (
+	findBatchSize  = 1000
+	findBatchBytes = 1 << 20
+)
+
+// grpcService — методы сервиса NoSQLdb
+type grpcService struct {
+	grpcapi.UnimplementedNoSQLdbServer
+	s *GRPCServer
+}
+
+func (g *grpcService) Insert(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdInsert, in)
+}
+
+// Find отправляет найденные документы порциями, чтобы большая выборка не упиралась
+// в ограничение размера сообщения gRPC; пустой результат — один ответ без документов
+func (g *grpcService) Find(in *grpcapi.Request, stream grpcapi.NoSQLdb_FindServer) error {
+	resp, err := g.run(api.CmdFind, in)
+	if err != nil {
+		return err
+	}
+	out := &grpcapi.Response{Status: resp.Status, Message: resp.Message, Warnings: resp.Warnings}
+	size := 0
+	flush := func() error {
+		out.Count = int64(len(out.Data))
+		if err := stream.Send(out); err != nil {
+			return err
+		}
+		out, size = &grpcapi.Response{Status: resp.Status}, 0
+		return nil
+	}
+	for _, doc := range resp.Data {
+		s, err := toStruct(doc)
+		if err != nil {
+			return err
+		}
+		docSize := proto.Size(s)
+		if len(out.Data) > 0 && (len(out.Data) == findBatchSize || size+docSize > findBatchBytes) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		out.Data = append(out.Data, s)
+		size += docSize
+	}
+	return flush()
+}
+
+func (g *grpcService) Update(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdUpdate, in)
+}
+
+func (g *grpcService) Delete(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdDelete, in)
+}
+
+func (g *grpcService) Count(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdCount, in)
+}
+
+func (g *grpcService) Distinct(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdDistinct, in)
+}
+
+func (g *grpcService) CreateIndex(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdCreateIndex, in)
+}
+
+func (g *grpcService) ListIndexes(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdListIndexes, in)
+}
+
+func (g *grpcService) CreateCollection(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdCreateCollection, in)
+}
+
+func (g *grpcService) DropCollection(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdDropCollection, in)
+}
+
+func (g *grpcService) RenameCollection(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdRenameCollection, in)
+}
+
+func (g *grpcService) CollMod(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdCollMod, in)
+}
+
+func (g *grpcService) ListDatabases(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdListDatabases, in)
+}
+
+func (g *grpcService) ListCollections(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdListCollections, in)
+}
+
+func (g *grpcService) DropDatabase(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdDropDatabase, in)
+}
+
+func (g *grpcService) Stats(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdStats, in)
+}
+
+func (g *grpcService) ReplStatus(_ context.Context, in *grpcapi.Request) (*grpcapi.Response, error) {
+	return g.unary(api.CmdReplStatus, in)
+}
+
+// unary выполняет команду command и отвечает одним сообщением
+func (g *grpcService) unary(command string, in *grpcapi.Request) (*grpcapi.Response, error) {
+	resp, err := g.run(command, in)
+	if err != nil {
+		return nil, err
+	}
+	return toProto(resp)
+}
+
+// run выполняет команду command в отдельной сессии; ошибка команды — статус gRPC
+func (g *grpcService) run(command string, in *grpcapi.Request) (api.Response, error) {
+	req := fromProto(in)
+	req.Command = command
+	if handlers.IsStreaming(req) {
+		return api.Response{}, status.Error(codes.InvalidArgument, "tailable find is served by the Tail method")
+	}
+	session := handlers.NewSession(g.s.Manager, g.s.Node)
+	resp := session.Handle(req)
+	session.Close()
+	if resp.Status != api.StatusSuccess {
+		return api.Response{}, responseError(resp)
+	}
+	return resp, nil
+}
+
+func (g *grpcService) Tail(in *grpcapi.Request, stream grpcapi.NoSQLdb_TailServer) error {
+	req := fromProto(in)
+	req.Command = api.CmdFind
+	if req.Options == nil {
+		req.Options = map[string]any{}
+	}
+	req.Options["tailable"] = true
+	return g.stream(req, stream)
+}
+
+func (g *grpcService) Watch(in *grpcapi.Request, stream grpcapi.NoSQLdb_WatchServer) error {
+	req := fromProto(in)
+	req.Command = api.CmdWatch
+	return g.stream(req, stream)
+}
+
+// stream отправляет ответы потокового запроса, пока клиент не закроет поток;
+// ответ с ошибкой (коллекция удалена, токен устарел) завершает поток статусом gRPC
+func (g *grpcService) stream(req api.Request, stream grpc.ServerStream) error {
+	session := handlers.NewSession(g.s.Manager, g.s.Node)
+	defer session.Close()
+	send := func(resp api.Response) error {
+		if resp.Status != api.StatusSuccess {
+			return responseError(resp)
+		}
+		out, err := toProto(resp)
+		if err != nil {
+			return err
+		}
+		return stream.SendMsg(out)
+	}
+	err := session.Stream(req, send, stream.Context().Done())
+	if stream.Context().Err() != nil {
+		// клиент закрыл поток: последний ответ курсора ему уже не нужен
+		return nil
+	}
+	return err
+}
+
+func (g *grpcService) Session(stream grpcapi.NoSQLdb_SessionServer) error {
+	// незафиксированная транзакция отбрасывается при закрытии потока
+	session := handlers.NewSession(g.s.Manager, g.s.Node)
+	defer session.Close()
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		req := fromProto(in)
+		var resp api.Response
+		if handlers.IsStreaming(req) || req.Command == api.CmdReplicate {
+			resp = api.Response{Status: api.StatusError, Message: req.Command + " is served by the Tail and Watch methods"}
+		} else {
+			resp = session.Handle(req)
+		}
+		out, err := toProto(resp)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(out); err != nil {
+			return err
+		}
+	}
+}
+
+// responseError переводит ответ с ошибкой в статус gRPC по коду ошибки. Сам ответ
+// прикладывается к статусу (details): в нём остаются code, адрес лидера и предупреждения схемы
+func responseError(resp api.Response) error {
+	code := codes.InvalidArgument
+	switch resp.Code {
+	case api.CodeNotFound:
+		code = codes.NotFound
+	case api.CodeAlreadyExists:
+		code = codes.AlreadyExists
+	case api.CodeNotWritable, api.CodeUnavailable:
+		code = codes.Unavailable
+	case api.CodeTimeout:
+		code = codes.DeadlineExceeded
+	case api.CodeInternal, api.CodeNotDurable:
+		code = codes.Internal
+	}
+	st := status.New(code, resp.Message)
+	out, err := toProto(resp)
+	if err != nil {
+		return st.Err()
+	}
+	if detailed, err := st.WithDetails(out); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
+
+func fromProto(in *grpcapi.Request) api.Request {
+	req := api.Request{
+		Database:   in.GetDatabase(),
+		Collection: in.GetCollection(),
+		Command:    in.GetOperation(),
+		Query:      structMap(in.GetQuery()),
+		Update:     structMap(in.GetUpdate()),
+		Projection: structMap(in.GetProjection()),
+		Field:      in.GetField(),
+		Sort:       in.GetSort(),
+		Limit:      int(in.GetLimit()),
+		Options:    structMap(in.GetOptions()),
+		Durability: in.GetDurability(),
+	}
+	for _, doc := range in.GetData() {
+		req.Data = append(req.Data, doc.AsMap())
+	}
+	return req
+}
+
+func structMap(s *structpb.Struct) map[string]any {
+	if s == nil {
+		return nil
+	}
+	return s.AsMap()
+}
+
+// toProto переводит ответ в сообщение; документы проходят через JSON, как в протоколе TCP
+func toProto(resp api.Response) (*grpcapi.Response, error) {
+	out := &grpcapi.Response{
+		Status:      resp.Status,
+		Message:     resp.Message,
+		Count:       int64(resp.Count),
+		Matched:     int64(resp.Matched),
+		Warnings:    resp.Warnings,
+		ResumeToken: resp.ResumeToken,
+		Tailable:    resp.Tailable,
+		Leader:      resp.Leader,
+		Code:        resp.Code,
+	}
+	for _, doc := range resp.Data {
+		s, err := toStruct(doc)
+		if err != nil {
+			return nil, err
+		}
+		out.Data = append(out.Data, s)
+	}
+	for _, value := range resp.Values {
+		v := &structpb.Value{}
+		if err := unmarshalJSON(value, v); err != nil {
+			return nil, err
+		}
+		out.Values = append(out.Values, v)
+	}
+	for _, event := range resp.Events {
+		e := &grpcapi.ChangeEvent{
+			Token:         event.Token,
+			Operation:     event.Operation,
+			Database:      event.Database,
+			Collection:    event.Collection,
+			Id:            event.ID,
+			RemovedFields: event.RemovedFields,
+		}
+		var err error
+		if e.Document, err = toStruct(event.Document); err != nil {
+			return nil, err
+		}
+		if e.UpdatedFields, err = toStruct(event.UpdatedFields); err != nil {
+			return nil, err
+		}
+		out.Events = append(out.Events, e)
+	}
+	return out, nil
+}
+
+func toStruct(doc map[string]any) (*structpb.Struct, error) {
+	if doc == nil {
+		return nil, nil
+	}
+	s := &structpb.Struct{}
+	if err := unmarshalJSON(doc, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// unmarshalJSON переносит значение в сообщение structpb через его JSON
+func unmarshalJSON(value any, msg interface{ UnmarshalJSON([]byte) error }) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return status.Error(codes.Internal, fmt.Sprintf("encode response: %v", err))
+	}
+	if err := msg.UnmarshalJSON(data); err != nil {
+		return status.Error(codes.Internal, fmt.Sprintf("encode response: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/grpcapi"
+	"nosql_db/internal/storage"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func doc(t *testing.T, m map[string]any) *structpb.Struct {
+	t.Helper()
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// findStream читает все порции ответа Find; ошибка команды приходит статусом потока
+func findStream(ctx context.Context, client grpcapi.NoSQLdbClient, req *grpcapi.Request) ([][]*structpb.Struct, error) {
+	stream, err := client.Find(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var batches [][]*structpb.Struct
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return batches, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.Count != int64(len(resp.Data)) {
+			return nil, fmt.Errorf("batch count %d, got %d document(s)", resp.Count, len(resp.Data))
+		}
+		batches = append(batches, resp.Data)
+	}
+}
+
+// findAll — findStream, которому ошибка не нужна
+func findAll(t *testing.T, ctx context.Context, client grpcapi.NoSQLdbClient, req *grpcapi.Request) [][]*structpb.Struct {
+	t.Helper()
+	batches, err := findStream(ctx, client, req)
+	if err != nil {
+		t.Fatalf("find %v: %v", req.Query, err)
+	}
+	return batches
+}
+
+// startGRPC запускает сервис gRPC на порту loopback и возвращает клиента к нему
+func startGRPC(t *testing.T) (grpcapi.NoSQLdbClient, context.Context) {
+	t.Helper()
+	mng := storage.NewManager()
+	if err := mng.OpenDataDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mng.Stop)
+	srv := NewGRPC("127.0.0.1:0")
+	srv.Manager = mng
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+
+	conn, err := grpc.NewClient(srv.Addr(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return grpcapi.NewNoSQLdbClient(conn), ctx
+}
+
+func TestGRPCService(t *testing.T) {
+	client, ctx := startGRPC(t)
+
+	// унарные команды и коды ошибок
+	if _, err := client.CreateCollection(ctx, &grpcapi.Request{Collection: "logs",
+		Options: doc(t, map[string]any{"capped": true, "max": 100})}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.CreateCollection(ctx, &grpcapi.Request{Collection: "logs"})
+	if status.Code(err) != codes.AlreadyExists {
+		t.Fatalf("expected AlreadyExists, got %v", err)
+	}
+	// к статусу приложен сам ответ с кодом ошибки
+	if details := status.Convert(err).Details(); len(details) != 1 {
+		t.Fatalf("expected the response in status details, got %v", details)
+	} else if resp, ok := details[0].(*grpcapi.Response); !ok || resp.Status != api.StatusError || resp.Code != api.CodeAlreadyExists {
+		t.Fatalf("status details: %v", details[0])
+	}
+	if _, err := client.DropCollection(ctx, &grpcapi.Request{Collection: "missing"}); status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+	inserted, err := client.Insert(ctx, &grpcapi.Request{Collection: "logs", Data: []*structpb.Struct{
+		doc(t, map[string]any{"level": "info", "n": 1}), doc(t, map[string]any{"level": "error", "n": 2}),
+	}})
+	if err != nil || inserted.Count != 2 {
+		t.Fatalf("insert: %v %v", inserted, err)
+	}
+	if found := findAll(t, ctx, client, &grpcapi.Request{Collection: "logs", Query: doc(t, map[string]any{"level": "error"})}); len(found) != 1 || found[0][0].AsMap()["n"] != 2.0 {
+		t.Fatalf("find: %v", found)
+	}
+	badQuery := doc(t, map[string]any{"n": map[string]any{"$text": map[string]any{"$search": "x"}}})
+	if _, err := findStream(ctx, client, &grpcapi.Request{Collection: "logs", Query: badQuery}); status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument from Find, got %v", err)
+	}
+	distinct, err := client.Distinct(ctx, &grpcapi.Request{Collection: "logs", Field: "level"})
+	if err != nil || len(distinct.Values) != 2 {
+		t.Fatalf("distinct: %v %v", distinct, err)
+	}
+
+	// Tail: текущие документы, затем новые
+	tailCtx, stopTail := context.WithCancel(ctx)
+	tail, err := client.Tail(tailCtx, &grpcapi.Request{Collection: "logs", Query: doc(t, map[string]any{"level": "error"})})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, err := tail.Recv(); err != nil || !first.Tailable || len(first.Data) != 1 {
+		t.Fatalf("first tail response: %v %v", first, err)
+	}
+	watch, err := client.Watch(ctx, &grpcapi.Request{Collection: "logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := watch.Recv(); err != nil || opened.ResumeToken == "" {
+		t.Fatalf("watch opened: %v %v", opened, err)
+	}
+
+	// Session: транзакция как в соединении TCP
+	session, err := client.Session(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, req := range []*grpcapi.Request{
+		{Operation: "begin"},
+		{Operation: "insert", Collection: "logs", Data: []*structpb.Struct{doc(t, map[string]any{"level": "error", "n": 3})}},
+		{Operation: "commit"},
+	} {
+		if err := session.Send(req); err != nil {
+			t.Fatal(err)
+		}
+		resp, err := session.Recv()
+		if err != nil || resp.Status != "success" {
+			t.Fatalf("%s: %v %v", req.Operation, resp, err)
+		}
+	}
+	session.CloseSend()
+
+	next, err := tail.Recv()
+	if err != nil || len(next.Data) != 1 || next.Data[0].AsMap()["n"] != 3.0 {
+		t.Fatalf("tailed document: %v %v", next, err)
+	}
+	stopTail()
+	event, err := watch.Recv()
+	if err != nil || len(event.Events) != 1 || event.Events[0].Operation != "insert" {
+		t.Fatalf("watch event: %v %v", event, err)
+	}
+}
+
+func TestGRPCFindBatches(t *testing.T) {
+	client, ctx := startGRPC(t)
+
+	insert := func(coll string, docs []*structpb.Struct) {
+		t.Helper()
+		if _, err := client.Insert(ctx, &grpcapi.Request{Collection: coll, Data: docs}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sizes := func(batches [][]*structpb.Struct) []int {
+		var n []int
+		for _, batch := range batches {
+			n = append(n, len(batch))
+		}
+		return n
+	}
+
+	// порции по числу документов
+	small := make([]*structpb.Struct, 2100)
+	for i := range small {
+		small[i] = doc(t, map[string]any{"n": i})
+	}
+	insert("small", small)
+	if got := sizes(findAll(t, ctx, client, &grpcapi.Request{Collection: "small"})); !slices.Equal(got, []int{1000, 1000, 100}) {
+		t.Fatalf("small documents: batches %v", got)
+	}
+
+	// порции по размеру: три документа по 600 КиБ вместе больше лимита клиента в 4 МиБ не дают,
+	// но и в одну порцию не помещаются
+	pad := strings.Repeat("x", 600<<10)
+	insert("large", []*structpb.Struct{doc(t, map[string]any{"pad": pad}), doc(t, map[string]any{"pad": pad}), doc(t, map[string]any{"pad": pad})})
+	if got := sizes(findAll(t, ctx, client, &grpcapi.Request{Collection: "large"})); !slices.Equal(got, []int{1, 1, 1}) {
+		t.Fatalf("large documents: batches %v", got)
+	}
+
+	if got := sizes(findAll(t, ctx, client, &grpcapi.Request{Collection: "small", Query: doc(t, map[string]any{"n": -1})})); !slices.Equal(got, []int{0}) {
+		t.Fatalf("empty result: batches %v", got)
+	}
+}
@@ -0,0 +1,328 @@
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"nosql_db/internal/api"
+	"nosql_db/internal/handlers"
+	"nosql_db/internal/query"
+	"nosql_db/internal/storage"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// openAPISpec — описание REST API, отдаётся по GET /openapi.json
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// maxBodyBytes — наибольшее тело HTTP-запроса
+const maxBodyBytes = 64 << 20
+
+// HTTPServer — REST-шлюз: HTTP-запрос переводится в api.Request и выполняется теми же
+// обработчиками, что и запросы TCP, в отдельной сессии (транзакции и потоки — только по TCP)
+type HTTPServer struct {
+	Manager *storage.CollectionMng // коллекции, с которыми работают запросы
+	Node    handlers.Node          // роль узла (реплика, кластер); nil — одиночный сервер
+	Address string
+	Timeout int
+
+	server   *http.Server
+	listener net.Listener
+}
+
+func NewHTTP(address string) *HTTPServer {
+	return &HTTPServer{
+		Manager: storage.GlobalManager,
+		Address: address,
+		Timeout: 60,
+	}
+}
+
+func (s *HTTPServer) Run() error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Listen открывает порт; с адресом ":0" порт выбирает система (см. Addr)
+func (s *HTTPServer) Listen() error {
+	listener, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return err
+	}
+	timeout := time.Duration(s.Timeout) * time.Second
+	s.listener = listener
+	s.server = &http.Server{Handler: s.Handler(), ReadTimeout: timeout, WriteTimeout: timeout}
+	return nil
+}
+
+// Addr возвращает адрес открытого порта
+func (s *HTTPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close закрывает порт и соединения: Serve возвращается
+func (s *HTTPServer) Close() error {
+	return s.server.Close()
+}
+
+// Serve принимает запросы на порту, открытом Listen, до вызова Close
+func (s *HTTPServer) Serve() error {
+	log.Printf("http server running on %s", s.listener.Addr())
+	if err := s.server.Serve(s.listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Handler возвращает маршруты REST API. База — параметр database (по умолчанию default),
+// ответ — api.Response в JSON с кодом HTTP по его результату
+func (s *HTTPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPISpec)
+	})
+	mux.HandleFunc("GET /db", s.handle(api.CmdListCollections, nil, http.StatusOK))
+	mux.HandleFunc("POST /db/{coll}", s.handle(api.CmdCreateCollection, collectionOptions, http.StatusCreated))
+	mux.HandleFunc("DELETE /db/{coll}", s.handle(api.CmdDropCollection, nil, http.StatusOK))
+
+	mux.HandleFunc("GET /db/{coll}/docs", s.handle(api.CmdFind, findParams, http.StatusOK))
+	mux.HandleFunc("POST /db/{coll}/docs", s.handle(api.CmdInsert, insertBody, http.StatusCreated))
+	mux.HandleFunc("PATCH /db/{coll}/docs", s.handle(api.CmdUpdate, updateParams, http.StatusOK))
+	mux.HandleFunc("DELETE /db/{coll}/docs", s.handle(api.CmdDelete, deleteParams, http.StatusOK))
+	mux.HandleFunc("GET /db/{coll}/docs/{id}", s.handle(api.CmdFind, byID(projectionParam), http.StatusOK))
+	mux.HandleFunc("PATCH /db/{coll}/docs/{id}", s.handle(api.CmdUpdate, byID(updateBody), http.StatusOK))
+	mux.HandleFunc("DELETE /db/{coll}/docs/{id}", s.handle(api.CmdDelete, byID(nil), http.StatusOK))
+	mux.HandleFunc("GET /db/{coll}/count", s.handle(api.CmdCount, filterParam, http.StatusOK))
+
+	mux.HandleFunc("GET /db/{coll}/indexes", s.handle(api.CmdListIndexes, nil, http.StatusOK))
+	mux.HandleFunc("POST /db/{coll}/indexes", s.handle(api.CmdCreateIndex, indexBody, http.StatusCreated))
+	return mux
+}
+
+// builder заполняет запрос параметрами и телом HTTP-запроса; ошибка — ответ 400
+type builder func(r *http.Request, req *api.Request) error
+
+// handle возвращает обработчик маршрута команды command; success — код успешного ответа
+func (s *HTTPServer) handle(command string, build builder, success int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		req := api.Request{
+			Command:    command,
+			Database:   params.Get("database"),
+			Collection: r.PathValue("coll"),
+			Durability: params.Get("durability"),
+		}
+		if build != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+			if err := build(r, &req); err != nil {
+				writeResponse(w, http.StatusBadRequest, api.Response{Status: api.StatusError, Message: err.Error()})
+				return
+			}
+		}
+
+		session := handlers.NewSession(s.Manager, s.Node)
+		resp := session.Handle(req)
+		session.Close()
+
+		status := httpStatus(resp, success)
+		if id := r.PathValue("id"); id != "" && resp.Status == api.StatusSuccess && matched(command, resp) == 0 {
+			status = http.StatusNotFound
+			resp = api.Response{Status: api.StatusError, Code: api.CodeNotFound, Message: fmt.Sprintf("document '%s' not found", id)}
+		}
+		writeResponse(w, status, resp)
+	}
+}
+
+// matched — число документов под условием запроса: у update Count считает только
+// изменённые, и $set, не меняющий документ, не должен давать 404
+func matched(command string, resp api.Response) int {
+	if command == api.CmdUpdate {
+		return resp.Matched
+	}
+	return resp.Count
+}
+
+// httpStatus выбирает код HTTP по коду ошибки ответа (api.Code*): ошибка без кода —
+// неверный запрос, сбои сервера — 5xx
+func httpStatus(resp api.Response, success int) int {
+	if resp.Status == api.StatusSuccess {
+		return success
+	}
+	switch resp.Code {
+	case api.CodeNotFound:
+		return http.StatusNotFound
+	case api.CodeAlreadyExists:
+		return http.StatusConflict
+	case api.CodeValidation:
+		return http.StatusUnprocessableEntity
+	case api.CodeNotWritable, api.CodeUnavailable:
+		// запись на последователе кластера или реплике: адрес лидера — в поле leader
+		return http.StatusServiceUnavailable
+	case api.CodeTimeout:
+		return http.StatusGatewayTimeout
+	case api.CodeInternal, api.CodeNotDurable:
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+func writeResponse(w http.ResponseWriter, status int, resp api.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("http: encode response: %v", err)
+	}
+}
+
+// decodeBody читает тело запроса как JSON
+func decodeBody(r *http.Request, v any) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("request body is empty")
+		}
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// filterParam — условия запроса из параметра filter (JSON), как в FIND клиента
+func filterParam(r *http.Request, req *api.Request) error {
+	q, err := query.Parse(r.URL.Query().Get("filter"))
+	if err != nil {
+		return fmt.Errorf("filter: %v", err)
+	}
+	req.Query = q.Conditions
+	return nil
+}
+
+// projectionParam — проекция из параметра projection (JSON)
+func projectionParam(r *http.Request, req *api.Request) error {
+	if value := r.URL.Query().Get("projection"); value != "" {
+		projection, err := query.ParseDocument(value)
+		if err != nil {
+			return fmt.Errorf("projection: %v", err)
+		}
+		req.Projection = projection
+	}
+	return nil
+}
+
+// findParams — filter, projection, sort (поля через запятую, "-" — по убыванию) и limit
+func findParams(r *http.Request, req *api.Request) error {
+	if err := filterParam(r, req); err != nil {
+		return err
+	}
+	if err := projectionParam(r, req); err != nil {
+		return err
+	}
+	params := r.URL.Query()
+	if value := params.Get("sort"); value != "" {
+		req.Sort = strings.Split(value, ",")
+	}
+	if value := params.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("limit must be a non-negative integer")
+		}
+		req.Limit = limit
+	}
+	return nil
+}
+
+// insertBody — документ или массив документов
+func insertBody(r *http.Request, req *api.Request) error {
+	var body any
+	if err := decodeBody(r, &body); err != nil {
+		return err
+	}
+	switch v := body.(type) {
+	case map[string]any:
+		req.Data = []map[string]any{v}
+	case []any:
+		for i, item := range v {
+			doc, ok := item.(map[string]any)
+			if !ok {
+				return fmt.Errorf("document %d is not a JSON object", i+1)
+			}
+			req.Data = append(req.Data, doc)
+		}
+	default:
+		return fmt.Errorf("body must be a JSON object or an array of objects")
+	}
+	return nil
+}
+
+// updateBody — изменения ($set, $inc, ...) в теле запроса
+func updateBody(r *http.Request, req *api.Request) error {
+	return decodeBody(r, &req.Update)
+}
+
+// updateParams требует filter, как deleteParams: обновление всех документов — явный filter={}
+func updateParams(r *http.Request, req *api.Request) error {
+	if !r.URL.Query().Has("filter") {
+		return fmt.Errorf("filter is required; use filter={} to update all documents")
+	}
+	if err := filterParam(r, req); err != nil {
+		return err
+	}
+	return updateBody(r, req)
+}
+
+// deleteParams требует filter: удаление всех документов — явный filter={}
+func deleteParams(r *http.Request, req *api.Request) error {
+	if !r.URL.Query().Has("filter") {
+		return fmt.Errorf("filter is required; use filter={} to delete all documents")
+	}
+	return filterParam(r, req)
+}
+
+// byID выбирает документ по _id из пути и дополняет запрос построителем build
+func byID(build builder) builder {
+	return func(r *http.Request, req *api.Request) error {
+		req.Query = map[string]any{"_id": r.PathValue("id")}
+		if build == nil {
+			return nil
+		}
+		return build(r, req)
+	}
+}
+
+// collectionOptions — параметры create_collection в теле (может быть пустым)
+func collectionOptions(r *http.Request, req *api.Request) error {
+	if err := json.NewDecoder(r.Body).Decode(&req.Options); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid JSON body: %v", err)
+	}
+	return nil
+}
+
+// indexBody — {"fields": [...], "type": ..., ...}: поля индекса и параметры create_index
+func indexBody(r *http.Request, req *api.Request) error {
+	if err := decodeBody(r, &req.Options); err != nil {
+		return err
+	}
+	fields, _ := req.Options["fields"].([]any)
+	if len(fields) == 0 {
+		return fmt.Errorf("fields must be a non-empty array of field names")
+	}
+	req.Query = make(map[string]any, len(fields))
+	for _, field := range fields {
+		name, ok := field.(string)
+		if !ok || name == "" {
+			return fmt.Errorf("fields must be a non-empty array of field names")
+		}
+		req.Query[name] = nil
+	}
+	delete(req.Options, "fields")
+	return nil
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/handlers"
+	"nosql_db/internal/storage"
+)
+
+type restClient struct {
+	t    *testing.T
+	base string
+}
+
+// do отправляет запрос REST-шлюзу и проверяет код ответа
+func (c *restClient) do(method, path, body string, status int) api.Response {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.base+path, strings.NewReader(body))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	defer httpResp.Body.Close()
+	var resp api.Response
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		c.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	if httpResp.StatusCode != status {
+		c.t.Fatalf("%s %s: expected %d, got %d (%s)", method, path, status, httpResp.StatusCode, resp.Message)
+	}
+	return resp
+}
+
+func TestHTTPGateway(t *testing.T) {
+	mng := storage.NewManager()
+	if err := mng.OpenDataDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer mng.Stop()
+	srv := NewHTTP("127.0.0.1:0")
+	srv.Manager = mng
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+	c := &restClient{t: t, base: ts.URL}
+
+	c.do("POST", "/db/users?database=shop", `{"validator": {"required": ["name"]}}`, http.StatusCreated)
+	if resp := c.do("POST", "/db/users?database=shop", "", http.StatusConflict); resp.Code != api.CodeAlreadyExists {
+		t.Fatalf("expected code %s, got %q", api.CodeAlreadyExists, resp.Code)
+	}
+	c.do("POST", "/db/users/indexes?database=shop", `{"fields": ["age"]}`, http.StatusCreated)
+	c.do("POST", "/db/users/indexes?database=shop", `{"fields": []}`, http.StatusBadRequest)
+	if resp := c.do("GET", "/db/users/indexes?database=shop", "", http.StatusOK); resp.Count != 1 || resp.Data[0]["type"] != "btree" {
+		t.Fatalf("indexes: %v", resp.Data)
+	}
+
+	inserted := c.do("POST", "/db/users/docs?database=shop",
+		`[{"name": "ann", "age": 31}, {"name": "bob", "age": 25}, {"name": "eve", "age": 40}]`, http.StatusCreated)
+	if inserted.Count != 3 {
+		t.Fatalf("expected 3 inserted, got %d", inserted.Count)
+	}
+	c.do("POST", "/db/users/docs?database=shop", `{"age": 1}`, http.StatusUnprocessableEntity)
+	c.do("POST", "/db/users/docs?database=shop", `{"name": `, http.StatusBadRequest)
+
+	filter := url.QueryEscape(`{"age": {"$gt": 30}}`)
+	found := c.do("GET", "/db/users/docs?database=shop&sort=-age&limit=1&filter="+filter, "", http.StatusOK)
+	if found.Count != 1 || found.Data[0]["name"] != "eve" {
+		t.Fatalf("find: %v", found.Data)
+	}
+	if resp := c.do("GET", "/db/users/count?database=shop&filter="+filter, "", http.StatusOK); resp.Count != 2 {
+		t.Fatalf("expected count 2, got %d", resp.Count)
+	}
+
+	id := found.Data[0]["_id"].(string)
+	c.do("PATCH", "/db/users/docs/"+id+"?database=shop", `{"$set": {"age": 41}}`, http.StatusOK)
+	doc := c.do("GET", "/db/users/docs/"+id+"?database=shop", "", http.StatusOK)
+	if doc.Data[0]["age"] != 41.0 {
+		t.Fatalf("document after PATCH: %v", doc.Data[0])
+	}
+	// $set того же значения не меняет документ, но документ существует
+	if resp := c.do("PATCH", "/db/users/docs/"+id+"?database=shop", `{"$set": {"age": 41}}`, http.StatusOK); resp.Count != 0 || resp.Matched != 1 {
+		t.Fatalf("no-op PATCH: count %d, matched %d", resp.Count, resp.Matched)
+	}
+	c.do("PATCH", "/db/users/docs/missing?database=shop", `{"$set": {"age": 1}}`, http.StatusNotFound)
+	c.do("GET", "/db/users/docs/missing?database=shop", "", http.StatusNotFound)
+	c.do("DELETE", "/db/users/docs/"+id+"?database=shop", "", http.StatusOK)
+	c.do("DELETE", "/db/users/docs/"+id+"?database=shop", "", http.StatusNotFound)
+
+	c.do("PATCH", "/db/users/docs?database=shop", `{"$set": {"age": 0}}`, http.StatusBadRequest)
+	c.do("DELETE", "/db/users/docs?database=shop", "", http.StatusBadRequest)
+	if resp := c.do("DELETE", "/db/users/docs?database=shop&filter={}", "", http.StatusOK); resp.Count != 2 {
+		t.Fatalf("expected 2 deleted, got %d", resp.Count)
+	}
+	c.do("DELETE", "/db/users?database=shop", "", http.StatusOK)
+	c.do("DELETE", "/db/users?database=shop", "", http.StatusNotFound)
+
+	httpResp, err := http.Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpResp.Body.Close()
+	var spec struct {
+		OpenAPI string         `json:"openapi"`
+		Paths   map[string]any `json:"paths"`
+	}
+	if err := json.NewDecoder(httpResp.Body).Decode(&spec); err != nil {
+		t.Fatal(err)
+	}
+	if spec.OpenAPI == "" || spec.Paths["/db/{coll}/docs/{id}"] == nil {
+		t.Fatalf("openapi spec is incomplete: %v", spec.Paths)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	_, ioErr := os.Open(filepath.Join(t.TempDir(), "missing"))
+	cases := []struct {
+		resp api.Response
+		want int
+	}{
+		{api.Response{Status: api.StatusSuccess}, http.StatusCreated},
+		{api.Response{Status: api.StatusError, Message: "unknown index type: x"}, http.StatusBadRequest},
+		{api.Response{Status: api.StatusError, Code: api.CodeNotFound}, http.StatusNotFound},
+		{api.Response{Status: api.StatusError, Code: api.CodeNotWritable, Leader: "node1:8080"}, http.StatusServiceUnavailable},
+		{api.Response{Status: api.StatusError, Code: api.CodeTimeout}, http.StatusGatewayTimeout},
+		// сбой файловой системы — ошибка сервера, а не клиента
+		{api.Response{Status: api.StatusError, Code: handlers.ErrorCode(fmt.Errorf("failed to load database: %w", ioErr))}, http.StatusInternalServerError},
+		{api.Response{Status: api.StatusError, Code: handlers.ErrorCode(storage.Errorf(storage.ErrExists, "collection 'x' already exists"))}, http.StatusConflict},
+		{api.Response{Status: api.StatusError, Code: handlers.ErrorCode(storage.Errorf(storage.ErrNotDurable, "changes applied but not persisted: %w", ioErr))}, http.StatusInternalServerError},
+	}
+	for _, tc := range cases {
+		if got := httpStatus(tc.resp, http.StatusCreated); got != tc.want {
+			t.Errorf("%+v: expected %d, got %d", tc.resp, tc.want, got)
+		}
+	}
+}
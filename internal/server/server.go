@@ -2,22 +2,32 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net"
 	"nosql_db/internal/api"
+	"nosql_db/internal/cluster"
 	"nosql_db/internal/handlers"
+	"nosql_db/internal/replication"
+	"nosql_db/internal/storage"
 	"time"
 )
 
 type TCPServer struct {
+	Manager       *storage.CollectionMng // коллекции, с которыми работают соединения
+	Replication   *replication.Node      // роль узла в репликации; nil — без репликации
+	Cluster       *cluster.Node          // узел кластера Raft; nil — без кластера
 	Address       string
 	Timeout       int
 	MaxConnection int
+
+	listener net.Listener
 }
 
 func New(address string) *TCPServer {
 	return &TCPServer{
+		Manager:       storage.GlobalManager,
 		Address:       address,
 		Timeout:       60,
 		MaxConnection: 100,
@@ -25,18 +35,46 @@ func New(address string) *TCPServer {
 }
 
 func (s *TCPServer) Run() error {
+	if err := s.Listen(); err != nil {
+		return err
+	}
+	return s.Serve()
+}
+
+// Listen открывает порт сервера; с адресом ":0" порт выбирает система (см. Addr)
+func (s *TCPServer) Listen() error {
 	listener, err := net.Listen("tcp", s.Address)
 	if err != nil {
 		return err
 	}
+	s.listener = listener
+	return nil
+}
+
+// Addr возвращает адрес открытого порта
+func (s *TCPServer) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close закрывает порт: Serve возвращается, открытые соединения дорабатывают сами
+func (s *TCPServer) Close() error {
+	return s.listener.Close()
+}
+
+// Serve принимает соединения на порту, открытом Listen, до вызова Close
+func (s *TCPServer) Serve() error {
+	listener := s.listener
 	defer listener.Close()
 
-	log.Printf("server running on %s", s.Address)
+	log.Printf("server running on %s", listener.Addr())
 
 	maxOpenConntecion := make(chan any, s.MaxConnection)
 
 	for {
 		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		}
 		if err != nil {
 			log.Printf("conn error: %v", err)
 			continue
@@ -52,6 +90,17 @@ func (s *TCPServer) Run() error {
 	}
 }
 
+// Node возвращает роль сервера для сессий: узел кластера, репликации или nil
+func (s *TCPServer) Node() handlers.Node {
+	if s.Cluster != nil {
+		return s.Cluster
+	}
+	if s.Replication != nil {
+		return s.Replication
+	}
+	return nil
+}
+
 func (s *TCPServer) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
@@ -62,30 +111,127 @@ func (s *TCPServer) handleConnection(conn net.Conn) {
 	clientAddr := conn.RemoteAddr().String()
 	log.Printf("client connected: %s", clientAddr)
 
-	decoder := json.NewDecoder(conn)
 	encoder := json.NewEncoder(conn)
+	send := func(resp api.Response) error {
+		_ = conn.SetWriteDeadline(time.Now().Add(timeoutDuration))
+		return encoder.Encode(resp)
+	}
 
-	for {
-		_ = conn.SetDeadline(time.Now().Add(timeoutDuration))
+	// незафиксированная транзакция отбрасывается при закрытии соединения
+	session := handlers.NewSession(s.Manager, s.Node())
+	defer session.Close()
+
+	// запросы читаются отдельно: следующий запрос останавливает потоковый курсор (tailable find, watch)
+	done := make(chan struct{})
+	defer close(done)
+	requests, readErr := readRequests(conn, done)
 
+	var next *api.Request
+	for {
 		var req api.Request
-		err := decoder.Decode(&req)
-		if err != nil {
+		if next != nil {
+			req, next = *next, nil
+		} else {
+			_ = conn.SetDeadline(time.Now().Add(timeoutDuration))
+			select {
+			case req = <-requests:
+			case err := <-readErr:
+				if err == io.EOF {
+					log.Printf("client disconnected: %s", clientAddr)
+				} else {
+					log.Printf("decode error from %s: %v", clientAddr, err)
+				}
+				return
+			}
+		}
+
+		if req.Command == api.CmdReplicate && s.Replication == nil {
+			if err := send(api.Response{Status: api.StatusError, Message: "replication is not enabled on this node"}); err != nil {
+				return
+			}
+			continue
+		}
+		if handlers.IsStreaming(req) || req.Command == api.CmdReplicate {
+			// курсор ждёт новых документов и событий сколько угодно: таймаут чтения снимается
+			_ = conn.SetReadDeadline(time.Time{})
+			run := func(stop <-chan struct{}) error { return session.Stream(req, send, stop) }
+			if req.Command == api.CmdReplicate {
+				// поток журнала операций для secondary
+				run = func(stop <-chan struct{}) error {
+					return s.Replication.Serve(req, clientAddr, func(msg any) error {
+						_ = conn.SetWriteDeadline(time.Now().Add(timeoutDuration))
+						return encoder.Encode(msg)
+					}, stop)
+				}
+			}
+			stopped, err := s.stream(conn, run, requests, readErr)
 			if err == io.EOF {
 				log.Printf("client disconnected: %s", clientAddr)
-			} else {
-				log.Printf("decode error from %s: %v", clientAddr, err)
+				return
 			}
-			return
+			if err != nil {
+				log.Printf("connection %s closed: %v", clientAddr, err)
+				return
+			}
+			next = stopped
+			continue
 		}
 
-		resp := handlers.HandleRequest(req)
-
-		_ = conn.SetDeadline(time.Now().Add(timeoutDuration))
+		resp := session.Handle(req)
 
-		if err := encoder.Encode(resp); err != nil {
+		if err := send(resp); err != nil {
 			log.Printf("encode error to %s: %v", clientAddr, err)
 			return
 		}
 	}
 }
+
+// readRequests читает запросы соединения в канал; ошибка чтения (в том числе io.EOF)
+// приходит в readErr, после неё запросов нет
+func readRequests(conn net.Conn, done <-chan struct{}) (<-chan api.Request, <-chan error) {
+	requests := make(chan api.Request)
+	readErr := make(chan error, 1)
+	go func() {
+		decoder := json.NewDecoder(conn)
+		for {
+			var req api.Request
+			if err := decoder.Decode(&req); err != nil {
+				readErr <- err
+				return
+			}
+			select {
+			case requests <- req:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return requests, readErr
+}
+
+// stream выполняет потоковый запрос run, пока клиент не пришлёт следующий запрос, и возвращает его.
+// Ошибка — соединение закрыто или ответ не отправлен
+func (s *TCPServer) stream(conn net.Conn, run func(stop <-chan struct{}) error,
+	requests <-chan api.Request, readErr <-chan error) (*api.Request, error) {
+	stop := make(chan struct{})
+	var next *api.Request
+	var err error
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case r := <-requests:
+			next = &r
+		case err = <-readErr:
+		}
+		close(stop)
+	}()
+
+	if sendErr := run(stop); sendErr != nil {
+		return nil, sendErr
+	}
+	// курсор мог завершиться сам (коллекция удалена, токен устарел): следующий запрос снова ждём с таймаутом
+	_ = conn.SetReadDeadline(time.Now().Add(time.Duration(s.Timeout) * time.Second))
+	<-watched
+	return next, err
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"nosql_db/internal/index"
+)
+
+// Capped-коллекции хранят последние Max документов или Size байт (в JSON), старые
+// удаляются при вставке и при росте документа обновлением. _id выдаются по порядку вставки — "%020d" от номера,
+// поэтому порядок вставки переживает перезапуск и совпадает с порядком _id
+
+// CappedOptions — ограничения capped-коллекции; 0 — без ограничения
+type CappedOptions struct {
+	Max  int   `json:"max,omitempty"`
+	Size int64 `json:"size,omitempty"`
+}
+
+// CollectionOptions — параметры create_collection
+type CollectionOptions struct {
+	Engine    string
+	Capped    *CappedOptions
+	Validator *ValidatorOptions
+}
+
+// cappedState — порядок вставки и размер документов capped-коллекции (под c.mutex)
+type cappedState struct {
+	opts      CappedOptions
+	order     *index.BTree  // _id документов в порядке вставки
+	bytes     int64         // суммарный размер документов в JSON
+	seq       uint64        // номер последнего выданного _id
+	committed string        // _id последнего зафиксированного документа
+	changed   chan struct{} // закрывается, когда фиксируются новые документы
+}
+
+func newCappedState(opts CappedOptions, data StorageEngine) *cappedState {
+	s := &cappedState{opts: opts, order: index.NewBPlusTree(64), changed: make(chan struct{})}
+	data.Scan(func(id string, doc map[string]any) bool {
+		s.add(id, doc)
+		if seq, err := strconv.ParseUint(id, 10, 64); err == nil && seq > s.seq {
+			s.seq = seq
+		}
+		return true
+	})
+	if s.seq > 0 {
+		s.committed = cappedID(s.seq)
+	}
+	return s
+}
+
+func cappedID(seq uint64) string {
+	return fmt.Sprintf("%020d", seq)
+}
+
+func jsonSize(doc map[string]any) int64 {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return 0
+	}
+	return int64(len(raw))
+}
+
+func (s *cappedState) add(id string, doc map[string]any) {
+	s.order.Insert(index.Key(id), index.Value(id))
+	s.bytes += jsonSize(doc)
+}
+
+func (s *cappedState) remove(id string, doc map[string]any) {
+	s.order.Delete(index.Key(id), index.Value(id))
+	s.bytes -= jsonSize(doc)
+}
+
+// oldest возвращает _id самого старого документа, кроме skip
+func (s *cappedState) oldest(skip string) (string, bool) {
+	var id string
+	s.order.Ascend(func(key index.Key, _ []index.Value) bool {
+		if string(key) == skip {
+			return true
+		}
+		id = string(key)
+		return false
+	})
+	return id, id != ""
+}
+
+// Capped возвращает ограничения коллекции; nil — коллекция не capped
+func (c *Collection) Capped() *CappedOptions {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.capped == nil {
+		return nil
+	}
+	opts := c.capped.opts
+	return &opts
+}
+
+// newIDInternal выдаёт _id нового документа
+func (c *Collection) newIDInternal() string {
+	if c.capped == nil {
+		return NewID()
+	}
+	c.capped.seq++
+	return cappedID(c.capped.seq)
+}
+
+// checkCappedInternal отклоняет документ, который один не помещается в коллекцию
+func (c *Collection) checkCappedInternal(doc map[string]any) error {
+	if c.capped == nil || c.capped.opts.Size == 0 {
+		return nil
+	}
+	if size := jsonSize(doc); size > c.capped.opts.Size {
+		return fmt.Errorf("document of %d bytes exceeds capped collection size %d", size, c.capped.opts.Size)
+	}
+	return nil
+}
+
+// trimCappedInternal удаляет самые старые документы, пока коллекция не уложится
+// в ограничения; keep — только что записанный (вставленный или выросший при
+// обновлении) документ, он остаётся, даже если он самый старый
+func (c *Collection) trimCappedInternal(keep string) {
+	if c.capped == nil {
+		return
+	}
+	opts := c.capped.opts
+	for (opts.Max > 0 && c.Data.Len() > opts.Max) || (opts.Size > 0 && c.capped.bytes > opts.Size) {
+		oldest, ok := c.capped.oldest(keep)
+		if !ok {
+			return
+		}
+		c.deleteInternal(oldest)
+	}
+}
+
+// notifyCappedInternal отмечает вставленные документы зафиксированными и будит tailable-курсоры.
+// Вызывается при фиксации: других записей в коллекцию в этот момент нет
+func (c *Collection) notifyCappedInternal() {
+	if c.capped == nil || c.capped.seq == 0 {
+		return
+	}
+	if id := cappedID(c.capped.seq); id != c.capped.committed {
+		c.capped.committed = id
+		close(c.capped.changed)
+		c.capped.changed = make(chan struct{})
+	}
+}
+
+// Tail возвращает копии зафиксированных документов capped-коллекции, вставленных
+// после after, в порядке вставки; позицию для следующего вызова и канал, который
+// закроется при фиксации новых документов или закрытии коллекции
+func (c *Collection) Tail(after string) ([]map[string]any, string, <-chan struct{}, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if c.closed {
+		return nil, after, nil, Errorf(ErrNotFound, "collection '%s' was dropped or renamed", c.Namespace())
+	}
+	if c.capped == nil {
+		return nil, after, nil, fmt.Errorf("collection '%s' is not capped", c.Namespace())
+	}
+	s := c.capped
+	if s.committed <= after {
+		return nil, after, s.changed, nil
+	}
+
+	var docs []map[string]any
+	for _, v := range s.order.RangeSearch(index.Key(after), index.Key(s.committed), false, true) {
+		if doc, ok := c.resolveInternal(string(v), c.committed); ok {
+			docs = append(docs, cloneDocument(doc))
+		}
+	}
+	return docs, s.committed, s.changed, nil
+}
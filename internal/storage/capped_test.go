@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// tailIDs возвращает _id документов из Tail
+func tailIDs(docs []map[string]any) []string {
+	var ids []string
+	for _, doc := range docs {
+		ids = append(ids, doc["_id"].(string))
+	}
+	return ids
+}
+
+func TestCappedCollection(t *testing.T) {
+	dir := t.TempDir()
+	m := openTestManager(t, dir)
+	ns := testNS("audit")
+	if err := m.CreateCollection(ns, CollectionOptions{Capped: &CappedOptions{Max: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for i := 0; i < 5; i++ {
+		result := mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+			id, err := coll.Insert(map[string]any{"n": float64(i)})
+			return WriteResult{InsertedIDs: []string{id}}, err
+		})
+		ids = append(ids, result.InsertedIDs[0])
+	}
+	// _id выдаются по порядку вставки, старые документы вытесняются
+	if !slices.IsSorted(ids) {
+		t.Fatalf("ids are not in insertion order: %v", ids)
+	}
+
+	withCollection(t, m, ns, func(coll *Collection) {
+		if coll.Count() != 3 || coll.Capped().Max != 3 {
+			t.Fatalf("%d document(s), capped %+v", coll.Count(), coll.Capped())
+		}
+		docs, position, changed, err := coll.Tail("")
+		if err != nil || !slices.Equal(tailIDs(docs), ids[2:]) || position != ids[4] {
+			t.Fatalf("tail: %v at %s, %v", tailIDs(docs), position, err)
+		}
+		// новых документов нет: канал закроется при следующей вставке
+		docs, _, changed, _ = coll.Tail(position)
+		if len(docs) != 0 {
+			t.Fatalf("tail after the last document: %v", tailIDs(docs))
+		}
+		select {
+		case <-changed:
+			t.Fatal("changed is closed without new documents")
+		default:
+		}
+
+		mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+			_, err := coll.Insert(map[string]any{"n": 5.0})
+			return WriteResult{}, err
+		})
+		<-changed
+		docs, _, _, _ = coll.Tail(position)
+		if len(docs) != 1 || docs[0]["n"] != 5.0 {
+			t.Fatalf("tail after an insert: %v", docs)
+		}
+	})
+
+	// после перезапуска порядок вставки и нумерация _id продолжаются
+	reopened := openTestManager(t, dir)
+	mustWrite(t, reopened, ns, func(coll *Collection) (WriteResult, error) {
+		_, err := coll.Insert(map[string]any{"n": 6.0})
+		return WriteResult{}, err
+	})
+	withCollection(t, reopened, ns, func(coll *Collection) {
+		docs, _, _, _ := coll.Tail("")
+		var values []any
+		for _, doc := range docs {
+			values = append(values, doc["n"])
+		}
+		if !slices.Equal(values, []any{4.0, 5.0, 6.0}) || coll.Capped() == nil {
+			t.Fatalf("after reopen: %v", values)
+		}
+	})
+
+	// курсор удалённой коллекции получает ошибку
+	withCollection(t, reopened, ns, func(coll *Collection) {
+		if err := reopened.DropCollection(ns); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, err := coll.Tail(""); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("tail of a dropped collection: %v", err)
+		}
+	})
+}
+
+func TestCappedSize(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	ns := testNS("log")
+	if err := m.CreateCollection(ns, CollectionOptions{Capped: &CappedOptions{Size: 100}}); err != nil {
+		t.Fatal(err)
+	}
+	// документ — около 40 байт в JSON: в 100 байт помещаются два
+	for _, text := range []string{"first", "second", "third"} {
+		mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+			_, err := coll.Insert(map[string]any{"text": text})
+			return WriteResult{}, err
+		})
+	}
+	result := m.Enqueue(ns, func(coll *Collection) (WriteResult, error) {
+		_, err := coll.Insert(map[string]any{"text": string(make([]byte, 200))})
+		return WriteResult{}, err
+	})
+	if result.Error == nil {
+		t.Fatal("document larger than the collection must be rejected")
+	}
+
+	withCollection(t, m, ns, func(coll *Collection) {
+		docs, _, _, _ := coll.Tail("")
+		if len(docs) != 2 || docs[0]["text"] != "second" || docs[1]["text"] != "third" {
+			t.Fatalf("documents: %v", docs)
+		}
+	})
+	withCollection(t, m, testNS("plain"), func(coll *Collection) {
+		if _, _, _, err := coll.Tail(""); err == nil {
+			t.Fatal("tail of a collection that is not capped must fail")
+		}
+	})
+}
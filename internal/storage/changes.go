@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Журнал изменений: события insert/update/delete, которые worker публикует при фиксации
+// задачи. Последние changeLogSize событий хранятся в памяти; токен события
+// ("эпоха.номер") позволяет продолжить чтение с места остановки после переподключения.
+// Эпоха — момент запуска сервера: после перезапуска старые токены недействительны
+
+// changeLogSize — сколько последних событий хранит журнал
+const changeLogSize = 10000
+
+// операции событий
+const (
+	ChangeInsert = "insert"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// ChangeEvent — изменение одного документа или команда (oplog.go). Документы событий
+// не меняются на месте. Формат JSON — журнал, который читает реплика
+type ChangeEvent struct {
+	Seq   uint64         `json:"seq"`
+	Token string         `json:"token"`
+	Time  int64          `json:"ts"` // момент фиксации, UnixNano
+	Op    string         `json:"op"`
+	NS    Namespace      `json:"ns"`
+	ID    string         `json:"id,omitempty"`
+	Doc   map[string]any `json:"doc,omitempty"` // документ после изменения; для delete — удалённый документ
+	// изменённые и удалённые поля верхнего уровня (update)
+	Updated map[string]any `json:"updated,omitempty"`
+	Removed []string       `json:"removed,omitempty"`
+
+	// параметры команд
+	To        *Namespace        `json:"to,omitempty"`        // rename_collection
+	Spec      *CollectionSpec   `json:"spec,omitempty"`      // create_collection
+	Index     *IndexSpec        `json:"index,omitempty"`     // create_index
+	Validator *ValidatorOptions `json:"validator,omitempty"` // coll_mod; nil — схема снята
+}
+
+type changeLog struct {
+	mu      sync.Mutex
+	epoch   string
+	seq     uint64        // номер последнего события
+	events  []ChangeEvent // последние события по возрастанию номера
+	changed chan struct{} // закрывается при публикации новых событий
+}
+
+func newChangeLog() *changeLog {
+	return &changeLog{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		changed: make(chan struct{}),
+	}
+}
+
+func (l *changeLog) token(seq uint64) string {
+	return fmt.Sprintf("%s.%d", l.epoch, seq)
+}
+
+// parseToken возвращает номер события токена; "" — текущая позиция журнала
+func (l *changeLog) parseToken(token string) (uint64, error) {
+	if token == "" {
+		return l.seq, nil
+	}
+	epoch, rest, found := strings.Cut(token, ".")
+	seq, err := strconv.ParseUint(rest, 10, 64)
+	if !found || err != nil {
+		return 0, fmt.Errorf("invalid resume token '%s'", token)
+	}
+	if epoch != l.epoch {
+		return 0, fmt.Errorf("resume token '%s' was issued before the server restarted", token)
+	}
+	if seq > l.seq {
+		return 0, fmt.Errorf("resume token '%s' is ahead of the change log", token)
+	}
+	return seq, nil
+}
+
+// publish нумерует события и добавляет их в журнал
+func (l *changeLog) publish(events []ChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now().UnixNano()
+	for i := range events {
+		events[i].Time = now
+		l.seq++
+		events[i].Seq = l.seq
+		events[i].Token = l.token(l.seq)
+	}
+	l.events = append(l.events, events...)
+	if len(l.events) > 2*changeLogSize {
+		// старые события отбрасываются пачкой, чтобы не копировать буфер на каждой публикации
+		l.events = append([]ChangeEvent(nil), l.events[len(l.events)-changeLogSize:]...)
+	}
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// since возвращает до limit событий после токена, токен позиции после них и канал,
+// который закроется при публикации новых событий
+func (l *changeLog) since(token string, limit int) ([]ChangeEvent, string, <-chan struct{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	seq, err := l.parseToken(token)
+	if err != nil {
+		return nil, token, nil, err
+	}
+	if seq == l.seq {
+		return nil, l.token(seq), l.changed, nil
+	}
+	if len(l.events) == 0 || seq+1 < l.events[0].Seq {
+		return nil, token, nil, fmt.Errorf("resume token '%s' is too old: the change log no longer has its events", token)
+	}
+	start := int(seq + 1 - l.events[0].Seq)
+	end := min(start+limit, len(l.events))
+	events := append([]ChangeEvent(nil), l.events[start:end]...)
+	return events, l.token(events[len(events)-1].Seq), l.changed, nil
+}
+
+// Changes возвращает до limit событий всех коллекций после токена ("" — с текущей позиции),
+// токен для следующего вызова и канал, который закроется при появлении новых событий
+func (m *CollectionMng) Changes(token string, limit int) ([]ChangeEvent, string, <-chan struct{}, error) {
+	return m.changes.since(token, limit)
+}
+
+// changeEventsInternal строит события по журналу отката фиксируемой задачи:
+// состояние документа после изменения — prev следующей записи с тем же _id
+// или текущее значение. Вызывается под c.mutex
+func (c *Collection) changeEventsInternal() []ChangeEvent {
+	ns := c.Namespace()
+	after := make(map[string]map[string]any)
+	events := make([]ChangeEvent, 0, len(c.undo))
+	for i := len(c.undo) - 1; i >= 0; i-- {
+		entry := c.undo[i]
+		doc, seen := after[entry.id]
+		if !seen {
+			doc, _ = c.Data.Get(entry.id)
+		}
+		after[entry.id] = entry.prev
+
+		event := ChangeEvent{NS: ns, ID: entry.id, Doc: doc}
+		switch {
+		case entry.prev == nil && doc == nil:
+			continue
+		case entry.prev == nil:
+			event.Op = ChangeInsert
+		case doc == nil:
+			event.Op, event.Doc = ChangeDelete, entry.prev
+		default:
+			event.Op = ChangeUpdate
+			event.Updated, event.Removed = documentDelta(entry.prev, doc)
+			if len(event.Updated) == 0 && len(event.Removed) == 0 {
+				continue
+			}
+		}
+		events = append(events, event)
+	}
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events
+}
+
+// documentDelta возвращает изменённые и удалённые поля верхнего уровня
+func documentDelta(prev, doc map[string]any) (map[string]any, []string) {
+	updated := make(map[string]any)
+	var removed []string
+	for field, value := range doc {
+		if old, ok := prev[field]; !ok || !reflect.DeepEqual(old, value) {
+			updated[field] = value
+		}
+	}
+	for field := range prev {
+		if _, ok := doc[field]; !ok {
+			removed = append(removed, field)
+		}
+	}
+	sort.Strings(removed)
+	return updated, removed
+}
+
+// ChangeToken возвращает токен текущей позиции журнала: после него событий ещё нет
+func (m *CollectionMng) ChangeToken() string {
+	m.changes.mu.Lock()
+	defer m.changes.mu.Unlock()
+	return m.changes.token(m.changes.seq)
+}
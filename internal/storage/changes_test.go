@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// changeOps возвращает операции и _id событий
+func changeOps(events []ChangeEvent) []string {
+	var ops []string
+	for _, event := range events {
+		ops = append(ops, event.Op+" "+event.ID)
+	}
+	return ops
+}
+
+func TestChangeEvents(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	ns := testNS("orders")
+	start := m.ChangeToken()
+	_, _, changed, err := m.Changes(start, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		_, err := coll.InsertWithID("a", map[string]any{"status": "new", "note": "x", "total": 10.0})
+		return WriteResult{}, err
+	})
+	// события публикуются при фиксации задачи
+	select {
+	case <-changed:
+	default:
+		t.Fatal("changed is not closed after a commit")
+	}
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		if _, err := coll.Replace("a", map[string]any{"_id": "a", "status": "paid", "total": 10.0}); err != nil {
+			return WriteResult{}, err
+		}
+		// события одной задачи — в порядке изменений
+		if _, err := coll.InsertWithID("tmp", map[string]any{}); err != nil {
+			return WriteResult{}, err
+		}
+		coll.Delete("tmp")
+		// запись без изменений события не даёт
+		_, err := coll.Replace("a", map[string]any{"_id": "a", "status": "paid", "total": 10.0})
+		return WriteResult{}, err
+	})
+	// отменённая задача ничего не публикует
+	failed := m.Enqueue(ns, func(coll *Collection) (WriteResult, error) {
+		coll.Delete("a")
+		return WriteResult{}, errors.New("abort")
+	})
+	if failed.Error == nil {
+		t.Fatal("failed operation succeeded")
+	}
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		coll.Delete("a")
+		return WriteResult{}, nil
+	})
+
+	events, next, _, err := m.Changes(start, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changeOps(events); !slices.Equal(got, []string{"insert a", "update a", "insert tmp", "delete tmp", "delete a"}) {
+		t.Fatalf("events: %v", got)
+	}
+	insert, update, del := events[0], events[1], events[4]
+	if insert.Doc["status"] != "new" || insert.NS != ns || insert.Time == 0 {
+		t.Fatalf("insert: %+v", insert)
+	}
+	// у update — изменённые и удалённые поля верхнего уровня и документ после изменения
+	if fmt.Sprint(update.Updated) != "map[status:paid]" || !slices.Equal(update.Removed, []string{"note"}) || update.Doc["status"] != "paid" {
+		t.Fatalf("update: %+v", update)
+	}
+	if del.Doc["status"] != "paid" || next != del.Token || m.ChangeToken() != next {
+		t.Fatalf("delete: %+v, next %s", del, next)
+	}
+
+	// чтение порциями: токен последнего события — позиция следующего вызова
+	first, token, _, _ := m.Changes(start, 3)
+	rest, _, _, _ := m.Changes(token, 3)
+	if len(first) != 3 || !slices.Equal(changeOps(rest), []string{"delete tmp", "delete a"}) {
+		t.Fatalf("batches: %v, %v", changeOps(first), changeOps(rest))
+	}
+	if events, _, _, err := m.Changes("", 100); err != nil || len(events) != 0 {
+		t.Fatalf("events after the current position: %v %v", changeOps(events), err)
+	}
+}
+
+func TestResumeTokens(t *testing.T) {
+	changes := newChangeLog()
+	for i := 0; i < 2*changeLogSize+1; i++ {
+		changes.publish([]ChangeEvent{{Op: ChangeInsert, ID: fmt.Sprint(i)}})
+	}
+	// журнал хранит последние события: токен старше них не продолжает поток
+	if _, _, _, err := changes.since(changes.token(1), 10); err == nil || !strings.Contains(err.Error(), "too old") {
+		t.Fatalf("old token: %v", err)
+	}
+	events, _, _, err := changes.since(changes.token(changes.seq-1), 10)
+	if err != nil || len(events) != 1 || events[0].ID != fmt.Sprint(2*changeLogSize) {
+		t.Fatalf("recent token: %v %v", changeOps(events), err)
+	}
+
+	restarted := newChangeLog()
+	restarted.epoch = changes.epoch + "x"
+	for _, tt := range []struct {
+		token, want string
+	}{
+		{"garbage", "invalid resume token"},
+		{changes.epoch + ".x", "invalid resume token"},
+		{changes.token(1), "before the server restarted"},
+		{restarted.token(5), "ahead of the change log"},
+	} {
+		if _, _, _, err := restarted.since(tt.token, 10); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: %v, want %q", tt.token, err, tt.want)
+		}
+	}
+}
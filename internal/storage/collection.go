@@ -3,86 +3,194 @@ package storage
 import (
 	"fmt"
 	"math/rand"
+	"nosql_db/internal/fulltext"
 	"nosql_db/internal/index"
+	"nosql_db/internal/vector"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Collection struct {
-	mutex   sync.RWMutex
-	Name    string
-	Data    *HashMap
-	Indexes map[string]*index.BTree
+	mutex       sync.RWMutex
+	root        string // каталог данных менеджера
+	DB          string // база данных коллекции
+	Name        string
+	Data        StorageEngine // документы по _id
+	Indexes     map[string]*index.BTree
+	TextIndexes map[string]*fulltext.Index // полнотекстовые индексы по имени индекса
+	GeoIndexes  map[string]*index.BTree    // гео-индексы: поле -> b-tree по геохешу
+	VecIndexes  map[string]*vector.Index   // векторные индексы по полю
+	HashIndexes map[string]*HashMap        // хеш-индексы: поле -> ключ значения -> id документов
+
+	undo     []undoEntry   // журнал отката текущей транзакции (nil вне транзакции)
+	commands []ChangeEvent // команды транзакции для журнала изменений (oplog.go)
+
+	// версионирование для снимков чтения (MVCC)
+	committed uint64                  // последняя зафиксированная версия
+	mods      uint64                  // счётчик изменений, включая откаты
+	versions  map[string]uint64       // версия текущего значения документа (0 — исходная)
+	history   map[string][]docVersion // вытесненные версии, ещё видимые снимкам
+	readers   map[uint64]int          // активные снимки: версия -> количество
+
+	nextExpiry int64        // не раньше этого момента (UnixNano) истекает документ TTL-индекса (ttl.go)
+	capped     *cappedState // порядок вставки capped-коллекции (capped.go), nil — обычная коллекция
+	closed     bool         // коллекция закрыта: удалена, переименована или выгружена
+
+	metaMu          sync.Mutex
+	meta            collectionMeta // движок и LSN сохранённых индексов
+	metaSaved       bool           // метаданные записаны на диск
+	validator       *Validator     // разобранная схема meta.Validator (validator.go)
+	forceCheckpoint bool           // набор индексов изменился: сохранить их при следующей записи
+
+	// вытеснение из памяти (memory.go)
+	lastUsed  atomic.Int64 // время последнего обращения, UnixNano
+	pins      atomic.Int32 // обращения, ещё держащие коллекцию: её нельзя выгрузить
+	sizeMu    sync.Mutex
+	indexSize indexSizeCache
+
+	orderMu sync.Mutex
+	order   idOrderCache // упорядоченные _id для постраничного обхода (snapshot.go)
 }
 
-func NewCollection(name string) *Collection {
-	return &Collection{
-		Name:    name,
-		Data:    NewHashMap(),
-		Indexes: make(map[string]*index.BTree),
+func newCollection(root string, ns Namespace, meta collectionMeta, engine StorageEngine) *Collection {
+	c := &Collection{
+		root:        root,
+		DB:          ns.DB,
+		Name:        ns.Coll,
+		Data:        engine,
+		Indexes:     make(map[string]*index.BTree),
+		TextIndexes: make(map[string]*fulltext.Index),
+		GeoIndexes:  make(map[string]*index.BTree),
+		VecIndexes:  make(map[string]*vector.Index),
+		HashIndexes: make(map[string]*HashMap),
+		versions:    make(map[string]uint64),
+		history:     make(map[string][]docVersion),
+		readers:     make(map[uint64]int),
+		meta:        meta,
 	}
+	if meta.Capped != nil {
+		c.capped = newCappedState(*meta.Capped, engine)
+	}
+	c.loadValidator()
+	return c
+}
+
+// Namespace возвращает базу и имя коллекции
+func (c *Collection) Namespace() Namespace {
+	return Namespace{DB: c.DB, Coll: c.Name}
 }
 
-func generateID() string {
+// NewID выдаёт _id нового документа обычной коллекции
+func NewID() string {
 	return fmt.Sprintf("%d-%d", time.Now().UnixNano(), rand.Intn(1000000))
 }
 
 func (c *Collection) Insert(doc map[string]any) (string, error) {
+	return c.InsertWithID("", doc)
+}
+
+// InsertWithID вставляет документ с заранее выданным _id (лидер кластера выдаёт их
+// до репликации, чтобы все узлы вставили одно и то же); пустой id — новый.
+// Capped-коллекция нумерует документы сама и id не использует
+func (c *Collection) InsertWithID(id string, doc map[string]any) (string, error) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	id := generateID()
+	if id == "" || c.capped != nil {
+		id = c.newIDInternal()
+	} else if _, ok := c.Data.Get(id); ok {
+		return "", fmt.Errorf("duplicate _id '%s'", id)
+	}
+	// хранимые документы не меняются на месте: сохраняем копию
+	stored := cloneDocument(doc)
+	stored["_id"] = id
+	if err := c.checkCappedInternal(stored); err != nil {
+		return "", err
+	}
 	doc["_id"] = id
-	c.Data.Put(id, doc)
+	c.recordChangeInternal(id)
+	c.Data.Put(id, stored)
 
-	c.updateIndexesOnInsert(id, doc)
+	c.updateIndexesOnInsert(id, stored)
+	c.trimCappedInternal(id)
 
 	return id, nil
 }
 
-// GetByID получает документ по _id
+// GetByID получает копию текущей версии документа по _id
 func (c *Collection) GetByID(id string) (map[string]any, bool) {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	val, ok := c.Data.Get(id)
+	doc, ok := c.Data.Get(id)
 	if !ok {
 		return nil, false
 	}
-
-	doc, ok := val.(map[string]any)
-	if !ok {
-		return nil, false
-	}
-	return doc, true
+	return cloneDocument(doc), true
 }
 
 // Delete удаляет документ по _id
 func (c *Collection) Delete(id string) bool {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
+	return c.deleteInternal(id)
+}
 
-	val, ok := c.Data.Get(id)
+func (c *Collection) deleteInternal(id string) bool {
+	doc, ok := c.Data.Get(id)
 	if !ok {
 		return false
 	}
-	doc := val.(map[string]any)
 
+	c.recordChangeInternal(id)
 	c.updateIndexesOnDelete(id, doc)
 
-	return c.Data.Remove(id)
+	return c.Data.Delete(id)
+}
+
+// Replace заменяет документ с тем же _id и обновляет индексы; false — документа нет.
+// В capped-коллекции выросший документ вытесняет самые старые остальные, а документ
+// больше всей коллекции отклоняется
+func (c *Collection) Replace(id string, doc map[string]any) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	prev, ok := c.Data.Get(id)
+	if !ok {
+		return false, nil
+	}
+
+	stored := cloneDocument(doc)
+	stored["_id"] = id
+	if err := c.checkCappedInternal(stored); err != nil {
+		return false, err
+	}
+	c.recordChangeInternal(id)
+	c.updateIndexesOnDelete(id, prev)
+	c.Data.Put(id, stored)
+	c.updateIndexesOnInsert(id, stored)
+	c.trimCappedInternal(id)
+	return true, nil
 }
 
+// All возвращает копии текущих версий всех документов (для write-операций в worker'е;
+// чтение должно идти через Snapshot)
 func (c *Collection) All() []map[string]any {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
 
-	items := c.Data.Items()
-	docs := make([]map[string]any, 0, len(items))
-	for _, v := range items {
-		if doc, ok := v.(map[string]any); ok {
-			docs = append(docs, doc)
-		}
-	}
+	docs := make([]map[string]any, 0, c.Data.Len())
+	c.Data.Scan(func(id string, doc map[string]any) bool {
+		docs = append(docs, cloneDocument(doc))
+		return true
+	})
 	return docs
 }
+
+// Count возвращает количество документов в коллекции
+func (c *Collection) Count() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.Data.Len()
+}
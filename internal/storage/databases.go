@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+)
+
+// CollectionInfo — коллекция в списке list_collections
+type CollectionInfo struct {
+	Name   string
+	Engine string
+}
+
+// ListDatabases возвращает имена баз: каталоги в каталоге данных и базы открытых коллекций
+func (m *CollectionMng) ListDatabases() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	for ns, coll := range m.collections {
+		if !slices.Contains(names, ns.DB) && coll.exists() {
+			names = append(names, ns.DB)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ListCollections возвращает коллекции базы с их движками хранения
+func (m *CollectionMng) ListCollections(db string) ([]CollectionInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.listCollectionsLocked(db)
+}
+
+func (m *CollectionMng) listCollectionsLocked(db string) ([]CollectionInfo, error) {
+	names, err := diskCollections(databaseDir(m.dir, db))
+	if err != nil {
+		return nil, err
+	}
+	for ns, coll := range m.collections {
+		if ns.DB == db && !slices.Contains(names, ns.Coll) && coll.exists() {
+			names = append(names, ns.Coll)
+		}
+	}
+	sort.Strings(names)
+
+	infos := make([]CollectionInfo, 0, len(names))
+	for _, name := range slices.Compact(names) {
+		ns := Namespace{DB: db, Coll: name}
+		info := CollectionInfo{Name: name, Engine: EngineHashMap}
+		if coll, loaded := m.collections[ns]; loaded {
+			info.Engine = coll.EngineName()
+		} else if meta, found, err := readCollectionMeta(collectionDir(m.dir, ns)); err != nil {
+			return nil, err
+		} else if found {
+			info.Engine = meta.Engine
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// collectionExistsLocked — создана ли коллекция: открыта и не пуста или есть на диске
+func (m *CollectionMng) collectionExistsLocked(ns Namespace) bool {
+	if coll, loaded := m.collections[ns]; loaded && coll.exists() {
+		return true
+	}
+	for _, path := range []string{collectionDir(m.dir, ns), legacyCollectionPath(m.dir, ns)} {
+		if _, err := os.Stat(path); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// DropCollection удаляет коллекцию с данными и индексами. Выполняется за барьером
+// в очереди коллекции, поэтому параллельных записей в неё нет
+func (m *CollectionMng) DropCollection(ns Namespace) error {
+	result := m.EnqueueTx([]Namespace{ns}, DurabilityFlushed, func(tx *Tx) (WriteResult, error) {
+		m.lockIdle(ns)
+		defer m.mu.Unlock()
+		if !m.collectionExistsLocked(ns) {
+			return WriteResult{}, Errorf(ErrNotFound, "collection '%s' does not exist", ns)
+		}
+		if err := m.removeCollectionLocked(ns); err != nil {
+			return WriteResult{}, err
+		}
+		// база без коллекций исчезает вместе с последней из них
+		os.Remove(databaseDir(m.dir, ns.DB))
+		m.publishCommand(ChangeEvent{Op: ChangeDropCollection, NS: ns})
+		return WriteResult{}, nil
+	})
+	return result.Error
+}
+
+// removeCollectionLocked закрывает коллекцию и удаляет её файлы
+func (m *CollectionMng) removeCollectionLocked(ns Namespace) error {
+	if coll, loaded := m.collections[ns]; loaded {
+		coll.Close()
+		delete(m.collections, ns)
+	}
+	if err := os.RemoveAll(collectionDir(m.dir, ns)); err != nil {
+		return fmt.Errorf("failed to remove collection files: %w", err)
+	}
+	if err := os.Remove(legacyCollectionPath(m.dir, ns)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove collection files: %w", err)
+	}
+	return nil
+}
+
+// DropDatabase удаляет базу со всеми коллекциями и возвращает их число.
+// Коллекции останавливаются барьерами одной транзакции
+func (m *CollectionMng) DropDatabase(db string) (int, error) {
+	infos, err := m.ListCollections(db)
+	if err != nil {
+		return 0, err
+	}
+	if len(infos) == 0 {
+		return 0, Errorf(ErrNotFound, "database '%s' does not exist", db)
+	}
+	names := make([]Namespace, 0, len(infos))
+	for _, info := range infos {
+		names = append(names, Namespace{DB: db, Coll: info.Name})
+	}
+
+	result := m.EnqueueTx(names, DurabilityFlushed, func(tx *Tx) (WriteResult, error) {
+		m.lockIdle(names...)
+		defer m.mu.Unlock()
+		// коллекция, созданная после составления списка, не остановлена барьером
+		current, err := m.listCollectionsLocked(db)
+		if err != nil {
+			return WriteResult{}, err
+		}
+		if len(current) != len(infos) {
+			return WriteResult{}, fmt.Errorf("collections of database '%s' changed during drop, retry", db)
+		}
+		for _, ns := range names {
+			if err := m.removeCollectionLocked(ns); err != nil {
+				return WriteResult{}, err
+			}
+		}
+		if err := os.RemoveAll(databaseDir(m.dir, db)); err != nil {
+			return WriteResult{}, fmt.Errorf("failed to remove database files: %w", err)
+		}
+		m.publishCommand(ChangeEvent{Op: ChangeDropDatabase, NS: Namespace{DB: db}})
+		return WriteResult{}, nil
+	})
+	return len(names), result.Error
+}
+
+// DropAll удаляет все базы узла: реплика и узел кластера заменяют ими данные источника
+func (m *CollectionMng) DropAll() error {
+	dbs, err := m.ListDatabases()
+	if err != nil {
+		return err
+	}
+	for _, db := range dbs {
+		infos, err := m.ListCollections(db)
+		if err != nil {
+			return err
+		}
+		if len(infos) == 0 {
+			continue
+		}
+		if _, err := m.DropDatabase(db); err != nil {
+			return fmt.Errorf("drop local database '%s': %w", db, err)
+		}
+	}
+	return nil
+}
+
+// RenameCollection переименовывает коллекцию, в том числе с переносом в другую базу.
+// Обе коллекции останавливаются барьерами; файлы данных и индексов переносятся
+// переименованием каталога
+func (m *CollectionMng) RenameCollection(from, to Namespace) error {
+	if from == to {
+		return fmt.Errorf("collection '%s' cannot be renamed to itself", from)
+	}
+	result := m.EnqueueTx([]Namespace{from, to}, DurabilityFlushed, func(tx *Tx) (WriteResult, error) {
+		if err := m.renameCollection(from, to); err != nil {
+			return WriteResult{}, err
+		}
+		m.publishCommand(ChangeEvent{Op: ChangeRenameCollection, NS: from, To: &to})
+		return WriteResult{}, nil
+	})
+	return result.Error
+}
+
+func (m *CollectionMng) renameCollection(from, to Namespace) error {
+	m.lockIdle(from, to)
+	defer m.mu.Unlock()
+
+	if !m.collectionExistsLocked(from) {
+		return Errorf(ErrNotFound, "collection '%s' does not exist", from)
+	}
+	if m.collectionExistsLocked(to) {
+		return Errorf(ErrExists, "collection '%s' already exists", to)
+	}
+	// загрузка переводит файл старого формата в каталог коллекции
+	coll, err := m.getCollectionLocked(from)
+	if err != nil {
+		return err
+	}
+	if err := coll.saveMeta(); err != nil {
+		return err
+	}
+	if empty, loaded := m.collections[to]; loaded {
+		empty.Close()
+		delete(m.collections, to)
+	}
+
+	persistent := coll.persistent()
+	if persistent {
+		coll.Close()
+		delete(m.collections, from)
+	}
+	if err := os.MkdirAll(databaseDir(m.dir, to.DB), 0755); err != nil {
+		return fmt.Errorf("mkdir error: %w", err)
+	}
+	if err := os.Rename(collectionDir(m.dir, from), collectionDir(m.dir, to)); err != nil {
+		return fmt.Errorf("failed to rename collection files: %w", err)
+	}
+	os.Remove(databaseDir(m.dir, from.DB))
+	if err := syncPath(databaseDir(m.dir, to.DB)); err != nil {
+		return err
+	}
+
+	if !persistent {
+		// данные коллекции в памяти переходят к новому имени вместе с объектом
+		coll.mutex.Lock()
+		coll.DB, coll.Name = to.DB, to.Coll
+		coll.mutex.Unlock()
+		delete(m.collections, from)
+		m.collections[to] = coll
+		return nil
+	}
+	_, err = m.getCollectionLocked(to)
+	return err
+}
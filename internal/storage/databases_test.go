@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// insertInto вставляет документы в коллекцию через её очередь записи
+func insertInto(t *testing.T, m *CollectionMng, ns Namespace, docs ...map[string]any) {
+	t.Helper()
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		for _, doc := range docs {
+			if _, err := coll.Insert(doc); err != nil {
+				return WriteResult{}, err
+			}
+		}
+		return WriteResult{}, nil
+	})
+}
+
+// collectionNames возвращает имена коллекций базы и их движки
+func collectionNames(t *testing.T, m *CollectionMng, db string) []string {
+	t.Helper()
+	infos, err := m.ListCollections(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name+":"+info.Engine)
+	}
+	return names
+}
+
+func TestDatabases(t *testing.T) {
+	dir := t.TempDir()
+	m := openTestManager(t, dir)
+	orders, items := Namespace{DB: "shop", Coll: "orders"}, Namespace{DB: "shop", Coll: "items"}
+	insertInto(t, m, orders, map[string]any{"total": 10.0}, map[string]any{"total": 20.0})
+	if err := m.CreateCollection(items, CollectionOptions{Engine: EngineLSM}); err != nil {
+		t.Fatal(err)
+	}
+	insertInto(t, m, items, map[string]any{"name": "pen"})
+	insertInto(t, m, testNS("notes"), map[string]any{"text": "hi"})
+	if err := m.CreateCollection(testNS("cache"), CollectionOptions{Engine: EngineMemory}); err != nil {
+		t.Fatal(err)
+	}
+	insertInto(t, m, testNS("cache"), map[string]any{"key": "a"})
+
+	if dbs, err := m.ListDatabases(); err != nil || !slices.Equal(dbs, []string{DefaultDatabase, "shop"}) {
+		t.Fatalf("databases: %v %v", dbs, err)
+	}
+	if got := collectionNames(t, m, "shop"); !slices.Equal(got, []string{"items:lsm", "orders:hashmap"}) {
+		t.Fatalf("shop collections: %v", got)
+	}
+	// файлы коллекции — в каталоге её базы
+	if _, err := os.Stat(filepath.Join(dir, "shop", "orders")); err != nil {
+		t.Fatalf("collection directory: %v", err)
+	}
+
+	// переименование с переносом в другую базу: файлы и данные переходят к новому имени
+	archived := Namespace{DB: "archive", Coll: "orders_2024"}
+	if err := m.RenameCollection(orders, archived); err != nil {
+		t.Fatal(err)
+	}
+	withCollection(t, m, archived, func(coll *Collection) {
+		if coll.Count() != 2 {
+			t.Fatalf("%d document(s) after rename", coll.Count())
+		}
+	})
+	if err := m.RenameCollection(orders, Namespace{DB: "shop", Coll: "other"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("rename of a missing collection: %v", err)
+	}
+	if err := m.RenameCollection(items, archived); !errors.Is(err, ErrExists) {
+		t.Fatalf("rename onto an existing collection: %v", err)
+	}
+	// коллекция в памяти переименовывается вместе с данными
+	if err := m.RenameCollection(testNS("cache"), testNS("cache2")); err != nil {
+		t.Fatal(err)
+	}
+	withCollection(t, m, testNS("cache2"), func(coll *Collection) {
+		if coll.Count() != 1 {
+			t.Fatalf("%d document(s) in the renamed memory collection", coll.Count())
+		}
+	})
+
+	// база исчезает вместе с последней коллекцией
+	if err := m.DropCollection(items); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.DropCollection(items); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("second drop: %v", err)
+	}
+	if dbs, _ := m.ListDatabases(); !slices.Equal(dbs, []string{"archive", DefaultDatabase}) {
+		t.Fatalf("databases after drop: %v", dbs)
+	}
+
+	// после перезапуска базы и коллекции читаются с диска
+	reopened := openTestManager(t, dir)
+	if dbs, _ := reopened.ListDatabases(); !slices.Equal(dbs, []string{"archive", DefaultDatabase}) {
+		t.Fatalf("databases after reopen: %v", dbs)
+	}
+	if got := collectionNames(t, reopened, "archive"); !slices.Equal(got, []string{"orders_2024:hashmap"}) {
+		t.Fatalf("archive collections after reopen: %v", got)
+	}
+
+	dropped, err := reopened.DropDatabase("archive")
+	if err != nil || dropped != 1 {
+		t.Fatalf("drop database: %d %v", dropped, err)
+	}
+	if _, err := reopened.DropDatabase("archive"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("drop of a missing database: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "archive")); !os.IsNotExist(err) {
+		t.Fatalf("database directory is still there: %v", err)
+	}
+}
+
+func TestFlatLayoutMigration(t *testing.T) {
+	// коллекции с индексами в формате базы по умолчанию — из них собирается плоский каталог
+	source := t.TempDir()
+	m := openTestManager(t, source)
+	for _, coll := range []string{"users", "users_2"} {
+		mustWrite(t, m, testNS(coll), func(c *Collection) (WriteResult, error) {
+			if err := c.CreateIndex("age", 64); err != nil {
+				return WriteResult{}, err
+			}
+			_, err := c.Insert(map[string]any{"age": 30.0})
+			return WriteResult{}, err
+		})
+	}
+
+	// collections/<коллекция>/, indexes/<коллекция>_<индекс>, <коллекция>.json
+	dir := t.TempDir()
+	flatIndexes := filepath.Join(dir, indexDirName)
+	if err := os.MkdirAll(flatIndexes, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, coll := range []string{"users", "users_2"} {
+		from := filepath.Join(source, DefaultDatabase, coll)
+		if err := os.Rename(filepath.Join(from, indexDirName, "age.idx"), filepath.Join(flatIndexes, coll+"_age.idx")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.CopyFS(filepath.Join(dir, "collections", coll), os.DirFS(from)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(flatIndexes, "orphan_age.idx"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.json"), []byte(`{"a": {"_id": "a"}, "b": {"_id": "b"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated := openTestManager(t, dir)
+	if _, err := os.Stat(filepath.Join(dir, layoutName)); err != nil {
+		t.Fatalf("layout marker: %v", err)
+	}
+	// индекс достаётся коллекции с самым длинным подходящим именем
+	for _, coll := range []string{"users", "users_2"} {
+		if _, err := os.Stat(filepath.Join(dir, DefaultDatabase, coll, indexDirName, "age.idx")); err != nil {
+			t.Fatalf("%s index file: %v", coll, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "collections")); !os.IsNotExist(err) {
+		t.Fatalf("flat collections directory is still there: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(flatIndexes, "orphan_age.idx")); err != nil {
+		t.Fatalf("index file without a collection must stay in place: %v", err)
+	}
+
+	if got := collectionNames(t, migrated, DefaultDatabase); !slices.Equal(got, []string{"notes:hashmap", "users:hashmap", "users_2:hashmap"}) {
+		t.Fatalf("collections after migration: %v", got)
+	}
+	withCollection(t, migrated, testNS("users"), func(coll *Collection) {
+		if coll.Count() != 1 || !coll.HasIndex("age") {
+			t.Fatalf("users: %d document(s), index %v", coll.Count(), coll.HasIndex("age"))
+		}
+	})
+	withCollection(t, migrated, testNS("notes"), func(coll *Collection) {
+		if coll.Count() != 2 {
+			t.Fatalf("notes: %d document(s)", coll.Count())
+		}
+	})
+
+	// повторное открытие по метке LAYOUT ничего не переносит
+	if got := collectionNames(t, openTestManager(t, dir), DefaultDatabase); len(got) != 3 {
+		t.Fatalf("collections after reopen: %v", got)
+	}
+}
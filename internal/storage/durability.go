@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// Durability — гарантия записи, после которой клиент получает ответ
+type Durability int
+
+const (
+	DurabilityNone    Durability = iota // ответ сразу после применения в памяти
+	DurabilityFlushed                   // ответ после записи в файлы (по умолчанию)
+	DurabilityFsynced                   // ответ после fsync файлов
+)
+
+// ParseDurability разбирает write concern из запроса; пустая строка — flushed
+func ParseDurability(name string) (Durability, error) {
+	switch name {
+	case "", "flushed":
+		return DurabilityFlushed, nil
+	case "none":
+		return DurabilityNone, nil
+	case "fsynced":
+		return DurabilityFsynced, nil
+	default:
+		return 0, fmt.Errorf("unknown durability: %s", name)
+	}
+}
+
+// processBatch применяет группу задач по порядку и сохраняет изменённые коллекции
+// один раз на группу (group commit). Задачи с durability "none" получают ответ сразу
+// после применения, остальные — после записи (и fsync, если его просил кто-то из группы).
+// Барьер транзакции делит группу: задачи до него сохраняются до остановки worker'а.
+// Если сохранить группу не удалось, её задачи получают ErrNotDurable: изменения уже
+// зафиксированы в памяти и видны чтениям, откатить их нельзя, но на диске их нет
+func (m *CollectionMng) processBatch(batch []WriteJob) {
+	var waiting []WriteJob
+	var results []WriteResult
+	dirty := make(map[*Collection]struct{})
+	sync := false
+
+	flush := func() {
+		colls := make([]*Collection, 0, len(dirty))
+		for coll := range dirty {
+			colls = append(colls, coll)
+		}
+		err := persistCollections(colls, sync)
+		if err != nil {
+			log.Printf("group commit failed: %v", err)
+		}
+		for i, job := range waiting {
+			if err != nil {
+				results[i] = WriteResult{Error: Errorf(ErrNotDurable, "changes applied but not persisted: %w", err)}
+			}
+			job.ResultChan <- results[i]
+		}
+		waiting, results, sync = nil, nil, false
+		clear(dirty)
+	}
+
+	for _, job := range batch {
+		if job.barrier != nil {
+			flush()
+			job.barrier.ready <- struct{}{}
+			<-job.barrier.done
+			continue
+		}
+
+		result, changed := m.processJob(job)
+		for _, coll := range changed {
+			dirty[coll] = struct{}{}
+		}
+		if result.Error != nil || job.Durability == DurabilityNone {
+			job.ResultChan <- result
+			continue
+		}
+		waiting = append(waiting, job)
+		results = append(results, result)
+		if job.Durability == DurabilityFsynced {
+			sync = true
+		}
+	}
+	flush()
+}
+
+// persistLogged сохраняет коллекции, ошибки только пишутся в лог
+func (m *CollectionMng) persistLogged(colls []*Collection, sync bool) {
+	if err := persistCollections(colls, sync); err != nil {
+		log.Printf("persist failed: %v", err)
+	}
+}
+
+// persistCollections сохраняет данные и индексы коллекций; sync — с fsync файлов
+func persistCollections(colls []*Collection, sync bool) error {
+	for _, coll := range colls {
+		if err := coll.persist(sync); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// persist сбрасывает изменения коллекции в её движок хранения и время от времени
+// сохраняет файлы индексов. Метаданные записываются до первых данных коллекции
+func (c *Collection) persist(sync bool) error {
+	c.metaMu.Lock()
+	saved := c.metaSaved
+	c.metaMu.Unlock()
+	if !saved {
+		if err := c.saveMeta(); err != nil {
+			return err
+		}
+	}
+	if err := c.Data.Flush(sync); err != nil {
+		return fmt.Errorf("failed to save data: %w", err)
+	}
+	if c.indexCheckpointDue() {
+		if err := c.checkpointIndexes(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func syncPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+// countingEngine считает сохранения движка коллекции
+type countingEngine struct {
+	StorageEngine
+	flushes atomic.Int32
+	syncs   atomic.Int32
+}
+
+func (e *countingEngine) Flush(sync bool) error {
+	e.flushes.Add(1)
+	if sync {
+		e.syncs.Add(1)
+	}
+	return e.StorageEngine.Flush(sync)
+}
+
+// failingEngine — движок, который не может сохранить данные
+type failingEngine struct {
+	StorageEngine
+}
+
+func (e *failingEngine) Flush(bool) error {
+	return errors.New("disk full")
+}
+
+func TestParseDurability(t *testing.T) {
+	for name, want := range map[string]Durability{"": DurabilityFlushed, "flushed": DurabilityFlushed, "none": DurabilityNone, "fsynced": DurabilityFsynced} {
+		if got, err := ParseDurability(name); err != nil || got != want {
+			t.Errorf("%q: %v %v", name, got, err)
+		}
+	}
+	if _, err := ParseDurability("majority"); err == nil {
+		t.Error("unknown durability must be rejected")
+	}
+}
+
+func TestGroupCommit(t *testing.T) {
+	dir := t.TempDir()
+	m := openTestManager(t, dir)
+	ns := testNS("test")
+	insert := func(coll *Collection) (WriteResult, error) {
+		id, err := coll.Insert(map[string]any{})
+		return WriteResult{InsertedIDs: []string{id}}, err
+	}
+	mustWrite(t, m, ns, insert)
+
+	// пока worker занят, задачи копятся в очереди и применяются одной группой
+	engine := &countingEngine{}
+	release, started := make(chan struct{}), make(chan struct{})
+	gate := make(chan WriteResult, 1)
+	m.enqueueJob(ns, WriteJob{Namespace: ns, Durability: DurabilityFlushed, ResultChan: gate,
+		Operation: func(coll *Collection) (WriteResult, error) {
+			engine.StorageEngine = coll.Data
+			coll.Data = engine
+			close(started)
+			<-release
+			return WriteResult{}, nil
+		}})
+	<-started
+	results := make([]chan WriteResult, 10)
+	for i := range results {
+		durability := DurabilityFlushed
+		switch i {
+		case 3:
+			durability = DurabilityFsynced
+		case 5:
+			durability = DurabilityNone
+		}
+		results[i] = make(chan WriteResult, 1)
+		m.enqueueJob(ns, WriteJob{Namespace: ns, Durability: durability, ResultChan: results[i], Operation: insert})
+	}
+	close(release)
+	<-gate
+	for _, result := range results {
+		if err := (<-result).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	// одно сохранение на группу, с fsync — потому что его просила одна из задач
+	if flushes, syncs := engine.flushes.Load(), engine.syncs.Load(); flushes != 1 || syncs != 1 {
+		t.Fatalf("%d flush(es), %d fsync(s) for a group of 10 writes", flushes, syncs)
+	}
+
+	// подтверждённые записи уже в файлах: их видит менеджер, открытый заново
+	withCollection(t, openTestManager(t, dir), ns, func(coll *Collection) {
+		if coll.Count() != 11 {
+			t.Fatalf("%d document(s) on disk", coll.Count())
+		}
+	})
+}
+
+func TestDurabilityNone(t *testing.T) {
+	dir := t.TempDir()
+	m := openTestManager(t, dir)
+	ns := testNS("test")
+	for _, durability := range []Durability{DurabilityNone, DurabilityNone, DurabilityFsynced} {
+		result := m.EnqueueDurable(ns, durability, func(coll *Collection) (WriteResult, error) {
+			_, err := coll.Insert(map[string]any{})
+			return WriteResult{}, err
+		})
+		if result.Error != nil {
+			t.Fatal(result.Error)
+		}
+	}
+	// ответ none не ждёт записи, но изменения всё равно сохраняются вместе с группой
+	withCollection(t, openTestManager(t, dir), ns, func(coll *Collection) {
+		if coll.Count() != 3 {
+			t.Fatalf("%d document(s) on disk", coll.Count())
+		}
+	})
+}
+
+func TestNotDurable(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	ns := testNS("test")
+	var id string
+	result := m.EnqueueDurable(ns, DurabilityFlushed, func(coll *Collection) (WriteResult, error) {
+		coll.Data = &failingEngine{StorageEngine: coll.Data}
+		var err error
+		id, err = coll.Insert(map[string]any{})
+		return WriteResult{}, err
+	})
+	// сохранение не удалось: клиент узнаёт об этом отдельным видом ошибки, а запись
+	// остаётся в памяти — откатить уже видимые изменения нельзя
+	if !errors.Is(result.Error, ErrNotDurable) {
+		t.Fatalf("expected ErrNotDurable, got %v", result.Error)
+	}
+	withCollection(t, m, ns, func(coll *Collection) {
+		if _, ok := coll.Data.Get(id); !ok {
+			t.Fatal("write is not applied in memory")
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package storage
+
+import "fmt"
+
+// Движки хранения документов коллекции
+const (
+	EngineHashMap = "hashmap" // HashMap в памяти + append-only сегменты (по умолчанию)
+	EngineMemory  = "memory"  // только память, ничего не пишется на диск
+	EngineLSM     = "lsm"     // LSM-дерево: memtable, SSTable, leveled compaction
+)
+
+// StorageEngine — хранилище документов коллекции по _id.
+// Коллекция вызывает Put/Delete под своим мьютексом; хранимые документы не меняются
+// на месте, поэтому Get и Scan отдают их без копирования.
+// Flush и Snapshot могут выполняться параллельно с чтением
+type StorageEngine interface {
+	Get(id string) (map[string]any, bool)
+	Put(id string, doc map[string]any)
+	Delete(id string) bool
+	// Scan обходит все документы; fn возвращает false, чтобы остановиться
+	Scan(fn func(id string, doc map[string]any) bool)
+	Len() int
+	// Snapshot — неизменяемый срез данных (например, для компактизации)
+	Snapshot() EngineSnapshot
+	// Flush делает изменения с прошлого вызова долговечными; sync — с fsync
+	Flush(sync bool) error
+	// LSN — номер последней записи на диске; по нему сверяются файлы индексов
+	LSN() uint64
+	// MemoryUsage — примерный объём данных движка в памяти, байт
+	MemoryUsage() int64
+	Close() error
+}
+
+// EngineSnapshot — срез данных движка на момент создания
+type EngineSnapshot interface {
+	Get(id string) (map[string]any, bool)
+	Scan(fn func(id string, doc map[string]any) bool)
+	Release()
+}
+
+// ValidEngine проверяет имя движка; пустое имя — движок по умолчанию
+func ValidEngine(name string) (string, error) {
+	switch name {
+	case "":
+		return EngineHashMap, nil
+	case EngineHashMap, EngineMemory, EngineLSM:
+		return name, nil
+	default:
+		return "", fmt.Errorf("unknown storage engine: %s", name)
+	}
+}
+
+// openEngine открывает данные коллекции движком name
+func openEngine(name, root string, ns Namespace) (StorageEngine, error) {
+	switch name {
+	case EngineMemory:
+		return newMemoryEngine(), nil
+	case EngineLSM:
+		return openLSMEngine(collectionDir(root, ns))
+	default:
+		return openHashMapEngine(collectionDir(root, ns), legacyCollectionPath(root, ns))
+	}
+}
+
+// mapSnapshot — снимок движков, держащих документы в памяти: копия ссылок на документы
+type mapSnapshot map[string]map[string]any
+
+func (s mapSnapshot) Get(id string) (map[string]any, bool) {
+	doc, ok := s[id]
+	return doc, ok
+}
+
+func (s mapSnapshot) Scan(fn func(id string, doc map[string]any) bool) {
+	for id, doc := range s {
+		if !fn(id, doc) {
+			return
+		}
+	}
+}
+
+func (s mapSnapshot) Release() {}
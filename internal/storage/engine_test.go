@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// benchEngines — движки, которые сравниваются: HashMap с сегментами JSON и LSM-дерево
+var benchEngines = []string{EngineHashMap, EngineLSM}
+
+const benchDocs = 50000
+
+func benchDocument(i int) map[string]any {
+	return map[string]any{"_id": fmt.Sprintf("doc%08d", i), "n": float64(i), "name": fmt.Sprintf("user %d", i)}
+}
+
+// BenchmarkEngineWrite измеряет запись документов; Flush — каждые 64 записи,
+// как при групповом коммите
+func BenchmarkEngineWrite(b *testing.B) {
+	b.Chdir(b.TempDir())
+
+	for _, name := range benchEngines {
+		b.Run(name, func(b *testing.B) {
+			engine, err := openEngine(name, defaultDataDir, Namespace{DB: DefaultDatabase, Coll: "bench_write_" + name})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer engine.Close()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				doc := benchDocument(i)
+				engine.Put(doc["_id"].(string), doc)
+				if i%64 == 63 {
+					if err := engine.Flush(false); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEngineGet измеряет точечное чтение после перезапуска: у LSM документы
+// лежат в таблицах на диске, промахи отсекаются фильтрами Блума без чтения блоков
+func BenchmarkEngineGet(b *testing.B) {
+	b.Chdir(b.TempDir())
+
+	for _, name := range benchEngines {
+		ns := Namespace{DB: DefaultDatabase, Coll: "bench_get_" + name}
+		engine, err := openEngine(name, defaultDataDir, ns)
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < benchDocs; i++ {
+			doc := benchDocument(i)
+			engine.Put(doc["_id"].(string), doc)
+			if i%1000 == 999 {
+				if err := engine.Flush(false); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		if err := engine.Flush(true); err != nil {
+			b.Fatal(err)
+		}
+		engine.Close()
+
+		if engine, err = openEngine(name, defaultDataDir, ns); err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(name+"/hit", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, ok := engine.Get(fmt.Sprintf("doc%08d", i*7919%benchDocs)); !ok {
+					b.Fatal("document not found")
+				}
+			}
+		})
+		b.Run(name+"/miss", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, ok := engine.Get(fmt.Sprintf("missing%08d", i)); ok {
+					b.Fatal("unexpected document")
+				}
+			}
+		})
+		engine.Close()
+	}
+}
+
+// engineIDs возвращает id документов, которые обходит scan, по алфавиту
+func engineIDs(scan func(fn func(id string, doc map[string]any) bool)) []string {
+	var ids []string
+	scan(func(id string, doc map[string]any) bool {
+		ids = append(ids, id)
+		return true
+	})
+	slices.Sort(ids)
+	return ids
+}
+
+// TestEngineConformance проверяет, что все движки одинаково выполняют контракт StorageEngine
+func TestEngineConformance(t *testing.T) {
+	for _, name := range []string{EngineHashMap, EngineMemory, EngineLSM} {
+		t.Run(name, func(t *testing.T) {
+			root, ns := t.TempDir(), testNS("test")
+			engine, err := openEngine(name, root, ns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer engine.Close()
+
+			for i := 0; i < 5; i++ {
+				doc := benchDocument(i)
+				engine.Put(doc["_id"].(string), doc)
+			}
+			engine.Put("doc00000001", map[string]any{"_id": "doc00000001", "n": 100.0})
+			if !engine.Delete("doc00000002") || engine.Delete("missing") {
+				t.Fatal("Delete must report whether the document existed")
+			}
+			if doc, ok := engine.Get("doc00000001"); !ok || doc["n"] != 100.0 {
+				t.Fatalf("Get after overwrite: %v %v", doc, ok)
+			}
+			if _, ok := engine.Get("doc00000002"); ok {
+				t.Fatal("deleted document is still returned")
+			}
+			want := []string{"doc00000000", "doc00000001", "doc00000003", "doc00000004"}
+			if ids := engineIDs(engine.Scan); engine.Len() != 4 || !slices.Equal(ids, want) {
+				t.Fatalf("Len %d, Scan %v", engine.Len(), ids)
+			}
+			if engine.MemoryUsage() <= 0 {
+				t.Fatalf("memory usage %d", engine.MemoryUsage())
+			}
+
+			// Scan останавливается, когда fn возвращает false
+			visited := 0
+			engine.Scan(func(string, map[string]any) bool {
+				visited++
+				return false
+			})
+			if visited != 1 {
+				t.Fatalf("Scan visited %d document(s) after stop", visited)
+			}
+
+			// снимок не видит изменений после создания
+			snap := engine.Snapshot()
+			engine.Put("late", map[string]any{"_id": "late"})
+			engine.Delete("doc00000000")
+			if ids := engineIDs(snap.Scan); !slices.Equal(ids, want) {
+				t.Fatalf("snapshot after changes: %v", ids)
+			}
+			if _, ok := snap.Get("late"); ok {
+				t.Fatal("snapshot sees a later Put")
+			}
+			snap.Release()
+
+			before := engine.LSN()
+			if err := engine.Flush(true); err != nil {
+				t.Fatal(err)
+			}
+			if name != EngineMemory && engine.LSN() <= before {
+				t.Fatalf("LSN did not grow after Flush: %d -> %d", before, engine.LSN())
+			}
+			if err := engine.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			// после перезапуска — сохранённые данные; memory начинает с пустой коллекции
+			reopened, err := openEngine(name, root, ns)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reopened.Close()
+			want = []string{"doc00000001", "doc00000003", "doc00000004", "late"}
+			if name == EngineMemory {
+				want = nil
+			}
+			if ids := engineIDs(reopened.Scan); reopened.Len() != len(want) || !slices.Equal(ids, want) {
+				t.Fatalf("after reopen: Len %d, Scan %v", reopened.Len(), ids)
+			}
+		})
+	}
+}
+
+func TestCollectionEngine(t *testing.T) {
+	dir := t.TempDir()
+	m := openTestManager(t, dir)
+	for _, name := range []string{EngineMemory, EngineLSM} {
+		if err := m.CreateCollection(testNS(name), CollectionOptions{Engine: name}); err != nil {
+			t.Fatal(err)
+		}
+		mustWrite(t, m, testNS(name), func(coll *Collection) (WriteResult, error) {
+			_, err := coll.Insert(map[string]any{"engine": name})
+			return WriteResult{}, err
+		})
+	}
+	// коллекция, созданная неявно первой записью, — hashmap
+	mustWrite(t, m, testNS("implicit"), func(coll *Collection) (WriteResult, error) {
+		_, err := coll.Insert(map[string]any{})
+		return WriteResult{}, err
+	})
+	if _, err := ValidEngine("btree"); err == nil {
+		t.Fatal("unknown engine must be rejected")
+	}
+
+	// движок записан в метаданных коллекции и выбирается при следующем открытии
+	reopened := openTestManager(t, dir)
+	for coll, want := range map[string]struct {
+		engine string
+		count  int
+	}{"memory": {EngineMemory, 0}, "lsm": {EngineLSM, 1}, "implicit": {EngineHashMap, 1}} {
+		withCollection(t, reopened, testNS(coll), func(c *Collection) {
+			if c.EngineName() != want.engine || c.Count() != want.count {
+				t.Errorf("%s: engine %s with %d document(s)", coll, c.EngineName(), c.Count())
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Виды ошибок хранилища: по ним обработчики выбирают код ответа (api.Code*),
+// не разбирая текст ошибки
+var (
+	ErrNotFound   = errors.New("not found")         // коллекция, база или индекс не существует
+	ErrExists     = errors.New("already exists")    // коллекция или индекс уже есть
+	ErrValidation = errors.New("failed validation") // документ не прошёл схему коллекции
+	ErrNotDurable = errors.New("not durable")       // изменения применены в памяти, но не сохранены
+)
+
+// kindError — ошибка вида kind со своим текстом
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string   { return e.err.Error() }
+func (e *kindError) Unwrap() []error { return []error{e.kind, e.err} }
+
+// Errorf возвращает ошибку с текстом fmt.Errorf(format, args...), для которой
+// errors.Is(err, kind) — true
+func Errorf(kind error, format string, args ...any) error {
+	return &kindError{kind: kind, err: fmt.Errorf(format, args...)}
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"nosql_db/internal/geo"
+	"nosql_db/internal/index"
+	"os"
+	"path/filepath"
+)
+
+// geoIndexExt — расширение файлов гео-индексов; формат тот же, что у .idx
+const geoIndexExt = ".geo"
+
+// CreateGeoIndex создаёт гео-индекс по полю с точками [lon, lat] или GeoJSON Point
+func (c *Collection) CreateGeoIndex(fieldName string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.GeoIndexes[fieldName]; exists {
+		return Errorf(ErrExists, "geo index on field '%s' already exists", fieldName)
+	}
+	c.GeoIndexes[fieldName] = c.buildGeoIndexInternal(fieldName)
+	c.noteIndexInternal(IndexSpec{Type: IndexGeo, Fields: []string{fieldName}})
+
+	return c.saveGeoIndexInternal(fieldName)
+}
+
+// HasGeoIndex проверяет существование гео-индекса на поле
+func (c *Collection) HasGeoIndex(fieldName string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	_, exists := c.GeoIndexes[fieldName]
+	return exists
+}
+
+// SearchGeo возвращает id документов, чьи точки попадают в ячейки геохеша, покрывающие box.
+// Результат — кандидаты: точную проверку выполняет вызывающий; false, если индекса нет
+func (c *Collection) SearchGeo(fieldName string, box geo.Box) ([]string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	btree, exists := c.GeoIndexes[fieldName]
+	if !exists {
+		return nil, false
+	}
+
+	var ids []string
+	for _, r := range geo.CoverBox(box) {
+		ids = append(ids, index.ValuesToStrings(btree.RangeSearch(r.Start, r.End, true, true))...)
+	}
+	return ids, true
+}
+
+// buildGeoIndexInternal строит гео-индекс по текущим данным, мьютексы не нужны
+func (c *Collection) buildGeoIndexInternal(fieldName string) *index.BTree {
+	btree := index.NewBPlusTree(64)
+	c.Data.Scan(func(id string, doc map[string]any) bool {
+		if point, ok := geo.ParsePoint(doc[fieldName]); ok {
+			btree.Insert(geo.Key(point), []byte(id))
+		}
+		return true
+	})
+	return btree
+}
+
+func geoIndexPath(dir, fieldName string) string {
+	return filepath.Join(dir, fieldName+geoIndexExt)
+}
+
+// loadGeoIndexInternal загружает гео-индекс без блокировок
+func (c *Collection) loadGeoIndexInternal(fieldName string) error {
+	indexPath := geoIndexPath(c.indexDir(), fieldName)
+	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
+		return nil
+	}
+	indexData, err := readBTreeFile(indexPath)
+	if err != nil {
+		return err
+	}
+	c.GeoIndexes[fieldName] = deserializeBTree(indexData)
+	return nil
+}
+
+// saveGeoIndexInternal сохраняет гео-индекс без блокировок
+func (c *Collection) saveGeoIndexInternal(fieldName string) error {
+	btree, exists := c.GeoIndexes[fieldName]
+	if !exists {
+		return Errorf(ErrNotFound, "geo index on field '%s' does not exist", fieldName)
+	}
+	return writeBTreeFile(geoIndexPath(c.indexDir(), fieldName), btree, fieldName)
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"nosql_db/internal/index"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// hashIndexExt — расширение файлов хеш-индексов
+const hashIndexExt = ".hash"
+
+// idSet — множество id документов с одинаковым значением поля
+type idSet map[string]struct{}
+
+// CreateHashIndex создаёт хеш-индекс по полю: только поиск на равенство ($eq, $in)
+func (c *Collection) CreateHashIndex(fieldName string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.HashIndexes[fieldName]; exists {
+		return Errorf(ErrExists, "hash index on field '%s' already exists", fieldName)
+	}
+	c.HashIndexes[fieldName] = c.buildHashIndexInternal(fieldName)
+	c.noteIndexInternal(IndexSpec{Type: IndexHash, Fields: []string{fieldName}})
+
+	return c.saveHashIndexInternal(fieldName)
+}
+
+// HasHashIndex проверяет существование хеш-индекса на поле
+func (c *Collection) HasHashIndex(fieldName string) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	_, exists := c.HashIndexes[fieldName]
+	return exists
+}
+
+// LookupHash возвращает id документов, у которых значение поля совпадает с одним из ключей;
+// false, если хеш-индекса нет
+func (c *Collection) LookupHash(fieldName string, keys []index.Key) ([]string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	hashIndex, exists := c.HashIndexes[fieldName]
+	if !exists {
+		return nil, false
+	}
+
+	var ids []string
+	for _, key := range keys {
+		set, ok := hashIndex.Get(string(key))
+		if !ok {
+			continue
+		}
+		start := len(ids)
+		for id := range set.(idSet) {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids[start:])
+	}
+	return ids, true
+}
+
+// buildHashIndexInternal строит хеш-индекс по текущим данным, мьютексы не нужны
+func (c *Collection) buildHashIndexInternal(fieldName string) *HashMap {
+	hashIndex := NewHashMap()
+	c.Data.Scan(func(id string, doc map[string]any) bool {
+		if fieldValue, exists := doc[fieldName]; exists {
+			hashInsert(hashIndex, index.ValueToKey(fieldValue), id)
+		}
+		return true
+	})
+	return hashIndex
+}
+
+func hashInsert(hashIndex *HashMap, key index.Key, docID string) {
+	set, ok := hashIndex.Get(string(key))
+	if !ok {
+		set = make(idSet)
+		hashIndex.Put(string(key), set)
+	}
+	set.(idSet)[docID] = struct{}{}
+}
+
+func hashDelete(hashIndex *HashMap, key index.Key, docID string) {
+	set, ok := hashIndex.Get(string(key))
+	if !ok {
+		return
+	}
+	delete(set.(idSet), docID)
+	if len(set.(idSet)) == 0 {
+		hashIndex.Remove(string(key))
+	}
+}
+
+func hashIndexPath(dir, fieldName string) string {
+	return filepath.Join(dir, fieldName+hashIndexExt)
+}
+
+// loadHashIndexInternal загружает хеш-индекс без блокировок;
+// файл старой версии кодирования ключей перестраивается из данных
+func (c *Collection) loadHashIndexInternal(fieldName string) error {
+	jsonData, err := os.ReadFile(hashIndexPath(c.indexDir(), fieldName))
+	if err != nil {
+		return fmt.Errorf("failed to read hash index file: %w", err)
+	}
+	var indexData HashIndexFile
+	if err := json.Unmarshal(jsonData, &indexData); err != nil {
+		return fmt.Errorf("failed to unmarshal hash index: %w", err)
+	}
+	if indexData.Version != indexFormatVersion {
+		c.HashIndexes[fieldName] = c.buildHashIndexInternal(fieldName)
+		return c.saveHashIndexInternal(fieldName)
+	}
+
+	hashIndex := NewHashMap()
+	for _, entry := range indexData.Entries {
+		for _, id := range entry.IDs {
+			hashInsert(hashIndex, entry.Key, id)
+		}
+	}
+	c.HashIndexes[fieldName] = hashIndex
+	return nil
+}
+
+// saveHashIndexInternal сохраняет хеш-индекс без блокировок
+func (c *Collection) saveHashIndexInternal(fieldName string) error {
+	hashIndex, exists := c.HashIndexes[fieldName]
+	if !exists {
+		return Errorf(ErrNotFound, "hash index on field '%s' does not exist", fieldName)
+	}
+
+	indexData := HashIndexFile{Version: indexFormatVersion, Field: fieldName}
+	for key, set := range hashIndex.Items() {
+		ids := make([]string, 0, len(set.(idSet)))
+		for id := range set.(idSet) {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		indexData.Entries = append(indexData.Entries, HashIndexEntry{Key: []byte(key), IDs: ids})
+	}
+
+	indexPath := hashIndexPath(c.indexDir(), fieldName)
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	jsonData, err := json.Marshal(indexData)
+	if err != nil {
+		return fmt.Errorf("failed to marshal hash index: %w", err)
+	}
+	if err := os.WriteFile(indexPath, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write hash index file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"nosql_db/internal/index"
+)
+
+func TestHashIndex(t *testing.T) {
+	dir := t.TempDir()
+	m := openTestManager(t, dir)
+	ns := testNS("users")
+
+	ids := map[string]string{}
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		for _, name := range []string{"ann", "bob", "cid"} {
+			id, err := coll.Insert(map[string]any{"name": name, "city": "Moscow"})
+			if err != nil {
+				return WriteResult{}, err
+			}
+			ids[name] = id
+		}
+		if _, err := coll.Insert(map[string]any{"name": "dan", "city": 1.0}); err != nil {
+			return WriteResult{}, err
+		}
+		return WriteResult{}, coll.CreateHashIndex("city")
+	})
+	if result := m.Enqueue(ns, func(coll *Collection) (WriteResult, error) {
+		return WriteResult{}, coll.CreateHashIndex("city")
+	}); !errors.Is(result.Error, ErrExists) {
+		t.Fatalf("second hash index on the field: %v", result.Error)
+	}
+
+	lookup := func(m *CollectionMng, values ...any) []string {
+		t.Helper()
+		keys := make([]index.Key, len(values))
+		for i, v := range values {
+			keys[i] = index.ValueToKey(v)
+		}
+		var found []string
+		withCollection(t, m, ns, func(coll *Collection) {
+			var ok bool
+			if found, ok = coll.LookupHash("city", keys); !ok {
+				t.Fatal("hash index is missing")
+			}
+		})
+		slices.Sort(found)
+		return found
+	}
+	names := func(names ...string) []string {
+		found := make([]string, len(names))
+		for i, name := range names {
+			found[i] = ids[name]
+		}
+		slices.Sort(found)
+		return found
+	}
+
+	if got := lookup(m, "Moscow"); !slices.Equal(got, names("ann", "bob", "cid")) {
+		t.Fatalf("lookup Moscow: %v", got)
+	}
+	// строка "1" и число 1 — разные ключи
+	if got := lookup(m, "1"); len(got) != 0 {
+		t.Fatalf("lookup \"1\": %v", got)
+	}
+
+	// индекс следует за изменениями и удалениями
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		if _, err := coll.Replace(ids["bob"], map[string]any{"name": "bob", "city": "Kazan"}); err != nil {
+			return WriteResult{}, err
+		}
+		coll.Delete(ids["cid"])
+		return WriteResult{}, nil
+	})
+	if got := lookup(m, "Moscow", "Kazan"); !slices.Equal(got, names("ann", "bob")) {
+		t.Fatalf("lookup after update and delete: %v", got)
+	}
+
+	// индекс сохраняется на диск и загружается после перезапуска
+	if got := lookup(openTestManager(t, dir), "Kazan"); !slices.Equal(got, names("bob")) {
+		t.Fatalf("lookup after reopen: %v", got)
+	}
+
+	withCollection(t, m, ns, func(coll *Collection) {
+		if _, ok := coll.LookupHash("name", []index.Key{index.ValueToKey("ann")}); ok || coll.HasHashIndex("name") {
+			t.Fatal("no hash index on name")
+		}
+	})
+}
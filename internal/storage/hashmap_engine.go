@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// hashMapEngine — движок по умолчанию: все документы в HashMap, изменения
+// дописываются в append-only сегменты (см. segments.go)
+type hashMapEngine struct {
+	mu    sync.RWMutex
+	data  *HashMap
+	dirty map[string]struct{} // документы, изменённые после последнего Flush
+	store *segmentStore
+	bytes int64 // оценка памяти документов
+
+	compaction sync.WaitGroup // фоновая компактизация; Close ждёт её завершения
+}
+
+// openHashMapEngine применяет сегменты из манифеста; данные в старом формате
+// (один JSON-файл legacyPath) переводятся в сегменты
+func openHashMapEngine(dir, legacyPath string) (*hashMapEngine, error) {
+	e := &hashMapEngine{data: NewHashMap(), dirty: make(map[string]struct{})}
+	st, found, err := openStore(dir, e.data)
+	if err != nil {
+		return nil, err
+	}
+	e.store = st
+	if !found {
+		if err := e.migrateLegacyFile(legacyPath); err != nil {
+			return nil, err
+		}
+	}
+	for id, v := range e.data.Items() {
+		e.bytes += entryOverhead + int64(len(id)) + valueSize(v)
+	}
+	return e, nil
+}
+
+// migrateLegacyFile переносит данные из JSON-файла в сегмент и удаляет старый файл.
+// Файлы индексов старого формата сохранялись при каждой записи, поэтому остаются актуальными
+func (e *hashMapEngine) migrateLegacyFile(path string) error {
+	bytes, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Файл может существовать, но быть пустым или содержать только пробелы
+	var raw map[string]any
+	if len(strings.TrimSpace(string(bytes))) > 0 {
+		if err := json.Unmarshal(bytes, &raw); err != nil {
+			return fmt.Errorf("unmarshal error: %w", err)
+		}
+	}
+	docs := make([]map[string]any, 0, len(raw))
+	for k, v := range raw {
+		if doc, ok := v.(map[string]any); ok {
+			e.data.Put(k, doc)
+			docs = append(docs, doc)
+		}
+	}
+
+	st := e.store
+	if err := os.MkdirAll(st.dir, 0755); err != nil {
+		return fmt.Errorf("mkdir error: %w", err)
+	}
+	name := st.reserveName()
+	if err := writeSegment(filepath.Join(st.dir, name), docs, 1); err != nil {
+		return fmt.Errorf("failed to write segment: %w", err)
+	}
+	st.lsn = 1
+	st.counts[name] = len(docs)
+	st.manifest.Segments = []string{name}
+	st.manifest.IndexLSN = st.lsn
+	if err := st.writeManifest(); err != nil {
+		return err
+	}
+	log.Printf("%s migrated to segment files", path)
+	return os.Remove(path)
+}
+
+// legacyIndexLSN — LSN файлов индексов из манифеста (до появления метаданных коллекции)
+func (e *hashMapEngine) legacyIndexLSN() uint64 {
+	e.store.mu.Lock()
+	defer e.store.mu.Unlock()
+	return e.store.manifest.IndexLSN
+}
+
+func (e *hashMapEngine) Get(id string) (map[string]any, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	val, ok := e.data.Get(id)
+	if !ok {
+		return nil, false
+	}
+	doc, ok := val.(map[string]any)
+	return doc, ok
+}
+
+func (e *hashMapEngine) Put(id string, doc map[string]any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if old, ok := e.data.Get(id); ok {
+		e.bytes -= documentSize(old.(map[string]any))
+	} else {
+		e.bytes += entryOverhead + int64(len(id))
+	}
+	e.bytes += documentSize(doc)
+	e.data.Put(id, doc)
+	e.dirty[id] = struct{}{}
+}
+
+func (e *hashMapEngine) Delete(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.dirty[id] = struct{}{}
+	if old, ok := e.data.Get(id); ok {
+		e.bytes -= entryOverhead + int64(len(id)) + documentSize(old.(map[string]any))
+	}
+	return e.data.Remove(id)
+}
+
+func (e *hashMapEngine) MemoryUsage() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.bytes
+}
+
+// Scan обходит копию набора документов: fn может обращаться к движку
+func (e *hashMapEngine) Scan(fn func(id string, doc map[string]any) bool) {
+	snap := e.Snapshot()
+	defer snap.Release()
+	snap.Scan(fn)
+}
+
+func (e *hashMapEngine) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.data.Size
+}
+
+func (e *hashMapEngine) Snapshot() EngineSnapshot {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	items := e.data.Items()
+	snap := make(mapSnapshot, len(items))
+	for id, v := range items {
+		if doc, ok := v.(map[string]any); ok {
+			snap[id] = doc
+		}
+	}
+	return snap
+}
+
+// Flush дописывает в журнал изменения с прошлого сброса: текущую версию
+// изменённых документов (put) или tombstone удалённых (del). Когда записей
+// в сегментах становится слишком много, запускает компактизацию
+func (e *hashMapEngine) Flush(sync bool) error {
+	st := e.store
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	e.mu.Lock()
+	records := make([]record, 0, len(e.dirty))
+	for id := range e.dirty {
+		if val, ok := e.data.Get(id); ok {
+			records = append(records, record{Op: opPut, ID: id, Doc: val.(map[string]any)})
+		} else {
+			records = append(records, record{Op: opDel, ID: id})
+		}
+	}
+	dirty := e.dirty
+	e.dirty = make(map[string]struct{})
+	e.mu.Unlock()
+
+	if err := st.append(records); err != nil {
+		// изменения попадут в следующий сброс
+		e.mu.Lock()
+		for id := range dirty {
+			e.dirty[id] = struct{}{}
+		}
+		e.mu.Unlock()
+		return err
+	}
+	if sync {
+		if err := st.sync(); err != nil {
+			return fmt.Errorf("fsync %s: %w", st.dir, err)
+		}
+	}
+	if records := st.records(); records > compactMinRecords && records > compactRatio*e.Len() {
+		e.startCompactionLocked()
+	}
+	return nil
+}
+
+// startCompactionLocked закрывает активный сегмент, снимает копию данных и сливает
+// закрытые сегменты в фоне. Вызывается из Flush под st.mu: Flush выполняется между
+// задачами worker'а, поэтому данные в памяти совпадают с записанными в журнал
+func (e *hashMapEngine) startCompactionLocked() {
+	st := e.store
+	if !st.compacting.CompareAndSwap(false, true) {
+		return
+	}
+	sealed, err := st.rotate()
+	if err != nil {
+		st.compacting.Store(false)
+		log.Printf("compaction of %s failed: %v", st.dir, err)
+		return
+	}
+	snap := e.Snapshot()
+	lsn := st.lsn
+	name := st.reserveName()
+
+	e.compaction.Add(1)
+	go func() {
+		defer e.compaction.Done()
+		defer st.compacting.Store(false)
+		if err := e.compact(snap, sealed, name, lsn); err != nil {
+			log.Printf("compaction of %s failed: %v", st.dir, err)
+		}
+	}()
+}
+
+// compact пишет снимок в новый сегмент и заменяет им закрытые сегменты. Новые записи
+// в это время идут в новый активный сегмент, который остаётся в манифесте после слитого
+func (e *hashMapEngine) compact(snap EngineSnapshot, sealed []string, name string, lsn uint64) error {
+	st := e.store
+	var docs []map[string]any
+	snap.Scan(func(id string, doc map[string]any) bool {
+		docs = append(docs, doc)
+		return true
+	})
+	snap.Release()
+	if err := writeSegment(filepath.Join(st.dir, name), docs, lsn); err != nil {
+		os.Remove(filepath.Join(st.dir, name))
+		return fmt.Errorf("failed to write compacted segment: %w", err)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	live := []string{name}
+	for _, segment := range st.manifest.Segments {
+		if !containsString(sealed, segment) {
+			live = append(live, segment)
+		}
+	}
+	previous, valid := st.manifest.Segments, st.manifest.Valid
+	st.manifest.Segments = live
+	st.manifest.Valid = nil
+	for segment, size := range valid {
+		if !containsString(sealed, segment) {
+			if st.manifest.Valid == nil {
+				st.manifest.Valid = make(map[string]int64)
+			}
+			st.manifest.Valid[segment] = size
+		}
+	}
+	if err := st.writeManifest(); err != nil {
+		st.manifest.Segments, st.manifest.Valid = previous, valid
+		os.Remove(filepath.Join(st.dir, name))
+		return err
+	}
+	for _, segment := range sealed {
+		os.Remove(filepath.Join(st.dir, segment))
+		delete(st.counts, segment)
+	}
+	st.counts[name] = len(docs)
+	return nil
+}
+
+func (e *hashMapEngine) LSN() uint64 {
+	e.store.mu.Lock()
+	defer e.store.mu.Unlock()
+	return e.store.lsn
+}
+
+// Close дожидается фоновой компактизации и закрывает активный сегмент. Без ожидания
+// движок, открытый в том же каталоге после выгрузки или переименования, мог бы взять
+// имя сегмента, зарезервированное компактизацией, а она — записать манифест без его сегментов
+func (e *hashMapEngine) Close() error {
+	e.compaction.Wait()
+	e.store.mu.Lock()
+	defer e.store.mu.Unlock()
+	return e.store.close()
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
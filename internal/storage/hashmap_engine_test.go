@@ -0,0 +1,280 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openTestEngine открывает движок hashmap в каталоге dir
+func openTestEngine(t *testing.T, dir string) *hashMapEngine {
+	t.Helper()
+	e, err := openHashMapEngine(dir, filepath.Join(dir, "legacy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func testDoc(id string, n int) map[string]any {
+	return map[string]any{"_id": id, "n": float64(n)}
+}
+
+// segmentFiles возвращает имена файлов сегментов в каталоге
+func segmentFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+segmentExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range files {
+		files[i] = filepath.Base(files[i])
+	}
+	return files
+}
+
+func TestSegmentsReopen(t *testing.T) {
+	dir := t.TempDir()
+	e := openTestEngine(t, dir)
+	for i := 0; i < 5; i++ {
+		e.Put(fmt.Sprint(i), testDoc(fmt.Sprint(i), i))
+	}
+	if err := e.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+	e.Put("1", testDoc("1", 10))
+	e.Delete("2")
+	e.Delete("missing")
+	if err := e.Flush(true); err != nil {
+		t.Fatal(err)
+	}
+	// несброшенное изменение при «сбое» теряется
+	e.Put("5", testDoc("5", 5))
+	lsn := e.LSN()
+
+	reopened := openTestEngine(t, dir)
+	if reopened.Len() != 4 || reopened.LSN() != lsn {
+		t.Fatalf("reopened: %d document(s), lsn %d (want %d)", reopened.Len(), reopened.LSN(), lsn)
+	}
+	if doc, _ := reopened.Get("1"); doc["n"] != 10.0 {
+		t.Fatalf("updated document: %v", doc)
+	}
+	for _, id := range []string{"2", "5"} {
+		if _, ok := reopened.Get(id); ok {
+			t.Fatalf("%s must be missing after reopen", id)
+		}
+	}
+}
+
+func TestSegmentsTornWrite(t *testing.T) {
+	dir := t.TempDir()
+	e := openTestEngine(t, dir)
+	e.Put("a", testDoc("a", 1))
+	if err := e.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+	e.Close()
+
+	// недописанная последняя строка отбрасывается, файл обрезается
+	segments := segmentFiles(t, dir)
+	path := filepath.Join(dir, segments[len(segments)-1])
+	before, _ := os.Stat(path)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"lsn":2,"op":"put","id":"b","doc":{"_id":"b"`)
+	f.Close()
+
+	reopened := openTestEngine(t, dir)
+	if _, ok := reopened.Get("b"); ok || reopened.Len() != 1 {
+		t.Fatalf("torn record was applied: %d document(s)", reopened.Len())
+	}
+	if after, _ := os.Stat(path); after.Size() != before.Size() {
+		t.Fatalf("segment is %d bytes, want %d", after.Size(), before.Size())
+	}
+	reopened.Put("b", testDoc("b", 2))
+	if err := reopened.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+	if again := openTestEngine(t, dir); again.Len() != 2 {
+		t.Fatalf("after rewrite: %d document(s)", again.Len())
+	}
+}
+
+// shortWriteFile дописывает в сегмент только часть буфера и возвращает ошибку
+type shortWriteFile struct {
+	*os.File
+}
+
+func (f shortWriteFile) Write(p []byte) (int, error) {
+	n, _ := f.File.Write(p[:len(p)/2])
+	return n, errors.New("disk full")
+}
+
+func TestSegmentsFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	e := openTestEngine(t, dir)
+	e.Put("a", testDoc("a", 1))
+	if err := e.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+
+	// часть записи уже в файле: append возвращает сегмент к концу целой записи
+	active := e.store.active
+	e.store.active = shortWriteFile{active.(*os.File)}
+	e.Put("b", testDoc("b", 2))
+	if err := e.Flush(false); err == nil {
+		t.Fatal("flush must fail")
+	}
+	e.store.active = active
+
+	// изменение осталось несброшенным и попадает в следующую запись
+	e.Put("c", testDoc("c", 3))
+	if err := e.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+	e.Close()
+	reopened := openTestEngine(t, dir)
+	for _, id := range []string{"a", "b", "c"} {
+		if _, ok := reopened.Get(id); !ok {
+			t.Fatalf("%s is missing after reopen: %d document(s)", id, reopened.Len())
+		}
+	}
+}
+
+// stuckFile — сегмент, который после неполной записи не удаётся и обрезать
+type stuckFile struct {
+	shortWriteFile
+}
+
+func (f stuckFile) Truncate(int64) error {
+	return errors.New("read-only file system")
+}
+
+func TestSegmentsFailedTruncate(t *testing.T) {
+	dir := t.TempDir()
+	e := openTestEngine(t, dir)
+	e.Put("a", testDoc("a", 1))
+	if err := e.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+
+	// недописанная строка остаётся в сегменте, следующие записи идут в новый
+	e.store.active = stuckFile{shortWriteFile{e.store.active.(*os.File)}}
+	e.Put("b", testDoc("b", 2))
+	if err := e.Flush(false); err == nil {
+		t.Fatal("flush must fail")
+	}
+	e.Put("c", testDoc("c", 3))
+	if err := e.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+	if segments := segmentFiles(t, dir); len(segments) != 2 {
+		t.Fatalf("segments after the failed truncate: %v", segments)
+	}
+	e.Close()
+
+	// replay закрытого сегмента останавливается на последней целой записи
+	reopened := openTestEngine(t, dir)
+	for _, id := range []string{"a", "b", "c"} {
+		if _, ok := reopened.Get(id); !ok {
+			t.Fatalf("%s is missing after reopen: %d document(s)", id, reopened.Len())
+		}
+	}
+}
+
+func TestSegmentsCompaction(t *testing.T) {
+	dir := t.TempDir()
+	e := openTestEngine(t, dir)
+
+	// 10 документов переписываются много раз: записей в сегментах гораздо больше, чем документов
+	for round := 0; round < 150; round++ {
+		for i := 0; i < 10; i++ {
+			e.Put(fmt.Sprint(i), testDoc(fmt.Sprint(i), round))
+		}
+		if err := e.Flush(false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for e.store.compacting.Load() {
+		if time.Now().After(deadline) {
+			t.Fatal("compaction did not finish")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	e.store.mu.Lock()
+	records, live := e.store.records(), len(e.store.manifest.Segments)
+	e.store.mu.Unlock()
+	if records >= compactMinRecords {
+		t.Fatalf("%d record(s) left after compaction", records)
+	}
+	if files := segmentFiles(t, dir); len(files) != live {
+		t.Fatalf("segment files %v, manifest lists %d", files, live)
+	}
+
+	reopened := openTestEngine(t, dir)
+	for i := 0; i < 10; i++ {
+		if doc, ok := reopened.Get(fmt.Sprint(i)); !ok || doc["n"] != 149.0 {
+			t.Fatalf("document %d after compaction: %v", i, doc)
+		}
+	}
+}
+
+func TestLegacyFileMigration(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "legacy.json")
+	if err := os.WriteFile(legacy, []byte(`{"a": {"_id": "a", "n": 1}, "b": {"_id": "b", "n": 2}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	e := openTestEngine(t, dir)
+	if e.Len() != 2 {
+		t.Fatalf("%d document(s) after migration", e.Len())
+	}
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Fatalf("legacy file is still there: %v", err)
+	}
+	if reopened := openTestEngine(t, dir); reopened.Len() != 2 {
+		t.Fatalf("%d document(s) after reopen", reopened.Len())
+	}
+}
+
+func TestCloseWaitsForCompaction(t *testing.T) {
+	dir := t.TempDir()
+	e := openTestEngine(t, dir)
+	for round := 0; round < 150; round++ {
+		for i := 0; i < 10; i++ {
+			e.Put(fmt.Sprint(i), testDoc(fmt.Sprint(i), round))
+		}
+		if err := e.Flush(false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// после Close компактизация не пишет в каталог: следующий движок открывает его сразу
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if e.store.compacting.Load() {
+		t.Fatal("compaction is still running after Close")
+	}
+
+	next := openTestEngine(t, dir)
+	next.Put("new", testDoc("new", 1))
+	if err := next.Flush(false); err != nil {
+		t.Fatal(err)
+	}
+	next.Close()
+	reopened := openTestEngine(t, dir)
+	if doc, ok := reopened.Get("new"); !ok || doc["n"] != 1.0 || reopened.Len() != 11 {
+		t.Fatalf("after reopen: %d document(s), new %v", reopened.Len(), doc)
+	}
+	if files := segmentFiles(t, dir); len(files) != len(reopened.store.manifest.Segments) {
+		t.Fatalf("segment files %v, manifest %v", files, reopened.store.manifest.Segments)
+	}
+}
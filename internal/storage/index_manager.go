@@ -3,36 +3,44 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"nosql_db/internal/fulltext"
+	"nosql_db/internal/geo"
 	"nosql_db/internal/index"
+	"nosql_db/internal/vector"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
+// btreeIndexExt — расширение файлов b-tree индексов в каталоге индексов коллекции
+const btreeIndexExt = ".idx"
+
 // CreateIndex создает индекс на указанном поле
 func (c *Collection) CreateIndex(fieldName string, order int) error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
 	if _, exists := c.Indexes[fieldName]; exists {
-		return fmt.Errorf("index on field '%s' already exists", fieldName)
+		return Errorf(ErrExists, "index on field '%s' already exists", fieldName)
 	}
+	c.Indexes[fieldName] = c.buildIndexInternal(fieldName, order)
+	c.noteIndexInternal(IndexSpec{Type: IndexBTree, Fields: []string{fieldName}})
+
+	return c.saveIndexInternal(fieldName)
+}
+
+// buildIndexInternal строит b-tree по полю из текущих данных, мьютексы не нужны
+func (c *Collection) buildIndexInternal(fieldName string, order int) *index.BTree {
 	btree := index.NewBPlusTree(order)
 
-	items := c.Data.Items()
-	for _, v := range items {
-		doc, ok := v.(map[string]any)
-		if !ok {
-			continue
-		}
+	c.Data.Scan(func(docID string, doc map[string]any) bool {
 		if fieldValue, exists := doc[fieldName]; exists {
-			docID := doc["_id"].(string)
 			key := index.ValueToKey(fieldValue)
 			btree.Insert(key, []byte(docID))
 		}
-	}
-	c.Indexes[fieldName] = btree
-
-	return c.saveIndexInternal(fieldName)
+		return true
+	})
+	return btree
 }
 
 // HasIndex проверяет существование индекса на поле
@@ -60,20 +68,50 @@ func (c *Collection) LoadIndex(fieldName string) error {
 
 // loadIndexInternal - приватная версия без блокировок
 func (c *Collection) loadIndexInternal(fieldName string) error {
-	indexPath := filepath.Join("data", "indexes", fmt.Sprintf("%s_%s.idx", c.Name, fieldName))
+	indexPath := filepath.Join(c.indexDir(), fieldName+btreeIndexExt)
 	if _, err := os.Stat(indexPath); os.IsNotExist(err) {
 		return nil
 	}
-	jsonData, err := os.ReadFile(indexPath)
+	indexData, err := readBTreeFile(indexPath)
+	if err != nil {
+		return err
+	}
+	if indexData.Version < indexFormatVersion {
+		// ключи в старом формате: перестраиваем индекс и перезаписываем файл
+		c.Indexes[fieldName] = c.buildIndexInternal(fieldName, 64)
+		return c.saveIndexInternal(fieldName)
+	}
+	btree := deserializeBTree(indexData)
+	c.Indexes[fieldName] = btree
+	return nil
+}
+
+// readBTreeFile читает сериализованный b-tree из файла
+func readBTreeFile(path string) (*IndexFile, error) {
+	jsonData, err := os.ReadFile(path)
 	if err != nil {
-		return fmt.Errorf("failed to read index file: %w", err)
+		return nil, fmt.Errorf("failed to read index file: %w", err)
 	}
 	var indexData IndexFile
 	if err := json.Unmarshal(jsonData, &indexData); err != nil {
-		return fmt.Errorf("failed to unmarshal index: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal index: %w", err)
+	}
+	return &indexData, nil
+}
+
+// writeBTreeFile сериализует b-tree и записывает его в файл
+func writeBTreeFile(path string, btree *index.BTree, fieldName string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	indexData := serializeBTree(btree, fieldName, 64)
+	jsonData, err := json.MarshalIndent(indexData, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+	if err := os.WriteFile(path, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write index file: %w", err)
 	}
-	btree := deserializeBTree(&indexData)
-	c.Indexes[fieldName] = btree
 	return nil
 }
 
@@ -82,23 +120,39 @@ func (c *Collection) LoadAllIndexes() error {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
-	indexDir := filepath.Join("data", "indexes")
-	if _, err := os.Stat(indexDir); os.IsNotExist(err) {
+	entries, err := os.ReadDir(c.indexDir())
+	if os.IsNotExist(err) {
 		return nil
 	}
-	entries, err := os.ReadDir(indexDir)
 	if err != nil {
 		return fmt.Errorf("failed to read index directory: %w", err)
 	}
-	prefix := c.Name + "_"
 	for _, entry := range entries {
 		if entry.IsDir() {
 			continue
 		}
 		name := entry.Name()
-		if len(name) > len(prefix) && name[:len(prefix)] == prefix && filepath.Ext(name) == ".idx" {
-			fieldName := name[len(prefix) : len(name)-4]
-			if err := c.loadIndexInternal(fieldName); err != nil {
+		ext := filepath.Ext(name)
+		indexName := strings.TrimSuffix(name, ext)
+		switch ext {
+		case btreeIndexExt:
+			if err := c.loadIndexInternal(indexName); err != nil {
+				return err
+			}
+		case textIndexExt:
+			if err := c.loadTextIndexInternal(indexName); err != nil {
+				return err
+			}
+		case geoIndexExt:
+			if err := c.loadGeoIndexInternal(indexName); err != nil {
+				return err
+			}
+		case vectorIndexExt:
+			if err := c.loadVectorIndexInternal(indexName); err != nil {
+				return err
+			}
+		case hashIndexExt:
+			if err := c.loadHashIndexInternal(indexName); err != nil {
 				return err
 			}
 		}
@@ -117,21 +171,10 @@ func (c *Collection) SaveIndex(fieldName string) error {
 func (c *Collection) saveIndexInternal(fieldName string) error {
 	btree, exists := c.Indexes[fieldName]
 	if !exists {
-		return fmt.Errorf("index on field '%s' does not exist", fieldName)
+		return Errorf(ErrNotFound, "index on field '%s' does not exist", fieldName)
 	}
-	indexPath := filepath.Join("data", "indexes", fmt.Sprintf("%s_%s.idx", c.Name, fieldName))
-	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
-		return fmt.Errorf("failed to create index directory: %w", err)
-	}
-	indexData := serializeBTree(btree, fieldName, 64)
-	jsonData, err := json.MarshalIndent(indexData, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal index: %w", err)
-	}
-	if err := os.WriteFile(indexPath, jsonData, 0644); err != nil {
-		return fmt.Errorf("failed to write index file: %w", err)
-	}
-	return nil
+	indexPath := filepath.Join(c.indexDir(), fieldName+btreeIndexExt)
+	return writeBTreeFile(indexPath, btree, fieldName)
 }
 
 // SaveAllIndexes сохраняет все индексы на диск
@@ -144,6 +187,26 @@ func (c *Collection) SaveAllIndexes() error {
 			return err
 		}
 	}
+	for name := range c.TextIndexes {
+		if err := c.saveTextIndexInternal(name); err != nil {
+			return err
+		}
+	}
+	for fieldName := range c.GeoIndexes {
+		if err := c.saveGeoIndexInternal(fieldName); err != nil {
+			return err
+		}
+	}
+	for fieldName := range c.VecIndexes {
+		if err := c.saveVectorIndexInternal(fieldName); err != nil {
+			return err
+		}
+	}
+	for fieldName := range c.HashIndexes {
+		if err := c.saveHashIndexInternal(fieldName); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -158,24 +221,49 @@ func (c *Collection) RebuildAllIndexes() error {
 	}
 	c.Indexes = make(map[string]*index.BTree)
 
-	items := c.Data.Items()
-
 	for _, fieldName := range fields {
-		btree := index.NewBPlusTree(64)
-		for _, v := range items {
-			doc, ok := v.(map[string]any)
-			if !ok {
-				continue
-			}
+		c.Indexes[fieldName] = c.buildIndexInternal(fieldName, 64)
+		if err := c.saveIndexInternal(fieldName); err != nil {
+			return err
+		}
+	}
 
-			if fieldValue, exists := doc[fieldName]; exists {
-				docID := doc["_id"].(string)
-				key := index.ValueToKey(fieldValue)
-				btree.Insert(key, []byte(docID))
+	for name, textIndex := range c.TextIndexes {
+		rebuilt := fulltext.NewIndex(textIndex.Fields())
+		c.Data.Scan(func(id string, doc map[string]any) bool {
+			rebuilt.Add(id, doc)
+			return true
+		})
+		c.TextIndexes[name] = rebuilt
+		if err := c.saveTextIndexInternal(name); err != nil {
+			return err
+		}
+	}
+
+	for fieldName := range c.GeoIndexes {
+		c.GeoIndexes[fieldName] = c.buildGeoIndexInternal(fieldName)
+		if err := c.saveGeoIndexInternal(fieldName); err != nil {
+			return err
+		}
+	}
+
+	for fieldName, vecIndex := range c.VecIndexes {
+		rebuilt := vector.NewIndex(vecIndex.Metric(), vecIndex.Dims())
+		c.Data.Scan(func(id string, doc map[string]any) bool {
+			if vec, ok := vector.Parse(doc[fieldName]); ok {
+				rebuilt.Add(id, vec)
 			}
+			return true
+		})
+		c.VecIndexes[fieldName] = rebuilt
+		if err := c.saveVectorIndexInternal(fieldName); err != nil {
+			return err
 		}
-		c.Indexes[fieldName] = btree
-		if err := c.saveIndexInternal(fieldName); err != nil {
+	}
+
+	for fieldName := range c.HashIndexes {
+		c.HashIndexes[fieldName] = c.buildHashIndexInternal(fieldName)
+		if err := c.saveHashIndexInternal(fieldName); err != nil {
 			return err
 		}
 	}
@@ -190,6 +278,28 @@ func (c *Collection) updateIndexesOnInsert(docID string, doc map[string]any) {
 			btree.Insert(key, []byte(docID))
 		}
 	}
+	for _, textIndex := range c.TextIndexes {
+		textIndex.Add(docID, doc)
+	}
+	for fieldName, btree := range c.GeoIndexes {
+		if point, ok := geo.ParsePoint(doc[fieldName]); ok {
+			btree.Insert(geo.Key(point), []byte(docID))
+		}
+	}
+	for fieldName, vecIndex := range c.VecIndexes {
+		if vec, ok := vector.Parse(doc[fieldName]); ok {
+			vecIndex.Add(docID, vec)
+		}
+	}
+	for fieldName, hashIndex := range c.HashIndexes {
+		if fieldValue, exists := doc[fieldName]; exists {
+			hashInsert(hashIndex, index.ValueToKey(fieldValue), docID)
+		}
+	}
+	c.noteExpiryInternal(doc)
+	if c.capped != nil {
+		c.capped.add(docID, doc)
+	}
 }
 
 // updateIndexesOnDelete (Приватный) - вызывается внутри Delete, мьютексы не нужны
@@ -200,4 +310,36 @@ func (c *Collection) updateIndexesOnDelete(docID string, doc map[string]any) {
 			btree.Delete(key, []byte(docID))
 		}
 	}
+	for _, textIndex := range c.TextIndexes {
+		textIndex.Remove(docID, doc)
+	}
+	for fieldName, btree := range c.GeoIndexes {
+		if point, ok := geo.ParsePoint(doc[fieldName]); ok {
+			btree.Delete(geo.Key(point), []byte(docID))
+		}
+	}
+	for _, vecIndex := range c.VecIndexes {
+		vecIndex.Remove(docID)
+	}
+	for fieldName, hashIndex := range c.HashIndexes {
+		if fieldValue, exists := doc[fieldName]; exists {
+			hashDelete(hashIndex, index.ValueToKey(fieldValue), docID)
+		}
+	}
+	if c.capped != nil {
+		c.capped.remove(docID, doc)
+	}
+}
+
+// ReadIndex вызывает fn с индексом поля под блокировкой чтения коллекции;
+// false, если индекса нет
+func (c *Collection) ReadIndex(fieldName string, fn func(btree *index.BTree)) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	btree, exists := c.Indexes[fieldName]
+	if !exists {
+		return false
+	}
+	fn(btree)
+	return true
 }
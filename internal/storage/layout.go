@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Каталог данных: <datadir>/<база>/<коллекция>/ — файлы движка, collection.json
+// и indexes/<индекс>.<тип>. Файл LAYOUT отмечает, что данные в этом формате
+
+// DefaultDatabase — база запросов, в которых она не указана
+const DefaultDatabase = "default"
+
+const (
+	layoutName    = "LAYOUT"
+	layoutVersion = 2
+
+	indexDirName = "indexes"
+	legacyExt    = ".json"
+)
+
+// defaultDataDir — каталог данных менеджера, пока OpenDataDir не задал другой
+const defaultDataDir = "data"
+
+// Namespace — коллекция в базе данных
+type Namespace struct {
+	DB   string `json:"db"`
+	Coll string `json:"coll"`
+}
+
+func (ns Namespace) String() string {
+	return ns.DB + "." + ns.Coll
+}
+
+// ValidateName проверяет имя базы или коллекции: оно становится именем каталога
+func ValidateName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s name is required", kind)
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") || len(name) > 128 {
+		return fmt.Errorf("invalid %s name: %q", kind, name)
+	}
+	return nil
+}
+
+// ValidateDatabaseName проверяет имя базы: кроме правил ValidateName, в нём нельзя точку —
+// она отделяет базу от коллекции в Namespace.String и в списке прогрева
+func ValidateDatabaseName(kind, name string) error {
+	if err := ValidateName(kind, name); err != nil {
+		return err
+	}
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("invalid %s name: %q (a dot separates the database from the collection)", kind, name)
+	}
+	return nil
+}
+
+func databaseDir(root, db string) string {
+	return filepath.Join(root, db)
+}
+
+func collectionDir(root string, ns Namespace) string {
+	return filepath.Join(root, ns.DB, ns.Coll)
+}
+
+// legacyCollectionPath — JSON-файл коллекции самого старого формата
+func legacyCollectionPath(root string, ns Namespace) string {
+	return filepath.Join(root, ns.DB, ns.Coll+legacyExt)
+}
+
+// indexDir — каталог файлов индексов коллекции
+func (c *Collection) indexDir() string {
+	return filepath.Join(collectionDir(c.root, c.Namespace()), indexDirName)
+}
+
+// OpenDataDir задаёт каталог данных менеджера; вызывается до первого обращения к коллекциям.
+// При первом запуске на нём данные плоского формата (collections/<коллекция>/,
+// indexes/<коллекция>_<индекс>, <коллекция>.json) переносятся в базу по умолчанию
+func (m *CollectionMng) OpenDataDir(dir string) error {
+	m.dir = dir
+	marker := filepath.Join(dir, layoutName)
+	if _, err := os.Stat(marker); err == nil {
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("mkdir error: %w", err)
+	}
+	if err := migrateFlatLayout(dir); err != nil {
+		return fmt.Errorf("failed to migrate data layout: %w", err)
+	}
+	raw, err := json.Marshal(map[string]int{"version": layoutVersion})
+	if err != nil {
+		return err
+	}
+	if err := writeFileSync(marker, raw); err != nil {
+		return fmt.Errorf("write layout marker: %w", err)
+	}
+	return syncPath(dir)
+}
+
+// migrateFlatLayout переносит коллекции плоского формата в базу по умолчанию.
+// Переносы — переименования, поэтому прерванная миграция продолжается при следующем запуске
+func migrateFlatLayout(dir string) error {
+	target := filepath.Join(dir, DefaultDatabase)
+	move := func(from, to string) error {
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return fmt.Errorf("mkdir error: %w", err)
+		}
+		return os.Rename(from, to)
+	}
+
+	flatDir := filepath.Join(dir, "collections")
+	entries, err := os.ReadDir(flatDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := move(filepath.Join(flatDir, entry.Name()), filepath.Join(target, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	entries, err = os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == legacyExt {
+			if err := move(filepath.Join(dir, entry.Name()), filepath.Join(target, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	// файлы индексов назывались <коллекция>_<индекс>.<тип>: владелец — коллекция
+	// с самым длинным подходящим именем
+	names, err := diskCollections(target)
+	if err != nil {
+		return err
+	}
+	flatIndexDir := filepath.Join(dir, indexDirName)
+	entries, err = os.ReadDir(flatIndexDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for _, entry := range entries {
+		owner := ""
+		for _, name := range names {
+			if strings.HasPrefix(entry.Name(), name+"_") && len(name) > len(owner) {
+				owner = name
+			}
+		}
+		if owner == "" {
+			log.Printf("data layout: index file %s has no collection, left in place", entry.Name())
+			continue
+		}
+		to := filepath.Join(target, owner, indexDirName, strings.TrimPrefix(entry.Name(), owner+"_"))
+		if err := move(filepath.Join(flatIndexDir, entry.Name()), to); err != nil {
+			return err
+		}
+	}
+
+	// каталоги плоского формата удаляются, только если опустели
+	os.Remove(flatDir)
+	os.Remove(flatIndexDir)
+	return syncPath(target)
+}
+
+// diskCollections возвращает коллекции базы на диске: каталоги и JSON-файлы старого формата
+func diskCollections(dbDir string) ([]string, error) {
+	entries, err := os.ReadDir(dbDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		switch {
+		case entry.IsDir():
+			names = append(names, entry.Name())
+		case filepath.Ext(entry.Name()) == legacyExt:
+			names = append(names, strings.TrimSuffix(entry.Name(), legacyExt))
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"encoding/json"
+	"log"
+	"nosql_db/internal/lsm"
+	"sync"
+)
+
+// lsmEngine хранит документы коллекции в LSM-дереве в виде JSON.
+// Изменения копятся в memtable и при Flush дописываются в WAL дерева; заполненная
+// memtable сбрасывается в таблицу в фоне
+type lsmEngine struct {
+	mu    sync.Mutex // упорядочивает изменения и счётчик документов
+	db    *lsm.DB
+	count int
+}
+
+func openLSMEngine(dir string) (*lsmEngine, error) {
+	db, err := lsm.Open(dir, lsm.Options{})
+	if err != nil {
+		return nil, err
+	}
+	e := &lsmEngine{db: db}
+	if err := db.Scan(func(string, []byte) bool {
+		e.count++
+		return true
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *lsmEngine) Get(id string) (map[string]any, bool) {
+	value, ok, err := e.db.Get(id)
+	if err != nil {
+		log.Printf("lsm: %v", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	return decodeDocument(id, value)
+}
+
+func (e *lsmEngine) Put(id string, doc map[string]any) {
+	value, err := json.Marshal(doc)
+	if err != nil {
+		// документы приходят из JSON-запросов, поэтому всегда сериализуются
+		log.Printf("lsm: marshal %s: %v", id, err)
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists, _ := e.db.Get(id); !exists {
+		e.count++
+	}
+	e.db.Put(id, value)
+}
+
+func (e *lsmEngine) Delete(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, exists, _ := e.db.Get(id); !exists {
+		return false
+	}
+	e.db.Delete(id)
+	e.count--
+	return true
+}
+
+func (e *lsmEngine) Scan(fn func(id string, doc map[string]any) bool) {
+	snap := e.Snapshot()
+	defer snap.Release()
+	snap.Scan(fn)
+}
+
+func (e *lsmEngine) Len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.count
+}
+
+func (e *lsmEngine) Snapshot() EngineSnapshot {
+	return lsmSnapshot{e.db.Snapshot()}
+}
+
+// Flush дописывает изменения в WAL дерева (sync — с fsync)
+func (e *lsmEngine) Flush(sync bool) error {
+	return e.db.Flush(sync)
+}
+
+func (e *lsmEngine) LSN() uint64 {
+	return e.db.Seq()
+}
+
+func (e *lsmEngine) MemoryUsage() int64 {
+	return e.db.MemoryUsage()
+}
+
+func (e *lsmEngine) Close() error {
+	return e.db.Close()
+}
+
+type lsmSnapshot struct {
+	snap *lsm.Snapshot
+}
+
+func (s lsmSnapshot) Get(id string) (map[string]any, bool) {
+	value, ok, err := s.snap.Get(id)
+	if err != nil {
+		log.Printf("lsm: %v", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	return decodeDocument(id, value)
+}
+
+func (s lsmSnapshot) Scan(fn func(id string, doc map[string]any) bool) {
+	err := s.snap.Scan(func(id string, value []byte) bool {
+		doc, ok := decodeDocument(id, value)
+		return !ok || fn(id, doc)
+	})
+	if err != nil {
+		log.Printf("lsm: %v", err)
+	}
+}
+
+func (s lsmSnapshot) Release() {
+	s.snap.Release()
+}
+
+func decodeDocument(id string, value []byte) (map[string]any, bool) {
+	var doc map[string]any
+	if err := json.Unmarshal(value, &doc); err != nil {
+		log.Printf("lsm: corrupted document %s: %v", id, err)
+		return nil, false
+	}
+	return doc, true
+}
@@ -2,103 +2,431 @@ package storage
 
 import (
 	"fmt"
+	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // WriteJob — задача в очереди модификации
 type WriteJob struct {
-	DBName     string                                      // имя базы/коллекции
+	Namespace  Namespace                                   // коллекция
 	Operation  func(coll *Collection) (WriteResult, error) // операция для выполнения
+	Tx         func(tx *Tx) (WriteResult, error)           // операция над несколькими коллекциями (вместо Operation)
+	Durability Durability                                  // когда отвечать: до записи, после записи или после fsync
 	ResultChan chan WriteResult                            // канал для ответа
+
+	names   []Namespace // коллекции, доступные операции Tx
+	barrier *txBarrier  // служебная задача: остановка worker'а на время транзакции
 }
 
 // WriteResult — результат выполнения write-операции
 type WriteResult struct {
-	InsertedIDs  []string // ID вставленных документов
-	DeletedCount int      // количество удаленных документов
-	Message      string   // сообщение
-	Error        error    // ошибка, если есть
+	InsertedIDs   []string // ID вставленных документов
+	DeletedCount  int      // количество удаленных документов
+	ModifiedCount int      // количество изменённых документов
+	MatchedCount  int      // количество документов, подошедших под условие update
+	Warnings      []string // предупреждения (нарушения схемы при уровне warn)
+	Message       string   // сообщение
+	Error         error    // ошибка, если есть
 }
 
 type CollectionMng struct {
+	dir         string // каталог данных (OpenDataDir)
 	mu          sync.Mutex
-	collections map[string]*Collection
-	writeQueue  chan WriteJob
-	stopChan    chan struct{}
+	collections map[Namespace]*Collection
+	loading     map[Namespace]*loadCall // коллекции, которые сейчас загружаются или выгружаются
+
+	qmu      sync.Mutex                // защищает queues
+	queues   map[Namespace]*writeQueue // очереди записи по коллекциям
+	txMu     sync.Mutex                // упорядочивает постановку барьеров транзакций
+	idle     time.Duration             // простой, после которого worker останавливается
+	stopChan chan struct{}
+	changes  *changeLog // события зафиксированных изменений (changes.go)
+
+	reaperOff atomic.Bool // TTL-reaper выключен: истёкшие документы удаляет источник данных узла (ttl.go)
+
+	memoryLimit int64         // лимит памяти открытых коллекций в байтах (под mu), 0 — без лимита
+	evictions   atomic.Uint64 // сколько раз коллекции выгружались из памяти
+	checkerOnce sync.Once
+}
+
+// writeQueue — очередь записи одной коллекции со своим worker'ом
+type writeQueue struct {
+	jobs    chan WriteJob
+	pending int // задачи, поставленные в очередь и ещё не выполненные (под qmu)
+}
+
+// txBarrier останавливает worker'ы коллекций транзакции, пока она выполняется
+type txBarrier struct {
+	ready chan struct{} // worker дошёл до барьера
+	done  chan struct{} // транзакция завершена
 }
 
 const writeQueueSize = 100
 
+// maxGroupCommit — сколько задач из очереди worker применяет перед одним сохранением
+const maxGroupCommit = 64
+
+// workerIdleTimeout — через сколько простоя worker коллекции останавливается
+const workerIdleTimeout = 30 * time.Second
+
 func NewManager() *CollectionMng {
 	m := &CollectionMng{
-		collections: make(map[string]*Collection),
-		writeQueue:  make(chan WriteJob, writeQueueSize),
+		dir:         defaultDataDir,
+		collections: make(map[Namespace]*Collection),
+		loading:     make(map[Namespace]*loadCall),
+		queues:      make(map[Namespace]*writeQueue),
 		stopChan:    make(chan struct{}),
+		idle:        workerIdleTimeout,
+		changes:     newChangeLog(),
 	}
-	go m.worker()
+	go m.ttlReaper()
 	return m
 }
 
 var GlobalManager = NewManager()
 
-func (m *CollectionMng) GetCollection(name string) (*Collection, error) {
+// loadCall — загрузка или выгрузка коллекции; остальные обратившиеся к коллекции ждут её (single-flight)
+type loadCall struct {
+	done chan struct{}
+	err  error
+}
+
+// slowLoadThreshold — загрузки дольше этого пишутся в лог
+const slowLoadThreshold = time.Second
+
+// GetCollection возвращает коллекцию, загружая её при первом обращении.
+// Загрузка идёт без m.mu: пока большая коллекция читается с диска, остальные доступны.
+// Коллекция закрепляется в памяти, пока вызывающий не вызовет Release
+func (m *CollectionMng) GetCollection(ns Namespace) (*Collection, error) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
+	for {
+		// выгружаемая коллекция ещё в collections, но обращения ждут конца выгрузки
+		call, loading := m.loading[ns]
+		if !loading {
+			if coll, exist := m.collections[ns]; exist {
+				coll.touch()
+				coll.pins.Add(1)
+				m.mu.Unlock()
+				return coll, nil
+			}
+			break
+		}
+		m.mu.Unlock()
+		<-call.done
+		if call.err != nil {
+			return nil, call.err
+		}
+		// загруженную коллекцию могли уже выгрузить: проверяем заново
+		m.mu.Lock()
+	}
+	call := &loadCall{done: make(chan struct{})}
+	m.loading[ns] = call
+	m.mu.Unlock()
+
+	coll, err := openCollection(m.dir, ns)
+
+	m.mu.Lock()
+	delete(m.loading, ns)
+	if err == nil {
+		coll.touch()
+		coll.pins.Add(1)
+		m.collections[ns] = coll
+	}
+	m.mu.Unlock()
+	call.err = err
+	close(call.done)
+	if err != nil {
+		return nil, err
+	}
+	m.enforceMemoryLimit(ns)
+	return coll, nil
+}
 
-	if coll, exist := m.collections[name]; exist {
+// getCollectionLocked возвращает коллекцию, загружая её под m.mu. Вызывается
+// после lockIdle из операций, которые открывают и закрывают коллекции сами;
+// лимит памяти после такой загрузки проверит memoryChecker
+func (m *CollectionMng) getCollectionLocked(ns Namespace) (*Collection, error) {
+	if coll, exist := m.collections[ns]; exist {
+		coll.touch()
 		return coll, nil
 	}
 
-	coll, err := LoadCollection(name)
+	coll, err := openCollection(m.dir, ns)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := coll.LoadAllIndexes(); err != nil {
-		return nil, fmt.Errorf("failed to load index %w", err)
-	}
+	coll.touch()
+	m.collections[ns] = coll
+
+	return coll, nil
+}
 
-	m.collections[name] = coll
+// lockIdle захватывает m.mu, дождавшись загрузок коллекций names. Пока мьютекс
+// захвачен, новые загрузки не начинаются: коллекции можно открывать и закрывать синхронно
+func (m *CollectionMng) lockIdle(names ...Namespace) {
+	for {
+		m.mu.Lock()
+		var call *loadCall
+		for _, ns := range names {
+			if c, loading := m.loading[ns]; loading {
+				call = c
+				break
+			}
+		}
+		if call == nil {
+			return
+		}
+		m.mu.Unlock()
+		<-call.done
+	}
+}
 
+// openCollection читает коллекцию с диска вместе с индексами
+func openCollection(root string, ns Namespace) (*Collection, error) {
+	start := time.Now()
+	coll, err := LoadCollection(root, ns)
+	if err != nil {
+		return nil, err
+	}
+	if coll.persistent() {
+		if err := coll.loadIndexes(); err != nil {
+			coll.Close()
+			return nil, err
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= slowLoadThreshold {
+		log.Printf("collection %s loaded in %v: %d document(s)", ns, elapsed.Round(time.Millisecond), coll.Data.Len())
+	}
 	return coll, nil
 }
 
-func (m *CollectionMng) worker() {
+// loadIndexes загружает файлы индексов коллекции и перестраивает их,
+// если журнал ушёл дальше сохранённых индексов (или они сохранялись во время сбоя)
+func (c *Collection) loadIndexes() error {
+	if err := c.LoadAllIndexes(); err != nil {
+		return fmt.Errorf("failed to load index %w", err)
+	}
+	if c.indexesStale() {
+		if err := c.RebuildAllIndexes(); err != nil {
+			return fmt.Errorf("failed to rebuild indexes: %w", err)
+		}
+		if err := c.markIndexCheckpoint(); err != nil {
+			return err
+		}
+	}
+	c.initExpiry(time.Now())
+	return nil
+}
+
+// CreateCollection явно создаёт коллекцию с выбранным движком хранения
+// (и ограничениями capped-коллекции). Выполняется за барьером в очереди коллекции,
+// поэтому параллельных записей в неё нет
+func (m *CollectionMng) CreateCollection(ns Namespace, opts CollectionOptions) error {
+	engine, err := ValidEngine(opts.Engine)
+	if err != nil {
+		return err
+	}
+	opts.Engine = engine
+	if opts.Capped != nil && opts.Capped.Max <= 0 && opts.Capped.Size <= 0 {
+		return fmt.Errorf("capped collection requires positive max or size")
+	}
+	if _, err := compileValidator(opts.Validator); err != nil {
+		return err
+	}
+	result := m.EnqueueTx([]Namespace{ns}, DurabilityFlushed, func(tx *Tx) (WriteResult, error) {
+		return WriteResult{}, m.createCollection(ns, opts)
+	})
+	return result.Error
+}
+
+func (m *CollectionMng) createCollection(ns Namespace, opts CollectionOptions) error {
+	m.lockIdle(ns)
+	defer m.mu.Unlock()
+
+	coll, loaded := m.collections[ns]
+	if !loaded {
+		var err error
+		if coll, err = LoadCollection(m.dir, ns); err != nil {
+			return err
+		}
+	}
+	if coll.exists() {
+		return Errorf(ErrExists, "collection '%s' already exists", ns)
+	}
+	// пустая коллекция, открытая чтением до создания, заменяется новой
+	coll.Close()
+
+	data, err := openEngine(opts.Engine, m.dir, ns)
+	if err != nil {
+		return err
+	}
+	created := newCollection(m.dir, ns, collectionMeta{Engine: opts.Engine, Capped: opts.Capped, Validator: opts.Validator}, data)
+	if err := created.saveMeta(); err != nil {
+		data.Close()
+		return err
+	}
+	created.touch()
+	m.collections[ns] = created
+	m.publishCommand(ChangeEvent{Op: ChangeCreateCollection, NS: ns, Spec: &CollectionSpec{
+		Engine: opts.Engine, Capped: opts.Capped, Validator: opts.Validator,
+	}})
+	return nil
+}
+
+// worker выполняет задачи одной коллекции по порядку и останавливается после простоя.
+// Накопившиеся в очереди задачи забираются группой и сохраняются одной записью на диск
+func (m *CollectionMng) worker(ns Namespace, q *writeQueue) {
+	idle := time.NewTimer(m.idle)
+	defer idle.Stop()
+
 	for {
 		select {
-		case job := <-m.writeQueue:
-			result := m.processJob(job)
-			job.ResultChan <- result
+		case job := <-q.jobs:
+			batch := []WriteJob{job}
+		drain:
+			for len(batch) < maxGroupCommit {
+				select {
+				case next := <-q.jobs:
+					batch = append(batch, next)
+				default:
+					break drain
+				}
+			}
+			m.processBatch(batch)
+
+			m.qmu.Lock()
+			q.pending -= len(batch)
+			m.qmu.Unlock()
+			idle.Reset(m.idle)
+		case <-idle.C:
+			// pending меняется только под qmu: если задач нет, новых в эту очередь не будет
+			m.qmu.Lock()
+			if q.pending == 0 {
+				delete(m.queues, ns)
+				m.qmu.Unlock()
+				return
+			}
+			m.qmu.Unlock()
+			idle.Reset(m.idle)
 		case <-m.stopChan:
 			return
 		}
 	}
 }
 
-func (m *CollectionMng) processJob(job WriteJob) WriteResult {
-	coll, err := m.GetCollection(job.DBName)
-	if err != nil {
-		return WriteResult{Error: fmt.Errorf("failed to get collection: %w", err)}
+// enqueueJob ставит задачу в очередь коллекции, запуская её worker при первом обращении
+func (m *CollectionMng) enqueueJob(ns Namespace, job WriteJob) {
+	m.qmu.Lock()
+	q, exists := m.queues[ns]
+	if !exists {
+		q = &writeQueue{jobs: make(chan WriteJob, writeQueueSize)}
+		m.queues[ns] = q
+		go m.worker(ns, q)
 	}
+	q.pending++
+	m.qmu.Unlock()
 
-	result, err := job.Operation(coll)
+	q.jobs <- job
+}
+
+// processJob выполняет задачу как транзакцию в памяти: при ошибке изменения откатываются,
+// при успехе становятся видимы. Возвращает изменённые коллекции, которые нужно сохранить
+func (m *CollectionMng) processJob(job WriteJob) (WriteResult, []*Collection) {
+	tx := &Tx{mng: m, allowed: job.names}
+	operation := job.Tx
+	if operation == nil {
+		operation = func(tx *Tx) (WriteResult, error) {
+			coll, err := tx.Collection(job.Namespace)
+			if err != nil {
+				return WriteResult{}, err
+			}
+			return job.Operation(coll)
+		}
+	}
+
+	result, err := operation(tx)
 	if err != nil {
-		return WriteResult{Error: err}
+		tx.rollback()
+		return WriteResult{Error: err}, nil
 	}
-	return result
+	return result, tx.commit()
+}
+
+func (m *CollectionMng) Enqueue(ns Namespace, operation func(coll *Collection) (WriteResult, error)) WriteResult {
+	return m.EnqueueDurable(ns, DurabilityFlushed, operation)
 }
 
-func (m *CollectionMng) Enqueue(dbName string, operation func(coll *Collection) (WriteResult, error)) WriteResult {
+// EnqueueDurable ставит операцию в очередь коллекции с заданной гарантией записи
+func (m *CollectionMng) EnqueueDurable(ns Namespace, durability Durability, operation func(coll *Collection) (WriteResult, error)) WriteResult {
 	resultChan := make(chan WriteResult, 1)
 	job := WriteJob{
-		DBName:     dbName,
+		Namespace:  ns,
 		Operation:  operation,
+		Durability: durability,
 		ResultChan: resultChan,
 	}
-	m.writeQueue <- job
+	m.enqueueJob(ns, job)
 	return <-resultChan
 }
 
+// EnqueueTx атомарно выполняет операцию над перечисленными коллекциями.
+// В очередь каждой коллекции ставится барьер; когда все worker'ы до него дошли,
+// операция выполняется, и ни одна другая запись в эти коллекции не идёт параллельно.
+// Барьеры ставятся под txMu, поэтому во всех очередях транзакции идут в одном порядке
+func (m *CollectionMng) EnqueueTx(names []Namespace, durability Durability, operation func(tx *Tx) (WriteResult, error)) WriteResult {
+	names = uniqueNamespaces(names)
+	barrier := &txBarrier{
+		ready: make(chan struct{}, len(names)),
+		done:  make(chan struct{}),
+	}
+
+	m.txMu.Lock()
+	for _, ns := range names {
+		m.enqueueJob(ns, WriteJob{Namespace: ns, barrier: barrier})
+	}
+	m.txMu.Unlock()
+
+	for range names {
+		<-barrier.ready
+	}
+
+	result, dirty := m.processJob(WriteJob{Tx: operation, names: names})
+	if result.Error != nil {
+		close(barrier.done)
+		return result
+	}
+	// worker'ы ждут на барьере, пока транзакция не сохранена: файлы коллекций пишет только она
+	if durability == DurabilityNone {
+		go func() {
+			m.persistLogged(dirty, false)
+			close(barrier.done)
+		}()
+		return result
+	}
+	defer close(barrier.done)
+	if err := persistCollections(dirty, durability == DurabilityFsynced); err != nil {
+		return WriteResult{Error: err}
+	}
+	return result
+}
+
+func uniqueNamespaces(names []Namespace) []Namespace {
+	set := make(map[Namespace]struct{}, len(names))
+	unique := make([]Namespace, 0, len(names))
+	for _, ns := range names {
+		if _, seen := set[ns]; !seen {
+			set[ns] = struct{}{}
+			unique = append(unique, ns)
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool { return unique[i].String() < unique[j].String() })
+	return unique
+}
+
 func (m *CollectionMng) Stop() {
 	close(m.stopChan)
 }
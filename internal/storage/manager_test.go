@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// BenchmarkWriteThroughput измеряет пропускную способность записи в зависимости
+// от числа коллекций: у каждой коллекции своя очередь и свой worker,
+// поэтому записи в разные коллекции не ждут друг друга
+func BenchmarkWriteThroughput(b *testing.B) {
+	b.Chdir(b.TempDir())
+
+	const writers = 16
+	for _, collections := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("collections=%d", collections), func(b *testing.B) {
+			m := NewManager()
+			defer m.Stop()
+
+			ids := make([]string, collections)
+			for i := range ids {
+				ns := Namespace{DB: DefaultDatabase, Coll: fmt.Sprintf("bench_%d_%d", collections, i)}
+				result := m.Enqueue(ns, func(coll *Collection) (WriteResult, error) {
+					id, err := coll.Insert(map[string]any{"n": 0.0})
+					return WriteResult{InsertedIDs: []string{id}}, err
+				})
+				ids[i] = result.InsertedIDs[0]
+			}
+
+			b.ResetTimer()
+			var wg sync.WaitGroup
+			for w := 0; w < writers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					i := w % collections
+					ns := Namespace{DB: DefaultDatabase, Coll: fmt.Sprintf("bench_%d_%d", collections, i)}
+					for n := w; n < b.N; n += writers {
+						m.Enqueue(ns, func(coll *Collection) (WriteResult, error) {
+							coll.Replace(ids[i], map[string]any{"n": float64(n)})
+							return WriteResult{}, nil
+						})
+					}
+				}(w)
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// BenchmarkWriteNextToSlowCollection измеряет запись в одну коллекцию, пока
+// очередь другой занята медленными задачами (например, массовой вставкой в logs)
+func BenchmarkWriteNextToSlowCollection(b *testing.B) {
+	b.Chdir(b.TempDir())
+
+	m := NewManager()
+	defer m.Stop()
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			m.Enqueue(Namespace{DB: DefaultDatabase, Coll: "bench_logs"}, func(coll *Collection) (WriteResult, error) {
+				time.Sleep(5 * time.Millisecond)
+				return WriteResult{}, nil
+			})
+		}
+	}()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		m.Enqueue(Namespace{DB: DefaultDatabase, Coll: "bench_users"}, func(coll *Collection) (WriteResult, error) {
+			return WriteResult{}, nil
+		})
+	}
+	b.StopTimer()
+
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,334 @@
+package storage
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"nosql_db/internal/index"
+)
+
+// Оценки памяти приблизительные: считаются байты ключей и значений
+// плюс фиксированные накладные расходы на элемент карты, слайса или интерфейса
+const (
+	entryOverhead = 48 // элемент карты: ключ, значение, место в бакете
+	valueOverhead = 16 // значение в интерфейсе any
+)
+
+// memoryCheckInterval — как часто проверяется лимит памяти (записи увеличивают объём коллекций)
+const memoryCheckInterval = 5 * time.Second
+
+// CollectionStats — открытая коллекция и занятая ею память
+type CollectionStats struct {
+	Namespace  Namespace
+	Engine     string
+	Documents  int
+	DataBytes  int64 // документы в памяти движка (для LSM — memtable, индексы блоков и фильтры)
+	IndexBytes int64
+	LastUsed   time.Time
+}
+
+// MemoryStats — память открытых коллекций и лимит менеджера
+type MemoryStats struct {
+	Collections []CollectionStats
+	Total       int64
+	Limit       int64 // 0 — без лимита
+	Evictions   uint64
+}
+
+// documentSize оценивает память документа
+func documentSize(doc map[string]any) int64 {
+	return valueSize(doc)
+}
+
+func valueSize(value any) int64 {
+	switch v := value.(type) {
+	case map[string]any:
+		size := int64(entryOverhead)
+		for key, item := range v {
+			size += entryOverhead + int64(len(key)) + valueSize(item)
+		}
+		return size
+	case []any:
+		size := int64(24)
+		for _, item := range v {
+			size += valueOverhead + valueSize(item)
+		}
+		return size
+	case string:
+		return valueOverhead + int64(len(v))
+	default:
+		return valueOverhead
+	}
+}
+
+// indexSizeCache — объём индексов коллекции на момент mods (пересчёт обходит все индексы)
+type indexSizeCache struct {
+	mods    uint64
+	indexes int
+	bytes   int64
+	valid   bool
+}
+
+// touch отмечает обращение к коллекции для вытеснения давно не используемых
+func (c *Collection) touch() {
+	c.lastUsed.Store(time.Now().UnixNano())
+}
+
+// Release снимает закрепление, взятое GetCollection
+func (c *Collection) Release() {
+	c.pins.Add(-1)
+}
+
+// Stats возвращает число документов и оценку памяти коллекции
+func (c *Collection) Stats() CollectionStats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return CollectionStats{
+		Namespace:  c.Namespace(),
+		Engine:     c.EngineName(),
+		Documents:  c.Data.Len(),
+		DataBytes:  c.Data.MemoryUsage(),
+		IndexBytes: c.indexBytesInternal(),
+		LastUsed:   time.Unix(0, c.lastUsed.Load()),
+	}
+}
+
+// indexBytesInternal пересчитывает объём индексов, только если коллекция изменилась
+func (c *Collection) indexBytesInternal() int64 {
+	c.sizeMu.Lock()
+	defer c.sizeMu.Unlock()
+	count := len(c.Indexes) + len(c.TextIndexes) + len(c.GeoIndexes) + len(c.VecIndexes) + len(c.HashIndexes)
+	if c.indexSize.valid && c.indexSize.mods == c.mods && c.indexSize.indexes == count {
+		return c.indexSize.bytes
+	}
+
+	var total int64
+	for _, tree := range c.Indexes {
+		total += btreeSize(tree)
+	}
+	for _, tree := range c.GeoIndexes {
+		total += btreeSize(tree)
+	}
+	for _, idx := range c.TextIndexes {
+		for term, docs := range idx.Postings() {
+			total += entryOverhead + int64(len(term))
+			for id := range docs {
+				total += entryOverhead + int64(len(id))
+			}
+		}
+		for id := range idx.Lengths() {
+			total += entryOverhead + int64(len(id))
+		}
+	}
+	for _, idx := range c.VecIndexes {
+		for id, vec := range idx.Vectors() {
+			total += entryOverhead + int64(len(id)) + 4*int64(len(vec))
+		}
+		for id, node := range idx.Nodes() {
+			total += entryOverhead + int64(len(id))
+			for _, friends := range node.Friends {
+				for _, friend := range friends {
+					total += valueOverhead + int64(len(friend))
+				}
+			}
+		}
+	}
+	for _, hm := range c.HashIndexes {
+		for key, set := range hm.Items() {
+			total += entryOverhead + int64(len(key))
+			for id := range set.(idSet) {
+				total += entryOverhead + int64(len(id))
+			}
+		}
+	}
+	c.indexSize = indexSizeCache{mods: c.mods, indexes: count, bytes: total, valid: true}
+	return total
+}
+
+func btreeSize(tree *index.BTree) int64 {
+	var total int64
+	tree.Ascend(func(key index.Key, values []index.Value) bool {
+		total += valueOverhead + int64(len(key))
+		for _, v := range values {
+			total += valueOverhead + int64(len(v))
+		}
+		return true
+	})
+	return total
+}
+
+// SetMemoryLimit задаёт лимит памяти открытых коллекций в байтах (0 — без лимита).
+// При превышении давно не используемые коллекции сохраняются и выгружаются;
+// при следующем обращении они загружаются с диска заново
+func (m *CollectionMng) SetMemoryLimit(limit int64) {
+	m.mu.Lock()
+	m.memoryLimit = limit
+	m.mu.Unlock()
+	if limit > 0 {
+		m.checkerOnce.Do(func() { go m.memoryChecker() })
+	}
+}
+
+func (m *CollectionMng) memoryChecker() {
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.enforceMemoryLimit(Namespace{})
+		case <-m.stopChan:
+			return
+		}
+	}
+}
+
+// MemoryStats возвращает память открытых коллекций, начиная с самых больших
+func (m *CollectionMng) MemoryStats() MemoryStats {
+	m.mu.Lock()
+	stats := MemoryStats{Limit: m.memoryLimit, Evictions: m.evictions.Load()}
+	m.mu.Unlock()
+	for _, coll := range m.pinLoaded() {
+		s := coll.Stats()
+		coll.Release()
+		stats.Collections = append(stats.Collections, s)
+		stats.Total += s.DataBytes + s.IndexBytes
+	}
+	sort.Slice(stats.Collections, func(i, j int) bool {
+		a, b := stats.Collections[i], stats.Collections[j]
+		return a.DataBytes+a.IndexBytes > b.DataBytes+b.IndexBytes
+	})
+	return stats
+}
+
+// enforceMemoryLimit выгружает коллекции в порядке давности обращения, пока
+// их общий объём больше лимита. keep — только что загруженная коллекция, она остаётся.
+// Жертвы выбираются под m.mu, а сохраняются на диск без него: остальные коллекции
+// в это время доступны
+func (m *CollectionMng) enforceMemoryLimit(keep Namespace) {
+	for _, coll := range m.pickVictims(keep) {
+		m.evictCollection(coll)
+	}
+}
+
+// pinLoaded закрепляет и возвращает открытые коллекции, кроме тех, что уже выгружаются:
+// их память скоро освободится. Вызывающий снимает закрепление Release
+func (m *CollectionMng) pinLoaded() []*Collection {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	colls := make([]*Collection, 0, len(m.collections))
+	for ns, coll := range m.collections {
+		if _, busy := m.loading[ns]; busy {
+			continue
+		}
+		coll.pins.Add(1)
+		colls = append(colls, coll)
+	}
+	return colls
+}
+
+// pickVictims выбирает коллекции для выгрузки и регистрирует их выгрузку в loading:
+// обращения к ним ждут её так же, как загрузку. Объём считается без m.mu (оценка индексов
+// обходит их целиком), закреплённые на это время коллекции не выгружаются
+func (m *CollectionMng) pickVictims(keep Namespace) []*Collection {
+	m.mu.Lock()
+	limit := m.memoryLimit
+	m.mu.Unlock()
+	if limit <= 0 {
+		return nil
+	}
+	var total int64
+	colls := m.pinLoaded()
+	sizes := make(map[*Collection]int64, len(colls))
+	for _, coll := range colls {
+		s := coll.Stats()
+		coll.Release()
+		sizes[coll] = s.DataBytes + s.IndexBytes
+		total += sizes[coll]
+	}
+	if total <= limit {
+		return nil
+	}
+	sort.Slice(colls, func(i, j int) bool { return colls[i].lastUsed.Load() < colls[j].lastUsed.Load() })
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var victims []*Collection
+	for _, coll := range colls {
+		if total <= m.memoryLimit {
+			break
+		}
+		ns := coll.Namespace()
+		// пока считался объём, коллекцию могли выгрузить или начать выгружать
+		_, busy := m.loading[ns]
+		if m.collections[ns] != coll || busy {
+			total -= sizes[coll]
+			continue
+		}
+		if ns == keep || !m.evictableLocked(coll) {
+			continue
+		}
+		m.loading[ns] = &loadCall{done: make(chan struct{})}
+		victims = append(victims, coll)
+		total -= sizes[coll]
+	}
+	return victims
+}
+
+// evictCollection сохраняет выбранную коллекцию и убирает её из памяти. Пока шло
+// сохранение, обращения к ней ждали, но в её очередь могли встать задачи: тогда
+// коллекция остаётся загруженной
+func (m *CollectionMng) evictCollection(coll *Collection) {
+	ns := coll.Namespace()
+	err := coll.flushForEviction()
+	if err != nil {
+		log.Printf("failed to evict collection %s: %v", ns, err)
+	}
+
+	m.mu.Lock()
+	call := m.loading[ns]
+	evict := err == nil && m.evictableLocked(coll)
+	if evict {
+		delete(m.collections, ns)
+	}
+	m.mu.Unlock()
+
+	if evict {
+		if err := coll.Close(); err != nil {
+			log.Printf("failed to close evicted collection %s: %v", ns, err)
+		}
+		m.evictions.Add(1)
+	}
+
+	m.mu.Lock()
+	delete(m.loading, ns)
+	m.mu.Unlock()
+	close(call.done)
+}
+
+// evictableLocked — коллекцию можно выгрузить: её данные есть на диске, никто не держит
+// объект (чтение, транзакция) и в её очереди нет задач, которые к ней обратятся
+func (m *CollectionMng) evictableLocked(coll *Collection) bool {
+	if !coll.persistent() || coll.pins.Load() > 0 {
+		return false
+	}
+	m.qmu.Lock()
+	defer m.qmu.Unlock()
+	q, exists := m.queues[coll.Namespace()]
+	return !exists || q.pending == 0
+}
+
+// flushForEviction сохраняет коллекцию вместе с индексами, чтобы загрузка не перестраивала их
+func (c *Collection) flushForEviction() error {
+	// коллекция, которую только читали, не создаётся на диске
+	if !c.exists() {
+		return nil
+	}
+	if err := c.persist(false); err != nil {
+		return err
+	}
+	if c.indexesStale() {
+		return c.checkpointIndexes()
+	}
+	return nil
+}
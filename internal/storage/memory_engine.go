@@ -0,0 +1,76 @@
+package storage
+
+import "sync"
+
+// memoryEngine — движок без диска (тесты, временные данные): после перезапуска коллекция пуста
+type memoryEngine struct {
+	mu    sync.RWMutex
+	docs  map[string]map[string]any
+	bytes int64 // оценка памяти документов
+}
+
+func newMemoryEngine() *memoryEngine {
+	return &memoryEngine{docs: make(map[string]map[string]any)}
+}
+
+func (e *memoryEngine) Get(id string) (map[string]any, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	doc, ok := e.docs[id]
+	return doc, ok
+}
+
+func (e *memoryEngine) Put(id string, doc map[string]any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if old, ok := e.docs[id]; ok {
+		e.bytes -= documentSize(old)
+	} else {
+		e.bytes += entryOverhead + int64(len(id))
+	}
+	e.bytes += documentSize(doc)
+	e.docs[id] = doc
+}
+
+func (e *memoryEngine) Delete(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	old, ok := e.docs[id]
+	if ok {
+		e.bytes -= entryOverhead + int64(len(id)) + documentSize(old)
+	}
+	delete(e.docs, id)
+	return ok
+}
+
+func (e *memoryEngine) Scan(fn func(id string, doc map[string]any) bool) {
+	snap := e.Snapshot()
+	defer snap.Release()
+	snap.Scan(fn)
+}
+
+func (e *memoryEngine) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.docs)
+}
+
+func (e *memoryEngine) Snapshot() EngineSnapshot {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	snap := make(mapSnapshot, len(e.docs))
+	for id, doc := range e.docs {
+		snap[id] = doc
+	}
+	return snap
+}
+
+func (e *memoryEngine) Flush(sync bool) error { return nil }
+func (e *memoryEngine) LSN() uint64           { return 0 }
+func (e *memoryEngine) Close() error          { return nil }
+
+func (e *memoryEngine) MemoryUsage() int64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.bytes
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// loadedCollections возвращает открытые коллекции менеджера
+func loadedCollections(m *CollectionMng) []string {
+	var names []string
+	for _, s := range m.MemoryStats().Collections {
+		names = append(names, s.Namespace.Coll)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// fillCollection создаёт коллекцию с индексом по n и n документами
+func fillCollection(t *testing.T, m *CollectionMng, ns Namespace, n int) {
+	t.Helper()
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		if err := coll.CreateIndex("n", 64); err != nil {
+			return WriteResult{}, err
+		}
+		for i := 0; i < n; i++ {
+			if _, err := coll.Insert(map[string]any{"n": float64(i), "name": fmt.Sprintf("document %d", i)}); err != nil {
+				return WriteResult{}, err
+			}
+		}
+		return WriteResult{}, nil
+	})
+}
+
+func TestMemoryStats(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	fillCollection(t, m, testNS("small"), 10)
+	fillCollection(t, m, testNS("large"), 500)
+	mustWrite(t, m, testNS("plain"), func(coll *Collection) (WriteResult, error) {
+		_, err := coll.Insert(map[string]any{"name": "no index"})
+		return WriteResult{}, err
+	})
+
+	stats := m.MemoryStats()
+	if len(stats.Collections) != 3 || stats.Limit != 0 || stats.Evictions != 0 {
+		t.Fatalf("stats: %+v", stats)
+	}
+	var total int64
+	for i, s := range stats.Collections {
+		total += s.DataBytes + s.IndexBytes
+		if i > 0 && s.DataBytes+s.IndexBytes > stats.Collections[i-1].DataBytes+stats.Collections[i-1].IndexBytes {
+			t.Fatalf("collections are not ordered by size: %+v", stats.Collections)
+		}
+	}
+	if total != stats.Total {
+		t.Fatalf("total %d, sum %d", stats.Total, total)
+	}
+	large, small, plain := stats.Collections[0], stats.Collections[1], stats.Collections[2]
+	if large.Namespace.Coll != "large" || large.Documents != 500 || large.Engine != EngineHashMap {
+		t.Fatalf("largest: %+v", large)
+	}
+	if large.DataBytes < 10*small.DataBytes || large.IndexBytes < 10*small.IndexBytes {
+		t.Fatalf("size does not grow with documents: %+v, %+v", large, small)
+	}
+	if plain.IndexBytes != 0 || plain.DataBytes == 0 {
+		t.Fatalf("collection without indexes: %+v", plain)
+	}
+}
+
+func TestMemoryEviction(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{"a", "b", "c", "d"}
+	source := openTestManager(t, dir)
+	for _, name := range names {
+		fillCollection(t, source, testNS(name), 200)
+	}
+
+	m := openTestManager(t, dir)
+	// коллекция в памяти не выгружается: её данных нет на диске
+	if err := m.CreateCollection(testNS("cache"), CollectionOptions{Engine: EngineMemory}); err != nil {
+		t.Fatal(err)
+	}
+	fillCollection(t, m, testNS("cache"), 200)
+	var cache, one int64
+	withCollection(t, m, testNS("cache"), func(coll *Collection) {
+		s := coll.Stats()
+		cache = s.DataBytes + s.IndexBytes
+	})
+	withCollection(t, m, testNS("a"), func(coll *Collection) {
+		s := coll.Stats()
+		one = s.DataBytes + s.IndexBytes
+	})
+	// лимит вмещает коллекцию в памяти и две коллекции с диска
+	m.SetMemoryLimit(cache + 2*one + one/2)
+
+	for _, name := range names {
+		withCollection(t, m, testNS(name), func(*Collection) {})
+	}
+	if got := loadedCollections(m); !slices.Equal(got, []string{"c", "cache", "d"}) {
+		t.Fatalf("loaded after eviction: %v", got)
+	}
+	stats := m.MemoryStats()
+	if stats.Evictions != 2 || stats.Total > stats.Limit {
+		t.Fatalf("%d eviction(s), %d of %d bytes", stats.Evictions, stats.Total, stats.Limit)
+	}
+
+	// закреплённая коллекция остаётся в памяти, даже если к ней давно не обращались
+	pinned, err := m.GetCollection(testNS("c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	withCollection(t, m, testNS("d"), func(*Collection) {})
+	withCollection(t, m, testNS("a"), func(*Collection) {})
+	if got := loadedCollections(m); !slices.Equal(got, []string{"a", "c", "cache"}) {
+		t.Fatalf("loaded with a pinned collection: %v", got)
+	}
+	if pinned.Count() != 200 {
+		t.Fatalf("pinned collection: %d document(s)", pinned.Count())
+	}
+	pinned.Release()
+
+	// выгруженная коллекция загружается с диска вместе с индексом
+	withCollection(t, m, testNS("b"), func(coll *Collection) {
+		tree, ok := coll.GetIndex("n")
+		if coll.Count() != 200 || !ok || tree == nil {
+			t.Fatalf("reloaded collection: %d document(s), index %v", coll.Count(), ok)
+		}
+	})
+	// запись в выгруженную коллекцию загружает её снова
+	mustWrite(t, m, testNS("d"), func(coll *Collection) (WriteResult, error) {
+		_, err := coll.Insert(map[string]any{"n": 1000.0})
+		return WriteResult{}, err
+	})
+	withCollection(t, openTestManager(t, dir), testNS("d"), func(coll *Collection) {
+		if coll.Count() != 201 {
+			t.Fatalf("%d document(s) after a write to an evicted collection", coll.Count())
+		}
+	})
+}
+
+func TestEvictionRecheck(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	ns := testNS("a")
+	fillCollection(t, m, ns, 50)
+	m.mu.Lock()
+	m.memoryLimit = 1
+	m.mu.Unlock()
+
+	victims := m.pickVictims(Namespace{})
+	if len(victims) != 1 {
+		t.Fatalf("victims: %d", len(victims))
+	}
+	// запись, поставленная во время сохранения, ждёт выгрузку и оставляет коллекцию в памяти
+	done := make(chan WriteResult, 1)
+	go func() {
+		done <- m.Enqueue(ns, func(coll *Collection) (WriteResult, error) {
+			_, err := coll.Insert(map[string]any{"n": 50.0})
+			return WriteResult{}, err
+		})
+	}()
+	waitQueue := func(pending bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			m.qmu.Lock()
+			q, exists := m.queues[ns]
+			queued := exists && q.pending > 0
+			m.qmu.Unlock()
+			if queued == pending {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("queue of %s: pending %v", ns, queued)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitQueue(true)
+	m.evictCollection(victims[0])
+	if result := <-done; result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	if got := loadedCollections(m); !slices.Equal(got, []string{"a"}) || m.MemoryStats().Evictions != 0 {
+		t.Fatalf("loaded after an eviction with a queued write: %v", got)
+	}
+
+	waitQueue(false)
+
+	// без обращений коллекция выгружается и загружается с диска с новой записью
+	m.enforceMemoryLimit(Namespace{})
+	if got := loadedCollections(m); len(got) != 0 || m.MemoryStats().Evictions != 1 {
+		t.Fatalf("loaded after eviction: %v", got)
+	}
+	withCollection(t, m, ns, func(coll *Collection) {
+		if coll.Count() != 51 {
+			t.Fatalf("%d document(s) after reload", coll.Count())
+		}
+	})
+}
+
+func TestStatsOutsideManagerLock(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	fillCollection(t, m, testNS("busy"), 10)
+	fillCollection(t, m, testNS("other"), 10)
+	busy, err := m.GetCollection(testNS("busy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Release()
+
+	// оценка памяти ждёт занятую коллекцию, но не держит менеджер: другие коллекции доступны
+	busy.mutex.Lock()
+	done := make(chan MemoryStats)
+	go func() { done <- m.MemoryStats() }()
+	time.Sleep(50 * time.Millisecond)
+	got := make(chan error)
+	go func() {
+		coll, err := m.GetCollection(testNS("other"))
+		if err == nil {
+			coll.Release()
+		}
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		busy.mutex.Unlock()
+		t.Fatal("GetCollection waited for memory stats of another collection")
+	}
+	busy.mutex.Unlock()
+	if stats := <-done; len(stats.Collections) != 2 {
+		t.Fatalf("memory stats: %+v", stats.Collections)
+	}
+}
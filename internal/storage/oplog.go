@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"nosql_db/internal/vector"
+)
+
+// Журнал операций для репликации: кроме изменений документов журнал изменений
+// (changes.go) содержит команды — создание, удаление и переименование коллекций,
+// удаление баз, индексы и coll_mod. Реплика применяет журнал через ApplyChanges;
+// применение идемпотентно, поэтому события, уже учтённые начальной синхронизацией,
+// можно применить повторно
+
+// команды журнала
+const (
+	ChangeCreateCollection = "create_collection"
+	ChangeDropCollection   = "drop_collection"
+	ChangeRenameCollection = "rename_collection"
+	ChangeDropDatabase     = "drop_database"
+	ChangeCreateIndex      = "create_index"
+	ChangeCollMod          = "coll_mod"
+)
+
+// типы индексов в IndexSpec
+const (
+	IndexBTree  = "btree"
+	IndexHash   = "hash"
+	IndexGeo    = "geo"
+	IndexText   = "text"
+	IndexVector = "vector"
+)
+
+// IsDocumentChange — событие об изменении документа, а не команда
+func IsDocumentChange(op string) bool {
+	return op == ChangeInsert || op == ChangeUpdate || op == ChangeDelete
+}
+
+// IndexSpec — описание индекса, по которому реплика создаёт такой же
+type IndexSpec struct {
+	Type   string   `json:"type"`
+	Fields []string `json:"fields"`
+	Metric string   `json:"metric,omitempty"` // vector
+	Dims   int      `json:"dims,omitempty"`   // vector
+	// срок жизни документов TTL-индекса (btree)
+	ExpireAfterSeconds *int64 `json:"expireAfterSeconds,omitempty"`
+}
+
+// CollectionSpec — параметры коллекции для начальной синхронизации реплики
+type CollectionSpec struct {
+	Engine    string            `json:"engine"`
+	Capped    *CappedOptions    `json:"capped,omitempty"`
+	Validator *ValidatorOptions `json:"validator,omitempty"`
+	Indexes   []IndexSpec       `json:"indexes,omitempty"`
+}
+
+// Spec возвращает параметры коллекции вместе с её индексами
+func (c *Collection) Spec() CollectionSpec {
+	spec := CollectionSpec{Engine: c.EngineName(), Capped: c.Capped(), Validator: c.ValidatorOptions()}
+	ttl := c.ttlFields()
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	for field := range c.Indexes {
+		index := IndexSpec{Type: IndexBTree, Fields: []string{field}}
+		if seconds, ok := ttl[field]; ok {
+			index.ExpireAfterSeconds = &seconds
+		}
+		spec.Indexes = append(spec.Indexes, index)
+	}
+	for field := range c.HashIndexes {
+		spec.Indexes = append(spec.Indexes, IndexSpec{Type: IndexHash, Fields: []string{field}})
+	}
+	for field := range c.GeoIndexes {
+		spec.Indexes = append(spec.Indexes, IndexSpec{Type: IndexGeo, Fields: []string{field}})
+	}
+	for _, textIndex := range c.TextIndexes {
+		spec.Indexes = append(spec.Indexes, IndexSpec{Type: IndexText, Fields: textIndex.Fields()})
+	}
+	for field, vecIndex := range c.VecIndexes {
+		spec.Indexes = append(spec.Indexes, IndexSpec{
+			Type: IndexVector, Fields: []string{field}, Metric: string(vecIndex.Metric()), Dims: vecIndex.Dims(),
+		})
+	}
+	slices.SortFunc(spec.Indexes, func(a, b IndexSpec) int {
+		if a.Type != b.Type {
+			return cmp.Compare(a.Type, b.Type)
+		}
+		return slices.Compare(a.Fields, b.Fields)
+	})
+	return spec
+}
+
+// hasIndex — есть ли у коллекции индекс с таким типом и полями
+func (c *Collection) hasIndex(spec IndexSpec) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	if len(spec.Fields) == 0 {
+		return false
+	}
+	field := spec.Fields[0]
+	switch spec.Type {
+	case IndexBTree:
+		_, ok := c.Indexes[field]
+		return ok
+	case IndexHash:
+		_, ok := c.HashIndexes[field]
+		return ok
+	case IndexGeo:
+		_, ok := c.GeoIndexes[field]
+		return ok
+	case IndexText:
+		_, ok := c.TextIndexes[TextIndexName(spec.Fields)]
+		return ok
+	case IndexVector:
+		_, ok := c.VecIndexes[field]
+		return ok
+	}
+	return false
+}
+
+// CreateIndexFromSpec создаёт индекс по описанию; существующий индекс не пересоздаётся,
+// у TTL-индекса обновляется срок
+func (c *Collection) CreateIndexFromSpec(spec IndexSpec) error {
+	if len(spec.Fields) == 0 {
+		return fmt.Errorf("index spec has no fields")
+	}
+	if spec.Type == IndexBTree && spec.ExpireAfterSeconds != nil {
+		return c.CreateTTLIndex(spec.Fields[0], *spec.ExpireAfterSeconds)
+	}
+	if c.hasIndex(spec) {
+		return nil
+	}
+	switch spec.Type {
+	case IndexBTree:
+		return c.CreateIndex(spec.Fields[0], 64)
+	case IndexHash:
+		return c.CreateHashIndex(spec.Fields[0])
+	case IndexGeo:
+		return c.CreateGeoIndex(spec.Fields[0])
+	case IndexText:
+		_, err := c.CreateTextIndex(spec.Fields)
+		return err
+	case IndexVector:
+		metric, err := vector.ParseMetric(spec.Metric)
+		if err != nil {
+			return err
+		}
+		return c.CreateVectorIndex(spec.Fields[0], metric, spec.Dims)
+	}
+	return fmt.Errorf("unknown index type: %s", spec.Type)
+}
+
+// noteIndexInternal запоминает созданный в задаче worker'а индекс для журнала;
+// событие публикуется при фиксации задачи. Вызывается под c.mutex
+func (c *Collection) noteIndexInternal(spec IndexSpec) {
+	if c.undo != nil {
+		c.commands = append(c.commands, ChangeEvent{Op: ChangeCreateIndex, NS: c.Namespace(), Index: &spec})
+	}
+}
+
+// publishCommand публикует команду в журнал; вызывается внутри EnqueueTx команды,
+// пока её барьер держит очереди затронутых коллекций
+func (m *CollectionMng) publishCommand(event ChangeEvent) {
+	m.changes.publish([]ChangeEvent{event})
+}
+
+// CollectionExists — создана ли коллекция
+func (m *CollectionMng) CollectionExists(ns Namespace) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.collectionExistsLocked(ns)
+}
+
+// Upsert записывает документ с заданным _id: заменяет существующий или вставляет новый.
+// Применение журнала реплики: проверки схемы и размера capped-коллекции выполнил primary
+func (c *Collection) Upsert(id string, doc map[string]any) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	stored := cloneDocument(doc)
+	stored["_id"] = id
+	c.recordChangeInternal(id)
+	if prev, ok := c.Data.Get(id); ok {
+		c.updateIndexesOnDelete(id, prev)
+	} else if c.capped != nil {
+		// следующий _id capped-коллекции продолжает номера primary
+		if seq, err := strconv.ParseUint(id, 10, 64); err == nil && seq > c.capped.seq {
+			c.capped.seq = seq
+		}
+	}
+	c.Data.Put(id, stored)
+	c.updateIndexesOnInsert(id, stored)
+	c.trimCappedInternal(id)
+}
+
+// ApplyChanges применяет события журнала другого узла. Подряд идущие изменения
+// документов одной коллекции применяются одной задачей её очереди, команды — по одной
+func (m *CollectionMng) ApplyChanges(events []ChangeEvent) error {
+	for start := 0; start < len(events); {
+		event := events[start]
+		if !IsDocumentChange(event.Op) {
+			if err := m.applyCommand(event); err != nil {
+				return fmt.Errorf("apply %s %s: %w", event.Op, event.NS, err)
+			}
+			start++
+			continue
+		}
+
+		end := start + 1
+		for end < len(events) && IsDocumentChange(events[end].Op) && events[end].NS == event.NS {
+			end++
+		}
+		batch := events[start:end]
+		result := m.Enqueue(event.NS, func(coll *Collection) (WriteResult, error) {
+			for _, change := range batch {
+				if change.Op == ChangeDelete {
+					coll.Delete(change.ID)
+				} else {
+					coll.Upsert(change.ID, change.Doc)
+				}
+			}
+			return WriteResult{}, nil
+		})
+		if result.Error != nil {
+			return fmt.Errorf("apply changes to %s: %w", event.NS, result.Error)
+		}
+		start = end
+	}
+	return nil
+}
+
+// applyCommand применяет команду журнала; уже выполненная команда пропускается
+func (m *CollectionMng) applyCommand(event ChangeEvent) error {
+	ns := event.NS
+	switch event.Op {
+	case ChangeCreateCollection:
+		if event.Spec == nil || m.CollectionExists(ns) {
+			return nil
+		}
+		return m.CreateCollection(ns, CollectionOptions{
+			Engine: event.Spec.Engine, Capped: event.Spec.Capped, Validator: event.Spec.Validator,
+		})
+	case ChangeDropCollection:
+		if !m.CollectionExists(ns) {
+			return nil
+		}
+		return m.DropCollection(ns)
+	case ChangeRenameCollection:
+		if event.To == nil || !m.CollectionExists(ns) || m.CollectionExists(*event.To) {
+			return nil
+		}
+		return m.RenameCollection(ns, *event.To)
+	case ChangeDropDatabase:
+		infos, err := m.ListCollections(ns.DB)
+		if err != nil || len(infos) == 0 {
+			return err
+		}
+		_, err = m.DropDatabase(ns.DB)
+		return err
+	case ChangeCreateIndex:
+		if event.Index == nil {
+			return nil
+		}
+		result := m.Enqueue(ns, func(coll *Collection) (WriteResult, error) {
+			return WriteResult{}, coll.CreateIndexFromSpec(*event.Index)
+		})
+		return result.Error
+	case ChangeCollMod:
+		mod := CollModOptions{SetSchema: true}
+		if event.Validator != nil {
+			mod.Schema, mod.Level = event.Validator.Schema, event.Validator.Level
+		}
+		if !m.CollectionExists(ns) {
+			return nil
+		}
+		_, err := m.CollMod(ns, mod)
+		return err
+	}
+	return fmt.Errorf("unknown operation '%s'", event.Op)
+}
+
+// DocumentEvents представляет документы копии коллекции вставками журнала для ApplyChanges
+func DocumentEvents(ns Namespace, docs []map[string]any) []ChangeEvent {
+	events := make([]ChangeEvent, 0, len(docs))
+	for _, doc := range docs {
+		id, _ := doc["_id"].(string)
+		events = append(events, ChangeEvent{Op: ChangeInsert, NS: ns, ID: id, Doc: doc})
+	}
+	return events
+}
+
+// CreateCollectionFromSpec создаёт коллекцию начальной синхронизации реплики вместе с индексами
+// и документами; существующая коллекция с тем же именем заменяется
+func (m *CollectionMng) CreateCollectionFromSpec(ns Namespace, spec CollectionSpec) error {
+	if m.CollectionExists(ns) {
+		if err := m.DropCollection(ns); err != nil {
+			return err
+		}
+	}
+	return m.CreateCollection(ns, CollectionOptions{Engine: spec.Engine, Capped: spec.Capped, Validator: spec.Validator})
+}
+
+// CreateIndexes создаёт индексы по описаниям одной задачей очереди коллекции
+func (m *CollectionMng) CreateIndexes(ns Namespace, specs []IndexSpec) error {
+	if len(specs) == 0 {
+		return nil
+	}
+	result := m.Enqueue(ns, func(coll *Collection) (WriteResult, error) {
+		for _, spec := range specs {
+			if err := coll.CreateIndexFromSpec(spec); err != nil {
+				return WriteResult{}, err
+			}
+		}
+		return WriteResult{}, nil
+	})
+	return result.Error
+}
@@ -5,53 +5,198 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
-// LoadCollection загружает коллекцию из базы данных
-func LoadCollection(name string) (*Collection, error) {
-	path := filepath.Join("data", name+".json")
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		return NewCollection(name), nil
-	}
-	bytes, err := os.ReadFile(path)
+// LoadCollection открывает коллекцию движком из её метаданных; коллекции без метаданных
+// (созданные до выбора движков или ещё не сохранённые) используют движок по умолчанию
+func LoadCollection(root string, ns Namespace) (*Collection, error) {
+	meta, found, err := readCollectionMeta(collectionDir(root, ns))
 	if err != nil {
 		return nil, err
 	}
-
-	// Файл может существовать, но быть пустым или содержать только пробелы
-	if len(strings.TrimSpace(string(bytes))) == 0 {
-		return NewCollection(name), nil
+	if !found {
+		meta.Engine = EngineHashMap
 	}
-	var raw map[string]any
-	if err := json.Unmarshal(bytes, &raw); err != nil {
-		return nil, fmt.Errorf("unmarshal error: %w", err)
+	engine, err := openEngine(meta.Engine, root, ns)
+	if err != nil {
+		return nil, err
 	}
-	hmap := NewHashMap()
-	for k, v := range raw {
-		hmap.Put(k, v)
+
+	coll := newCollection(root, ns, meta, engine)
+	coll.metaSaved = found
+	if !found && engine.LSN() > 0 {
+		// данные старого формата: LSN файлов индексов хранился в манифесте сегментов
+		if legacy, ok := engine.(*hashMapEngine); ok {
+			coll.meta.IndexLSN = legacy.legacyIndexLSN()
+		}
+		if err := coll.saveMeta(); err != nil {
+			return nil, err
+		}
 	}
-	coll := NewCollection(name)
-	coll.Data = hmap
 	return coll, nil
 }
 
-// Save сохраняет данные в json в базе данных
-func (c *Collection) Save() error {
-	c.mutex.RLock()
-	defer c.mutex.RUnlock()
+const metaName = "collection.json"
+
+// collectionMeta — параметры, выбранные при создании коллекции, и LSN сохранённых индексов
+type collectionMeta struct {
+	Engine   string `json:"engine"`
+	IndexLSN uint64 `json:"index_lsn"` // LSN движка, по который сохранены файлы индексов
 
-	items := c.Data.Items()
-	data, err := json.MarshalIndent(items, "", "  ")
+	TTL       map[string]int64  `json:"ttl,omitempty"`       // TTL-индексы: поле -> срок жизни документа в секундах
+	Capped    *CappedOptions    `json:"capped,omitempty"`    // ограничения capped-коллекции
+	Validator *ValidatorOptions `json:"validator,omitempty"` // JSON Schema документов и уровень проверки
+}
+
+// readCollectionMeta читает метаданные коллекции; false — их ещё нет
+func readCollectionMeta(dir string) (collectionMeta, bool, error) {
+	var meta collectionMeta
+	raw, err := os.ReadFile(filepath.Join(dir, metaName))
+	if os.IsNotExist(err) {
+		return meta, false, nil
+	}
 	if err != nil {
-		return fmt.Errorf("marshal error: %w", err)
+		return meta, false, fmt.Errorf("failed to read collection meta: %w", err)
 	}
-	if err := os.MkdirAll("data", 0755); err != nil {
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return meta, false, fmt.Errorf("failed to parse collection meta: %w", err)
+	}
+	if _, err := ValidEngine(meta.Engine); err != nil {
+		return meta, false, err
+	}
+	return meta, true, nil
+}
+
+// saveMeta атомарно записывает метаданные коллекции
+func (c *Collection) saveMeta() error {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	return c.saveMetaLocked()
+}
+
+func (c *Collection) saveMetaLocked() error {
+	dir := collectionDir(c.root, c.Namespace())
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return fmt.Errorf("mkdir error: %w", err)
 	}
-	path := filepath.Join("data", c.Name+".json")
-	if err := os.WriteFile(path, data, 0644); err != nil {
-		return fmt.Errorf("write file error: %w", err)
+	raw, err := json.Marshal(c.meta)
+	if err != nil {
+		return fmt.Errorf("marshal collection meta: %w", err)
+	}
+	tmp := filepath.Join(dir, metaName+".tmp")
+	if err := writeFileSync(tmp, raw); err != nil {
+		return fmt.Errorf("write collection meta: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, metaName)); err != nil {
+		return fmt.Errorf("rename collection meta: %w", err)
+	}
+	c.metaSaved = true
+	return syncPath(dir)
+}
+
+// EngineName возвращает движок хранения коллекции
+func (c *Collection) EngineName() string {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	return c.meta.Engine
+}
+
+// persistent — false для коллекций в памяти: их данные и индексы не сохраняются
+func (c *Collection) persistent() bool {
+	return c.EngineName() != EngineMemory
+}
+
+// exists — была ли коллекция создана: сохранены метаданные или в ней есть данные
+func (c *Collection) exists() bool {
+	c.metaMu.Lock()
+	saved := c.metaSaved
+	c.metaMu.Unlock()
+	return saved || c.Data.Len() > 0 || c.Data.LSN() > 0
+}
+
+// indexCheckpointDue — пора ли заново сохранить файлы индексов
+func (c *Collection) indexCheckpointDue() bool {
+	if !c.persistent() {
+		return false
+	}
+	lsn := c.Data.LSN()
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	return c.forceCheckpoint || lsn-min(lsn, c.meta.IndexLSN) >= indexCheckpointRecords
+}
+
+// checkpointIndexes сохраняет все индексы и отмечает в метаданных LSN, которому они соответствуют.
+// Вызывается из worker'а после Flush, когда состояние в памяти совпадает с журналом
+func (c *Collection) checkpointIndexes() error {
+	if err := c.SaveAllIndexes(); err != nil {
+		return fmt.Errorf("failed to save indexes: %w", err)
+	}
+	return c.markIndexCheckpoint()
+}
+
+// markIndexCheckpoint делает fsync файлов индексов и записывает в метаданные текущий LSN
+func (c *Collection) markIndexCheckpoint() error {
+	if err := c.syncIndexFiles(); err != nil {
+		return err
+	}
+	lsn := c.Data.LSN()
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	c.meta.IndexLSN = lsn
+	c.forceCheckpoint = false
+	return c.saveMetaLocked()
+}
+
+// syncIndexFiles выполняет fsync файлов индексов коллекции и их каталога
+func (c *Collection) syncIndexFiles() error {
+	indexDir := c.indexDir()
+	entries, err := os.ReadDir(indexDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, entry := range entries {
+		if err := syncPath(filepath.Join(indexDir, entry.Name())); err != nil {
+			return fmt.Errorf("fsync %s: %w", entry.Name(), err)
+		}
+	}
+	return syncPath(indexDir)
+}
+
+// InvalidateIndexCheckpoint помечает файлы индексов как не соответствующие журналу:
+// вызывается перед изменением набора индексов, чтобы после сбоя они перестроились из данных
+func (c *Collection) InvalidateIndexCheckpoint() error {
+	if !c.persistent() {
+		return nil
+	}
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	if !c.metaSaved {
+		return nil
+	}
+	c.meta.IndexLSN = 0
+	c.forceCheckpoint = true
+	return c.saveMetaLocked()
+}
+
+// indexesStale — true, если журнал ушёл дальше сохранённых индексов
+func (c *Collection) indexesStale() bool {
+	lsn := c.Data.LSN()
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	return lsn != c.meta.IndexLSN
+}
+
+// Close закрывает файлы коллекции и будит tailable-курсоры: они завершаются с ошибкой
+func (c *Collection) Close() error {
+	c.mutex.Lock()
+	c.closed = true
+	if c.capped != nil {
+		close(c.capped.changed)
+		c.capped.changed = make(chan struct{})
 	}
-	return nil
+	c.mutex.Unlock()
+	return c.Data.Close()
 }
@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// queueCount возвращает число запущенных worker'ов коллекций
+func queueCount(m *CollectionMng) int {
+	m.qmu.Lock()
+	defer m.qmu.Unlock()
+	return len(m.queues)
+}
+
+func TestQueueOrder(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	ns := testNS("test")
+
+	// задачи одной коллекции выполняются в порядке постановки, в том числе внутри группы
+	var order []int
+	results := make([]chan WriteResult, 300)
+	for i := range results {
+		results[i] = make(chan WriteResult, 1)
+		m.enqueueJob(ns, WriteJob{Namespace: ns, Durability: DurabilityFlushed, ResultChan: results[i],
+			Operation: func(coll *Collection) (WriteResult, error) {
+				order = append(order, i)
+				if i%7 == 0 {
+					return WriteResult{}, errors.New("rejected")
+				}
+				_, err := coll.Insert(map[string]any{"i": float64(i)})
+				return WriteResult{}, err
+			}})
+	}
+	for i, result := range results {
+		// ошибка одной задачи группы не задевает соседние
+		if err := (<-result).Error; (err != nil) != (i%7 == 0) {
+			t.Fatalf("job %d: %v", i, err)
+		}
+	}
+	for i := range order {
+		if order[i] != i {
+			t.Fatalf("job %d ran at position %d", order[i], i)
+		}
+	}
+	withCollection(t, m, ns, func(coll *Collection) {
+		if coll.Count() != 300-43 {
+			t.Fatalf("%d document(s)", coll.Count())
+		}
+	})
+}
+
+func TestQueuesIndependent(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	release := make(chan struct{})
+	slow := make(chan WriteResult, 1)
+	started := make(chan struct{})
+	m.enqueueJob(testNS("logs"), WriteJob{Namespace: testNS("logs"), Durability: DurabilityFlushed, ResultChan: slow,
+		Operation: func(coll *Collection) (WriteResult, error) {
+			close(started)
+			<-release
+			return WriteResult{}, nil
+		}})
+	<-started
+
+	// запись в другую коллекцию не ждёт занятой очереди logs
+	done := make(chan WriteResult, 1)
+	go func() {
+		done <- m.Enqueue(testNS("users"), func(coll *Collection) (WriteResult, error) {
+			_, err := coll.Insert(map[string]any{"name": "ann"})
+			return WriteResult{}, err
+		})
+	}()
+	select {
+	case result := <-done:
+		if result.Error != nil {
+			t.Fatal(result.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("write to users waited for the busy logs queue")
+	}
+	select {
+	case <-slow:
+		t.Fatal("slow job finished before it was released")
+	default:
+	}
+	close(release)
+	if result := <-slow; result.Error != nil {
+		t.Fatal(result.Error)
+	}
+}
+
+func TestQueueIdleStop(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	m.idle = 20 * time.Millisecond
+	insert := func(coll *Collection) (WriteResult, error) {
+		id, err := coll.Insert(map[string]any{})
+		return WriteResult{InsertedIDs: []string{id}}, err
+	}
+	mustWrite(t, m, testNS("a"), insert)
+	mustWrite(t, m, testNS("b"), insert)
+	if n := queueCount(m); n != 2 {
+		t.Fatalf("%d queue(s) after writes to two collections", n)
+	}
+
+	// простаивающий worker останавливается, следующая запись запускает новый
+	deadline := time.Now().Add(5 * time.Second)
+	for queueCount(m) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d idle queue(s) still running", queueCount(m))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	mustWrite(t, m, testNS("a"), insert)
+	withCollection(t, m, testNS("a"), func(coll *Collection) {
+		if coll.Count() != 2 {
+			t.Fatalf("%d document(s) after the worker restarted", coll.Count())
+		}
+	})
+	if n := queueCount(m); n != 1 {
+		t.Fatalf("%d queue(s) after a write to one collection", n)
+	}
+}
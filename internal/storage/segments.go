@@ -0,0 +1,316 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	manifestName = "MANIFEST"
+	segmentExt   = ".log"
+
+	manifestVersion = 1
+
+	// maxSegmentSize — размер активного сегмента, после которого начинается новый
+	maxSegmentSize = 16 << 20
+	// compactMinRecords и compactRatio — компактизация запускается, когда записей в сегментах
+	// больше compactMinRecords и больше compactRatio * числа живых документов
+	compactMinRecords = 1000
+	compactRatio      = 2
+	// indexCheckpointRecords — через сколько новых записей файлы индексов сохраняются заново
+	indexCheckpointRecords = 10000
+)
+
+// record — запись журнала данных: put с документом или del (tombstone)
+type record struct {
+	LSN uint64         `json:"lsn"`
+	Op  string         `json:"op"`
+	ID  string         `json:"id"`
+	Doc map[string]any `json:"doc,omitempty"`
+}
+
+const (
+	opPut = "put"
+	opDel = "del"
+)
+
+// Manifest — список живых сегментов коллекции в порядке применения
+type Manifest struct {
+	Version     int      `json:"version"`
+	Segments    []string `json:"segments"`
+	NextSegment int      `json:"next_segment"`
+	IndexLSN    uint64   `json:"index_lsn"` // LSN индексов в формате до метаданных коллекции
+	// Valid — длина целых записей закрытых сегментов, в конце которых осталась
+	// недописанная строка (после сбоя записи не удалось обрезать файл)
+	Valid map[string]int64 `json:"valid,omitempty"`
+}
+
+// segmentFile — активный сегмент; *os.File, в тестах — файл со сбоями записи
+type segmentFile interface {
+	io.Writer
+	Truncate(size int64) error
+	Sync() error
+	Close() error
+}
+
+// segmentStore — файлы данных движка hashmap: append-only сегменты и манифест.
+// mu упорядочивает запись, ротацию и компактизацию; берётся до мьютекса коллекции
+type segmentStore struct {
+	mu         sync.Mutex
+	dir        string
+	manifest   Manifest
+	counts     map[string]int // записей в сегменте
+	active     segmentFile
+	activeSize int64  // длина активного сегмента: конец последней целой записи
+	lsn        uint64 // последний записанный LSN
+	sinceIndex int    // записей после сохранения индексов
+	compacting atomic.Bool
+}
+
+func newSegmentStore(dir string) *segmentStore {
+	return &segmentStore{
+		dir:      dir,
+		manifest: Manifest{Version: manifestVersion, NextSegment: 1},
+		counts:   make(map[string]int),
+	}
+}
+
+// records возвращает общее число записей во всех сегментах
+func (st *segmentStore) records() int {
+	total := 0
+	for _, n := range st.counts {
+		total += n
+	}
+	return total
+}
+
+// openStore читает манифест и применяет сегменты по порядку; false — манифеста нет
+func openStore(dir string, data *HashMap) (*segmentStore, bool, error) {
+	st := newSegmentStore(dir)
+	raw, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if os.IsNotExist(err) {
+		return st, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if err := json.Unmarshal(raw, &st.manifest); err != nil {
+		return nil, false, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	for i, name := range st.manifest.Segments {
+		last := i == len(st.manifest.Segments)-1
+		if err := st.replay(name, data, last); err != nil {
+			return nil, false, err
+		}
+	}
+	st.sinceIndex = int(st.lsn - min(st.lsn, st.manifest.IndexLSN))
+	return st, true, nil
+}
+
+// replay применяет записи сегмента. Недописанная последняя строка последнего сегмента
+// (сбой во время записи) отбрасывается, файл обрезается до последней целой записи
+func (st *segmentStore) replay(name string, data *HashMap, last bool) error {
+	path := filepath.Join(st.dir, name)
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open segment %s: %w", name, err)
+	}
+	defer f.Close()
+
+	var source io.Reader = f
+	if size, ok := st.manifest.Valid[name]; ok {
+		source = io.LimitReader(f, size)
+	}
+	reader := bufio.NewReader(source)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF && len(line) == 0 {
+			break
+		}
+		complete := err == nil
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read segment %s: %w", name, err)
+		}
+
+		var rec record
+		if decodeErr := json.Unmarshal(bytes.TrimSpace(line), &rec); decodeErr != nil || !complete {
+			if last {
+				return os.Truncate(path, offset)
+			}
+			return fmt.Errorf("corrupted record in segment %s at offset %d", name, offset)
+		}
+		offset += int64(len(line))
+
+		switch rec.Op {
+		case opPut:
+			data.Put(rec.ID, rec.Doc)
+		case opDel:
+			data.Remove(rec.ID)
+		}
+		st.counts[name]++
+		st.lsn = max(st.lsn, rec.LSN)
+	}
+	return nil
+}
+
+// append дописывает записи в активный сегмент, начиная новый при переполнении
+func (st *segmentStore) append(records []record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if st.active == nil || st.activeSize >= maxSegmentSize {
+		if _, err := st.rotate(); err != nil {
+			return err
+		}
+	}
+
+	var buf bytes.Buffer
+	lsn := st.lsn
+	for i := range records {
+		lsn++
+		records[i].LSN = lsn
+		line, err := json.Marshal(records[i])
+		if err != nil {
+			return fmt.Errorf("marshal error: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	n, err := st.active.Write(buf.Bytes())
+	if err != nil {
+		// недописанная строка в середине сегмента обрезала бы при replay всё, что запишется
+		// после неё: файл возвращается к концу последней целой записи
+		if n > 0 {
+			if truncErr := st.active.Truncate(st.activeSize); truncErr != nil {
+				// обрезать не удалось: следующие записи пойдут в новый сегмент, а манифест,
+				// записанный при ротации, ограничит replay этого сегмента целыми записями
+				if st.manifest.Valid == nil {
+					st.manifest.Valid = make(map[string]int64)
+				}
+				st.manifest.Valid[st.activeName()] = st.activeSize
+				st.active.Close()
+				st.active = nil
+			}
+		}
+		return fmt.Errorf("write segment error: %w", err)
+	}
+	st.activeSize += int64(n)
+
+	st.lsn = lsn
+	st.counts[st.activeName()] += len(records)
+	st.sinceIndex += len(records)
+	return nil
+}
+
+func (st *segmentStore) activeName() string {
+	return st.manifest.Segments[len(st.manifest.Segments)-1]
+}
+
+// rotate закрывает активный сегмент и начинает новый; возвращает сегменты до ротации
+func (st *segmentStore) rotate() ([]string, error) {
+	if err := os.MkdirAll(st.dir, 0755); err != nil {
+		return nil, fmt.Errorf("mkdir error: %w", err)
+	}
+	if st.active != nil {
+		if err := st.active.Close(); err != nil {
+			return nil, err
+		}
+		st.active = nil
+	}
+
+	sealed := append([]string(nil), st.manifest.Segments...)
+	name := st.reserveName()
+	f, err := os.OpenFile(filepath.Join(st.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create segment: %w", err)
+	}
+	st.manifest.Segments = append(st.manifest.Segments, name)
+	if err := st.writeManifest(); err != nil {
+		f.Close()
+		st.manifest.Segments = sealed
+		return nil, err
+	}
+	st.active = f
+	st.activeSize = 0
+	return sealed, nil
+}
+
+func (st *segmentStore) reserveName() string {
+	name := fmt.Sprintf("%06d%s", st.manifest.NextSegment, segmentExt)
+	st.manifest.NextSegment++
+	return name
+}
+
+// writeManifest атомарно заменяет манифест (запись во временный файл, fsync, rename)
+func (st *segmentStore) writeManifest() error {
+	raw, err := json.Marshal(st.manifest)
+	if err != nil {
+		return fmt.Errorf("marshal manifest: %w", err)
+	}
+	tmp := filepath.Join(st.dir, manifestName+".tmp")
+	if err := writeFileSync(tmp, raw); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(st.dir, manifestName)); err != nil {
+		return fmt.Errorf("rename manifest: %w", err)
+	}
+	return syncPath(st.dir)
+}
+
+// sync выполняет fsync активного сегмента
+func (st *segmentStore) sync() error {
+	if st.active == nil {
+		return nil
+	}
+	return st.active.Sync()
+}
+
+// close закрывает активный сегмент
+func (st *segmentStore) close() error {
+	if st.active == nil {
+		return nil
+	}
+	err := st.active.Close()
+	st.active = nil
+	return err
+}
+
+// writeSegment пишет полный сегмент из документов (результат компактизации или миграции)
+func writeSegment(path string, docs []map[string]any, lsn uint64) error {
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		id, _ := doc["_id"].(string)
+		line, err := json.Marshal(record{LSN: lsn, Op: opPut, ID: id, Doc: doc})
+		if err != nil {
+			return fmt.Errorf("marshal error: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return writeFileSync(path, buf.Bytes())
+}
+
+func writeFileSync(path string, data []byte) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -2,13 +2,52 @@ package storage
 
 import (
 	"nosql_db/internal/index"
+	"nosql_db/internal/vector"
 )
 
+// indexFormatVersion — версия кодирования ключей в файлах индексов;
+// индексы старых версий при загрузке перестраиваются из данных
+const indexFormatVersion = 2
+
 // IndexFile структура для сохранения индекса
 type IndexFile struct {
-	Field string           `json:"field"`
-	Order int              `json:"order"`
-	Nodes []SerializedNode `json:"nodes"`
+	Version int              `json:"version"`
+	Field   string           `json:"field"`
+	Order   int              `json:"order"`
+	Nodes   []SerializedNode `json:"nodes"`
+}
+
+// TextIndexFile структура для сохранения полнотекстового индекса
+type TextIndexFile struct {
+	Name     string                    `json:"name"`
+	Fields   []string                  `json:"fields"`
+	Postings map[string]map[string]int `json:"postings"`
+	Lengths  map[string]int            `json:"lengths"`
+}
+
+// HashIndexFile структура для сохранения хеш-индекса
+type HashIndexFile struct {
+	Version int              `json:"version"`
+	Field   string           `json:"field"`
+	Entries []HashIndexEntry `json:"entries"`
+}
+
+// HashIndexEntry — ключ хеш-индекса (закодированное значение поля) и id документов
+type HashIndexEntry struct {
+	Key []byte   `json:"key"`
+	IDs []string `json:"ids"`
+}
+
+// VectorIndexFile структура для сохранения векторного индекса вместе с графом HNSW
+type VectorIndexFile struct {
+	Field    string                  `json:"field"`
+	Metric   string                  `json:"metric"`
+	Dims     int                     `json:"dims"`
+	Vectors  map[string][]float32    `json:"vectors"`
+	Nodes    map[string]*vector.Node `json:"nodes"`
+	Deleted  []string                `json:"deleted,omitempty"`
+	Entry    string                  `json:"entry"`
+	MaxLevel int                     `json:"max_level"`
 }
 
 // SerializedNode представляет сериализованный узел b-tree
@@ -23,9 +62,10 @@ type SerializedNode struct {
 func serializeBTree(tree *index.BTree, fieldName string, order int) *IndexFile {
 	if tree == nil || tree.GetRoot() == nil {
 		return &IndexFile{
-			Field: fieldName,
-			Order: order,
-			Nodes: []SerializedNode{},
+			Version: indexFormatVersion,
+			Field:   fieldName,
+			Order:   order,
+			Nodes:   []SerializedNode{},
 		}
 	}
 	var nodes []SerializedNode
@@ -86,9 +126,10 @@ func serializeBTree(tree *index.BTree, fieldName string, order int) *IndexFile {
 		nodes = append(nodes, serialized)
 	}
 	return &IndexFile{
-		Field: fieldName,
-		Order: order,
-		Nodes: nodes,
+		Version: indexFormatVersion,
+		Field:   fieldName,
+		Order:   order,
+		Nodes:   nodes,
 	}
 }
 
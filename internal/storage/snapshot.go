@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"sort"
+	"time"
+)
+
+// docVersion — вытесненная версия документа, видимая снимкам с версиями [from, to).
+// doc == nil — в этом интервале документа не было
+type docVersion struct {
+	doc  map[string]any
+	from uint64
+	to   uint64
+}
+
+// Snapshot — согласованный снимок коллекции для чтения: видит только изменения,
+// зафиксированные до его создания. Возвращаемые документы — копии.
+// Снимок нужно освободить через Release, чтобы старые версии могли быть удалены
+type Snapshot struct {
+	coll     *Collection
+	ts       uint64 // версия коллекции на момент создания
+	mods     uint64 // счётчик изменений на момент создания
+	dirty    bool   // в момент создания шла незафиксированная запись
+	released bool
+
+	// TTL-индексы: документы, истёкшие к now, снимку не видны
+	ttl      map[string]int64
+	now      int64
+	expiring bool // к now мог истечь хотя бы один документ
+}
+
+// Snapshot создаёт снимок последней зафиксированной версии коллекции
+func (c *Collection) Snapshot() *Snapshot {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.readers[c.committed]++
+	snap := &Snapshot{
+		coll:  c,
+		ts:    c.committed,
+		mods:  c.mods,
+		dirty: len(c.undo) > 0,
+		ttl:   c.ttlFields(),
+		now:   time.Now().UnixNano(),
+	}
+	snap.expiring = snap.ttl != nil && c.nextExpiry <= snap.now
+	return snap
+}
+
+// expired — истёк ли документ к моменту снимка
+func (s *Snapshot) expired(doc map[string]any) bool {
+	if !s.expiring {
+		return false
+	}
+	at, ok := expiresAt(doc, s.ttl)
+	return ok && at <= s.now
+}
+
+// Release освобождает снимок и удаляет версии, которые больше никому не видны
+func (s *Snapshot) Release() {
+	c := s.coll
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if s.released {
+		return
+	}
+	s.released = true
+	if c.readers[s.ts]--; c.readers[s.ts] == 0 {
+		delete(c.readers, s.ts)
+	}
+	c.gcVersionsInternal()
+}
+
+// Collection возвращает коллекцию снимка (для доступа к индексам)
+func (s *Snapshot) Collection() *Collection {
+	return s.coll
+}
+
+// Stale — true, если после создания снимка коллекция менялась (или менялась в момент создания)
+// или в ней есть истёкшие документы, ещё не удалённые reaper'ом: тогда текущие индексы
+// могут не совпадать со снимком, и ответ только по индексам недопустим
+func (s *Snapshot) Stale() bool {
+	s.coll.mutex.RLock()
+	defer s.coll.mutex.RUnlock()
+	return s.dirty || s.expiring || s.coll.mods != s.mods
+}
+
+// Modifications возвращает счётчик изменений коллекции
+func (s *Snapshot) Modifications() uint64 {
+	s.coll.mutex.RLock()
+	defer s.coll.mutex.RUnlock()
+	return s.coll.mods
+}
+
+// Changed возвращает id документов, изменённых после снимка (в том числе незафиксированно)
+func (s *Snapshot) Changed() []string {
+	s.coll.mutex.RLock()
+	defer s.coll.mutex.RUnlock()
+
+	var ids []string
+	for id, versions := range s.coll.history {
+		for _, v := range versions {
+			if v.to > s.ts {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+// Get возвращает копию документа в версии снимка
+func (s *Snapshot) Get(id string) (map[string]any, bool) {
+	s.coll.mutex.RLock()
+	defer s.coll.mutex.RUnlock()
+
+	doc, ok := s.coll.resolveInternal(id, s.ts)
+	if !ok || s.expired(doc) {
+		return nil, false
+	}
+	return cloneDocument(doc), true
+}
+
+// All возвращает копии всех документов в версии снимка
+func (s *Snapshot) All() []map[string]any {
+	s.coll.mutex.RLock()
+	defer s.coll.mutex.RUnlock()
+
+	docs := make([]map[string]any, 0, s.coll.Data.Len())
+	s.coll.Data.Scan(func(id string, doc map[string]any) bool {
+		if doc, ok := s.coll.resolveVersionInternal(id, doc, s.ts); ok && !s.expired(doc) {
+			docs = append(docs, cloneDocument(doc))
+		}
+		return true
+	})
+	// документы, удалённые после снимка, остались только в истории
+	for id := range s.coll.history {
+		if _, current := s.coll.Data.Get(id); current {
+			continue
+		}
+		if doc, ok := s.coll.resolveInternal(id, s.ts); ok && !s.expired(doc) {
+			docs = append(docs, cloneDocument(doc))
+		}
+	}
+	// capped-коллекция отдаёт документы в порядке вставки — это порядок _id
+	if s.coll.capped != nil {
+		sort.Slice(docs, func(i, j int) bool { return docs[i]["_id"].(string) < docs[j]["_id"].(string) })
+	}
+	return docs
+}
+
+// idOrderCache — упорядоченные _id документов коллекции на момент mods
+type idOrderCache struct {
+	mods  uint64
+	ids   []string
+	valid bool
+}
+
+// Page возвращает до limit (0 — без ограничения) документов снимка с _id больше after
+// по возрастанию _id, пропуская не прошедшие match. Порядок _id кешируется до следующего
+// изменения коллекции: обход большой коллекции порциями не сортирует её на каждой порции
+func (s *Snapshot) Page(after string, limit int, match func(doc map[string]any) bool) []map[string]any {
+	ids := s.orderedIDs()
+	var docs []map[string]any
+	for i := sort.SearchStrings(ids, after); i < len(ids) && (limit <= 0 || len(docs) < limit); i++ {
+		if ids[i] == after {
+			continue
+		}
+		if doc, ok := s.Get(ids[i]); ok && match(doc) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// orderedIDs возвращает упорядоченные _id, видимые снимку (и, возможно, лишние — их отсеет Get).
+// Пока коллекция не менялась после снимка, берётся кеш коллекции
+func (s *Snapshot) orderedIDs() []string {
+	c := s.coll
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	current := !s.dirty && c.mods == s.mods
+	if current {
+		c.orderMu.Lock()
+		defer c.orderMu.Unlock()
+		if c.order.valid && c.order.mods == c.mods {
+			return c.order.ids
+		}
+	}
+
+	ids := make([]string, 0, c.Data.Len())
+	c.Data.Scan(func(id string, _ map[string]any) bool {
+		ids = append(ids, id)
+		return true
+	})
+	// документы, удалённые после снимка, остались только в истории
+	for id := range c.history {
+		if _, exists := c.Data.Get(id); !exists {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if current {
+		c.order = idOrderCache{mods: c.mods, ids: ids, valid: true}
+	}
+	return ids
+}
+
+// Count возвращает количество документов в версии снимка
+func (s *Snapshot) Count() int {
+	if n, ok := s.StoredCount(); ok {
+		return n
+	}
+	return len(s.All())
+}
+
+// StoredCount возвращает счётчик движка, если он совпадает с версией снимка: снимок не отстал
+// от коллекции, не видит своих незафиксированных изменений и истёкших документов. Иначе ok = false
+// и посчитать документы можно только обходом
+func (s *Snapshot) StoredCount() (n int, ok bool) {
+	s.coll.mutex.RLock()
+	defer s.coll.mutex.RUnlock()
+	if s.dirty || s.expiring || s.coll.mods != s.mods {
+		return 0, false
+	}
+	return s.coll.Data.Len(), true
+}
+
+// resolveInternal находит версию документа, видимую снимку ts, без блокировок
+func (c *Collection) resolveInternal(id string, ts uint64) (map[string]any, bool) {
+	for _, v := range c.history[id] {
+		if v.from <= ts && ts < v.to {
+			return v.doc, v.doc != nil
+		}
+	}
+	if c.versions[id] > ts {
+		return nil, false
+	}
+	return c.Data.Get(id)
+}
+
+// resolveVersionInternal — resolveInternal для документа, уже прочитанного из движка (обход Scan)
+func (c *Collection) resolveVersionInternal(id string, current map[string]any, ts uint64) (map[string]any, bool) {
+	if _, changed := c.versions[id]; changed {
+		return c.resolveInternal(id, ts)
+	}
+	return current, true
+}
+
+// recordChangeInternal вызывается перед изменением документа: вытесняемая версия
+// уходит в историю, чтобы её видели более ранние снимки. Внутри транзакции
+// изменение получает версию следующей фиксации, вне её — фиксируется сразу
+func (c *Collection) recordChangeInternal(id string) {
+	ts := c.committed + 1
+	prevVersion := c.versions[id]
+	prev, _ := c.Data.Get(id)
+
+	// повторное изменение в той же транзакции промежуточных версий не создаёт
+	if prevVersion != ts {
+		c.history[id] = append(c.history[id], docVersion{doc: prev, from: prevVersion, to: ts})
+	}
+	c.versions[id] = ts
+	c.mods++
+
+	if c.undo != nil {
+		c.undo = append(c.undo, undoEntry{id: id, prev: prev, prevVersion: prevVersion})
+	} else {
+		c.committed = ts
+		c.gcVersionsInternal()
+	}
+}
+
+// gcVersionsInternal удаляет версии, которые не видны ни одному активному снимку
+func (c *Collection) gcVersionsInternal() {
+	oldest := c.committed
+	for ts := range c.readers {
+		oldest = min(oldest, ts)
+	}
+
+	for id, versions := range c.history {
+		kept := versions[:0]
+		for _, v := range versions {
+			if v.to > oldest {
+				kept = append(kept, v)
+			}
+		}
+		if len(kept) == 0 {
+			delete(c.history, id)
+			if c.versions[id] <= oldest {
+				delete(c.versions, id)
+			}
+			continue
+		}
+		c.history[id] = kept
+	}
+}
+
+// cloneDocument делает глубокую копию документа
+func cloneDocument(doc map[string]any) map[string]any {
+	clone := make(map[string]any, len(doc))
+	for k, v := range doc {
+		clone[k] = cloneValue(v)
+	}
+	return clone
+}
+
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return cloneDocument(v)
+	case []any:
+		clone := make([]any, len(v))
+		for i, item := range v {
+			clone[i] = cloneValue(item)
+		}
+		return clone
+	default:
+		return v
+	}
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSnapshotIsolation(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	ns := testNS("test")
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		for _, id := range []string{"a", "b"} {
+			if _, err := coll.InsertWithID(id, map[string]any{"n": 1.0, "tags": []any{"x"}}); err != nil {
+				return WriteResult{}, err
+			}
+		}
+		return WriteResult{}, nil
+	})
+
+	withCollection(t, m, ns, func(coll *Collection) {
+		snap := coll.Snapshot()
+		if snap.Stale() {
+			t.Fatal("fresh snapshot is stale")
+		}
+
+		mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+			if _, err := coll.Replace("a", map[string]any{"n": 2.0}); err != nil {
+				return WriteResult{}, err
+			}
+			coll.Delete("b")
+			_, err := coll.InsertWithID("c", map[string]any{"n": 3.0})
+			return WriteResult{}, err
+		})
+
+		// снимок видит данные на момент создания
+		if doc, ok := snap.Get("a"); !ok || doc["n"] != 1.0 {
+			t.Fatalf("a in the snapshot: %v %v", doc, ok)
+		}
+		if _, ok := snap.Get("b"); !ok {
+			t.Fatal("b deleted after the snapshot must stay visible")
+		}
+		if _, ok := snap.Get("c"); ok {
+			t.Fatal("c inserted after the snapshot must not be visible")
+		}
+		if ids := snapshotIDs(snap); !slices.Equal(ids, []string{"a", "b"}) || snap.Count() != 2 {
+			t.Fatalf("snapshot documents: %v, count %d", ids, snap.Count())
+		}
+		changed := snap.Changed()
+		slices.Sort(changed)
+		if !snap.Stale() || !slices.Equal(changed, []string{"a", "b", "c"}) {
+			t.Fatalf("changed after the snapshot: %v, stale %v", changed, snap.Stale())
+		}
+
+		fresh := coll.Snapshot()
+		if ids := snapshotIDs(fresh); !slices.Equal(ids, []string{"a", "c"}) {
+			t.Fatalf("new snapshot: %v", ids)
+		}
+		fresh.Release()
+
+		// старые версии живут, пока снимок не освобождён
+		coll.mutex.RLock()
+		history := len(coll.history)
+		coll.mutex.RUnlock()
+		if history == 0 {
+			t.Fatal("versions were collected under an active snapshot")
+		}
+		snap.Release()
+		snap.Release()
+		coll.mutex.RLock()
+		history, versions, readers := len(coll.history), len(coll.versions), len(coll.readers)
+		coll.mutex.RUnlock()
+		if history != 0 || versions != 0 || readers != 0 {
+			t.Fatalf("after release: %d history, %d versions, %d readers", history, versions, readers)
+		}
+	})
+}
+
+func TestSnapshotCopiesAndUncommitted(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	ns := testNS("test")
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		_, err := coll.InsertWithID("a", map[string]any{"tags": []any{"x"}, "meta": map[string]any{"v": 1.0}})
+		return WriteResult{}, err
+	})
+
+	// документы снимка — копии: их изменение не задевает коллекцию
+	withCollection(t, m, ns, func(coll *Collection) {
+		snap := coll.Snapshot()
+		defer snap.Release()
+		doc, _ := snap.Get("a")
+		doc["tags"].([]any)[0] = "changed"
+		doc["meta"].(map[string]any)["v"] = 2.0
+		all := snap.All()
+		all[0]["new"] = true
+		if stored, _ := coll.GetByID("a"); stored["tags"].([]any)[0] != "x" || stored["meta"].(map[string]any)["v"] != 1.0 || stored["new"] != nil {
+			t.Fatalf("stored document was modified through a snapshot: %v", stored)
+		}
+	})
+
+	// снимок посреди транзакции не видит её незафиксированных изменений
+	result := m.EnqueueTx([]Namespace{ns}, DurabilityFlushed, func(tx *Tx) (WriteResult, error) {
+		coll, err := tx.Collection(ns)
+		if err != nil {
+			return WriteResult{}, err
+		}
+		if _, err := coll.InsertWithID("b", map[string]any{}); err != nil {
+			return WriteResult{}, err
+		}
+		coll.Delete("a")
+		snap := coll.Snapshot()
+		defer snap.Release()
+		if ids := snapshotIDs(snap); !slices.Equal(ids, []string{"a"}) || !snap.Stale() {
+			t.Errorf("snapshot inside a transaction: %v, stale %v", ids, snap.Stale())
+		}
+		return WriteResult{}, nil
+	})
+	if result.Error != nil {
+		t.Fatal(result.Error)
+	}
+	withCollection(t, m, ns, func(coll *Collection) {
+		snap := coll.Snapshot()
+		defer snap.Release()
+		if ids := snapshotIDs(snap); !slices.Equal(ids, []string{"b"}) {
+			t.Fatalf("after commit: %v", ids)
+		}
+	})
+}
+
+// snapshotIDs возвращает _id документов снимка по алфавиту
+func snapshotIDs(snap *Snapshot) []string {
+	var ids []string
+	for _, doc := range snap.All() {
+		ids = append(ids, doc["_id"].(string))
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"testing"
+)
+
+// openTestManager открывает менеджер коллекций в каталоге dir; второй менеджер
+// в том же каталоге видит то, что сохранил первый, как сервер после перезапуска
+func openTestManager(t *testing.T, dir string) *CollectionMng {
+	t.Helper()
+	m := NewManager()
+	if err := m.OpenDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Stop)
+	return m
+}
+
+// testNS — коллекция в базе по умолчанию
+func testNS(coll string) Namespace {
+	return Namespace{DB: DefaultDatabase, Coll: coll}
+}
+
+// mustWrite выполняет операцию в очереди коллекции и проверяет, что она прошла без ошибки
+func mustWrite(t *testing.T, m *CollectionMng, ns Namespace, operation func(coll *Collection) (WriteResult, error)) WriteResult {
+	t.Helper()
+	result := m.Enqueue(ns, operation)
+	if result.Error != nil {
+		t.Fatalf("%s: %v", ns, result.Error)
+	}
+	return result
+}
+
+// withCollection вызывает fn с закреплённой коллекцией
+func withCollection(t *testing.T, m *CollectionMng, ns Namespace, fn func(coll *Collection)) {
+	t.Helper()
+	coll, err := m.GetCollection(ns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer coll.Release()
+	fn(coll)
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"nosql_db/internal/fulltext"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// textIndexExt — расширение файлов полнотекстовых индексов (лежат рядом с .idx)
+const textIndexExt = ".text"
+
+// TextIndexName возвращает имя текстового индекса по списку полей
+func TextIndexName(fields []string) string {
+	return strings.Join(fields, "_") + "_text"
+}
+
+// CreateTextIndex создаёт полнотекстовый индекс по полям; у коллекции может быть только один такой индекс
+func (c *Collection) CreateTextIndex(fields []string) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for name := range c.TextIndexes {
+		return "", fmt.Errorf("collection already has text index '%s'", name)
+	}
+
+	name := TextIndexName(fields)
+	textIndex := fulltext.NewIndex(fields)
+	c.Data.Scan(func(id string, doc map[string]any) bool {
+		textIndex.Add(id, doc)
+		return true
+	})
+	c.TextIndexes[name] = textIndex
+	c.noteIndexInternal(IndexSpec{Type: IndexText, Fields: textIndex.Fields()})
+
+	return name, c.saveTextIndexInternal(name)
+}
+
+// SearchText выполняет запрос $text по текстовому индексу коллекции
+// и возвращает релевантность найденных документов
+func (c *Collection) SearchText(search string) (map[string]float64, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	for _, textIndex := range c.TextIndexes {
+		return textIndex.Search(search), nil
+	}
+	return nil, fmt.Errorf("text index required for $text query")
+}
+
+func textIndexPath(dir, name string) string {
+	return filepath.Join(dir, name+textIndexExt)
+}
+
+// loadTextIndexInternal загружает текстовый индекс без блокировок
+func (c *Collection) loadTextIndexInternal(name string) error {
+	jsonData, err := os.ReadFile(textIndexPath(c.indexDir(), name))
+	if err != nil {
+		return fmt.Errorf("failed to read text index file: %w", err)
+	}
+	var indexData TextIndexFile
+	if err := json.Unmarshal(jsonData, &indexData); err != nil {
+		return fmt.Errorf("failed to unmarshal text index: %w", err)
+	}
+	c.TextIndexes[name] = fulltext.Restore(indexData.Fields, indexData.Postings, indexData.Lengths)
+	return nil
+}
+
+// saveTextIndexInternal сохраняет текстовый индекс без блокировок
+func (c *Collection) saveTextIndexInternal(name string) error {
+	textIndex, exists := c.TextIndexes[name]
+	if !exists {
+		return Errorf(ErrNotFound, "text index '%s' does not exist", name)
+	}
+	indexPath := textIndexPath(c.indexDir(), name)
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	jsonData, err := json.Marshal(TextIndexFile{
+		Name:     name,
+		Fields:   textIndex.Fields(),
+		Postings: textIndex.Postings(),
+		Lengths:  textIndex.Lengths(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal text index: %w", err)
+	}
+	if err := os.WriteFile(indexPath, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write text index file: %w", err)
+	}
+	return nil
+}
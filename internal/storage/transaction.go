@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+)
+
+// undoEntry — состояние документа до изменения: prev == nil, если документа не было
+type undoEntry struct {
+	id          string
+	prev        map[string]any
+	prevVersion uint64
+}
+
+// Tx — атомарная группа изменений в одной или нескольких коллекциях.
+// Выполняется в worker'е: при ошибке все изменения данных и индексов откатываются,
+// при успехе затронутые коллекции сохраняются на диск вместе с остальной группой задач
+type Tx struct {
+	mng     *CollectionMng
+	allowed []Namespace   // коллекции, объявленные в EnqueueTx (nil — одна коллекция задачи)
+	colls   []*Collection // коллекции в порядке первого обращения
+}
+
+// Collection возвращает коллекцию и начинает запись её журнала отката
+func (tx *Tx) Collection(ns Namespace) (*Collection, error) {
+	for _, coll := range tx.colls {
+		if coll.Namespace() == ns {
+			return coll, nil
+		}
+	}
+	if tx.allowed != nil && !slices.Contains(tx.allowed, ns) {
+		return nil, fmt.Errorf("collection '%s' is not part of the transaction", ns)
+	}
+	coll, err := tx.mng.GetCollection(ns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	coll.beginUndo()
+	tx.colls = append(tx.colls, coll)
+	return coll, nil
+}
+
+// rollback возвращает все затронутые коллекции в состояние до транзакции
+func (tx *Tx) rollback() {
+	for i := len(tx.colls) - 1; i >= 0; i-- {
+		tx.colls[i].rollbackUndo()
+		tx.colls[i].Release()
+	}
+}
+
+// commit делает изменения видимыми новым снимкам, публикует их в журнал изменений
+// и возвращает изменённые коллекции.
+// Сохранение на диск выполняет менеджер — одно на группу задач (group commit)
+func (tx *Tx) commit() []*Collection {
+	var dirty []*Collection
+	var events []ChangeEvent
+	for _, coll := range tx.colls {
+		if coll.hasUndo() {
+			dirty = append(dirty, coll)
+		}
+		events = append(events, coll.endUndo()...)
+		// от выгрузки до сохранения коллекцию защищает незавершённая задача в её очереди
+		coll.Release()
+	}
+	tx.mng.changes.publish(events)
+	return dirty
+}
+
+func (c *Collection) beginUndo() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.undo = []undoEntry{}
+}
+
+func (c *Collection) hasUndo() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.undo) > 0
+}
+
+// endUndo фиксирует изменения транзакции: они становятся видны новым снимкам.
+// Возвращает события изменений для журнала
+func (c *Collection) endUndo() []ChangeEvent {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	var events []ChangeEvent
+	if len(c.undo) > 0 {
+		events = c.changeEventsInternal()
+		c.committed++
+		c.notifyCappedInternal()
+	}
+	events = append(events, c.commands...)
+	c.undo, c.commands = nil, nil
+	c.gcVersionsInternal()
+	return events
+}
+
+// rollbackUndo применяет журнал отката в обратном порядке вместе с индексами
+// и удаляет версии, созданные транзакцией
+func (c *Collection) rollbackUndo() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	pending := c.committed + 1
+	for i := len(c.undo) - 1; i >= 0; i-- {
+		entry := c.undo[i]
+		if doc, ok := c.Data.Get(entry.id); ok {
+			c.updateIndexesOnDelete(entry.id, doc)
+			c.Data.Delete(entry.id)
+		}
+		if entry.prev != nil {
+			c.Data.Put(entry.id, entry.prev)
+			c.updateIndexesOnInsert(entry.id, entry.prev)
+		}
+		c.versions[entry.id] = entry.prevVersion
+		if versions := c.history[entry.id]; len(versions) > 0 && versions[len(versions)-1].to == pending {
+			c.history[entry.id] = versions[:len(versions)-1]
+		}
+	}
+	if len(c.undo) > 0 {
+		c.mods++
+	}
+	c.undo, c.commands = nil, nil
+	c.gcVersionsInternal()
+}
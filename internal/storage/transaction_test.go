@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"nosql_db/internal/index"
+)
+
+func TestTxRollback(t *testing.T) {
+	m := openTestManager(t, t.TempDir())
+	ns, other := testNS("test"), testNS("other")
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		if _, err := coll.InsertWithID("a", map[string]any{"n": 1.0}); err != nil {
+			return WriteResult{}, err
+		}
+		return WriteResult{}, coll.CreateIndex("n", 3)
+	})
+
+	// изменения до ошибки откатываются вместе с индексом
+	result := m.EnqueueTx([]Namespace{ns}, DurabilityFlushed, func(tx *Tx) (WriteResult, error) {
+		coll, err := tx.Collection(ns)
+		if err != nil {
+			return WriteResult{}, err
+		}
+		if _, err := coll.InsertWithID("b", map[string]any{"n": 2.0}); err != nil {
+			return WriteResult{}, err
+		}
+		if _, err := coll.Replace("a", map[string]any{"n": 5.0}); err != nil {
+			return WriteResult{}, err
+		}
+		_, err = tx.Collection(other)
+		return WriteResult{}, err
+	})
+	if result.Error == nil || !strings.Contains(result.Error.Error(), "not part of the transaction") {
+		t.Fatalf("undeclared collection: %v", result.Error)
+	}
+
+	withCollection(t, m, ns, func(coll *Collection) {
+		if doc, ok := coll.GetByID("a"); !ok || doc["n"] != 1.0 || coll.Count() != 1 {
+			t.Fatalf("after rollback: %v, %d document(s)", doc, coll.Count())
+		}
+		coll.ReadIndex("n", func(btree *index.BTree) {
+			for n, want := range map[float64]int{1: 1, 2: 0, 5: 0} {
+				if ids := btree.Search(index.ValueToKey(n)); len(ids) != want {
+					t.Errorf("index entries for n=%v after rollback: %v", n, ids)
+				}
+			}
+		})
+	})
+
+	// транзакция над двумя коллекциями фиксируется целиком
+	committed := m.EnqueueTx([]Namespace{ns, other}, DurabilityFlushed, func(tx *Tx) (WriteResult, error) {
+		for _, name := range []Namespace{ns, other} {
+			coll, err := tx.Collection(name)
+			if err != nil {
+				return WriteResult{}, err
+			}
+			if _, err := coll.InsertWithID("t", map[string]any{"n": 7.0}); err != nil {
+				return WriteResult{}, err
+			}
+		}
+		return WriteResult{}, nil
+	})
+	if committed.Error != nil {
+		t.Fatal(committed.Error)
+	}
+	for _, name := range []Namespace{ns, other} {
+		withCollection(t, m, name, func(coll *Collection) {
+			if _, ok := coll.GetByID("t"); !ok {
+				t.Fatalf("%s: committed document is missing", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,287 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"nosql_db/internal/index"
+)
+
+// TTL-индексы: b-tree по полю с датой и срок жизни документа после неё.
+// Дата — строка RFC 3339 или число секунд Unix; у массива берётся самая ранняя.
+// Снимки чтения не показывают истёкшие документы, а reaper удаляет их через очередь записи
+
+// ttlReapInterval — как часто reaper проверяет коллекции с TTL-индексами
+const ttlReapInterval = 10 * time.Second
+
+// CreateTTLIndex создаёт b-tree индекс на поле (если его нет) и задаёт срок жизни
+// документов: seconds после даты в поле. Повторный вызов меняет срок
+func (c *Collection) CreateTTLIndex(fieldName string, seconds int64) error {
+	if seconds < 0 {
+		return fmt.Errorf("expireAfterSeconds must not be negative")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.Indexes[fieldName]; !exists {
+		c.Indexes[fieldName] = c.buildIndexInternal(fieldName, 64)
+		if err := c.saveIndexInternal(fieldName); err != nil {
+			return err
+		}
+	}
+
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	if c.meta.TTL == nil {
+		c.meta.TTL = make(map[string]int64)
+	}
+	c.meta.TTL[fieldName] = seconds
+	c.noteIndexInternal(IndexSpec{Type: IndexBTree, Fields: []string{fieldName}, ExpireAfterSeconds: &seconds})
+	// документы с новым сроком могут быть уже истёкшими
+	c.nextExpiry = 0
+	return c.saveMetaLocked()
+}
+
+// ttlFields возвращает копию настроек TTL: поле -> срок жизни в секундах
+func (c *Collection) ttlFields() map[string]int64 {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	if len(c.meta.TTL) == 0 {
+		return nil
+	}
+	ttl := make(map[string]int64, len(c.meta.TTL))
+	for field, seconds := range c.meta.TTL {
+		ttl[field] = seconds
+	}
+	return ttl
+}
+
+// Expired возвращает проверку, истёк ли документ к now. Записи пропускают истёкшие,
+// но ещё не удалённые reaper'ом документы так же, как снимки чтения
+func (c *Collection) Expired(now time.Time) func(doc map[string]any) bool {
+	ttl := c.ttlFields()
+	at := now.UnixNano()
+	return func(doc map[string]any) bool {
+		if ttl == nil {
+			return false
+		}
+		expiry, ok := expiresAt(doc, ttl)
+		return ok && expiry <= at
+	}
+}
+
+// expiresAt возвращает момент истечения документа (UnixNano); false — документ не истекает
+func expiresAt(doc map[string]any, ttl map[string]int64) (int64, bool) {
+	expiry, found := int64(math.MaxInt64), false
+	for field, seconds := range ttl {
+		at, ok := ttlDate(doc[field])
+		if !ok {
+			continue
+		}
+		if at = at.Add(time.Duration(seconds) * time.Second); at.UnixNano() < expiry {
+			expiry, found = at.UnixNano(), true
+		}
+	}
+	return expiry, found
+}
+
+// ttlDate разбирает дату поля TTL-индекса
+func ttlDate(value any) (time.Time, bool) {
+	switch v := value.(type) {
+	case float64:
+		sec, frac := math.Modf(v)
+		return time.Unix(int64(sec), int64(frac*1e9)), true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	case []any:
+		var earliest time.Time
+		found := false
+		for _, item := range v {
+			if t, ok := ttlDate(item); ok && (!found || t.Before(earliest)) {
+				earliest, found = t, true
+			}
+		}
+		return earliest, found
+	}
+	return time.Time{}, false
+}
+
+// noteExpiryInternal сдвигает nextExpiry, если документ истекает раньше; вызывается под c.mutex
+func (c *Collection) noteExpiryInternal(doc map[string]any) {
+	ttl := c.ttlFields()
+	if ttl == nil {
+		return
+	}
+	if at, ok := expiresAt(doc, ttl); ok && at < c.nextExpiry {
+		c.nextExpiry = at
+	}
+}
+
+// initExpiry вычисляет nextExpiry загруженной коллекции по ключам TTL-индексов: без этого
+// до первого прохода reaper'а каждый снимок считал бы, что в коллекции есть истёкшие документы
+func (c *Collection) initExpiry(now time.Time) {
+	ttl := c.ttlFields()
+	if ttl == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	expired, next := c.expiredIDsInternal(ttl, now.UnixNano())
+	if len(expired) > 0 {
+		// истёкшие документы ждут reaper'а, снимки их скрывают
+		next = 0
+	}
+	c.nextExpiry = next
+}
+
+// expiredIDsInternal находит истёкшие к now документы по ключам TTL-индексов
+// и возвращает их вместе с ближайшим сроком среди остальных
+func (c *Collection) expiredIDsInternal(ttl map[string]int64, now int64) ([]string, int64) {
+	next := int64(math.MaxInt64)
+	seen := make(map[string]struct{})
+	var expired []string
+	for field := range ttl {
+		btree, ok := c.Indexes[field]
+		if !ok {
+			continue
+		}
+		btree.Ascend(func(key index.Key, ids []index.Value) bool {
+			for _, v := range ids {
+				id := string(v)
+				if _, done := seen[id]; done {
+					continue
+				}
+				seen[id] = struct{}{}
+				doc, ok := c.Data.Get(id)
+				if !ok {
+					continue
+				}
+				at, ok := expiresAt(doc, ttl)
+				switch {
+				case !ok:
+				case at <= now:
+					expired = append(expired, id)
+				case at < next:
+					next = at
+				}
+			}
+			return true
+		})
+	}
+	return expired, next
+}
+
+// ReapExpired удаляет истёкшие документы; выполняется в worker'е коллекции
+func (c *Collection) ReapExpired(now time.Time) int {
+	ttl := c.ttlFields()
+	if ttl == nil {
+		return 0
+	}
+	c.mutex.RLock()
+	expired, next := c.expiredIDsInternal(ttl, now.UnixNano())
+	c.mutex.RUnlock()
+
+	removed := 0
+	for _, id := range expired {
+		if c.Delete(id) {
+			removed++
+		}
+	}
+	// reaper выполняется в worker'е: других записей в коллекцию между обходом и удалением нет
+	c.mutex.Lock()
+	c.nextExpiry = next
+	c.mutex.Unlock()
+	return removed
+}
+
+// ExpiredIDs возвращает истёкшие к now документы коллекции, не удаляя их
+func (c *Collection) ExpiredIDs(now time.Time) []string {
+	ttl := c.ttlFields()
+	if ttl == nil {
+		return nil
+	}
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	expired, _ := c.expiredIDsInternal(ttl, now.UnixNano())
+	return expired
+}
+
+// expiryDue — есть ли в коллекции документы, которые могли истечь к now
+func (c *Collection) expiryDue(now time.Time) bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return c.nextExpiry <= now.UnixNano() && c.ttlFields() != nil
+}
+
+// ttlReaper периодически удаляет истёкшие документы открытых коллекций.
+// Удаление идёт через очередь записи, как обычный delete
+func (m *CollectionMng) ttlReaper() {
+	ticker := time.NewTicker(ttlReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.reapExpired()
+		case <-m.stopChan:
+			return
+		}
+	}
+}
+
+// DisableTTLReaper выключает reaper узла. Реплика не удаляет истёкшие документы сама:
+// удаления приходят из журнала primary, и данные узлов не расходятся
+func (m *CollectionMng) DisableTTLReaper() {
+	m.reaperOff.Store(true)
+}
+
+func (m *CollectionMng) reapExpired() {
+	if m.reaperOff.Load() {
+		return
+	}
+	now := time.Now()
+	for _, ns := range m.expiryDue(now) {
+		result := m.EnqueueDurable(ns, DurabilityNone, func(coll *Collection) (WriteResult, error) {
+			removed := coll.ReapExpired(now)
+			return WriteResult{DeletedCount: removed}, nil
+		})
+		if result.Error != nil {
+			log.Printf("ttl: %s: %v", ns, result.Error)
+		} else if result.DeletedCount > 0 {
+			log.Printf("ttl: removed %d expired document(s) from %s", result.DeletedCount, ns)
+		}
+	}
+}
+
+// expiryDue возвращает открытые коллекции, в которых могли истечь документы
+func (m *CollectionMng) expiryDue(now time.Time) []Namespace {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var due []Namespace
+	for ns, coll := range m.collections {
+		if coll.expiryDue(now) {
+			due = append(due, ns)
+		}
+	}
+	return due
+}
+
+// ExpiredIDs возвращает истёкшие к now документы открытых коллекций. Узел кластера
+// удаляет их записью журнала на лидере вместо reaper'а каждого узла
+func (m *CollectionMng) ExpiredIDs(now time.Time) map[Namespace][]string {
+	expired := make(map[Namespace][]string)
+	for _, ns := range m.expiryDue(now) {
+		coll, err := m.GetCollection(ns)
+		if err != nil {
+			log.Printf("ttl: %s: %v", ns, err)
+			continue
+		}
+		if ids := coll.ExpiredIDs(now); len(ids) > 0 {
+			expired[ns] = ids
+		}
+		coll.Release()
+	}
+	return expired
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTTLIndex(t *testing.T) {
+	dir := t.TempDir()
+	m := openTestManager(t, dir)
+	ns := testNS("sessions")
+	now := time.Now()
+	past := now.Add(-2 * time.Hour).Format(time.RFC3339)
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		for id, doc := range map[string]map[string]any{
+			"old":    {"at": past},
+			"fresh":  {"at": float64(now.Unix())},
+			"array":  {"at": []any{past, float64(now.Add(time.Hour).Unix())}},
+			"nodate": {"at": "soon"},
+			"none":   {},
+		} {
+			if _, err := coll.InsertWithID(id, doc); err != nil {
+				return WriteResult{}, err
+			}
+		}
+		// TTL-индекс на уже заполненной коллекции
+		return WriteResult{}, coll.CreateTTLIndex("at", 60)
+	})
+
+	// истёкшие документы не видны до того, как reaper их удалил
+	withCollection(t, m, ns, func(coll *Collection) {
+		snap := coll.Snapshot()
+		defer snap.Release()
+		if got := snapshotIDs(snap); !slices.Equal(got, []string{"fresh", "nodate", "none"}) || snap.Count() != 3 {
+			t.Fatalf("visible: %v, count %d", got, snap.Count())
+		}
+		if _, ok := snap.Get("old"); ok {
+			t.Fatal("expired document is visible")
+		}
+		if coll.Count() != 5 {
+			t.Fatalf("%d stored document(s) before the reaper", coll.Count())
+		}
+	})
+
+	// reaper удаляет их через очередь записи вместе с ключами индекса
+	m.reapExpired()
+	withCollection(t, m, ns, func(coll *Collection) {
+		tree, _ := coll.GetIndex("at")
+		if coll.Count() != 3 || tree.Len() != 2 {
+			t.Fatalf("after the reaper: %d document(s), %d index key(s)", coll.Count(), tree.Len())
+		}
+	})
+
+	// выключенный reaper (реплика) ничего не удаляет
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		_, err := coll.InsertWithID("stale", map[string]any{"at": past})
+		return WriteResult{}, err
+	})
+	m.DisableTTLReaper()
+	m.reapExpired()
+	withCollection(t, m, ns, func(coll *Collection) {
+		if coll.Count() != 4 {
+			t.Fatalf("disabled reaper: %d document(s)", coll.Count())
+		}
+	})
+
+	// срок и поле TTL сохраняются в метаданных коллекции
+	reopened := openTestManager(t, dir)
+	withCollection(t, reopened, ns, func(coll *Collection) {
+		spec := coll.Spec()
+		if len(spec.Indexes) != 1 || spec.Indexes[0].ExpireAfterSeconds == nil || *spec.Indexes[0].ExpireAfterSeconds != 60 {
+			t.Fatalf("indexes after reopen: %+v", spec.Indexes)
+		}
+		// fresh истекает через 60 секунд, stale остался от выключенного reaper'а
+		if removed := coll.ReapExpired(now.Add(2 * time.Minute)); removed != 2 {
+			t.Fatalf("fresh document must expire after 60 seconds, removed %d", removed)
+		}
+	})
+	mustWrite(t, reopened, ns, func(coll *Collection) (WriteResult, error) {
+		_, err := coll.InsertWithID("late", map[string]any{"at": past})
+		return WriteResult{}, err
+	})
+	withCollection(t, reopened, ns, func(coll *Collection) {
+		snap := coll.Snapshot()
+		defer snap.Release()
+		if got := snapshotIDs(snap); !slices.Equal(got, []string{"nodate", "none"}) {
+			t.Fatalf("visible after reopen: %v", got)
+		}
+	})
+
+	withCollection(t, reopened, ns, func(coll *Collection) {
+		if err := coll.CreateTTLIndex("at", -1); err == nil {
+			t.Fatal("negative expireAfterSeconds must be rejected")
+		}
+	})
+}
+
+func TestTTLExpiryAfterReopen(t *testing.T) {
+	dir := t.TempDir()
+	m := openTestManager(t, dir)
+	ns := testNS("sessions")
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		if _, err := coll.InsertWithID("live", map[string]any{"at": time.Now().Add(time.Hour).Format(time.RFC3339)}); err != nil {
+			return WriteResult{}, err
+		}
+		return WriteResult{}, coll.CreateTTLIndex("at", 60)
+	})
+
+	// срок ближайшего документа вычисляется при загрузке: снимок не считается устаревшим
+	// до первого прохода reaper'а, и запросы могут отвечать по индексам
+	withCollection(t, openTestManager(t, dir), ns, func(coll *Collection) {
+		snap := coll.Snapshot()
+		defer snap.Release()
+		if snap.Stale() {
+			t.Fatal("snapshot of a reopened TTL collection without expired documents is stale")
+		}
+	})
+
+	// истёкший документ после перезапуска по-прежнему скрыт
+	mustWrite(t, m, ns, func(coll *Collection) (WriteResult, error) {
+		_, err := coll.InsertWithID("old", map[string]any{"at": time.Now().Add(-time.Hour).Format(time.RFC3339)})
+		return WriteResult{}, err
+	})
+	withCollection(t, openTestManager(t, dir), ns, func(coll *Collection) {
+		snap := coll.Snapshot()
+		defer snap.Release()
+		if got := snapshotIDs(snap); !slices.Equal(got, []string{"live"}) || !snap.Stale() {
+			t.Fatalf("visible after reopen: %v, stale %v", got, snap.Stale())
+		}
+	})
+}
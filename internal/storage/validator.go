@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"fmt"
+	"log"
+
+	"nosql_db/internal/schema"
+)
+
+// Валидатор коллекции — JSON Schema, которой должны соответствовать вставляемые
+// и обновлённые документы. Проверку выполняют обработчики insert/update
+
+// уровни проверки валидатора
+const (
+	ValidationStrict = "strict" // документ, не прошедший проверку, отклоняется
+	ValidationWarn   = "warn"   // нарушения пишутся в лог и возвращаются предупреждением
+)
+
+// ValidatorOptions — схема и уровень проверки; хранятся в collection.json
+type ValidatorOptions struct {
+	Schema map[string]any `json:"schema"`
+	Level  string         `json:"level"`
+}
+
+// Validator — разобранная схема коллекции
+type Validator struct {
+	Schema *schema.Schema
+	Level  string
+}
+
+// CollModOptions — изменения коллекции в coll_mod
+type CollModOptions struct {
+	Schema    map[string]any // новая схема, если SetSchema; пустая схема снимает валидатор
+	SetSchema bool
+	Level     string // новый уровень проверки; "" — прежний
+}
+
+// ValidLevel проверяет уровень проверки; пустой — strict
+func ValidLevel(level string) (string, error) {
+	switch level {
+	case "":
+		return ValidationStrict, nil
+	case ValidationStrict, ValidationWarn:
+		return level, nil
+	}
+	return "", fmt.Errorf("unknown validation level '%s' (expected strict or warn)", level)
+}
+
+// compileValidator разбирает сохранённые настройки валидатора
+func compileValidator(opts *ValidatorOptions) (*Validator, error) {
+	if opts == nil {
+		return nil, nil
+	}
+	level, err := ValidLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	compiled, err := schema.Compile(opts.Schema)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{Schema: compiled, Level: level}, nil
+}
+
+// loadValidator разбирает валидатор из метаданных при открытии коллекции
+func (c *Collection) loadValidator() {
+	validator, err := compileValidator(c.meta.Validator)
+	if err != nil {
+		// схема прошла проверку при сохранении: сюда попадает только испорченный collection.json
+		log.Printf("collection %s: ignoring invalid validator: %v", c.Namespace(), err)
+		return
+	}
+	c.validator = validator
+}
+
+// Validator возвращает валидатор коллекции; nil — документы не проверяются
+func (c *Collection) Validator() *Validator {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	return c.validator
+}
+
+// ValidatorOptions возвращает копию настроек валидатора; nil — валидатора нет
+func (c *Collection) ValidatorOptions() *ValidatorOptions {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+	if c.meta.Validator == nil {
+		return nil
+	}
+	opts := *c.meta.Validator
+	return &opts
+}
+
+// modifyValidator применяет coll_mod к настройкам валидатора и сохраняет их
+func (c *Collection) modifyValidator(mod CollModOptions) error {
+	c.metaMu.Lock()
+	defer c.metaMu.Unlock()
+
+	var next *ValidatorOptions
+	if c.meta.Validator != nil {
+		opts := *c.meta.Validator
+		next = &opts
+	}
+	if mod.SetSchema {
+		if len(mod.Schema) == 0 {
+			next = nil
+		} else {
+			level := ValidationStrict
+			if next != nil {
+				level = next.Level
+			}
+			next = &ValidatorOptions{Schema: mod.Schema, Level: level}
+		}
+	}
+	if mod.Level != "" {
+		if next == nil {
+			return fmt.Errorf("collection '%s' has no validator", c.Namespace())
+		}
+		next.Level = mod.Level
+	}
+
+	validator, err := compileValidator(next)
+	if err != nil {
+		return err
+	}
+	prev := c.meta.Validator
+	c.meta.Validator = next
+	if err := c.saveMetaLocked(); err != nil {
+		c.meta.Validator = prev
+		return err
+	}
+	c.validator = validator
+	return nil
+}
+
+// CollMod меняет настройки существующей коллекции за барьером в её очереди:
+// записи до coll_mod проверяются прежним валидатором, после — новым
+func (m *CollectionMng) CollMod(ns Namespace, mod CollModOptions) (*ValidatorOptions, error) {
+	if mod.Level != "" {
+		if _, err := ValidLevel(mod.Level); err != nil {
+			return nil, err
+		}
+	}
+	var opts *ValidatorOptions
+	result := m.EnqueueTx([]Namespace{ns}, DurabilityFlushed, func(tx *Tx) (WriteResult, error) {
+		coll, err := tx.Collection(ns)
+		if err != nil {
+			return WriteResult{}, err
+		}
+		if !coll.exists() {
+			return WriteResult{}, Errorf(ErrNotFound, "collection '%s' does not exist", ns)
+		}
+		if err := coll.modifyValidator(mod); err != nil {
+			return WriteResult{}, err
+		}
+		opts = coll.ValidatorOptions()
+		m.publishCommand(ChangeEvent{Op: ChangeCollMod, NS: ns, Validator: opts})
+		return WriteResult{}, nil
+	})
+	return opts, result.Error
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCollMod(t *testing.T) {
+	dir := t.TempDir()
+	m := openTestManager(t, dir)
+	ns := testNS("users")
+	schema := map[string]any{"required": []any{"name"}}
+
+	if _, err := m.CollMod(ns, CollModOptions{Schema: schema, SetSchema: true}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("coll_mod of a missing collection: %v", err)
+	}
+	insertInto(t, m, ns, map[string]any{"age": 1.0})
+	if _, err := m.CollMod(ns, CollModOptions{Level: ValidationWarn}); err == nil {
+		t.Fatal("level without a validator must be rejected")
+	}
+	if _, err := m.CollMod(ns, CollModOptions{Schema: map[string]any{"type": "date"}, SetSchema: true}); err == nil {
+		t.Fatal("invalid schema must be rejected")
+	}
+	if _, err := m.CollMod(ns, CollModOptions{Schema: schema, SetSchema: true, Level: "loose"}); err == nil {
+		t.Fatal("unknown level must be rejected")
+	}
+
+	// уровень по умолчанию — strict, смена уровня сохраняет схему
+	if opts, err := m.CollMod(ns, CollModOptions{Schema: schema, SetSchema: true}); err != nil || opts.Level != ValidationStrict {
+		t.Fatalf("set validator: %+v %v", opts, err)
+	}
+	if opts, err := m.CollMod(ns, CollModOptions{Level: ValidationWarn}); err != nil || opts.Level != ValidationWarn || opts.Schema == nil {
+		t.Fatalf("set level: %+v %v", opts, err)
+	}
+
+	withCollection(t, openTestManager(t, dir), ns, func(coll *Collection) {
+		validator := coll.Validator()
+		if validator == nil || validator.Level != ValidationWarn || len(validator.Schema.Validate(map[string]any{})) != 1 {
+			t.Fatalf("validator after reopen: %+v", validator)
+		}
+	})
+
+	if opts, err := m.CollMod(ns, CollModOptions{Schema: map[string]any{}, SetSchema: true}); err != nil || opts != nil {
+		t.Fatalf("remove validator: %+v %v", opts, err)
+	}
+	withCollection(t, m, ns, func(coll *Collection) {
+		if coll.Validator() != nil || coll.ValidatorOptions() != nil {
+			t.Fatal("validator is not removed")
+		}
+	})
+}
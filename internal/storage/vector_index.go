@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"nosql_db/internal/operators"
+	"nosql_db/internal/vector"
+	"os"
+	"path/filepath"
+)
+
+// vectorIndexExt — расширение файлов векторных индексов
+const vectorIndexExt = ".vec"
+
+// VectorQuery — параметры запроса $vectorSearch
+type VectorQuery struct {
+	Field  string
+	Vector []float32
+	K      int
+	Exact  bool           // точный перебор вместо графа HNSW
+	Ef     int            // ширина поиска по графу (numCandidates)
+	Metric vector.Metric  // метрика для точного поиска без индекса
+	Filter map[string]any // условия, которым должны соответствовать документы
+}
+
+// CreateVectorIndex создаёт векторный индекс по полю; dims = 0 — по первому вектору
+func (c *Collection) CreateVectorIndex(fieldName string, metric vector.Metric, dims int) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, exists := c.VecIndexes[fieldName]; exists {
+		return Errorf(ErrExists, "vector index on field '%s' already exists", fieldName)
+	}
+	vecIndex := vector.NewIndex(metric, dims)
+	c.Data.Scan(func(id string, doc map[string]any) bool {
+		if vec, ok := vector.Parse(doc[fieldName]); ok {
+			vecIndex.Add(id, vec)
+		}
+		return true
+	})
+	c.VecIndexes[fieldName] = vecIndex
+	c.noteIndexInternal(IndexSpec{Type: IndexVector, Fields: []string{fieldName}, Metric: string(metric), Dims: vecIndex.Dims()})
+
+	return c.saveVectorIndexInternal(fieldName)
+}
+
+// SearchVector ищет k документов с ближайшими векторами, удовлетворяющих фильтру.
+// Без векторного индекса доступен только точный поиск перебором документов
+func (c *Collection) SearchVector(q VectorQuery) ([]vector.Result, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	accept := func(id string) bool {
+		if len(q.Filter) == 0 {
+			return true
+		}
+		doc, ok := c.docInternal(id)
+		return ok && operators.MatchDocument(doc, q.Filter)
+	}
+
+	vecIndex, exists := c.VecIndexes[q.Field]
+	if !exists {
+		if !q.Exact {
+			return nil, fmt.Errorf("vector index on field '%s' required for approximate $vectorSearch", q.Field)
+		}
+		vectors := make(map[string][]float32)
+		c.Data.Scan(func(id string, doc map[string]any) bool {
+			if !accept(id) {
+				return true
+			}
+			if vec, ok := vector.Parse(doc[q.Field]); ok {
+				vectors[id] = vec
+			}
+			return true
+		})
+		return vector.Rank(q.Metric, q.Vector, q.K, vectors), nil
+	}
+
+	if vecIndex.Dims() != 0 && len(q.Vector) != vecIndex.Dims() {
+		return nil, fmt.Errorf("query vector has %d dimensions, index expects %d", len(q.Vector), vecIndex.Dims())
+	}
+	if q.Exact {
+		return vecIndex.SearchExact(q.Vector, q.K, accept), nil
+	}
+	return vecIndex.Search(q.Vector, q.K, q.Ef, accept), nil
+}
+
+// VectorIndexMetric возвращает метрику векторного индекса поля
+func (c *Collection) VectorIndexMetric(fieldName string) (vector.Metric, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	vecIndex, exists := c.VecIndexes[fieldName]
+	if !exists {
+		return "", false
+	}
+	return vecIndex.Metric(), true
+}
+
+// docInternal возвращает документ по id без блокировок
+func (c *Collection) docInternal(id string) (map[string]any, bool) {
+	return c.Data.Get(id)
+}
+
+func vectorIndexPath(dir, fieldName string) string {
+	return filepath.Join(dir, fieldName+vectorIndexExt)
+}
+
+// loadVectorIndexInternal загружает векторный индекс без блокировок
+func (c *Collection) loadVectorIndexInternal(fieldName string) error {
+	jsonData, err := os.ReadFile(vectorIndexPath(c.indexDir(), fieldName))
+	if err != nil {
+		return fmt.Errorf("failed to read vector index file: %w", err)
+	}
+	var indexData VectorIndexFile
+	if err := json.Unmarshal(jsonData, &indexData); err != nil {
+		return fmt.Errorf("failed to unmarshal vector index: %w", err)
+	}
+	c.VecIndexes[fieldName] = vector.Restore(vector.Metric(indexData.Metric), indexData.Dims,
+		indexData.Vectors, indexData.Nodes, indexData.Deleted, indexData.Entry, indexData.MaxLevel)
+	return nil
+}
+
+// saveVectorIndexInternal сохраняет векторный индекс без блокировок
+func (c *Collection) saveVectorIndexInternal(fieldName string) error {
+	vecIndex, exists := c.VecIndexes[fieldName]
+	if !exists {
+		return Errorf(ErrNotFound, "vector index on field '%s' does not exist", fieldName)
+	}
+	indexPath := vectorIndexPath(c.indexDir(), fieldName)
+	if err := os.MkdirAll(filepath.Dir(indexPath), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	entry, maxLevel := vecIndex.Entry()
+	jsonData, err := json.Marshal(VectorIndexFile{
+		Field:    fieldName,
+		Metric:   string(vecIndex.Metric()),
+		Dims:     vecIndex.Dims(),
+		Vectors:  vecIndex.Vectors(),
+		Nodes:    vecIndex.Nodes(),
+		Deleted:  vecIndex.Deleted(),
+		Entry:    entry,
+		MaxLevel: maxLevel,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal vector index: %w", err)
+	}
+	if err := os.WriteFile(indexPath, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write vector index file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WarmUp загружает коллекции заранее, по parallel одновременно, и пишет ход загрузки в лог.
+// spec — список через запятую: "база.коллекция", "база.*" (все коллекции базы)
+// или имя коллекции базы по умолчанию. Запросы к ещё не загруженным коллекциям
+// ждут их загрузки, а не начинают свою
+func (m *CollectionMng) WarmUp(spec string, parallel int) {
+	names := m.warmupCollections(spec)
+	if len(names) == 0 {
+		return
+	}
+	parallel = max(parallel, 1)
+	log.Printf("warm-up: loading %d collection(s), %d in parallel", len(names), parallel)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	var done, failed atomic.Int32
+	sem := make(chan struct{}, parallel)
+	for _, ns := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			began := time.Now()
+			coll, err := m.GetCollection(ns)
+			n := done.Add(1)
+			if err != nil {
+				failed.Add(1)
+				log.Printf("warm-up: [%d/%d] %s failed: %v", n, len(names), ns, err)
+				return
+			}
+			docs := coll.Data.Len()
+			coll.Release()
+			log.Printf("warm-up: [%d/%d] %s loaded: %d document(s) in %v",
+				n, len(names), ns, docs, time.Since(began).Round(time.Millisecond))
+		}()
+	}
+	wg.Wait()
+	log.Printf("warm-up: finished in %v, %d loaded, %d failed",
+		time.Since(start).Round(time.Millisecond), len(names)-int(failed.Load()), failed.Load())
+}
+
+// warmupCollections разворачивает список прогрева в существующие коллекции
+func (m *CollectionMng) warmupCollections(spec string) []Namespace {
+	var names []Namespace
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		ns := Namespace{DB: DefaultDatabase, Coll: entry}
+		if db, coll, found := strings.Cut(entry, "."); found {
+			ns = Namespace{DB: db, Coll: coll}
+		}
+		if err := ValidateDatabaseName("database", ns.DB); err != nil {
+			log.Printf("warm-up: skipping %q: %v", entry, err)
+			continue
+		}
+
+		if ns.Coll == "*" {
+			infos, err := m.ListCollections(ns.DB)
+			if err != nil {
+				log.Printf("warm-up: skipping %q: %v", entry, err)
+				continue
+			}
+			for _, info := range infos {
+				names = append(names, Namespace{DB: ns.DB, Coll: info.Name})
+			}
+			continue
+		}
+		if err := ValidateName("collection", ns.Coll); err != nil {
+			log.Printf("warm-up: skipping %q: %v", entry, err)
+			continue
+		}
+		m.mu.Lock()
+		exists := m.collectionExistsLocked(ns)
+		m.mu.Unlock()
+		if !exists {
+			// загрузка несуществующей коллекции только заняла бы память пустой
+			log.Printf("warm-up: skipping %s: collection does not exist", ns)
+			continue
+		}
+		names = append(names, ns)
+	}
+	return uniqueNamespaces(names)
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSingleFlightLoad(t *testing.T) {
+	dir := t.TempDir()
+	source := openTestManager(t, dir)
+	for _, name := range []string{"big", "small"} {
+		insertInto(t, source, testNS(name), map[string]any{"name": name})
+	}
+
+	// одновременные обращения к незагруженной коллекции получают одну загрузку
+	m := openTestManager(t, dir)
+	colls := make([]*Collection, 16)
+	var wg sync.WaitGroup
+	for i := range colls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			coll, err := m.GetCollection(testNS("small"))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			colls[i] = coll
+			coll.Release()
+		}()
+	}
+	wg.Wait()
+	for _, coll := range colls {
+		if coll != colls[0] {
+			t.Fatal("concurrent loads opened different collections")
+		}
+	}
+
+	// пока big загружается, остальные коллекции доступны, а обращения к big ждут загрузки
+	call := &loadCall{done: make(chan struct{})}
+	m.mu.Lock()
+	m.loading[testNS("big")] = call
+	m.mu.Unlock()
+
+	loaded := make(chan *Collection, 1)
+	go func() {
+		coll, err := m.GetCollection(testNS("big"))
+		if err != nil {
+			t.Error(err)
+		}
+		loaded <- coll
+	}()
+	withCollection(t, m, testNS("small"), func(coll *Collection) {
+		if coll.Count() != 1 {
+			t.Fatalf("small: %d document(s)", coll.Count())
+		}
+	})
+	select {
+	case <-loaded:
+		t.Fatal("collection is returned before its load finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	big, err := openCollection(dir, testNS("big"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	delete(m.loading, testNS("big"))
+	m.collections[testNS("big")] = big
+	m.mu.Unlock()
+	close(call.done)
+	if coll := <-loaded; coll != big {
+		t.Fatal("waiter did not get the loaded collection")
+	}
+	big.Release()
+
+	// ошибка загрузки достаётся всем ждавшим
+	call = &loadCall{done: make(chan struct{}), err: errors.New("disk failure")}
+	m.mu.Lock()
+	m.loading[testNS("broken")] = call
+	m.mu.Unlock()
+	close(call.done)
+	if _, err := m.GetCollection(testNS("broken")); err == nil || err.Error() != "disk failure" {
+		t.Fatalf("failed load: %v", err)
+	}
+}
+
+func TestWarmUp(t *testing.T) {
+	dir := t.TempDir()
+	source := openTestManager(t, dir)
+	for _, ns := range []Namespace{{DB: "shop", Coll: "orders"}, {DB: "shop", Coll: "items"}, testNS("notes"), testNS("other")} {
+		insertInto(t, source, ns, map[string]any{"name": ns.Coll})
+	}
+
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	t.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	m := openTestManager(t, dir)
+	m.WarmUp(" shop.*, notes, missing, bad/name, default.notes,", 2)
+	if got := loadedCollections(m); !slices.Equal(got, []string{"items", "notes", "orders"}) {
+		t.Fatalf("warmed up: %v", got)
+	}
+	logged := out.String()
+	for _, line := range []string{
+		"loading 3 collection(s), 2 in parallel",
+		"[3/3]",
+		"shop.orders loaded: 1 document(s)",
+		"skipping default.missing: collection does not exist",
+		`skipping "bad/name"`,
+		"3 loaded, 0 failed",
+	} {
+		if !strings.Contains(logged, line) {
+			t.Errorf("log has no %q:\n%s", line, logged)
+		}
+	}
+
+	// пустой список ничего не загружает и не пишет в лог
+	out.Reset()
+	empty := openTestManager(t, dir)
+	empty.WarmUp("", 4)
+	if got := loadedCollections(empty); len(got) != 0 || out.Len() != 0 {
+		t.Fatalf("empty warm-up: %v, log %q", got, out.String())
+	}
+}
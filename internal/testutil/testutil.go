@@ -0,0 +1,97 @@
+// Package testutil — общие заготовки тестов, которые поднимают серверы на портах
+// loopback: менеджер коллекций во временном каталоге, TCP-сервер и клиент его протокола
+package testutil
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"nosql_db/internal/api"
+	"nosql_db/internal/server"
+	"nosql_db/internal/storage"
+)
+
+// NewManager открывает менеджер коллекций во временном каталоге теста
+// и останавливает его по окончании теста
+func NewManager(t testing.TB) *storage.CollectionMng {
+	t.Helper()
+	mng := storage.NewManager()
+	if err := mng.OpenDataDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(mng.Stop)
+	return mng
+}
+
+// StartServer запускает TCP-сервер над mng на свободном порту loopback. configure
+// вызывается после открытия порта (адрес уже известен) и до приёма соединений;
+// сервер закрывается раньше всего, что подготовлено до него
+func StartServer(t testing.TB, mng *storage.CollectionMng, configure func(srv *server.TCPServer)) *server.TCPServer {
+	t.Helper()
+	srv := server.New("127.0.0.1:0")
+	srv.Manager = mng
+	if err := srv.Listen(); err != nil {
+		t.Fatal(err)
+	}
+	if configure != nil {
+		configure(srv)
+	}
+	go srv.Serve()
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+// Client — клиент протокола TCP: запрос и ответ — строки JSON
+type Client struct {
+	t       testing.TB
+	conn    net.Conn
+	decoder *json.Decoder
+}
+
+// Dial подключается к серверу; соединение закрывается по окончании теста
+func Dial(t testing.TB, addr string) *Client {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &Client{t: t, conn: conn, decoder: json.NewDecoder(conn)}
+}
+
+// Do отправляет запрос и возвращает ответ, в том числе с ошибкой
+func (c *Client) Do(req api.Request) api.Response {
+	c.t.Helper()
+	if err := json.NewEncoder(c.conn).Encode(req); err != nil {
+		c.t.Fatal(err)
+	}
+	var resp api.Response
+	if err := c.decoder.Decode(&resp); err != nil {
+		c.t.Fatal(err)
+	}
+	return resp
+}
+
+// OK отправляет запрос и проверяет, что он выполнен
+func (c *Client) OK(req api.Request) api.Response {
+	c.t.Helper()
+	resp := c.Do(req)
+	if resp.Status != api.StatusSuccess {
+		c.t.Fatalf("%s: %s", req.Command, resp.Message)
+	}
+	return resp
+}
+
+// WaitFor ждёт до 10 секунд, пока условие станет истинным
+func WaitFor(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
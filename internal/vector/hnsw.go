@@ -0,0 +1,172 @@
+package vector
+
+import (
+	"container/heap"
+	"sort"
+)
+
+// insertNode встраивает вектор id в граф HNSW
+func (idx *Index) insertNode(id string) {
+	level := idx.randomLevel()
+	node := &Node{Level: level, Friends: make([][]string, level+1)}
+	idx.nodes[id] = node
+
+	if idx.entry == "" {
+		idx.entry, idx.maxLevel = id, level
+		return
+	}
+
+	query := idx.vectors[id]
+	entries := []candidate{{id: idx.entry, distance: idx.distanceTo(query, idx.entry)}}
+
+	// жадный спуск по верхним уровням до уровня нового узла
+	for l := idx.maxLevel; l > level; l-- {
+		entries = idx.searchLayer(query, entries, 1, l)
+	}
+
+	for l := min(level, idx.maxLevel); l >= 0; l-- {
+		found := idx.searchLayer(query, entries, defaultEfConstruction, l)
+		neighbors := found
+		if len(neighbors) > maxConnections(l) {
+			neighbors = neighbors[:maxConnections(l)]
+		}
+		for _, n := range neighbors {
+			if n.id == id {
+				continue
+			}
+			node.Friends[l] = append(node.Friends[l], n.id)
+			idx.connect(n.id, id, l)
+		}
+		entries = found
+	}
+
+	if level > idx.maxLevel {
+		idx.entry, idx.maxLevel = id, level
+	}
+}
+
+// connect добавляет ребро from -> to на уровне и оставляет ближайших соседей, если их слишком много
+func (idx *Index) connect(from, to string, level int) {
+	node := idx.nodes[from]
+	if node == nil || node.Level < level {
+		return
+	}
+	node.Friends[level] = append(node.Friends[level], to)
+	if len(node.Friends[level]) <= maxConnections(level) {
+		return
+	}
+
+	base := idx.vectors[from]
+	ranked := make([]candidate, len(node.Friends[level]))
+	for i, friendID := range node.Friends[level] {
+		ranked[i] = candidate{id: friendID, distance: idx.distanceTo(base, friendID)}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].distance < ranked[j].distance
+	})
+	friends := node.Friends[level][:0]
+	for _, c := range ranked[:maxConnections(level)] {
+		friends = append(friends, c.id)
+	}
+	node.Friends[level] = friends
+}
+
+// unlink физически удаляет узел из графа и ссылки на него; соседи, потерявшие ребро,
+// получают в замену соседей удалённого узла
+func (idx *Index) unlink(id string) {
+	node := idx.nodes[id]
+	delete(idx.nodes, id)
+	delete(idx.deleted, id)
+	if node == nil {
+		return
+	}
+
+	for otherID, other := range idx.nodes {
+		for l := range other.Friends {
+			pos := indexOf(other.Friends[l], id)
+			if pos < 0 {
+				continue
+			}
+			other.Friends[l] = append(other.Friends[l][:pos], other.Friends[l][pos+1:]...)
+			if l <= node.Level {
+				for _, replacement := range node.Friends[l] {
+					if replacement != otherID && indexOf(other.Friends[l], replacement) < 0 {
+						idx.connect(otherID, replacement, l)
+					}
+				}
+			}
+		}
+	}
+
+	if idx.entry == id {
+		idx.entry, idx.maxLevel = "", 0
+		for otherID, other := range idx.nodes {
+			if idx.entry == "" || other.Level > idx.maxLevel {
+				idx.entry, idx.maxLevel = otherID, other.Level
+			}
+		}
+	}
+}
+
+// searchGraph спускается по уровням и возвращает ef ближайших на нулевом уровне
+func (idx *Index) searchGraph(query []float32, ef int) []candidate {
+	entries := []candidate{{id: idx.entry, distance: idx.distanceTo(query, idx.entry)}}
+	for l := idx.maxLevel; l > 0; l-- {
+		entries = idx.searchLayer(query, entries, 1, l)
+	}
+	return idx.searchLayer(query, entries, ef, 0)
+}
+
+// searchLayer — поиск ef ближайших на одном уровне графа; результат по возрастанию расстояния
+func (idx *Index) searchLayer(query []float32, entries []candidate, ef, level int) []candidate {
+	visited := make(map[string]bool, ef*4)
+	candidates := &minHeap{}
+	results := &maxHeap{}
+	for _, e := range entries {
+		visited[e.id] = true
+		heap.Push(candidates, e)
+		heap.Push(results, e)
+		if results.Len() > ef {
+			heap.Pop(results)
+		}
+	}
+
+	for candidates.Len() > 0 {
+		current := heap.Pop(candidates).(candidate)
+		if results.Len() >= ef && current.distance > (*results)[0].distance {
+			break
+		}
+		node := idx.nodes[current.id]
+		if node == nil || node.Level < level {
+			continue
+		}
+		for _, neighborID := range node.Friends[level] {
+			if visited[neighborID] {
+				continue
+			}
+			visited[neighborID] = true
+			d := idx.distanceTo(query, neighborID)
+			if results.Len() < ef || d < (*results)[0].distance {
+				heap.Push(candidates, candidate{id: neighborID, distance: d})
+				heap.Push(results, candidate{id: neighborID, distance: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+	return sortedAscending(results)
+}
+
+func (idx *Index) distanceTo(query []float32, id string) float64 {
+	return idx.metric.Distance(query, idx.vectors[id])
+}
+
+func indexOf(ids []string, id string) int {
+	for i, v := range ids {
+		if v == id {
+			return i
+		}
+	}
+	return -1
+}
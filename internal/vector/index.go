@@ -0,0 +1,233 @@
+package vector
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// параметры графа HNSW
+const (
+	defaultM              = 16  // соседей на верхних уровнях
+	defaultEfConstruction = 100 // ширина поиска при вставке
+)
+
+// Index — векторный индекс поля: точный перебор и приближённый поиск по графу HNSW.
+// Удалённые векторы помечаются и остаются в графе для навигации,
+// пока их не станет больше половины — тогда граф перестраивается
+type Index struct {
+	metric  Metric
+	dims    int
+	vectors map[string][]float32
+
+	nodes    map[string]*Node
+	deleted  map[string]bool
+	entry    string
+	maxLevel int
+	rng      *rand.Rand
+}
+
+// Node — узел графа HNSW: верхний уровень и списки соседей по уровням
+type Node struct {
+	Level   int
+	Friends [][]string
+}
+
+// Result — найденный документ и оценка близости (больше — ближе)
+type Result struct {
+	ID    string
+	Score float64
+}
+
+// NewIndex создаёт пустой индекс; dims = 0 — размерность берётся из первого вектора
+func NewIndex(metric Metric, dims int) *Index {
+	return &Index{
+		metric:  metric,
+		dims:    dims,
+		vectors: make(map[string][]float32),
+		nodes:   make(map[string]*Node),
+		deleted: make(map[string]bool),
+		rng:     rand.New(rand.NewSource(1)),
+	}
+}
+
+// Restore восстанавливает индекс вместе с графом
+func Restore(metric Metric, dims int, vectors map[string][]float32, nodes map[string]*Node, deleted []string, entry string, maxLevel int) *Index {
+	idx := NewIndex(metric, dims)
+	if vectors != nil {
+		idx.vectors = vectors
+	}
+	if nodes != nil {
+		idx.nodes = nodes
+	}
+	for _, id := range deleted {
+		idx.deleted[id] = true
+	}
+	idx.entry = entry
+	idx.maxLevel = maxLevel
+	return idx
+}
+
+// Metric возвращает метрику индекса
+func (idx *Index) Metric() Metric { return idx.metric }
+
+// Dims возвращает размерность векторов
+func (idx *Index) Dims() int { return idx.dims }
+
+// Vectors возвращает векторы индекса (включая помеченные удалёнными)
+func (idx *Index) Vectors() map[string][]float32 { return idx.vectors }
+
+// Nodes возвращает узлы графа
+func (idx *Index) Nodes() map[string]*Node { return idx.nodes }
+
+// Entry возвращает точку входа в граф и её уровень
+func (idx *Index) Entry() (string, int) { return idx.entry, idx.maxLevel }
+
+// Deleted возвращает id векторов, помеченных удалёнными
+func (idx *Index) Deleted() []string {
+	ids := make([]string, 0, len(idx.deleted))
+	for id := range idx.deleted {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Len возвращает количество живых векторов
+func (idx *Index) Len() int {
+	return len(idx.vectors) - len(idx.deleted)
+}
+
+// Add добавляет вектор документа; false, если размерность не совпадает с индексом
+func (idx *Index) Add(id string, vec []float32) bool {
+	if idx.dims == 0 {
+		idx.dims = len(vec)
+	}
+	if len(vec) != idx.dims {
+		return false
+	}
+	if _, exists := idx.vectors[id]; exists {
+		idx.unlink(id)
+	}
+	idx.vectors[id] = vec
+	idx.insertNode(id)
+	return true
+}
+
+// Remove помечает вектор документа удалённым
+func (idx *Index) Remove(id string) {
+	if _, exists := idx.vectors[id]; !exists || idx.deleted[id] {
+		return
+	}
+	idx.deleted[id] = true
+	if len(idx.deleted)*2 > len(idx.vectors) {
+		idx.rebuild()
+	}
+}
+
+// SearchExact перебирает все векторы и возвращает k ближайших, прошедших accept
+func (idx *Index) SearchExact(query []float32, k int, accept func(id string) bool) []Result {
+	if len(query) != idx.dims {
+		return nil
+	}
+	return rank(idx.metric, query, k, func(yield func(id string, vec []float32)) {
+		for id, vec := range idx.vectors {
+			if idx.deleted[id] || (accept != nil && !accept(id)) {
+				continue
+			}
+			yield(id, vec)
+		}
+	})
+}
+
+// Rank — точный поиск k ближайших среди произвольного набора векторов (без индекса)
+func Rank(metric Metric, query []float32, k int, vectors map[string][]float32) []Result {
+	return rank(metric, query, k, func(yield func(id string, vec []float32)) {
+		for id, vec := range vectors {
+			if len(vec) == len(query) {
+				yield(id, vec)
+			}
+		}
+	})
+}
+
+// rank вычисляет расстояния до всех векторов из each и оставляет k ближайших
+func rank(metric Metric, query []float32, k int, each func(yield func(id string, vec []float32))) []Result {
+	var found []candidate
+	each(func(id string, vec []float32) {
+		found = append(found, candidate{id: id, distance: metric.Distance(query, vec)})
+	})
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].distance != found[j].distance {
+			return found[i].distance < found[j].distance
+		}
+		return found[i].id < found[j].id
+	})
+	return toResults(metric, found, k)
+}
+
+// Search ищет k ближайших по графу HNSW с шириной поиска ef.
+// Если после фильтра accept кандидатов меньше k, ширина поиска увеличивается
+func (idx *Index) Search(query []float32, k, ef int, accept func(id string) bool) []Result {
+	if len(query) != idx.dims || idx.entry == "" || k <= 0 {
+		return nil
+	}
+	ef = max(ef, k)
+	for {
+		var matched []candidate
+		for _, c := range idx.searchGraph(query, ef) {
+			if idx.deleted[c.id] || (accept != nil && !accept(c.id)) {
+				continue
+			}
+			matched = append(matched, c)
+		}
+		if len(matched) >= k || ef >= len(idx.vectors) {
+			return toResults(idx.metric, matched, k)
+		}
+		ef *= 2
+	}
+}
+
+// toResults переводит отсортированных кандидатов в результаты с оценкой
+func toResults(metric Metric, found []candidate, k int) []Result {
+	if k > 0 && len(found) > k {
+		found = found[:k]
+	}
+	results := make([]Result, len(found))
+	for i, c := range found {
+		results[i] = Result{ID: c.id, Score: metric.Score(c.distance)}
+	}
+	return results
+}
+
+// rebuild строит граф заново по живым векторам
+func (idx *Index) rebuild() {
+	live := make([]string, 0, idx.Len())
+	for id := range idx.vectors {
+		if idx.deleted[id] {
+			delete(idx.vectors, id)
+			continue
+		}
+		live = append(live, id)
+	}
+	sort.Strings(live)
+
+	idx.nodes = make(map[string]*Node, len(live))
+	idx.deleted = make(map[string]bool)
+	idx.entry, idx.maxLevel = "", 0
+	for _, id := range live {
+		idx.insertNode(id)
+	}
+}
+
+// randomLevel выбирает уровень узла с экспоненциально убывающей вероятностью
+func (idx *Index) randomLevel() int {
+	return int(math.Floor(-math.Log(1-idx.rng.Float64()) / math.Log(defaultM)))
+}
+
+func maxConnections(level int) int {
+	if level == 0 {
+		return defaultM * 2
+	}
+	return defaultM
+}
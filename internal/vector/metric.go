@@ -0,0 +1,91 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+)
+
+// Metric — мера близости векторов
+type Metric string
+
+const (
+	MetricCosine Metric = "cosine"
+	MetricDot    Metric = "dot"
+	MetricL2     Metric = "l2"
+)
+
+// ParseMetric проверяет имя метрики; пустое имя — косинусная
+func ParseMetric(name string) (Metric, error) {
+	switch Metric(name) {
+	case "":
+		return MetricCosine, nil
+	case MetricCosine, MetricDot, MetricL2:
+		return Metric(name), nil
+	default:
+		return "", fmt.Errorf("unknown vector metric: %s", name)
+	}
+}
+
+// Distance возвращает расстояние между векторами: чем меньше, тем ближе.
+// cosine: 1 - cos, dot: -скалярное произведение, l2: квадрат евклидова расстояния
+func (m Metric) Distance(a, b []float32) float64 {
+	switch m {
+	case MetricDot:
+		return -dot(a, b)
+	case MetricL2:
+		var sum float64
+		for i := range a {
+			d := float64(a[i]) - float64(b[i])
+			sum += d * d
+		}
+		return sum
+	default:
+		na, nb := norm(a), norm(b)
+		if na == 0 || nb == 0 {
+			return 1
+		}
+		return 1 - dot(a, b)/(na*nb)
+	}
+}
+
+// Score переводит расстояние в оценку близости: чем больше, тем ближе.
+// cosine: косинус угла, dot: скалярное произведение, l2: 1 / (1 + евклидово расстояние)
+func (m Metric) Score(distance float64) float64 {
+	switch m {
+	case MetricDot:
+		return -distance
+	case MetricL2:
+		return 1 / (1 + math.Sqrt(distance))
+	default:
+		return 1 - distance
+	}
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
+
+func norm(a []float32) float64 {
+	return math.Sqrt(dot(a, a))
+}
+
+// Parse читает вектор из значения поля документа: массив чисел
+func Parse(value any) ([]float32, bool) {
+	items, ok := value.([]any)
+	if !ok || len(items) == 0 {
+		return nil, false
+	}
+	vec := make([]float32, len(items))
+	for i, item := range items {
+		f, ok := item.(float64)
+		if !ok {
+			return nil, false
+		}
+		vec[i] = float32(f)
+	}
+	return vec, true
+}
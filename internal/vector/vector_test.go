@@ -0,0 +1,175 @@
+package vector
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	a, b := []float32{1, 0}, []float32{0, 2}
+	for _, tt := range []struct {
+		metric          Metric
+		distance, score float64
+	}{
+		{MetricCosine, 1, 0},
+		{MetricDot, 0, 0},
+		{MetricL2, 5, 1 / (1 + math.Sqrt(5))},
+	} {
+		if d := tt.metric.Distance(a, b); math.Abs(d-tt.distance) > 1e-9 {
+			t.Errorf("%s distance: %v", tt.metric, d)
+		}
+		if s := tt.metric.Score(tt.metric.Distance(a, b)); math.Abs(s-tt.score) > 1e-9 {
+			t.Errorf("%s score: %v", tt.metric, s)
+		}
+	}
+	// косинус не зависит от длины, скалярное произведение — зависит
+	if d := MetricCosine.Distance([]float32{1, 1}, []float32{3, 3}); math.Abs(d) > 1e-9 {
+		t.Errorf("cosine of parallel vectors: %v", d)
+	}
+	if MetricDot.Distance([]float32{1, 1}, []float32{3, 3}) >= MetricDot.Distance([]float32{1, 1}, []float32{1, 1}) {
+		t.Error("dot must prefer the longer vector")
+	}
+	if d := MetricCosine.Distance([]float32{0, 0}, a); d != 1 {
+		t.Errorf("cosine with a zero vector: %v", d)
+	}
+
+	if m, err := ParseMetric(""); err != nil || m != MetricCosine {
+		t.Errorf("default metric: %v %v", m, err)
+	}
+	if _, err := ParseMetric("manhattan"); err == nil {
+		t.Error("unknown metric must be rejected")
+	}
+	if vec, ok := Parse([]any{1.0, 0.5}); !ok || !slices.Equal(vec, []float32{1, 0.5}) {
+		t.Errorf("parse: %v %v", vec, ok)
+	}
+	for _, value := range []any{[]any{}, []any{1.0, "2"}, "1,2", nil} {
+		if _, ok := Parse(value); ok {
+			t.Errorf("%v must not parse", value)
+		}
+	}
+}
+
+// randomIndex заполняет индекс n случайными векторами размерности dims
+func randomIndex(metric Metric, n, dims int) (*Index, *rand.Rand) {
+	rng := rand.New(rand.NewSource(7))
+	idx := NewIndex(metric, 0)
+	for i := 0; i < n; i++ {
+		idx.Add(fmt.Sprintf("v%d", i), randomVector(rng, dims))
+	}
+	return idx, rng
+}
+
+func randomVector(rng *rand.Rand, dims int) []float32 {
+	vec := make([]float32, dims)
+	for i := range vec {
+		vec[i] = rng.Float32()*2 - 1
+	}
+	return vec
+}
+
+func ids(results []Result) []string {
+	found := make([]string, len(results))
+	for i, r := range results {
+		found[i] = r.ID
+	}
+	return found
+}
+
+func TestSearchRecall(t *testing.T) {
+	for _, metric := range []Metric{MetricCosine, MetricDot, MetricL2} {
+		idx, rng := randomIndex(metric, 2000, 16)
+		if idx.Dims() != 16 || idx.Len() != 2000 {
+			t.Fatalf("dims %d, len %d", idx.Dims(), idx.Len())
+		}
+		hits, total := 0, 0
+		for q := 0; q < 50; q++ {
+			query := randomVector(rng, 16)
+			exact := ids(idx.SearchExact(query, 10, nil))
+			approx := idx.Search(query, 10, 64, nil)
+			if len(approx) != 10 {
+				t.Fatalf("%s: %d result(s)", metric, len(approx))
+			}
+			for i := 1; i < len(approx); i++ {
+				if approx[i].Score > approx[i-1].Score {
+					t.Fatalf("%s: results are not ordered by score: %v", metric, approx)
+				}
+			}
+			for _, id := range ids(approx) {
+				if slices.Contains(exact, id) {
+					hits++
+				}
+			}
+			total += len(exact)
+		}
+		if recall := float64(hits) / float64(total); recall < 0.9 {
+			t.Errorf("%s: recall %.2f", metric, recall)
+		}
+	}
+}
+
+func TestSearchFilterAndRemove(t *testing.T) {
+	idx, rng := randomIndex(MetricL2, 500, 8)
+	query := randomVector(rng, 8)
+
+	// фильтр пропускает один вектор из десяти: ширина поиска растёт, пока не найдётся k
+	even := func(id string) bool { return id[len(id)-1] == '0' }
+	found := idx.Search(query, 20, 16, even)
+	if len(found) != 20 {
+		t.Fatalf("filtered search: %d result(s)", len(found))
+	}
+	for _, r := range found {
+		if !even(r.ID) {
+			t.Fatalf("%s does not pass the filter", r.ID)
+		}
+	}
+
+	if idx.Add("bad", []float32{1, 2}) {
+		t.Fatal("vector of another dimension must be rejected")
+	}
+	if got := idx.Search([]float32{1, 2}, 5, 16, nil); got != nil {
+		t.Fatalf("query of another dimension: %v", got)
+	}
+
+	nearest := idx.SearchExact(query, 1, nil)[0].ID
+	idx.Remove(nearest)
+	if slices.Contains(ids(idx.Search(query, 10, 64, nil)), nearest) || idx.Len() != 499 {
+		t.Fatal("removed vector is still found")
+	}
+	// больше половины удалённых — граф перестраивается без них
+	removed := map[string]bool{nearest: true}
+	for i := 0; i < 300; i++ {
+		idx.Remove(fmt.Sprintf("v%d", i))
+		removed[fmt.Sprintf("v%d", i)] = true
+	}
+	if deleted := len(idx.Deleted()); len(idx.Vectors()) >= 500 || deleted*2 > len(idx.Vectors()) || idx.Len() != 500-len(removed) {
+		t.Fatalf("graph is not rebuilt: %d deleted, %d vectors, %d live", len(idx.Deleted()), len(idx.Vectors()), idx.Len())
+	}
+	if got, want := ids(idx.Search(query, 10, 200, nil)), ids(idx.SearchExact(query, 10, nil)); !slices.Equal(got, want) {
+		t.Fatalf("after rebuild: %v, exact %v", got, want)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	idx, rng := randomIndex(MetricCosine, 300, 8)
+	idx.Remove("v1")
+	entry, level := idx.Entry()
+	restored := Restore(idx.Metric(), idx.Dims(), idx.Vectors(), idx.Nodes(), idx.Deleted(), entry, level)
+	for q := 0; q < 10; q++ {
+		query := randomVector(rng, 8)
+		if got, want := ids(restored.Search(query, 5, 32, nil)), ids(idx.Search(query, 5, 32, nil)); !slices.Equal(got, want) {
+			t.Fatalf("restored index: %v, original %v", got, want)
+		}
+	}
+	if restored.Len() != 299 {
+		t.Fatalf("restored len %d", restored.Len())
+	}
+
+	if got := ids(Rank(MetricL2, []float32{0, 0}, 2, map[string][]float32{
+		"far": {3, 4}, "near": {1, 0}, "other": {1, 1, 1}, "mid": {0, 2},
+	})); !slices.Equal(got, []string{"near", "mid"}) {
+		t.Fatalf("rank: %v", got)
+	}
+}